@@ -23,6 +23,8 @@ type Response struct {
 	ReplyTo       int                  // message to reply to, if 0 then no reply but common message
 	DeleteReplyTo bool                 // delete message what bot replays to
 	CheckResults  []spamcheck.Response // check results for the message
+	Dry           bool                 // ban not applied, set by listener in dry run or training mode
+	Temporary     bool                 // muted, restricted or banned for a limited time, set by listener from punishment ladder
 }
 
 // SenderChat is the sender of the message, sent on behalf of a chat. The
@@ -85,6 +87,8 @@ type Image struct {
 	Height   int
 	Caption  string    `json:",omitempty"`
 	Entities *[]Entity `json:",omitempty"`
+	Text     string    `json:",omitempty"` // text extracted from the image (OCR), empty if not extracted
+	Hash     string    `json:",omitempty"` // perceptual hash of the image, empty if not calculated
 }
 
 // User defines user info of the Message
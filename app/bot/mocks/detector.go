@@ -26,21 +26,24 @@ import (
 //			CheckFunc: func(request spamcheck.Request) (bool, []spamcheck.Response) {
 //				panic("mock out the Check method")
 //			},
-//			RecordReactionFunc: func(userID int64) spamcheck.Response {
-//				panic("mock out the RecordReaction method")
-//			},
 //			GetLuaPluginNamesFunc: func() []string {
 //				panic("mock out the GetLuaPluginNames method")
 //			},
 //			IsApprovedUserFunc: func(userID string) bool {
 //				panic("mock out the IsApprovedUser method")
 //			},
+//			LoadDomainsFunc: func(blocked io.Reader, allowed io.Reader) (tgspam.LoadResult, error) {
+//				panic("mock out the LoadDomains method")
+//			},
 //			LoadSamplesFunc: func(exclReader io.Reader, spamReaders []io.Reader, hamReaders []io.Reader) (tgspam.LoadResult, error) {
 //				panic("mock out the LoadSamples method")
 //			},
 //			LoadStopWordsFunc: func(readers ...io.Reader) (tgspam.LoadResult, error) {
 //				panic("mock out the LoadStopWords method")
 //			},
+//			RecordReactionFunc: func(userID int64) spamcheck.Response {
+//				panic("mock out the RecordReaction method")
+//			},
 //			RemoveApprovedUserFunc: func(id string) error {
 //				panic("mock out the RemoveApprovedUser method")
 //			},
@@ -50,6 +53,9 @@ import (
 //			RemoveSpamFunc: func(msg string) error {
 //				panic("mock out the RemoveSpam method")
 //			},
+//			SeedApprovedUserFunc: func(user approved.UserInfo) error {
+//				panic("mock out the SeedApprovedUser method")
+//			},
 //			UpdateHamFunc: func(msg string) error {
 //				panic("mock out the UpdateHam method")
 //			},
@@ -72,21 +78,24 @@ type DetectorMock struct {
 	// CheckFunc mocks the Check method.
 	CheckFunc func(request spamcheck.Request) (bool, []spamcheck.Response)
 
-	// RecordReactionFunc mocks the RecordReaction method.
-	RecordReactionFunc func(userID int64) spamcheck.Response
-
 	// GetLuaPluginNamesFunc mocks the GetLuaPluginNames method.
 	GetLuaPluginNamesFunc func() []string
 
 	// IsApprovedUserFunc mocks the IsApprovedUser method.
 	IsApprovedUserFunc func(userID string) bool
 
+	// LoadDomainsFunc mocks the LoadDomains method.
+	LoadDomainsFunc func(blocked io.Reader, allowed io.Reader) (tgspam.LoadResult, error)
+
 	// LoadSamplesFunc mocks the LoadSamples method.
 	LoadSamplesFunc func(exclReader io.Reader, spamReaders []io.Reader, hamReaders []io.Reader) (tgspam.LoadResult, error)
 
 	// LoadStopWordsFunc mocks the LoadStopWords method.
 	LoadStopWordsFunc func(readers ...io.Reader) (tgspam.LoadResult, error)
 
+	// RecordReactionFunc mocks the RecordReaction method.
+	RecordReactionFunc func(userID int64) spamcheck.Response
+
 	// RemoveApprovedUserFunc mocks the RemoveApprovedUser method.
 	RemoveApprovedUserFunc func(id string) error
 
@@ -96,6 +105,9 @@ type DetectorMock struct {
 	// RemoveSpamFunc mocks the RemoveSpam method.
 	RemoveSpamFunc func(msg string) error
 
+	// SeedApprovedUserFunc mocks the SeedApprovedUser method.
+	SeedApprovedUserFunc func(user approved.UserInfo) error
+
 	// UpdateHamFunc mocks the UpdateHam method.
 	UpdateHamFunc func(msg string) error
 
@@ -117,11 +129,6 @@ type DetectorMock struct {
 			// Request is the request argument value.
 			Request spamcheck.Request
 		}
-		// RecordReaction holds details about calls to the RecordReaction method.
-		RecordReaction []struct {
-			// UserID is the userID argument value.
-			UserID int64
-		}
 		// GetLuaPluginNames holds details about calls to the GetLuaPluginNames method.
 		GetLuaPluginNames []struct {
 		}
@@ -130,6 +137,13 @@ type DetectorMock struct {
 			// UserID is the userID argument value.
 			UserID string
 		}
+		// LoadDomains holds details about calls to the LoadDomains method.
+		LoadDomains []struct {
+			// Blocked is the blocked argument value.
+			Blocked io.Reader
+			// Allowed is the allowed argument value.
+			Allowed io.Reader
+		}
 		// LoadSamples holds details about calls to the LoadSamples method.
 		LoadSamples []struct {
 			// ExclReader is the exclReader argument value.
@@ -144,6 +158,11 @@ type DetectorMock struct {
 			// Readers is the readers argument value.
 			Readers []io.Reader
 		}
+		// RecordReaction holds details about calls to the RecordReaction method.
+		RecordReaction []struct {
+			// UserID is the userID argument value.
+			UserID int64
+		}
 		// RemoveApprovedUser holds details about calls to the RemoveApprovedUser method.
 		RemoveApprovedUser []struct {
 			// ID is the id argument value.
@@ -159,6 +178,11 @@ type DetectorMock struct {
 			// Msg is the msg argument value.
 			Msg string
 		}
+		// SeedApprovedUser holds details about calls to the SeedApprovedUser method.
+		SeedApprovedUser []struct {
+			// User is the user argument value.
+			User approved.UserInfo
+		}
 		// UpdateHam holds details about calls to the UpdateHam method.
 		UpdateHam []struct {
 			// Msg is the msg argument value.
@@ -173,14 +197,16 @@ type DetectorMock struct {
 	lockAddApprovedUser    sync.RWMutex
 	lockApprovedUsers      sync.RWMutex
 	lockCheck              sync.RWMutex
-	lockRecordReaction     sync.RWMutex
 	lockGetLuaPluginNames  sync.RWMutex
 	lockIsApprovedUser     sync.RWMutex
+	lockLoadDomains        sync.RWMutex
 	lockLoadSamples        sync.RWMutex
 	lockLoadStopWords      sync.RWMutex
+	lockRecordReaction     sync.RWMutex
 	lockRemoveApprovedUser sync.RWMutex
 	lockRemoveHam          sync.RWMutex
 	lockRemoveSpam         sync.RWMutex
+	lockSeedApprovedUser   sync.RWMutex
 	lockUpdateHam          sync.RWMutex
 	lockUpdateSpam         sync.RWMutex
 }
@@ -297,45 +323,6 @@ func (mock *DetectorMock) ResetCheckCalls() {
 	mock.lockCheck.Unlock()
 }
 
-// RecordReaction calls RecordReactionFunc.
-func (mock *DetectorMock) RecordReaction(userID int64) spamcheck.Response {
-	if mock.RecordReactionFunc == nil {
-		panic("DetectorMock.RecordReactionFunc: method is nil but Detector.RecordReaction was just called")
-	}
-	callInfo := struct {
-		UserID int64
-	}{
-		UserID: userID,
-	}
-	mock.lockRecordReaction.Lock()
-	mock.calls.RecordReaction = append(mock.calls.RecordReaction, callInfo)
-	mock.lockRecordReaction.Unlock()
-	return mock.RecordReactionFunc(userID)
-}
-
-// RecordReactionCalls gets all the calls that were made to RecordReaction.
-// Check the length with:
-//
-//	len(mockedDetector.RecordReactionCalls())
-func (mock *DetectorMock) RecordReactionCalls() []struct {
-	UserID int64
-} {
-	var calls []struct {
-		UserID int64
-	}
-	mock.lockRecordReaction.RLock()
-	calls = mock.calls.RecordReaction
-	mock.lockRecordReaction.RUnlock()
-	return calls
-}
-
-// ResetRecordReactionCalls reset all the calls that were made to RecordReaction.
-func (mock *DetectorMock) ResetRecordReactionCalls() {
-	mock.lockRecordReaction.Lock()
-	mock.calls.RecordReaction = nil
-	mock.lockRecordReaction.Unlock()
-}
-
 // GetLuaPluginNames calls GetLuaPluginNamesFunc.
 func (mock *DetectorMock) GetLuaPluginNames() []string {
 	if mock.GetLuaPluginNamesFunc == nil {
@@ -409,6 +396,49 @@ func (mock *DetectorMock) ResetIsApprovedUserCalls() {
 	mock.lockIsApprovedUser.Unlock()
 }
 
+// LoadDomains calls LoadDomainsFunc.
+func (mock *DetectorMock) LoadDomains(blocked io.Reader, allowed io.Reader) (tgspam.LoadResult, error) {
+	if mock.LoadDomainsFunc == nil {
+		panic("DetectorMock.LoadDomainsFunc: method is nil but Detector.LoadDomains was just called")
+	}
+	callInfo := struct {
+		Blocked io.Reader
+		Allowed io.Reader
+	}{
+		Blocked: blocked,
+		Allowed: allowed,
+	}
+	mock.lockLoadDomains.Lock()
+	mock.calls.LoadDomains = append(mock.calls.LoadDomains, callInfo)
+	mock.lockLoadDomains.Unlock()
+	return mock.LoadDomainsFunc(blocked, allowed)
+}
+
+// LoadDomainsCalls gets all the calls that were made to LoadDomains.
+// Check the length with:
+//
+//	len(mockedDetector.LoadDomainsCalls())
+func (mock *DetectorMock) LoadDomainsCalls() []struct {
+	Blocked io.Reader
+	Allowed io.Reader
+} {
+	var calls []struct {
+		Blocked io.Reader
+		Allowed io.Reader
+	}
+	mock.lockLoadDomains.RLock()
+	calls = mock.calls.LoadDomains
+	mock.lockLoadDomains.RUnlock()
+	return calls
+}
+
+// ResetLoadDomainsCalls reset all the calls that were made to LoadDomains.
+func (mock *DetectorMock) ResetLoadDomainsCalls() {
+	mock.lockLoadDomains.Lock()
+	mock.calls.LoadDomains = nil
+	mock.lockLoadDomains.Unlock()
+}
+
 // LoadSamples calls LoadSamplesFunc.
 func (mock *DetectorMock) LoadSamples(exclReader io.Reader, spamReaders []io.Reader, hamReaders []io.Reader) (tgspam.LoadResult, error) {
 	if mock.LoadSamplesFunc == nil {
@@ -495,6 +525,45 @@ func (mock *DetectorMock) ResetLoadStopWordsCalls() {
 	mock.lockLoadStopWords.Unlock()
 }
 
+// RecordReaction calls RecordReactionFunc.
+func (mock *DetectorMock) RecordReaction(userID int64) spamcheck.Response {
+	if mock.RecordReactionFunc == nil {
+		panic("DetectorMock.RecordReactionFunc: method is nil but Detector.RecordReaction was just called")
+	}
+	callInfo := struct {
+		UserID int64
+	}{
+		UserID: userID,
+	}
+	mock.lockRecordReaction.Lock()
+	mock.calls.RecordReaction = append(mock.calls.RecordReaction, callInfo)
+	mock.lockRecordReaction.Unlock()
+	return mock.RecordReactionFunc(userID)
+}
+
+// RecordReactionCalls gets all the calls that were made to RecordReaction.
+// Check the length with:
+//
+//	len(mockedDetector.RecordReactionCalls())
+func (mock *DetectorMock) RecordReactionCalls() []struct {
+	UserID int64
+} {
+	var calls []struct {
+		UserID int64
+	}
+	mock.lockRecordReaction.RLock()
+	calls = mock.calls.RecordReaction
+	mock.lockRecordReaction.RUnlock()
+	return calls
+}
+
+// ResetRecordReactionCalls reset all the calls that were made to RecordReaction.
+func (mock *DetectorMock) ResetRecordReactionCalls() {
+	mock.lockRecordReaction.Lock()
+	mock.calls.RecordReaction = nil
+	mock.lockRecordReaction.Unlock()
+}
+
 // RemoveApprovedUser calls RemoveApprovedUserFunc.
 func (mock *DetectorMock) RemoveApprovedUser(id string) error {
 	if mock.RemoveApprovedUserFunc == nil {
@@ -612,6 +681,45 @@ func (mock *DetectorMock) ResetRemoveSpamCalls() {
 	mock.lockRemoveSpam.Unlock()
 }
 
+// SeedApprovedUser calls SeedApprovedUserFunc.
+func (mock *DetectorMock) SeedApprovedUser(user approved.UserInfo) error {
+	if mock.SeedApprovedUserFunc == nil {
+		panic("DetectorMock.SeedApprovedUserFunc: method is nil but Detector.SeedApprovedUser was just called")
+	}
+	callInfo := struct {
+		User approved.UserInfo
+	}{
+		User: user,
+	}
+	mock.lockSeedApprovedUser.Lock()
+	mock.calls.SeedApprovedUser = append(mock.calls.SeedApprovedUser, callInfo)
+	mock.lockSeedApprovedUser.Unlock()
+	return mock.SeedApprovedUserFunc(user)
+}
+
+// SeedApprovedUserCalls gets all the calls that were made to SeedApprovedUser.
+// Check the length with:
+//
+//	len(mockedDetector.SeedApprovedUserCalls())
+func (mock *DetectorMock) SeedApprovedUserCalls() []struct {
+	User approved.UserInfo
+} {
+	var calls []struct {
+		User approved.UserInfo
+	}
+	mock.lockSeedApprovedUser.RLock()
+	calls = mock.calls.SeedApprovedUser
+	mock.lockSeedApprovedUser.RUnlock()
+	return calls
+}
+
+// ResetSeedApprovedUserCalls reset all the calls that were made to SeedApprovedUser.
+func (mock *DetectorMock) ResetSeedApprovedUserCalls() {
+	mock.lockSeedApprovedUser.Lock()
+	mock.calls.SeedApprovedUser = nil
+	mock.lockSeedApprovedUser.Unlock()
+}
+
 // UpdateHam calls UpdateHamFunc.
 func (mock *DetectorMock) UpdateHam(msg string) error {
 	if mock.UpdateHamFunc == nil {
@@ -704,10 +812,6 @@ func (mock *DetectorMock) ResetCalls() {
 	mock.calls.Check = nil
 	mock.lockCheck.Unlock()
 
-	mock.lockRecordReaction.Lock()
-	mock.calls.RecordReaction = nil
-	mock.lockRecordReaction.Unlock()
-
 	mock.lockGetLuaPluginNames.Lock()
 	mock.calls.GetLuaPluginNames = nil
 	mock.lockGetLuaPluginNames.Unlock()
@@ -716,6 +820,10 @@ func (mock *DetectorMock) ResetCalls() {
 	mock.calls.IsApprovedUser = nil
 	mock.lockIsApprovedUser.Unlock()
 
+	mock.lockLoadDomains.Lock()
+	mock.calls.LoadDomains = nil
+	mock.lockLoadDomains.Unlock()
+
 	mock.lockLoadSamples.Lock()
 	mock.calls.LoadSamples = nil
 	mock.lockLoadSamples.Unlock()
@@ -724,6 +832,10 @@ func (mock *DetectorMock) ResetCalls() {
 	mock.calls.LoadStopWords = nil
 	mock.lockLoadStopWords.Unlock()
 
+	mock.lockRecordReaction.Lock()
+	mock.calls.RecordReaction = nil
+	mock.lockRecordReaction.Unlock()
+
 	mock.lockRemoveApprovedUser.Lock()
 	mock.calls.RemoveApprovedUser = nil
 	mock.lockRemoveApprovedUser.Unlock()
@@ -736,6 +848,10 @@ func (mock *DetectorMock) ResetCalls() {
 	mock.calls.RemoveSpam = nil
 	mock.lockRemoveSpam.Unlock()
 
+	mock.lockSeedApprovedUser.Lock()
+	mock.calls.SeedApprovedUser = nil
+	mock.lockSeedApprovedUser.Unlock()
+
 	mock.lockUpdateHam.Lock()
 	mock.calls.UpdateHam = nil
 	mock.lockUpdateHam.Unlock()
@@ -7,9 +7,11 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
+	"github.com/umputun/tg-spam/app/metrics"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/lib/approved"
 	"github.com/umputun/tg-spam/lib/spamcheck"
@@ -43,11 +45,13 @@ type Detector interface {
 	Check(request spamcheck.Request) (spam bool, cr []spamcheck.Response)
 	LoadSamples(exclReader io.Reader, spamReaders, hamReaders []io.Reader) (tgspam.LoadResult, error)
 	LoadStopWords(readers ...io.Reader) (tgspam.LoadResult, error)
+	LoadDomains(blocked, allowed io.Reader) (tgspam.LoadResult, error)
 	UpdateSpam(msg string) error
 	UpdateHam(msg string) error
 	RemoveHam(msg string) error
 	RemoveSpam(msg string) error
 	AddApprovedUser(user approved.UserInfo) error
+	SeedApprovedUser(user approved.UserInfo) error
 	RemoveApprovedUser(id string) error
 	ApprovedUsers() (res []approved.UserInfo)
 	IsApprovedUser(userID string) bool
@@ -62,7 +66,7 @@ type SamplesStore interface {
 	Stats(ctx context.Context) (*storage.SamplesStats, error)
 }
 
-// DictStore is a storage for dictionaries, i.e. stop words and ignored words
+// DictStore is a storage for dictionaries, i.e. stop words, ignored words and blocked/allowed domains
 type DictStore interface {
 	Reader(ctx context.Context, t storage.DictionaryType) (io.ReadCloser, error)
 }
@@ -109,6 +113,8 @@ func (s *SpamFilter) OnMessage(msg Message, checkOnly bool) (response Response)
 		FirstName: firstName, LastName: lastName, IsPremium: isPremium}
 	if msg.Image != nil {
 		spamReq.Meta.Images = 1
+		spamReq.ImageText = msg.Image.Text
+		spamReq.Meta.ImageHash = msg.Image.Hash
 	}
 	if msg.WithVideo || msg.WithVideoNote {
 		spamReq.Meta.HasVideo = true
@@ -134,7 +140,8 @@ func (s *SpamFilter) OnMessage(msg Message, checkOnly bool) (response Response)
 	spamReq.Meta.MessageID = msg.ID
 
 	// count mentions and links from entities (both regular and caption entities)
-	// links are counted from entities only - telegram provides url/text_link entities for all links
+	// links are counted from entities only - telegram provides url/text_link entities for all links.
+	// targets of text links are not in the message text, pass them for the domains check
 	if msg.Entities != nil {
 		for _, entity := range *msg.Entities {
 			switch entity.Type {
@@ -143,6 +150,9 @@ func (s *SpamFilter) OnMessage(msg Message, checkOnly bool) (response Response)
 			case "url", "text_link":
 				spamReq.Meta.Links++
 			}
+			if entity.Type == "text_link" && entity.URL != "" {
+				spamReq.Meta.URLs = append(spamReq.Meta.URLs, entity.URL)
+			}
 		}
 	}
 	if msg.Image != nil && msg.Image.Entities != nil {
@@ -153,9 +163,14 @@ func (s *SpamFilter) OnMessage(msg Message, checkOnly bool) (response Response)
 			case "url", "text_link":
 				spamReq.Meta.Links++
 			}
+			if entity.Type == "text_link" && entity.URL != "" {
+				spamReq.Meta.URLs = append(spamReq.Meta.URLs, entity.URL)
+			}
 		}
 	}
+	checkStart := time.Now()
 	isSpam, checkResults := s.Check(spamReq)
+	metrics.ObserveCheck(checkStart, isSpam, checkResults)
 	crs := make([]string, 0, len(checkResults))
 	for _, cr := range checkResults {
 		crs = append(crs, fmt.Sprintf("{name: %s, spam: %v, details: %s}", cr.Name, cr.Spam, cr.Details))
@@ -212,6 +227,9 @@ func (s *SpamFilter) OnReaction(userID int64, userName string) Response {
 		return Response{}
 	}
 	resp := s.RecordReaction(userID)
+	if resp.Details != "disabled" {
+		metrics.CountChecks([]spamcheck.Response{resp})
+	}
 	if resp.Spam {
 		log.Printf("[INFO] user %s (%d) detected as reaction spammer", userName, userID)
 		return Response{
@@ -232,6 +250,17 @@ func (s *SpamFilter) AddApprovedUser(id int64, name string) error {
 	return nil
 }
 
+// SeedApprovedUser credits user with count checked messages, the user is approved once the count reaches
+// the first messages count. Used for users verified by the join challenge.
+func (s *SpamFilter) SeedApprovedUser(id int64, name string, count int) error {
+	log.Printf("[INFO] seed approved user: id:%d, name:%q, count:%d", id, name, count)
+	user := approved.UserInfo{UserID: fmt.Sprintf("%d", id), UserName: name, Count: count}
+	if err := s.Detector.SeedApprovedUser(user); err != nil {
+		return fmt.Errorf("failed to seed approved user: %w", err)
+	}
+	return nil
+}
+
 // RemoveApprovedUser removes users from the list of approved users in both the detector and the storage
 func (s *SpamFilter) RemoveApprovedUser(id int64) error {
 	log.Printf("[INFO] remove aproved user: %d", id)
@@ -246,6 +275,7 @@ func (s *SpamFilter) ReloadSamples() (err error) {
 	log.Printf("[DEBUG] reloading samples")
 
 	var exclReader, spamReader, hamReader, stopWordsReader, spamDynamicReader, hamDynamicReader io.ReadCloser
+	var blockedReader, allowedReader io.ReadCloser
 	ctx := context.TODO()
 
 	// check mandatory data presence
@@ -289,6 +319,17 @@ func (s *SpamFilter) ReloadSamples() (err error) {
 	}
 	defer exclReader.Close()
 
+	// blocked and allowed domains are optional
+	if blockedReader, err = s.params.DictStore.Reader(ctx, storage.DictionaryTypeBlockedDomain); err != nil {
+		return fmt.Errorf("failed to get blocked domains: %w", err)
+	}
+	defer blockedReader.Close()
+
+	if allowedReader, err = s.params.DictStore.Reader(ctx, storage.DictionaryTypeAllowedDomain); err != nil {
+		return fmt.Errorf("failed to get allowed domains: %w", err)
+	}
+	defer allowedReader.Close()
+
 	// reload samples and stop-words. note: we don't need reset as LoadSamples and LoadStopWords clear the state first
 	lr, err := s.LoadSamples(exclReader, []io.Reader{spamReader, spamDynamicReader},
 		[]io.Reader{hamReader, hamDynamicReader})
@@ -301,8 +342,14 @@ func (s *SpamFilter) ReloadSamples() (err error) {
 		return fmt.Errorf("failed to reload stop words: %w", err)
 	}
 
-	log.Printf("[INFO] loaded samples - spam: %d, ham: %d, excluded tokens: %d, stop-words: %d",
-		lr.SpamSamples, lr.HamSamples, lr.ExcludedTokens, ls.StopWords)
+	ld, err := s.LoadDomains(blockedReader, allowedReader)
+	if err != nil {
+		return fmt.Errorf("failed to reload domains: %w", err)
+	}
+
+	log.Printf("[INFO] loaded samples - spam: %d, ham: %d, excluded tokens: %d, stop-words: %d, "+
+		"blocked domains: %d, allowed domains: %d",
+		lr.SpamSamples, lr.HamSamples, lr.ExcludedTokens, ls.StopWords, ld.BlockedDomains, ld.AllowedDomains)
 
 	return nil
 }
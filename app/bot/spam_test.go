@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -50,6 +51,47 @@ func TestSpamFilter_OnMessage(t *testing.T) {
 				Meta:     spamcheck.MetaData{Images: 1},
 			},
 		},
+		{
+			name: "image text passed to detector",
+			message: Message{
+				From:  User{ID: 1, Username: "user1"},
+				Image: &Image{FileID: "123", Text: "buy crypto"},
+			},
+			wantResponse: Response{
+				Text:          `detected: "user1" (1)`,
+				Send:          true,
+				BanInterval:   PermanentBanDuration,
+				DeleteReplyTo: true,
+				User:          User{ID: 1, Username: "user1"},
+				CheckResults:  []spamcheck.Response{{Name: "test", Spam: true, Details: "spam"}},
+			},
+			wantRequest: spamcheck.Request{
+				ImageText: "buy crypto",
+				UserID:    "1",
+				UserName:  "user1",
+				Meta:      spamcheck.MetaData{Images: 1},
+			},
+		},
+		{
+			name: "image hash passed to detector",
+			message: Message{
+				From:  User{ID: 1, Username: "user1"},
+				Image: &Image{FileID: "123", Hash: "ff00ff00ff00ff00"},
+			},
+			wantResponse: Response{
+				Text:          `detected: "user1" (1)`,
+				Send:          true,
+				BanInterval:   PermanentBanDuration,
+				DeleteReplyTo: true,
+				User:          User{ID: 1, Username: "user1"},
+				CheckResults:  []spamcheck.Response{{Name: "test", Spam: true, Details: "spam"}},
+			},
+			wantRequest: spamcheck.Request{
+				UserID:   "1",
+				UserName: "user1",
+				Meta:     spamcheck.MetaData{Images: 1, ImageHash: "ff00ff00ff00ff00"},
+			},
+		},
 		{
 			name: "spam with both video and forward",
 			message: Message{
@@ -339,7 +381,7 @@ func TestSpamFilter_OnMessage(t *testing.T) {
 				Msg:      "Click here for details",
 				UserID:   "1",
 				UserName: "user1",
-				Meta:     spamcheck.MetaData{Images: 1, Links: 1},
+				Meta:     spamcheck.MetaData{Images: 1, Links: 1, URLs: []string{"https://example.com"}},
 			},
 		},
 		{
@@ -364,7 +406,7 @@ func TestSpamFilter_OnMessage(t *testing.T) {
 				Msg:      "visit https://site.com or click here",
 				UserID:   "1",
 				UserName: "user1",
-				Meta:     spamcheck.MetaData{Links: 2}, // 2 from entities (url + text_link)
+				Meta:     spamcheck.MetaData{Links: 2, URLs: []string{"https://other.com"}}, // 2 from entities (url + text_link)
 			},
 		},
 		{
@@ -548,7 +590,7 @@ func TestSpamFilter_OnMessage(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			det := &mocks.DetectorMock{
 				CheckFunc: func(req spamcheck.Request) (bool, []spamcheck.Response) {
-					if tc.wantRequest != (spamcheck.Request{}) {
+					if !reflect.DeepEqual(tc.wantRequest, spamcheck.Request{}) {
 						// OnMessage stores any appended quote/reply text in Quote so
 						// AuthoredText() yields the user's own text; verify that, then
 						// normalize Quote before comparing the rest against wantRequest.
@@ -797,6 +839,7 @@ func TestSpamFilter_ReloadSamples(t *testing.T) {
 		readerErr    error
 		loadErr      error
 		stopWordsErr error
+		domainsErr   error
 		expectError  bool
 	}{
 		{
@@ -831,6 +874,12 @@ func TestSpamFilter_ReloadSamples(t *testing.T) {
 			stopWordsErr: errors.New("stop words error"),
 			expectError:  true,
 		},
+		{
+			name:        "domains error",
+			statsResult: &storage.SamplesStats{PresetSpam: 10, PresetHam: 5},
+			domainsErr:  errors.New("domains error"),
+			expectError: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -842,6 +891,9 @@ func TestSpamFilter_ReloadSamples(t *testing.T) {
 				LoadStopWordsFunc: func(readers ...io.Reader) (tgspam.LoadResult, error) {
 					return tgspam.LoadResult{StopWords: 3}, tc.stopWordsErr
 				},
+				LoadDomainsFunc: func(blocked, allowed io.Reader) (tgspam.LoadResult, error) {
+					return tgspam.LoadResult{BlockedDomains: 1}, tc.domainsErr
+				},
 			}
 
 			samplesStore := &mocks.SamplesStoreMock{
@@ -878,7 +930,11 @@ func TestSpamFilter_ReloadSamples(t *testing.T) {
 			// verify all required methods were called
 			assert.Len(t, det.LoadSamplesCalls(), 1)
 			assert.Len(t, det.LoadStopWordsCalls(), 1)
+			assert.Len(t, det.LoadDomainsCalls(), 1)
 			assert.Len(t, samplesStore.StatsCalls(), 1)
+			require.Len(t, dictStore.ReaderCalls(), 4)
+			assert.Equal(t, storage.DictionaryTypeBlockedDomain, dictStore.ReaderCalls()[2].T)
+			assert.Equal(t, storage.DictionaryTypeAllowedDomain, dictStore.ReaderCalls()[3].T)
 		})
 	}
 }
@@ -986,6 +1042,27 @@ func TestSpamFilter_IsApprovedUser(t *testing.T) {
 	}
 }
 
+func TestSpamFilter_SeedApprovedUser(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		det := &mocks.DetectorMock{
+			SeedApprovedUserFunc: func(user approved.UserInfo) error { return nil },
+		}
+		s := NewSpamFilter(det, SpamConfig{})
+		require.NoError(t, s.SeedApprovedUser(123, "user", 2))
+		require.Len(t, det.SeedApprovedUserCalls(), 1)
+		assert.Equal(t, approved.UserInfo{UserID: "123", UserName: "user", Count: 2}, det.SeedApprovedUserCalls()[0].User)
+	})
+
+	t.Run("detector error", func(t *testing.T) {
+		det := &mocks.DetectorMock{
+			SeedApprovedUserFunc: func(user approved.UserInfo) error { return errors.New("failed") },
+		}
+		s := NewSpamFilter(det, SpamConfig{})
+		err := s.SeedApprovedUser(123, "user", 2)
+		require.EqualError(t, err, "failed to seed approved user: failed")
+	})
+}
+
 func TestSpamFilter_DynamicSamples(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -19,9 +19,12 @@ const EncryptPrefix = "ENC:"
 // Sensitive field name constants
 const (
 	FieldTelegramToken  = "telegram.token"
+	FieldWebhookSecret  = "telegram.webhook_secret"
 	FieldOpenAIToken    = "openai.token"
 	FieldGeminiToken    = "gemini.token"
+	FieldHooksSecret    = "hooks.secret"
 	FieldServerAuthHash = "server.auth_hash"
+	FieldLLMTokens      = "llm.providers.token"
 )
 
 // MinKeyLength defines the minimum acceptable length for an encryption key
@@ -149,17 +152,27 @@ func IsEncrypted(value string) bool {
 }
 
 // sensitiveFieldAccessors maps sensitive field names to accessors that return
-// a pointer to the backing string on a Settings instance plus a human-readable
-// label used in error messages. Adding a new sensitive field is a single-place
-// change here; defaultSensitiveFields derives its list from these keys.
+// pointers to the backing strings on a Settings instance plus a human-readable
+// label used in error messages. Most fields have a single backing string, LLM
+// provider tokens have one per provider. Adding a new sensitive field is a
+// single-place change here; defaultSensitiveFields derives its list from these keys.
 var sensitiveFieldAccessors = map[string]struct {
 	label string
-	get   func(*Settings) *string
+	get   func(*Settings) []*string
 }{
-	FieldTelegramToken:  {"Telegram token", func(s *Settings) *string { return &s.Telegram.Token }},
-	FieldOpenAIToken:    {"OpenAI token", func(s *Settings) *string { return &s.OpenAI.Token }},
-	FieldGeminiToken:    {"Gemini token", func(s *Settings) *string { return &s.Gemini.Token }},
-	FieldServerAuthHash: {"Server auth hash", func(s *Settings) *string { return &s.Server.AuthHash }},
+	FieldTelegramToken:  {"Telegram token", func(s *Settings) []*string { return []*string{&s.Telegram.Token} }},
+	FieldWebhookSecret:  {"Telegram webhook secret", func(s *Settings) []*string { return []*string{&s.Telegram.WebhookSecret} }},
+	FieldOpenAIToken:    {"OpenAI token", func(s *Settings) []*string { return []*string{&s.OpenAI.Token} }},
+	FieldGeminiToken:    {"Gemini token", func(s *Settings) []*string { return []*string{&s.Gemini.Token} }},
+	FieldHooksSecret:    {"Hooks secret", func(s *Settings) []*string { return []*string{&s.Hooks.Secret} }},
+	FieldServerAuthHash: {"Server auth hash", func(s *Settings) []*string { return []*string{&s.Server.AuthHash} }},
+	FieldLLMTokens: {"LLM provider token", func(s *Settings) []*string {
+		res := make([]*string, 0, len(s.LLM.Providers))
+		for i := range s.LLM.Providers {
+			res = append(res, &s.LLM.Providers[i].Token)
+		}
+		return res
+	}},
 }
 
 // EncryptSensitiveFields encrypts sensitive fields in a Settings object
@@ -180,15 +193,16 @@ func (c *Crypter) EncryptSensitiveFields(settings *Settings, sensitiveFields ...
 		if !ok {
 			return fmt.Errorf("unknown sensitive field: %s", field)
 		}
-		target := accessor.get(settings)
-		if *target == "" || IsEncrypted(*target) {
-			continue
-		}
-		encrypted, err := c.Encrypt(*target)
-		if err != nil {
-			return fmt.Errorf("failed to encrypt %s: %w", accessor.label, err)
+		for _, target := range accessor.get(settings) {
+			if *target == "" || IsEncrypted(*target) {
+				continue
+			}
+			encrypted, err := c.Encrypt(*target)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", accessor.label, err)
+			}
+			*target = encrypted
 		}
-		*target = encrypted
 	}
 
 	return nil
@@ -213,15 +227,16 @@ func (c *Crypter) DecryptSensitiveFields(settings *Settings, sensitiveFields ...
 		if !ok {
 			return fmt.Errorf("unknown sensitive field: %s", field)
 		}
-		target := accessor.get(settings)
-		if !IsEncrypted(*target) {
-			continue
-		}
-		decrypted, err := c.Decrypt(*target)
-		if err != nil {
-			return fmt.Errorf("failed to decrypt %s: %w", accessor.label, err)
+		for _, target := range accessor.get(settings) {
+			if !IsEncrypted(*target) {
+				continue
+			}
+			decrypted, err := c.Decrypt(*target)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", accessor.label, err)
+			}
+			*target = decrypted
 		}
-		*target = decrypted
 	}
 
 	return nil
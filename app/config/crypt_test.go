@@ -102,6 +102,10 @@ func TestCrypter_EncryptDecryptSensitiveFields(t *testing.T) {
 		Server: ServerSettings{
 			AuthHash: "server-auth-hash-secret",
 		},
+		Hooks: HooksSettings{
+			URLs:   []string{"https://example.com/hook"},
+			Secret: "hooks-secret",
+		},
 	}
 
 	// encrypt sensitive fields
@@ -113,6 +117,7 @@ func TestCrypter_EncryptDecryptSensitiveFields(t *testing.T) {
 	assert.True(t, IsEncrypted(settings.OpenAI.Token))
 	assert.True(t, IsEncrypted(settings.Gemini.Token))
 	assert.True(t, IsEncrypted(settings.Server.AuthHash))
+	assert.True(t, IsEncrypted(settings.Hooks.Secret))
 
 	// verify non-sensitive fields are not encrypted
 	assert.Equal(t, "public-group-name", settings.Telegram.Group)
@@ -134,6 +139,8 @@ func TestCrypter_EncryptDecryptSensitiveFields(t *testing.T) {
 	assert.Equal(t, "openai-token-secret", settings.OpenAI.Token)
 	assert.Equal(t, "gemini-token-secret", settings.Gemini.Token)
 	assert.Equal(t, "server-auth-hash-secret", settings.Server.AuthHash)
+	assert.Equal(t, "hooks-secret", settings.Hooks.Secret)
+	assert.Equal(t, []string{"https://example.com/hook"}, settings.Hooks.URLs)
 
 	// verify non-sensitive fields are unchanged
 	assert.Equal(t, "public-group-name", settings.Telegram.Group)
@@ -161,6 +168,24 @@ func TestCrypter_GeminiTokenRoundTrip(t *testing.T) {
 	assert.Equal(t, "gemini-secret-token", settings.Gemini.Token)
 }
 
+func TestCrypter_LLMProviderTokensRoundTrip(t *testing.T) {
+	crypter, err := NewCrypter("test-master-key-20-chars", "test-instance")
+	require.NoError(t, err)
+
+	settings := &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{
+		{Name: "claude", Type: "anthropic", Token: "anthropic-secret"},
+		{Name: "local", Type: "ollama"},
+	}}}
+
+	require.NoError(t, crypter.EncryptSensitiveFields(settings))
+	assert.True(t, IsEncrypted(settings.LLM.Providers[0].Token))
+	assert.Empty(t, settings.LLM.Providers[1].Token, "empty token is not encrypted")
+
+	require.NoError(t, crypter.DecryptSensitiveFields(settings))
+	assert.Equal(t, "anthropic-secret", settings.LLM.Providers[0].Token)
+	assert.Empty(t, settings.LLM.Providers[1].Token)
+}
+
 func TestCrypter_EncryptWithInvalidKey(t *testing.T) {
 	// test with empty key
 	_, err := NewCrypter("", "test-instance")
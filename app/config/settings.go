@@ -1,12 +1,21 @@
 // Package config provides the application settings domain model, a database-backed
-// store, and a field-level crypter for sensitive values. It is the single source
-// of truth for configuration consumed by the app regardless of whether values
-// originate from CLI flags, environment variables, or the database.
+// store, a yaml config file loader, and a field-level crypter for sensitive values.
+// It is the single source of truth for configuration consumed by the app regardless
+// of whether values originate from CLI flags, environment variables, a config file,
+// or the database.
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/umputun/tg-spam/app/storage"
@@ -25,6 +34,7 @@ type Settings struct {
 	Logger        LoggerSettings        `json:"logger" yaml:"logger" db:"logger"`
 	CAS           CASSettings           `json:"cas" yaml:"cas" db:"cas"`
 	Meta          MetaSettings          `json:"meta" yaml:"meta" db:"meta"`
+	Domains       DomainsSettings       `json:"domains" yaml:"domains" db:"domains"`
 	OpenAI        OpenAISettings        `json:"openai" yaml:"openai" db:"openai"`
 	Gemini        GeminiSettings        `json:"gemini" yaml:"gemini" db:"gemini"`
 	LLM           LLMSettings           `json:"llm" yaml:"llm" db:"llm"`
@@ -38,6 +48,18 @@ type Settings struct {
 	Reactions     ReactionsSettings     `json:"reactions" yaml:"reactions" db:"reactions"`
 	Report        ReportSettings        `json:"report" yaml:"report" db:"report"`
 	Warn          WarnSettings          `json:"warn" yaml:"warn" db:"warn"`
+	Punish        PunishSettings        `json:"punish" yaml:"punish" db:"punish"`
+	Captcha       CaptchaSettings       `json:"captcha" yaml:"captcha" db:"captcha"`
+	OCR           OCRSettings           `json:"ocr" yaml:"ocr" db:"ocr"`
+	ImageHash     ImageHashSettings     `json:"image_hash" yaml:"image_hash" db:"image_hash"`
+	Scoring       ScoringSettings       `json:"scoring" yaml:"scoring" db:"scoring"`
+	Review        ReviewSettings        `json:"review" yaml:"review" db:"review"`
+	Reputation    ReputationSettings    `json:"reputation" yaml:"reputation" db:"reputation"`
+	Federation    FederationSettings    `json:"federation" yaml:"federation" db:"federation"`
+	Hooks         HooksSettings         `json:"hooks" yaml:"hooks" db:"hooks"`
+
+	// additional groups protected by the same instance, see GroupSettings
+	Groups []GroupSettings `json:"groups,omitempty" yaml:"groups,omitempty" db:"groups"`
 
 	// spam detection settings
 	SimilarityThreshold float64 `json:"similarity_threshold" yaml:"similarity_threshold" db:"similarity_threshold"`
@@ -46,6 +68,8 @@ type Settings struct {
 	MaxEmoji            int     `json:"max_emoji" yaml:"max_emoji" db:"max_emoji"`
 	MinSpamProbability  float64 `json:"min_spam_probability" yaml:"min_spam_probability" db:"min_spam_probability"`
 	MultiLangWords      int     `json:"multi_lang_words" yaml:"multi_lang_words" db:"multi_lang_words"`
+	ObfuscatedWords     int     `json:"obfuscated_words" yaml:"obfuscated_words" db:"obfuscated_words"`
+	NormalizeText       bool    `json:"normalize_text" yaml:"normalize_text" db:"normalize_text"`
 	ProhibitedLangs     string  `json:"prohibited_langs" yaml:"prohibited_langs" db:"prohibited_langs"`
 	ProhibitedLangsMin  int     `json:"prohibited_langs_min" yaml:"prohibited_langs_min" db:"prohibited_langs_min"`
 
@@ -74,10 +98,12 @@ type Settings struct {
 
 // TelegramSettings contains Telegram-specific settings
 type TelegramSettings struct {
-	Group        string        `json:"group" yaml:"group" db:"telegram_group"`
-	IdleDuration time.Duration `json:"idle_duration" yaml:"idle_duration" db:"telegram_idle_duration"`
-	Timeout      time.Duration `json:"timeout" yaml:"timeout" db:"telegram_timeout"`
-	Token        string        `json:"token" yaml:"token" db:"telegram_token"`
+	Group         string        `json:"group" yaml:"group" db:"telegram_group"`
+	IdleDuration  time.Duration `json:"idle_duration" yaml:"idle_duration" db:"telegram_idle_duration"`
+	Timeout       time.Duration `json:"timeout" yaml:"timeout" db:"telegram_timeout"`
+	Token         string        `json:"token" yaml:"token" db:"telegram_token"`
+	WebhookURL    string        `json:"webhook_url" yaml:"webhook_url" db:"telegram_webhook_url"`
+	WebhookSecret string        `json:"webhook_secret" yaml:"webhook_secret" db:"telegram_webhook_secret"`
 }
 
 // AdminSettings contains admin-related settings
@@ -105,9 +131,10 @@ type LoggerSettings struct {
 
 // CASSettings contains Combot Anti-Spam System settings
 type CASSettings struct {
-	API       string        `json:"api" yaml:"api" db:"cas_api"`
-	Timeout   time.Duration `json:"timeout" yaml:"timeout" db:"cas_timeout"`
-	UserAgent string        `json:"user_agent" yaml:"user_agent" db:"cas_user_agent"`
+	API          string        `json:"api" yaml:"api" db:"cas_api"`
+	Timeout      time.Duration `json:"timeout" yaml:"timeout" db:"cas_timeout"`
+	UserAgent    string        `json:"user_agent" yaml:"user_agent" db:"cas_user_agent"`
+	SyncInterval time.Duration `json:"sync_interval" yaml:"sync_interval" db:"cas_sync_interval"`
 }
 
 // MetaSettings contains message metadata check settings
@@ -128,6 +155,13 @@ type MetaSettings struct {
 	ExternalReply   bool   `json:"external_reply" yaml:"external_reply" db:"meta_external_reply"`
 }
 
+// DomainsSettings contains settings of the linked domains check. Blocked and allowed domains are kept
+// in the dictionary, the check is active if any of them is set.
+type DomainsSettings struct {
+	ExpandShort bool     `json:"expand_short" yaml:"expand_short" db:"domains_expand_short"`
+	Shorteners  []string `json:"shorteners,omitempty" yaml:"shorteners,omitempty" db:"domains_shorteners"` // empty uses default list
+}
+
 // OpenAISettings contains OpenAI integration settings
 type OpenAISettings struct {
 	APIBase            string   `json:"api_base" yaml:"api_base" db:"openai_api_base"`
@@ -163,8 +197,35 @@ type GeminiSettings struct {
 type LLMSettings struct {
 	Consensus      string        `json:"consensus" yaml:"consensus" db:"llm_consensus"`
 	RequestTimeout time.Duration `json:"request_timeout" yaml:"request_timeout" db:"llm_request_timeout"`
+
+	// verdict cache, reuses LLM results for identical messages, disabled if CacheTTL is 0
+	CacheTTL     time.Duration `json:"cache_ttl" yaml:"cache_ttl" db:"llm_cache_ttl"`
+	CacheSize    int           `json:"cache_size" yaml:"cache_size" db:"llm_cache_size"`
+	CachePersist bool          `json:"cache_persist" yaml:"cache_persist" db:"llm_cache_persist"`
+
+	// additional named LLM checkers, run after openai and gemini in this order
+	Providers []LLMProviderSettings `json:"providers,omitempty" yaml:"providers,omitempty" db:"llm_providers"`
+}
+
+// LLMProviderSettings describes an additional named LLM checker. Each provider has its own
+// veto mode, history size and short-message flag and takes part in the LLM consensus.
+type LLMProviderSettings struct {
+	Name               string `json:"name" yaml:"name"`
+	Type               string `json:"type" yaml:"type"` // openai (any compatible api), gemini, anthropic or ollama
+	APIBase            string `json:"api_base,omitempty" yaml:"api_base,omitempty"`
+	Token              string `json:"token,omitempty" yaml:"token,omitempty"`
+	Model              string `json:"model,omitempty" yaml:"model,omitempty"`
+	Prompt             string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	Veto               bool   `json:"veto" yaml:"veto"`
+	HistorySize        int    `json:"history_size" yaml:"history_size"`
+	CheckShortMessages bool   `json:"check_short_messages" yaml:"check_short_messages"`
+	RetryCount         int    `json:"retry_count" yaml:"retry_count"`
+	MaxTokensResponse  int    `json:"max_tokens_response" yaml:"max_tokens_response"`
 }
 
+// llmProviderTypes lists supported LLM provider types
+var llmProviderTypes = []string{"openai", "gemini", "anthropic", "ollama"}
+
 // DeleteSettings contains settings for automatic deletion of service messages
 type DeleteSettings struct {
 	JoinMessages  bool `json:"join_messages" yaml:"join_messages" db:"delete_join_messages"`
@@ -198,6 +259,351 @@ type WarnSettings struct {
 	Window    time.Duration `json:"window" yaml:"window" db:"warn_window"`
 }
 
+// PunishSettings contains the escalating punishment ladder settings. Spam detected within the window is punished
+// by restriction for Mute on the first offense, for Restrict on the second and by permanent ban on the following ones.
+type PunishSettings struct {
+	Enabled   bool          `json:"enabled" yaml:"enabled" db:"punish_enabled"`
+	Mute      time.Duration `json:"mute" yaml:"mute" db:"punish_mute"`             // 0 skips the step
+	Restrict  time.Duration `json:"restrict" yaml:"restrict" db:"punish_restrict"` // 0 skips the step
+	Window    time.Duration `json:"window" yaml:"window" db:"punish_window"`
+	Permanent []string      `json:"permanent,omitempty" yaml:"permanent,omitempty" db:"punish_permanent"` // check names
+}
+
+// minRestrictDuration and maxRestrictDuration are the limits of punishment ladder restrictions,
+// telegram treats restrictions shorter than 30 seconds or longer than 366 days as permanent
+const (
+	minRestrictDuration = 30 * time.Second
+	maxRestrictDuration = 366 * 24 * time.Hour
+)
+
+// CaptchaSettings contains join challenge (captcha) settings for new chat members
+type CaptchaSettings struct {
+	Enabled      bool          `json:"enabled" yaml:"enabled" db:"captcha_enabled"`
+	Type         string        `json:"type" yaml:"type" db:"captcha_type"`
+	Timeout      time.Duration `json:"timeout" yaml:"timeout" db:"captcha_timeout"`
+	Action       string        `json:"action" yaml:"action" db:"captcha_action"`
+	ApproveCount int           `json:"approve_count" yaml:"approve_count" db:"captcha_approve_count"`
+}
+
+// OCRSettings contains image text extraction (OCR) settings. The extracted text goes through
+// the same content checks as the message text.
+type OCRSettings struct {
+	Type      string        `json:"type" yaml:"type" db:"ocr_type"` // none, tesseract or http
+	Tesseract string        `json:"tesseract" yaml:"tesseract" db:"ocr_tesseract"`
+	Lang      string        `json:"lang" yaml:"lang" db:"ocr_lang"`
+	URL       string        `json:"url" yaml:"url" db:"ocr_url"`
+	Timeout   time.Duration `json:"timeout" yaml:"timeout" db:"ocr_timeout"`
+}
+
+// ImageHashSettings contains settings of the check comparing message images with perceptual hashes
+// of images reported as spam
+type ImageHashSettings struct {
+	Enabled  bool `json:"enabled" yaml:"enabled" db:"image_hash_enabled"`
+	Distance int  `json:"distance" yaml:"distance" db:"image_hash_distance"` // max hamming distance, 0..64
+}
+
+// ScoringSettings contains weighted scoring settings. In scoring mode checks contribute their weights
+// to the total score instead of any single check marking the message as spam.
+type ScoringSettings struct {
+	Enabled             bool               `json:"enabled" yaml:"enabled" db:"scoring_enabled"`
+	Weights             map[string]float64 `json:"weights,omitempty" yaml:"weights,omitempty" db:"scoring_weights"` // by check name
+	DefaultWeight       float64            `json:"default_weight" yaml:"default_weight" db:"scoring_default_weight"`
+	Threshold           float64            `json:"threshold" yaml:"threshold" db:"scoring_threshold"`
+	SuspiciousThreshold float64            `json:"suspicious_threshold" yaml:"suspicious_threshold" db:"scoring_suspicious_threshold"`
+}
+
+// ReviewSettings contains admin review queue settings. Suspicious messages are quarantined (deleted)
+// and sent to admin chat to approve or ban, instead of being reported only.
+type ReviewSettings struct {
+	Enabled         bool    `json:"enabled" yaml:"enabled" db:"review_enabled"`
+	MinProbability  float64 `json:"min_probability" yaml:"min_probability" db:"review_min_probability"` // classifier band start, 0 disables
+	LLMDisagreement bool    `json:"llm_disagreement" yaml:"llm_disagreement" db:"review_llm_disagreement"`
+}
+
+// ReputationSettings contains per-user reputation settings. Reputation counts checked messages, spam hits,
+// warnings, reports and flagged reactions of each user, it weights user reports and holds back approval.
+type ReputationSettings struct {
+	Enabled      bool          `json:"enabled" yaml:"enabled" db:"reputation_enabled"`
+	ApprovalHold time.Duration `json:"approval_hold" yaml:"approval_hold" db:"reputation_approval_hold"` // 0 disables
+}
+
+// FederationSettings contains federated ban-list sharing settings. Federated instances publish signed feeds
+// of recently banned users and pull feeds of peers, a match with a peer's ban is a spam check result.
+type FederationSettings struct {
+	Enabled      bool                     `json:"enabled" yaml:"enabled" db:"federation_enabled"`
+	KeyFile      string                   `json:"key_file" yaml:"key_file" db:"federation_key_file"` // empty - in dynamic data dir
+	FeedWindow   time.Duration            `json:"feed_window" yaml:"feed_window" db:"federation_feed_window"`
+	PullInterval time.Duration            `json:"pull_interval" yaml:"pull_interval" db:"federation_pull_interval"`
+	Peers        []FederationPeerSettings `json:"peers,omitempty" yaml:"peers,omitempty" db:"federation_peers"`
+}
+
+// FederationPeerSettings describes a federation peer, the feed url, the public key to verify the feed
+// and the trust level: "ban" treats a match as spam, "review" marks the message as suspicious.
+type FederationPeerSettings struct {
+	Name      string `json:"name" yaml:"name"`
+	URL       string `json:"url" yaml:"url"`
+	PublicKey string `json:"public_key" yaml:"public_key"` // base64 encoded ed25519 public key
+	Trust     string `json:"trust" yaml:"trust"`
+}
+
+// HooksSettings contains outgoing event webhooks settings. Moderation events are posted to all urls,
+// signed with the secret, failed deliveries are retried with backoff up to MaxAttempts times.
+type HooksSettings struct {
+	URLs        []string      `json:"urls,omitempty" yaml:"urls,omitempty" db:"hooks_urls"` // empty disables webhooks
+	Secret      string        `json:"secret" yaml:"secret" db:"hooks_secret"`
+	MaxAttempts int           `json:"max_attempts" yaml:"max_attempts" db:"hooks_max_attempts"`
+	RetryDelay  time.Duration `json:"retry_delay" yaml:"retry_delay" db:"hooks_retry_delay"` // doubled on each retry
+}
+
+// federationTrustLevels lists supported trust levels of federation peers
+var federationTrustLevels = []string{string(tgspam.FederationTrustBan), string(tgspam.FederationTrustReview)}
+
+// ParseFederationPeerSpec parses a federation peer definition in the name;url=...;key=...[;trust=ban|review] format.
+// Trust defaults to review.
+func ParseFederationPeerSpec(spec string) (FederationPeerSettings, error) {
+	parts := strings.Split(spec, ";")
+	res := FederationPeerSettings{Name: strings.TrimSpace(parts[0]), Trust: string(tgspam.FederationTrustReview)}
+	if res.Name == "" {
+		return FederationPeerSettings{}, fmt.Errorf("empty federation peer name in %q", spec)
+	}
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return FederationPeerSettings{}, fmt.Errorf("invalid option %q for federation peer %q, expected key=value", part, res.Name)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		switch key {
+		case "url":
+			res.URL = val
+		case "key":
+			res.PublicKey = val
+		case "trust":
+			res.Trust = val
+		default:
+			return FederationPeerSettings{}, fmt.Errorf("unknown option %q for federation peer %q", key, res.Name)
+		}
+	}
+	return res, nil
+}
+
+// ParseFederationPeerSpecs parses a list of federation peer definitions, see ParseFederationPeerSpec for the format
+func ParseFederationPeerSpecs(specs []string) ([]FederationPeerSettings, error) {
+	var res []FederationPeerSettings
+	for _, spec := range specs {
+		p, err := ParseFederationPeerSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// GroupSettings describes an additional group protected by the same instance. The group shares samples,
+// approved users and storage with the primary group, but has its own admin chat and superusers.
+// Superusers from Admin.SuperUsers apply to every group.
+type GroupSettings struct {
+	Group      string         `json:"group" yaml:"group"`
+	AdminGroup string         `json:"admin_group,omitempty" yaml:"admin_group,omitempty"`
+	SuperUsers []string       `json:"super_users,omitempty" yaml:"super_users,omitempty"`
+	Overrides  GroupOverrides `json:"overrides" yaml:"overrides"`
+}
+
+// GroupOverrides holds optional per-group detector settings. Nil fields inherit the instance-wide value.
+type GroupOverrides struct {
+	SimilarityThreshold *float64 `json:"similarity_threshold,omitempty" yaml:"similarity_threshold,omitempty"`
+	MinMsgLen           *int     `json:"min_msg_len,omitempty" yaml:"min_msg_len,omitempty"`
+	MaxEmoji            *int     `json:"max_emoji,omitempty" yaml:"max_emoji,omitempty"`
+	MinSpamProbability  *float64 `json:"min_spam_probability,omitempty" yaml:"min_spam_probability,omitempty"`
+	MultiLangWords      *int     `json:"multi_lang_words,omitempty" yaml:"multi_lang_words,omitempty"`
+	FirstMessagesCount  *int     `json:"first_messages_count,omitempty" yaml:"first_messages_count,omitempty"`
+	ParanoidMode        *bool    `json:"paranoid_mode,omitempty" yaml:"paranoid_mode,omitempty"`
+}
+
+// IsEmpty returns true if no detector setting is overridden
+func (o GroupOverrides) IsEmpty() bool {
+	return o == GroupOverrides{}
+}
+
+// ParseGroupSpec parses a compact CLI definition of an additional group:
+// "group[;admin=admin-group][;super=user1,user2][;option=value...]". Supported options are
+// similarity-threshold, min-msg-len, max-emoji, min-probability, multi-lang, first-messages-count
+// and paranoid, named after the corresponding instance-wide flags.
+func ParseGroupSpec(spec string) (GroupSettings, error) {
+	parts := strings.Split(spec, ";")
+	res := GroupSettings{Group: strings.TrimPrefix(strings.TrimSpace(parts[0]), "@")}
+	if res.Group == "" {
+		return GroupSettings{}, fmt.Errorf("empty group in %q", spec)
+	}
+
+	parseInt := func(val string) (*int, error) {
+		v, err := strconv.Atoi(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %q: %w", val, err)
+		}
+		return &v, nil
+	}
+	parseFloat := func(val string) (*float64, error) {
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q: %w", val, err)
+		}
+		return &v, nil
+	}
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return GroupSettings{}, fmt.Errorf("invalid option %q for group %q, expected key=value", part, res.Group)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		var err error
+		switch key {
+		case "admin":
+			res.AdminGroup = strings.TrimPrefix(val, "@")
+		case "super":
+			for s := range strings.SplitSeq(val, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					res.SuperUsers = append(res.SuperUsers, s)
+				}
+			}
+		case "similarity-threshold":
+			res.Overrides.SimilarityThreshold, err = parseFloat(val)
+		case "min-msg-len":
+			res.Overrides.MinMsgLen, err = parseInt(val)
+		case "max-emoji":
+			res.Overrides.MaxEmoji, err = parseInt(val)
+		case "min-probability":
+			res.Overrides.MinSpamProbability, err = parseFloat(val)
+		case "multi-lang":
+			res.Overrides.MultiLangWords, err = parseInt(val)
+		case "first-messages-count":
+			res.Overrides.FirstMessagesCount, err = parseInt(val)
+		case "paranoid":
+			v, perr := strconv.ParseBool(val)
+			if perr != nil {
+				err = fmt.Errorf("invalid bool %q: %w", val, perr)
+				break
+			}
+			res.Overrides.ParanoidMode = &v
+		default:
+			return GroupSettings{}, fmt.Errorf("unknown option %q for group %q", key, res.Group)
+		}
+		if err != nil {
+			return GroupSettings{}, fmt.Errorf("option %q for group %q: %w", key, res.Group, err)
+		}
+	}
+	return res, nil
+}
+
+// ParseGroupSpecs parses a list of group definitions, see ParseGroupSpec for the format
+func ParseGroupSpecs(specs []string) ([]GroupSettings, error) {
+	var res []GroupSettings
+	for _, spec := range specs {
+		g, err := ParseGroupSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, g)
+	}
+	return res, nil
+}
+
+// ParseLLMProviderSpec parses a compact CLI definition of an LLM provider:
+// "name;type=openai|gemini|anthropic|ollama[;option=value...]". Supported options are url, token, model,
+// prompt, veto, history, short, retry and max-tokens. The prompt can't contain ";".
+func ParseLLMProviderSpec(spec string) (LLMProviderSettings, error) {
+	parts := strings.Split(spec, ";")
+	res := LLMProviderSettings{Name: strings.TrimSpace(parts[0])}
+	if res.Name == "" {
+		return LLMProviderSettings{}, fmt.Errorf("empty llm provider name in %q", spec)
+	}
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return LLMProviderSettings{}, fmt.Errorf("invalid option %q for llm provider %q, expected key=value", part, res.Name)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		var err error
+		switch key {
+		case "type":
+			res.Type = val
+		case "url":
+			res.APIBase = val
+		case "token":
+			res.Token = val
+		case "model":
+			res.Model = val
+		case "prompt":
+			res.Prompt = val
+		case "veto":
+			res.Veto, err = strconv.ParseBool(val)
+		case "short":
+			res.CheckShortMessages, err = strconv.ParseBool(val)
+		case "history":
+			res.HistorySize, err = strconv.Atoi(val)
+		case "retry":
+			res.RetryCount, err = strconv.Atoi(val)
+		case "max-tokens":
+			res.MaxTokensResponse, err = strconv.Atoi(val)
+		default:
+			return LLMProviderSettings{}, fmt.Errorf("unknown option %q for llm provider %q", key, res.Name)
+		}
+		if err != nil {
+			return LLMProviderSettings{}, fmt.Errorf("option %q for llm provider %q: invalid value %q", key, res.Name, val)
+		}
+	}
+	if res.Type == "" {
+		return LLMProviderSettings{}, fmt.Errorf("type is not set for llm provider %q", res.Name)
+	}
+	return res, nil
+}
+
+// ParseLLMProviderSpecs parses a list of LLM provider definitions, see ParseLLMProviderSpec for the format
+func ParseLLMProviderSpecs(specs []string) ([]LLMProviderSettings, error) {
+	var res []LLMProviderSettings
+	for _, spec := range specs {
+		p, err := ParseLLMProviderSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// ParseScoringWeights parses check weights in the name:weight format, e.g. "emoji:0.5"
+func ParseScoringWeights(specs []string) (map[string]float64, error) {
+	res := make(map[string]float64, len(specs))
+	for _, spec := range specs {
+		name, val, ok := strings.Cut(spec, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid check weight %q, expected name:weight", spec)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight of check %q: %w", name, err)
+		}
+		res[name] = w
+	}
+	return res, nil
+}
+
 // LuaPluginsSettings contains Lua plugins settings
 type LuaPluginsSettings struct {
 	Enabled        bool     `json:"enabled" yaml:"enabled" db:"lua_plugins_enabled"`
@@ -252,6 +658,10 @@ type TransientSettings struct {
 	// encryption for database stored configuration
 	ConfigDBEncryptKey string `json:"-" yaml:"-"`
 
+	// yaml configuration file, watched for changes if ConfigWatch is set
+	ConfigFile  string `json:"-" yaml:"-"`
+	ConfigWatch bool   `json:"-" yaml:"-"`
+
 	// temporary auth password (used only to generate hash)
 	WebAuthPasswd string `json:"-" yaml:"-"`
 
@@ -291,6 +701,52 @@ func (s *Settings) Validate() error {
 		return fmt.Errorf("warn.window (%v) exceeds storage retention (%v); older rows are pruned and would not be counted",
 			s.Warn.Window, storage.WarningsRetention)
 	}
+	if s.Punish.Enabled {
+		if s.Punish.Mute < 0 || s.Punish.Restrict < 0 {
+			return fmt.Errorf("punish.mute (%v) and punish.restrict (%v) must be >= 0 (0 skips the step)",
+				s.Punish.Mute, s.Punish.Restrict)
+		}
+		steps := []struct {
+			name string
+			val  time.Duration
+		}{{"punish.mute", s.Punish.Mute}, {"punish.restrict", s.Punish.Restrict}}
+		for _, st := range steps {
+			if st.val != 0 && (st.val < minRestrictDuration || st.val > maxRestrictDuration) {
+				return fmt.Errorf("%s (%v) must be between %v and %v or 0, telegram makes shorter or longer restrictions permanent",
+					st.name, st.val, minRestrictDuration, maxRestrictDuration)
+			}
+		}
+		if s.Punish.Window <= 0 || s.Punish.Window > storage.OffensesRetention {
+			return fmt.Errorf("punish.window (%v) must be positive and not exceed storage retention (%v)",
+				s.Punish.Window, storage.OffensesRetention)
+		}
+	}
+	if s.Captcha.Enabled {
+		if !slices.Contains([]string{"button", "math", "emoji"}, s.Captcha.Type) {
+			return fmt.Errorf("captcha.type %q is not one of button, math or emoji", s.Captcha.Type)
+		}
+		if s.Captcha.Timeout <= 0 {
+			return fmt.Errorf("captcha.timeout (%v) must be positive", s.Captcha.Timeout)
+		}
+		if s.Captcha.Action != "ban" && s.Captcha.Action != "kick" {
+			return fmt.Errorf("captcha.action %q is not one of ban or kick", s.Captcha.Action)
+		}
+	}
+	if s.Captcha.ApproveCount < 0 {
+		return fmt.Errorf("captcha.approve-count (%d) must be >= 0 (0 disables)", s.Captcha.ApproveCount)
+	}
+	switch s.OCR.Type {
+	case "", "none", "tesseract":
+	case "http":
+		if s.OCR.URL == "" {
+			return fmt.Errorf("ocr.url is required for http OCR")
+		}
+	default:
+		return fmt.Errorf("ocr.type %q is not one of none, tesseract or http", s.OCR.Type)
+	}
+	if s.ImageHash.Enabled && (s.ImageHash.Distance < 0 || s.ImageHash.Distance > 64) {
+		return fmt.Errorf("image-hash.distance (%d) must be between 0 and 64", s.ImageHash.Distance)
+	}
 	if s.MaxShortMsgCount < 0 {
 		return fmt.Errorf("max-short-msg-count (%d) must be >= 0 (0 disables)", s.MaxShortMsgCount)
 	}
@@ -303,6 +759,43 @@ func (s *Settings) Validate() error {
 	if s.MaxShortMsgCount > 0 && s.ParanoidMode {
 		return fmt.Errorf("max-short-msg-count is incompatible with paranoid mode")
 	}
+	if err := s.validateGroups(); err != nil {
+		return err
+	}
+	if err := s.validateWebhook(); err != nil {
+		return err
+	}
+	if err := s.validateLLMProviders(); err != nil {
+		return err
+	}
+	if err := s.validateFederation(); err != nil {
+		return err
+	}
+	if err := s.validateHooks(); err != nil {
+		return err
+	}
+	if err := s.validateScoring(); err != nil {
+		return err
+	}
+	if s.Review.MinProbability < 0 || s.Review.MinProbability > 100 {
+		return fmt.Errorf("review.min-probability (%v) must be in range 0-100", s.Review.MinProbability)
+	}
+	if s.Review.MinProbability > 0 && s.MinSpamProbability > 0 && s.Review.MinProbability >= s.MinSpamProbability {
+		return fmt.Errorf("review.min-probability (%v) must be below min-probability (%v)",
+			s.Review.MinProbability, s.MinSpamProbability)
+	}
+	if s.CAS.SyncInterval < 0 {
+		return fmt.Errorf("cas.sync-interval (%v) must be >= 0 (0 disables)", s.CAS.SyncInterval)
+	}
+	if s.Reputation.ApprovalHold < 0 {
+		return fmt.Errorf("reputation.approval-hold (%v) must be >= 0 (0 disables)", s.Reputation.ApprovalHold)
+	}
+	if s.LLM.CacheTTL < 0 {
+		return fmt.Errorf("llm.cache-ttl (%v) must be >= 0 (0 disables)", s.LLM.CacheTTL)
+	}
+	if s.LLM.CacheTTL > 0 && s.LLM.CacheSize <= 0 {
+		return fmt.Errorf("llm.cache-size (%d) must be positive if llm.cache-ttl is set", s.LLM.CacheSize)
+	}
 	// ValidateProhibitedLangs already returns a fully-formed, user-facing message
 	// shared across all call sites; return it verbatim.
 	if err := tgspam.ValidateProhibitedLangs(s.ProhibitedLangs, s.ProhibitedLangsMin); err != nil {
@@ -311,6 +804,194 @@ func (s *Settings) Validate() error {
 	return nil
 }
 
+// webhookSecretRe matches secret tokens accepted by telegram setWebhook
+var webhookSecretRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// validateWebhook checks the webhook mode settings: the url must be https as telegram posts updates only over TLS,
+// and updates are received by the web server, so it must be enabled
+func (s *Settings) validateWebhook() error {
+	if s.Telegram.WebhookSecret != "" && !webhookSecretRe.MatchString(s.Telegram.WebhookSecret) {
+		return fmt.Errorf("telegram.webhook-secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+	if s.Telegram.WebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(s.Telegram.WebhookURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("telegram.webhook-url %q must be a valid https url", s.Telegram.WebhookURL)
+	}
+	if !s.Server.Enabled {
+		return fmt.Errorf("telegram.webhook-url requires the web server, set server.enabled")
+	}
+	return nil
+}
+
+// validateGroups checks additional groups: each must be set, differ from the primary and other groups,
+// and must not share an admin chat with another group, as admin callbacks are routed by the admin chat
+func (s *Settings) validateGroups() error {
+	seen := map[string]bool{strings.TrimPrefix(s.Telegram.Group, "@"): true}
+	admins := map[string]bool{}
+	if s.Admin.AdminGroup != "" {
+		admins[strings.TrimPrefix(s.Admin.AdminGroup, "@")] = true
+	}
+	for i, g := range s.Groups {
+		if g.Group == "" {
+			return fmt.Errorf("groups[%d]: group is not set", i)
+		}
+		if seen[g.Group] {
+			return fmt.Errorf("groups[%d]: group %q is configured more than once", i, g.Group)
+		}
+		seen[g.Group] = true
+		if g.AdminGroup == "" {
+			continue
+		}
+		if admins[g.AdminGroup] {
+			return fmt.Errorf("groups[%d]: admin group %q is already used by another group", i, g.AdminGroup)
+		}
+		admins[g.AdminGroup] = true
+	}
+	return nil
+}
+
+// validateLLMProviders checks additional LLM providers: names must be unique and differ from the
+// builtin openai and gemini checkers, hosted apis need a token
+func (s *Settings) validateLLMProviders() error {
+	seen := map[string]bool{"openai": true, "gemini": true}
+	for i, p := range s.LLM.Providers {
+		if p.Name == "" {
+			return fmt.Errorf("llm.providers[%d]: name is not set", i)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("llm.providers[%d]: name %q is reserved or used more than once", i, p.Name)
+		}
+		seen[p.Name] = true
+		if !slices.Contains(llmProviderTypes, p.Type) {
+			return fmt.Errorf("llm.providers[%d]: type %q is not one of %s", i, p.Type, strings.Join(llmProviderTypes, ", "))
+		}
+		switch {
+		case p.Type == "openai" && p.APIBase == "" && p.Token == "":
+			return fmt.Errorf("llm.providers[%d]: openai provider %q needs url or token", i, p.Name)
+		case (p.Type == "gemini" || p.Type == "anthropic") && p.Token == "":
+			return fmt.Errorf("llm.providers[%d]: %s provider %q needs token", i, p.Type, p.Name)
+		}
+		if p.HistorySize < 0 || p.RetryCount < 0 || p.MaxTokensResponse < 0 {
+			return fmt.Errorf("llm.providers[%d]: history, retry and max-tokens of %q must be >= 0", i, p.Name)
+		}
+	}
+	return nil
+}
+
+// validateFederation checks federation settings, checked only if federation is enabled.
+// Peer names must be unique, each peer needs an url and a valid ed25519 public key.
+func (s *Settings) validateFederation() error {
+	if !s.Federation.Enabled {
+		return nil
+	}
+	if s.Federation.FeedWindow <= 0 {
+		return fmt.Errorf("federation.feed-window (%v) must be positive", s.Federation.FeedWindow)
+	}
+	if len(s.Federation.Peers) > 0 && s.Federation.PullInterval <= 0 {
+		return fmt.Errorf("federation.pull-interval (%v) must be positive", s.Federation.PullInterval)
+	}
+	seen := map[string]bool{}
+	for i, p := range s.Federation.Peers {
+		if p.Name == "" {
+			return fmt.Errorf("federation.peers[%d]: name is not set", i)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("federation.peers[%d]: name %q is used more than once", i, p.Name)
+		}
+		seen[p.Name] = true
+		if p.URL == "" {
+			return fmt.Errorf("federation.peers[%d]: url of %q is not set", i, p.Name)
+		}
+		if key, err := base64.StdEncoding.DecodeString(p.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("federation.peers[%d]: key of %q is not a base64 encoded ed25519 public key", i, p.Name)
+		}
+		if !slices.Contains(federationTrustLevels, p.Trust) {
+			return fmt.Errorf("federation.peers[%d]: trust %q of %q is not one of %s", i, p.Trust, p.Name,
+				strings.Join(federationTrustLevels, ", "))
+		}
+	}
+	return nil
+}
+
+// validateHooks checks event webhooks settings, checked only if webhook urls are set.
+// The secret is required, receivers can't verify unsigned events.
+func (s *Settings) validateHooks() error {
+	if len(s.Hooks.URLs) == 0 {
+		return nil
+	}
+	for _, h := range s.Hooks.URLs {
+		u, err := url.Parse(h)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("hooks.url %q must be a valid http or https url", h)
+		}
+	}
+	if s.Hooks.Secret == "" {
+		return fmt.Errorf("hooks.secret is required with hooks.url")
+	}
+	if s.Hooks.MaxAttempts <= 0 {
+		return fmt.Errorf("hooks.max-attempts (%d) must be positive", s.Hooks.MaxAttempts)
+	}
+	if s.Hooks.RetryDelay <= 0 {
+		return fmt.Errorf("hooks.retry-delay (%v) must be positive", s.Hooks.RetryDelay)
+	}
+	return nil
+}
+
+// validateScoring checks weighted scoring settings, thresholds and weights are checked only if scoring is enabled
+func (s *Settings) validateScoring() error {
+	if !s.Scoring.Enabled {
+		return nil
+	}
+	if s.Scoring.Threshold <= 0 {
+		return fmt.Errorf("scoring.threshold (%v) must be positive", s.Scoring.Threshold)
+	}
+	if s.Scoring.SuspiciousThreshold < 0 || s.Scoring.SuspiciousThreshold >= s.Scoring.Threshold {
+		return fmt.Errorf("scoring.suspicious-threshold (%v) must be >= 0 and below scoring.threshold (%v)",
+			s.Scoring.SuspiciousThreshold, s.Scoring.Threshold)
+	}
+	if s.Scoring.DefaultWeight < 0 {
+		return fmt.Errorf("scoring.default-weight (%v) must be >= 0", s.Scoring.DefaultWeight)
+	}
+	for name, w := range s.Scoring.Weights {
+		if w < 0 {
+			return fmt.Errorf("scoring.weight of %q (%v) must be >= 0", name, w)
+		}
+	}
+	return nil
+}
+
+// ForGroup returns a copy of settings with the group's detector overrides applied.
+// The copy shares slices and nested pointers with s and must be treated as read-only.
+func (s *Settings) ForGroup(g GroupSettings) *Settings {
+	res := *s
+	o := g.Overrides
+	if o.SimilarityThreshold != nil {
+		res.SimilarityThreshold = *o.SimilarityThreshold
+	}
+	if o.MinMsgLen != nil {
+		res.MinMsgLen = *o.MinMsgLen
+	}
+	if o.MaxEmoji != nil {
+		res.MaxEmoji = *o.MaxEmoji
+	}
+	if o.MinSpamProbability != nil {
+		res.MinSpamProbability = *o.MinSpamProbability
+	}
+	if o.MultiLangWords != nil {
+		res.MultiLangWords = *o.MultiLangWords
+	}
+	if o.FirstMessagesCount != nil {
+		res.FirstMessagesCount = *o.FirstMessagesCount
+	}
+	if o.ParanoidMode != nil {
+		res.ParanoidMode = *o.ParanoidMode
+	}
+	return &res
+}
+
 // IsOpenAIEnabled returns true if OpenAI integration is enabled
 func (s *Settings) IsOpenAIEnabled() bool {
 	return s.OpenAI.APIBase != "" || s.OpenAI.Token != ""
@@ -358,12 +1039,16 @@ var zeroAwarePaths = map[string]bool{
 	"Meta.MentionsLimit":      true, // app/main.go:803 (>= 0); app/config/settings.go IsMetaEnabled (>= 0)
 	"MaxEmoji":                true, // lib/tgspam/detector.go:249 (>= 0): -1 disables, 0 = no emojis allowed
 	"MultiLangWords":          true, // lib/tgspam/detector.go:268 (> 0): 0 disables
+	"ObfuscatedWords":         true, // lib/tgspam/detector.go:398 (> 0): 0 disables
 	"MaxBackups":              true, // app/main.go:546 (> 0): description says "set 0 to disable"
 	"Reactions.MaxReactions":  true, // app/main.go:724 (> 0): 0 disables
 	"Duplicates.Threshold":    true, // app/main.go:717 (> 0): 0 disables
 	"Report.AutoBanThreshold": true, // app/main.go:336, app/events/reports.go:191 (> 0): 0 disables
 	"Report.RateLimit":        true, // app/events/reports.go:154 (<= 0): 0 disables rate limiting
 	"Warn.Threshold":          true, // app/main.go, app/events/admin.go (> 0): 0 disables
+	"Punish.Mute":             true, // app/events/punishment.go next (> 0): 0 skips the step
+	"Punish.Restrict":         true, // app/events/punishment.go next (> 0): 0 skips the step
+	"Captcha.ApproveCount":    true, // app/events/captcha.go accept (> 0): 0 disables seeding approved users
 	"OpenAI.HistorySize":      true, // lib/tgspam/detector.go:409 (> 0): 0 disables history
 	"Gemini.HistorySize":      true, // lib/tgspam/detector.go:409 (> 0): 0 disables history
 	"FirstMessagesCount":      true, // app/main.go:703, lib/tgspam/detector.go:205,208 (> 0): 0 disables
@@ -372,6 +1057,7 @@ var zeroAwarePaths = map[string]bool{
 	"MaxShortMsgCount":        true, // lib/tgspam/detector.go:253 (> 0): 0 disables
 	"Meta.ImageTextLen":       true, // app/main.go image-only wiring (> 0): 0 falls back to MinMsgLen
 	"ProhibitedLangs":         true, // lib/tgspam/detector.go:276 (len > 0): empty disables
+	"Reputation.ApprovalHold": true, // app/storage/reputation.go HoldApproval (> 0): 0 disables warning hold
 }
 
 // ApplyDefaults fills zero-valued fields in s with the corresponding values from
@@ -437,3 +1123,86 @@ func applyDefaultsRecursive(target, template reflect.Value, path string) {
 		}
 	}
 }
+
+// Diff returns paths of fields which differ in s and other, e.g. "Meta.LinksLimit", in order of declaration.
+// Nested settings structs are compared field by field, all other fields, including slices and maps, as a whole.
+// The Transient group is skipped, as it is never stored and can't be changed by loading settings.
+func (s *Settings) Diff(other *Settings) []string {
+	var res []string
+	diffRecursive(reflect.ValueOf(s).Elem(), reflect.ValueOf(other).Elem(), "", &res)
+	return res
+}
+
+// diffRecursive walks two parallel struct values and adds paths of differing leaf fields to res
+func diffRecursive(a, b reflect.Value, path string, res *[]string) {
+	at := a.Type()
+	for i := 0; i < a.NumField(); i++ {
+		ft := at.Field(i)
+		if !ft.IsExported() || ft.Name == "Transient" {
+			continue
+		}
+		fieldPath := ft.Name
+		if path != "" {
+			fieldPath = path + "." + ft.Name
+		}
+		if a.Field(i).Kind() == reflect.Struct {
+			diffRecursive(a.Field(i), b.Field(i), fieldPath, res)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			*res = append(*res, fieldPath)
+		}
+	}
+}
+
+// maskedValue replaces values of sensitive fields in Changes
+const maskedValue = "*****"
+
+// Change is a difference of a single setting between two versions of settings, values are JSON encoded
+type Change struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Changes returns settings changed from s to other, in the same order as Diff, with old and new values.
+// Values of sensitive fields, e.g. tokens and auth hash, are masked, so a change is listed but not revealed.
+func (s *Settings) Changes(other *Settings) []Change {
+	paths := s.Diff(other)
+	if len(paths) == 0 {
+		return nil
+	}
+	oldMasked, newMasked := s.masked(), other.masked()
+	res := make([]Change, 0, len(paths))
+	for _, path := range paths {
+		res = append(res, Change{Path: path, Old: fieldValue(oldMasked, path), New: fieldValue(newMasked, path)})
+	}
+	return res
+}
+
+// masked returns a copy of settings with non-empty sensitive fields replaced by maskedValue
+func (s *Settings) masked() *Settings {
+	res := *s
+	res.LLM.Providers = slices.Clone(s.LLM.Providers) // tokens are replaced in place
+	for _, accessor := range sensitiveFieldAccessors {
+		for _, target := range accessor.get(&res) {
+			if *target != "" {
+				*target = maskedValue
+			}
+		}
+	}
+	return &res
+}
+
+// fieldValue returns JSON encoded value of the settings field by path, e.g. "Meta.LinksLimit"
+func fieldValue(s *Settings, path string) string {
+	v := reflect.ValueOf(s).Elem()
+	for name := range strings.SplitSeq(path, ".") {
+		v = v.FieldByName(name)
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprintf("%v", v.Interface())
+	}
+	return string(data)
+}
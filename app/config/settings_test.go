@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"testing"
 	"time"
@@ -384,6 +386,14 @@ func TestSettings_ApplyDefaults_SkipsZeroAware(t *testing.T) {
 			},
 			assertFn: func(t *testing.T, target *Settings) { assert.Equal(t, 0, target.MultiLangWords) },
 		},
+		{
+			name: "ObfuscatedWords",
+			setup: func(target, template *Settings) {
+				target.ObfuscatedWords = 0
+				template.ObfuscatedWords = 3
+			},
+			assertFn: func(t *testing.T, target *Settings) { assert.Equal(t, 0, target.ObfuscatedWords) },
+		},
 		{
 			name: "MaxBackups",
 			setup: func(target, template *Settings) {
@@ -652,6 +662,60 @@ func TestSettings_ApplyDefaults_NilTemplate(t *testing.T) {
 	assert.Equal(t, ":9090", target.Server.ListenAddr)
 }
 
+func TestSettings_Diff(t *testing.T) {
+	a := &Settings{MinMsgLen: 50, Meta: MetaSettings{LinksLimit: -1}, LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "p1"}}}}
+	a.Transient.Dbg = true
+
+	t.Run("same settings", func(t *testing.T) {
+		b := *a
+		assert.Empty(t, a.Diff(&b))
+	})
+
+	t.Run("changed fields", func(t *testing.T) {
+		b := *a
+		b.MinMsgLen = 100
+		b.Meta.LinksLimit = 2
+		b.Meta.Forward = true
+		b.LLM.Providers = []LLMProviderSettings{{Name: "p2"}}
+		b.Scoring.Weights = map[string]float64{"stopword": 0.5}
+		assert.Equal(t, []string{"Meta.LinksLimit", "Meta.Forward", "LLM.Providers", "Scoring.Weights", "MinMsgLen"}, a.Diff(&b))
+	})
+
+	t.Run("transient ignored", func(t *testing.T) {
+		b := *a
+		b.Transient.Dbg = false
+		b.Transient.ConfigDB = true
+		assert.Empty(t, a.Diff(&b))
+	})
+}
+
+func TestSettings_Changes(t *testing.T) {
+	a := &Settings{MinMsgLen: 50, Telegram: TelegramSettings{Token: "token-1", Group: "g1"},
+		LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "p1", Token: "llm-1"}}}}
+	b := *a
+	b.MinMsgLen = 100
+	b.Telegram.Token = "token-2"
+	b.Telegram.Group = "g2"
+	b.Meta.UsernameSymbols = "@"
+	b.LLM.Providers = []LLMProviderSettings{{Name: "p1", Token: "llm-2", Model: "m1"}}
+	b.Server.AuthHash = "hash"
+
+	changes := a.Changes(&b)
+	require.Len(t, changes, 6)
+	assert.Equal(t, Change{Path: "Telegram.Group", Old: `"g1"`, New: `"g2"`}, changes[0])
+	assert.Equal(t, Change{Path: "Telegram.Token", Old: `"*****"`, New: `"*****"`}, changes[1])
+	assert.Equal(t, Change{Path: "Meta.UsernameSymbols", Old: `""`, New: `"@"`}, changes[2])
+	assert.Equal(t, "LLM.Providers", changes[3].Path)
+	assert.Contains(t, changes[3].Old, `"token":"*****"`)
+	assert.Contains(t, changes[3].New, `"token":"*****","model":"m1"`)
+	assert.NotContains(t, changes[3].Old+changes[3].New, "llm-")
+	assert.Equal(t, Change{Path: "Server.AuthHash", Old: `""`, New: `"*****"`}, changes[4])
+	assert.Equal(t, Change{Path: "MinMsgLen", Old: "50", New: "100"}, changes[5])
+	assert.Equal(t, "token-2", b.Telegram.Token, "settings not masked in place")
+	assert.Equal(t, "llm-2", b.LLM.Providers[0].Token, "providers not masked in place")
+	assert.Nil(t, a.Changes(a))
+}
+
 func TestSettings_IsStartupMessageEnabled(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -680,6 +744,7 @@ func TestSettings_IsStartupMessageEnabled(t *testing.T) {
 }
 
 func TestSettings_Validate(t *testing.T) {
+	testPubKey := base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize))
 	tests := []struct {
 		name    string
 		s       *Settings
@@ -701,6 +766,123 @@ func TestSettings_Validate(t *testing.T) {
 			s:       &Settings{Report: ReportSettings{Threshold: 4, AutoBanThreshold: 4}},
 			wantErr: "",
 		},
+		{
+			name: "captcha enabled with valid settings",
+			s: &Settings{Captcha: CaptchaSettings{Enabled: true, Type: "emoji", Timeout: time.Minute,
+				Action: "kick"}},
+			wantErr: "",
+		},
+		{
+			name:    "captcha enabled with unknown type",
+			s:       &Settings{Captcha: CaptchaSettings{Enabled: true, Type: "riddle", Timeout: time.Minute, Action: "ban"}},
+			wantErr: `captcha.type "riddle" is not one of button, math or emoji`,
+		},
+		{
+			name:    "captcha enabled with zero timeout",
+			s:       &Settings{Captcha: CaptchaSettings{Enabled: true, Type: "button", Action: "ban"}},
+			wantErr: "captcha.timeout (0s) must be positive",
+		},
+		{
+			name:    "captcha enabled with unknown action",
+			s:       &Settings{Captcha: CaptchaSettings{Enabled: true, Type: "button", Timeout: time.Minute, Action: "mute"}},
+			wantErr: `captcha.action "mute" is not one of ban or kick`,
+		},
+		{
+			name:    "captcha disabled with empty settings is valid",
+			s:       &Settings{Captcha: CaptchaSettings{Enabled: false}},
+			wantErr: "",
+		},
+		{
+			name:    "ocr http without url",
+			s:       &Settings{OCR: OCRSettings{Type: "http"}},
+			wantErr: "ocr.url is required for http OCR",
+		},
+		{
+			name:    "ocr http with url is valid",
+			s:       &Settings{OCR: OCRSettings{Type: "http", URL: "http://ocr:8080"}},
+			wantErr: "",
+		},
+		{
+			name:    "ocr unknown type",
+			s:       &Settings{OCR: OCRSettings{Type: "magic"}},
+			wantErr: `ocr.type "magic" is not one of none, tesseract or http`,
+		},
+		{
+			name:    "image hash distance too large",
+			s:       &Settings{ImageHash: ImageHashSettings{Enabled: true, Distance: 65}},
+			wantErr: "image-hash.distance (65) must be between 0 and 64",
+		},
+		{
+			name:    "image hash negative distance",
+			s:       &Settings{ImageHash: ImageHashSettings{Enabled: true, Distance: -1}},
+			wantErr: "image-hash.distance (-1) must be between 0 and 64",
+		},
+		{
+			name:    "image hash disabled with bad distance is valid",
+			s:       &Settings{ImageHash: ImageHashSettings{Distance: 100}},
+			wantErr: "",
+		},
+		{
+			name: "scoring is valid",
+			s: &Settings{Scoring: ScoringSettings{Enabled: true, DefaultWeight: 1, Threshold: 2, SuspiciousThreshold: 1,
+				Weights: map[string]float64{"emoji": 0.5}}},
+			wantErr: "",
+		},
+		{
+			name:    "scoring without threshold",
+			s:       &Settings{Scoring: ScoringSettings{Enabled: true, DefaultWeight: 1}},
+			wantErr: "scoring.threshold (0) must be positive",
+		},
+		{
+			name:    "scoring suspicious threshold above spam threshold",
+			s:       &Settings{Scoring: ScoringSettings{Enabled: true, Threshold: 2, SuspiciousThreshold: 2}},
+			wantErr: "scoring.suspicious-threshold (2) must be >= 0 and below scoring.threshold (2)",
+		},
+		{
+			name:    "scoring negative default weight",
+			s:       &Settings{Scoring: ScoringSettings{Enabled: true, Threshold: 2, DefaultWeight: -1}},
+			wantErr: "scoring.default-weight (-1) must be >= 0",
+		},
+		{
+			name:    "scoring negative check weight",
+			s:       &Settings{Scoring: ScoringSettings{Enabled: true, Threshold: 2, Weights: map[string]float64{"cas": -1}}},
+			wantErr: `scoring.weight of "cas" (-1) must be >= 0`,
+		},
+		{
+			name:    "scoring disabled with bad threshold is valid",
+			s:       &Settings{Scoring: ScoringSettings{Threshold: -1}},
+			wantErr: "",
+		},
+		{
+			name:    "review probability band is valid",
+			s:       &Settings{MinSpamProbability: 50, Review: ReviewSettings{Enabled: true, MinProbability: 40}},
+			wantErr: "",
+		},
+		{
+			name:    "review probability out of range",
+			s:       &Settings{Review: ReviewSettings{MinProbability: 101}},
+			wantErr: "review.min-probability (101) must be in range 0-100",
+		},
+		{
+			name:    "review probability above spam probability",
+			s:       &Settings{MinSpamProbability: 50, Review: ReviewSettings{MinProbability: 50}},
+			wantErr: "review.min-probability (50) must be below min-probability (50)",
+		},
+		{
+			name:    "cas negative sync interval",
+			s:       &Settings{CAS: CASSettings{API: "https://api.cas.chat", SyncInterval: -time.Minute}},
+			wantErr: "cas.sync-interval (-1m0s) must be >= 0 (0 disables)",
+		},
+		{
+			name:    "reputation negative approval hold",
+			s:       &Settings{Reputation: ReputationSettings{Enabled: true, ApprovalHold: -time.Hour}},
+			wantErr: "reputation.approval-hold (-1h0m0s) must be >= 0 (0 disables)",
+		},
+		{
+			name:    "captcha negative approve count",
+			s:       &Settings{Captcha: CaptchaSettings{ApproveCount: -1}},
+			wantErr: "captcha.approve-count (-1) must be >= 0 (0 disables)",
+		},
 		{
 			name:    "warn threshold negative is rejected",
 			s:       &Settings{Warn: WarnSettings{Threshold: -1, Window: 720 * time.Hour}},
@@ -741,6 +923,21 @@ func TestSettings_Validate(t *testing.T) {
 			s:       &Settings{Warn: WarnSettings{Threshold: 0, Window: storage.WarningsRetention + time.Hour}},
 			wantErr: "",
 		},
+		{name: "punish ladder valid", s: &Settings{Punish: PunishSettings{Enabled: true, Mute: time.Minute, Window: time.Hour}}},
+		{name: "punish negative step is rejected", s: &Settings{Punish: PunishSettings{Enabled: true, Restrict: -time.Hour,
+			Window: time.Hour}}, wantErr: "punish.mute (0s) and punish.restrict (-1h0m0s) must be >= 0 (0 skips the step)"},
+		{name: "punish window not set is rejected", s: &Settings{Punish: PunishSettings{Enabled: true, Mute: time.Minute}},
+			wantErr: "punish.window (0s) must be positive"},
+		{name: "punish window above storage retention is rejected", s: &Settings{Punish: PunishSettings{Enabled: true,
+			Window: storage.OffensesRetention + time.Hour}}, wantErr: "not exceed storage retention"},
+		{name: "punish mute below telegram minimum is rejected", s: &Settings{Punish: PunishSettings{Enabled: true,
+			Mute: 10 * time.Second, Window: time.Hour}}, wantErr: "punish.mute (10s) must be between 30s and 8784h0m0s or 0"},
+		{name: "punish restrict above telegram maximum is rejected", s: &Settings{Punish: PunishSettings{Enabled: true,
+			Mute: time.Minute, Restrict: 367 * 24 * time.Hour, Window: time.Hour}},
+			wantErr: "punish.restrict (8808h0m0s) must be between 30s and 8784h0m0s or 0"},
+		{name: "punish steps at telegram limits are valid", s: &Settings{Punish: PunishSettings{Enabled: true,
+			Mute: 30 * time.Second, Restrict: 366 * 24 * time.Hour, Window: time.Hour}}},
+		{name: "punish disabled is not validated", s: &Settings{Punish: PunishSettings{Mute: -time.Minute}}},
 		{name: "max-short-msg-count negative is rejected", s: &Settings{MaxShortMsgCount: -1}, wantErr: "max-short-msg-count (-1) must be >= 0 (0 disables)"},
 		{name: "max-short-msg-count zero is valid (disabled)", s: &Settings{MaxShortMsgCount: 0}, wantErr: ""},
 		{
@@ -772,6 +969,195 @@ func TestSettings_Validate(t *testing.T) {
 		{name: "prohibited-langs empty with min below one is valid", s: &Settings{ProhibitedLangs: "", ProhibitedLangsMin: 0}, wantErr: ""},
 		{name: "prohibited-langs whitespace-only is valid (disabled)", s: &Settings{ProhibitedLangs: "  ", ProhibitedLangsMin: 0}, wantErr: ""},
 		{name: "prohibited-langs delimiter-only is valid (disabled)", s: &Settings{ProhibitedLangs: " , ", ProhibitedLangsMin: 0}, wantErr: ""},
+		{
+			name: "additional groups with own admin chats are valid",
+			s: &Settings{Telegram: TelegramSettings{Group: "main"}, Admin: AdminSettings{AdminGroup: "main-admin"},
+				Groups: []GroupSettings{{Group: "second", AdminGroup: "second-admin"}, {Group: "third"}}},
+			wantErr: "",
+		},
+		{
+			name:    "additional group without name is rejected",
+			s:       &Settings{Telegram: TelegramSettings{Group: "main"}, Groups: []GroupSettings{{AdminGroup: "admin"}}},
+			wantErr: "groups[0]: group is not set",
+		},
+		{
+			name:    "additional group same as primary is rejected",
+			s:       &Settings{Telegram: TelegramSettings{Group: "@main"}, Groups: []GroupSettings{{Group: "main"}}},
+			wantErr: `groups[0]: group "main" is configured more than once`,
+		},
+		{
+			name: "duplicated additional group is rejected",
+			s: &Settings{Telegram: TelegramSettings{Group: "main"},
+				Groups: []GroupSettings{{Group: "second"}, {Group: "second"}}},
+			wantErr: `groups[1]: group "second" is configured more than once`,
+		},
+		{
+			name: "admin chat shared with primary group is rejected",
+			s: &Settings{Telegram: TelegramSettings{Group: "main"}, Admin: AdminSettings{AdminGroup: "admin"},
+				Groups: []GroupSettings{{Group: "second", AdminGroup: "admin"}}},
+			wantErr: `groups[0]: admin group "admin" is already used by another group`,
+		},
+		{
+			name: "admin chat shared between additional groups is rejected",
+			s: &Settings{Telegram: TelegramSettings{Group: "main"},
+				Groups: []GroupSettings{{Group: "second", AdminGroup: "admin"}, {Group: "third", AdminGroup: "admin"}}},
+			wantErr: `groups[1]: admin group "admin" is already used by another group`,
+		},
+		{
+			name: "llm providers are valid",
+			s: &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{
+				{Name: "local", Type: "ollama"}, {Name: "llamacpp", Type: "openai", APIBase: "http://localhost:8080/v1"},
+				{Name: "claude", Type: "anthropic", Token: "t"}, {Name: "flash", Type: "gemini", Token: "t"}}}},
+			wantErr: "",
+		},
+		{
+			name:    "llm provider without name",
+			s:       &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{{Type: "ollama"}}}},
+			wantErr: "llm.providers[0]: name is not set",
+		},
+		{
+			name:    "llm provider with builtin name",
+			s:       &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "openai", Type: "ollama"}}}},
+			wantErr: `llm.providers[0]: name "openai" is reserved or used more than once`,
+		},
+		{
+			name: "llm provider duplicate name",
+			s: &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{
+				{Name: "local", Type: "ollama"}, {Name: "local", Type: "ollama"}}}},
+			wantErr: `llm.providers[1]: name "local" is reserved or used more than once`,
+		},
+		{
+			name:    "llm provider unknown type",
+			s:       &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "x", Type: "magic"}}}},
+			wantErr: `llm.providers[0]: type "magic" is not one of openai, gemini, anthropic, ollama`,
+		},
+		{
+			name:    "llm openai provider without url and token",
+			s:       &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "x", Type: "openai"}}}},
+			wantErr: `llm.providers[0]: openai provider "x" needs url or token`,
+		},
+		{
+			name:    "llm anthropic provider without token",
+			s:       &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "x", Type: "anthropic"}}}},
+			wantErr: `llm.providers[0]: anthropic provider "x" needs token`,
+		},
+		{
+			name:    "llm provider negative history",
+			s:       &Settings{LLM: LLMSettings{Providers: []LLMProviderSettings{{Name: "x", Type: "ollama", HistorySize: -1}}}},
+			wantErr: `llm.providers[0]: history, retry and max-tokens of "x" must be >= 0`,
+		},
+		{
+			name:    "llm cache enabled",
+			s:       &Settings{LLM: LLMSettings{CacheTTL: time.Hour, CacheSize: 100}},
+			wantErr: "",
+		},
+		{
+			name:    "llm cache negative ttl",
+			s:       &Settings{LLM: LLMSettings{CacheTTL: -time.Second}},
+			wantErr: "llm.cache-ttl (-1s) must be >= 0",
+		},
+		{
+			name:    "llm cache without size",
+			s:       &Settings{LLM: LLMSettings{CacheTTL: time.Hour}},
+			wantErr: "llm.cache-size (0) must be positive if llm.cache-ttl is set",
+		},
+		{
+			name: "webhook with server",
+			s: &Settings{Telegram: TelegramSettings{WebhookURL: "https://bot.example.com/telegram/webhook", WebhookSecret: "s3cret_-"},
+				Server: ServerSettings{Enabled: true}},
+			wantErr: "",
+		},
+		{
+			name:    "webhook without server",
+			s:       &Settings{Telegram: TelegramSettings{WebhookURL: "https://bot.example.com/telegram/webhook"}},
+			wantErr: "telegram.webhook-url requires the web server, set server.enabled",
+		},
+		{
+			name:    "webhook not https",
+			s:       &Settings{Telegram: TelegramSettings{WebhookURL: "http://bot.example.com/hook"}, Server: ServerSettings{Enabled: true}},
+			wantErr: `telegram.webhook-url "http://bot.example.com/hook" must be a valid https url`,
+		},
+		{
+			name:    "webhook secret with invalid characters",
+			s:       &Settings{Telegram: TelegramSettings{WebhookSecret: "bad secret!"}},
+			wantErr: "telegram.webhook-secret must be 1-256 characters of A-Z, a-z, 0-9, _ and -",
+		},
+		{
+			name: "federation enabled",
+			s: &Settings{Federation: FederationSettings{Enabled: true, FeedWindow: time.Hour, PullInterval: time.Minute,
+				Peers: []FederationPeerSettings{{Name: "a", URL: "https://a.example.com", PublicKey: testPubKey, Trust: "ban"}}}},
+			wantErr: "",
+		},
+		{
+			name:    "federation disabled skips checks",
+			s:       &Settings{Federation: FederationSettings{Peers: []FederationPeerSettings{{Name: "a"}}}},
+			wantErr: "",
+		},
+		{
+			name:    "federation without feed window",
+			s:       &Settings{Federation: FederationSettings{Enabled: true}},
+			wantErr: "federation.feed-window (0s) must be positive",
+		},
+		{
+			name: "federation peers without pull interval",
+			s: &Settings{Federation: FederationSettings{Enabled: true, FeedWindow: time.Hour,
+				Peers: []FederationPeerSettings{{Name: "a", URL: "https://a.example.com", PublicKey: testPubKey, Trust: "ban"}}}},
+			wantErr: "federation.pull-interval (0s) must be positive",
+		},
+		{
+			name: "federation peer duplicate name",
+			s: &Settings{Federation: FederationSettings{Enabled: true, FeedWindow: time.Hour, PullInterval: time.Minute,
+				Peers: []FederationPeerSettings{{Name: "a", URL: "u", PublicKey: testPubKey, Trust: "ban"},
+					{Name: "a", URL: "u", PublicKey: testPubKey, Trust: "ban"}}}},
+			wantErr: `federation.peers[1]: name "a" is used more than once`,
+		},
+		{
+			name: "federation peer without url",
+			s: &Settings{Federation: FederationSettings{Enabled: true, FeedWindow: time.Hour, PullInterval: time.Minute,
+				Peers: []FederationPeerSettings{{Name: "a", PublicKey: testPubKey, Trust: "ban"}}}},
+			wantErr: `federation.peers[0]: url of "a" is not set`,
+		},
+		{
+			name: "federation peer bad key",
+			s: &Settings{Federation: FederationSettings{Enabled: true, FeedWindow: time.Hour, PullInterval: time.Minute,
+				Peers: []FederationPeerSettings{{Name: "a", URL: "u", PublicKey: "c2hvcnQ=", Trust: "ban"}}}},
+			wantErr: `federation.peers[0]: key of "a" is not a base64 encoded ed25519 public key`,
+		},
+		{
+			name: "federation peer unknown trust",
+			s: &Settings{Federation: FederationSettings{Enabled: true, FeedWindow: time.Hour, PullInterval: time.Minute,
+				Peers: []FederationPeerSettings{{Name: "a", URL: "u", PublicKey: testPubKey, Trust: "full"}}}},
+			wantErr: `federation.peers[0]: trust "full" of "a" is not one of ban, review`,
+		},
+		{
+			name: "valid hooks",
+			s: &Settings{Hooks: HooksSettings{URLs: []string{"https://a.example.com/hook", "http://10.0.0.1:8080/events"},
+				Secret: "secret", MaxAttempts: 10, RetryDelay: time.Second}},
+		},
+		{
+			name: "hooks settings ignored without urls",
+			s:    &Settings{Hooks: HooksSettings{MaxAttempts: -1}},
+		},
+		{
+			name:    "hooks bad url",
+			s:       &Settings{Hooks: HooksSettings{URLs: []string{"ftp://a.example.com"}, Secret: "secret", MaxAttempts: 1, RetryDelay: time.Second}},
+			wantErr: `hooks.url "ftp://a.example.com" must be a valid http or https url`,
+		},
+		{
+			name:    "hooks without secret",
+			s:       &Settings{Hooks: HooksSettings{URLs: []string{"https://a.example.com"}, MaxAttempts: 1, RetryDelay: time.Second}},
+			wantErr: "hooks.secret is required with hooks.url",
+		},
+		{
+			name:    "hooks zero attempts",
+			s:       &Settings{Hooks: HooksSettings{URLs: []string{"https://a.example.com"}, Secret: "secret", RetryDelay: time.Second}},
+			wantErr: "hooks.max-attempts (0) must be positive",
+		},
+		{
+			name:    "hooks zero retry delay",
+			s:       &Settings{Hooks: HooksSettings{URLs: []string{"https://a.example.com"}, Secret: "secret", MaxAttempts: 1}},
+			wantErr: "hooks.retry-delay (0s) must be positive",
+		},
 	}
 
 	for _, tt := range tests {
@@ -786,3 +1172,202 @@ func TestSettings_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGroupSpec(t *testing.T) {
+	floatPtr := func(v float64) *float64 { return &v }
+	intPtr := func(v int) *int { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    GroupSettings
+		wantErr string
+	}{
+		{name: "group only", spec: "mygroup", want: GroupSettings{Group: "mygroup"}},
+		{name: "at prefix trimmed", spec: " @mygroup ", want: GroupSettings{Group: "mygroup"}},
+		{
+			name: "admin and supers",
+			spec: "-100123;admin=@admins;super=user1, user2,,",
+			want: GroupSettings{Group: "-100123", AdminGroup: "admins", SuperUsers: []string{"user1", "user2"}},
+		},
+		{
+			name: "all overrides",
+			spec: "g;similarity-threshold=0.7;min-msg-len=10;max-emoji=-1;min-probability=80;multi-lang=3;" +
+				"first-messages-count=5;paranoid=true;",
+			want: GroupSettings{Group: "g", Overrides: GroupOverrides{
+				SimilarityThreshold: floatPtr(0.7), MinMsgLen: intPtr(10), MaxEmoji: intPtr(-1),
+				MinSpamProbability: floatPtr(80), MultiLangWords: intPtr(3), FirstMessagesCount: intPtr(5),
+				ParanoidMode: boolPtr(true),
+			}},
+		},
+		{name: "empty group", spec: ";admin=a", wantErr: "empty group"},
+		{name: "no value", spec: "g;paranoid", wantErr: `invalid option "paranoid" for group "g", expected key=value`},
+		{name: "unknown option", spec: "g;blah=1", wantErr: `unknown option "blah" for group "g"`},
+		{name: "bad int", spec: "g;min-msg-len=abc", wantErr: `option "min-msg-len" for group "g": invalid int "abc"`},
+		{name: "bad float", spec: "g;similarity-threshold=x", wantErr: `option "similarity-threshold" for group "g": invalid float "x"`},
+		{name: "bad bool", spec: "g;paranoid=maybe", wantErr: `option "paranoid" for group "g": invalid bool "maybe"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseGroupSpec(tt.spec)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+			assert.Equal(t, tt.want.Overrides == GroupOverrides{}, res.Overrides.IsEmpty())
+		})
+	}
+}
+
+func TestParseGroupSpecs(t *testing.T) {
+	res, err := ParseGroupSpecs(nil)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	res, err = ParseGroupSpecs([]string{"g1;admin=a1", "g2"})
+	require.NoError(t, err)
+	assert.Equal(t, []GroupSettings{{Group: "g1", AdminGroup: "a1"}, {Group: "g2"}}, res)
+
+	_, err = ParseGroupSpecs([]string{"g1", "g2;bad"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid option "bad" for group "g2"`)
+}
+
+func TestParseLLMProviderSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    LLMProviderSettings
+		wantErr string
+	}{
+		{name: "minimal", spec: "local;type=ollama", want: LLMProviderSettings{Name: "local", Type: "ollama"}},
+		{
+			name: "all options",
+			spec: " claude ; type=anthropic;url=https://example.com;token=secret;model=claude-test;prompt=check spam, answer json;" +
+				"veto=true;history=5;short=true;retry=2;max-tokens=512;",
+			want: LLMProviderSettings{Name: "claude", Type: "anthropic", APIBase: "https://example.com", Token: "secret",
+				Model: "claude-test", Prompt: "check spam, answer json", Veto: true, HistorySize: 5, CheckShortMessages: true,
+				RetryCount: 2, MaxTokensResponse: 512},
+		},
+		{name: "empty name", spec: ";type=ollama", wantErr: "empty llm provider name"},
+		{name: "no type", spec: "local;model=x", wantErr: `type is not set for llm provider "local"`},
+		{name: "no value", spec: "local;veto", wantErr: `invalid option "veto" for llm provider "local", expected key=value`},
+		{name: "unknown option", spec: "local;blah=1", wantErr: `unknown option "blah" for llm provider "local"`},
+		{name: "bad int", spec: "local;history=abc", wantErr: `option "history" for llm provider "local": invalid value "abc"`},
+		{name: "bad bool", spec: "local;veto=maybe", wantErr: `option "veto" for llm provider "local": invalid value "maybe"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseLLMProviderSpec(tt.spec)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestParseLLMProviderSpecs(t *testing.T) {
+	res, err := ParseLLMProviderSpecs(nil)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	res, err = ParseLLMProviderSpecs([]string{"local;type=ollama", "paid;type=openai;token=t;veto=true"})
+	require.NoError(t, err)
+	assert.Equal(t, []LLMProviderSettings{{Name: "local", Type: "ollama"},
+		{Name: "paid", Type: "openai", Token: "t", Veto: true}}, res)
+
+	_, err = ParseLLMProviderSpecs([]string{"local;type=ollama", "paid"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `type is not set for llm provider "paid"`)
+}
+
+func TestParseFederationPeerSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    FederationPeerSettings
+		wantErr string
+	}{
+		{name: "default trust", spec: "friends;url=https://example.com/federation/feed;key=abc=",
+			want: FederationPeerSettings{Name: "friends", URL: "https://example.com/federation/feed", PublicKey: "abc=", Trust: "review"}},
+		{name: "all options", spec: " friends ; url=https://example.com/federation/feed ;key=abc=;trust=ban;",
+			want: FederationPeerSettings{Name: "friends", URL: "https://example.com/federation/feed", PublicKey: "abc=", Trust: "ban"}},
+		{name: "empty name", spec: ";url=https://example.com", wantErr: "empty federation peer name"},
+		{name: "no value", spec: "friends;trust", wantErr: `invalid option "trust" for federation peer "friends", expected key=value`},
+		{name: "unknown option", spec: "friends;blah=1", wantErr: `unknown option "blah" for federation peer "friends"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseFederationPeerSpec(tt.spec)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+
+	res, err := ParseFederationPeerSpecs([]string{"a;url=u1;key=k1", "b;url=u2;key=k2;trust=ban"})
+	require.NoError(t, err)
+	assert.Equal(t, []FederationPeerSettings{{Name: "a", URL: "u1", PublicKey: "k1", Trust: "review"},
+		{Name: "b", URL: "u2", PublicKey: "k2", Trust: "ban"}}, res)
+	_, err = ParseFederationPeerSpecs([]string{"a;url=u1", ";url=u2"})
+	require.Error(t, err)
+}
+
+func TestParseScoringWeights(t *testing.T) {
+	res, err := ParseScoringWeights([]string{"emoji:0.5", " cas : 3 ", "lua-plugin:1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"emoji": 0.5, "cas": 3, "lua-plugin": 1}, res)
+
+	_, err = ParseScoringWeights([]string{"emoji"})
+	require.EqualError(t, err, `invalid check weight "emoji", expected name:weight`)
+
+	_, err = ParseScoringWeights([]string{":1"})
+	require.Error(t, err)
+
+	_, err = ParseScoringWeights([]string{"emoji:abc"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid weight of check "emoji"`)
+}
+
+func TestSettings_ForGroup(t *testing.T) {
+	s := &Settings{SimilarityThreshold: 0.5, MinMsgLen: 50, MaxEmoji: 2, MinSpamProbability: 50, MultiLangWords: 0,
+		FirstMessagesCount: 1, ParanoidMode: false, Telegram: TelegramSettings{Group: "main"}}
+
+	t.Run("no overrides", func(t *testing.T) {
+		res := s.ForGroup(GroupSettings{Group: "g"})
+		assert.Equal(t, s, res)
+		assert.NotSame(t, s, res)
+	})
+
+	t.Run("all overrides", func(t *testing.T) {
+		g, err := ParseGroupSpec("g;similarity-threshold=0.8;min-msg-len=10;max-emoji=-1;min-probability=70;" +
+			"multi-lang=2;first-messages-count=3;paranoid=true")
+		require.NoError(t, err)
+		res := s.ForGroup(g)
+		assert.InDelta(t, 0.8, res.SimilarityThreshold, 0.0001)
+		assert.Equal(t, 10, res.MinMsgLen)
+		assert.Equal(t, -1, res.MaxEmoji)
+		assert.InDelta(t, 70, res.MinSpamProbability, 0.0001)
+		assert.Equal(t, 2, res.MultiLangWords)
+		assert.Equal(t, 3, res.FirstMessagesCount)
+		assert.True(t, res.ParanoidMode)
+		assert.Equal(t, "main", res.Telegram.Group, "non-detector settings kept")
+		assert.Equal(t, 50, s.MinMsgLen, "original settings not modified")
+		assert.False(t, s.ParanoidMode, "original settings not modified")
+	})
+}
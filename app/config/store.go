@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"time"
 
@@ -33,6 +34,15 @@ const (
 	CmdCountConfig
 )
 
+// config history queries
+const (
+	CmdCreateConfigHistoryTable engine.DBCmd = iota + 1800
+	CmdCreateConfigHistoryIndexes
+	CmdAddConfigHistory
+	CmdListConfigHistory
+	CmdSelectConfigHistory
+)
+
 // queries holds all config queries
 var configQueries = engine.NewQueryMap().
 	Add(CmdCreateConfigTable, engine.Query{
@@ -69,6 +79,48 @@ var configQueries = engine.NewQueryMap().
 	AddSame(CmdSelectConfigUpdatedAt, `SELECT updated_at FROM config WHERE gid = ?`).
 	AddSame(CmdCountConfig, `SELECT COUNT(*) FROM config WHERE gid = ?`)
 
+// configHistoryQueries holds queries of saved settings revisions, data is stored the same way as in config table
+var configHistoryQueries = engine.NewQueryMap().
+	Add(CmdCreateConfigHistoryTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS config_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			gid TEXT NOT NULL,
+			data TEXT NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			comment TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		Postgres: `CREATE TABLE IF NOT EXISTS config_history (
+			id SERIAL PRIMARY KEY,
+			gid TEXT NOT NULL,
+			data TEXT NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			comment TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}).
+	AddSame(CmdCreateConfigHistoryIndexes, `CREATE INDEX IF NOT EXISTS idx_config_history_gid ON config_history(gid, id)`).
+	AddSame(CmdAddConfigHistory, `INSERT INTO config_history (gid, data, author, comment, created_at) VALUES (?, ?, ?, ?, ?)`).
+	AddSame(CmdListConfigHistory, `SELECT id, author, comment, created_at FROM config_history
+		WHERE gid = ? ORDER BY id DESC LIMIT ?`).
+	AddSame(CmdSelectConfigHistory, `SELECT data FROM config_history WHERE gid = ? AND id = ?`)
+
+// Revision is a saved version of settings, recorded on each Save. Settings of the revision are loaded with LoadRevision.
+type Revision struct {
+	ID        int64     `db:"id" json:"id"`
+	Author    string    `db:"author" json:"author"`   // web UI user made the change, empty if saved by CLI
+	Comment   string    `db:"comment" json:"comment"` // optional description of the change
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// revisionCtxKey is the context key of the author and comment recorded with the saved revision
+type revisionCtxKey struct{}
+
+// WithRevisionInfo returns a context with author and comment recorded in the history by Save called with it
+func WithRevisionInfo(ctx context.Context, author, comment string) context.Context {
+	return context.WithValue(ctx, revisionCtxKey{}, Revision{Author: author, Comment: comment})
+}
+
 // NewStore creates a new settings store
 func NewStore(ctx context.Context, db *engine.SQL, opts ...StoreOption) (*Store, error) {
 	if db == nil {
@@ -100,6 +152,17 @@ func NewStore(ctx context.Context, db *engine.SQL, opts ...StoreOption) (*Store,
 		return nil, fmt.Errorf("failed to init config table: %w", err)
 	}
 
+	historyCfg := engine.TableConfig{
+		Name:          "config_history",
+		CreateTable:   CmdCreateConfigHistoryTable,
+		CreateIndexes: CmdCreateConfigHistoryIndexes,
+		MigrateFunc:   noopMigrate, // new table, no migration needed
+		QueriesMap:    configHistoryQueries,
+	}
+	if err := engine.InitTable(ctx, db, historyCfg); err != nil {
+		return nil, fmt.Errorf("failed to init config history table: %w", err)
+	}
+
 	return res, nil
 }
 
@@ -170,7 +233,8 @@ func (s *Store) Load(ctx context.Context) (*Settings, error) {
 	return result, nil
 }
 
-// Save stores the settings to the database
+// Save stores the settings to the database and adds them to the history as a new revision.
+// Author and comment of the revision are taken from the context, see WithRevisionInfo.
 func (s *Store) Save(ctx context.Context, settings *Settings) error {
 	if settings == nil {
 		return errors.New("nil settings")
@@ -184,6 +248,8 @@ func (s *Store) Save(ctx context.Context, settings *Settings) error {
 
 	// clear transient fields that shouldn't be persisted
 	safeCopy.Transient = TransientSettings{}
+	// providers are encrypted in place, don't let it touch the caller's slice
+	safeCopy.LLM.Providers = slices.Clone(settings.LLM.Providers)
 
 	// encrypt sensitive fields if crypter is configured
 	if s.crypter != nil {
@@ -202,16 +268,79 @@ func (s *Store) Save(ctx context.Context, settings *Settings) error {
 	if err != nil {
 		return fmt.Errorf("failed to get upsert query: %w", err)
 	}
+	historyQuery, err := configHistoryQueries.Pick(s.Type(), CmdAddConfigHistory)
+	if err != nil {
+		return fmt.Errorf("failed to get history insert query: %w", err)
+	}
 
-	query = s.Adopt(query)
-	_, err = s.ExecContext(ctx, query, s.GID(), string(data), time.Now())
+	tx, err := s.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err = tx.ExecContext(ctx, s.Adopt(query), s.GID(), string(data), now); err != nil {
 		return fmt.Errorf("failed to save settings: %w", err)
 	}
+	rev, _ := ctx.Value(revisionCtxKey{}).(Revision)
+	if _, err = tx.ExecContext(ctx, s.Adopt(historyQuery), s.GID(), string(data), rev.Author, rev.Comment, now); err != nil {
+		return fmt.Errorf("failed to add settings revision: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit settings: %w", err)
+	}
 
 	return nil
 }
 
+// Revisions returns up to limit latest saved revisions of the settings, newest first
+func (s *Store) Revisions(ctx context.Context, limit int) ([]Revision, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	query, err := configHistoryQueries.Pick(s.Type(), CmdListConfigHistory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history query: %w", err)
+	}
+
+	var res []Revision
+	if err := s.SelectContext(ctx, &res, s.Adopt(query), s.GID(), limit); err != nil {
+		return nil, fmt.Errorf("failed to get settings revisions: %w", err)
+	}
+	return res, nil
+}
+
+// LoadRevision retrieves the settings of the saved revision, sensitive fields are decrypted the same way as by Load
+func (s *Store) LoadRevision(ctx context.Context, id int64) (*Settings, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	query, err := configHistoryQueries.Pick(s.Type(), CmdSelectConfigHistory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history select query: %w", err)
+	}
+
+	var data string
+	if err := s.GetContext(ctx, &data, s.Adopt(query), s.GID(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no settings revision %d: %w", id, err)
+		}
+		return nil, fmt.Errorf("failed to get settings revision %d: %w", id, err)
+	}
+
+	result := New()
+	if err := json.Unmarshal([]byte(data), result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal settings revision %d: %w", id, err)
+	}
+	if s.crypter != nil {
+		if err := s.crypter.DecryptSensitiveFields(result, s.sensitiveFields...); err != nil {
+			return nil, fmt.Errorf("failed to decrypt sensitive fields of revision %d: %w", id, err)
+		}
+	}
+	return result, nil
+}
+
 // Delete removes the settings from the database
 func (s *Store) Delete(ctx context.Context) error {
 	s.Lock()
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -97,10 +98,12 @@ func (s *SettingsTestSuite) SetupTest() {
 			_, _ = db.Exec("ROLLBACK")
 		}
 
-		// now we can safely drop the table
-		_, err := db.Exec("DROP TABLE IF EXISTS config")
-		if err != nil && !strings.Contains(err.Error(), "no such table") {
-			s.Require().NoError(err)
+		// now we can safely drop the tables
+		for _, table := range []string{"config", "config_history"} {
+			_, err := db.Exec("DROP TABLE IF EXISTS " + table)
+			if err != nil && !strings.Contains(err.Error(), "no such table") {
+				s.Require().NoError(err)
+			}
 		}
 	}
 }
@@ -372,10 +375,12 @@ func (s *SettingsTestSuite) TestStore_WithEncryption() {
 			settings.OpenAI.Token = "super-secret-openai-token"
 			settings.Server.AuthHash = "super-secret-auth-hash"
 			settings.Telegram.Group = "non-sensitive-group"
+			settings.LLM.Providers = []LLMProviderSettings{{Name: "claude", Type: "anthropic", Token: "super-secret-llm-token"}}
 
 			// save settings with encryption
 			err = store.Save(s.ctx, settings)
 			s.Require().NoError(err)
+			s.Equal("super-secret-llm-token", settings.LLM.Providers[0].Token, "caller's settings should not be encrypted")
 
 			// verify data is encrypted in the database
 			var record struct {
@@ -419,6 +424,8 @@ func (s *SettingsTestSuite) TestStore_WithEncryption() {
 				"Auth hash should be decrypted when loaded")
 			s.Equal("non-sensitive-group", loaded.Telegram.Group,
 				"Non-sensitive fields should be unchanged")
+			s.Equal("super-secret-llm-token", loaded.LLM.Providers[0].Token,
+				"LLM provider token should be decrypted when loaded")
 
 			// now access without encryption
 			plainStore, err := NewStore(s.ctx, db)
@@ -435,6 +442,71 @@ func (s *SettingsTestSuite) TestStore_WithEncryption() {
 				"OpenAI token should remain encrypted when loaded without crypter")
 			s.True(IsEncrypted(encryptedLoaded.Server.AuthHash),
 				"Auth hash should remain encrypted when loaded without crypter")
+			s.True(IsEncrypted(encryptedLoaded.LLM.Providers[0].Token),
+				"LLM provider token should remain encrypted when loaded without crypter")
+		})
+	}
+}
+
+func (s *SettingsTestSuite) TestStore_History() {
+	for _, db := range s.getTestDB() {
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			crypter, err := NewCrypter("test-master-key-20-chars", "test-instance")
+			s.Require().NoError(err)
+			store, err := NewStore(s.ctx, db, WithCrypter(crypter))
+			s.Require().NoError(err)
+
+			revs, err := store.Revisions(s.ctx, 10)
+			s.Require().NoError(err)
+			s.Empty(revs)
+
+			settings := New()
+			settings.MinMsgLen = 10
+			settings.Telegram.Token = "secret-token-1"
+			s.Require().NoError(store.Save(s.ctx, settings))
+
+			settings.MinMsgLen = 20
+			settings.Telegram.Token = "secret-token-2"
+			s.Require().NoError(store.Save(WithRevisionInfo(s.ctx, "admin", "raise min len"), settings))
+
+			revs, err = store.Revisions(s.ctx, 10)
+			s.Require().NoError(err)
+			s.Require().Len(revs, 2)
+			s.Equal("admin", revs[0].Author, "newest first")
+			s.Equal("raise min len", revs[0].Comment)
+			s.Empty(revs[1].Author)
+			s.Greater(revs[0].ID, revs[1].ID)
+			s.WithinDuration(time.Now(), revs[0].CreatedAt, time.Minute)
+
+			limited, err := store.Revisions(s.ctx, 1)
+			s.Require().NoError(err)
+			s.Equal(revs[:1], limited)
+
+			// sensitive fields are encrypted in history rows too
+			var data string
+			err = db.GetContext(s.ctx, &data, db.Adopt("SELECT data FROM config_history WHERE gid = ? AND id = ?"),
+				db.GID(), revs[1].ID)
+			s.Require().NoError(err)
+			s.NotContains(data, "secret-token-1")
+			s.Contains(data, EncryptPrefix)
+
+			first, err := store.LoadRevision(s.ctx, revs[1].ID)
+			s.Require().NoError(err)
+			s.Equal(10, first.MinMsgLen)
+			s.Equal("secret-token-1", first.Telegram.Token, "decrypted on load")
+
+			current, err := store.Load(s.ctx)
+			s.Require().NoError(err)
+			s.Equal(20, current.MinMsgLen, "history doesn't change current settings")
+
+			_, err = store.LoadRevision(s.ctx, revs[0].ID+100)
+			s.Require().ErrorIs(err, sql.ErrNoRows)
+
+			// history survives deletion of the settings
+			s.Require().NoError(store.Delete(s.ctx))
+			revs, err = store.Revisions(s.ctx, 10)
+			s.Require().NoError(err)
+			s.Len(revs, 2)
 		})
 	}
 }
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envRefRe matches environment variable references in yaml values, ${NAME} or ${NAME:-default},
+// and the escaped $${ which is kept as a literal ${
+var envRefRe = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// secretEnvRefs maps sensitive fields to environment variables referenced instead of their values in exported yaml.
+// The names match env vars of the corresponding CLI options, so the exported file works with the same environment.
+// LLM provider tokens are referenced by provider name, see llmTokenEnvRef.
+var secretEnvRefs = map[string]string{
+	FieldTelegramToken:  "TELEGRAM_TOKEN",
+	FieldWebhookSecret:  "TELEGRAM_WEBHOOK_SECRET",
+	FieldOpenAIToken:    "OPENAI_TOKEN",
+	FieldGeminiToken:    "GEMINI_TOKEN",
+	FieldHooksSecret:    "HOOKS_SECRET",
+	FieldServerAuthHash: "SERVER_AUTH_HASH",
+}
+
+// LoadYAML reads settings from the yaml config file. Environment variables referenced in values as ${NAME}
+// are replaced with their values, ${NAME:-default} falls back to the default if the variable is not set,
+// and $${ is kept as a literal ${. A referenced variable without default must be set.
+// Values are expanded after parsing, so secrets with yaml special characters don't need quoting.
+// Unknown fields are rejected, to catch typos which would otherwise silently leave the default value.
+// Fields not set in the file are left zero, the caller fills them with defaults.
+func LoadYAML(r io.Reader) (*Settings, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read yaml: %w", err)
+	}
+
+	res := New()
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse yaml: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return res, nil // empty file, all defaults
+	}
+	root := doc.Content[0]
+
+	if err := checkYAMLFields(root, reflect.TypeFor[Settings](), ""); err != nil {
+		return nil, err
+	}
+	if err := expandEnvRefs(root); err != nil {
+		return nil, err
+	}
+	if err := root.Decode(res); err != nil {
+		return nil, fmt.Errorf("failed to decode yaml: %w", err)
+	}
+	return res, nil
+}
+
+// ExportYAML returns settings in the format of the yaml config file, see LoadYAML.
+// Non-empty sensitive fields are written as references to environment variables, e.g. ${TELEGRAM_TOKEN},
+// so the exported file doesn't reveal secrets. The auth hash set on CLI is not exported, as it is not a part
+// of the stored configuration.
+func (s *Settings) ExportYAML() ([]byte, error) {
+	res := *s
+	res.LLM.Providers = slices.Clone(s.LLM.Providers) // tokens are replaced in place
+	if res.Transient.AuthFromCLI {
+		res.Server.AuthHash = ""
+	}
+	for field, env := range secretEnvRefs {
+		for _, target := range sensitiveFieldAccessors[field].get(&res) {
+			if *target != "" {
+				*target = "${" + env + "}"
+			}
+		}
+	}
+	for i, p := range res.LLM.Providers {
+		if p.Token != "" {
+			res.LLM.Providers[i].Token = "${" + llmTokenEnvRef(p.Name) + "}"
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&res); err != nil {
+		return nil, fmt.Errorf("failed to encode yaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode yaml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// llmTokenEnvRef returns the name of environment variable referenced by exported token of the LLM provider,
+// e.g. LLM_PROVIDER_LOCAL_QWEN_TOKEN for "local-qwen"
+func llmTokenEnvRef(provider string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, provider)
+	return "LLM_PROVIDER_" + name + "_TOKEN"
+}
+
+// expandEnvRefs replaces environment variable references in all scalar values of the node,
+// mapping keys are not expanded. Expanded plain values are resolved again, so ${LIMIT} can set a number.
+func expandEnvRefs(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := expandEnvRefs(n.Content[i]); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, c := range n.Content {
+			if err := expandEnvRefs(c); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return nil
+		}
+		var expandErr error
+		n.Value = envRefRe.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if ref == "$${" {
+				return "${"
+			}
+			m := envRefRe.FindStringSubmatch(ref)
+			if val, ok := os.LookupEnv(m[1]); ok {
+				return val
+			}
+			if m[2] != "" {
+				return m[3]
+			}
+			if expandErr == nil {
+				expandErr = fmt.Errorf("line %d: environment variable %s is not set", n.Line, m[1])
+			}
+			return ""
+		})
+		if expandErr != nil {
+			return expandErr
+		}
+		if n.Style == 0 {
+			n.Tag = "" // resolve the expanded value, the reference itself is always a string
+		}
+	}
+	return nil
+}
+
+// checkYAMLFields checks all mapping keys of the node are yaml fields of the type, recursively.
+// Maps are not checked, as any key is valid for them.
+func checkYAMLFields(n *yaml.Node, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		fields := map[string]reflect.Type{}
+		for i := range t.NumField() {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if key == "<<" {
+				continue // merge key
+			}
+			ft, ok := fields[key]
+			if !ok {
+				return fmt.Errorf("line %d: unknown field %q", n.Content[i].Line, path+key)
+			}
+			if err := checkYAMLFields(n.Content[i+1], ft, path+key+"."); err != nil {
+				return err
+			}
+		}
+	case t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := checkYAMLFields(c, t.Elem(), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadYAML(t *testing.T) {
+	t.Setenv("TG_SPAM_TEST_TOKEN", "123:secret #with: yaml chars")
+	t.Setenv("TG_SPAM_TEST_LIMIT", "5")
+
+	t.Run("full config", func(t *testing.T) {
+		data := `
+instance_id: test
+similarity_threshold: 0.7
+min_msg_len: ${TG_SPAM_TEST_LIMIT}
+telegram:
+  group: my-group
+  token: ${TG_SPAM_TEST_TOKEN}
+  timeout: 45s
+openai:
+  token: "${TG_SPAM_TEST_MISSING:-}"
+  model: gpt-4o-mini
+server:
+  auth_hash: $2a$10$abcdef
+message:
+  spam: "price is $${PRICE}"
+llm:
+  providers:
+    - name: local
+      type: ollama
+      model: ${TG_SPAM_TEST_MISSING:-qwen3}
+groups:
+  - group: "-100123"
+    overrides:
+      min_msg_len: 20
+`
+		s, err := LoadYAML(strings.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "test", s.InstanceID)
+		assert.InDelta(t, 0.7, s.SimilarityThreshold, 1e-9)
+		assert.Equal(t, 5, s.MinMsgLen, "expanded value resolved as number")
+		assert.Equal(t, "my-group", s.Telegram.Group)
+		assert.Equal(t, "123:secret #with: yaml chars", s.Telegram.Token)
+		assert.Equal(t, 45*time.Second, s.Telegram.Timeout)
+		assert.Empty(t, s.OpenAI.Token)
+		assert.Equal(t, "gpt-4o-mini", s.OpenAI.Model)
+		assert.Equal(t, "$2a$10$abcdef", s.Server.AuthHash, "bare $ kept")
+		assert.Equal(t, "price is ${PRICE}", s.Message.Spam)
+		require.Len(t, s.LLM.Providers, 1)
+		assert.Equal(t, "qwen3", s.LLM.Providers[0].Model)
+		require.Len(t, s.Groups, 1)
+		require.NotNil(t, s.Groups[0].Overrides.MinMsgLen)
+		assert.Equal(t, 20, *s.Groups[0].Overrides.MinMsgLen)
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		s, err := LoadYAML(strings.NewReader(""))
+		require.NoError(t, err)
+		assert.Equal(t, New(), s)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := LoadYAML(strings.NewReader("telegram:\n  group: g\n  tokn: x\n"))
+		require.EqualError(t, err, `line 3: unknown field "telegram.tokn"`)
+	})
+
+	t.Run("unknown field in list", func(t *testing.T) {
+		_, err := LoadYAML(strings.NewReader("llm:\n  providers:\n    - name: x\n      kind: ollama\n"))
+		require.EqualError(t, err, `line 4: unknown field "llm.providers.kind"`)
+	})
+
+	t.Run("transient fields not loaded", func(t *testing.T) {
+		_, err := LoadYAML(strings.NewReader("transient:\n  dbg: true\n"))
+		require.EqualError(t, err, `line 1: unknown field "transient"`)
+	})
+
+	t.Run("unset variable", func(t *testing.T) {
+		_, err := LoadYAML(strings.NewReader("telegram:\n  token: ${TG_SPAM_TEST_MISSING}\n"))
+		require.EqualError(t, err, "line 2: environment variable TG_SPAM_TEST_MISSING is not set")
+	})
+
+	t.Run("bad value", func(t *testing.T) {
+		_, err := LoadYAML(strings.NewReader("min_msg_len: many\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode yaml")
+	})
+
+	t.Run("bad yaml", func(t *testing.T) {
+		_, err := LoadYAML(strings.NewReader("telegram: [\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse yaml")
+	})
+}
+
+func TestSettings_ExportYAML(t *testing.T) {
+	s := New()
+	s.InstanceID = "test"
+	s.MinMsgLen = 50
+	s.Telegram.Group = "my-group"
+	s.Telegram.Token = "secret-token"
+	s.Telegram.Timeout = 30 * time.Second
+	s.Hooks.Secret = "hooks-secret"
+	s.Server.AuthHash = "$2a$10$hash"
+	s.LLM.Providers = []LLMProviderSettings{{Name: "local-qwen", Type: "ollama", Token: "llm-secret"}, {Name: "free", Type: "ollama"}}
+	s.Transient.Dbg = true
+
+	data, err := s.ExportYAML()
+	require.NoError(t, err)
+	out := string(data)
+	assert.Contains(t, out, "token: ${TELEGRAM_TOKEN}")
+	assert.Contains(t, out, "secret: ${HOOKS_SECRET}")
+	assert.Contains(t, out, "auth_hash: ${SERVER_AUTH_HASH}")
+	assert.Contains(t, out, "token: ${LLM_PROVIDER_LOCAL_QWEN_TOKEN}")
+	assert.Contains(t, out, "timeout: 30s")
+	for _, secret := range []string{"secret-token", "hooks-secret", "$2a$10$hash", "llm-secret"} {
+		assert.NotContains(t, out, secret)
+	}
+	assert.NotContains(t, out, "transient")
+	assert.Equal(t, "secret-token", s.Telegram.Token, "original settings not changed")
+	assert.Equal(t, "llm-secret", s.LLM.Providers[0].Token, "original providers not changed")
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Setenv("TELEGRAM_TOKEN", "secret-token")
+		t.Setenv("HOOKS_SECRET", "hooks-secret")
+		t.Setenv("SERVER_AUTH_HASH", "$2a$10$hash")
+		t.Setenv("LLM_PROVIDER_LOCAL_QWEN_TOKEN", "llm-secret")
+		loaded, err := LoadYAML(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, s.Telegram, loaded.Telegram)
+		assert.Equal(t, s.Hooks, loaded.Hooks)
+		assert.Equal(t, s.Server, loaded.Server)
+		assert.Equal(t, s.LLM, loaded.LLM)
+		assert.Equal(t, 50, loaded.MinMsgLen)
+		assert.Equal(t, "test", loaded.InstanceID)
+		assert.Equal(t, TransientSettings{}, loaded.Transient)
+	})
+
+	t.Run("auth hash from cli not exported", func(t *testing.T) {
+		cli := *s
+		cli.Transient.AuthFromCLI = true
+		data, err := cli.ExportYAML()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `auth_hash: ""`)
+	})
+}
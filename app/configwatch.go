@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/webapi"
+)
+
+// configWatcher reloads settings from the yaml config file when it changes. The directory of the file is watched,
+// not the file itself, as editors and kubernetes config maps replace the file instead of writing to it.
+// Events only trigger the check, settings are reloaded if the content of the file has changed.
+type configWatcher struct {
+	path     string
+	load     func() (*config.Settings, error) // loads, normalizes and validates settings from the file
+	replace  func(*config.Settings) []string  // replaces running settings, returns settings which require restart
+	debounce time.Duration                    // delay after the last event before the file is checked
+	hash     [sha256.Size]byte                // hash of the loaded content
+}
+
+// watchConfigFile starts watching the config file in background if enabled. Changed settings replace settings
+// of the web server, which applies them to detectors and listener, or are applied directly without the server.
+func watchConfigFile(ctx context.Context, settings *config.Settings, reloadNormalize func(*config.Settings),
+	srv *webapi.Server, live *liveConfig) {
+	if !settings.Transient.ConfigWatch || settings.Transient.ConfigFile == "" {
+		return
+	}
+
+	current := settings // used without web server only
+	w := &configWatcher{
+		path:     settings.Transient.ConfigFile,
+		debounce: 500 * time.Millisecond,
+		load: func() (*config.Settings, error) {
+			res := config.New()
+			res.Transient, res.InstanceID = settings.Transient, settings.InstanceID
+			if err := loadConfigFromFile(settings.Transient.ConfigFile, res, nil); err != nil {
+				return nil, err
+			}
+			if reloadNormalize != nil {
+				reloadNormalize(res)
+			}
+			if err := res.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid configuration: %w", err)
+			}
+			return res, nil
+		},
+		replace: func(s *config.Settings) []string {
+			if srv != nil {
+				return srv.ReplaceSettings(s)
+			}
+			restart := live.apply(current, s)
+			current = s
+			return restart
+		},
+	}
+	go func() {
+		if err := w.Run(ctx); err != nil {
+			log.Printf("[WARN] config file watcher failed, %v", err)
+		}
+	}()
+}
+
+// Run watches the config file until context is canceled
+func (w *configWatcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to watch config file directory: %w", err)
+	}
+	if data, err := os.ReadFile(w.path); err == nil {
+		w.hash = sha256.Sum256(data)
+	}
+	log.Printf("[INFO] watching config file %s for changes", w.path)
+
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[WARN] config file watcher error: %v", err)
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+// reload loads and replaces settings if the content of the config file has changed.
+// Invalid settings are reported and ignored, the running settings stay as they are.
+func (w *configWatcher) reload() {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		log.Printf("[WARN] can't read config file %s: %v", w.path, err)
+		return
+	}
+	hash := sha256.Sum256(data)
+	if hash == w.hash {
+		return
+	}
+	w.hash = hash
+
+	settings, err := w.load()
+	if err != nil {
+		log.Printf("[WARN] config file %s changed, but not applied: %v", w.path, err)
+		return
+	}
+	restart := w.replace(settings)
+	log.Printf("[INFO] configuration reloaded from %s, restart required to apply: %v", w.path, restart)
+}
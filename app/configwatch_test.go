@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestConfigWatcher_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tg-spam.yml")
+	require.NoError(t, os.WriteFile(path, []byte("min_msg_len: 10\n"), 0o600))
+
+	var mu sync.Mutex
+	var replaced []*config.Settings
+	loads := 0
+	w := &configWatcher{
+		path:     path,
+		debounce: 50 * time.Millisecond,
+		load: func() (*config.Settings, error) {
+			mu.Lock()
+			loads++
+			mu.Unlock()
+			fh, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			defer fh.Close()
+			return config.LoadYAML(fh)
+		},
+		replace: func(s *config.Settings) []string {
+			mu.Lock()
+			defer mu.Unlock()
+			replaced = append(replaced, s)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, w.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	replacedCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(replaced)
+	}
+	loadsCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return loads
+	}
+	time.Sleep(100 * time.Millisecond) // let watcher start
+
+	// file replaced, as editors do
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte("min_msg_len: 20\n"), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+	require.Eventually(t, func() bool { return replacedCount() == 1 }, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 20, replaced[0].MinMsgLen)
+	mu.Unlock()
+
+	// same content written, not reloaded
+	require.NoError(t, os.WriteFile(path, []byte("min_msg_len: 20\n"), 0o600))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, 1, loadsCount())
+
+	// invalid content, not applied
+	require.NoError(t, os.WriteFile(path, []byte("min_msg_lenn: 30\n"), 0o600))
+	require.Eventually(t, func() bool { return loadsCount() == 2 }, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, replacedCount())
+
+	// fixed content written
+	require.NoError(t, os.WriteFile(path, []byte("min_msg_len: 30\n"), 0o600))
+	require.Eventually(t, func() bool { return replacedCount() == 2 }, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 30, replaced[1].MinMsgLen)
+	mu.Unlock()
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		settings := config.New()
+		settings.Transient.ConfigFile = "/not/used.yml"
+		watchConfigFile(context.Background(), settings, nil, nil, &liveConfig{}) // doesn't start watcher
+	})
+
+	t.Run("applied without server", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tg-spam.yml")
+		require.NoError(t, os.WriteFile(path, []byte("min_msg_len: 10\n"), 0o600))
+		prev := makeTestSettings()
+		prev.MinMsgLen = 10
+		prev.Transient.ConfigFile, prev.Transient.ConfigWatch = path, true
+		detector, err := newDetector(prev)
+		require.NoError(t, err)
+		live := &liveConfig{detectors: []liveDetector{{detector: detector}}}
+		defaults := *prev
+		normalize := func(s *config.Settings) { s.ApplyDefaults(&defaults) }
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watchConfigFile(ctx, prev, normalize, nil, live)
+		time.Sleep(100 * time.Millisecond) // let watcher start
+
+		isShort := func() bool { // checked with detector, it is reconfigured under its lock
+			_, cr := detector.Check(spamcheck.Request{Msg: "fifteen letters"})
+			return slices.ContainsFunc(cr, func(r spamcheck.Response) bool { return r.Name == "message length" })
+		}
+		assert.False(t, isShort())
+		require.NoError(t, os.WriteFile(path, []byte("min_msg_len: 25\n"), 0o600))
+		require.Eventually(t, isShort, 2*time.Second, 10*time.Millisecond)
+	})
+}
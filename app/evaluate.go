@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/storage/engine"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+// evaluateOptions are options of the evaluate command
+type evaluateOptions struct {
+	Spam     string `long:"spam" description:"file with spam messages, one per line"`
+	Ham      string `long:"ham" description:"file with ham messages, one per line"`
+	Corpus   string `long:"corpus" description:"JSONL file with check requests labeled by \"spam\" field"`
+	Folds    int    `long:"folds" description:"k-fold cross validation on stored samples, number of folds"`
+	External bool   `long:"external" description:"make checks calling external services, CAS and LLMs, as configured"`
+}
+
+// labeledRequest is a line of the evaluation corpus, check request with the expected result
+type labeledRequest struct {
+	spamcheck.Request
+	Spam bool `json:"spam"`
+}
+
+// evalData is the data detector is loaded with, read from the samples and dictionary stores
+type evalData struct {
+	spam, ham                      []string
+	excluded, stopWords            []string
+	blockedDomains, allowedDomains []string
+}
+
+// runEvaluate handles the evaluate command, it prints the accuracy report to stdout.
+// Returns the process exit code.
+func runEvaluate(settings *config.Settings, opts evaluateOptions) int {
+	if err := evaluate(context.Background(), settings, opts, os.Stdout); err != nil {
+		log.Printf("[ERROR] evaluation failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// evaluate checks the labeled corpus and, if folds set, the stored samples with k-fold cross validation.
+// Detector is made with the same settings, samples and dictionaries as the bot uses, but all checks are
+// made with CheckOnly, so nothing is written to the database. CAS and LLM checks are made only if
+// opts.External set, see evalSettings. Reports are written to w.
+func evaluate(ctx context.Context, settings *config.Settings, opts evaluateOptions, w io.Writer) error {
+	if opts.Spam == "" && opts.Ham == "" && opts.Corpus == "" && opts.Folds == 0 {
+		return errors.New("nothing to evaluate, set --spam, --ham, --corpus or --folds")
+	}
+	if opts.Folds == 1 || opts.Folds < 0 {
+		return fmt.Errorf("invalid number of folds %d, at least 2 required", opts.Folds)
+	}
+
+	corpus, err := readCorpus(opts)
+	if err != nil {
+		return err
+	}
+
+	dataDB, err := makeDB(ctx, settings)
+	if err != nil {
+		return fmt.Errorf("can't make db, %w", err)
+	}
+	defer dataDB.Close()
+
+	data, err := readEvalData(ctx, dataDB)
+	if err != nil {
+		return err
+	}
+	detector := makeDetector(evalSettings(settings, opts.External))
+	if err := data.load(detector, data.spam, data.ham); err != nil {
+		return err
+	}
+
+	if len(corpus) > 0 {
+		rep := newEvalReport()
+		for _, lr := range corpus {
+			rep.check(detector, lr)
+		}
+		rep.write(w, fmt.Sprintf("corpus, %d messages", len(corpus)))
+	}
+
+	if opts.Folds > 0 {
+		rep, err := crossValidate(detector, data, opts.Folds)
+		if err != nil {
+			return err
+		}
+		rep.write(w, fmt.Sprintf("%d-fold cross validation, %d spam and %d ham samples",
+			opts.Folds, len(data.spam), len(data.ham)))
+	}
+	return nil
+}
+
+// evalSettings returns settings of the evaluation detector. Checks calling external services, i.e. CAS and LLMs,
+// are disabled unless external is set: every evaluated message would be sent to them, which is slow, may cost
+// money and makes results depend on the service. The copy shares slices with settings, which are not modified.
+func evalSettings(settings *config.Settings, external bool) *config.Settings {
+	if external {
+		log.Printf("[WARN] external checks enabled, messages are sent to CAS and LLMs as configured")
+		return settings
+	}
+	res := *settings
+	res.CAS.API = ""
+	res.OpenAI.Token, res.OpenAI.APIBase = "", ""
+	res.Gemini.Token = ""
+	res.LLM.Providers = nil
+	return &res
+}
+
+// crossValidate splits stored samples into k folds and checks each fold by the detector trained on the other ones,
+// so the classifier and similarity checks never see the checked message in the samples.
+// Samples are assigned to folds round-robin, i.e. the result is the same for the same samples.
+func crossValidate(detector *tgspam.Detector, data evalData, folds int) (*evalReport, error) {
+	if len(data.spam) < folds || len(data.ham) < folds {
+		return nil, fmt.Errorf("not enough samples for %d folds, spam: %d, ham: %d", folds, len(data.spam), len(data.ham))
+	}
+	split := func(samples []string, fold int) (train, test []string) {
+		for i, s := range samples {
+			if i%folds == fold {
+				test = append(test, s)
+				continue
+			}
+			train = append(train, s)
+		}
+		return train, test
+	}
+
+	rep := newEvalReport()
+	for fold := range folds {
+		trainSpam, testSpam := split(data.spam, fold)
+		trainHam, testHam := split(data.ham, fold)
+		if err := data.load(detector, trainSpam, trainHam); err != nil {
+			return nil, fmt.Errorf("fold %d: %w", fold+1, err)
+		}
+		for _, msg := range testSpam {
+			rep.check(detector, labeledRequest{Request: spamcheck.Request{Msg: msg}, Spam: true})
+		}
+		for _, msg := range testHam {
+			rep.check(detector, labeledRequest{Request: spamcheck.Request{Msg: msg}, Spam: false})
+		}
+	}
+	return rep, nil
+}
+
+// readEvalData reads all spam and ham samples, preset and user, and dictionaries from the database
+func readEvalData(ctx context.Context, dataDB *engine.SQL) (res evalData, err error) {
+	samplesStore, err := storage.NewSamples(ctx, dataDB)
+	if err != nil {
+		return res, fmt.Errorf("can't make samples store, %w", err)
+	}
+	if res.spam, err = samplesStore.Read(ctx, storage.SampleTypeSpam, storage.SampleOriginAny); err != nil {
+		return res, fmt.Errorf("can't read spam samples, %w", err)
+	}
+	if res.ham, err = samplesStore.Read(ctx, storage.SampleTypeHam, storage.SampleOriginAny); err != nil {
+		return res, fmt.Errorf("can't read ham samples, %w", err)
+	}
+	if len(res.spam) == 0 || len(res.ham) == 0 {
+		return res, errors.New("no spam or ham samples in the database, run with --convert=only to load sample files first")
+	}
+
+	dictionaryStore, err := storage.NewDictionary(ctx, dataDB)
+	if err != nil {
+		return res, fmt.Errorf("can't make dictionary store, %w", err)
+	}
+	dicts := []struct {
+		t   storage.DictionaryType
+		res *[]string
+	}{
+		{storage.DictionaryTypeIgnoredWord, &res.excluded},
+		{storage.DictionaryTypeStopPhrase, &res.stopWords},
+		{storage.DictionaryTypeBlockedDomain, &res.blockedDomains},
+		{storage.DictionaryTypeAllowedDomain, &res.allowedDomains},
+	}
+	for _, d := range dicts {
+		if *d.res, err = dictionaryStore.Read(ctx, d.t); err != nil {
+			return res, fmt.Errorf("can't read %s dictionary, %w", d.t, err)
+		}
+	}
+	return res, nil
+}
+
+// load loads the given samples and all dictionaries to the detector, replacing previously loaded ones
+func (e evalData) load(detector *tgspam.Detector, spam, ham []string) error {
+	lines := func(s []string) io.Reader { return strings.NewReader(strings.Join(s, "\n")) }
+	if _, err := detector.LoadSamples(lines(e.excluded), []io.Reader{lines(spam)}, []io.Reader{lines(ham)}); err != nil {
+		return fmt.Errorf("can't load samples, %w", err)
+	}
+	if _, err := detector.LoadStopWords(lines(e.stopWords)); err != nil {
+		return fmt.Errorf("can't load stop words, %w", err)
+	}
+	if _, err := detector.LoadDomains(lines(e.blockedDomains), lines(e.allowedDomains)); err != nil {
+		return fmt.Errorf("can't load domains, %w", err)
+	}
+	return nil
+}
+
+// readCorpus reads labeled messages from spam and ham text files and JSONL corpus
+func readCorpus(opts evaluateOptions) ([]labeledRequest, error) {
+	var res []labeledRequest
+	for _, f := range []struct {
+		name string
+		spam bool
+	}{{opts.Spam, true}, {opts.Ham, false}} {
+		if f.name == "" {
+			continue
+		}
+		err := scanLines(f.name, func(line string) error {
+			res = append(res, labeledRequest{Request: spamcheck.Request{Msg: line}, Spam: f.spam})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Corpus != "" {
+		err := scanLines(opts.Corpus, func(line string) error {
+			var lr labeledRequest
+			if err := json.Unmarshal([]byte(line), &lr); err != nil {
+				return fmt.Errorf("can't unmarshal %q, %w", line, err)
+			}
+			res = append(res, lr)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// scanLines calls fn for each non-empty line of the file
+func scanLines(fileName string, fn func(line string) error) error {
+	fh, err := os.Open(fileName) //nolint:gosec // file name is set by the user running the command
+	if err != nil {
+		return fmt.Errorf("can't open %s, %w", fileName, err)
+	}
+	defer fh.Close()
+
+	scanner := bufio.NewScanner(fh)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s: %w", fileName, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("can't read %s, %w", fileName, err)
+	}
+	return nil
+}
+
+// evalReport accumulates the confusion matrix and contribution of each check
+type evalReport struct {
+	tp, fp, tn, fn int
+	checks         map[string]*checkStats
+}
+
+// checkStats counts spam verdicts of a single check
+type checkStats struct {
+	spam   int // spam messages flagged by the check
+	ham    int // ham messages flagged by the check, false alarms
+	single int // spam messages flagged by this check only
+	errors int // check failed
+}
+
+func newEvalReport() *evalReport {
+	return &evalReport{checks: map[string]*checkStats{}}
+}
+
+// check runs the detector on the labeled request and counts the result
+func (r *evalReport) check(detector *tgspam.Detector, lr labeledRequest) {
+	req := lr.Request
+	req.CheckOnly = true
+	spam, cr := detector.Check(req)
+
+	switch {
+	case spam && lr.Spam:
+		r.tp++
+	case spam && !lr.Spam:
+		r.fp++
+	case !spam && lr.Spam:
+		r.fn++
+	default:
+		r.tn++
+	}
+
+	var flagged []string
+	for _, resp := range cr {
+		st, ok := r.checks[resp.Name]
+		if !ok {
+			st = &checkStats{}
+			r.checks[resp.Name] = st
+		}
+		if resp.Error != nil {
+			st.errors++
+		}
+		if !resp.Spam {
+			continue
+		}
+		flagged = append(flagged, resp.Name)
+		if lr.Spam {
+			st.spam++
+			continue
+		}
+		st.ham++
+	}
+	if lr.Spam && len(flagged) == 1 {
+		r.checks[flagged[0]].single++
+	}
+}
+
+func (r *evalReport) precision() float64 { return ratio(r.tp, r.tp+r.fp) }
+func (r *evalReport) recall() float64    { return ratio(r.tp, r.tp+r.fn) }
+
+func (r *evalReport) f1() float64 {
+	p, rc := r.precision(), r.recall()
+	if p+rc == 0 {
+		return 0
+	}
+	return 2 * p * rc / (p + rc)
+}
+
+// write prints the report, checks which never flagged a message and never failed are skipped
+func (r *evalReport) write(w io.Writer, title string) {
+	fmt.Fprintf(w, "\n%s\n", title)
+	fmt.Fprintf(w, "precision: %.4f, recall: %.4f, f1: %.4f, accuracy: %.4f\n",
+		r.precision(), r.recall(), r.f1(), ratio(r.tp+r.tn, r.tp+r.tn+r.fp+r.fn))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n\tpredicted spam\tpredicted ham\n")
+	fmt.Fprintf(tw, "actual spam\t%d\t%d\n", r.tp, r.fn)
+	fmt.Fprintf(tw, "actual ham\t%d\t%d\n", r.fp, r.tn)
+	_ = tw.Flush()
+
+	names := make([]string, 0, len(r.checks))
+	for name, st := range r.checks {
+		if st.spam+st.ham+st.errors > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	slices.Sort(names)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\ncheck\tspam flagged\tham flagged\tonly check\terrors\n")
+	for _, name := range names {
+		st := r.checks[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", name, st.spam, st.ham, st.single, st.errors)
+	}
+	_ = tw.Flush()
+}
+
+// ratio returns a/b, 0 if b is 0
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
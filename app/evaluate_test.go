@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/storage/engine"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+func TestEvaluate(t *testing.T) {
+	ctx := t.Context()
+	tmpDir := t.TempDir()
+
+	settings := makeTestSettings()
+	settings.InstanceID = "gr1"
+	settings.Files.DynamicDataPath = tmpDir
+	settings.Transient.DataBaseURL = "tg-spam.db"
+	settings.SimilarityThreshold = 0.5
+
+	db, err := engine.NewSqlite(filepath.Join(tmpDir, "tg-spam.db"), "gr1")
+	require.NoError(t, err)
+	samplesStore, err := storage.NewSamples(ctx, db)
+	require.NoError(t, err)
+	for _, s := range []string{"win free money now click here", "free crypto money click link now",
+		"earn money fast click here now", "free money giveaway click now"} {
+		require.NoError(t, samplesStore.Add(ctx, storage.SampleTypeSpam, storage.SampleOriginPreset, s))
+	}
+	for _, s := range []string{"hello everyone how are you today", "see you at the meeting tomorrow",
+		"thanks for the help with the code", "how are you doing with the project today"} {
+		require.NoError(t, samplesStore.Add(ctx, storage.SampleTypeHam, storage.SampleOriginUser, s))
+	}
+	dictStore, err := storage.NewDictionary(ctx, db)
+	require.NoError(t, err)
+	require.NoError(t, dictStore.Add(ctx, storage.DictionaryTypeStopPhrase, "buy followers"))
+	require.NoError(t, db.Close())
+
+	spamFile := filepath.Join(tmpDir, "spam.txt")
+	require.NoError(t, os.WriteFile(spamFile, []byte("free money click here now\n\nbuy followers cheap\n"), 0o600))
+	hamFile := filepath.Join(tmpDir, "ham.txt")
+	require.NoError(t, os.WriteFile(hamFile, []byte("how are you today everyone\n"), 0o600))
+	corpusFile := filepath.Join(tmpDir, "corpus.jsonl")
+	require.NoError(t, os.WriteFile(corpusFile, []byte(`{"msg": "win free money click now", "spam": true}`+"\n"+
+		`{"msg": "thanks for the meeting today", "user_name": "user1", "spam": false}`+"\n"+
+		`{"msg": "see you tomorrow", "spam": true}`+"\n"), 0o600))
+
+	t.Run("text files and corpus", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := evaluate(ctx, settings, evaluateOptions{Spam: spamFile, Ham: hamFile, Corpus: corpusFile}, &buf)
+		require.NoError(t, err)
+		out := buf.String()
+		assert.Contains(t, out, "corpus, 6 messages")
+		assert.Contains(t, out, "precision: 1.0000, recall: 0.7500, f1: 0.8571, accuracy: 0.8333")
+		assert.Regexp(t, `actual spam\s+3\s+1\n`, out)
+		assert.Regexp(t, `actual ham\s+0\s+2\n`, out)
+		assert.Regexp(t, `classifier\s+3\s+0\s+0\s+0\n`, out)
+		assert.Regexp(t, `stopword\s+1\s+0\s+0\s+0\n`, out)
+		assert.NotContains(t, out, "cross validation")
+	})
+
+	t.Run("cross validation", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, evaluate(ctx, settings, evaluateOptions{Folds: 2}, &buf))
+		out := buf.String()
+		assert.Contains(t, out, "2-fold cross validation, 4 spam and 4 ham samples")
+		assert.Regexp(t, `actual spam\s+4\s+0\n`, out)
+		assert.Regexp(t, `actual ham\s+0\s+4\n`, out)
+		assert.NotContains(t, out, "corpus")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tbl := []struct {
+			name string
+			opts evaluateOptions
+			err  string
+		}{
+			{name: "nothing to evaluate", opts: evaluateOptions{}, err: "nothing to evaluate"},
+			{name: "one fold", opts: evaluateOptions{Folds: 1}, err: "invalid number of folds 1"},
+			{name: "too many folds", opts: evaluateOptions{Folds: 5}, err: "not enough samples for 5 folds"},
+			{name: "missing file", opts: evaluateOptions{Spam: filepath.Join(tmpDir, "none.txt")}, err: "can't open"},
+			{name: "bad corpus", opts: evaluateOptions{Corpus: spamFile}, err: "can't unmarshal"},
+		}
+		for _, tt := range tbl {
+			t.Run(tt.name, func(t *testing.T) {
+				err := evaluate(ctx, settings, tt.opts, &bytes.Buffer{})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			})
+		}
+	})
+
+	t.Run("no samples", func(t *testing.T) {
+		s := *settings
+		s.Transient.DataBaseURL = "empty.db"
+		err := evaluate(ctx, &s, evaluateOptions{Ham: hamFile}, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no spam or ham samples")
+	})
+}
+
+func TestEvalSettings(t *testing.T) {
+	settings := makeTestSettings()
+	settings.CAS.API = "https://api.cas.chat"
+	settings.OpenAI.Token, settings.OpenAI.APIBase = "openai-token", "http://localhost:1234/v1"
+	settings.Gemini.Token = "gemini-token"
+	settings.LLM.Providers = []config.LLMProviderSettings{{Name: "local", Type: "ollama", Model: "llama3"}}
+	settings.SimilarityThreshold = 0.7
+
+	t.Run("external checks disabled by default", func(t *testing.T) {
+		res := evalSettings(settings, false)
+		assert.Empty(t, res.CAS.API)
+		assert.False(t, res.IsOpenAIEnabled())
+		assert.Empty(t, res.Gemini.Token)
+		assert.Empty(t, res.LLM.Providers)
+		assert.InDelta(t, 0.7, res.SimilarityThreshold, 0.0001, "other settings kept")
+
+		assert.Equal(t, "https://api.cas.chat", settings.CAS.API, "original settings not changed")
+		assert.Len(t, settings.LLM.Providers, 1, "original settings not changed")
+
+		detector := makeDetector(res)
+		_, cr := detector.Check(spamcheck.Request{Msg: "some message to check", UserID: "123", CheckOnly: true})
+		for _, r := range cr {
+			assert.NotContains(t, []string{"cas", "openai", "gemini", "local"}, r.Name)
+		}
+	})
+
+	t.Run("external checks enabled", func(t *testing.T) {
+		res := evalSettings(settings, true)
+		assert.Same(t, settings, res)
+	})
+}
+
+func TestEvalReport(t *testing.T) {
+	detector := tgspam.NewDetector(tgspam.Config{MaxAllowedEmoji: 1})
+	rep := newEvalReport()
+	rep.check(detector, labeledRequest{Request: spamcheck.Request{Msg: "hi 😀😀😀"}, Spam: true})
+	rep.check(detector, labeledRequest{Request: spamcheck.Request{Msg: "ok 👍👍"}, Spam: false})
+	rep.check(detector, labeledRequest{Request: spamcheck.Request{Msg: "spam without emoji"}, Spam: true})
+	rep.check(detector, labeledRequest{Request: spamcheck.Request{Msg: "just ham"}, Spam: false})
+
+	assert.Equal(t, 1, rep.tp)
+	assert.Equal(t, 1, rep.fp)
+	assert.Equal(t, 1, rep.fn)
+	assert.Equal(t, 1, rep.tn)
+	assert.InDelta(t, 0.5, rep.precision(), 0.0001)
+	assert.InDelta(t, 0.5, rep.recall(), 0.0001)
+	assert.InDelta(t, 0.5, rep.f1(), 0.0001)
+	assert.Equal(t, &checkStats{spam: 1, ham: 1, single: 1}, rep.checks["emoji"])
+
+	empty := newEvalReport()
+	assert.Zero(t, empty.f1(), "no division by zero")
+}
@@ -14,6 +14,11 @@ import (
 	"github.com/hashicorp/go-multierror"
 
 	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/metrics"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam"
 )
 
 //go:generate moq --out mocks/warnings.go --pkg mocks --with-resets --skip-ensure . Warnings
@@ -39,9 +44,14 @@ type admin struct {
 	warnMsg                string
 	aggressiveCleanup      bool
 	aggressiveCleanupLimit int
-	warnings               Warnings      // storage for /warn records, used by DirectWarnReport auto-ban path
-	warnThreshold          int           // auto-ban after N /warn within warnWindow (0 disables auto-ban)
-	warnWindow             time.Duration // sliding window for counting warns
+	warnings               Warnings       // storage for /warn records, used by DirectWarnReport auto-ban path
+	warnThreshold          int            // auto-ban after N /warn within warnWindow (0 disables auto-ban)
+	warnWindow             time.Duration  // sliding window for counting warns
+	imageHashes            ImageHashes    // spam image hashes storage, photos reported as spam are added to it
+	reviews                ReviewQueue    // review queue of quarantined suspicious messages, nil disables quarantine
+	reputation             Reputation     // per-user history, warnings are recorded to it if set
+	events                 EventPublisher // publishes bans, unbans, warnings and added samples if set
+	auditLog               AuditLog       // records unbans, /spam, /ban and /warn reports if set
 }
 
 const (
@@ -50,8 +60,9 @@ const (
 	infoPrefix         = "!"
 )
 
-// ReportBan a ban message to admin chat with a button to unban the user
-func (a *admin) ReportBan(banUserStr string, msg *bot.Message) {
+// ReportBan a ban message to admin chat with a button to unban the user.
+// The punishment defines the action shown, with the offense history if the punishment ladder is enabled.
+func (a *admin) ReportBan(banUserStr string, msg *bot.Message, p punishment) {
 	log.Printf("[DEBUG] report to admin chat, ban msgsData for %s, group: %d", banUserStr, a.adminChatID)
 	msgText := msg.Text
 	if msg.Quote != "" {
@@ -72,15 +83,21 @@ func (a *admin) ReportBan(banUserStr string, msg *bot.Message) {
 
 	// for channels, use t.me link (tg://user doesn't resolve negative IDs);
 	// for regular users, keep the standard tg://user link
-	banLine := fmt.Sprintf("**%spermanently banned [%s](tg://user?id=%d)**",
-		would, escapeMarkDownV1Text(banUserStr), msg.From.ID)
+	action := p.action(msg.SenderChat.ID != 0)
+	banLine := fmt.Sprintf("**%s%s [%s](tg://user?id=%d)**",
+		would, action, escapeMarkDownV1Text(banUserStr), msg.From.ID)
 	switch {
 	case msg.SenderChat.ID != 0 && msg.SenderChat.UserName != "":
-		banLine = fmt.Sprintf("**%spermanently banned [%s](https://t.me/%s)**",
-			would, escapeMarkDownV1Text(banUserStr), msg.SenderChat.UserName)
+		banLine = fmt.Sprintf("**%s%s [%s](https://t.me/%s)**",
+			would, action, escapeMarkDownV1Text(banUserStr), msg.SenderChat.UserName)
 	case msg.SenderChat.ID != 0:
-		banLine = fmt.Sprintf("**%spermanently banned %s (%d)**",
-			would, escapeMarkDownV1Text(banUserStr), msg.SenderChat.ID)
+		banLine = fmt.Sprintf("**%s%s %s (%d)**",
+			would, action, escapeMarkDownV1Text(banUserStr), msg.SenderChat.ID)
+	}
+	// offense history goes right after the ban line, getCleanMessage skips the first two lines
+	// and trims the empty one left before the original message
+	if history := p.historyLine(); history != "" {
+		banLine += "\n_" + escapeMarkDownV1Text(history) + "_"
 	}
 	forwardMsg := fmt.Sprintf("%s\n\n%s\n\n", banLine, text)
 	if err := a.sendWithUnbanMarkup(forwardMsg, "change ban", callbackUser, msg.ID, a.adminChatID); err != nil {
@@ -106,6 +123,28 @@ func (a *admin) ReportReactionBan(banUserStr string, user bot.User) {
 	}
 }
 
+// ReportSuspicious sends a message marked as suspicious by the weighted scoring to admin chat for review.
+// The message is not deleted and the user is not banned, the checks contributed to the score are listed.
+func (a *admin) ReportSuspicious(userStr string, msg *bot.Message, checks []spamcheck.Response) {
+	msgText := msg.Text
+	if msg.Quote != "" {
+		msgText = msg.Text + "\n" + msg.Quote
+	}
+	text := strings.ReplaceAll(escapeMarkDownV1Text(msgText), "\n", " ")
+
+	scored := make([]string, 0, len(checks))
+	for _, c := range checks {
+		if c.Score != 0 || c.Suspicious {
+			scored = append(scored, escapeMarkDownV1Text(c.String()))
+		}
+	}
+	forwardMsg := fmt.Sprintf("**suspicious message from [%s](tg://user?id=%d)**\n\n%s\n\n_%s_",
+		escapeMarkDownV1Text(userStr), msg.From.ID, text, strings.Join(scored, "; "))
+	if err := send(tbapi.NewMessage(a.adminChatID, forwardMsg), a.tbAPI); err != nil {
+		log.Printf("[WARN] failed to send suspicious message report, %v", err)
+	}
+}
+
 // MsgHandler handles messages received on admin chat. this is usually forwarded spam failed
 // to be detected by the bot. we need to update spam filter with this message and ban the user.
 // the user will be banned even in training mode, but not in the dry mode.
@@ -221,11 +260,14 @@ func (a *admin) MsgHandler(update tbapi.Update) error {
 	} else {
 		banReq := banRequest{duration: bot.PermanentBanDuration, userID: info.UserID,
 			channelID: channelIDFromCallback(info.UserID),
-			chatID:    a.primChatID, tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: username}
+			chatID:    a.primChatID, tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: username,
+			events: a.events, source: hooks.SourceAdmin}
 		if err := banUserOrChannel(banReq); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("failed to ban user %d: %w", info.UserID, err))
 		}
 	}
+	recordAudit(context.TODO(), a.auditLog, update.Message.From, storage.AuditRecord{Action: storage.AuditSpam,
+		UserID: info.UserID, UserName: info.UserName, MsgID: info.MsgID, Text: msgTxt, Reason: "forwarded to admin chat"})
 
 	if err := errs.ErrorOrNil(); err != nil {
 		return fmt.Errorf("spam notification failed: %w", err)
@@ -289,10 +331,12 @@ func (a *admin) msgHandlerFallback(update tbapi.Update, fwdID int64, username, m
 
 	// ban user (no message deletion - we don't have the message ID from primary chat)
 	banReq := banRequest{duration: bot.PermanentBanDuration, userID: fwdID, chatID: a.primChatID,
-		tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: username}
+		tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: username, events: a.events, source: hooks.SourceAdmin}
 	if err := banUserOrChannel(banReq); err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("failed to ban user %d: %w", fwdID, err))
 	}
+	recordAudit(context.TODO(), a.auditLog, update.Message.From, storage.AuditRecord{Action: storage.AuditSpam,
+		UserID: fwdID, UserName: username, Text: msgTxt, Reason: "forwarded to admin chat, message not found"})
 
 	// warn admin that the original message must be deleted manually
 	snippet := msgTxt
@@ -385,8 +429,18 @@ func (a *admin) DirectWarnReport(update tbapi.Update) error {
 		warnTargetName, a.warnMsg)
 	if err := send(tbapi.NewMessage(a.primChatID, escapeMarkDownV1Text(warnMsg)), a.tbAPI); err != nil {
 		errs = multierror.Append(errs, fmt.Errorf("failed to send warning to main chat: %w", err))
+	} else {
+		metrics.Actions.Inc("warn")
 	}
 
+	if target, ok := a.resolveWarnTarget(origMsg); ok {
+		recordReputation(context.TODO(), a.reputation, target.userID, target.userName, storage.RepWarning)
+		publishEvent(context.TODO(), a.events, hooks.Event{Type: hooks.EventWarn, Source: hooks.SourceAdmin, ChatID: a.primChatID,
+			UserID: target.userID, UserName: target.userName, ChannelID: target.channelID, Text: origMsg.Text,
+			Details: "warned by " + update.Message.From.UserName})
+		recordAudit(context.TODO(), a.auditLog, update.Message.From, storage.AuditRecord{Action: storage.AuditWarn,
+			UserID: target.userID, UserName: target.userName, MsgID: origMsg.MessageID, Text: msgTxt})
+	}
 	if banErr := a.trackWarnAndMaybeBan(origMsg); banErr != nil {
 		errs = multierror.Append(errs, banErr)
 	}
@@ -476,6 +530,8 @@ func (a *admin) executeWarnBan(target warnTarget, count int) error {
 		training:  a.trainingMode,
 		userName:  target.userName,
 		restrict:  a.softBan,
+		events:    a.events,
+		source:    hooks.SourceWarnings,
 	}
 	if err := banUserOrChannel(banReq); err != nil {
 		return fmt.Errorf("failed to auto-ban %q (%d) after %d warns: %w",
@@ -583,6 +639,26 @@ func (a *admin) deleteUserMessages(userID int64) (deleted int, err error) {
 	return deleted, nil
 }
 
+// addImageHash stores the perceptual hash of the message photo in the spam image hashes blocklist
+func (a *admin) addImageHash(msg *tbapi.Message, userID int64, userName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), imageDownloadTimeout)
+	defer cancel()
+
+	photo := msg.Photo[len(msg.Photo)-1] // the highest quality photo
+	img, err := downloadFile(ctx, a.tbAPI, photo.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to download photo %s: %w", photo.FileID, err)
+	}
+	hash, err := tgspam.ImageHash(img)
+	if err != nil {
+		return fmt.Errorf("failed to hash photo %s: %w", photo.FileID, err)
+	}
+	if err := a.imageHashes.Add(ctx, hash, userID, userName); err != nil {
+		return fmt.Errorf("failed to store image hash: %w", err)
+	}
+	return nil
+}
+
 // directReport handles messages replayed with "/spam" or "spam", or "/ban" or "ban" by admin
 func (a *admin) directReport(update tbapi.Update, updateSamples bool) error {
 	logFrom := update.Message.ReplyToMessage.From.UserName
@@ -687,6 +763,13 @@ func (a *admin) directReport(update tbapi.Update, updateSamples bool) error {
 		}
 	}
 
+	// add the spam photo to the image hashes blocklist, failure is not critical for the rest of the report
+	if updateSamples && a.imageHashes != nil && len(origMsg.Photo) > 0 {
+		if err := a.addImageHash(origMsg, removeID, logFrom); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
 	// delete original message
 	_, err := a.tbAPI.Request(tbapi.DeleteMessageConfig{BaseChatMessage: tbapi.BaseChatMessage{
 		MessageID:  origMsg.MessageID,
@@ -721,12 +804,19 @@ func (a *admin) directReport(update tbapi.Update, updateSamples bool) error {
 	} else {
 		// ban user or channel
 		banReq := banRequest{duration: bot.PermanentBanDuration, userID: origMsg.From.ID, channelID: channelID,
-			chatID: a.primChatID, tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: username}
+			chatID: a.primChatID, tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: username,
+			events: a.events, source: hooks.SourceAdmin}
 
 		if err := banUserOrChannel(banReq); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("failed to ban user %d: %w", origMsg.From.ID, err))
 		}
 	}
+	auditAction := storage.AuditBan
+	if updateSamples {
+		auditAction = storage.AuditSpam
+	}
+	recordAudit(context.TODO(), a.auditLog, update.Message.From, storage.AuditRecord{Action: auditAction,
+		UserID: displayID, UserName: displayName, MsgID: origMsg.MessageID, Text: msgTxt})
 
 	// aggressive cleanup - delete all messages from the spammer (non-blocking)
 	// use channel ID for lookup when the message was sent on behalf of a channel;
@@ -791,6 +881,15 @@ func (a *admin) InlineCallbackHandler(query *tbapi.CallbackQuery) error {
 		return nil
 	}
 
+	// if callback msgsData starts with "Q+" or "Q-", admin resolved a quarantined message
+	if strings.HasPrefix(callbackData, reviewApprovePrefix) || strings.HasPrefix(callbackData, reviewBanPrefix) {
+		ctx := context.TODO()
+		if strings.HasPrefix(callbackData, reviewApprovePrefix) {
+			return a.callbackReviewApprove(ctx, query)
+		}
+		return a.callbackReviewBan(ctx, query)
+	}
+
 	// if callback msgsData starts with "!", we should show a spam info details
 	if strings.HasPrefix(callbackData, infoPrefix) {
 		if err := a.callbackShowInfo(query); err != nil {
@@ -877,12 +976,16 @@ func (a *admin) callbackBanConfirmed(query *tbapi.CallbackQuery) error {
 			userName = ""
 		}
 		banReq := banRequest{duration: bot.PermanentBanDuration, userID: userID, channelID: channelIDFromCallback(userID),
-			chatID: a.primChatID, tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: userName, restrict: false}
+			chatID: a.primChatID, tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, userName: userName, restrict: false,
+			events: a.events, source: hooks.SourceAdmin}
 		if err := banUserOrChannel(banReq); err != nil {
 			return fmt.Errorf("failed to ban user %d: %w", userID, err)
 		}
 	}
 
+	bannedName, _ := a.extractUsername(query.Message.Text) // the name is optional for the audit record
+	recordAudit(context.TODO(), a.auditLog, query.From, storage.AuditRecord{Action: storage.AuditBanConfirm,
+		UserID: userID, UserName: bannedName, MsgID: msgID})
 	return nil
 }
 
@@ -900,7 +1003,7 @@ func (a *admin) callbackUnbanConfirmed(query *tbapi.CallbackQuery) error {
 		return fmt.Errorf("failed to send callback response: %w", err)
 	}
 
-	userID, _, err := parseCallbackData(callbackData)
+	userID, msgID, err := parseCallbackData(callbackData)
 	if err != nil {
 		return fmt.Errorf("failed to parse callback msgsData %q: %w", callbackData, err)
 	}
@@ -918,15 +1021,17 @@ func (a *admin) callbackUnbanConfirmed(query *tbapi.CallbackQuery) error {
 
 	// unban user or channel if not in training mode (in training mode, the ban is not applied automatically)
 	if !a.trainingMode {
-		if userID < 0 {
-			// negative ID indicates a channel - use channel-specific unban
-			if uerr := a.unbanChannel(userID); uerr != nil {
-				return uerr
-			}
-		} else {
-			if uerr := a.unban(userID); uerr != nil {
-				return uerr
-			}
+		var uerr error
+		switch {
+		case userID < 0: // negative ID indicates a channel - use channel-specific unban
+			uerr = a.unbanChannel(userID)
+		case isRestrictionNotice(query.Message.Text): // restricted by the punishment ladder, not banned
+			uerr = a.unrestrict(userID)
+		default:
+			uerr = a.unban(userID)
+		}
+		if uerr != nil {
+			return uerr
 		}
 	}
 
@@ -939,6 +1044,8 @@ func (a *admin) callbackUnbanConfirmed(query *tbapi.CallbackQuery) error {
 	if err := a.bot.AddApprovedUser(userID, name); err != nil {
 		return fmt.Errorf("failed to add user %d to approved list: %w", userID, err)
 	}
+	recordAudit(context.TODO(), a.auditLog, query.From, storage.AuditRecord{Action: storage.AuditUnban,
+		UserID: userID, UserName: name, MsgID: msgID})
 
 	// create the original forwarded message with new indication of "unbanned" and an empty keyboard
 	updText := query.Message.Text + fmt.Sprintf("\n\n_unbanned by %s in %v_", query.From.UserName, sinceQuery(query))
@@ -969,26 +1076,7 @@ func (a *admin) callbackUnbanConfirmed(query *tbapi.CallbackQuery) error {
 
 func (a *admin) unban(userID int64) error {
 	if a.softBan { // soft ban, just drop restrictions
-		_, err := a.tbAPI.Request(tbapi.RestrictChatMemberConfig{
-			ChatMemberConfig: tbapi.ChatMemberConfig{UserID: userID, ChatConfig: tbapi.ChatConfig{ChatID: a.primChatID}},
-			Permissions: &tbapi.ChatPermissions{
-				CanSendMessages:      true,
-				CanSendAudios:        true,
-				CanSendDocuments:     true,
-				CanSendPhotos:        true,
-				CanSendVideos:        true,
-				CanSendVideoNotes:    true,
-				CanSendVoiceNotes:    true,
-				CanSendOtherMessages: true,
-				CanChangeInfo:        true,
-				CanInviteUsers:       true,
-				CanPinMessages:       true,
-			},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to drop restrictions for user %d: %w", userID, err)
-		}
-		return nil
+		return a.unrestrict(userID)
 	}
 
 	// hard ban, unban the user for real
@@ -1002,6 +1090,34 @@ func (a *admin) unban(userID int64) error {
 	if err != nil {
 		return fmt.Errorf("failed to unban user %d: %w", userID, err)
 	}
+	metrics.Actions.Inc("unban")
+	a.publishUnban(userID, 0)
+	return nil
+}
+
+// unrestrict drops restrictions of the user restricted in soft ban mode or by the punishment ladder
+func (a *admin) unrestrict(userID int64) error {
+	_, err := a.tbAPI.Request(tbapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tbapi.ChatMemberConfig{UserID: userID, ChatConfig: tbapi.ChatConfig{ChatID: a.primChatID}},
+		Permissions: &tbapi.ChatPermissions{
+			CanSendMessages:      true,
+			CanSendAudios:        true,
+			CanSendDocuments:     true,
+			CanSendPhotos:        true,
+			CanSendVideos:        true,
+			CanSendVideoNotes:    true,
+			CanSendVoiceNotes:    true,
+			CanSendOtherMessages: true,
+			CanChangeInfo:        true,
+			CanInviteUsers:       true,
+			CanPinMessages:       true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to drop restrictions for user %d: %w", userID, err)
+	}
+	metrics.Actions.Inc("unrestrict")
+	a.publishUnban(userID, 0)
 	return nil
 }
 
@@ -1014,9 +1130,23 @@ func (a *admin) unbanChannel(channelID int64) error {
 	if err != nil {
 		return fmt.Errorf("failed to unban channel %d: %w", channelID, err)
 	}
+	metrics.Actions.Inc("unban_channel")
+	a.publishUnban(0, channelID)
 	return nil
 }
 
+// publishUnban publishes the unban event of the user or channel
+func (a *admin) publishUnban(userID, channelID int64) {
+	if a.events == nil {
+		return // skip user name lookup
+	}
+	e := hooks.Event{Type: hooks.EventUnban, Source: hooks.SourceAdmin, ChatID: a.primChatID, UserID: userID, ChannelID: channelID}
+	if userID != 0 {
+		e.UserName = a.locator.UserNameByID(context.TODO(), userID)
+	}
+	publishEvent(context.TODO(), a.events, e)
+}
+
 // callbackShowInfo handles the callback when user asks for spam detection details for the ban.
 // callback data: !userID:msgID
 func (a *admin) callbackShowInfo(query *tbapi.CallbackQuery) error {
@@ -1072,6 +1202,8 @@ func (a *admin) deleteAndBan(userID int64, msgID int) error {
 		dry:       a.dry,
 		training:  false, // reset training flag, ban for real
 		userName:  userName,
+		events:    a.events,
+		source:    hooks.SourceAdmin,
 	}
 
 	// check if user is super and don't ban if so
@@ -1190,9 +1322,9 @@ func (a *admin) extractUsername(text string) (string, error) {
 		return matches[1], nil
 	}
 
-	// regex for plain channel format: permanently banned channelname (-100999888)
-	// uses (.+?) to handle multi-word channel titles like "Spam News Channel"
-	plainChannelRegex := regexp.MustCompile(`permanently banned (.+?) \(-?\d+\)`)
+	// regex for plain channel format: permanently banned channelname (-100999888), or "banned for 24h0m0s"
+	// with the punishment ladder. uses (.+?) to handle multi-word channel titles like "Spam News Channel"
+	plainChannelRegex := regexp.MustCompile(`(?:permanently banned|banned for \S+) (.+?) \(-?\d+\)`)
 	if matches := plainChannelRegex.FindStringSubmatch(text); len(matches) > 1 {
 		return matches[1], nil
 	}
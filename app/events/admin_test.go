@@ -3,6 +3,8 @@ package events
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -38,7 +40,7 @@ func TestAdmin_reportBan(t *testing.T) {
 
 	t.Run("normal user name", func(t *testing.T) {
 		mockAPI.ResetCalls()
-		adm.ReportBan("testUser", msg)
+		adm.ReportBan("testUser", msg, permanentBan)
 
 		require.Len(t, mockAPI.SendCalls(), 1)
 		t.Logf("sent text: %+v", mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text)
@@ -52,7 +54,7 @@ func TestAdmin_reportBan(t *testing.T) {
 
 	t.Run("name with md chars", func(t *testing.T) {
 		mockAPI.ResetCalls()
-		adm.ReportBan("test_User", msg)
+		adm.ReportBan("test_User", msg, permanentBan)
 
 		require.Len(t, mockAPI.SendCalls(), 1)
 		t.Logf("sent text: %+v", mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text)
@@ -71,7 +73,7 @@ func TestAdmin_reportBan(t *testing.T) {
 			Text:  "Спасибо!!",
 			Quote: "Бесплатный VPN для Telegram",
 		}
-		adm.ReportBan("spammer", msgWithQuote)
+		adm.ReportBan("spammer", msgWithQuote, permanentBan)
 
 		require.Len(t, mockAPI.SendCalls(), 1)
 		sentText := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text
@@ -81,6 +83,54 @@ func TestAdmin_reportBan(t *testing.T) {
 	})
 }
 
+func TestAdmin_reportBanPunishment(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil }}
+	adm := admin{tbAPI: mockAPI, adminChatID: 123}
+	p := punishment{duration: 10 * time.Minute, restrict: true, offense: 2, window: 720 * time.Hour,
+		history: []storage.Offense{{Checks: "stop_word", Action: "restricted for 10m0s",
+			CreatedAt: time.Date(2026, 10, 1, 9, 5, 0, 0, time.Local)}}}
+
+	t.Run("user", func(t *testing.T) {
+		mockAPI.ResetCalls()
+		adm.ReportBan("spammer", &bot.Message{From: bot.User{ID: 456}, Text: "buy now"}, p)
+		require.Len(t, mockAPI.SendCalls(), 1)
+		text := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text
+		assert.Equal(t, "**restricted for 10m0s [spammer](tg://user?id=456)**\n"+
+			"_offense #2 within 720h0m0s, previous: 2026-10-01 09:05 restricted for 10m0s (stop\\_word)_\n\nbuy now\n\n", text)
+
+		clean, err := adm.getCleanMessage(text)
+		require.NoError(t, err)
+		assert.Equal(t, "buy now", clean, "history line is not a part of the message")
+	})
+
+	t.Run("channel", func(t *testing.T) {
+		mockAPI.ResetCalls()
+		msg := &bot.Message{From: bot.User{ID: 136817688}, SenderChat: bot.SenderChat{ID: -100999888}, Text: "spam"}
+		adm.ReportBan("Some Channel", msg, p)
+		require.Len(t, mockAPI.SendCalls(), 1)
+		assert.Contains(t, mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text, "**banned for 10m0s Some Channel (-100999888)**")
+	})
+}
+
+func TestAdmin_ReportSuspicious(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil }}
+	adm := admin{tbAPI: mockAPI, adminChatID: 123}
+
+	msg := &bot.Message{From: bot.User{ID: 456}, Text: "buy *now*", Quote: "quoted"}
+	adm.ReportSuspicious("some_user", msg, []spamcheck.Response{
+		{Name: "classifier", Details: "probability of spam: 60.00%", Score: 1.2},
+		{Name: "emoji", Details: "0/2"},
+		{Name: "score", Details: "1.20/2.00, suspicious", Score: 1.2, Suspicious: true},
+	})
+
+	require.Len(t, mockAPI.SendCalls(), 1)
+	sent := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig)
+	assert.Equal(t, int64(123), sent.ChatID)
+	assert.Equal(t, "**suspicious message from [some\\_user](tg://user?id=456)**\n\nbuy \\*now\\* quoted\n\n"+
+		"_classifier: ham, probability of spam: 60.00%, score: 1.20; score: ham, 1.20/2.00, suspicious, score: 1.20_", sent.Text)
+	assert.Nil(t, sent.ReplyMarkup, "no buttons, review only")
+}
+
 func TestAdmin_getCleanMessage(t *testing.T) {
 	a := &admin{}
 
@@ -183,6 +233,8 @@ func TestAdmin_extractUsername(t *testing.T) {
 		{name: "reaction rendered", banMessage: "permanently banned @spammer (42) reaction spammer", expectedResult: "@spammer"},
 		{name: "reaction rendered dry", banMessage: "[dry run] would have permanently banned @spammer (42) reaction spammer", expectedResult: "@spammer"},
 		{name: "reaction rendered training", banMessage: "[training] would have permanently banned @spammer (42) reaction spammer", expectedResult: "@spammer"},
+		{name: "plain channel with ladder", banMessage: "**banned for 24h0m0s Spam News (-100999888)**\n_offense #2_\n\ntext",
+			expectedResult: "Spam News"},
 		{name: "invalid format", banMessage: "permanently banned John_Doe some message text", expectError: true},
 	}
 
@@ -212,7 +264,7 @@ func TestAdmin_dryModeForwardMessage(t *testing.T) {
 	}
 	msg := &bot.Message{}
 
-	adm.ReportBan("testUser", msg)
+	adm.ReportBan("testUser", msg, permanentBan)
 	assert.Contains(t, mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text, "would have permanently banned [testUser]")
 }
 
@@ -232,7 +284,7 @@ func TestAdmin_reportBanChannel(t *testing.T) {
 			SenderChat: bot.SenderChat{ID: -100999888, UserName: "spamchannel"},
 			Text:       "spam from channel",
 		}
-		adm.ReportBan("spamchannel", msg)
+		adm.ReportBan("spamchannel", msg, permanentBan)
 
 		require.Len(t, mockAPI.SendCalls(), 1)
 		sentText := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text
@@ -253,7 +305,7 @@ func TestAdmin_reportBanChannel(t *testing.T) {
 			SenderChat: bot.SenderChat{ID: -100999888},
 			Text:       "spam from channel",
 		}
-		adm.ReportBan("Some Channel", msg)
+		adm.ReportBan("Some Channel", msg, permanentBan)
 
 		require.Len(t, mockAPI.SendCalls(), 1)
 		sentText := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text
@@ -267,7 +319,7 @@ func TestAdmin_reportBanChannel(t *testing.T) {
 			From: bot.User{ID: 456, Username: "spammer"},
 			Text: "spam from user",
 		}
-		adm.ReportBan("spammer", msg)
+		adm.ReportBan("spammer", msg, permanentBan)
 
 		require.Len(t, mockAPI.SendCalls(), 1)
 		sentText := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text
@@ -466,6 +518,30 @@ func TestAdmin_DirectCommands(t *testing.T) {
 		assert.Contains(t, warnMsg.Text, "@user please follow our rules")
 	})
 
+	t.Run("recorded to audit log", func(t *testing.T) {
+		_, _, adm, teardown := setupTest()
+		defer teardown()
+		auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+		adm.auditLog = auditLog
+
+		require.NoError(t, adm.DirectSpamReport(createReplyUpdate("admin", 111, "spammer", 222, "spam message text")))
+		require.NoError(t, adm.DirectBanReport(createReplyUpdate("admin", 111, "spammer", 222, "spam message text")))
+		require.NoError(t, adm.DirectWarnReport(createReplyUpdate("admin2", 112, "user", 333, "rude message")))
+
+		require.Len(t, auditLog.AddCalls(), 3)
+		assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceTelegram, Actor: "admin", ActorID: 111,
+			Action: storage.AuditSpam, UserID: 222, UserName: "spammer", MsgID: 999, Text: "spam message text"},
+			auditLog.AddCalls()[0].Rec)
+		assert.Equal(t, storage.AuditBan, auditLog.AddCalls()[1].Rec.Action)
+		assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceTelegram, Actor: "admin2", ActorID: 112,
+			Action: storage.AuditWarn, UserID: 333, UserName: "user", MsgID: 999, Text: "rude message"},
+			auditLog.AddCalls()[2].Rec)
+
+		adm.dry = true
+		require.NoError(t, adm.DirectSpamReport(createReplyUpdate("admin", 111, "spammer", 222, "spam message text")))
+		assert.Len(t, auditLog.AddCalls(), 3, "nothing done in dry mode")
+	})
+
 	t.Run("DirectSpamReport_ChannelMessage", func(t *testing.T) {
 		mockAPI, botMock, adm, teardown := setupTest()
 		defer teardown()
@@ -755,6 +831,25 @@ func TestAdmin_DirectWarnReport_AutoBan(t *testing.T) {
 		assert.Equal(t, 0, countMemberBans(mockAPI))
 	})
 
+	t.Run("warning recorded to reputation even if auto-ban disabled", func(t *testing.T) {
+		_, _, adm := setupTest()
+		adm.warnThreshold = 0
+		reputation := &mocks.ReputationMock{IncFunc: func(ctx context.Context, userID int64, userName string,
+			ev storage.ReputationEvent) error {
+			return nil
+		}}
+		adm.reputation = reputation
+
+		require.NoError(t, adm.DirectWarnReport(createReplyUpdate("user", 222)))
+		require.Len(t, reputation.IncCalls(), 1)
+		assert.Equal(t, int64(222), reputation.IncCalls()[0].UserID)
+		assert.Equal(t, storage.RepWarning, reputation.IncCalls()[0].Ev)
+
+		require.NoError(t, adm.DirectWarnReport(createChannelReplyUpdate(-100500, "chan")))
+		require.Len(t, reputation.IncCalls(), 2)
+		assert.Equal(t, int64(-100500), reputation.IncCalls()[1].UserID, "channel warned, not the shared bot user")
+	})
+
 	t.Run("negative threshold is treated as disabled", func(t *testing.T) {
 		mockAPI, warningsMock, adm := setupTest()
 		adm.warnThreshold = -1
@@ -1420,6 +1515,63 @@ func TestAdmin_InlineCallbacks(t *testing.T) {
 		assert.Equal(t, "Spam News Channel", botMock.AddApprovedUserCalls()[0].Name)
 	})
 
+	t.Run("callbackUnbanConfirmed_restricted_by_ladder", func(t *testing.T) {
+		mockAPI, _, adm, _ := setupCallback(false, false)
+		botMock := &mocks.BotMock{
+			UpdateHamFunc:       func(msg string) error { return nil },
+			AddApprovedUserFunc: func(id int64, name string) error { return nil },
+		}
+		adm.bot = botMock
+
+		query := &tbapi.CallbackQuery{
+			ID:   "test-callback-id",
+			Data: "777:999",
+			Message: &tbapi.Message{
+				MessageID: 789,
+				Chat:      tbapi.Chat{ID: 456},
+				Text:      "restricted for 10m0s spammer\noffense #1 within 720h0m0s\n\nsome message",
+				From:      &tbapi.User{UserName: "bot"},
+			},
+			From: &tbapi.User{UserName: "admin", ID: 111},
+		}
+		require.NoError(t, adm.callbackUnbanConfirmed(query))
+
+		var restrictions []tbapi.RestrictChatMemberConfig
+		for _, call := range mockAPI.RequestCalls() {
+			_, isUnban := call.C.(tbapi.UnbanChatMemberConfig)
+			assert.False(t, isUnban, "restricted user is not unbanned")
+			if r, ok := call.C.(tbapi.RestrictChatMemberConfig); ok {
+				restrictions = append(restrictions, r)
+			}
+		}
+		require.Len(t, restrictions, 1)
+		assert.Equal(t, int64(777), restrictions[0].UserID)
+		assert.True(t, restrictions[0].Permissions.CanSendMessages)
+
+		require.Len(t, botMock.UpdateHamCalls(), 1)
+		assert.Equal(t, "some message", botMock.UpdateHamCalls()[0].Msg)
+		require.Len(t, botMock.AddApprovedUserCalls(), 1)
+	})
+
+	t.Run("callbackUnbanConfirmed_audit_log", func(t *testing.T) {
+		_, _, adm, _ := setupCallback(false, false)
+		adm.bot = &mocks.BotMock{
+			UpdateHamFunc:       func(msg string) error { return nil },
+			AddApprovedUserFunc: func(id int64, name string) error { return nil },
+		}
+		auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error {
+			return fmt.Errorf("db error") // logged only
+		}}
+		adm.auditLog = auditLog
+
+		query := &tbapi.CallbackQuery{ID: "test-callback-id", Data: "777:999", From: &tbapi.User{UserName: "admin", ID: 111},
+			Message: &tbapi.Message{MessageID: 789, Chat: tbapi.Chat{ID: 456}, Text: "permanently banned spammer (777)\n\nmsg"}}
+		require.NoError(t, adm.callbackUnbanConfirmed(query))
+		require.Len(t, auditLog.AddCalls(), 1)
+		assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceTelegram, Actor: "admin", ActorID: 111,
+			Action: storage.AuditUnban, UserID: 777, UserName: "spammer", MsgID: 999}, auditLog.AddCalls()[0].Rec)
+	})
+
 	t.Run("callbackBanConfirmed_SoftBan_channel", func(t *testing.T) {
 		mockAPI, botMock, adm, _ := setupCallback(false, true)
 
@@ -2860,6 +3012,94 @@ func TestAdmin_DirectReportWithAggressiveCleanup(t *testing.T) {
 	})
 }
 
+func TestAdmin_DirectReportImageHash(t *testing.T) {
+	pngData := makeTestPNG(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file/photo-large" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(pngData)
+	}))
+	defer ts.Close()
+
+	setup := func(dry bool) (*admin, *mocks.ImageHashesMock, *mocks.TbAPIMock) {
+		mockAPI := &mocks.TbAPIMock{
+			SendFunc:    func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+			GetFileDirectURLFunc: func(fileID string) (string, error) {
+				return ts.URL + "/file/" + fileID, nil
+			},
+		}
+		botMock := &mocks.BotMock{
+			OnMessageFunc:          func(msg bot.Message, checkOnly bool) bot.Response { return bot.Response{} },
+			RemoveApprovedUserFunc: func(id int64) error { return nil },
+			UpdateSpamFunc:         func(msg string) error { return nil },
+		}
+		hashes := &mocks.ImageHashesMock{AddFunc: func(ctx context.Context, hash string, userID int64, userName string) error {
+			return nil
+		}}
+		adm := &admin{tbAPI: mockAPI, bot: botMock, primChatID: 123, adminChatID: 456, superUsers: SuperUsers{},
+			imageHashes: hashes, dry: dry}
+		return adm, hashes, mockAPI
+	}
+
+	makeUpdate := func(photos ...string) tbapi.Update {
+		origMsg := &tbapi.Message{MessageID: 999, From: &tbapi.User{ID: 666, UserName: "spammer"}, Caption: "promo"}
+		for _, p := range photos {
+			origMsg.Photo = append(origMsg.Photo, tbapi.PhotoSize{FileID: p})
+		}
+		return tbapi.Update{Message: &tbapi.Message{MessageID: 789, Chat: tbapi.Chat{ID: 123}, Text: "/spam",
+			From: &tbapi.User{UserName: "admin", ID: 111}, ReplyToMessage: origMsg}}
+	}
+
+	t.Run("spam photo hash stored", func(t *testing.T) {
+		adm, hashes, mockAPI := setup(false)
+		err := adm.directReport(makeUpdate("photo-small", "photo-large"), true)
+		require.NoError(t, err)
+		require.Len(t, mockAPI.GetFileDirectURLCalls(), 1)
+		assert.Equal(t, "photo-large", mockAPI.GetFileDirectURLCalls()[0].FileID)
+		require.Len(t, hashes.AddCalls(), 1)
+		assert.Equal(t, "0000000000000000", hashes.AddCalls()[0].Hash)
+		assert.Equal(t, int64(666), hashes.AddCalls()[0].UserID)
+		assert.Equal(t, "spammer", hashes.AddCalls()[0].UserName)
+	})
+
+	t.Run("ban report doesn't store hash", func(t *testing.T) {
+		adm, hashes, _ := setup(false)
+		require.NoError(t, adm.directReport(makeUpdate("photo-large"), false))
+		assert.Empty(t, hashes.AddCalls())
+	})
+
+	t.Run("dry mode doesn't store hash", func(t *testing.T) {
+		adm, hashes, _ := setup(true)
+		require.NoError(t, adm.directReport(makeUpdate("photo-large"), true))
+		assert.Empty(t, hashes.AddCalls())
+	})
+
+	t.Run("message without photo", func(t *testing.T) {
+		adm, hashes, mockAPI := setup(false)
+		require.NoError(t, adm.directReport(makeUpdate(), true))
+		assert.Empty(t, hashes.AddCalls())
+		assert.Empty(t, mockAPI.GetFileDirectURLCalls())
+	})
+
+	t.Run("download failure reported, message still banned", func(t *testing.T) {
+		adm, hashes, mockAPI := setup(false)
+		err := adm.directReport(makeUpdate("photo-missing"), true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to download photo photo-missing")
+		assert.Empty(t, hashes.AddCalls())
+		banned := false
+		for _, call := range mockAPI.RequestCalls() {
+			if _, ok := call.C.(tbapi.BanChatMemberConfig); ok {
+				banned = true
+			}
+		}
+		assert.True(t, banned)
+	})
+}
+
 func TestAdmin_DeleteUserMessages(t *testing.T) {
 	t.Run("successful deletion", func(t *testing.T) {
 		mockAPI := &mocks.TbAPIMock{
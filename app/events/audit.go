@@ -0,0 +1,36 @@
+package events
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+//go:generate moq --out mocks/audit_log.go --pkg mocks --with-resets --skip-ensure . AuditLog
+
+// AuditLog is an interface for the log of moderation actions made by admins
+type AuditLog interface {
+	Add(ctx context.Context, rec storage.AuditRecord) error
+}
+
+// recordAudit adds the action made by the telegram admin to the audit log, no-op if the audit log is not set.
+// Failures are logged only, the action itself is already done.
+func recordAudit(ctx context.Context, al AuditLog, by *tbapi.User, rec storage.AuditRecord) {
+	if al == nil {
+		return
+	}
+	rec.Source = storage.AuditSourceTelegram
+	if by != nil {
+		rec.Actor, rec.ActorID = by.UserName, by.ID
+		if rec.Actor == "" {
+			rec.Actor = strings.TrimSpace(by.FirstName + " " + by.LastName)
+		}
+	}
+	if err := al.Add(ctx, rec); err != nil {
+		log.Printf("[WARN] failed to record %s by %q to audit log: %v", rec.Action, rec.Actor, err)
+	}
+}
@@ -0,0 +1,333 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/metrics"
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+//go:generate moq --out mocks/challenges.go --pkg mocks --with-resets --skip-ensure . Challenges
+
+// Challenges is an interface for pending join challenges storage
+type Challenges interface {
+	Add(ctx context.Context, ch storage.Challenge) error
+	Get(ctx context.Context, chatID, userID int64) (*storage.Challenge, error)
+	Delete(ctx context.Context, chatID, userID int64) error
+	Expired(ctx context.Context, now time.Time) ([]storage.Challenge, error)
+}
+
+// join challenge types
+const (
+	CaptchaButton = "button" // press a single button
+	CaptchaMath   = "math"   // pick the result of a simple addition
+	CaptchaEmoji  = "emoji"  // pick the named emoji
+)
+
+// actions on failed or expired join challenge
+const (
+	CaptchaBan  = "ban"  // ban permanently
+	CaptchaKick = "kick" // remove from the chat, the user can join again
+)
+
+const (
+	captchaPrefix        = "C"              // callback data prefix for join challenge buttons, C<userID>:<answer>
+	captchaCheckInterval = 10 * time.Second // how often expired challenges are checked
+)
+
+// CaptchaConfig defines join challenge (captcha) parameters
+type CaptchaConfig struct {
+	Storage      Challenges    // pending challenges storage
+	Enabled      bool          // enable join challenge for new members
+	Type         string        // challenge type: button, math or emoji
+	Timeout      time.Duration // time given to solve the challenge
+	Action       string        // action on failed or expired challenge: ban or kick
+	ApproveCount int           // number of first messages credited to the user who passed the challenge (0=disabled)
+}
+
+// joinCaptcha restricts new chat members until they solve a challenge posted by the bot.
+// Users who picked a wrong answer or didn't answer in time are banned or kicked.
+type joinCaptcha struct {
+	CaptchaConfig
+	tbAPI        TbAPI
+	bot          Bot
+	dry          bool
+	trainingMode bool
+	events       EventPublisher // publishes bans of users failed the challenge if set
+}
+
+// captchaQuestion is a generated challenge with the buttons to pick from
+type captchaQuestion struct {
+	text    string
+	options []string
+	answer  string
+}
+
+// captchaEmojis is a set of emojis with their names used by the emoji challenge
+var captchaEmojis = []struct{ emoji, name string }{
+	{"🍎", "apple"}, {"🚗", "car"}, {"🐶", "dog"}, {"🌲", "tree"}, {"⚽", "ball"},
+	{"🏠", "house"}, {"🐟", "fish"}, {"⭐", "star"}, {"🎸", "guitar"}, {"🚲", "bicycle"},
+}
+
+// Challenge restricts the new chat member and posts the challenge. Bots and approved users are not challenged.
+func (c *joinCaptcha) Challenge(ctx context.Context, chatID int64, user tbapi.User) error {
+	if user.IsBot {
+		return nil
+	}
+	if c.bot.IsApprovedUser(user.ID) {
+		log.Printf("[DEBUG] new member %s (%d) is approved already, no join challenge", user.UserName, user.ID)
+		return nil
+	}
+	if c.dry || c.trainingMode {
+		log.Printf("[INFO] dry or training mode, join challenge for %s (%d) skipped", user.UserName, user.ID)
+		return nil
+	}
+
+	if err := c.setPermissions(chatID, user.ID, false); err != nil {
+		return fmt.Errorf("failed to restrict new member %d: %w", user.ID, err)
+	}
+
+	q := newCaptchaQuestion(c.Type)
+	tbMsg := tbapi.NewMessage(chatID, fmt.Sprintf("%s, %s. You have %v to answer.", captchaUserName(user), q.text, c.Timeout))
+	buttons := make([]tbapi.InlineKeyboardButton, 0, len(q.options))
+	for _, opt := range q.options {
+		buttons = append(buttons, tbapi.NewInlineKeyboardButtonData(opt, fmt.Sprintf("%s%d:%s", captchaPrefix, user.ID, opt)))
+	}
+	tbMsg.ReplyMarkup = tbapi.NewInlineKeyboardMarkup(buttons)
+	sent, err := c.tbAPI.Send(tbMsg)
+	if err != nil {
+		// don't leave the user restricted without a way to pass the challenge
+		if permErr := c.setPermissions(chatID, user.ID, true); permErr != nil {
+			log.Printf("[WARN] failed to drop restrictions for %d: %v", user.ID, permErr)
+		}
+		return fmt.Errorf("failed to send join challenge to %d: %w", user.ID, err)
+	}
+
+	ch := storage.Challenge{ChatID: chatID, UserID: user.ID, UserName: user.UserName, MsgID: sent.MessageID,
+		Answer: q.answer, ExpiresAt: time.Now().Add(c.Timeout)}
+	if err := c.Storage.Add(ctx, ch); err != nil {
+		// the answer can't be verified without the stored challenge, release the user
+		if permErr := c.setPermissions(chatID, user.ID, true); permErr != nil {
+			log.Printf("[WARN] failed to drop restrictions for %d: %v", user.ID, permErr)
+		}
+		c.deleteMessage(ch)
+		return fmt.Errorf("failed to save join challenge for %d: %w", user.ID, err)
+	}
+	log.Printf("[INFO] join challenge (%s) sent to %s (%d) in chat %d", c.Type, user.UserName, user.ID, chatID)
+	return nil
+}
+
+// HandleCallback processes a button press on the join challenge, callback data: C<userID>:<answer>.
+// Presses by other users are ignored.
+func (c *joinCaptcha) HandleCallback(ctx context.Context, query *tbapi.CallbackQuery) error {
+	userID, answer, err := parseCaptchaCallback(query.Data)
+	if err != nil {
+		return err
+	}
+	if query.From == nil || query.From.ID != userID {
+		return c.answerCallback(query.ID, "this challenge is for another user")
+	}
+	if query.Message == nil {
+		return fmt.Errorf("no message in join challenge callback for %d", userID)
+	}
+
+	ch, err := c.Storage.Get(ctx, query.Message.Chat.ID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get join challenge for %d: %w", userID, err)
+	}
+	if ch == nil {
+		return c.answerCallback(query.ID, "challenge expired")
+	}
+
+	if answer != ch.Answer {
+		if err := c.answerCallback(query.ID, "wrong answer"); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		return c.reject(ctx, *ch, "wrong answer")
+	}
+	if err := c.answerCallback(query.ID, "welcome!"); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+	return c.accept(ctx, *ch)
+}
+
+// Expire rejects users who didn't solve the challenge in time
+func (c *joinCaptcha) Expire(ctx context.Context) {
+	expired, err := c.Storage.Expired(ctx, time.Now())
+	if err != nil {
+		log.Printf("[WARN] failed to get expired join challenges: %v", err)
+		return
+	}
+	for _, ch := range expired {
+		if err := c.reject(ctx, ch, "timeout"); err != nil {
+			log.Printf("[WARN] failed to reject expired join challenge: %v", err)
+		}
+	}
+}
+
+// accept drops restrictions, removes the challenge and optionally credits the user with first messages
+func (c *joinCaptcha) accept(ctx context.Context, ch storage.Challenge) error {
+	errs := new(multierror.Error)
+	if err := c.setPermissions(ch.ChatID, ch.UserID, true); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("failed to drop restrictions: %w", err))
+	}
+	c.deleteMessage(ch)
+	if err := c.Storage.Delete(ctx, ch.ChatID, ch.UserID); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if c.ApproveCount > 0 {
+		if err := c.bot.SeedApprovedUser(ch.UserID, ch.UserName, c.ApproveCount); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	metrics.Actions.Inc("captcha_pass")
+	log.Printf("[INFO] user %s (%d) passed join challenge in chat %d", ch.UserName, ch.UserID, ch.ChatID)
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return fmt.Errorf("failed to accept user %d: %w", ch.UserID, err)
+	}
+	return nil
+}
+
+// reject bans or kicks the user and removes the challenge. the challenge is removed even if the ban failed,
+// otherwise the expired challenge would be retried on every check
+func (c *joinCaptcha) reject(ctx context.Context, ch storage.Challenge, reason string) error {
+	log.Printf("[INFO] user %s (%d) failed join challenge in chat %d: %s, action: %s",
+		ch.UserName, ch.UserID, ch.ChatID, reason, c.Action)
+	errs := new(multierror.Error)
+	banReq := banRequest{duration: bot.PermanentBanDuration, userID: ch.UserID, chatID: ch.ChatID,
+		userName: ch.UserName, tbAPI: c.tbAPI, events: c.events, source: hooks.SourceCaptcha}
+	if c.Action == CaptchaKick {
+		banReq.duration = time.Minute
+	}
+	if err := banUserOrChannel(banReq); err != nil {
+		errs = multierror.Append(errs, err)
+	} else if c.Action == CaptchaKick {
+		// unban right away, so the user is removed from the chat but can join again
+		_, err := c.tbAPI.Request(tbapi.UnbanChatMemberConfig{
+			ChatMemberConfig: tbapi.ChatMemberConfig{UserID: ch.UserID, ChatConfig: tbapi.ChatConfig{ChatID: ch.ChatID}},
+			OnlyIfBanned:     true,
+		})
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to unban kicked user: %w", err))
+		}
+	}
+	c.deleteMessage(ch)
+	if err := c.Storage.Delete(ctx, ch.ChatID, ch.UserID); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	metrics.Actions.Inc("captcha_fail")
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return fmt.Errorf("failed to reject user %d: %w", ch.UserID, err)
+	}
+	return nil
+}
+
+// setPermissions restricts the user from sending anything or drops such restrictions
+func (c *joinCaptcha) setPermissions(chatID, userID int64, allow bool) error {
+	_, err := c.tbAPI.Request(tbapi.RestrictChatMemberConfig{
+		ChatMemberConfig: tbapi.ChatMemberConfig{UserID: userID, ChatConfig: tbapi.ChatConfig{ChatID: chatID}},
+		Permissions: &tbapi.ChatPermissions{
+			CanSendMessages:      allow,
+			CanSendAudios:        allow,
+			CanSendDocuments:     allow,
+			CanSendPhotos:        allow,
+			CanSendVideos:        allow,
+			CanSendVideoNotes:    allow,
+			CanSendVoiceNotes:    allow,
+			CanSendOtherMessages: allow,
+			CanChangeInfo:        allow,
+			CanInviteUsers:       allow,
+			CanPinMessages:       allow,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	return nil
+}
+
+// deleteMessage removes the challenge message, errors are logged only
+func (c *joinCaptcha) deleteMessage(ch storage.Challenge) {
+	if ch.MsgID == 0 {
+		return
+	}
+	_, err := c.tbAPI.Request(tbapi.DeleteMessageConfig{BaseChatMessage: tbapi.BaseChatMessage{
+		MessageID:  ch.MsgID,
+		ChatConfig: tbapi.ChatConfig{ChatID: ch.ChatID},
+	}})
+	if err != nil {
+		log.Printf("[WARN] failed to delete join challenge message %d: %v", ch.MsgID, err)
+	}
+}
+
+func (c *joinCaptcha) answerCallback(queryID, text string) error {
+	if _, err := c.tbAPI.Request(tbapi.NewCallback(queryID, text)); err != nil {
+		return fmt.Errorf("failed to send callback response: %w", err)
+	}
+	return nil
+}
+
+// newCaptchaQuestion makes a question of the given type, unknown type falls back to the button
+func newCaptchaQuestion(typ string) captchaQuestion {
+	//nolint:gosec // challenge doesn't need cryptographically secure randomness
+	switch typ {
+	case CaptchaMath:
+		a, b := rand.IntN(9)+1, rand.IntN(9)+1
+		answer := strconv.Itoa(a + b)
+		options := []string{answer}
+		for len(options) < 4 {
+			opt := strconv.Itoa(rand.IntN(17) + 2) // sums of two 1-9 numbers are in 2-18 range
+			if !slices.Contains(options, opt) {
+				options = append(options, opt)
+			}
+		}
+		rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		return captchaQuestion{text: fmt.Sprintf("please answer, how much is %d + %d", a, b), options: options, answer: answer}
+	case CaptchaEmoji:
+		idx := rand.Perm(len(captchaEmojis))[:4]
+		options := make([]string, 0, len(idx))
+		for _, i := range idx {
+			options = append(options, captchaEmojis[i].emoji)
+		}
+		answer := captchaEmojis[idx[rand.IntN(len(idx))]]
+		return captchaQuestion{text: fmt.Sprintf("please press the button with the %s", answer.name),
+			options: options, answer: answer.emoji}
+	default:
+		return captchaQuestion{text: "please press the button below to confirm you are not a bot",
+			options: []string{"I'm not a bot"}, answer: "I'm not a bot"}
+	}
+}
+
+// parseCaptchaCallback parses callback data in C<userID>:<answer> format
+func parseCaptchaCallback(data string) (userID int64, answer string, err error) {
+	idStr, answer, ok := strings.Cut(strings.TrimPrefix(data, captchaPrefix), ":")
+	if !ok {
+		return 0, "", fmt.Errorf("unexpected join challenge callback data %q", data)
+	}
+	if userID, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		return 0, "", fmt.Errorf("failed to parse user id from join challenge callback %q: %w", data, err)
+	}
+	return userID, answer, nil
+}
+
+// captchaUserName returns @username or the full name if the user has no username
+func captchaUserName(user tbapi.User) string {
+	if user.UserName != "" {
+		return "@" + user.UserName
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
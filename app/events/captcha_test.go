@@ -0,0 +1,332 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/events/mocks"
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+func TestJoinCaptcha_Challenge(t *testing.T) {
+	ctx := context.Background()
+	newMocks := func() (*mocks.TbAPIMock, *mocks.BotMock, *mocks.ChallengesMock) {
+		tbAPI := &mocks.TbAPIMock{
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+			SendFunc:    func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{MessageID: 321}, nil },
+		}
+		b := &mocks.BotMock{IsApprovedUserFunc: func(userID int64) bool { return userID == 99 }}
+		st := &mocks.ChallengesMock{AddFunc: func(ctx context.Context, ch storage.Challenge) error { return nil }}
+		return tbAPI, b, st
+	}
+
+	t.Run("restrict and post challenge", func(t *testing.T) {
+		tbAPI, b, st := newMocks()
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Type: CaptchaMath, Timeout: time.Minute},
+			tbAPI: tbAPI, bot: b}
+		err := c.Challenge(ctx, 100, tbapi.User{ID: 42, UserName: "user1"})
+		require.NoError(t, err)
+
+		require.Len(t, tbAPI.RequestCalls(), 1)
+		restrict, ok := tbAPI.RequestCalls()[0].C.(tbapi.RestrictChatMemberConfig)
+		require.True(t, ok)
+		assert.Equal(t, int64(42), restrict.UserID)
+		assert.False(t, restrict.Permissions.CanSendMessages)
+
+		require.Len(t, tbAPI.SendCalls(), 1)
+		msg := tbAPI.SendCalls()[0].C.(tbapi.MessageConfig)
+		assert.Equal(t, int64(100), msg.ChatID)
+		assert.Contains(t, msg.Text, "@user1, please answer, how much is")
+		assert.Contains(t, msg.Text, "You have 1m0s to answer")
+		markup := msg.ReplyMarkup.(tbapi.InlineKeyboardMarkup)
+		require.Len(t, markup.InlineKeyboard, 1)
+		require.Len(t, markup.InlineKeyboard[0], 4)
+
+		require.Len(t, st.AddCalls(), 1)
+		ch := st.AddCalls()[0].Ch
+		assert.Equal(t, int64(100), ch.ChatID)
+		assert.Equal(t, int64(42), ch.UserID)
+		assert.Equal(t, "user1", ch.UserName)
+		assert.Equal(t, 321, ch.MsgID)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), ch.ExpiresAt, 5*time.Second)
+		callbacks := make([]string, 0, 4)
+		for _, btn := range markup.InlineKeyboard[0] {
+			callbacks = append(callbacks, *btn.CallbackData)
+		}
+		assert.Contains(t, callbacks, "C42:"+ch.Answer, "answer is one of the buttons")
+	})
+
+	t.Run("bots, approved users and dry mode are skipped", func(t *testing.T) {
+		tbAPI, b, st := newMocks()
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Timeout: time.Minute}, tbAPI: tbAPI, bot: b}
+		require.NoError(t, c.Challenge(ctx, 100, tbapi.User{ID: 1, IsBot: true}))
+		require.NoError(t, c.Challenge(ctx, 100, tbapi.User{ID: 99}))
+		c.dry = true
+		require.NoError(t, c.Challenge(ctx, 100, tbapi.User{ID: 42}))
+		assert.Empty(t, tbAPI.RequestCalls())
+		assert.Empty(t, tbAPI.SendCalls())
+		assert.Empty(t, st.AddCalls())
+	})
+
+	t.Run("send failure drops restrictions", func(t *testing.T) {
+		tbAPI, b, st := newMocks()
+		tbAPI.SendFunc = func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, errors.New("send error") }
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Timeout: time.Minute}, tbAPI: tbAPI, bot: b}
+		err := c.Challenge(ctx, 100, tbapi.User{ID: 42})
+		require.ErrorContains(t, err, "failed to send join challenge to 42: send error")
+
+		require.Len(t, tbAPI.RequestCalls(), 2)
+		unrestrict := tbAPI.RequestCalls()[1].C.(tbapi.RestrictChatMemberConfig)
+		assert.True(t, unrestrict.Permissions.CanSendMessages)
+		assert.Empty(t, st.AddCalls())
+	})
+
+	t.Run("storage failure drops restrictions and deletes challenge message", func(t *testing.T) {
+		tbAPI, b, st := newMocks()
+		st.AddFunc = func(ctx context.Context, ch storage.Challenge) error { return errors.New("db error") }
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Timeout: time.Minute}, tbAPI: tbAPI, bot: b}
+		err := c.Challenge(ctx, 100, tbapi.User{ID: 42})
+		require.ErrorContains(t, err, "failed to save join challenge for 42: db error")
+
+		require.Len(t, tbAPI.RequestCalls(), 3)
+		unrestrict := tbAPI.RequestCalls()[1].C.(tbapi.RestrictChatMemberConfig)
+		assert.True(t, unrestrict.Permissions.CanSendMessages)
+		del := tbAPI.RequestCalls()[2].C.(tbapi.DeleteMessageConfig)
+		assert.Equal(t, 321, del.MessageID)
+	})
+}
+
+func TestJoinCaptcha_HandleCallback(t *testing.T) {
+	ctx := context.Background()
+	pending := &storage.Challenge{ChatID: 100, UserID: 42, UserName: "user1", MsgID: 321, Answer: "7"}
+	newCaptcha := func(ch *storage.Challenge) (*joinCaptcha, *mocks.TbAPIMock, *mocks.BotMock, *mocks.ChallengesMock) {
+		tbAPI := &mocks.TbAPIMock{
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+		}
+		b := &mocks.BotMock{SeedApprovedUserFunc: func(id int64, name string, count int) error { return nil }}
+		st := &mocks.ChallengesMock{
+			GetFunc:    func(ctx context.Context, chatID, userID int64) (*storage.Challenge, error) { return ch, nil },
+			DeleteFunc: func(ctx context.Context, chatID, userID int64) error { return nil },
+		}
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Action: CaptchaBan, ApproveCount: 3},
+			tbAPI: tbAPI, bot: b}
+		return c, tbAPI, b, st
+	}
+	query := func(fromID int64, data string) *tbapi.CallbackQuery {
+		return &tbapi.CallbackQuery{ID: "q1", From: &tbapi.User{ID: fromID}, Data: data,
+			Message: &tbapi.Message{MessageID: 321, Chat: tbapi.Chat{ID: 100}}}
+	}
+
+	t.Run("correct answer", func(t *testing.T) {
+		c, tbAPI, b, st := newCaptcha(pending)
+		require.NoError(t, c.HandleCallback(ctx, query(42, "C42:7")))
+
+		require.Len(t, st.GetCalls(), 1)
+		assert.Equal(t, int64(100), st.GetCalls()[0].ChatID)
+		assert.Equal(t, int64(42), st.GetCalls()[0].UserID)
+
+		require.Len(t, tbAPI.RequestCalls(), 3)
+		cb := tbAPI.RequestCalls()[0].C.(tbapi.CallbackConfig)
+		assert.Equal(t, "welcome!", cb.Text)
+		unrestrict := tbAPI.RequestCalls()[1].C.(tbapi.RestrictChatMemberConfig)
+		assert.True(t, unrestrict.Permissions.CanSendMessages)
+		assert.Equal(t, int64(42), unrestrict.UserID)
+		del := tbAPI.RequestCalls()[2].C.(tbapi.DeleteMessageConfig)
+		assert.Equal(t, 321, del.MessageID)
+
+		require.Len(t, st.DeleteCalls(), 1)
+		require.Len(t, b.SeedApprovedUserCalls(), 1)
+		assert.Equal(t, int64(42), b.SeedApprovedUserCalls()[0].ID)
+		assert.Equal(t, "user1", b.SeedApprovedUserCalls()[0].Name)
+		assert.Equal(t, 3, b.SeedApprovedUserCalls()[0].Count)
+	})
+
+	t.Run("correct answer without approve count", func(t *testing.T) {
+		c, _, b, st := newCaptcha(pending)
+		c.ApproveCount = 0
+		require.NoError(t, c.HandleCallback(ctx, query(42, "C42:7")))
+		require.Len(t, st.DeleteCalls(), 1)
+		assert.Empty(t, b.SeedApprovedUserCalls())
+	})
+
+	t.Run("wrong answer bans the user", func(t *testing.T) {
+		c, tbAPI, b, st := newCaptcha(pending)
+		require.NoError(t, c.HandleCallback(ctx, query(42, "C42:8")))
+
+		require.Len(t, tbAPI.RequestCalls(), 3)
+		cb := tbAPI.RequestCalls()[0].C.(tbapi.CallbackConfig)
+		assert.Equal(t, "wrong answer", cb.Text)
+		ban := tbAPI.RequestCalls()[1].C.(tbapi.BanChatMemberConfig)
+		assert.Equal(t, int64(42), ban.UserID)
+		assert.Equal(t, int64(100), ban.ChatID)
+		_, ok := tbAPI.RequestCalls()[2].C.(tbapi.DeleteMessageConfig)
+		assert.True(t, ok)
+		require.Len(t, st.DeleteCalls(), 1)
+		assert.Empty(t, b.SeedApprovedUserCalls())
+	})
+
+	t.Run("press by another user", func(t *testing.T) {
+		c, tbAPI, _, st := newCaptcha(pending)
+		require.NoError(t, c.HandleCallback(ctx, query(43, "C42:7")))
+		require.Len(t, tbAPI.RequestCalls(), 1)
+		cb := tbAPI.RequestCalls()[0].C.(tbapi.CallbackConfig)
+		assert.Equal(t, "this challenge is for another user", cb.Text)
+		assert.Empty(t, st.GetCalls())
+	})
+
+	t.Run("challenge not found", func(t *testing.T) {
+		c, tbAPI, _, st := newCaptcha(nil)
+		require.NoError(t, c.HandleCallback(ctx, query(42, "C42:7")))
+		require.Len(t, tbAPI.RequestCalls(), 1)
+		cb := tbAPI.RequestCalls()[0].C.(tbapi.CallbackConfig)
+		assert.Equal(t, "challenge expired", cb.Text)
+		assert.Empty(t, st.DeleteCalls())
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		c, _, _, st := newCaptcha(nil)
+		st.GetFunc = func(ctx context.Context, chatID, userID int64) (*storage.Challenge, error) {
+			return nil, errors.New("db error")
+		}
+		err := c.HandleCallback(ctx, query(42, "C42:7"))
+		require.ErrorContains(t, err, "failed to get join challenge for 42: db error")
+	})
+
+	t.Run("bad callback data", func(t *testing.T) {
+		c, tbAPI, _, _ := newCaptcha(pending)
+		require.Error(t, c.HandleCallback(ctx, query(42, "Cblah")))
+		require.Error(t, c.HandleCallback(ctx, query(42, "Cxx:7")))
+		assert.Empty(t, tbAPI.RequestCalls())
+	})
+}
+
+func TestJoinCaptcha_Expire(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("kick expired users", func(t *testing.T) {
+		tbAPI := &mocks.TbAPIMock{
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+		}
+		st := &mocks.ChallengesMock{
+			ExpiredFunc: func(ctx context.Context, now time.Time) ([]storage.Challenge, error) {
+				return []storage.Challenge{{ChatID: 100, UserID: 1, MsgID: 11}, {ChatID: 100, UserID: 2, MsgID: 12}}, nil
+			},
+			DeleteFunc: func(ctx context.Context, chatID, userID int64) error { return nil },
+		}
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Action: CaptchaKick}, tbAPI: tbAPI, bot: &mocks.BotMock{}}
+		c.Expire(ctx)
+
+		require.Len(t, st.ExpiredCalls(), 1)
+		require.Len(t, tbAPI.RequestCalls(), 6, "ban, unban and delete message for each user")
+		ban := tbAPI.RequestCalls()[0].C.(tbapi.BanChatMemberConfig)
+		assert.Equal(t, int64(1), ban.UserID)
+		assert.InDelta(t, time.Now().Add(time.Minute).Unix(), ban.UntilDate, 5)
+		unban := tbAPI.RequestCalls()[1].C.(tbapi.UnbanChatMemberConfig)
+		assert.Equal(t, int64(1), unban.UserID)
+		assert.True(t, unban.OnlyIfBanned)
+		del := tbAPI.RequestCalls()[2].C.(tbapi.DeleteMessageConfig)
+		assert.Equal(t, 11, del.MessageID)
+
+		require.Len(t, st.DeleteCalls(), 2)
+		assert.Equal(t, int64(2), st.DeleteCalls()[1].UserID)
+	})
+
+	t.Run("challenge removed even if ban failed", func(t *testing.T) {
+		tbAPI := &mocks.TbAPIMock{
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+				if _, ok := c.(tbapi.BanChatMemberConfig); ok {
+					return nil, errors.New("not enough rights")
+				}
+				return &tbapi.APIResponse{Ok: true}, nil
+			},
+		}
+		st := &mocks.ChallengesMock{
+			ExpiredFunc: func(ctx context.Context, now time.Time) ([]storage.Challenge, error) {
+				return []storage.Challenge{{ChatID: 100, UserID: 1}}, nil
+			},
+			DeleteFunc: func(ctx context.Context, chatID, userID int64) error { return nil },
+		}
+		c := &joinCaptcha{CaptchaConfig: CaptchaConfig{Storage: st, Action: CaptchaBan}, tbAPI: tbAPI, bot: &mocks.BotMock{}}
+		c.Expire(ctx)
+		require.Len(t, tbAPI.RequestCalls(), 1, "no unban and no message to delete")
+		require.Len(t, st.DeleteCalls(), 1)
+	})
+}
+
+func TestNewCaptchaQuestion(t *testing.T) {
+	t.Run("button", func(t *testing.T) {
+		q := newCaptchaQuestion(CaptchaButton)
+		assert.Equal(t, []string{"I'm not a bot"}, q.options)
+		assert.Equal(t, "I'm not a bot", q.answer)
+		assert.Equal(t, q, newCaptchaQuestion("unknown"), "unknown type falls back to button")
+	})
+
+	t.Run("math", func(t *testing.T) {
+		for range 50 {
+			q := newCaptchaQuestion(CaptchaMath)
+			require.Len(t, q.options, 4)
+			assert.Contains(t, q.options, q.answer)
+			var a, b int
+			expr := strings.TrimPrefix(q.text, "please answer, how much is ")
+			aStr, bStr, ok := strings.Cut(expr, " + ")
+			require.True(t, ok, q.text)
+			a, _ = strconv.Atoi(aStr)
+			b, _ = strconv.Atoi(bStr)
+			assert.Equal(t, strconv.Itoa(a+b), q.answer)
+			uniq := map[string]bool{}
+			for _, o := range q.options {
+				uniq[o] = true
+			}
+			assert.Len(t, uniq, 4, "options are unique")
+		}
+	})
+
+	t.Run("emoji", func(t *testing.T) {
+		for range 50 {
+			q := newCaptchaQuestion(CaptchaEmoji)
+			require.Len(t, q.options, 4)
+			assert.Contains(t, q.options, q.answer)
+			for _, e := range captchaEmojis {
+				if e.emoji == q.answer {
+					assert.Contains(t, q.text, e.name)
+				}
+			}
+		}
+	})
+}
+
+func TestParseCaptchaCallback(t *testing.T) {
+	tbl := []struct {
+		data    string
+		userID  int64
+		answer  string
+		wantErr bool
+	}{
+		{data: "C42:7", userID: 42, answer: "7"},
+		{data: "C42:I'm not a bot", userID: 42, answer: "I'm not a bot"},
+		{data: "C42:🍎", userID: 42, answer: "🍎"},
+		{data: "C42:a:b", userID: 42, answer: "a:b"},
+		{data: "C42", wantErr: true},
+		{data: "Cabc:7", wantErr: true},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.data, func(t *testing.T) {
+			userID, answer, err := parseCaptchaCallback(tt.data)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.userID, userID)
+			assert.Equal(t, tt.answer, answer)
+		})
+	}
+}
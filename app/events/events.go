@@ -12,6 +12,8 @@ import (
 	tbapi "github.com/OvyFlash/telegram-bot-api"
 
 	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/metrics"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/lib/spamcheck"
 )
@@ -27,6 +29,7 @@ type TbAPI interface {
 	Request(c tbapi.Chattable) (*tbapi.APIResponse, error)
 	GetChat(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error)
 	GetChatAdministrators(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error)
+	GetFileDirectURL(fileID string) (string, error)
 }
 
 // Locator is an interface for message locator
@@ -47,6 +50,7 @@ type Bot interface {
 	UpdateSpam(msg string) error
 	UpdateHam(msg string) error
 	AddApprovedUser(id int64, name string) error
+	SeedApprovedUser(id int64, name string, count int) error
 	RemoveApprovedUser(id int64) error
 	IsApprovedUser(userID int64) bool
 }
@@ -143,6 +147,9 @@ type banRequest struct {
 	dry      bool
 	training bool // training mode, do not do the actual ban
 	restrict bool // restrict instead of ban
+
+	events EventPublisher // optional, publishes the ban event to webhooks
+	source string         // what triggered the ban, reported in the event
 }
 
 // The bot must be an administrator in the supergroup for this to work
@@ -190,6 +197,8 @@ func banUserOrChannel(r banRequest) error {
 			return fmt.Errorf("response is not Ok: %v", string(resp.Result))
 		}
 		log.Printf("[INFO] channel %s banned by bot for %v", r.userName, r.duration)
+		metrics.Actions.Inc("ban_channel")
+		r.publish(hooks.EventBan)
 		return nil
 	}
 
@@ -221,6 +230,8 @@ func banUserOrChannel(r banRequest) error {
 			return fmt.Errorf("response is not Ok: %v", string(resp.Result))
 		}
 		log.Printf("[INFO] %s restricted by bot for %v", r.userName, r.duration)
+		metrics.Actions.Inc("restrict")
+		r.publish(hooks.EventSoftBan)
 		return nil
 	}
 
@@ -239,9 +250,21 @@ func banUserOrChannel(r banRequest) error {
 	}
 
 	log.Printf("[INFO] user %s banned by bot for %v", r.userName, r.duration)
+	metrics.Actions.Inc("ban")
+	r.publish(hooks.EventBan)
 	return nil
 }
 
+// publish sends the event of the completed ban, permanent bans have no duration in details
+func (r banRequest) publish(eventType string) {
+	e := hooks.Event{Type: eventType, Source: r.source, ChatID: r.chatID, UserID: r.userID, UserName: r.userName,
+		ChannelID: r.channelID}
+	if r.duration < bot.PermanentBanDuration {
+		e.Details = "duration: " + r.duration.String()
+	}
+	publishEvent(context.Background(), r.events, e)
+}
+
 // transform converts telegram message to internal message format.
 // properly handles all message types - text, photo, video, etc, and their combinations.
 // also handles forwarded messages, replies, and message entities like links and mentions.
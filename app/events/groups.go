@@ -0,0 +1,206 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/umputun/tg-spam/app/hooks"
+)
+
+// GroupConfig defines an additional group protected by the same listener. Samples, approved users and
+// storage are shared with the primary group, admin chat and superusers are per group.
+type GroupConfig struct {
+	Group      string     // can be int64 or public group username (without "@" prefix)
+	AdminGroup string     // admin chat for this group, empty disables admin notifications for the group
+	SuperUsers SuperUsers // superusers of this group, in addition to listener's SuperUsers
+	Bot        Bot        // optional bot with group-specific detector settings, nil to use listener's Bot
+}
+
+// chatGroup is a protected group resolved at startup, with its own admin chat, superusers and handlers
+type chatGroup struct {
+	chatID          int64
+	adminChatID     int64
+	linkedChannelID int64
+	superUsers      SuperUsers
+	bot             Bot
+	adminHandler    *admin
+	reportsHandler  *userReports
+}
+
+// primaryGroup returns the primary group built from the listener's own fields
+func (l *TelegramListener) primaryGroup() *chatGroup {
+	return &chatGroup{
+		chatID:          l.chatID,
+		adminChatID:     l.adminChatID,
+		linkedChannelID: l.linkedChannelID,
+		superUsers:      l.SuperUsers,
+		bot:             l.Bot,
+		adminHandler:    l.adminHandler,
+		reportsHandler:  l.reportsHandler,
+	}
+}
+
+// groupFor returns the group for the given chat. Chats not matching any additional group,
+// including testing chats, fall back to the primary group
+func (l *TelegramListener) groupFor(chatID int64) *chatGroup {
+	if g, ok := l.groups[chatID]; ok {
+		return g
+	}
+	return l.primaryGroup()
+}
+
+// adminGroupFor returns the group served by the given admin chat, nil if the chat is not an admin chat
+func (l *TelegramListener) adminGroupFor(adminChatID int64) *chatGroup {
+	if adminChatID == l.adminChatID {
+		return l.primaryGroup()
+	}
+	for _, g := range l.groups {
+		if g.adminChatID != 0 && g.adminChatID == adminChatID {
+			return g
+		}
+	}
+	return nil
+}
+
+// setupGroups resolves additional groups and makes admin and report handlers for each of them.
+// supers is the list of superusers configured for the listener, before chat admins of the primary group added.
+func (l *TelegramListener) setupGroups(supers SuperUsers) error {
+	l.groups = make(map[int64]*chatGroup, len(l.Groups))
+	adminChats := map[int64]string{}
+	if l.adminChatID != 0 {
+		adminChats[l.adminChatID] = l.Group
+	}
+
+	for _, gc := range l.Groups {
+		chatID, err := l.getChatID(gc.Group)
+		if err != nil {
+			return fmt.Errorf("failed to get chat ID for group %q: %w", gc.Group, err)
+		}
+		if _, dup := l.groups[chatID]; dup || chatID == l.chatID {
+			return fmt.Errorf("group %q (%d) is configured more than once", gc.Group, chatID)
+		}
+
+		g := &chatGroup{chatID: chatID, bot: l.Bot}
+		if gc.Bot != nil {
+			g.bot = gc.Bot
+		}
+
+		if gc.AdminGroup != "" {
+			if g.adminChatID, err = l.getChatID(gc.AdminGroup); err != nil {
+				return fmt.Errorf("failed to get chat ID for admin group %q: %w", gc.AdminGroup, err)
+			}
+			if other, used := adminChats[g.adminChatID]; used {
+				return fmt.Errorf("admin group %q of %q is already used by group %q", gc.AdminGroup, gc.Group, other)
+			}
+			adminChats[g.adminChatID] = gc.Group
+		}
+
+		chatInfo, err := l.TbAPI.GetChat(tbapi.ChatInfoConfig{ChatConfig: tbapi.ChatConfig{ChatID: chatID}})
+		if err != nil {
+			log.Printf("[WARN] failed to get chat info for linked channel resolution of %q: %v", gc.Group, err)
+		} else {
+			g.linkedChannelID = chatInfo.LinkedChatID
+		}
+
+		g.superUsers = append(slices.Clone(supers), gc.SuperUsers...)
+		if g.superUsers, err = l.chatSupers(chatID, g.superUsers); err != nil {
+			log.Printf("[WARN] failed to update superusers of %q: %v", gc.Group, err)
+		}
+
+		l.groups[chatID] = g
+		log.Printf("[INFO] additional group %q, chat ID: %d, admin chat ID: %d, supers: {%s}",
+			gc.Group, g.chatID, g.adminChatID, strings.Join(g.superUsers, ", "))
+	}
+
+	// handlers are made after all groups resolved, as training bot depends on the full list of bots
+	for _, g := range l.groups {
+		g.adminHandler = l.makeAdminHandler(g)
+		g.reportsHandler = l.makeReportsHandler(g)
+	}
+	return nil
+}
+
+// makeAdminHandler makes admin handler for the group
+func (l *TelegramListener) makeAdminHandler(g *chatGroup) *admin {
+	return &admin{
+		tbAPI: l.TbAPI, bot: l.withSampleEvents(l.trainingBot(g.bot), hooks.SourceAdmin), locator: l.Locator,
+		superUsers: g.superUsers, primChatID: g.chatID, adminChatID: g.adminChatID,
+		trainingMode: l.TrainingMode, softBan: l.SoftBanMode, dry: l.Dry, warnMsg: l.WarnMsg,
+		aggressiveCleanup: l.AggressiveCleanup, aggressiveCleanupLimit: l.AggressiveCleanupLimit,
+		warnings: l.Warnings, warnThreshold: l.WarnThreshold, warnWindow: l.WarnWindow,
+		imageHashes: l.ImageHashes, reviews: l.Reviews, reputation: l.Reputation, events: l.Events, auditLog: l.AuditLog,
+	}
+}
+
+// makeReportsHandler makes user reports handler for the group
+func (l *TelegramListener) makeReportsHandler(g *chatGroup) *userReports {
+	return &userReports{
+		ReportConfig: l.ReportConfig,
+		tbAPI:        l.TbAPI, bot: l.withSampleEvents(l.trainingBot(g.bot), hooks.SourceReports), locator: l.Locator,
+		superUsers: g.superUsers, primChatID: g.chatID, adminChatID: g.adminChatID,
+		trainingMode: l.TrainingMode, softBanMode: l.SoftBanMode, dry: l.Dry, reputation: l.Reputation, events: l.Events,
+		auditLog: l.AuditLog,
+	}
+}
+
+// trainingBot returns the bot used by admin and report handlers. If some groups use their own bots,
+// spam/ham updates and approved users changes are applied to all of them, so group detectors
+// stay in sync on the shared samples and approved users
+func (l *TelegramListener) trainingBot(b Bot) Bot {
+	bots := []Bot{l.Bot}
+	for _, gc := range l.Groups {
+		if gc.Bot != nil && !slices.Contains(bots, gc.Bot) {
+			bots = append(bots, gc.Bot)
+		}
+	}
+	if len(bots) < 2 {
+		return b
+	}
+	return &multiBot{Bot: b, all: bots}
+}
+
+// multiBot checks messages with its own bot and broadcasts training and approval changes to all bots
+type multiBot struct {
+	Bot
+	all []Bot
+}
+
+// UpdateSpam updates spam samples of all bots
+func (m *multiBot) UpdateSpam(msg string) error {
+	return m.each(func(b Bot) error { return b.UpdateSpam(msg) })
+}
+
+// UpdateHam updates ham samples of all bots
+func (m *multiBot) UpdateHam(msg string) error {
+	return m.each(func(b Bot) error { return b.UpdateHam(msg) })
+}
+
+// AddApprovedUser adds approved user to all bots
+func (m *multiBot) AddApprovedUser(id int64, name string) error {
+	return m.each(func(b Bot) error { return b.AddApprovedUser(id, name) })
+}
+
+// SeedApprovedUser credits approved messages count to the user in all bots
+func (m *multiBot) SeedApprovedUser(id int64, name string, count int) error {
+	return m.each(func(b Bot) error { return b.SeedApprovedUser(id, name, count) })
+}
+
+// RemoveApprovedUser removes approved user from all bots
+func (m *multiBot) RemoveApprovedUser(id int64) error {
+	return m.each(func(b Bot) error { return b.RemoveApprovedUser(id) })
+}
+
+func (m *multiBot) each(fn func(b Bot) error) error {
+	errs := new(multierror.Error)
+	for _, b := range m.all {
+		if err := fn(b); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,265 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/events/mocks"
+)
+
+func TestTelegramListener_setupGroups(t *testing.T) {
+	newAPI := func() *mocks.TbAPIMock {
+		return &mocks.TbAPIMock{
+			GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+				if config.SuperGroupUsername == "@second" {
+					return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 200}}, nil
+				}
+				if config.SuperGroupUsername != "" {
+					return tbapi.ChatFullInfo{}, errors.New("not found")
+				}
+				return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: config.ChatID}, LinkedChatID: config.ChatID + 1}, nil
+			},
+			GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+				return []tbapi.ChatMember{{User: &tbapi.User{ID: config.ChatID + 10}}}, nil
+			},
+		}
+	}
+
+	t.Run("groups resolved", func(t *testing.T) {
+		mockAPI := newAPI()
+		groupBot := &mocks.BotMock{}
+		l := TelegramListener{TbAPI: mockAPI, Bot: &mocks.BotMock{}, chatID: 100, adminChatID: 900,
+			SuperUsers: SuperUsers{"global", "110"},
+			Groups: []GroupConfig{
+				{Group: "second", AdminGroup: "800", SuperUsers: SuperUsers{"local"}, Bot: groupBot},
+				{Group: "300"},
+			}}
+		require.NoError(t, l.setupGroups(SuperUsers{"global"}))
+		require.Len(t, l.groups, 2)
+
+		g := l.groups[200]
+		require.NotNil(t, g)
+		assert.Equal(t, int64(800), g.adminChatID)
+		assert.Equal(t, int64(201), g.linkedChannelID)
+		assert.Equal(t, SuperUsers{"global", "local", "210"}, g.superUsers, "primary chat admins not included")
+		assert.Same(t, groupBot, g.bot)
+		require.NotNil(t, g.adminHandler)
+		assert.Equal(t, int64(200), g.adminHandler.primChatID)
+		assert.Equal(t, int64(800), g.adminHandler.adminChatID)
+		require.NotNil(t, g.reportsHandler)
+		assert.Equal(t, int64(200), g.reportsHandler.primChatID)
+		assert.Equal(t, int64(800), g.reportsHandler.adminChatID)
+		assert.IsType(t, &multiBot{}, g.adminHandler.bot, "training goes to all bots")
+
+		g = l.groups[300]
+		require.NotNil(t, g)
+		assert.Equal(t, int64(0), g.adminChatID)
+		assert.Equal(t, SuperUsers{"global", "310"}, g.superUsers)
+		assert.Same(t, l.Bot, g.bot)
+		assert.Equal(t, SuperUsers{"global", "110"}, l.SuperUsers, "listener supers not changed")
+	})
+
+	t.Run("no additional groups", func(t *testing.T) {
+		l := TelegramListener{TbAPI: newAPI(), Bot: &mocks.BotMock{}, chatID: 100}
+		require.NoError(t, l.setupGroups(nil))
+		assert.Empty(t, l.groups)
+		assert.Same(t, l.Bot, l.trainingBot(l.Bot), "no fan-out without group bots")
+	})
+
+	tbl := []struct {
+		name    string
+		groups  []GroupConfig
+		wantErr string
+	}{
+		{name: "same as primary", groups: []GroupConfig{{Group: "100"}}, wantErr: `group "100" (100) is configured more than once`},
+		{name: "duplicate", groups: []GroupConfig{{Group: "200"}, {Group: "second"}},
+			wantErr: `group "second" (200) is configured more than once`},
+		{name: "unknown group", groups: []GroupConfig{{Group: "unknown"}}, wantErr: `failed to get chat ID for group "unknown"`},
+		{name: "unknown admin group", groups: []GroupConfig{{Group: "200", AdminGroup: "unknown"}},
+			wantErr: `failed to get chat ID for admin group "unknown"`},
+		{name: "admin chat of primary", groups: []GroupConfig{{Group: "200", AdminGroup: "900"}},
+			wantErr: `admin group "900" of "200" is already used by group "main"`},
+		{name: "shared admin chat", groups: []GroupConfig{{Group: "200", AdminGroup: "800"}, {Group: "300", AdminGroup: "800"}},
+			wantErr: `admin group "800" of "300" is already used by group "200"`},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			l := TelegramListener{TbAPI: newAPI(), Bot: &mocks.BotMock{}, Group: "main", chatID: 100, adminChatID: 900,
+				Groups: tt.groups}
+			err := l.setupGroups(nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTelegramListener_groupRouting(t *testing.T) {
+	primAdmin, secondAdmin := &admin{}, &admin{}
+	l := TelegramListener{chatID: 100, adminChatID: 900, linkedChannelID: 101, SuperUsers: SuperUsers{"super"},
+		adminHandler: primAdmin, TestingIDs: []int64{555}}
+	l.groups = map[int64]*chatGroup{
+		200: {chatID: 200, adminChatID: 800, linkedChannelID: 201, superUsers: SuperUsers{"local"}, adminHandler: secondAdmin},
+		300: {chatID: 300},
+	}
+
+	assert.Same(t, primAdmin, l.groupFor(100).adminHandler)
+	assert.Same(t, secondAdmin, l.groupFor(200).adminHandler)
+	assert.Same(t, primAdmin, l.groupFor(555).adminHandler, "testing chat goes to primary group")
+
+	assert.Equal(t, int64(100), l.adminGroupFor(900).chatID)
+	assert.Equal(t, int64(200), l.adminGroupFor(800).chatID)
+	assert.Nil(t, l.adminGroupFor(0), "group without admin chat can't be matched")
+	assert.Nil(t, l.adminGroupFor(123))
+
+	assert.True(t, l.isChatAllowed(100))
+	assert.True(t, l.isChatAllowed(200))
+	assert.True(t, l.isChatAllowed(300))
+	assert.True(t, l.isChatAllowed(555))
+	assert.False(t, l.isChatAllowed(800))
+
+	assert.True(t, l.isAdminChat(900, "super", 1))
+	assert.True(t, l.isAdminChat(800, "local", 2))
+	assert.False(t, l.isAdminChat(900, "local", 2), "superuser of another group")
+	assert.False(t, l.isAdminChat(800, "super", 1), "superusers are resolved per group")
+	assert.False(t, l.isAdminChat(123, "super", 1))
+
+	assert.True(t, l.isLinkedChannel(&tbapi.Message{Chat: tbapi.Chat{ID: 200}, SenderChat: &tbapi.Chat{ID: 201}}))
+	assert.False(t, l.isLinkedChannel(&tbapi.Message{Chat: tbapi.Chat{ID: 200}, SenderChat: &tbapi.Chat{ID: 101}}))
+	assert.True(t, l.isLinkedChannel(&tbapi.Message{Chat: tbapi.Chat{ID: 100}, SenderChat: &tbapi.Chat{ID: 101}}))
+	assert.False(t, l.isLinkedChannel(&tbapi.Message{Chat: tbapi.Chat{ID: 300}, SenderChat: &tbapi.Chat{ID: 101}}))
+}
+
+func TestTelegramListener_DoWithGroups(t *testing.T) {
+	mockLogger := &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}}
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: config.ChatID}}, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) {
+			return tbapi.Message{Text: c.(tbapi.MessageConfig).Text}, nil
+		},
+		RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+	}
+	spamResp := func(msg bot.Message) bot.Response {
+		if msg.Text != "buy now" {
+			return bot.Response{}
+		}
+		return bot.Response{Send: true, Text: "banned", BanInterval: time.Hour,
+			User: bot.User{Username: msg.From.Username, ID: msg.From.ID}}
+	}
+	primBot := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response { return spamResp(msg) }}
+	groupBot := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response { return spamResp(msg) }}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{
+		SpamLogger: mockLogger,
+		TbAPI:      mockAPI,
+		Bot:        primBot,
+		Group:      "100",
+		AdminGroup: "900",
+		StartupMsg: "startup",
+		Locator:    locator,
+		SuperUsers: SuperUsers{"super"},
+		Groups:     []GroupConfig{{Group: "200", AdminGroup: "800", Bot: groupBot}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 3)
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 1, Chat: tbapi.Chat{ID: 200}, Text: "buy now",
+		From: &tbapi.User{UserName: "spammer", ID: 42}}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 2, Chat: tbapi.Chat{ID: 300}, Text: "buy now",
+		From: &tbapi.User{UserName: "other", ID: 43}}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 3, Chat: tbapi.Chat{ID: 100}, Text: "buy now",
+		From: &tbapi.User{UserName: "spammer2", ID: 44}}}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+
+	require.Len(t, groupBot.OnMessageCalls(), 1, "message from additional group checked by its own bot")
+	assert.Equal(t, "spammer", groupBot.OnMessageCalls()[0].Msg.From.Username)
+	require.Len(t, primBot.OnMessageCalls(), 1, "message from unknown chat ignored")
+	assert.Equal(t, "spammer2", primBot.OnMessageCalls()[0].Msg.From.Username)
+
+	sentTo := map[int64][]string{}
+	for _, c := range mockAPI.SendCalls() {
+		mc := c.C.(tbapi.MessageConfig)
+		sentTo[mc.ChatID] = append(sentTo[mc.ChatID], mc.Text)
+	}
+	assert.Equal(t, []string{"startup", "banned"}, sentTo[100])
+	assert.Equal(t, []string{"startup", "banned"}, sentTo[200])
+	require.Len(t, sentTo[800], 1, "ban in additional group reported to its admin chat")
+	assert.Contains(t, sentTo[800][0], "spammer")
+	require.Len(t, sentTo[900], 1, "ban in primary group reported to primary admin chat")
+	assert.Contains(t, sentTo[900][0], "spammer2")
+
+	bans := map[int64]int64{}
+	for _, c := range mockAPI.RequestCalls() {
+		if b, ok := c.C.(tbapi.BanChatMemberConfig); ok {
+			bans[b.UserID] = b.ChatID
+		}
+	}
+	assert.Equal(t, map[int64]int64{42: 200, 44: 100}, bans, "users banned in the group they posted to")
+	assert.Len(t, mockAPI.GetChatAdministratorsCalls(), 2, "admins fetched for each group")
+}
+
+func TestMultiBot(t *testing.T) {
+	b1 := &mocks.BotMock{
+		UpdateSpamFunc:         func(msg string) error { return nil },
+		UpdateHamFunc:          func(msg string) error { return nil },
+		AddApprovedUserFunc:    func(id int64, name string) error { return nil },
+		SeedApprovedUserFunc:   func(id int64, name string, count int) error { return nil },
+		RemoveApprovedUserFunc: func(id int64) error { return nil },
+		OnMessageFunc:          func(msg bot.Message, checkOnly bool) bot.Response { return bot.Response{Text: "b1"} },
+	}
+	b2 := &mocks.BotMock{
+		UpdateSpamFunc:         func(msg string) error { return errors.New("spam err") },
+		UpdateHamFunc:          func(msg string) error { return nil },
+		AddApprovedUserFunc:    func(id int64, name string) error { return nil },
+		SeedApprovedUserFunc:   func(id int64, name string, count int) error { return nil },
+		RemoveApprovedUserFunc: func(id int64) error { return errors.New("remove err") },
+	}
+	mb := &multiBot{Bot: b1, all: []Bot{b1, b2}}
+
+	err := mb.UpdateSpam("spam msg")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spam err")
+	assert.Len(t, b1.UpdateSpamCalls(), 1, "all bots updated even if one failed")
+	assert.Len(t, b2.UpdateSpamCalls(), 1)
+
+	require.NoError(t, mb.UpdateHam("ham msg"))
+	assert.Equal(t, "ham msg", b1.UpdateHamCalls()[0].Msg)
+	assert.Equal(t, "ham msg", b2.UpdateHamCalls()[0].Msg)
+
+	require.NoError(t, mb.AddApprovedUser(1, "user"))
+	assert.Len(t, b1.AddApprovedUserCalls(), 1)
+	assert.Len(t, b2.AddApprovedUserCalls(), 1)
+
+	require.NoError(t, mb.SeedApprovedUser(1, "user", 3))
+	assert.Equal(t, 3, b1.SeedApprovedUserCalls()[0].Count)
+	assert.Equal(t, 3, b2.SeedApprovedUserCalls()[0].Count)
+
+	err = mb.RemoveApprovedUser(1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remove err")
+	assert.Len(t, b1.RemoveApprovedUserCalls(), 1)
+
+	assert.Equal(t, "b1", mb.OnMessage(bot.Message{}, false).Text, "messages checked by own bot only")
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"github.com/umputun/tg-spam/app/hooks"
+)
+
+//go:generate moq --out mocks/event_publisher.go --pkg mocks --with-resets --skip-ensure . EventPublisher
+
+// EventPublisher is an interface for publishing moderation events to outgoing webhooks
+type EventPublisher interface {
+	Publish(ctx context.Context, e hooks.Event) error
+}
+
+// publishEvent publishes the event, no-op if webhooks are not configured.
+// Publishing only queues the event, failures are logged and don't affect moderation.
+func publishEvent(ctx context.Context, pub EventPublisher, e hooks.Event) {
+	if pub == nil {
+		return
+	}
+	if err := pub.Publish(ctx, e); err != nil {
+		log.Printf("[WARN] failed to publish %s event: %v", e.Type, err)
+	}
+}
+
+// samplesBot wraps the bot to publish spam and ham samples added by admins and reports handling
+type samplesBot struct {
+	Bot
+	events EventPublisher
+	source string
+}
+
+// withSampleEvents returns the bot publishing added samples, or the bot itself if events are not published
+func (l *TelegramListener) withSampleEvents(b Bot, source string) Bot {
+	if l.Events == nil {
+		return b
+	}
+	return &samplesBot{Bot: b, events: l.Events, source: source}
+}
+
+// UpdateSpam updates spam samples and publishes the added sample
+func (b *samplesBot) UpdateSpam(msg string) error {
+	if err := b.Bot.UpdateSpam(msg); err != nil {
+		return err
+	}
+	b.publish(msg, "spam")
+	return nil
+}
+
+// UpdateHam updates ham samples and publishes the added sample
+func (b *samplesBot) UpdateHam(msg string) error {
+	if err := b.Bot.UpdateHam(msg); err != nil {
+		return err
+	}
+	b.publish(msg, "ham")
+	return nil
+}
+
+func (b *samplesBot) publish(msg, sampleType string) {
+	publishEvent(context.Background(), b.events, hooks.Event{Type: hooks.EventSampleAdded, Source: b.source, Text: msg,
+		Details: sampleType})
+}
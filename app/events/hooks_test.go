@@ -0,0 +1,130 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/events/mocks"
+	"github.com/umputun/tg-spam/app/hooks"
+)
+
+func TestBanUserOrChannel_PublishesEvent(t *testing.T) {
+	okAPI := func() *mocks.TbAPIMock {
+		return &mocks.TbAPIMock{RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		}}
+	}
+
+	tests := []struct {
+		name string
+		req  banRequest
+		want []hooks.Event
+	}{
+		{
+			name: "ban",
+			req:  banRequest{userID: 1, chatID: 10, userName: "spammer", duration: bot.PermanentBanDuration, source: hooks.SourceAdmin},
+			want: []hooks.Event{{Type: hooks.EventBan, Source: hooks.SourceAdmin, ChatID: 10, UserID: 1, UserName: "spammer"}},
+		},
+		{
+			name: "soft ban with duration",
+			req: banRequest{userID: 1, chatID: 10, userName: "spammer", duration: time.Hour, restrict: true,
+				source: hooks.SourceReports},
+			want: []hooks.Event{{Type: hooks.EventSoftBan, Source: hooks.SourceReports, ChatID: 10, UserID: 1,
+				UserName: "spammer", Details: "duration: 1h0m0s"}},
+		},
+		{
+			name: "channel ban",
+			req:  banRequest{userID: 1, channelID: -100, chatID: 10, duration: bot.PermanentBanDuration, source: hooks.SourceDetector},
+			want: []hooks.Event{{Type: hooks.EventBan, Source: hooks.SourceDetector, ChatID: 10, UserID: 1, ChannelID: -100}},
+		},
+		{name: "dry run", req: banRequest{userID: 1, chatID: 10, duration: time.Hour, dry: true}},
+		{name: "training", req: banRequest{userID: 1, chatID: 10, duration: time.Hour, training: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &mocks.EventPublisherMock{PublishFunc: func(ctx context.Context, e hooks.Event) error { return nil }}
+			tt.req.tbAPI, tt.req.events = okAPI(), pub
+			require.NoError(t, banUserOrChannel(tt.req))
+			var got []hooks.Event
+			for _, c := range pub.PublishCalls() {
+				got = append(got, c.E)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("failed ban is not published", func(t *testing.T) {
+		pub := &mocks.EventPublisherMock{}
+		api := &mocks.TbAPIMock{RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return nil, errors.New("api error")
+		}}
+		require.Error(t, banUserOrChannel(banRequest{userID: 1, tbAPI: api, events: pub, duration: time.Hour}))
+		assert.Empty(t, pub.PublishCalls())
+	})
+
+	t.Run("publish error does not fail the ban", func(t *testing.T) {
+		pub := &mocks.EventPublisherMock{PublishFunc: func(ctx context.Context, e hooks.Event) error {
+			return errors.New("queue error")
+		}}
+		require.NoError(t, banUserOrChannel(banRequest{userID: 1, tbAPI: okAPI(), events: pub, duration: time.Hour}))
+		assert.Len(t, pub.PublishCalls(), 1)
+	})
+}
+
+func TestAdmin_unbanPublishesEvent(t *testing.T) {
+	pub := &mocks.EventPublisherMock{PublishFunc: func(ctx context.Context, e hooks.Event) error { return nil }}
+	api := &mocks.TbAPIMock{RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+		return &tbapi.APIResponse{Ok: true}, nil
+	}}
+	locator := &mocks.LocatorMock{UserNameByIDFunc: func(ctx context.Context, userID int64) string { return "user" }}
+	a := &admin{tbAPI: api, locator: locator, primChatID: 10, events: pub}
+
+	require.NoError(t, a.unban(1))
+	require.NoError(t, a.unbanChannel(-100))
+	require.Len(t, pub.PublishCalls(), 2)
+	assert.Equal(t, hooks.Event{Type: hooks.EventUnban, Source: hooks.SourceAdmin, ChatID: 10, UserID: 1, UserName: "user"},
+		pub.PublishCalls()[0].E)
+	assert.Equal(t, hooks.Event{Type: hooks.EventUnban, Source: hooks.SourceAdmin, ChatID: 10, ChannelID: -100},
+		pub.PublishCalls()[1].E)
+
+	a.events = nil
+	require.NoError(t, a.unban(1))
+	assert.Len(t, locator.UserNameByIDCalls(), 1, "no user name lookup without events")
+}
+
+func TestTelegramListener_withSampleEvents(t *testing.T) {
+	b := &mocks.BotMock{
+		UpdateSpamFunc: func(msg string) error { return nil },
+		UpdateHamFunc: func(msg string) error {
+			if msg == "bad" {
+				return errors.New("update error")
+			}
+			return nil
+		},
+	}
+
+	l := &TelegramListener{}
+	assert.Same(t, b, l.withSampleEvents(b, hooks.SourceAdmin), "bot unchanged without events")
+
+	pub := &mocks.EventPublisherMock{PublishFunc: func(ctx context.Context, e hooks.Event) error { return nil }}
+	l.Events = pub
+	wrapped := l.withSampleEvents(b, hooks.SourceAdmin)
+	require.NoError(t, wrapped.UpdateSpam("spam msg"))
+	require.NoError(t, wrapped.UpdateHam("ham msg"))
+	require.Error(t, wrapped.UpdateHam("bad"))
+
+	assert.Len(t, b.UpdateSpamCalls(), 1)
+	assert.Len(t, b.UpdateHamCalls(), 2)
+	require.Len(t, pub.PublishCalls(), 2, "failed update not published")
+	assert.Equal(t, hooks.Event{Type: hooks.EventSampleAdded, Source: hooks.SourceAdmin, Text: "spam msg", Details: "spam"},
+		pub.PublishCalls()[0].E)
+	assert.Equal(t, hooks.Event{Type: hooks.EventSampleAdded, Source: hooks.SourceAdmin, Text: "ham msg", Details: "ham"},
+		pub.PublishCalls()[1].E)
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+//go:generate moq --out mocks/image_text_extractor.go --pkg mocks --with-resets --skip-ensure . ImageTextExtractor
+//go:generate moq --out mocks/image_hashes.go --pkg mocks --with-resets --skip-ensure . ImageHashes
+
+// ImageTextExtractor is an interface for image text extraction (OCR), see tgspam.ImageTextExtractor
+type ImageTextExtractor interface {
+	ExtractText(ctx context.Context, img []byte) (string, error)
+}
+
+// ImageHashes is an interface for the blocklist of perceptual hashes of spam images
+type ImageHashes interface {
+	Add(ctx context.Context, hash string, userID int64, userName string) error
+}
+
+const (
+	maxImageSize         = 10 * 1024 * 1024 // limits the size of downloaded images, larger images are not checked
+	imageDownloadTimeout = 30 * time.Second // timeout for image processing if not set by ImageTextConfig
+)
+
+// ImageTextConfig defines image text extraction (OCR) parameters
+type ImageTextConfig struct {
+	Extractor ImageTextExtractor // extracts text from images, nil disables extraction
+	Timeout   time.Duration      // timeout for image download and text extraction
+}
+
+// processImage downloads the image attached to the message once and sets the text extracted from it (OCR)
+// and the image perceptual hash, each one only if enabled.
+// Images from approved users and superusers are not processed as their messages skip content checks.
+// Errors are logged only, the message is checked without the image text or hash in this case.
+func (l *TelegramListener) processImage(ctx context.Context, g *chatGroup, msg *bot.Message) {
+	if l.ImageText.Extractor == nil && l.ImageHashes == nil {
+		return
+	}
+	if msg.Image == nil || msg.Image.FileID == "" {
+		return
+	}
+	senderID := msg.From.ID
+	if msg.SenderChat.ID != 0 {
+		senderID = msg.SenderChat.ID
+	}
+	if g.superUsers.IsSuper(msg.From.Username, msg.From.ID) || g.bot.IsApprovedUser(senderID) {
+		return
+	}
+
+	timeout := l.ImageText.Timeout
+	if timeout <= 0 {
+		timeout = imageDownloadTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	img, err := downloadFile(ctx, l.TbAPI, msg.Image.FileID)
+	if err != nil {
+		log.Printf("[WARN] failed to download image %s: %v", msg.Image.FileID, err)
+		return
+	}
+
+	if l.ImageHashes != nil {
+		hash, err := tgspam.ImageHash(img)
+		if err != nil {
+			log.Printf("[WARN] failed to hash image %s: %v", msg.Image.FileID, err)
+		} else {
+			msg.Image.Hash = hash
+		}
+	}
+
+	if l.ImageText.Extractor != nil {
+		st := time.Now()
+		text, err := l.ImageText.Extractor.ExtractText(ctx, img)
+		if err != nil {
+			log.Printf("[WARN] failed to extract text from image %s: %v", msg.Image.FileID, err)
+			return
+		}
+		log.Printf("[DEBUG] extracted text from image %s in %v: %q", msg.Image.FileID, time.Since(st), text)
+		msg.Image.Text = text
+	}
+}
+
+// downloadFile gets the file by telegram file id via the bot api
+func downloadFile(ctx context.Context, tbAPI TbAPI, fileID string) ([]byte, error) {
+	fileURL, err := tbAPI.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// url error includes the file url with the bot token, don't leak it to the logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("file is too large, more than %d bytes", maxImageSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/events/mocks"
+)
+
+// makeTestPNG makes a png image with brightness growing left to right, its image hash is all zeros
+func makeTestPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for x := range 64 {
+		for y := range 32 {
+			img.Set(x, y, color.Gray{Y: uint8(x * 4)})
+		}
+	}
+	buf := bytes.Buffer{}
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestTelegramListener_processImage(t *testing.T) {
+	pngData := makeTestPNG(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file/img1":
+			_, _ = w.Write([]byte("image data"))
+		case "/file/png":
+			_, _ = w.Write(pngData)
+		case "/file/large":
+			_, _ = w.Write([]byte(strings.Repeat("x", maxImageSize+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	newListener := func() (*TelegramListener, *chatGroup, *mocks.ImageTextExtractorMock) {
+		tbAPI := &mocks.TbAPIMock{
+			GetFileDirectURLFunc: func(fileID string) (string, error) {
+				if fileID == "bad" {
+					return "", errors.New("file not found")
+				}
+				return ts.URL + "/file/" + fileID, nil
+			},
+		}
+		extractor := &mocks.ImageTextExtractorMock{
+			ExtractTextFunc: func(ctx context.Context, img []byte) (string, error) { return "text from " + string(img), nil },
+		}
+		b := &mocks.BotMock{IsApprovedUserFunc: func(userID int64) bool { return userID == 99 }}
+		l := &TelegramListener{TbAPI: tbAPI, ImageText: ImageTextConfig{Extractor: extractor, Timeout: time.Second}}
+		g := &chatGroup{bot: b, superUsers: SuperUsers{"admin"}}
+		return l, g, extractor
+	}
+
+	t.Run("text extracted", func(t *testing.T) {
+		l, g, extractor := newListener()
+		msg := &bot.Message{From: bot.User{ID: 1, Username: "user"}, Image: &bot.Image{FileID: "img1"}}
+		l.processImage(context.Background(), g, msg)
+		assert.Equal(t, "text from image data", msg.Image.Text)
+		require.Len(t, extractor.ExtractTextCalls(), 1)
+		assert.Equal(t, "image data", string(extractor.ExtractTextCalls()[0].Img))
+	})
+
+	t.Run("approved users and superusers skipped", func(t *testing.T) {
+		l, g, extractor := newListener()
+		msg := &bot.Message{From: bot.User{ID: 99, Username: "user"}, Image: &bot.Image{FileID: "img1"}}
+		l.processImage(context.Background(), g, msg)
+		msg = &bot.Message{From: bot.User{ID: 2, Username: "admin"}, Image: &bot.Image{FileID: "img1"}}
+		l.processImage(context.Background(), g, msg)
+		assert.Empty(t, msg.Image.Text)
+		assert.Empty(t, extractor.ExtractTextCalls())
+	})
+
+	t.Run("no image or no extractor", func(t *testing.T) {
+		l, g, extractor := newListener()
+		l.processImage(context.Background(), g, &bot.Message{From: bot.User{ID: 1}, Text: "text"})
+		l.ImageText.Extractor = nil
+		msg := &bot.Message{From: bot.User{ID: 1}, Image: &bot.Image{FileID: "img1"}}
+		l.processImage(context.Background(), g, msg)
+		assert.Empty(t, msg.Image.Text)
+		assert.Empty(t, extractor.ExtractTextCalls())
+	})
+
+	t.Run("download failures", func(t *testing.T) {
+		l, g, extractor := newListener()
+		for _, fileID := range []string{"bad", "missing", "large"} {
+			msg := &bot.Message{From: bot.User{ID: 1}, Image: &bot.Image{FileID: fileID}}
+			l.processImage(context.Background(), g, msg)
+			assert.Empty(t, msg.Image.Text, fileID)
+		}
+		assert.Empty(t, extractor.ExtractTextCalls())
+	})
+
+	t.Run("image hash calculated", func(t *testing.T) {
+		l, g, _ := newListener()
+		l.ImageText.Extractor = nil
+		l.ImageHashes = &mocks.ImageHashesMock{}
+		msg := &bot.Message{From: bot.User{ID: 1}, Image: &bot.Image{FileID: "png"}}
+		l.processImage(context.Background(), g, msg)
+		assert.Equal(t, "0000000000000000", msg.Image.Hash)
+		assert.Empty(t, msg.Image.Text)
+	})
+
+	t.Run("hash failure doesn't prevent text extraction", func(t *testing.T) {
+		l, g, extractor := newListener()
+		l.ImageHashes = &mocks.ImageHashesMock{}
+		msg := &bot.Message{From: bot.User{ID: 1}, Image: &bot.Image{FileID: "img1"}}
+		l.processImage(context.Background(), g, msg)
+		assert.Empty(t, msg.Image.Hash)
+		assert.Equal(t, "text from image data", msg.Image.Text)
+		require.Len(t, extractor.ExtractTextCalls(), 1)
+	})
+
+	t.Run("extraction failure", func(t *testing.T) {
+		l, g, extractor := newListener()
+		extractor.ExtractTextFunc = func(ctx context.Context, img []byte) (string, error) { return "", errors.New("ocr error") }
+		msg := &bot.Message{From: bot.User{ID: 1}, Image: &bot.Image{FileID: "img1"}}
+		l.processImage(context.Background(), g, msg)
+		assert.Empty(t, msg.Image.Text)
+		require.Len(t, extractor.ExtractTextCalls(), 1)
+	})
+}
+
+func Test_downloadFile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	}))
+	defer ts.Close()
+
+	t.Run("download error hides file url", func(t *testing.T) {
+		tbAPI := &mocks.TbAPIMock{GetFileDirectURLFunc: func(fileID string) (string, error) {
+			return "http://127.0.0.1:1/file/bot-secret-token/photo.jpg", nil
+		}}
+		_, err := downloadFile(context.Background(), tbAPI, "f1")
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret-token")
+	})
+
+	t.Run("successful download", func(t *testing.T) {
+		tbAPI := &mocks.TbAPIMock{GetFileDirectURLFunc: func(fileID string) (string, error) { return ts.URL + "/" + fileID, nil }}
+		data, err := downloadFile(context.Background(), tbAPI, "f1")
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+		require.Len(t, tbAPI.GetFileDirectURLCalls(), 1)
+		assert.Equal(t, "f1", tbAPI.GetFileDirectURLCalls()[0].FileID)
+	})
+}
@@ -15,12 +15,15 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tbapi "github.com/OvyFlash/telegram-bot-api"
 	"github.com/hashicorp/go-multierror"
 
 	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/lib/spamcheck"
 )
 
@@ -42,48 +45,81 @@ func (f SpamLoggerFunc) Save(msg *bot.Message, response *bot.Response) {
 // TelegramListener listens to tg update, forward to bots and send back responses
 // Not thread safe
 type TelegramListener struct {
-	TbAPI                   TbAPI         // telegram bot API
-	SpamLogger              SpamLogger    // logger to save spam to files and db
-	Bot                     Bot           // bot to handle messages
-	BotUsername             string        // telegram bot username (without "@" prefix)
-	Group                   string        // can be int64 or public group username (without "@" prefix)
-	AdminGroup              string        // can be int64 or public group username (without "@" prefix)
-	IdleDuration            time.Duration // idle timeout to send "idle" message to bots
-	SuperUsers              SuperUsers    // list of superusers, can ban and report spam, can't be banned
-	TestingIDs              []int64       // list of chat IDs to test the bot
-	StartupMsg              string        // message to send on startup to the primary chat
-	WarnMsg                 string        // message to send on warning
-	NoSpamReply             bool          // do not reply on spam messages in the primary chat
-	SuppressJoinMessage     bool          // delete join message when kick out user
-	DeleteJoinMessages      bool          // delete join messages immediately
-	DeleteLeaveMessages     bool          // delete leave messages immediately
-	TrainingMode            bool          // do not ban users, just report and train spam detector
-	SoftBanMode             bool          // do not ban users, but restrict their actions
-	Locator                 Locator       // message locator to get info about messages
-	ReportConfig            ReportConfig  // user spam reporting configuration
-	DisableAdminSpamForward bool          // disable forwarding spam reports to admin chat support
-	Dry                     bool          // dry run, do not ban or send messages
-	AggressiveCleanup       bool          // delete all messages from user when banned via /spam command
-	AggressiveCleanupLimit  int           // max messages to delete in aggressive cleanup mode
-	WarnThreshold           int           // auto-ban after N warns within window (0=disabled)
-	WarnWindow              time.Duration // sliding window for counting warns
-	Warnings                Warnings      // storage for admin /warn records
+	TbAPI                   TbAPI           // telegram bot API
+	SpamLogger              SpamLogger      // logger to save spam to files and db
+	Bot                     Bot             // bot to handle messages
+	BotUsername             string          // telegram bot username (without "@" prefix)
+	Group                   string          // can be int64 or public group username (without "@" prefix)
+	AdminGroup              string          // can be int64 or public group username (without "@" prefix)
+	Groups                  []GroupConfig   // additional groups to protect, each with own admin chat and superusers
+	IdleDuration            time.Duration   // idle timeout to send "idle" message to bots
+	SuperUsers              SuperUsers      // list of superusers, can ban and report spam, can't be banned
+	TestingIDs              []int64         // list of chat IDs to test the bot
+	StartupMsg              string          // message to send on startup to the primary chat
+	WarnMsg                 string          // message to send on warning
+	NoSpamReply             bool            // do not reply on spam messages in the primary chat
+	SuppressJoinMessage     bool            // delete join message when kick out user
+	DeleteJoinMessages      bool            // delete join messages immediately
+	DeleteLeaveMessages     bool            // delete leave messages immediately
+	TrainingMode            bool            // do not ban users, just report and train spam detector
+	SoftBanMode             bool            // do not ban users, but restrict their actions
+	Locator                 Locator         // message locator to get info about messages
+	ReportConfig            ReportConfig    // user spam reporting configuration
+	DisableAdminSpamForward bool            // disable forwarding spam reports to admin chat support
+	Dry                     bool            // dry run, do not ban or send messages
+	AggressiveCleanup       bool            // delete all messages from user when banned via /spam command
+	AggressiveCleanupLimit  int             // max messages to delete in aggressive cleanup mode
+	WarnThreshold           int             // auto-ban after N warns within window (0=disabled)
+	WarnWindow              time.Duration   // sliding window for counting warns
+	Warnings                Warnings        // storage for admin /warn records
+	Captcha                 CaptchaConfig   // join challenge (captcha) configuration
+	ImageText               ImageTextConfig // image text extraction (OCR) configuration
+	ImageHashes             ImageHashes     // spam image hashes storage, enables image hashing if set
+	Reviews                 ReviewQueue     // review queue, suspicious messages are quarantined for admin review if set
+	Reputation              Reputation      // per-user history of checked messages, spam, warnings and reports if set
+	Webhook                 WebhookConfig   // webhook mode configuration, updates are long polled if URL is empty
+	Events                  EventPublisher  // publishes moderation events to outgoing webhooks if set
+	Punishment              PunishConfig    // escalating punishment ladder, spammers are banned permanently if disabled
+	AuditLog                AuditLog        // log of actions made by admins in telegram if set
 
 	adminHandler    *admin
 	reportsHandler  *userReports
-	dmUsers         dmUsers // recent DM senders, stored in memory for admin UI
+	captcha         *joinCaptcha // join challenge handler, nil if disabled
+	dmUsers         dmUsers      // recent DM senders, stored in memory for admin UI
 	chatID          int64
 	adminChatID     int64
-	linkedChannelID int64 // channel linked to the discussion group, resolved at startup
+	linkedChannelID int64                // channel linked to the discussion group, resolved at startup
+	groups          map[int64]*chatGroup // additional groups by chat ID, resolved at startup
 
 	msgs struct {
 		once sync.Once
 		ch   chan bot.Response
 	}
 
+	webhook struct { // updates posted to the webhook, created on first use by Do or HandleWebhook
+		once sync.Once
+		ch   chan tbapi.Update
+	}
+
 	// serializes extra-message deletion goroutines so concurrent spam bursts still respect
 	// the per-request rate limiting inside deleteExtraMessages
 	extraDeletesMu sync.Mutex
+
+	pendingFlags atomic.Pointer[ListenerFlags] // set by Reconfigure, applied by the update loop before the next update
+}
+
+// ListenerFlags are listener settings which can be changed while the listener is running, see Reconfigure
+type ListenerFlags struct {
+	WarnMsg                 string // message to send on warning
+	NoSpamReply             bool   // do not reply on spam messages in the primary chat
+	SuppressJoinMessage     bool   // delete join message when kick out user
+	DeleteJoinMessages      bool   // delete join messages immediately
+	DeleteLeaveMessages     bool   // delete leave messages immediately
+	TrainingMode            bool   // do not ban users, just report and train spam detector
+	SoftBanMode             bool   // do not ban users, but restrict their actions
+	DisableAdminSpamForward bool   // disable forwarding spam reports to admin chat support
+	AggressiveCleanup       bool   // delete all messages from user when banned via /spam command
+	AggressiveCleanupLimit  int    // max messages to delete in aggressive cleanup mode
 }
 
 // GetDMUsers returns the list of recent DM senders
@@ -91,6 +127,38 @@ func (l *TelegramListener) GetDMUsers() []DMUser {
 	return l.dmUsers.List()
 }
 
+// Reconfigure changes listener flags while the listener is running. Updates are processed one by one, so the flags
+// are applied between updates: the update in progress completes with the previous flags and none is dropped.
+// If called several times before the next update, the last flags win. Safe to call concurrently with Do.
+func (l *TelegramListener) Reconfigure(flags ListenerFlags) {
+	l.pendingFlags.Store(&flags)
+}
+
+// applyPendingFlags applies flags set by Reconfigure, if any, and remakes admin and report handlers,
+// as they copy the flags on creation. Called by the update loop only.
+func (l *TelegramListener) applyPendingFlags() {
+	f := l.pendingFlags.Swap(nil)
+	if f == nil {
+		return
+	}
+	l.WarnMsg, l.NoSpamReply, l.SuppressJoinMessage = f.WarnMsg, f.NoSpamReply, f.SuppressJoinMessage
+	l.DeleteJoinMessages, l.DeleteLeaveMessages = f.DeleteJoinMessages, f.DeleteLeaveMessages
+	l.TrainingMode, l.SoftBanMode, l.DisableAdminSpamForward = f.TrainingMode, f.SoftBanMode, f.DisableAdminSpamForward
+	l.AggressiveCleanup, l.AggressiveCleanupLimit = f.AggressiveCleanup, f.AggressiveCleanupLimit
+
+	primary := l.primaryGroup()
+	l.adminHandler = l.makeAdminHandler(primary)
+	l.reportsHandler = l.makeReportsHandler(primary)
+	for _, g := range l.groups {
+		g.adminHandler = l.makeAdminHandler(g)
+		g.reportsHandler = l.makeReportsHandler(g)
+	}
+	if l.captcha != nil {
+		l.captcha.trainingMode = l.TrainingMode
+	}
+	log.Printf("[INFO] telegram listener reconfigured, %+v", *f)
+}
+
 // Do process all events, blocked call
 func (l *TelegramListener) Do(ctx context.Context) error {
 	log.Printf("[INFO] start telegram listener for %q", l.Group)
@@ -119,6 +187,8 @@ func (l *TelegramListener) Do(ctx context.Context) error {
 		log.Printf("[INFO] linked channel ID: %d", l.linkedChannelID)
 	}
 
+	// configured superusers apply to all groups, chat admins only to their own group
+	configuredSupers := slices.Clone(l.SuperUsers)
 	if err := l.updateSupers(); err != nil {
 		log.Printf("[WARN] failed to update superusers: %v", err)
 	}
@@ -138,28 +208,17 @@ func (l *TelegramListener) Do(ctx context.Context) error {
 		}
 	})
 
-	// send startup message if any set
-	if l.StartupMsg != "" && !l.TrainingMode && !l.Dry {
-		if err := l.sendBotResponse(bot.Response{Send: true, Text: l.StartupMsg}, l.chatID, NotificationSilent); err != nil {
-			log.Printf("[WARN] failed to send startup message, %v", err)
-		} else {
-			log.Printf("[DEBUG] startup message sent")
-		}
-	}
+	primary := l.primaryGroup()
+	l.adminHandler = l.makeAdminHandler(primary)
+	l.reportsHandler = l.makeReportsHandler(primary)
 
-	l.adminHandler = &admin{
-		tbAPI: l.TbAPI, bot: l.Bot, locator: l.Locator, superUsers: l.SuperUsers,
-		primChatID: l.chatID, adminChatID: l.adminChatID,
-		trainingMode: l.TrainingMode, softBan: l.SoftBanMode, dry: l.Dry, warnMsg: l.WarnMsg,
-		aggressiveCleanup: l.AggressiveCleanup, aggressiveCleanupLimit: l.AggressiveCleanupLimit,
-		warnings: l.Warnings, warnThreshold: l.WarnThreshold, warnWindow: l.WarnWindow,
+	if err := l.setupGroups(configuredSupers); err != nil {
+		return fmt.Errorf("failed to setup additional groups: %w", err)
 	}
 
-	l.reportsHandler = &userReports{
-		ReportConfig: l.ReportConfig,
-		tbAPI:        l.TbAPI, bot: l.Bot, locator: l.Locator, superUsers: l.SuperUsers,
-		primChatID: l.chatID, adminChatID: l.adminChatID,
-		trainingMode: l.TrainingMode, softBanMode: l.SoftBanMode, dry: l.Dry,
+	// send startup message if any set
+	if l.StartupMsg != "" && !l.TrainingMode && !l.Dry {
+		l.sendStartupMsg()
 	}
 
 	adminForwardStatus := "enabled"
@@ -173,12 +232,19 @@ func (l *TelegramListener) Do(ctx context.Context) error {
 			l.AggressiveCleanupLimit)
 	}
 
-	u := tbapi.NewUpdate(0)
-	u.Timeout = 60
-	u.AllowedUpdates = []string{"message", "edited_message", "callback_query", "message_reaction"}
+	// expired challenges are checked periodically, the channel stays nil (never fires) if captcha is disabled
+	var captchaCheck <-chan time.Time
+	if l.Captcha.Enabled {
+		l.captcha = &joinCaptcha{CaptchaConfig: l.Captcha, tbAPI: l.TbAPI, bot: l.trainingBot(l.Bot),
+			dry: l.Dry, trainingMode: l.TrainingMode, events: l.Events}
+		ticker := time.NewTicker(captchaCheckInterval)
+		defer ticker.Stop()
+		captchaCheck = ticker.C
+		log.Printf("[INFO] join captcha enabled, type: %s, timeout: %v, action: %s",
+			l.Captcha.Type, l.Captcha.Timeout, l.Captcha.Action)
+	}
 
-	updates := l.TbAPI.GetUpdatesChan(u)
-	log.Printf("[DEBUG] start listening for updates")
+	updates := l.updatesChan()
 	// single reusable idle timer, re-armed each iteration: time.After in a loop leaks one
 	// live timer per update until it fires
 	idleTimer := time.NewTimer(l.IdleDuration)
@@ -193,163 +259,191 @@ func (l *TelegramListener) Do(ctx context.Context) error {
 			if !ok {
 				return fmt.Errorf("telegram update chan closed")
 			}
+			l.applyPendingFlags()
+			l.procUpdate(ctx, update)
 
-			// handle admin chat messages. can be just messages (MsgHandler will ignore those)
-			// or forwards of undetected spam by admins to admin's chat (in this case MsgHandler will process them and ban/train)
-			if update.Message != nil && update.Message.From != nil &&
-				l.isAdminChat(update.Message.Chat.ID, update.Message.From.UserName, update.Message.From.ID) {
-				if l.DisableAdminSpamForward {
-					continue
-				}
-				if err := l.adminHandler.MsgHandler(update); err != nil {
-					log.Printf("[WARN] failed to process admin chat message: %v", err)
-					errResp := l.sendBotResponse(bot.Response{Send: true, Text: "error: " + err.Error()}, l.adminChatID, NotificationDefault)
-					if errResp != nil {
-						log.Printf("[WARN] failed to respond on error, %v", errResp)
-					}
-				}
-				continue
-			}
+		case <-captchaCheck:
+			l.applyPendingFlags()
+			l.captcha.Expire(ctx)
 
-			// handle admin chat inline buttons - route based on callback prefix
-			if update.CallbackQuery != nil {
-				callbackData := update.CallbackQuery.Data
-
-				// delegate report callbacks (prefixes R+, R-, R?, R!, RX) to reportsHandler
-				if len(callbackData) >= 3 && callbackData[:1] == "R" {
-					if err := l.reportsHandler.HandleReportCallback(ctx, update.CallbackQuery); err != nil {
-						log.Printf("[WARN] failed to process report callback: %v", err)
-						errResp := l.sendBotResponse(bot.Response{Send: true, Text: "error: " + err.Error()}, l.adminChatID, NotificationDefault)
-						if errResp != nil {
-							log.Printf("[WARN] failed to respond on error, %v", errResp)
-						}
-					}
-				} else {
-					// all other callbacks (?, +, !, or no prefix) go to admin handler
-					if err := l.adminHandler.InlineCallbackHandler(update.CallbackQuery); err != nil {
-						log.Printf("[WARN] failed to process callback: %v", err)
-						errResp := l.sendBotResponse(bot.Response{Send: true, Text: "error: " + err.Error()}, l.adminChatID, NotificationDefault)
-						if errResp != nil {
-							log.Printf("[WARN] failed to respond on error, %v", errResp)
-						}
-					}
-				}
-				continue
+		case <-idleTimer.C: // hit bots on idle timeout
+			l.applyPendingFlags()
+			resp := l.Bot.OnMessage(bot.Message{Text: "idle"}, false)
+			if err := l.sendBotResponse(resp, l.chatID, NotificationSilent); err != nil {
+				log.Printf("[WARN] failed to respond on idle, %v", err)
 			}
+		}
+	}
+}
 
-			// handle edited messages
-			if update.EditedMessage != nil {
-				log.Printf("[INFO] processing edited message, id: %d", update.EditedMessage.MessageID)
-				// we need to process an edited message as a new message, so we create a new update object
-				// and copy the edited message to the message field.
-				editedUpdate := tbapi.Update{
-					Message: update.EditedMessage,
-				}
-				if err := l.procEvents(editedUpdate); err != nil {
-					log.Printf("[WARN] failed to process edited message update: %v", err)
-				}
-				continue
+// procUpdate dispatches a single telegram update to the handlers, used for both polling and webhook updates
+func (l *TelegramListener) procUpdate(ctx context.Context, update tbapi.Update) {
+	// handle admin chat messages. can be just messages (MsgHandler will ignore those)
+	// or forwards of undetected spam by admins to admin's chat (in this case MsgHandler will process them and ban/train)
+	if update.Message != nil && update.Message.From != nil &&
+		l.isAdminChat(update.Message.Chat.ID, update.Message.From.UserName, update.Message.From.ID) {
+		if l.DisableAdminSpamForward {
+			return
+		}
+		g := l.adminGroupFor(update.Message.Chat.ID)
+		if err := g.adminHandler.MsgHandler(update); err != nil {
+			log.Printf("[WARN] failed to process admin chat message: %v", err)
+			errResp := l.sendBotResponse(bot.Response{Send: true, Text: "error: " + err.Error()}, g.adminChatID, NotificationDefault)
+			if errResp != nil {
+				log.Printf("[WARN] failed to respond on error, %v", errResp)
 			}
+		}
+		return
+	}
 
-			if update.MessageReaction != nil {
-				if err := l.procReaction(ctx, update.MessageReaction); err != nil {
-					log.Printf("[WARN] failed to process reaction: %v", err)
-				}
-				continue
-			}
+	// handle admin chat inline buttons - route based on callback prefix
+	if update.CallbackQuery != nil {
+		callbackData := update.CallbackQuery.Data
 
-			if update.Message == nil {
-				continue
+		// join challenge buttons are posted to the protected chat itself, not to the admin chat
+		if l.captcha != nil && strings.HasPrefix(callbackData, captchaPrefix) {
+			if err := l.captcha.HandleCallback(ctx, update.CallbackQuery); err != nil {
+				log.Printf("[WARN] failed to process join challenge callback: %v", err)
 			}
+			return
+		}
 
-			if update.Message.NewChatMembers != nil {
-				// handle join messages with mutually exclusive logic to prevent double-deletion:
-				// - if DeleteJoinMessages=true: delete immediately, don't store in locator
-				// - if DeleteJoinMessages=false: store in locator for potential later deletion via SuppressJoinMessage
-				// this prevents "message not found" errors when both flags are enabled
-				if l.DeleteJoinMessages {
-					l.deleteSystemMessage(update.Message.MessageID, update.Message.Chat.ID, "join")
-				} else {
-					err := l.procNewChatMemberMessage(update)
-					if err != nil {
-						log.Printf("[WARN] failed to process new chat member: %v", err)
-					}
-				}
-				continue
+		// callbacks are routed to the group served by the admin chat the buttons belong to,
+		// callbacks from other chats go to the primary group handlers which ignore them
+		g := l.primaryGroup()
+		if update.CallbackQuery.Message != nil {
+			if ag := l.adminGroupFor(update.CallbackQuery.Message.Chat.ID); ag != nil {
+				g = ag
 			}
+		}
 
-			// handle left member messages, i.e. "blah blah removed from the chat"
-			if update.Message.LeftChatMember != nil {
-				if l.SuppressJoinMessage {
-					// delete the stored join message when user leaves
-					err := l.procLeftChatMemberMessage(update)
-					if err != nil {
-						log.Printf("[WARN] failed to process left chat member: %v", err)
-					}
-				}
-				// immediately delete leave message if requested
-				if l.DeleteLeaveMessages {
-					l.deleteSystemMessage(update.Message.MessageID, update.Message.Chat.ID, "leave")
+		// delegate report callbacks (prefixes R+, R-, R?, R!, RX) to reportsHandler
+		if len(callbackData) >= 3 && callbackData[:1] == "R" {
+			if err := g.reportsHandler.HandleReportCallback(ctx, update.CallbackQuery); err != nil {
+				log.Printf("[WARN] failed to process report callback: %v", err)
+				errResp := l.sendBotResponse(bot.Response{Send: true, Text: "error: " + err.Error()}, g.adminChatID, NotificationDefault)
+				if errResp != nil {
+					log.Printf("[WARN] failed to respond on error, %v", errResp)
 				}
-				continue
 			}
-
-			// messages without a sender can't be matched against superusers or report commands,
-			// send them straight to the regular processing which handles nil From safely
-			if update.Message.From == nil {
-				if err := l.procEvents(update); err != nil {
-					log.Printf("[WARN] failed to process update: %v", err)
+		} else {
+			// all other callbacks (?, +, !, or no prefix) go to admin handler
+			if err := g.adminHandler.InlineCallbackHandler(update.CallbackQuery); err != nil {
+				log.Printf("[WARN] failed to process callback: %v", err)
+				errResp := l.sendBotResponse(bot.Response{Send: true, Text: "error: " + err.Error()}, g.adminChatID, NotificationDefault)
+				if errResp != nil {
+					log.Printf("[WARN] failed to respond on error, %v", errResp)
 				}
-				continue
 			}
+		}
+		return
+	}
 
-			// handle spam reports from superusers and linked channel
-			fromSuper := l.SuperUsers.IsSuper(update.Message.From.UserName, update.Message.From.ID) ||
-				l.isLinkedChannel(update.Message)
-			if update.Message.ReplyToMessage != nil && fromSuper {
-				if l.procSuperReply(update) {
-					// superuser command processed, skip the rest
-					continue
-				}
-			}
+	// handle edited messages
+	if update.EditedMessage != nil {
+		log.Printf("[INFO] processing edited message, id: %d", update.EditedMessage.MessageID)
+		// we need to process an edited message as a new message, so we create a new update object
+		// and copy the edited message to the message field.
+		editedUpdate := tbapi.Update{
+			Message: update.EditedMessage,
+		}
+		if err := l.procEvents(editedUpdate); err != nil {
+			log.Printf("[WARN] failed to process edited message update: %v", err)
+		}
+		return
+	}
 
-			// delete orphaned report commands (sent without replying to a message)
-			if !fromSuper && l.isReportCommand(update.Message.Text) && update.Message.ReplyToMessage == nil {
-				log.Printf("[DEBUG] deleting orphaned report command %q from %s (%d)",
-					update.Message.Text, update.Message.From.UserName, update.Message.From.ID)
-				_, err := l.TbAPI.Request(tbapi.DeleteMessageConfig{BaseChatMessage: tbapi.BaseChatMessage{
-					MessageID:  update.Message.MessageID,
-					ChatConfig: tbapi.ChatConfig{ChatID: update.Message.Chat.ID},
-				}})
-				if err != nil {
-					log.Printf("[WARN] failed to delete orphaned report message %d: %v", update.Message.MessageID, err)
-				}
-				continue
-			}
+	if update.MessageReaction != nil {
+		if err := l.procReaction(ctx, update.MessageReaction); err != nil {
+			log.Printf("[WARN] failed to process reaction: %v", err)
+		}
+		return
+	}
 
-			// handle spam reports from regular users. senders posting on behalf of a chat
-			// (anonymous admin, "post as channel") are excluded: their From is a telegram pseudo-user
-			// which can never be an approved reporter, so the report would be dropped after the
-			// command message is already deleted
-			if update.Message.ReplyToMessage != nil && !fromSuper && update.Message.SenderChat == nil {
-				if l.procUserReply(ctx, update) {
-					// user command processed, skip the rest
-					continue
-				}
-			}
+	if update.Message == nil {
+		return
+	}
 
-			// process regular messages, the main part of the bot
-			if err := l.procEvents(update); err != nil {
-				log.Printf("[WARN] failed to process update: %v", err)
-				continue
+	if update.Message.NewChatMembers != nil {
+		// handle join messages with mutually exclusive logic to prevent double-deletion:
+		// - if DeleteJoinMessages=true: delete immediately, don't store in locator
+		// - if DeleteJoinMessages=false: store in locator for potential later deletion via SuppressJoinMessage
+		// this prevents "message not found" errors when both flags are enabled
+		if l.DeleteJoinMessages {
+			l.deleteSystemMessage(update.Message.MessageID, update.Message.Chat.ID, "join")
+		} else {
+			err := l.procNewChatMemberMessage(update)
+			if err != nil {
+				log.Printf("[WARN] failed to process new chat member: %v", err)
 			}
+		}
+		l.challengeNewMembers(ctx, update.Message)
+		return
+	}
 
-		case <-idleTimer.C: // hit bots on idle timeout
-			resp := l.Bot.OnMessage(bot.Message{Text: "idle"}, false)
-			if err := l.sendBotResponse(resp, l.chatID, NotificationSilent); err != nil {
-				log.Printf("[WARN] failed to respond on idle, %v", err)
+	// handle left member messages, i.e. "blah blah removed from the chat"
+	if update.Message.LeftChatMember != nil {
+		if l.SuppressJoinMessage {
+			// delete the stored join message when user leaves
+			err := l.procLeftChatMemberMessage(update)
+			if err != nil {
+				log.Printf("[WARN] failed to process left chat member: %v", err)
 			}
 		}
+		// immediately delete leave message if requested
+		if l.DeleteLeaveMessages {
+			l.deleteSystemMessage(update.Message.MessageID, update.Message.Chat.ID, "leave")
+		}
+		return
+	}
+
+	// messages without a sender can't be matched against superusers or report commands,
+	// send them straight to the regular processing which handles nil From safely
+	if update.Message.From == nil {
+		if err := l.procEvents(update); err != nil {
+			log.Printf("[WARN] failed to process update: %v", err)
+		}
+		return
+	}
+
+	// handle spam reports from superusers and linked channel
+	fromSuper := l.groupFor(update.Message.Chat.ID).superUsers.IsSuper(update.Message.From.UserName, update.Message.From.ID) ||
+		l.isLinkedChannel(update.Message)
+	if update.Message.ReplyToMessage != nil && fromSuper {
+		if l.procSuperReply(update) {
+			// superuser command processed, skip the rest
+			return
+		}
+	}
+
+	// delete orphaned report commands (sent without replying to a message)
+	if !fromSuper && l.isReportCommand(update.Message.Text) && update.Message.ReplyToMessage == nil {
+		log.Printf("[DEBUG] deleting orphaned report command %q from %s (%d)",
+			update.Message.Text, update.Message.From.UserName, update.Message.From.ID)
+		_, err := l.TbAPI.Request(tbapi.DeleteMessageConfig{BaseChatMessage: tbapi.BaseChatMessage{
+			MessageID:  update.Message.MessageID,
+			ChatConfig: tbapi.ChatConfig{ChatID: update.Message.Chat.ID},
+		}})
+		if err != nil {
+			log.Printf("[WARN] failed to delete orphaned report message %d: %v", update.Message.MessageID, err)
+		}
+		return
+	}
+
+	// handle spam reports from regular users. senders posting on behalf of a chat
+	// (anonymous admin, "post as channel") are excluded: their From is a telegram pseudo-user
+	// which can never be an approved reporter, so the report would be dropped after the
+	// command message is already deleted
+	if update.Message.ReplyToMessage != nil && !fromSuper && update.Message.SenderChat == nil {
+		if l.procUserReply(ctx, update) {
+			// user command processed, skip the rest
+			return
+		}
+	}
+
+	// process regular messages, the main part of the bot
+	if err := l.procEvents(update); err != nil {
+		log.Printf("[WARN] failed to process update: %v", err)
 	}
 }
 
@@ -405,17 +499,33 @@ func (l *TelegramListener) procEvents(update tbapi.Update) error {
 		log.Printf("[WARN] failed to add message to locator: %v", err)
 	}
 
+	g := l.groupFor(fromChat)
+
 	// skip spam check for anonymous admin posts from this group or from the linked channel.
 	// when admins post "as the group", SenderChat.ID equals the group's chat ID;
 	// when the linked channel posts, SenderChat.ID equals the linked channel ID.
-	if msg.SenderChat.ID != 0 && (msg.SenderChat.ID == fromChat || msg.SenderChat.ID == l.linkedChannelID) {
+	if msg.SenderChat.ID != 0 && (msg.SenderChat.ID == fromChat || msg.SenderChat.ID == g.linkedChannelID) {
 		log.Printf("[DEBUG] skipping spam check for anonymous admin post from group itself or linked channel")
 		return nil
 	}
 
-	resp := l.Bot.OnMessage(*msg, false)
+	l.processImage(ctx, g, msg)
+	resp := g.bot.OnMessage(*msg, false)
+	recordReputation(ctx, l.Reputation, locatorUserID, locatorUserName, storage.RepMessageChecked)
 
 	if !resp.Send { // not spam
+		if g.adminChatID == 0 || !slices.ContainsFunc(resp.CheckResults, func(r spamcheck.Response) bool { return r.Suspicious }) {
+			return nil
+		}
+		if l.Reviews != nil && !g.superUsers.IsSuper(msg.From.Username, msg.From.ID) {
+			err := g.adminHandler.QuarantineSuspicious(ctx, bot.DisplayName(*msg), msg, resp.CheckResults)
+			if err == nil {
+				return nil
+			}
+			log.Printf("[WARN] failed to quarantine suspicious message, reported only: %v", err)
+		}
+		log.Printf("[INFO] suspicious message from %s reported to admin chat", bot.DisplayName(*msg))
+		g.adminHandler.ReportSuspicious(bot.DisplayName(*msg), msg, resp.CheckResults)
 		return nil
 	}
 
@@ -431,45 +541,67 @@ func (l *TelegramListener) procEvents(update tbapi.Update) error {
 	// ban user if requested by bot
 	if resp.Send && resp.BanInterval > 0 {
 		log.Printf("[DEBUG] ban initiated for %+v", resp)
-		l.SpamLogger.Save(msg, &resp)
 		spamUserID := msg.From.ID
 		if msg.SenderChat.ID != 0 {
 			spamUserID = msg.SenderChat.ID
 		}
+		isSuper := g.superUsers.IsSuper(msg.From.Username, msg.From.ID)
+
+		// the ladder replaces the permanent ban requested by bot with the punishment for this offense.
+		// it is picked before the detection is saved, as temporary punishments are not published to federation peers
+		pn := punishment{duration: resp.BanInterval}
+		if resp.BanInterval == bot.PermanentBanDuration && !isSuper {
+			pn = l.Punishment.next(ctx, spamUserID, resp.CheckResults)
+		}
+		resp.Dry = l.Dry || l.TrainingMode
+		resp.Temporary = pn.duration < bot.PermanentBanDuration
+		l.SpamLogger.Save(msg, &resp)
 		if err := l.Locator.AddSpam(ctx, spamUserID, resp.CheckResults); err != nil {
 			log.Printf("[WARN] failed to add spam to locator: %v", err)
 		}
+		recordReputation(ctx, l.Reputation, spamUserID, locatorUserName, storage.RepSpamHit)
 		banUserStr := l.getBanUsername(resp, update)
+		publishEvent(ctx, l.Events, hooks.Event{Type: hooks.EventSpamDetected, Source: hooks.SourceDetector, ChatID: fromChat,
+			UserID: msg.From.ID, UserName: banUserStr, ChannelID: resp.ChannelID, Text: msg.Text, Checks: resp.CheckResults})
 
-		if l.SuperUsers.IsSuper(msg.From.Username, msg.From.ID) {
+		if isSuper {
 			if l.TrainingMode {
-				l.adminHandler.ReportBan(banUserStr, msg)
+				g.adminHandler.ReportBan(banUserStr, msg, permanentBan)
 			}
 			log.Printf("[DEBUG] superuser %s requested ban, ignored", banUserStr)
 			return nil
 		}
 
-		banReq := banRequest{duration: resp.BanInterval, userID: resp.User.ID, channelID: resp.ChannelID, userName: banUserStr,
-			chatID: fromChat, dry: l.Dry, training: l.TrainingMode, tbAPI: l.TbAPI, restrict: l.SoftBanMode}
+		banReq := banRequest{duration: pn.duration, userID: resp.User.ID, channelID: resp.ChannelID, userName: banUserStr,
+			chatID: fromChat, dry: l.Dry, training: l.TrainingMode, tbAPI: l.TbAPI, restrict: l.SoftBanMode || pn.restrict,
+			events: l.Events, source: hooks.SourceDetector}
 		if err := banUserOrChannel(banReq); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("failed to ban %s: %w", banUserStr, err))
-		} else if l.adminChatID != 0 && msg.From.ID != 0 {
-			l.adminHandler.ReportBan(banUserStr, msg)
+		} else {
+			if !l.Dry && !l.TrainingMode {
+				l.Punishment.record(ctx, spamUserID, locatorUserName, resp.CheckResults, pn)
+			}
+			if g.adminChatID != 0 && msg.From.ID != 0 {
+				g.adminHandler.ReportBan(banUserStr, msg, pn)
+			}
 		}
 	}
 
 	// delete extra messages if spam detected (e.g., duplicates); runs in a goroutine because the
 	// rate-limit sleeps between deletions would otherwise stall the single-threaded update loop,
-	// same pattern as admin's aggressiveCleanup
-	go l.deleteExtraMessages(resp.CheckResults, msg.From.ID, msg.From.Username, fromChat)
+	// same pattern as admin's aggressiveCleanup. flags and super users are resolved here, as Reconfigure
+	// and updates of chat admins change them while the goroutine runs
+	if !l.Dry && !l.TrainingMode {
+		go l.deleteExtraMessages(resp.CheckResults, g.superUsers, msg.From.ID, msg.From.Username, fromChat)
+	}
 
 	// delete message if requested by bot
 	canDelete := resp.DeleteReplyTo && resp.ReplyTo != 0 && !l.Dry &&
-		!l.SuperUsers.IsSuper(msg.From.Username, msg.From.ID) && !l.TrainingMode
+		!g.superUsers.IsSuper(msg.From.Username, msg.From.ID) && !l.TrainingMode
 	if canDelete {
 		if _, err := l.TbAPI.Request(tbapi.DeleteMessageConfig{BaseChatMessage: tbapi.BaseChatMessage{
 			MessageID:  resp.ReplyTo,
-			ChatConfig: tbapi.ChatConfig{ChatID: l.chatID},
+			ChatConfig: tbapi.ChatConfig{ChatID: g.chatID},
 		}}); err != nil {
 			errs = multierror.Append(errs, fmt.Errorf("failed to delete message %d: %w", resp.ReplyTo, err))
 		}
@@ -483,22 +615,23 @@ func (l *TelegramListener) procEvents(update tbapi.Update) error {
 
 // procSuperReply processes superuser commands (reply) /spam, /ban, /warn
 func (l *TelegramListener) procSuperReply(update tbapi.Update) (handled bool) {
+	adminHandler := l.groupFor(update.Message.Chat.ID).adminHandler
 	switch {
 	case strings.EqualFold(update.Message.Text, "/spam") || strings.EqualFold(update.Message.Text, "spam"):
 		log.Printf("[DEBUG] superuser %s reported spam", update.Message.From.UserName)
-		if err := l.adminHandler.DirectSpamReport(update); err != nil {
+		if err := adminHandler.DirectSpamReport(update); err != nil {
 			log.Printf("[WARN] failed to process direct spam report: %v", err)
 		}
 		return true
 	case strings.EqualFold(update.Message.Text, "/ban") || strings.EqualFold(update.Message.Text, "ban"):
 		log.Printf("[DEBUG] superuser %s requested ban", update.Message.From.UserName)
-		if err := l.adminHandler.DirectBanReport(update); err != nil {
+		if err := adminHandler.DirectBanReport(update); err != nil {
 			log.Printf("[WARN] failed to process direct ban request: %v", err)
 		}
 		return true
 	case strings.EqualFold(update.Message.Text, "/warn") || strings.EqualFold(update.Message.Text, "warn"):
 		log.Printf("[DEBUG] superuser %s requested warning", update.Message.From.UserName)
-		if err := l.adminHandler.DirectWarnReport(update); err != nil {
+		if err := adminHandler.DirectWarnReport(update); err != nil {
 			log.Printf("[WARN] failed to process direct warning request: %v", err)
 		}
 		return true
@@ -556,7 +689,7 @@ func (l *TelegramListener) procUserReply(ctx context.Context, update tbapi.Updat
 			return true // command is suppressed when feature is disabled
 		}
 		log.Printf("[DEBUG] user %s (%d) reported spam", update.Message.From.UserName, update.Message.From.ID)
-		if err := l.reportsHandler.DirectUserReport(ctx, update); err != nil {
+		if err := l.groupFor(update.Message.Chat.ID).reportsHandler.DirectUserReport(ctx, update); err != nil {
 			log.Printf("[WARN] failed to process user spam report: %v", err)
 		}
 		return true
@@ -591,6 +724,27 @@ func (l *TelegramListener) procNewChatMemberMessage(update tbapi.Update) error {
 	return nil
 }
 
+// challengeNewMembers posts join challenge to each new member of the protected chat if captcha is enabled.
+// Members added by superusers are not challenged.
+func (l *TelegramListener) challengeNewMembers(ctx context.Context, msg *tbapi.Message) {
+	if l.captcha == nil || !l.isChatAllowed(msg.Chat.ID) {
+		return
+	}
+	supers := l.groupFor(msg.Chat.ID).superUsers
+	if msg.From != nil && supers.IsSuper(msg.From.UserName, msg.From.ID) {
+		log.Printf("[DEBUG] new members added by superuser %s, no join challenge", msg.From.UserName)
+		return
+	}
+	for _, member := range msg.NewChatMembers {
+		if supers.IsSuper(member.UserName, member.ID) {
+			continue
+		}
+		if err := l.captcha.Challenge(ctx, msg.Chat.ID, member); err != nil {
+			log.Printf("[WARN] failed to challenge new member %s (%d): %v", member.UserName, member.ID, err)
+		}
+	}
+}
+
 // procLeftChatMemberMessage deletes the message about new chat member if the user kicked out
 func (l *TelegramListener) procLeftChatMemberMessage(update tbapi.Update) error {
 	fromChat := update.Message.Chat.ID
@@ -632,28 +786,34 @@ func (l *TelegramListener) deleteSystemMessage(msgID int, chatID int64, msgType
 	}
 }
 
-// isLinkedChannel checks if the message was sent on behalf of the linked channel
+// isLinkedChannel checks if the message was sent on behalf of the channel linked to the message's group
 func (l *TelegramListener) isLinkedChannel(msg *tbapi.Message) bool {
-	return l.linkedChannelID != 0 && msg.SenderChat != nil && msg.SenderChat.ID == l.linkedChannelID
+	linkedChannelID := l.groupFor(msg.Chat.ID).linkedChannelID
+	return linkedChannelID != 0 && msg.SenderChat != nil && msg.SenderChat.ID == linkedChannelID
 }
 
 func (l *TelegramListener) isChatAllowed(fromChat int64) bool {
 	if fromChat == l.chatID {
 		return true
 	}
+	if _, ok := l.groups[fromChat]; ok {
+		return true
+	}
 	return slices.Contains(l.TestingIDs, fromChat)
 }
 
+// isAdminChat checks if the message is sent to the admin chat of any group by a superuser of this group
 func (l *TelegramListener) isAdminChat(fromChat int64, from string, fromID int64) bool {
-	if fromChat == l.adminChatID {
-		log.Printf("[DEBUG] message in admin chat %d, from %s (%d)", fromChat, from, fromID)
-		if !l.SuperUsers.IsSuper(from, fromID) {
-			log.Printf("[DEBUG] %s (%d) is not superuser in admin chat, ignored", from, fromID)
-			return false
-		}
-		return true
+	g := l.adminGroupFor(fromChat)
+	if g == nil {
+		return false
 	}
-	return false
+	log.Printf("[DEBUG] message in admin chat %d, from %s (%d)", fromChat, from, fromID)
+	if !g.superUsers.IsSuper(from, fromID) {
+		log.Printf("[DEBUG] %s (%d) is not superuser in admin chat, ignored", from, fromID)
+		return false
+	}
+	return true
 }
 
 func (l *TelegramListener) getBanUsername(resp bot.Response, update tbapi.Update) string {
@@ -711,6 +871,21 @@ func (l *TelegramListener) sendBotResponse(resp bot.Response, chatID int64, noti
 	return nil
 }
 
+// sendStartupMsg sends the startup message to the primary and all additional groups
+func (l *TelegramListener) sendStartupMsg() {
+	chats := []int64{l.chatID}
+	for chatID := range l.groups {
+		chats = append(chats, chatID)
+	}
+	for _, chatID := range chats {
+		if err := l.sendBotResponse(bot.Response{Send: true, Text: l.StartupMsg}, chatID, NotificationSilent); err != nil {
+			log.Printf("[WARN] failed to send startup message to %d, %v", chatID, err)
+			continue
+		}
+		log.Printf("[DEBUG] startup message sent to %d", chatID)
+	}
+}
+
 func (l *TelegramListener) getChatID(group string) (int64, error) {
 	chatID, err := strconv.ParseInt(group, 10, 64)
 	if err == nil {
@@ -728,8 +903,19 @@ func (l *TelegramListener) getChatID(group string) (int64, error) {
 // updateSupers updates the list of super-users based on the chat administrators fetched from the Telegram API.
 // it uses the user ID first, but can match by username if set in the list of super-users.
 func (l *TelegramListener) updateSupers() error {
+	supers, err := l.chatSupers(l.chatID, l.SuperUsers)
+	l.SuperUsers = supers
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] added admins, full list of supers: {%s}", strings.Join(l.SuperUsers, ", "))
+	return nil
+}
+
+// chatSupers returns the list of super-users extended with administrators of the given chat
+func (l *TelegramListener) chatSupers(chatID int64, supers SuperUsers) (SuperUsers, error) {
 	isSuper := func(username string, id int64) bool {
-		for _, super := range l.SuperUsers {
+		for _, super := range supers {
 			if super == fmt.Sprintf("%d", id) {
 				return true
 			}
@@ -740,9 +926,9 @@ func (l *TelegramListener) updateSupers() error {
 		return false
 	}
 
-	admins, err := l.TbAPI.GetChatAdministrators(tbapi.ChatAdministratorsConfig{ChatConfig: tbapi.ChatConfig{ChatID: l.chatID}})
+	admins, err := l.TbAPI.GetChatAdministrators(tbapi.ChatAdministratorsConfig{ChatConfig: tbapi.ChatConfig{ChatID: chatID}})
 	if err != nil {
-		return fmt.Errorf("failed to get chat administrators: %w", err)
+		return supers, fmt.Errorf("failed to get chat administrators: %w", err)
 	}
 
 	for _, admin := range admins {
@@ -752,21 +938,21 @@ func (l *TelegramListener) updateSupers() error {
 		if isSuper(admin.User.UserName, admin.User.ID) {
 			continue // already in the list
 		}
-		l.SuperUsers = append(l.SuperUsers, fmt.Sprintf("%d", admin.User.ID))
+		supers = append(supers, fmt.Sprintf("%d", admin.User.ID))
 	}
-
-	log.Printf("[INFO] added admins, full list of supers: {%s}", strings.Join(l.SuperUsers, ", "))
-	return nil
+	return supers, nil
 }
 
-// deleteExtraMessages deletes additional messages specified in check results (e.g., duplicate messages)
-func (l *TelegramListener) deleteExtraMessages(checkResults []spamcheck.Response, userID int64, username string, chatID int64) {
-	if len(checkResults) == 0 || l.Dry || l.TrainingMode {
+// deleteExtraMessages deletes additional messages specified in check results (e.g., duplicate messages).
+// Super users of the chat are passed by caller, the method runs in a goroutine and doesn't read the groups.
+func (l *TelegramListener) deleteExtraMessages(checkResults []spamcheck.Response, supers SuperUsers, userID int64,
+	username string, chatID int64) {
+	if len(checkResults) == 0 {
 		return
 	}
 
 	// don't delete messages from superusers
-	if l.SuperUsers.IsSuper(username, userID) {
+	if supers.IsSuper(username, userID) {
 		log.Printf("[DEBUG] skip extra deletions for superuser %s (%d)", username, userID)
 		return
 	}
@@ -801,24 +987,25 @@ func (l *TelegramListener) procReaction(ctx context.Context, r *tbapi.MessageRea
 		log.Printf("[DEBUG] reaction from anonymous user, skipped")
 		return nil
 	}
-	if r.Chat.ID != l.chatID {
-		log.Printf("[DEBUG] reaction from chat %d, not primary chat %d, skipped", r.Chat.ID, l.chatID)
+	if r.Chat.ID != l.chatID && l.groups[r.Chat.ID] == nil {
+		log.Printf("[DEBUG] reaction from chat %d, not a protected chat, skipped", r.Chat.ID)
 		return nil
 	}
+	g := l.groupFor(r.Chat.ID)
 	// count only net new reactions; changes (👍→👎) and removals have newReactionsAdded <= 0
 	newReactionsAdded := len(r.NewReaction) - len(r.OldReaction)
 	if newReactionsAdded <= 0 {
 		return nil
 	}
 
-	if l.SuperUsers.IsSuper(r.User.UserName, r.User.ID) {
+	if g.superUsers.IsSuper(r.User.UserName, r.User.ID) {
 		log.Printf("[DEBUG] superuser %s reaction ignored", r.User.UserName)
 		return nil
 	}
 
 	var resp bot.Response
 	for range newReactionsAdded {
-		resp = l.Bot.OnReaction(r.User.ID, r.User.UserName)
+		resp = g.bot.OnReaction(r.User.ID, r.User.UserName)
 		if resp.BanInterval > 0 {
 			break
 		}
@@ -830,18 +1017,22 @@ func (l *TelegramListener) procReaction(ctx context.Context, r *tbapi.MessageRea
 	if err := l.Locator.AddSpam(ctx, r.User.ID, resp.CheckResults); err != nil {
 		log.Printf("[WARN] failed to add reaction spam to locator: %v", err)
 	}
+	recordReputation(ctx, l.Reputation, r.User.ID, r.User.UserName, storage.RepReactionFlagged)
+	resp.Dry = l.Dry || l.TrainingMode
+	resp.Temporary = resp.BanInterval < bot.PermanentBanDuration
 	l.SpamLogger.Save(&bot.Message{From: resp.User, Text: "[reaction spam]"}, &resp)
 
 	banUserStr := resp.User.String()
 	banReq := banRequest{
 		duration: resp.BanInterval, userID: resp.User.ID, userName: banUserStr,
-		chatID: l.chatID, dry: l.Dry, training: l.TrainingMode, tbAPI: l.TbAPI, restrict: l.SoftBanMode,
+		chatID: g.chatID, dry: l.Dry, training: l.TrainingMode, tbAPI: l.TbAPI, restrict: l.SoftBanMode,
+		events: l.Events, source: hooks.SourceReactions,
 	}
 	if err := banUserOrChannel(banReq); err != nil {
 		return fmt.Errorf("failed to ban reaction spammer %s: %w", banUserStr, err)
 	}
-	if l.adminChatID != 0 && resp.User.ID != 0 {
-		l.adminHandler.ReportReactionBan(banUserStr, resp.User)
+	if g.adminChatID != 0 && resp.User.ID != 0 {
+		g.adminHandler.ReportReactionBan(banUserStr, resp.User)
 	}
 	return nil
 }
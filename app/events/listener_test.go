@@ -16,6 +16,7 @@ import (
 
 	"github.com/umputun/tg-spam/app/bot"
 	"github.com/umputun/tg-spam/app/events/mocks"
+	"github.com/umputun/tg-spam/app/hooks"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/app/storage/engine"
 	"github.com/umputun/tg-spam/lib/spamcheck"
@@ -421,6 +422,7 @@ func TestTelegramListener_DoWithBotBan(t *testing.T) {
 		assert.Len(t, mockLogger.SaveCalls(), 1)
 		assert.Equal(t, "text 123", mockLogger.SaveCalls()[0].Msg.Text)
 		assert.Equal(t, "user", mockLogger.SaveCalls()[0].Msg.From.Username)
+		assert.False(t, mockLogger.SaveCalls()[0].Response.Dry)
 		assert.Len(t, mockAPI.SendCalls(), 1)
 		assert.Equal(t, "bot's answer", mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text)
 		assert.Len(t, mockAPI.RequestCalls(), 1)
@@ -664,6 +666,343 @@ func TestTelegramListener_DoWithBotSoftBan(t *testing.T) {
 	assert.False(t, botMock.OnMessageCalls()[0].CheckOnly)
 }
 
+func TestTelegramListener_DoWithSuspicious(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) {
+			return tbapi.Message{Text: c.(tbapi.MessageConfig).Text}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+	}
+	botMock := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response {
+		if msg.Text == "suspicious text" {
+			return bot.Response{CheckResults: []spamcheck.Response{
+				{Name: "emoji", Spam: true, Details: "3/2", Score: 0.5},
+				{Name: "stopword", Spam: false, Details: "not found"},
+				{Name: "score", Details: "1.50/2.00, suspicious", Score: 1.5, Suspicious: true}}}
+		}
+		return bot.Response{CheckResults: []spamcheck.Response{{Name: "score", Details: "0.50/2.00", Score: 0.5}}}
+	}}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{
+		SpamLogger: &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}},
+		TbAPI:      mockAPI,
+		Bot:        botMock,
+		Group:      "gr",
+		AdminGroup: "987654321",
+		Locator:    locator,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Minute)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 2)
+	updChan <- tbapi.Update{Message: &tbapi.Message{Chat: tbapi.Chat{ID: 123}, Text: "regular text",
+		From: &tbapi.User{UserName: "user", ID: 101}, Date: time.Now().Unix()}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{Chat: tbapi.Chat{ID: 123}, Text: "suspicious text",
+		From: &tbapi.User{UserName: "user", ID: 101}, Date: time.Now().Unix()}}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+	require.Len(t, mockAPI.SendCalls(), 1, "only suspicious message reported")
+	sent := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig)
+	assert.Equal(t, int64(987654321), sent.ChatID)
+	assert.Contains(t, sent.Text, "suspicious message from [user](tg://user?id=101)")
+	assert.Contains(t, sent.Text, "suspicious text")
+	assert.Contains(t, sent.Text, "emoji: spam, 3/2, score: 0.50")
+	assert.NotContains(t, sent.Text, "stopword", "checks without score are not listed")
+	assert.Empty(t, mockAPI.RequestCalls(), "nothing deleted or banned")
+}
+
+func TestTelegramListener_DoWithQuarantine(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) {
+			return tbapi.Message{Text: c.(tbapi.MessageConfig).Text}, nil
+		},
+		RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+	}
+	botMock := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response {
+		return bot.Response{CheckResults: []spamcheck.Response{
+			{Name: "classifier", Details: "probability of spam: 55.00%, suspicious", Suspicious: true}}}
+	}}
+	reviews := &mocks.ReviewQueueMock{AddFunc: func(ctx context.Context, rv storage.Review) (int64, error) {
+		if rv.UserID == 102 {
+			return 0, errors.New("db error")
+		}
+		return 1, nil
+	}}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{
+		SpamLogger: &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}},
+		TbAPI:      mockAPI,
+		Bot:        botMock,
+		Group:      "gr",
+		AdminGroup: "987654321",
+		Locator:    locator,
+		Reviews:    reviews,
+		SuperUsers: SuperUsers{"super"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Minute)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 3)
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 1, Chat: tbapi.Chat{ID: 123}, Text: "suspicious text",
+		From: &tbapi.User{UserName: "user", ID: 101}, Date: time.Now().Unix()}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 2, Chat: tbapi.Chat{ID: 123}, Text: "queue failed",
+		From: &tbapi.User{UserName: "user2", ID: 102}, Date: time.Now().Unix()}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 3, Chat: tbapi.Chat{ID: 123}, Text: "from super",
+		From: &tbapi.User{UserName: "super", ID: 103}, Date: time.Now().Unix()}}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+
+	require.Len(t, reviews.AddCalls(), 2, "superuser message not quarantined")
+	assert.Equal(t, int64(101), reviews.AddCalls()[0].Rv.UserID)
+	assert.Equal(t, "suspicious text", reviews.AddCalls()[0].Rv.Text)
+
+	require.Len(t, mockAPI.RequestCalls(), 1, "only quarantined message deleted")
+	assert.Equal(t, 1, mockAPI.RequestCalls()[0].C.(tbapi.DeleteMessageConfig).MessageID)
+
+	require.Len(t, mockAPI.SendCalls(), 3)
+	assert.Contains(t, mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text, "quarantined suspicious message from [user]")
+	assert.Contains(t, mockAPI.SendCalls()[1].C.(tbapi.MessageConfig).Text, "suspicious message from [user2]",
+		"reported if queue failed")
+	assert.Contains(t, mockAPI.SendCalls()[2].C.(tbapi.MessageConfig).Text, "suspicious message from [super]",
+		"superuser message reported only")
+}
+
+func TestTelegramListener_DoWithReputation(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+		RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+	}
+	botMock := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response {
+		if msg.Text == "spam text" {
+			return bot.Response{Send: true, Text: "bot's answer", BanInterval: 2 * time.Minute,
+				User: bot.User{Username: "spammer", ID: 102}, CheckResults: []spamcheck.Response{{Name: "stopword", Spam: true}}}
+		}
+		return bot.Response{}
+	}}
+	reputation := &mocks.ReputationMock{IncFunc: func(ctx context.Context, userID int64, userName string,
+		ev storage.ReputationEvent) error {
+		return nil
+	}}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{
+		SpamLogger: &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}},
+		TbAPI:      mockAPI,
+		Bot:        botMock,
+		Group:      "gr",
+		Locator:    locator,
+		Reputation: reputation,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Minute)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 2)
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 1, Chat: tbapi.Chat{ID: 123}, Text: "good text",
+		From: &tbapi.User{UserName: "user", ID: 101}, Date: time.Now().Unix()}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 2, Chat: tbapi.Chat{ID: 123}, Text: "spam text",
+		From: &tbapi.User{UserName: "spammer", ID: 102}, Date: time.Now().Unix()}}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+
+	calls := reputation.IncCalls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, int64(101), calls[0].UserID)
+	assert.Equal(t, "user", calls[0].UserName)
+	assert.Equal(t, storage.RepMessageChecked, calls[0].Ev)
+	assert.Equal(t, int64(102), calls[1].UserID)
+	assert.Equal(t, storage.RepMessageChecked, calls[1].Ev)
+	assert.Equal(t, int64(102), calls[2].UserID)
+	assert.Equal(t, storage.RepSpamHit, calls[2].Ev)
+}
+
+func TestTelegramListener_DoWithPunishLadder(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+		RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+	}
+	botMock := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response {
+		checks := []spamcheck.Response{{Name: "stopword", Spam: true}}
+		if msg.From.ID == 103 {
+			checks = append(checks, spamcheck.Response{Name: "cas", Spam: true})
+		}
+		return bot.Response{Send: true, Text: "bot's answer", BanInterval: bot.PermanentBanDuration,
+			User: bot.User{Username: msg.From.Username, ID: msg.From.ID}, CheckResults: checks}
+	}}
+	var recorded []storage.Offense
+	offenses := &mocks.OffensesMock{
+		AddFunc: func(ctx context.Context, offense storage.Offense) error {
+			recorded = append([]storage.Offense{offense}, recorded...)
+			return nil
+		},
+		SinceFunc: func(ctx context.Context, userID int64, since time.Time) ([]storage.Offense, error) {
+			var res []storage.Offense
+			for _, o := range recorded {
+				if o.UserID == userID {
+					res = append(res, o)
+				}
+			}
+			return res, nil
+		},
+	}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	spamLogger := &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}}
+	l := TelegramListener{
+		SpamLogger:  spamLogger,
+		TbAPI:       mockAPI,
+		Bot:         botMock,
+		Group:       "gr",
+		Locator:     locator,
+		NoSpamReply: true,
+		Punishment: PunishConfig{Enabled: true, Offenses: offenses, Mute: 10 * time.Minute, Restrict: 24 * time.Hour,
+			Window: 720 * time.Hour, PermanentChecks: []string{"cas"}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Minute)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 4)
+	for i, userID := range []int64{102, 102, 102, 103} {
+		updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: i + 1, Chat: tbapi.Chat{ID: 123}, Text: "spam text",
+			From: &tbapi.User{UserName: "spammer", ID: userID}, Date: time.Now().Unix()}}
+	}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+
+	var actions []string
+	for _, c := range mockAPI.RequestCalls() {
+		switch r := c.C.(type) {
+		case tbapi.RestrictChatMemberConfig:
+			actions = append(actions, fmt.Sprintf("restrict %d for %v", r.UserID,
+				time.Until(time.Unix(r.UntilDate, 0)).Round(time.Minute)))
+		case tbapi.BanChatMemberConfig:
+			actions = append(actions, fmt.Sprintf("ban %d", r.UserID))
+		}
+	}
+	assert.Equal(t, []string{"restrict 102 for 10m0s", "restrict 102 for 24h0m0s", "ban 102", "ban 103"}, actions)
+
+	require.Len(t, recorded, 4)
+	assert.Equal(t, storage.Offense{UserID: 103, UserName: "spammer", Checks: "stopword,cas", Action: "permanently banned"},
+		recorded[0])
+	assert.Equal(t, "restricted for 10m0s", recorded[3].Action)
+
+	var temporary []bool
+	for _, c := range spamLogger.SaveCalls() {
+		temporary = append(temporary, c.Response.Temporary)
+	}
+	assert.Equal(t, []bool{true, true, false, false}, temporary, "restrictions saved as temporary, not published to federation")
+}
+
+func TestTelegramListener_DoWithEvents(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+		RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+	}
+	botMock := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response {
+		if msg.Text == "spam text" {
+			return bot.Response{Send: true, Text: "bot's answer", BanInterval: 2 * time.Minute,
+				User: bot.User{Username: "spammer", ID: 102}, CheckResults: []spamcheck.Response{{Name: "stopword", Spam: true}}}
+		}
+		return bot.Response{}
+	}}
+	pub := &mocks.EventPublisherMock{PublishFunc: func(ctx context.Context, e hooks.Event) error { return nil }}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{
+		SpamLogger: &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}},
+		TbAPI:      mockAPI,
+		Bot:        botMock,
+		Group:      "gr",
+		Locator:    locator,
+		Events:     pub,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Minute)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 2)
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 1, Chat: tbapi.Chat{ID: 123}, Text: "good text",
+		From: &tbapi.User{UserName: "user", ID: 101}, Date: time.Now().Unix()}}
+	updChan <- tbapi.Update{Message: &tbapi.Message{MessageID: 2, Chat: tbapi.Chat{ID: 123}, Text: "spam text",
+		From: &tbapi.User{UserName: "spammer", ID: 102}, Date: time.Now().Unix()}}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+
+	calls := pub.PublishCalls()
+	require.Len(t, calls, 2, "nothing published for ham")
+	assert.Equal(t, hooks.Event{Type: hooks.EventSpamDetected, Source: hooks.SourceDetector, ChatID: 123, UserID: 102,
+		UserName: "@spammer (102)", Text: "spam text", Checks: []spamcheck.Response{{Name: "stopword", Spam: true}}}, calls[0].E)
+	assert.Equal(t, hooks.Event{Type: hooks.EventBan, Source: hooks.SourceDetector, ChatID: 123, UserID: 102,
+		UserName: "@spammer (102)", Details: "duration: 2m0s"}, calls[1].E)
+}
+
 func TestTelegramListener_DoWithTraining(t *testing.T) {
 	mockLogger := &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}}
 	mockAPI := &mocks.TbAPIMock{
@@ -724,6 +1063,7 @@ func TestTelegramListener_DoWithTraining(t *testing.T) {
 	assert.Len(t, mockLogger.SaveCalls(), 1)
 	assert.Equal(t, "text 321", mockLogger.SaveCalls()[0].Msg.Text)
 	assert.Equal(t, "user", mockLogger.SaveCalls()[0].Msg.From.Username)
+	assert.True(t, mockLogger.SaveCalls()[0].Response.Dry, "saved as not banned in training mode")
 	assert.Empty(t, mockAPI.SendCalls(), "no messages should be sent in training mode")
 	assert.Empty(t, mockAPI.RequestCalls())
 
@@ -3011,6 +3351,189 @@ func TestTelegramListener_NoDeleteWhenFlagsDisabled(t *testing.T) {
 	}
 }
 
+func TestTelegramListener_Reconfigure(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+		RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		},
+	}
+	b := &mocks.BotMock{}
+
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{TbAPI: mockAPI, Bot: b, SuperUsers: SuperUsers{"admin"}, Group: "gr", Locator: locator,
+		AdminGroup: "456"}
+
+	// flags set before the update are applied by the update loop before processing it, the last call wins
+	l.Reconfigure(ListenerFlags{DeleteJoinMessages: false})
+	l.Reconfigure(ListenerFlags{DeleteJoinMessages: true, SoftBanMode: true, WarnMsg: "new warning", AggressiveCleanupLimit: 10})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updChan := make(chan tbapi.Update, 1)
+	updChan <- tbapi.Update{Message: &tbapi.Message{Chat: tbapi.Chat{ID: 123}, From: &tbapi.User{UserName: "admin", ID: 100},
+		NewChatMembers: []tbapi.User{{UserName: "new_user", ID: 321}}, MessageID: 44}}
+	close(updChan)
+	mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+
+	err := l.Do(ctx)
+	require.EqualError(t, err, "telegram update chan closed")
+
+	deleted := false
+	for _, call := range mockAPI.RequestCalls() {
+		if dc, ok := call.C.(tbapi.DeleteMessageConfig); ok && dc.MessageID == 44 {
+			deleted = true
+		}
+	}
+	assert.True(t, deleted, "join message deleted with the new flags")
+	assert.True(t, l.SoftBanMode)
+	assert.Equal(t, "new warning", l.WarnMsg)
+	assert.True(t, l.adminHandler.softBan, "admin handler remade with the new flags")
+	assert.Equal(t, 10, l.adminHandler.aggressiveCleanupLimit)
+	assert.True(t, l.reportsHandler.softBanMode, "reports handler remade with the new flags")
+	assert.Nil(t, l.pendingFlags.Load(), "pending flags applied")
+}
+
+func TestTelegramListener_JoinCaptcha(t *testing.T) {
+	newListener := func() (*TelegramListener, *mocks.TbAPIMock, *mocks.ChallengesMock) {
+		mockAPI := &mocks.TbAPIMock{
+			GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+				return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+			},
+			GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+				return nil, nil
+			},
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+			SendFunc:    func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{MessageID: 77}, nil },
+		}
+		var pending []storage.Challenge
+		st := &mocks.ChallengesMock{
+			AddFunc: func(ctx context.Context, ch storage.Challenge) error {
+				pending = append(pending, ch)
+				return nil
+			},
+			GetFunc: func(ctx context.Context, chatID, userID int64) (*storage.Challenge, error) {
+				for _, ch := range pending {
+					if ch.ChatID == chatID && ch.UserID == userID {
+						return &ch, nil
+					}
+				}
+				return nil, nil
+			},
+			DeleteFunc: func(ctx context.Context, chatID, userID int64) error { return nil },
+		}
+		b := &mocks.BotMock{IsApprovedUserFunc: func(userID int64) bool { return false }}
+		locator, teardown := prepTestLocator(t)
+		t.Cleanup(teardown)
+		l := &TelegramListener{
+			TbAPI:      mockAPI,
+			Bot:        b,
+			SuperUsers: SuperUsers{"admin"},
+			Group:      "gr",
+			Locator:    locator,
+			Captcha: CaptchaConfig{Storage: st, Enabled: true, Type: CaptchaButton, Timeout: time.Minute,
+				Action: CaptchaBan},
+		}
+		return l, mockAPI, st
+	}
+	run := func(t *testing.T, l *TelegramListener, mockAPI *mocks.TbAPIMock, updates ...tbapi.Update) {
+		updChan := make(chan tbapi.Update, len(updates))
+		for _, u := range updates {
+			updChan <- u
+		}
+		close(updChan)
+		mockAPI.GetUpdatesChanFunc = func(config tbapi.UpdateConfig) tbapi.UpdatesChannel { return updChan }
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := l.Do(ctx)
+		require.EqualError(t, err, "telegram update chan closed")
+	}
+	restrictCalls := func(mockAPI *mocks.TbAPIMock) (res []tbapi.RestrictChatMemberConfig) {
+		for _, call := range mockAPI.RequestCalls() {
+			if rc, ok := call.C.(tbapi.RestrictChatMemberConfig); ok {
+				res = append(res, rc)
+			}
+		}
+		return res
+	}
+
+	t.Run("new member challenged and released on button press", func(t *testing.T) {
+		l, mockAPI, st := newListener()
+		join := tbapi.Update{Message: &tbapi.Message{
+			Chat:           tbapi.Chat{ID: 123},
+			From:           &tbapi.User{UserName: "new_user", ID: 321},
+			NewChatMembers: []tbapi.User{{UserName: "new_user", ID: 321}},
+			MessageID:      22,
+		}}
+		press := tbapi.Update{CallbackQuery: &tbapi.CallbackQuery{ID: "q1", From: &tbapi.User{ID: 321},
+			Data: "C321:I'm not a bot", Message: &tbapi.Message{MessageID: 77, Chat: tbapi.Chat{ID: 123}}}}
+		run(t, l, mockAPI, join, press)
+
+		require.Len(t, st.AddCalls(), 1)
+		assert.Equal(t, int64(321), st.AddCalls()[0].Ch.UserID)
+		assert.Equal(t, 77, st.AddCalls()[0].Ch.MsgID)
+		require.Len(t, mockAPI.SendCalls(), 1)
+		assert.Contains(t, mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text, "@new_user, please press the button")
+
+		restricts := restrictCalls(mockAPI)
+		require.Len(t, restricts, 2)
+		assert.False(t, restricts[0].Permissions.CanSendMessages)
+		assert.True(t, restricts[1].Permissions.CanSendMessages)
+		require.Len(t, st.DeleteCalls(), 1)
+
+		meta, found := l.Locator.Message(context.Background(), "new_123_321")
+		assert.True(t, found, "join message still stored in locator")
+		assert.Equal(t, 22, meta.MsgID)
+	})
+
+	t.Run("members added by superuser are not challenged", func(t *testing.T) {
+		l, mockAPI, st := newListener()
+		join := tbapi.Update{Message: &tbapi.Message{
+			Chat:           tbapi.Chat{ID: 123},
+			From:           &tbapi.User{UserName: "admin", ID: 100},
+			NewChatMembers: []tbapi.User{{UserName: "new_user", ID: 321}},
+			MessageID:      22,
+		}}
+		run(t, l, mockAPI, join)
+		assert.Empty(t, st.AddCalls())
+		assert.Empty(t, restrictCalls(mockAPI))
+	})
+
+	t.Run("members of other chats are not challenged", func(t *testing.T) {
+		l, mockAPI, st := newListener()
+		join := tbapi.Update{Message: &tbapi.Message{
+			Chat:           tbapi.Chat{ID: 999},
+			From:           &tbapi.User{UserName: "new_user", ID: 321},
+			NewChatMembers: []tbapi.User{{UserName: "new_user", ID: 321}},
+			MessageID:      22,
+		}}
+		run(t, l, mockAPI, join)
+		assert.Empty(t, st.AddCalls())
+	})
+
+	t.Run("captcha disabled", func(t *testing.T) {
+		l, mockAPI, st := newListener()
+		l.Captcha.Enabled = false
+		join := tbapi.Update{Message: &tbapi.Message{
+			Chat:           tbapi.Chat{ID: 123},
+			From:           &tbapi.User{UserName: "new_user", ID: 321},
+			NewChatMembers: []tbapi.User{{UserName: "new_user", ID: 321}},
+			MessageID:      22,
+		}}
+		run(t, l, mockAPI, join)
+		assert.Empty(t, st.AddCalls())
+		assert.Empty(t, restrictCalls(mockAPI))
+	})
+}
+
 func TestTelegramListener_CallbackRouting(t *testing.T) {
 	t.Run("R+ callback routes to reportsHandler", func(t *testing.T) {
 		mockAPI := &mocks.TbAPIMock{
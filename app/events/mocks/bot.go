@@ -29,6 +29,9 @@ import (
 //			RemoveApprovedUserFunc: func(id int64) error {
 //				panic("mock out the RemoveApprovedUser method")
 //			},
+//			SeedApprovedUserFunc: func(id int64, name string, count int) error {
+//				panic("mock out the SeedApprovedUser method")
+//			},
 //			UpdateHamFunc: func(msg string) error {
 //				panic("mock out the UpdateHam method")
 //			},
@@ -57,6 +60,9 @@ type BotMock struct {
 	// RemoveApprovedUserFunc mocks the RemoveApprovedUser method.
 	RemoveApprovedUserFunc func(id int64) error
 
+	// SeedApprovedUserFunc mocks the SeedApprovedUser method.
+	SeedApprovedUserFunc func(id int64, name string, count int) error
+
 	// UpdateHamFunc mocks the UpdateHam method.
 	UpdateHamFunc func(msg string) error
 
@@ -96,6 +102,15 @@ type BotMock struct {
 			// ID is the id argument value.
 			ID int64
 		}
+		// SeedApprovedUser holds details about calls to the SeedApprovedUser method.
+		SeedApprovedUser []struct {
+			// ID is the id argument value.
+			ID int64
+			// Name is the name argument value.
+			Name string
+			// Count is the count argument value.
+			Count int
+		}
 		// UpdateHam holds details about calls to the UpdateHam method.
 		UpdateHam []struct {
 			// Msg is the msg argument value.
@@ -112,6 +127,7 @@ type BotMock struct {
 	lockOnMessage          sync.RWMutex
 	lockOnReaction         sync.RWMutex
 	lockRemoveApprovedUser sync.RWMutex
+	lockSeedApprovedUser   sync.RWMutex
 	lockUpdateHam          sync.RWMutex
 	lockUpdateSpam         sync.RWMutex
 }
@@ -323,6 +339,53 @@ func (mock *BotMock) ResetRemoveApprovedUserCalls() {
 	mock.lockRemoveApprovedUser.Unlock()
 }
 
+// SeedApprovedUser calls SeedApprovedUserFunc.
+func (mock *BotMock) SeedApprovedUser(id int64, name string, count int) error {
+	if mock.SeedApprovedUserFunc == nil {
+		panic("BotMock.SeedApprovedUserFunc: method is nil but Bot.SeedApprovedUser was just called")
+	}
+	callInfo := struct {
+		ID    int64
+		Name  string
+		Count int
+	}{
+		ID:    id,
+		Name:  name,
+		Count: count,
+	}
+	mock.lockSeedApprovedUser.Lock()
+	mock.calls.SeedApprovedUser = append(mock.calls.SeedApprovedUser, callInfo)
+	mock.lockSeedApprovedUser.Unlock()
+	return mock.SeedApprovedUserFunc(id, name, count)
+}
+
+// SeedApprovedUserCalls gets all the calls that were made to SeedApprovedUser.
+// Check the length with:
+//
+//	len(mockedBot.SeedApprovedUserCalls())
+func (mock *BotMock) SeedApprovedUserCalls() []struct {
+	ID    int64
+	Name  string
+	Count int
+} {
+	var calls []struct {
+		ID    int64
+		Name  string
+		Count int
+	}
+	mock.lockSeedApprovedUser.RLock()
+	calls = mock.calls.SeedApprovedUser
+	mock.lockSeedApprovedUser.RUnlock()
+	return calls
+}
+
+// ResetSeedApprovedUserCalls reset all the calls that were made to SeedApprovedUser.
+func (mock *BotMock) ResetSeedApprovedUserCalls() {
+	mock.lockSeedApprovedUser.Lock()
+	mock.calls.SeedApprovedUser = nil
+	mock.lockSeedApprovedUser.Unlock()
+}
+
 // UpdateHam calls UpdateHamFunc.
 func (mock *BotMock) UpdateHam(msg string) error {
 	if mock.UpdateHamFunc == nil {
@@ -423,6 +486,10 @@ func (mock *BotMock) ResetCalls() {
 	mock.calls.RemoveApprovedUser = nil
 	mock.lockRemoveApprovedUser.Unlock()
 
+	mock.lockSeedApprovedUser.Lock()
+	mock.calls.SeedApprovedUser = nil
+	mock.lockSeedApprovedUser.Unlock()
+
 	mock.lockUpdateHam.Lock()
 	mock.calls.UpdateHam = nil
 	mock.lockUpdateHam.Unlock()
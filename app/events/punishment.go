@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+//go:generate moq --out mocks/offenses.go --pkg mocks --with-resets --skip-ensure . Offenses
+
+// Offenses is an interface for the history of punished spam offenses
+type Offenses interface {
+	Add(ctx context.Context, offense storage.Offense) error
+	Since(ctx context.Context, userID int64, since time.Time) ([]storage.Offense, error)
+}
+
+// PunishConfig defines the escalating punishment ladder for detected spam. The first offense within
+// the window restricts the sender for Mute, the second one for Restrict, the third and following offenses
+// are punished by permanent ban. Spam detected by any of PermanentChecks is banned permanently right away.
+type PunishConfig struct {
+	Offenses        Offenses      // offense history storage
+	Enabled         bool          // enable the ladder, detected spammers are banned permanently otherwise
+	Mute            time.Duration // restriction on the first offense, 0 skips the step
+	Restrict        time.Duration // restriction on the second offense, 0 skips the step
+	Window          time.Duration // offenses older than this are not counted
+	PermanentChecks []string      // names of checks always punished by permanent ban, e.g. cas
+}
+
+// punishment is a penalty for a detected spam message
+type punishment struct {
+	duration time.Duration     // ban or restriction duration, bot.PermanentBanDuration for permanent ban
+	restrict bool              // restrict the sender instead of ban
+	offense  int               // number of the offense within the window, 0 if offenses are not tracked
+	window   time.Duration     // window the offenses are counted within
+	forcedBy string            // check forced the permanent ban regardless of the offense number
+	history  []storage.Offense // earlier offenses within the window, newest first
+}
+
+// permanentBan is the punishment without the ladder, same for every offense
+var permanentBan = punishment{duration: bot.PermanentBanDuration}
+
+// action returns the punishment as shown to admins, e.g. "restricted for 10m0s".
+// Channels can't be restricted, they are banned for the same duration instead.
+func (p punishment) action(channel bool) string {
+	switch {
+	case p.duration >= bot.PermanentBanDuration:
+		return "permanently banned"
+	case p.restrict && !channel:
+		return fmt.Sprintf("restricted for %v", p.duration)
+	default:
+		return fmt.Sprintf("banned for %v", p.duration)
+	}
+}
+
+// next picks the punishment for a new offense of the user or channel based on the offense history.
+// Failure to read the history is logged, and the offense is treated as the first one.
+func (c PunishConfig) next(ctx context.Context, userID int64, checks []spamcheck.Response) punishment {
+	if !c.Enabled || c.Offenses == nil {
+		return permanentBan
+	}
+
+	res := permanentBan
+	history, err := c.Offenses.Since(ctx, userID, time.Now().Add(-c.Window))
+	if err != nil {
+		log.Printf("[WARN] failed to get offenses of %d: %v", userID, err)
+	}
+	res.history, res.offense, res.window = history, len(history)+1, c.Window
+
+	for _, check := range checks {
+		if check.Spam && slices.Contains(c.PermanentChecks, check.Name) {
+			res.forcedBy = check.Name
+			return res
+		}
+	}
+
+	steps := make([]time.Duration, 0, 2)
+	for _, d := range []time.Duration{c.Mute, c.Restrict} {
+		if d > 0 {
+			steps = append(steps, d)
+		}
+	}
+	if res.offense <= len(steps) {
+		res.duration, res.restrict = steps[res.offense-1], true
+	}
+	return res
+}
+
+// record saves the applied punishment to the offense history, no-op if offenses are not tracked.
+// Failures are logged only, the punishment is already applied at this point.
+func (c PunishConfig) record(ctx context.Context, userID int64, userName string, checks []spamcheck.Response,
+	p punishment) {
+	if !c.Enabled || c.Offenses == nil || p.offense == 0 {
+		return
+	}
+	names := make([]string, 0, len(checks))
+	for _, check := range checks {
+		if check.Spam {
+			names = append(names, check.Name)
+		}
+	}
+	offense := storage.Offense{UserID: userID, UserName: userName, Checks: strings.Join(names, ","),
+		Action: p.action(userID < 0)}
+	if err := c.Offenses.Add(ctx, offense); err != nil {
+		log.Printf("[WARN] failed to record offense of %d: %v", userID, err)
+	}
+}
+
+// historyLine returns the offense number and earlier offenses for admin notification, empty if not tracked
+func (p punishment) historyLine() string {
+	if p.offense == 0 {
+		return ""
+	}
+	res := fmt.Sprintf("offense #%d within %v", p.offense, p.window)
+	if p.forcedBy != "" {
+		res += ", permanent for " + p.forcedBy
+	}
+	if len(p.history) == 0 {
+		return res
+	}
+	prev := make([]string, 0, len(p.history))
+	for _, o := range p.history {
+		prev = append(prev, fmt.Sprintf("%s %s (%s)", o.CreatedAt.Format("2006-01-02 15:04"), o.Action, o.Checks))
+	}
+	return res + ", previous: " + strings.Join(prev, "; ")
+}
+
+// isRestrictionNotice checks if the ban notification from admin chat reports a restriction by the punishment ladder.
+// Texts of callback messages come without markdown, the action starts the first line.
+func isRestrictionNotice(text string) bool {
+	first, _, _ := strings.Cut(text, "\n")
+	return strings.HasPrefix(strings.TrimPrefix(first, "would have "), "restricted for ")
+}
@@ -0,0 +1,131 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/events/mocks"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestPunishConfig_next(t *testing.T) {
+	history := func(n int) []storage.Offense {
+		res := make([]storage.Offense, n)
+		for i := range res {
+			res[i] = storage.Offense{UserID: 1, Checks: "stopword", Action: "restricted for 10m0s"}
+		}
+		return res
+	}
+	spamChecks := []spamcheck.Response{{Name: "stopword", Spam: true}, {Name: "cas", Spam: false}}
+
+	tests := []struct {
+		name     string
+		cfg      PunishConfig
+		prev     int
+		checks   []spamcheck.Response
+		duration time.Duration
+		restrict bool
+		forcedBy string
+	}{
+		{name: "first offense", prev: 0, checks: spamChecks, duration: 10 * time.Minute, restrict: true},
+		{name: "second offense", prev: 1, checks: spamChecks, duration: 24 * time.Hour, restrict: true},
+		{name: "third offense", prev: 2, checks: spamChecks, duration: bot.PermanentBanDuration},
+		{name: "many offenses", prev: 5, checks: spamChecks, duration: bot.PermanentBanDuration},
+		{name: "permanent check", prev: 0, checks: []spamcheck.Response{{Name: "stopword", Spam: true},
+			{Name: "cas", Spam: true}}, duration: bot.PermanentBanDuration, forcedBy: "cas"},
+		{name: "mute step skipped", cfg: PunishConfig{Restrict: time.Hour}, prev: 0, checks: spamChecks,
+			duration: time.Hour, restrict: true},
+		{name: "mute step skipped, second offense", cfg: PunishConfig{Restrict: time.Hour}, prev: 1, checks: spamChecks,
+			duration: bot.PermanentBanDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offenses := &mocks.OffensesMock{SinceFunc: func(ctx context.Context, userID int64, since time.Time) ([]storage.Offense, error) {
+				return history(tt.prev), nil
+			}}
+			cfg := tt.cfg
+			if cfg.Mute == 0 && cfg.Restrict == 0 {
+				cfg = PunishConfig{Mute: 10 * time.Minute, Restrict: 24 * time.Hour}
+			}
+			cfg.Enabled, cfg.Offenses, cfg.Window = true, offenses, 720*time.Hour
+			cfg.PermanentChecks = []string{"cas", "prohibited-language"}
+
+			p := cfg.next(context.Background(), 1, tt.checks)
+			assert.Equal(t, tt.duration, p.duration)
+			assert.Equal(t, tt.restrict, p.restrict)
+			assert.Equal(t, tt.forcedBy, p.forcedBy)
+			assert.Equal(t, tt.prev+1, p.offense)
+			assert.Len(t, p.history, tt.prev)
+
+			require.Len(t, offenses.SinceCalls(), 1)
+			assert.Equal(t, int64(1), offenses.SinceCalls()[0].UserID)
+			assert.WithinDuration(t, time.Now().Add(-720*time.Hour), offenses.SinceCalls()[0].Since, time.Second)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		offenses := &mocks.OffensesMock{}
+		p := PunishConfig{Offenses: offenses, Mute: time.Minute}.next(context.Background(), 1, spamChecks)
+		assert.Equal(t, permanentBan, p)
+		assert.Empty(t, offenses.SinceCalls())
+	})
+
+	t.Run("history error", func(t *testing.T) {
+		offenses := &mocks.OffensesMock{SinceFunc: func(ctx context.Context, userID int64, since time.Time) ([]storage.Offense, error) {
+			return nil, errors.New("db error")
+		}}
+		cfg := PunishConfig{Enabled: true, Offenses: offenses, Mute: time.Minute, Window: time.Hour}
+		p := cfg.next(context.Background(), 1, spamChecks)
+		assert.Equal(t, time.Minute, p.duration, "treated as the first offense")
+		assert.Equal(t, 1, p.offense)
+	})
+}
+
+func TestPunishConfig_record(t *testing.T) {
+	offenses := &mocks.OffensesMock{AddFunc: func(ctx context.Context, offense storage.Offense) error { return nil }}
+	cfg := PunishConfig{Enabled: true, Offenses: offenses}
+	checks := []spamcheck.Response{{Name: "stopword", Spam: true}, {Name: "emoji", Spam: false}, {Name: "links", Spam: true}}
+
+	cfg.record(context.Background(), 1, "spammer", checks, punishment{duration: time.Hour, restrict: true, offense: 2})
+	cfg.record(context.Background(), -100, "channel", checks, punishment{duration: time.Hour, restrict: true, offense: 1})
+	cfg.record(context.Background(), 2, "other", checks, permanentBan) // not tracked
+	require.Len(t, offenses.AddCalls(), 2)
+	assert.Equal(t, storage.Offense{UserID: 1, UserName: "spammer", Checks: "stopword,links", Action: "restricted for 1h0m0s"},
+		offenses.AddCalls()[0].Offense)
+	assert.Equal(t, "banned for 1h0m0s", offenses.AddCalls()[1].Offense.Action, "channels can't be restricted")
+
+	offenses.AddFunc = func(ctx context.Context, offense storage.Offense) error { return errors.New("db error") }
+	cfg.record(context.Background(), 1, "spammer", checks, punishment{duration: time.Hour, offense: 1}) // logged only
+	assert.Len(t, offenses.AddCalls(), 3)
+
+	PunishConfig{Offenses: offenses}.record(context.Background(), 1, "spammer", checks, punishment{offense: 1})
+	assert.Len(t, offenses.AddCalls(), 3, "disabled ladder doesn't record")
+}
+
+func TestPunishment_historyLine(t *testing.T) {
+	assert.Empty(t, permanentBan.historyLine())
+
+	p := punishment{duration: bot.PermanentBanDuration, offense: 1, window: 24 * time.Hour, forcedBy: "cas"}
+	assert.Equal(t, "offense #1 within 24h0m0s, permanent for cas", p.historyLine())
+
+	p = punishment{duration: time.Hour, restrict: true, offense: 3, window: 24 * time.Hour, history: []storage.Offense{
+		{Checks: "classifier", Action: "restricted for 24h0m0s", CreatedAt: time.Date(2026, 10, 2, 11, 30, 0, 0, time.Local)},
+		{Checks: "stopword,links", Action: "restricted for 10m0s", CreatedAt: time.Date(2026, 10, 1, 9, 5, 0, 0, time.Local)},
+	}}
+	assert.Equal(t, "offense #3 within 24h0m0s, previous: 2026-10-02 11:30 restricted for 24h0m0s (classifier); "+
+		"2026-10-01 09:05 restricted for 10m0s (stopword,links)", p.historyLine())
+}
+
+func TestIsRestrictionNotice(t *testing.T) {
+	assert.True(t, isRestrictionNotice("restricted for 10m0s user\noffense #1\n\nmsg"))
+	assert.True(t, isRestrictionNotice("would have restricted for 10m0s user\n\nmsg"))
+	assert.False(t, isRestrictionNotice("permanently banned user\n\nrestricted for 10m0s"))
+	assert.False(t, isRestrictionNotice("banned for 24h0m0s channel (-100123)\n\nmsg"))
+}
@@ -11,6 +11,8 @@ import (
 	tbapi "github.com/OvyFlash/telegram-bot-api"
 
 	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/metrics"
 	"github.com/umputun/tg-spam/app/storage"
 )
 
@@ -48,6 +50,9 @@ type userReports struct {
 	trainingMode bool
 	softBanMode  bool
 	dry          bool
+	reputation   Reputation     // per-user history, reports of trusted reporters weigh more if set
+	events       EventPublisher // publishes auto-bans if set
+	auditLog     AuditLog       // records admin decisions on reports if set
 }
 
 // DirectUserReport handles a regular user's report of the message he replied to. the listener decides
@@ -166,6 +171,8 @@ func (r *userReports) DirectUserReport(ctx context.Context, update tbapi.Update)
 	if err := r.Storage.Add(ctx, report); err != nil {
 		return fmt.Errorf("failed to add report: %w", err)
 	}
+	metrics.Actions.Inc("report")
+	recordReputation(ctx, r.reputation, origMsg.From.ID, reportedName, storage.RepReportReceived)
 
 	// check if threshold reached
 	if err := r.checkReportThreshold(ctx, origMsg.MessageID, r.primChatID); err != nil {
@@ -212,7 +219,7 @@ func (r *userReports) checkReportThreshold(ctx context.Context, msgID int, chatI
 		return fmt.Errorf("failed to get reports: %w", err)
 	}
 
-	reportCount := len(reports)
+	reportCount := r.weightedReportCount(ctx, reports)
 
 	// check if auto-ban threshold reached
 	if r.AutoBanThreshold > 0 && reportCount >= r.AutoBanThreshold {
@@ -244,6 +251,33 @@ func (r *userReports) checkReportThreshold(ctx context.Context, msgID int, chatI
 	return r.sendReportNotification(ctx, reports)
 }
 
+// weightedReportCount returns the number of reports compared with thresholds. Reports of trusted reporters,
+// with reputation score of trustedReporterScore or more, count as two. Without reputation each report counts as one.
+func (r *userReports) weightedReportCount(ctx context.Context, reports []storage.Report) int {
+	count := len(reports)
+	if r.reputation == nil {
+		return count
+	}
+	for _, report := range reports {
+		rep, err := r.reputation.Get(ctx, report.ReporterUserID)
+		if err != nil {
+			log.Printf("[WARN] failed to get reputation of reporter %d: %v", report.ReporterUserID, err)
+			continue
+		}
+		if rep.Score() >= trustedReporterScore {
+			count++
+		}
+	}
+	return count
+}
+
+// recordReportsOutcome records the upheld or rejected outcome of the reports to the reputation of their reporters
+func (r *userReports) recordReportsOutcome(ctx context.Context, reports []storage.Report, ev storage.ReputationEvent) {
+	for _, report := range reports {
+		recordReputation(ctx, r.reputation, report.ReporterUserID, report.ReporterUserName, ev)
+	}
+}
+
 // executeAutoBan executes automatic ban when auto-ban threshold is reached
 func (r *userReports) executeAutoBan(ctx context.Context, reports []storage.Report) error {
 	if len(reports) == 0 {
@@ -296,10 +330,13 @@ func (r *userReports) executeAutoBan(ctx context.Context, reports []storage.Repo
 		training: r.trainingMode,
 		userName: reportedUserName,
 		restrict: r.softBanMode, // IMPORTANT: use soft-ban if enabled
+		events:   r.events,
+		source:   hooks.SourceReports,
 	}
 	if err := banUserOrChannel(banReq); err != nil {
 		log.Printf("[WARN] failed to auto-ban user %d: %v", reportedUserID, err)
 	}
+	r.recordReportsOutcome(ctx, reports, storage.RepReportUpheld)
 
 	// handle admin notification - update existing or send new
 	// CRITICAL: only delete reports if notification succeeds, otherwise admin callbacks will fail
@@ -694,10 +731,15 @@ func (r *userReports) callbackReportBan(ctx context.Context, query *tbapi.Callba
 		training: r.trainingMode,
 		userName: reportedUserName,
 		restrict: r.softBanMode, // respect soft-ban mode
+		events:   r.events,
+		source:   hooks.SourceAdmin,
 	}
 	if err := banUserOrChannel(banReq); err != nil {
 		log.Printf("[WARN] failed to ban user %d: %v", reportedUserID, err)
 	}
+	r.recordReportsOutcome(ctx, reports, storage.RepReportUpheld)
+	recordAudit(ctx, r.auditLog, query.From, storage.AuditRecord{Action: storage.AuditReportBan, UserID: reportedUserID,
+		UserName: reportedUserName, MsgID: msgID, Text: msgText, Reason: fmt.Sprintf("%d reports", len(reports))})
 
 	// delete all reports for this message
 	if err := r.Storage.DeleteByMessage(ctx, msgID, chatID); err != nil {
@@ -736,6 +778,10 @@ func (r *userReports) callbackReportReject(ctx context.Context, query *tbapi.Cal
 	}
 
 	chatID := reports[0].ChatID
+	r.recordReportsOutcome(ctx, reports, storage.RepReportRejected)
+	recordAudit(ctx, r.auditLog, query.From, storage.AuditRecord{Action: storage.AuditReportReject,
+		UserID: reports[0].ReportedUserID, UserName: reports[0].ReportedUserName, MsgID: msgID, Text: reports[0].MsgText,
+		Reason: fmt.Sprintf("%d reports", len(reports))})
 
 	// delete all reports for this message
 	if err := r.Storage.DeleteByMessage(ctx, msgID, chatID); err != nil {
@@ -850,10 +896,15 @@ func (r *userReports) callbackReportBanReporterConfirm(ctx context.Context, quer
 		dry:      r.dry,
 		training: r.trainingMode,
 		userName: reporterName,
+		events:   r.events,
+		source:   hooks.SourceAdmin,
 	}
 	if banErr := banUserOrChannel(banReq); banErr != nil {
 		log.Printf("[WARN] failed to ban reporter %d: %v", reporterID, banErr)
 	}
+	recordReputation(ctx, r.reputation, reporterID, reporterName, storage.RepReportRejected)
+	recordAudit(ctx, r.auditLog, query.From, storage.AuditRecord{Action: storage.AuditReporterBan, UserID: reporterID,
+		UserName: reporterName, MsgID: msgID, Reason: "false report on " + reports[0].ReportedUserName})
 
 	// delete reporter from database
 	if delErr := r.Storage.DeleteReporter(ctx, reporterID, msgID, chatID); delErr != nil {
@@ -1059,6 +1059,44 @@ func TestUserReports_CheckReportThreshold(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to get reports")
 		assert.Contains(t, err.Error(), "database error")
 	})
+
+	t.Run("report of trusted reporter counts as two", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{MessageID: 999}, nil }}
+		mockReports := &mocks.ReportsMock{
+			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
+				return []storage.Report{{MsgID: msgID, ChatID: chatID, ReporterUserID: 111, ReportedUserID: 666}}, nil
+			},
+			UpdateAdminMsgIDFunc: func(ctx context.Context, msgID int, chatID int64, adminMsgID int) error { return nil },
+		}
+		reputation := &mocks.ReputationMock{GetFunc: func(ctx context.Context, userID int64) (storage.UserReputation, error) {
+			return storage.UserReputation{UserID: userID, Messages: 100, ReportsUpheld: 2}, nil
+		}}
+		rep := &userReports{tbAPI: mockAPI, adminChatID: 456, reputation: reputation,
+			ReportConfig: ReportConfig{Storage: mockReports, Threshold: 2}}
+
+		require.NoError(t, rep.checkReportThreshold(context.Background(), 100, 200))
+		require.Len(t, reputation.GetCalls(), 1)
+		assert.Equal(t, int64(111), reputation.GetCalls()[0].UserID)
+		assert.Len(t, mockAPI.SendCalls(), 1, "notification sent")
+	})
+
+	t.Run("report of untrusted reporter counts as one", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{MessageID: 999}, nil }}
+		mockReports := &mocks.ReportsMock{
+			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
+				return []storage.Report{{MsgID: msgID, ChatID: chatID, ReporterUserID: 111, ReportedUserID: 666}}, nil
+			},
+		}
+		reputation := &mocks.ReputationMock{GetFunc: func(ctx context.Context, userID int64) (storage.UserReputation, error) {
+			return storage.UserReputation{UserID: userID, Messages: 100, ReportsRejected: 3, Warnings: 3}, nil
+		}}
+		rep := &userReports{tbAPI: mockAPI, adminChatID: 456, reputation: reputation,
+			ReportConfig: ReportConfig{Storage: mockReports, Threshold: 2}}
+
+		require.NoError(t, rep.checkReportThreshold(context.Background(), 100, 200))
+		assert.Len(t, reputation.GetCalls(), 1)
+		assert.Empty(t, mockAPI.SendCalls(), "threshold not reached")
+	})
 }
 
 func TestUserReports_AutoBan(t *testing.T) {
@@ -2037,6 +2075,37 @@ func TestUserReports_CallbackReportBan(t *testing.T) {
 		assert.Len(t, mockBot.UpdateSpamCalls(), 1)
 	})
 
+	t.Run("ban approval recorded to reporters reputation", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{
+			SendFunc:    func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+		}
+		mockReports := &mocks.ReportsMock{
+			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
+				return []storage.Report{{MsgID: 100, ChatID: 200, ReporterUserID: 111, ReporterUserName: "user1",
+					ReportedUserID: 666, ReportedUserName: "spammer", MsgText: "spam msg"}}, nil
+			},
+			DeleteByMessageFunc: func(ctx context.Context, msgID int, chatID int64) error { return nil },
+		}
+		mockBot := &mocks.BotMock{
+			RemoveApprovedUserFunc: func(userID int64) error { return nil },
+			UpdateSpamFunc:         func(msg string) error { return nil },
+		}
+		reputation := &mocks.ReputationMock{IncFunc: func(ctx context.Context, userID int64, userName string,
+			ev storage.ReputationEvent) error {
+			return nil
+		}}
+		rep := &userReports{tbAPI: mockAPI, adminChatID: 456, primChatID: 200, bot: mockBot, reputation: reputation,
+			ReportConfig: ReportConfig{Storage: mockReports}}
+		query := &tbapi.CallbackQuery{Data: "R+666:100", From: &tbapi.User{UserName: "admin"},
+			Message: &tbapi.Message{Chat: tbapi.Chat{ID: 456}, MessageID: 999, Date: time.Now().Unix()}}
+
+		require.NoError(t, rep.callbackReportBan(context.Background(), query))
+		require.Len(t, reputation.IncCalls(), 1)
+		assert.Equal(t, int64(111), reputation.IncCalls()[0].UserID)
+		assert.Equal(t, storage.RepReportUpheld, reputation.IncCalls()[0].Ev)
+	})
+
 	t.Run("no reports found", func(t *testing.T) {
 		mockReports := &mocks.ReportsMock{
 			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
@@ -2226,6 +2295,59 @@ func TestUserReports_CallbackReportReject(t *testing.T) {
 		assert.Len(t, mockReports.DeleteByMessageCalls(), 1)
 	})
 
+	t.Run("reject recorded to reporters reputation", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil }}
+		mockReports := &mocks.ReportsMock{
+			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
+				return []storage.Report{
+					{MsgID: 100, ChatID: 200, ReporterUserID: 111, ReporterUserName: "user1", ReportedUserID: 666},
+					{MsgID: 100, ChatID: 200, ReporterUserID: 222, ReporterUserName: "user2", ReportedUserID: 666},
+				}, nil
+			},
+			DeleteByMessageFunc: func(ctx context.Context, msgID int, chatID int64) error { return nil },
+		}
+		reputation := &mocks.ReputationMock{IncFunc: func(ctx context.Context, userID int64, userName string,
+			ev storage.ReputationEvent) error {
+			return nil
+		}}
+		rep := &userReports{tbAPI: mockAPI, adminChatID: 456, primChatID: 200, reputation: reputation,
+			ReportConfig: ReportConfig{Storage: mockReports}}
+		query := &tbapi.CallbackQuery{Data: "R-666:100", From: &tbapi.User{UserName: "admin"},
+			Message: &tbapi.Message{Chat: tbapi.Chat{ID: 456}, MessageID: 999, Date: time.Now().Unix()}}
+
+		require.NoError(t, rep.callbackReportReject(context.Background(), query))
+		require.Len(t, reputation.IncCalls(), 2)
+		assert.Equal(t, int64(111), reputation.IncCalls()[0].UserID)
+		assert.Equal(t, "user1", reputation.IncCalls()[0].UserName)
+		assert.Equal(t, storage.RepReportRejected, reputation.IncCalls()[0].Ev)
+		assert.Equal(t, int64(222), reputation.IncCalls()[1].UserID)
+		assert.Equal(t, storage.RepReportRejected, reputation.IncCalls()[1].Ev)
+	})
+
+	t.Run("reject recorded to audit log", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil }}
+		mockReports := &mocks.ReportsMock{
+			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
+				return []storage.Report{
+					{MsgID: 100, ChatID: 200, ReporterUserID: 111, ReportedUserID: 666, ReportedUserName: "spammer", MsgText: "hi"},
+					{MsgID: 100, ChatID: 200, ReporterUserID: 222, ReportedUserID: 666, ReportedUserName: "spammer", MsgText: "hi"},
+				}, nil
+			},
+			DeleteByMessageFunc: func(ctx context.Context, msgID int, chatID int64) error { return nil },
+		}
+		auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+		rep := &userReports{tbAPI: mockAPI, adminChatID: 456, primChatID: 200, auditLog: auditLog,
+			ReportConfig: ReportConfig{Storage: mockReports}}
+		query := &tbapi.CallbackQuery{Data: "R-666:100", From: &tbapi.User{ID: 1, FirstName: "Admin", LastName: "Person"},
+			Message: &tbapi.Message{Chat: tbapi.Chat{ID: 456}, MessageID: 999, Date: time.Now().Unix()}}
+
+		require.NoError(t, rep.callbackReportReject(context.Background(), query))
+		require.Len(t, auditLog.AddCalls(), 1)
+		assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceTelegram, Actor: "Admin Person", ActorID: 1,
+			Action: storage.AuditReportReject, UserID: 666, UserName: "spammer", MsgID: 100, Text: "hi", Reason: "2 reports"},
+			auditLog.AddCalls()[0].Rec)
+	})
+
 	t.Run("no reports found", func(t *testing.T) {
 		mockReports := &mocks.ReportsMock{
 			GetByMessageFunc: func(ctx context.Context, msgID int, chatID int64) ([]storage.Report, error) {
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"log"
+
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+//go:generate moq --out mocks/reputation.go --pkg mocks --with-resets --skip-ensure . Reputation
+
+// Reputation is an interface for per-user history counters
+type Reputation interface {
+	Inc(ctx context.Context, userID int64, userName string, ev storage.ReputationEvent) error
+	Get(ctx context.Context, userID int64) (storage.UserReputation, error)
+}
+
+// trustedReporterScore is the min reputation score of a reporter whose report counts as two
+const trustedReporterScore = 20
+
+// recordReputation increments the user's counter of the event, no-op if reputation is not tracked.
+// Failures are logged only, reputation is a secondary signal and must not break message processing.
+func recordReputation(ctx context.Context, rep Reputation, userID int64, userName string, ev storage.ReputationEvent) {
+	if rep == nil || userID == 0 {
+		return
+	}
+	if err := rep.Inc(ctx, userID, userName, ev); err != nil {
+		log.Printf("[WARN] failed to record %s for user %d: %v", ev, userID, err)
+	}
+}
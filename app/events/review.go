@@ -0,0 +1,189 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+//go:generate moq --out mocks/review_queue.go --pkg mocks --with-resets --skip-ensure . ReviewQueue
+
+// ReviewQueue is an interface for the admin review queue of quarantined suspicious messages
+type ReviewQueue interface {
+	Add(ctx context.Context, rv storage.Review) (int64, error)
+	Get(ctx context.Context, id int64) (storage.Review, error)
+	Resolve(ctx context.Context, id int64, status storage.ReviewStatus, admin string) error
+}
+
+// review callback prefixes, callback data is the prefix followed by the review id, e.g. Q+123
+const (
+	reviewApprovePrefix = "Q+"
+	reviewBanPrefix     = "Q-"
+)
+
+// QuarantineSuspicious puts a suspicious message to the review queue, deletes it from the chat and sends it to admin chat
+// with approve and ban buttons. In dry and training modes the message is not deleted. The user is not restricted,
+// next messages are checked as usual.
+func (a *admin) QuarantineSuspicious(ctx context.Context, userStr string, msg *bot.Message, checks []spamcheck.Response) error {
+	userID := msg.From.ID
+	if msg.SenderChat.ID != 0 {
+		userID = msg.SenderChat.ID
+	}
+	msgText := msg.Text
+	if msg.Quote != "" {
+		msgText = msg.Text + "\n" + msg.Quote
+	}
+
+	id, err := a.reviews.Add(ctx, storage.Review{ChatID: a.primChatID, MsgID: msg.ID, UserID: userID, UserName: userStr,
+		Text: msgText, Checks: checks})
+	if err != nil {
+		return fmt.Errorf("failed to add message %d to review queue: %w", msg.ID, err)
+	}
+
+	header := "quarantined suspicious message"
+	if a.hidesQuarantined() {
+		_, err := a.tbAPI.Request(tbapi.DeleteMessageConfig{BaseChatMessage: tbapi.BaseChatMessage{
+			MessageID: msg.ID, ChatConfig: tbapi.ChatConfig{ChatID: a.primChatID}}})
+		if err != nil {
+			log.Printf("[WARN] failed to delete quarantined message %d: %v", msg.ID, err)
+		}
+	} else {
+		header = "suspicious message for review, not deleted"
+	}
+
+	suspicious := make([]string, 0, len(checks))
+	for _, c := range checks {
+		if c.Score != 0 || c.Suspicious {
+			suspicious = append(suspicious, escapeMarkDownV1Text(c.String()))
+		}
+	}
+	text := strings.ReplaceAll(escapeMarkDownV1Text(msgText), "\n", " ")
+	notification := fmt.Sprintf("**%s from [%s](tg://user?id=%d)**\n\n%s\n\n_%s_",
+		header, escapeMarkDownV1Text(userStr), msg.From.ID, text, strings.Join(suspicious, "; "))
+
+	tbMsg := tbapi.NewMessage(a.adminChatID, notification)
+	tbMsg.ReplyMarkup = tbapi.NewInlineKeyboardMarkup(
+		tbapi.NewInlineKeyboardRow(
+			tbapi.NewInlineKeyboardButtonData("✅ Approve", reviewApprovePrefix+strconv.FormatInt(id, 10)),
+			tbapi.NewInlineKeyboardButtonData("⛔️ Ban", reviewBanPrefix+strconv.FormatInt(id, 10)),
+		),
+	)
+	if err := send(tbMsg, a.tbAPI); err != nil {
+		return fmt.Errorf("failed to send quarantined message %d to admin chat: %w", msg.ID, err)
+	}
+	log.Printf("[INFO] message %d from %s quarantined for review, id:%d", msg.ID, userStr, id)
+	return nil
+}
+
+// callbackReviewApprove restores the quarantined message as a bot repost and updates ham samples
+// callback data: Q+reviewID
+func (a *admin) callbackReviewApprove(ctx context.Context, query *tbapi.CallbackQuery) error {
+	rv, err := a.pendingReview(ctx, query.Data)
+	if err != nil {
+		return err
+	}
+	if err := a.reviews.Resolve(ctx, rv.ID, storage.ReviewApproved, query.From.UserName); err != nil {
+		return fmt.Errorf("failed to approve review: %w", err)
+	}
+
+	if a.hidesQuarantined() && rv.Text != "" {
+		repost := fmt.Sprintf("_message from %s restored after review:_\n\n%s",
+			escapeMarkDownV1Text(rv.UserName), escapeMarkDownV1Text(rv.Text))
+		if err := send(tbapi.NewMessage(rv.ChatID, repost), a.tbAPI); err != nil {
+			log.Printf("[WARN] failed to restore message %d: %v", rv.MsgID, err)
+		}
+	}
+	if rv.Text != "" {
+		if err := a.bot.UpdateHam(rv.Text); err != nil {
+			log.Printf("[WARN] failed to update ham samples: %v", err)
+		}
+	}
+
+	if err := a.resolveReviewNotification(query, "approved"); err != nil {
+		return err
+	}
+	log.Printf("[INFO] review %d approved by %s, message %d from %s", rv.ID, query.From.UserName, rv.MsgID, rv.UserName)
+	return nil
+}
+
+// callbackReviewBan bans the user of the quarantined message and updates spam samples. The review is resolved
+// only if the ban succeeded, otherwise it stays in the queue and the error is reported to admin chat.
+// callback data: Q-reviewID
+func (a *admin) callbackReviewBan(ctx context.Context, query *tbapi.CallbackQuery) error {
+	rv, err := a.pendingReview(ctx, query.Data)
+	if err != nil {
+		return err
+	}
+
+	banReq := banRequest{duration: bot.PermanentBanDuration, userID: rv.UserID, chatID: rv.ChatID, userName: rv.UserName,
+		tbAPI: a.tbAPI, dry: a.dry, training: a.trainingMode, restrict: a.softBan, events: a.events, source: hooks.SourceAdmin}
+	if rv.UserID < 0 { // message sent on behalf of a channel
+		banReq.userID, banReq.channelID = 0, rv.UserID
+	}
+	if err := banUserOrChannel(banReq); err != nil {
+		return fmt.Errorf("failed to ban %s on review %d: %w", rv.UserName, rv.ID, err)
+	}
+	if err := a.reviews.Resolve(ctx, rv.ID, storage.ReviewBanned, query.From.UserName); err != nil {
+		return fmt.Errorf("failed to resolve review %d, %s is banned: %w", rv.ID, rv.UserName, err)
+	}
+
+	if err := a.bot.RemoveApprovedUser(rv.UserID); err != nil {
+		log.Printf("[DEBUG] can't remove user %d from approved list: %v", rv.UserID, err)
+	}
+	if !a.dry && rv.Text != "" {
+		if err := a.bot.UpdateSpam(rv.Text); err != nil {
+			log.Printf("[WARN] failed to update spam samples: %v", err)
+		}
+	}
+
+	if err := a.resolveReviewNotification(query, "banned"); err != nil {
+		return err
+	}
+	log.Printf("[INFO] review %d, %s banned by %s", rv.ID, rv.UserName, query.From.UserName)
+	return nil
+}
+
+// pendingReview returns the review from callback data, reviews resolved already are rejected
+func (a *admin) pendingReview(ctx context.Context, callbackData string) (storage.Review, error) {
+	if a.reviews == nil {
+		return storage.Review{}, fmt.Errorf("review queue is not enabled")
+	}
+	id, err := strconv.ParseInt(callbackData[len(reviewApprovePrefix):], 10, 64)
+	if err != nil {
+		return storage.Review{}, fmt.Errorf("failed to parse review id from %q: %w", callbackData, err)
+	}
+	rv, err := a.reviews.Get(ctx, id)
+	if err != nil {
+		return storage.Review{}, fmt.Errorf("failed to get review: %w", err)
+	}
+	if rv.Status != storage.ReviewPending {
+		return storage.Review{}, fmt.Errorf("review %d is already %s by %s", id, rv.Status, rv.ResolvedBy)
+	}
+	return rv, nil
+}
+
+// resolveReviewNotification marks the admin notification with the decision and removes the buttons
+func (a *admin) resolveReviewNotification(query *tbapi.CallbackQuery, action string) error {
+	updText := query.Message.Text + fmt.Sprintf("\n\n_%s by %s in %v_", action, query.From.UserName, sinceQuery(query))
+	editMsg := tbapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, updText)
+	editMsg.ReplyMarkup = &tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+	if err := send(editMsg, a.tbAPI); err != nil {
+		return fmt.Errorf("failed to update review notification, chatID:%d, msgID:%d, %w",
+			query.Message.Chat.ID, query.Message.MessageID, err)
+	}
+	return nil
+}
+
+// hidesQuarantined returns true if quarantined messages are deleted from the chat and restored on approve
+func (a *admin) hidesQuarantined() bool {
+	return !a.dry && !a.trainingMode
+}
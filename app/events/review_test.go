@@ -0,0 +1,224 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/events/mocks"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestAdmin_QuarantineSuspicious(t *testing.T) {
+	checks := []spamcheck.Response{
+		{Name: "classifier", Details: "probability of spam: 55.00%, suspicious", Suspicious: true},
+		{Name: "emoji", Details: "0/2"},
+	}
+	newMocks := func() (*mocks.TbAPIMock, *mocks.ReviewQueueMock) {
+		mockAPI := &mocks.TbAPIMock{
+			SendFunc:    func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+		}
+		reviews := &mocks.ReviewQueueMock{AddFunc: func(ctx context.Context, rv storage.Review) (int64, error) { return 42, nil }}
+		return mockAPI, reviews
+	}
+
+	t.Run("message hidden and sent for review", func(t *testing.T) {
+		mockAPI, reviews := newMocks()
+		adm := admin{tbAPI: mockAPI, reviews: reviews, primChatID: 100, adminChatID: 123}
+		msg := &bot.Message{ID: 7, From: bot.User{ID: 456}, Text: "buy *now*"}
+		require.NoError(t, adm.QuarantineSuspicious(context.Background(), "some_user", msg, checks))
+
+		require.Len(t, reviews.AddCalls(), 1)
+		assert.Equal(t, storage.Review{ChatID: 100, MsgID: 7, UserID: 456, UserName: "some_user", Text: "buy *now*", Checks: checks},
+			reviews.AddCalls()[0].Rv)
+
+		require.Len(t, mockAPI.RequestCalls(), 1)
+		del := mockAPI.RequestCalls()[0].C.(tbapi.DeleteMessageConfig)
+		assert.Equal(t, 7, del.MessageID)
+		assert.Equal(t, int64(100), del.ChatID)
+
+		require.Len(t, mockAPI.SendCalls(), 1)
+		sent := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig)
+		assert.Equal(t, int64(123), sent.ChatID)
+		assert.Equal(t, "**quarantined suspicious message from [some\\_user](tg://user?id=456)**\n\nbuy \\*now\\*\n\n"+
+			"_classifier: ham, probability of spam: 55.00%, suspicious_", sent.Text)
+		kb := sent.ReplyMarkup.(tbapi.InlineKeyboardMarkup)
+		require.Len(t, kb.InlineKeyboard, 1)
+		require.Len(t, kb.InlineKeyboard[0], 2)
+		assert.Equal(t, "Q+42", *kb.InlineKeyboard[0][0].CallbackData)
+		assert.Equal(t, "Q-42", *kb.InlineKeyboard[0][1].CallbackData)
+	})
+
+	t.Run("channel message in training mode not deleted", func(t *testing.T) {
+		mockAPI, reviews := newMocks()
+		adm := admin{tbAPI: mockAPI, reviews: reviews, primChatID: 100, adminChatID: 123, trainingMode: true}
+		msg := &bot.Message{ID: 7, From: bot.User{ID: 136817688}, SenderChat: bot.SenderChat{ID: -1001}, Text: "text"}
+		require.NoError(t, adm.QuarantineSuspicious(context.Background(), "channel", msg, checks))
+
+		require.Len(t, reviews.AddCalls(), 1)
+		assert.Equal(t, int64(-1001), reviews.AddCalls()[0].Rv.UserID)
+		assert.Empty(t, mockAPI.RequestCalls())
+		require.Len(t, mockAPI.SendCalls(), 1)
+		assert.Contains(t, mockAPI.SendCalls()[0].C.(tbapi.MessageConfig).Text, "suspicious message for review, not deleted")
+	})
+
+	t.Run("queue error", func(t *testing.T) {
+		mockAPI, reviews := newMocks()
+		reviews.AddFunc = func(ctx context.Context, rv storage.Review) (int64, error) { return 0, errors.New("db error") }
+		adm := admin{tbAPI: mockAPI, reviews: reviews, primChatID: 100, adminChatID: 123}
+		err := adm.QuarantineSuspicious(context.Background(), "user", &bot.Message{ID: 7, Text: "text"}, checks)
+		require.EqualError(t, err, "failed to add message 7 to review queue: db error")
+		assert.Empty(t, mockAPI.RequestCalls(), "message not deleted")
+		assert.Empty(t, mockAPI.SendCalls())
+	})
+}
+
+func TestAdmin_InlineCallbackHandlerReview(t *testing.T) {
+	pending := storage.Review{ID: 42, ChatID: 100, MsgID: 7, UserID: 456, UserName: "some_user", Text: "borderline text",
+		Status: storage.ReviewPending}
+	newQuery := func(data string) *tbapi.CallbackQuery {
+		return &tbapi.CallbackQuery{Data: data, From: &tbapi.User{UserName: "admin"},
+			Message: &tbapi.Message{MessageID: 555, Chat: tbapi.Chat{ID: 123}, Text: "quarantined suspicious message",
+				Date: time.Now().Unix()}}
+	}
+	newMocks := func(rv storage.Review) (*mocks.TbAPIMock, *mocks.BotMock, *mocks.ReviewQueueMock) {
+		mockAPI := &mocks.TbAPIMock{
+			SendFunc:    func(c tbapi.Chattable) (tbapi.Message, error) { return tbapi.Message{}, nil },
+			RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return &tbapi.APIResponse{Ok: true}, nil },
+		}
+		botMock := &mocks.BotMock{
+			UpdateHamFunc:          func(msg string) error { return nil },
+			UpdateSpamFunc:         func(msg string) error { return nil },
+			RemoveApprovedUserFunc: func(id int64) error { return nil },
+		}
+		reviews := &mocks.ReviewQueueMock{
+			GetFunc:     func(ctx context.Context, id int64) (storage.Review, error) { return rv, nil },
+			ResolveFunc: func(ctx context.Context, id int64, status storage.ReviewStatus, admin string) error { return nil },
+		}
+		return mockAPI, botMock, reviews
+	}
+
+	t.Run("approve restores message", func(t *testing.T) {
+		mockAPI, botMock, reviews := newMocks(pending)
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123}
+		require.NoError(t, adm.InlineCallbackHandler(newQuery("Q+42")))
+
+		require.Len(t, reviews.GetCalls(), 1)
+		assert.Equal(t, int64(42), reviews.GetCalls()[0].ID)
+		require.Len(t, reviews.ResolveCalls(), 1)
+		assert.Equal(t, storage.ReviewApproved, reviews.ResolveCalls()[0].Status)
+		assert.Equal(t, "admin", reviews.ResolveCalls()[0].Admin)
+		require.Len(t, botMock.UpdateHamCalls(), 1)
+		assert.Equal(t, "borderline text", botMock.UpdateHamCalls()[0].Msg)
+		assert.Empty(t, botMock.UpdateSpamCalls())
+
+		require.Len(t, mockAPI.SendCalls(), 2)
+		repost := mockAPI.SendCalls()[0].C.(tbapi.MessageConfig)
+		assert.Equal(t, int64(100), repost.ChatID)
+		assert.Equal(t, "_message from some\\_user restored after review:_\n\nborderline text", repost.Text)
+		edit := mockAPI.SendCalls()[1].C.(tbapi.EditMessageTextConfig)
+		assert.Equal(t, 555, edit.MessageID)
+		assert.Contains(t, edit.Text, "_approved by admin in ")
+		assert.Empty(t, edit.ReplyMarkup.InlineKeyboard)
+		assert.Empty(t, mockAPI.RequestCalls(), "nobody banned")
+	})
+
+	t.Run("approve in training mode doesn't repost", func(t *testing.T) {
+		mockAPI, botMock, reviews := newMocks(pending)
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123, trainingMode: true}
+		require.NoError(t, adm.InlineCallbackHandler(newQuery("Q+42")))
+		require.Len(t, mockAPI.SendCalls(), 1)
+		assert.IsType(t, tbapi.EditMessageTextConfig{}, mockAPI.SendCalls()[0].C)
+		assert.Len(t, botMock.UpdateHamCalls(), 1)
+	})
+
+	t.Run("ban user", func(t *testing.T) {
+		mockAPI, botMock, reviews := newMocks(pending)
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123}
+		require.NoError(t, adm.InlineCallbackHandler(newQuery("Q-42")))
+
+		require.Len(t, reviews.ResolveCalls(), 1)
+		assert.Equal(t, storage.ReviewBanned, reviews.ResolveCalls()[0].Status)
+		require.Len(t, botMock.RemoveApprovedUserCalls(), 1)
+		assert.Equal(t, int64(456), botMock.RemoveApprovedUserCalls()[0].ID)
+		require.Len(t, botMock.UpdateSpamCalls(), 1)
+		assert.Equal(t, "borderline text", botMock.UpdateSpamCalls()[0].Msg)
+
+		require.Len(t, mockAPI.RequestCalls(), 1)
+		ban := mockAPI.RequestCalls()[0].C.(tbapi.BanChatMemberConfig)
+		assert.Equal(t, int64(456), ban.UserID)
+		assert.Equal(t, int64(100), ban.ChatID)
+		require.Len(t, mockAPI.SendCalls(), 1)
+		assert.Contains(t, mockAPI.SendCalls()[0].C.(tbapi.EditMessageTextConfig).Text, "_banned by admin in ")
+	})
+
+	t.Run("ban failed", func(t *testing.T) {
+		mockAPI, botMock, reviews := newMocks(pending)
+		mockAPI.RequestFunc = func(c tbapi.Chattable) (*tbapi.APIResponse, error) { return nil, errors.New("not enough rights") }
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123}
+		err := adm.InlineCallbackHandler(newQuery("Q-42"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to ban some_user on review 42")
+		assert.Contains(t, err.Error(), "not enough rights")
+		assert.Empty(t, reviews.ResolveCalls(), "review stays in the queue")
+		assert.Empty(t, botMock.UpdateSpamCalls())
+		assert.Empty(t, mockAPI.SendCalls(), "buttons kept to retry")
+	})
+
+	t.Run("resolve failed after ban", func(t *testing.T) {
+		mockAPI, botMock, reviews := newMocks(pending)
+		reviews.ResolveFunc = func(ctx context.Context, id int64, status storage.ReviewStatus, admin string) error {
+			return errors.New("db locked")
+		}
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123}
+		err := adm.InlineCallbackHandler(newQuery("Q-42"))
+		require.EqualError(t, err, "failed to resolve review 42, some_user is banned: db locked")
+		assert.Len(t, mockAPI.RequestCalls(), 1)
+	})
+
+	t.Run("ban channel", func(t *testing.T) {
+		rv := pending
+		rv.UserID = -1001
+		mockAPI, botMock, reviews := newMocks(rv)
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123}
+		require.NoError(t, adm.InlineCallbackHandler(newQuery("Q-42")))
+		require.Len(t, mockAPI.RequestCalls(), 1)
+		ban := mockAPI.RequestCalls()[0].C.(tbapi.BanChatSenderChatConfig)
+		assert.Equal(t, int64(-1001), ban.SenderChatID)
+	})
+
+	t.Run("already resolved", func(t *testing.T) {
+		rv := pending
+		rv.Status, rv.ResolvedBy = storage.ReviewApproved, "other"
+		mockAPI, botMock, reviews := newMocks(rv)
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, primChatID: 100, adminChatID: 123}
+		err := adm.InlineCallbackHandler(newQuery("Q-42"))
+		require.EqualError(t, err, "review 42 is already approved by other")
+		assert.Empty(t, reviews.ResolveCalls())
+		assert.Empty(t, mockAPI.RequestCalls())
+		assert.Empty(t, mockAPI.SendCalls())
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockAPI, botMock, reviews := newMocks(pending)
+		adm := admin{tbAPI: mockAPI, bot: botMock, reviews: reviews, adminChatID: 123}
+		err := adm.InlineCallbackHandler(newQuery("Q+abc"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse review id")
+		assert.Empty(t, reviews.GetCalls())
+	})
+
+	t.Run("queue disabled", func(t *testing.T) {
+		mockAPI, botMock, _ := newMocks(pending)
+		adm := admin{tbAPI: mockAPI, bot: botMock, adminChatID: 123}
+		require.EqualError(t, adm.InlineCallbackHandler(newQuery("Q+42")), "review queue is not enabled")
+	})
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+// webhookSecretHeader is the header telegram sets to the secret token on each webhook request
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookQueueSize is the number of webhook updates waiting for processing, telegram retries rejected updates
+const webhookQueueSize = 100
+
+// maxWebhookBodySize limits the size of the update posted to the webhook, telegram updates are way smaller
+const maxWebhookBodySize = 1 << 20
+
+// allowedUpdates is the list of update types the bot receives, both for long polling and webhook
+var allowedUpdates = []string{"message", "edited_message", "callback_query", "message_reaction"}
+
+// WebhookConfig defines the webhook mode. In webhook mode telegram posts updates to the URL
+// and the web server passes them to HandleWebhook, instead of the listener polling for updates.
+type WebhookConfig struct {
+	URL    string // public url of the webhook, empty for long polling
+	Secret string // secret token telegram sends with each update, required in webhook mode
+}
+
+// SetupUpdatesMode registers the webhook with telegram if the webhook URL is set, or deletes a webhook
+// left from a previous run otherwise, as telegram rejects polling while a webhook is set.
+// Should be called once on startup, before the listener's Do.
+func SetupUpdatesMode(api TbAPI, wh WebhookConfig) error {
+	if wh.URL == "" {
+		if _, err := api.Request(tbapi.DeleteWebhookConfig{}); err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		return nil
+	}
+	if wh.Secret == "" {
+		return errors.New("webhook secret is required")
+	}
+	cfg, err := tbapi.NewWebhook(wh.URL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url %q: %w", wh.URL, err)
+	}
+	cfg.SecretToken = wh.Secret
+	cfg.AllowedUpdates = allowedUpdates
+	if _, err := api.Request(cfg); err != nil {
+		return fmt.Errorf("failed to set webhook %s: %w", wh.URL, err)
+	}
+	log.Printf("[INFO] webhook set to %s", wh.URL)
+	return nil
+}
+
+// HandleWebhook handles updates posted by telegram to the webhook. It verifies the secret token
+// and queues the update for Do, which processes it the same way as polled updates.
+func (l *TelegramListener) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	if l.Webhook.URL == "" || l.Webhook.Secret == "" {
+		http.Error(w, "webhook mode is disabled", http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(l.Webhook.Secret)) != 1 {
+		log.Printf("[WARN] webhook request from %s rejected, invalid secret token", r.RemoteAddr)
+		http.Error(w, "invalid secret token", http.StatusUnauthorized)
+		return
+	}
+
+	var update tbapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&update); err != nil {
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			http.Error(w, "update is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "can't decode update", http.StatusBadRequest)
+		return
+	}
+
+	// don't wait for the queue, a full queue means processing is behind and telegram retries the update later
+	select {
+	case l.webhookUpdates() <- update:
+		w.WriteHeader(http.StatusOK)
+	default:
+		log.Printf("[WARN] webhook update %d rejected, update queue is full", update.UpdateID)
+		http.Error(w, "update queue is full", http.StatusServiceUnavailable)
+	}
+}
+
+// updatesChan returns the channel of updates to process, posted to the webhook in webhook mode
+// or long polled otherwise
+func (l *TelegramListener) updatesChan() tbapi.UpdatesChannel {
+	if l.Webhook.URL != "" {
+		log.Printf("[DEBUG] start receiving updates on webhook")
+		return l.webhookUpdates()
+	}
+	u := tbapi.NewUpdate(0)
+	u.Timeout = 60
+	u.AllowedUpdates = allowedUpdates
+	log.Printf("[DEBUG] start listening for updates")
+	return l.TbAPI.GetUpdatesChan(u)
+}
+
+func (l *TelegramListener) webhookUpdates() chan tbapi.Update {
+	l.webhook.once.Do(func() {
+		l.webhook.ch = make(chan tbapi.Update, webhookQueueSize)
+	})
+	return l.webhook.ch
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/events/mocks"
+)
+
+func TestSetupUpdatesMode(t *testing.T) {
+	t.Run("polling deletes webhook", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		}}
+		require.NoError(t, SetupUpdatesMode(mockAPI, WebhookConfig{}))
+		require.Len(t, mockAPI.RequestCalls(), 1)
+		assert.Equal(t, tbapi.DeleteWebhookConfig{}, mockAPI.RequestCalls()[0].C)
+	})
+
+	t.Run("webhook is set with secret", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return &tbapi.APIResponse{Ok: true}, nil
+		}}
+		require.NoError(t, SetupUpdatesMode(mockAPI, WebhookConfig{URL: "https://example.com/telegram/webhook", Secret: "secret"}))
+		require.Len(t, mockAPI.RequestCalls(), 1)
+		cfg, ok := mockAPI.RequestCalls()[0].C.(tbapi.WebhookConfig)
+		require.True(t, ok)
+		assert.Equal(t, "https://example.com/telegram/webhook", cfg.URL.String())
+		assert.Equal(t, "secret", cfg.SecretToken)
+		assert.Equal(t, allowedUpdates, cfg.AllowedUpdates)
+	})
+
+	t.Run("webhook without secret", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{}
+		err := SetupUpdatesMode(mockAPI, WebhookConfig{URL: "https://example.com/telegram/webhook"})
+		require.EqualError(t, err, "webhook secret is required")
+		assert.Empty(t, mockAPI.RequestCalls())
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		mockAPI := &mocks.TbAPIMock{RequestFunc: func(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+			return nil, errors.New("api error")
+		}}
+		err := SetupUpdatesMode(mockAPI, WebhookConfig{URL: "https://example.com/telegram/webhook", Secret: "secret"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to set webhook")
+		err = SetupUpdatesMode(mockAPI, WebhookConfig{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to delete webhook")
+	})
+}
+
+func TestTelegramListener_HandleWebhook(t *testing.T) {
+	const update = `{"update_id": 1, "message": {"message_id": 10, "text": "hello", "chat": {"id": 123}}}`
+	l := &TelegramListener{Webhook: WebhookConfig{URL: "https://example.com/telegram/webhook", Secret: "secret"}}
+
+	tests := []struct {
+		name     string
+		listener *TelegramListener
+		secret   string
+		body     string
+		want     int
+	}{
+		{name: "valid update", listener: l, secret: "secret", body: update, want: http.StatusOK},
+		{name: "wrong secret", listener: l, secret: "bad", body: update, want: http.StatusUnauthorized},
+		{name: "no secret", listener: l, body: update, want: http.StatusUnauthorized},
+		{name: "bad body", listener: l, secret: "secret", body: "not json", want: http.StatusBadRequest},
+		{name: "too large", listener: l, secret: "secret", want: http.StatusRequestEntityTooLarge,
+			body: `{"update_id": 2, "message": {"text": "` + strings.Repeat("a", maxWebhookBodySize) + `"}}`},
+		{name: "webhook disabled", listener: &TelegramListener{}, secret: "", body: update, want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				req.Header.Set(webhookSecretHeader, tt.secret)
+			}
+			w := httptest.NewRecorder()
+			tt.listener.HandleWebhook(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	require.Len(t, l.webhookUpdates(), 1, "only the valid update is queued")
+	upd := <-l.webhookUpdates()
+	assert.Equal(t, 1, upd.UpdateID)
+	assert.Equal(t, "hello", upd.Message.Text)
+
+	t.Run("queue full", func(t *testing.T) {
+		full := &TelegramListener{Webhook: WebhookConfig{URL: "https://example.com/telegram/webhook", Secret: "secret"}}
+		for range webhookQueueSize {
+			full.webhookUpdates() <- tbapi.Update{}
+		}
+		req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(update))
+		req.Header.Set(webhookSecretHeader, "secret")
+		w := httptest.NewRecorder()
+		st := time.Now()
+		full.HandleWebhook(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Less(t, time.Since(st), time.Second, "rejected without waiting for the queue")
+	})
+}
+
+func TestTelegramListener_DoWebhook(t *testing.T) {
+	mockAPI := &mocks.TbAPIMock{
+		GetChatFunc: func(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+			return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+		},
+		GetChatAdministratorsFunc: func(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+			return nil, nil
+		},
+		SendFunc: func(c tbapi.Chattable) (tbapi.Message, error) {
+			return tbapi.Message{}, nil
+		},
+	}
+	botMock := &mocks.BotMock{OnMessageFunc: func(msg bot.Message, checkOnly bool) bot.Response {
+		return bot.Response{}
+	}}
+	locator, teardown := prepTestLocator(t)
+	defer teardown()
+
+	l := TelegramListener{
+		SpamLogger: &mocks.SpamLoggerMock{SaveFunc: func(msg *bot.Message, response *bot.Response) {}},
+		TbAPI:      mockAPI,
+		Bot:        botMock,
+		Group:      "gr",
+		Locator:    locator,
+		Webhook:    WebhookConfig{URL: "https://example.com/telegram/webhook", Secret: "secret"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- l.Do(ctx) }()
+
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook",
+		strings.NewReader(`{"update_id": 1, "message": {"message_id": 10, "text": "text 123", "chat": {"id": 123}, "from": {"id": 1, "username": "user"}}}`))
+	req.Header.Set(webhookSecretHeader, "secret")
+	w := httptest.NewRecorder()
+	l.HandleWebhook(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Eventually(t, func() bool { return len(botMock.OnMessageCalls()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "text 123", botMock.OnMessageCalls()[0].Msg.Text)
+	assert.Equal(t, "user", botMock.OnMessageCalls()[0].Msg.From.Username)
+
+	cancel()
+	err := <-done
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, mockAPI.GetUpdatesChanCalls(), "updates are not polled in webhook mode")
+}
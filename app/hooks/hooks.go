@@ -0,0 +1,273 @@
+// Package hooks delivers moderation events to outgoing webhooks. Events are queued in a persistent
+// storage, one delivery per webhook url, and posted by a background worker with HMAC-SHA256 signature.
+// Failed deliveries are retried with exponential backoff until delivered or out of attempts.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/umputun/tg-spam/app/metrics"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+//go:generate moq --out mocks/queue.go --pkg mocks --with-resets --skip-ensure . Queue
+
+// event types
+const (
+	EventSpamDetected = "spam_detected" // message detected as spam by the bot
+	EventBan          = "ban"           // user or channel banned
+	EventSoftBan      = "soft_ban"      // user restricted instead of banned, soft ban mode
+	EventUnban        = "unban"         // user or channel unbanned by admin
+	EventWarn         = "warn"          // user warned by admin
+	EventSampleAdded  = "sample_added"  // spam or ham sample added to the classifier
+)
+
+// event sources, what triggered the event
+const (
+	SourceDetector  = "detector"  // spam detected in a regular message
+	SourceAdmin     = "admin"     // admin action, forwarded message or command in admin chat or group
+	SourceReports   = "reports"   // auto-ban after enough user reports
+	SourceWarnings  = "warnings"  // auto-ban after too many warnings
+	SourceCaptcha   = "captcha"   // new member failed captcha
+	SourceReactions = "reactions" // reaction spam
+	SourceWeb       = "web"       // web UI
+)
+
+// signature and metadata headers of each delivery
+const (
+	SignatureHeader = "X-Tg-Spam-Signature-256"
+	EventHeader     = "X-Tg-Spam-Event"
+	DeliveryHeader  = "X-Tg-Spam-Delivery"
+)
+
+// default delivery parameters
+const (
+	defaultMaxAttempts = 10
+	defaultRetryDelay  = 30 * time.Second
+	maxRetryDelay      = 6 * time.Hour
+	deliveryBatch      = 50
+	pollInterval       = 5 * time.Second
+	maxErrorLen        = 256
+)
+
+// Event is a moderation event sent to webhooks as json body
+type Event struct {
+	ID        string               `json:"id"`
+	Type      string               `json:"type"`
+	Time      time.Time            `json:"time"`
+	Source    string               `json:"source,omitempty"`
+	ChatID    int64                `json:"chat_id,omitempty"`
+	UserID    int64                `json:"user_id,omitempty"`
+	UserName  string               `json:"user_name,omitempty"`
+	ChannelID int64                `json:"channel_id,omitempty"`
+	Text      string               `json:"text,omitempty"`
+	Details   string               `json:"details,omitempty"` // free-form details, e.g. sample type or ban duration
+	Checks    []spamcheck.Response `json:"checks,omitempty"`  // check results, for spam_detected only
+}
+
+// Queue is a persistent queue of deliveries, implemented by storage.EventDeliveries
+type Queue interface {
+	Add(ctx context.Context, deliveries []storage.EventDelivery) error
+	Due(ctx context.Context, now time.Time, limit int) ([]storage.EventDelivery, error)
+	Retry(ctx context.Context, id int64, next time.Time, lastErr string) error
+	Delete(ctx context.Context, id int64) error
+}
+
+// Dispatcher queues events for all webhook urls and delivers them in the background.
+// Publish is safe for concurrent use, Run should be started once.
+type Dispatcher struct {
+	URLs        []string      // webhook urls, each event is delivered to all of them
+	Secret      string        // secret to sign the body with HMAC-SHA256
+	Queue       Queue         // persistent deliveries queue
+	HTTPClient  *http.Client  // optional, http client with 10s timeout used by default
+	MaxAttempts int           // attempts before the delivery is dropped
+	RetryDelay  time.Duration // delay before the first retry, doubled on each following one
+
+	wake chan struct{}
+}
+
+// NewDispatcher makes a dispatcher for the given urls with defaults for unset parameters
+func NewDispatcher(urls []string, secret string, queue Queue) *Dispatcher {
+	return &Dispatcher{
+		URLs:        urls,
+		Secret:      secret,
+		Queue:       queue,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: defaultMaxAttempts,
+		RetryDelay:  defaultRetryDelay,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Publish queues the event for delivery to all urls. ID and Time are set if empty.
+// Delivery itself is asynchronous, done by Run.
+func (d *Dispatcher) Publish(ctx context.Context, e Event) error {
+	if len(d.URLs) == 0 {
+		return nil
+	}
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", e.Type, err)
+	}
+	deliveries := make([]storage.EventDelivery, 0, len(d.URLs))
+	for _, u := range d.URLs {
+		deliveries = append(deliveries, storage.EventDelivery{URL: u, EventType: e.Type, Payload: string(body)})
+	}
+	if err := d.Queue.Add(ctx, deliveries); err != nil {
+		return fmt.Errorf("failed to queue %s event: %w", e.Type, err)
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default: // worker already signaled
+	}
+	return nil
+}
+
+// Run delivers queued events until the context is canceled. Deliveries left from
+// a previous run are picked up on start.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("[INFO] event webhooks enabled for %d url(s)", len(d.URLs))
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverDue sends all due deliveries, in batches, and reschedules or drops failed ones
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.Queue.Due(ctx, time.Now(), deliveryBatch)
+		if err != nil {
+			log.Printf("[WARN] failed to get due event deliveries: %v", err)
+			return
+		}
+		failed := 0
+		for _, dl := range due {
+			if !d.deliver(ctx, dl) {
+				failed++
+			}
+		}
+		// stop when the queue is drained or when everything failed, failed deliveries are rescheduled
+		// into the future, so a full batch of successes means there may be more due
+		if len(due) < deliveryBatch || failed == len(due) {
+			return
+		}
+	}
+}
+
+// deliver posts a single delivery and updates the queue, returns true if delivered
+func (d *Dispatcher) deliver(ctx context.Context, dl storage.EventDelivery) bool {
+	err := d.post(ctx, dl)
+	if err == nil {
+		metrics.HookDeliveries.Inc("delivered")
+		if err := d.Queue.Delete(ctx, dl.ID); err != nil {
+			log.Printf("[WARN] failed to remove delivered event %d: %v", dl.ID, err)
+		}
+		return true
+	}
+	if ctx.Err() != nil {
+		return false // shutting down, the delivery stays queued for the next run
+	}
+
+	attempts := dl.Attempts + 1
+	if attempts >= d.MaxAttempts {
+		metrics.HookDeliveries.Inc("dropped")
+		log.Printf("[WARN] dropped %s event delivery %d to %s after %d attempts: %v", dl.EventType, dl.ID, dl.URL, attempts, err)
+		if err := d.Queue.Delete(ctx, dl.ID); err != nil {
+			log.Printf("[WARN] failed to remove dropped event %d: %v", dl.ID, err)
+		}
+		return false
+	}
+
+	metrics.HookDeliveries.Inc("failed")
+	next := time.Now().Add(d.backoff(attempts))
+	log.Printf("[DEBUG] %s event delivery %d to %s failed, attempt %d, retry at %s: %v",
+		dl.EventType, dl.ID, dl.URL, attempts, next.Format(time.RFC3339), err)
+	errMsg := err.Error()
+	if len(errMsg) > maxErrorLen {
+		errMsg = errMsg[:maxErrorLen]
+	}
+	if err := d.Queue.Retry(ctx, dl.ID, next, errMsg); err != nil {
+		log.Printf("[WARN] failed to reschedule event delivery %d: %v", dl.ID, err)
+	}
+	return false
+}
+
+// post sends the delivery payload to its url, any non-2xx response is an error
+func (d *Dispatcher) post(ctx context.Context, dl storage.EventDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewBufferString(dl.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(d.Secret, []byte(dl.Payload)))
+	req.Header.Set(EventHeader, dl.EventType)
+	req.Header.Set(DeliveryHeader, fmt.Sprintf("%d", dl.ID))
+
+	client := d.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // drain to reuse the connection
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns the delay before the given attempt, doubled on each attempt and capped by maxRetryDelay
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.RetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Sign returns the signature header value for the body, "sha256=" followed by hex encoded HMAC-SHA256.
+// Receivers should compute the same over the raw request body and compare in constant time.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,196 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/hooks/mocks"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestDispatcher_Publish(t *testing.T) {
+	t.Run("queued for each url", func(t *testing.T) {
+		q := &mocks.QueueMock{AddFunc: func(ctx context.Context, deliveries []storage.EventDelivery) error { return nil }}
+		d := NewDispatcher([]string{"https://a.example.com", "https://b.example.com"}, "secret", q)
+		err := d.Publish(context.Background(), Event{Type: EventSpamDetected, UserID: 1, Text: "spam",
+			Checks: []spamcheck.Response{{Name: "stopword", Spam: true, Details: "buy"}}})
+		require.NoError(t, err)
+
+		require.Len(t, q.AddCalls(), 1)
+		dls := q.AddCalls()[0].Deliveries
+		require.Len(t, dls, 2)
+		assert.Equal(t, "https://a.example.com", dls[0].URL)
+		assert.Equal(t, "https://b.example.com", dls[1].URL)
+		assert.Equal(t, EventSpamDetected, dls[0].EventType)
+		assert.Equal(t, dls[0].Payload, dls[1].Payload, "same event for all urls")
+
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(dls[0].Payload), &e))
+		assert.Len(t, e.ID, 32)
+		assert.WithinDuration(t, time.Now(), e.Time, time.Minute)
+		assert.Equal(t, int64(1), e.UserID)
+		assert.Equal(t, []spamcheck.Response{{Name: "stopword", Spam: true, Details: "buy"}}, e.Checks)
+		assert.Len(t, d.wake, 1, "worker signaled")
+	})
+
+	t.Run("no urls", func(t *testing.T) {
+		q := &mocks.QueueMock{}
+		d := NewDispatcher(nil, "secret", q)
+		require.NoError(t, d.Publish(context.Background(), Event{Type: EventBan}))
+		assert.Empty(t, q.AddCalls())
+	})
+
+	t.Run("queue error", func(t *testing.T) {
+		q := &mocks.QueueMock{AddFunc: func(ctx context.Context, deliveries []storage.EventDelivery) error {
+			return errors.New("db error")
+		}}
+		d := NewDispatcher([]string{"https://a.example.com"}, "secret", q)
+		err := d.Publish(context.Background(), Event{Type: EventBan})
+		require.EqualError(t, err, "failed to queue ban event: db error")
+	})
+}
+
+func TestDispatcher_deliverDue(t *testing.T) {
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	q := &mocks.QueueMock{
+		DueFunc: func(ctx context.Context, now time.Time, limit int) ([]storage.EventDelivery, error) {
+			return []storage.EventDelivery{
+				{ID: 1, URL: ts.URL + "/ok", EventType: EventBan, Payload: `{"type":"ban"}`},
+				{ID: 2, URL: ts.URL + "/fail", EventType: EventWarn, Payload: `{"type":"warn"}`, Attempts: 2},
+				{ID: 3, URL: ts.URL + "/fail", EventType: EventWarn, Payload: `{"type":"warn"}`, Attempts: 4},
+			}, nil
+		},
+		DeleteFunc: func(ctx context.Context, id int64) error { return nil },
+		RetryFunc:  func(ctx context.Context, id int64, next time.Time, lastErr string) error { return nil },
+	}
+	d := NewDispatcher([]string{ts.URL}, "secret", q)
+	d.MaxAttempts = 5
+	d.RetryDelay = time.Minute
+	d.deliverDue(context.Background())
+
+	require.Len(t, received, 3)
+	assert.Equal(t, Sign("secret", []byte(`{"type":"ban"}`)), received[0].Header.Get(SignatureHeader))
+	assert.Equal(t, EventBan, received[0].Header.Get(EventHeader))
+	assert.Equal(t, "1", received[0].Header.Get(DeliveryHeader))
+	assert.Equal(t, "application/json", received[0].Header.Get("Content-Type"))
+	assert.Equal(t, `{"type":"ban"}`, bodies[0])
+
+	require.Len(t, q.DueCalls(), 1, "partial batch, no more due")
+	assert.Equal(t, deliveryBatch, q.DueCalls()[0].Limit)
+
+	require.Len(t, q.DeleteCalls(), 2)
+	assert.Equal(t, int64(1), q.DeleteCalls()[0].ID, "delivered")
+	assert.Equal(t, int64(3), q.DeleteCalls()[1].ID, "dropped after max attempts")
+
+	require.Len(t, q.RetryCalls(), 1)
+	assert.Equal(t, int64(2), q.RetryCalls()[0].ID)
+	assert.Equal(t, "unexpected status 500", q.RetryCalls()[0].LastErr)
+	assert.WithinDuration(t, time.Now().Add(4*time.Minute), q.RetryCalls()[0].Next, 10*time.Second, "third attempt backoff")
+}
+
+func TestDispatcher_Run(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, string(body))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	var queue []storage.EventDelivery
+	var qmu sync.Mutex
+	q := &mocks.QueueMock{
+		AddFunc: func(ctx context.Context, deliveries []storage.EventDelivery) error {
+			qmu.Lock()
+			defer qmu.Unlock()
+			for _, dl := range deliveries {
+				dl.ID = int64(len(queue) + 1)
+				queue = append(queue, dl)
+			}
+			return nil
+		},
+		DueFunc: func(ctx context.Context, now time.Time, limit int) ([]storage.EventDelivery, error) {
+			qmu.Lock()
+			defer qmu.Unlock()
+			return append([]storage.EventDelivery(nil), queue...), nil
+		},
+		DeleteFunc: func(ctx context.Context, id int64) error {
+			qmu.Lock()
+			defer qmu.Unlock()
+			for i, dl := range queue {
+				if dl.ID == id {
+					queue = append(queue[:i], queue[i+1:]...)
+					break
+				}
+			}
+			return nil
+		},
+	}
+
+	d := NewDispatcher([]string{ts.URL}, "secret", q)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, d.Publish(ctx, Event{Type: EventUnban, UserID: 42}))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 1
+	}, time.Second, 10*time.Millisecond, "delivered without waiting for the poll interval")
+
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &e))
+	assert.Equal(t, EventUnban, e.Type)
+	assert.Equal(t, int64(42), e.UserID)
+
+	cancel()
+	<-done
+}
+
+func TestDispatcher_backoff(t *testing.T) {
+	d := &Dispatcher{RetryDelay: 30 * time.Second}
+	assert.Equal(t, 30*time.Second, d.backoff(1))
+	assert.Equal(t, time.Minute, d.backoff(2))
+	assert.Equal(t, 2*time.Minute, d.backoff(3))
+	assert.Equal(t, maxRetryDelay, d.backoff(100), "capped")
+	assert.Equal(t, defaultRetryDelay, (&Dispatcher{}).backoff(1), "default delay")
+}
+
+func TestSign(t *testing.T) {
+	// reference value from: echo -n '{"type":"ban"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=038e33b85735cbfd80e2f082bd1c49de23d1ffc405080946c5a5ca858788d3f5", Sign("secret", []byte(`{"type":"ban"}`)))
+	assert.NotEqual(t, Sign("secret", []byte("body")), Sign("other", []byte("body")))
+}
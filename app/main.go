@@ -2,7 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -32,6 +38,8 @@ import (
 	"github.com/umputun/tg-spam/app/bot"
 	"github.com/umputun/tg-spam/app/config"
 	"github.com/umputun/tg-spam/app/events"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/metrics"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/app/storage/engine"
 	"github.com/umputun/tg-spam/app/webapi"
@@ -44,12 +52,18 @@ type options struct {
 	DataBaseURL        string `long:"db" env:"DB" default:"tg-spam.db" description:"database URL, if empty uses sqlite"`
 	ConfigDB           bool   `long:"confdb" env:"CONFDB" description:"load configuration from database"`
 	ConfigDBEncryptKey string `long:"confdb-encrypt-key" env:"CONFDB_ENCRYPT_KEY" description:"encryption key for sensitive config values in database"`
+	ConfigFile         string `long:"config" env:"CONFIG_FILE" description:"load configuration from yaml file"`
+	ConfigWatch        bool   `long:"config-watch" env:"CONFIG_WATCH" description:"apply changes of configuration file without restart"`
 
 	Telegram struct {
 		Token        string        `long:"token" env:"TOKEN" description:"telegram bot token"`
 		Group        string        `long:"group" env:"GROUP" description:"group name/id"`
 		Timeout      time.Duration `long:"timeout" env:"TIMEOUT" default:"30s" description:"http client timeout for telegram" `
 		IdleDuration time.Duration `long:"idle" env:"IDLE" default:"30s" description:"idle duration"`
+		ExtraGroups  []string      `long:"extra-group" env:"EXTRA_GROUP" env-delim:"|" description:"additional group, group[;admin=group][;super=user1,user2][;option=value]"`
+
+		WebhookURL    string `long:"webhook-url" env:"WEBHOOK_URL" description:"public https url of the webhook, enables webhook mode instead of polling"`
+		WebhookSecret string `long:"webhook-secret" env:"WEBHOOK_SECRET" description:"webhook secret token, random if not set"`
 	} `group:"telegram" namespace:"telegram" env-namespace:"TELEGRAM"`
 
 	AdminGroup              string `long:"admin.group" env:"ADMIN_GROUP" description:"admin group name, or channel id"`
@@ -78,9 +92,10 @@ type options struct {
 	} `group:"delete" namespace:"delete" env-namespace:"DELETE"`
 
 	CAS struct {
-		API       string        `long:"api" env:"API" default:"https://api.cas.chat" description:"CAS API"`
-		Timeout   time.Duration `long:"timeout" env:"TIMEOUT" default:"5s" description:"CAS timeout"`
-		UserAgent string        `long:"user-agent" env:"USER_AGENT" description:"User-Agent header for CAS API requests"`
+		API          string        `long:"api" env:"API" default:"https://api.cas.chat" description:"CAS API"`
+		Timeout      time.Duration `long:"timeout" env:"TIMEOUT" default:"5s" description:"CAS timeout"`
+		UserAgent    string        `long:"user-agent" env:"USER_AGENT" description:"User-Agent header for CAS API requests"`
+		SyncInterval time.Duration `long:"sync-interval" env:"SYNC_INTERVAL" default:"0s" description:"CAS export sync interval, 0 to disable local mirror"`
 	} `group:"cas" namespace:"cas" env-namespace:"CAS"`
 
 	Meta struct {
@@ -100,6 +115,11 @@ type options struct {
 		ExternalReply   bool   `long:"external-reply" env:"EXTERNAL_REPLY" description:"enable external reply check"`
 	} `group:"meta" namespace:"meta" env-namespace:"META"`
 
+	Domains struct {
+		ExpandShort bool     `long:"expand-short" env:"EXPAND_SHORT" description:"resolve url shortener links before matching domains"`
+		Shorteners  []string `long:"shortener" env:"SHORTENER" env-delim:"," description:"url shortener domain to resolve, repeatable, default list if not set"`
+	} `group:"domains" namespace:"domains" env-namespace:"DOMAINS"`
+
 	OpenAI struct {
 		Token              string   `long:"token" env:"TOKEN" description:"openai token, disabled if not set"`
 		APIBase            string   `long:"apibase" env:"API_BASE" description:"custom openai API base, default is https://api.openai.com/v1"`
@@ -132,6 +152,10 @@ type options struct {
 	LLM struct {
 		Consensus      string        `long:"consensus" env:"CONSENSUS" choice:"any" choice:"all" default:"any" description:"how eligible LLMs flip the base decision"`
 		RequestTimeout time.Duration `long:"request-timeout" env:"REQUEST_TIMEOUT" default:"30s" description:"timeout for individual LLM requests"`
+		Providers      []string      `long:"provider" env:"PROVIDER" env-delim:"|" description:"additional LLM checker, name;type=openai|gemini|anthropic|ollama[;option=value]"`
+		CacheTTL       time.Duration `long:"cache-ttl" env:"CACHE_TTL" default:"0s" description:"reuse LLM verdicts for identical messages for this long, 0 disables"`
+		CacheSize      int           `long:"cache-size" env:"CACHE_SIZE" default:"1000" description:"max number of cached LLM verdicts"`
+		CachePersist   bool          `long:"cache-persist" env:"CACHE_PERSIST" description:"keep cached LLM verdicts in the database"`
 	} `group:"llm" namespace:"llm" env-namespace:"LLM"`
 
 	LuaPlugins struct {
@@ -172,6 +196,69 @@ type options struct {
 		Window    time.Duration `long:"window" env:"WINDOW" default:"720h" description:"sliding window for counting warns"`
 	} `group:"warn" namespace:"warn" env-namespace:"WARN"`
 
+	Punish struct {
+		Enabled   bool          `long:"enabled" env:"ENABLED" description:"escalate punishment by offense history instead of permanent ban"`
+		Mute      time.Duration `long:"mute" env:"MUTE" default:"10m" description:"restriction on the first offense, 0 skips the step"`
+		Restrict  time.Duration `long:"restrict" env:"RESTRICT" default:"24h" description:"restriction on the second offense, 0 skips the step"`
+		Window    time.Duration `long:"window" env:"WINDOW" default:"720h" description:"offenses older than this are not counted"`
+		Permanent []string      `long:"permanent" env:"PERMANENT" env-delim:"," description:"check always punished by permanent ban, e.g. cas, repeatable"`
+	} `group:"punish" namespace:"punish" env-namespace:"PUNISH"`
+
+	Captcha struct {
+		Enabled      bool          `long:"enabled" env:"ENABLED" description:"enable join challenge (captcha) for new members"`
+		Type         string        `long:"type" env:"TYPE" default:"button" choice:"button" choice:"math" choice:"emoji" description:"challenge type"`
+		Timeout      time.Duration `long:"timeout" env:"TIMEOUT" default:"5m" description:"time to solve the challenge"`
+		Action       string        `long:"action" env:"ACTION" default:"ban" choice:"ban" choice:"kick" description:"action on failed or expired challenge"`
+		ApproveCount int           `long:"approve-count" env:"APPROVE_COUNT" default:"0" description:"first messages credited to users who passed the challenge (0=disabled)"`
+	} `group:"captcha" namespace:"captcha" env-namespace:"CAPTCHA"`
+
+	OCR struct {
+		Type      string        `long:"type" env:"TYPE" default:"none" choice:"none" choice:"tesseract" choice:"http" description:"image text extraction engine"`
+		Tesseract string        `long:"tesseract" env:"TESSERACT" default:"tesseract" description:"tesseract command"`
+		Lang      string        `long:"lang" env:"LANG" default:"eng" description:"tesseract languages, e.g. eng+rus"`
+		URL       string        `long:"url" env:"URL" description:"http OCR service url"`
+		Timeout   time.Duration `long:"timeout" env:"TIMEOUT" default:"30s" description:"image download and text extraction timeout"`
+	} `group:"ocr" namespace:"ocr" env-namespace:"OCR"`
+
+	ImageHash struct {
+		Enabled  bool `long:"enabled" env:"ENABLED" description:"enable spam image hash check"`
+		Distance int  `long:"distance" env:"DISTANCE" default:"5" description:"max hamming distance to a spam image hash"`
+	} `group:"image-hash" namespace:"image-hash" env-namespace:"IMAGE_HASH"`
+
+	Scoring struct {
+		Enabled             bool     `long:"enabled" env:"ENABLED" description:"decide spam by weighted score of all checks"`
+		Weights             []string `long:"weight" env:"WEIGHT" env-delim:"," description:"weight of a check, check-name:weight"`
+		DefaultWeight       float64  `long:"default-weight" env:"DEFAULT_WEIGHT" default:"1" description:"weight of checks without explicit weight"`
+		Threshold           float64  `long:"threshold" env:"THRESHOLD" default:"1" description:"total score to mark message as spam"`
+		SuspiciousThreshold float64  `long:"suspicious-threshold" env:"SUSPICIOUS_THRESHOLD" default:"0" description:"total score to report message to admin for review, 0 disables"`
+	} `group:"scoring" namespace:"scoring" env-namespace:"SCORING"`
+
+	Review struct {
+		Enabled         bool    `long:"enabled" env:"ENABLED" description:"quarantine suspicious messages for admin review"`
+		MinProbability  float64 `long:"min-probability" env:"MIN_PROBABILITY" default:"0" description:"min classifier spam probability percent to mark message as suspicious, 0 disables"`
+		LLMDisagreement bool    `long:"llm-disagreement" env:"LLM_DISAGREEMENT" description:"mark message as suspicious if LLM checks disagree"`
+	} `group:"review" namespace:"review" env-namespace:"REVIEW"`
+
+	Reputation struct {
+		Enabled      bool          `long:"enabled" env:"ENABLED" description:"track per-user reputation, weight reports and hold approval by it"`
+		ApprovalHold time.Duration `long:"approval-hold" env:"APPROVAL_HOLD" default:"24h" description:"don't approve users warned within this period, 0 disables"`
+	} `group:"reputation" namespace:"reputation" env-namespace:"REPUTATION"`
+
+	Federation struct {
+		Enabled      bool          `long:"enabled" env:"ENABLED" description:"publish banned users feed and pull feeds of peers"`
+		KeyFile      string        `long:"key-file" env:"KEY_FILE" description:"ed25519 private key file, generated if missing, default in dynamic data dir"`
+		FeedWindow   time.Duration `long:"feed-window" env:"FEED_WINDOW" default:"720h" description:"publish users banned within this period"`
+		PullInterval time.Duration `long:"pull-interval" env:"PULL_INTERVAL" default:"15m" description:"interval to pull feeds of peers"`
+		Peers        []string      `long:"peer" env:"PEER" env-delim:"|" description:"federation peer, name;url=feed-url;key=public-key[;trust=ban|review]"`
+	} `group:"federation" namespace:"federation" env-namespace:"FEDERATION"`
+
+	Hooks struct {
+		URLs        []string      `long:"url" env:"URL" env-delim:"," description:"webhook url to post moderation events to, repeatable"`
+		Secret      string        `long:"secret" env:"SECRET" description:"secret to sign events with HMAC-SHA256, required with url"`
+		MaxAttempts int           `long:"max-attempts" env:"MAX_ATTEMPTS" default:"10" description:"delivery attempts before the event is dropped"`
+		RetryDelay  time.Duration `long:"retry-delay" env:"RETRY_DELAY" default:"30s" description:"delay before the first retry, doubled on each retry"`
+	} `group:"hooks" namespace:"hooks" env-namespace:"HOOKS"`
+
 	Files struct {
 		SamplesDataPath string        `long:"samples" env:"SAMPLES" description:"samples data path, defaults to dynamic data path"`
 		DynamicDataPath string        `long:"dynamic" env:"DYNAMIC" default:"data" description:"dynamic data path"`
@@ -184,6 +271,8 @@ type options struct {
 	MaxEmoji            int     `long:"max-emoji" env:"MAX_EMOJI" default:"2" description:"max emoji count in message, -1 to disable check"`
 	MinSpamProbability  float64 `long:"min-probability" env:"MIN_PROBABILITY" default:"50" description:"min spam probability percent to ban"`
 	MultiLangWords      int     `long:"multi-lang" env:"MULTI_LANG" default:"0" description:"number of words in different languages to consider as spam"`
+	ObfuscatedWords     int     `long:"obfuscated-words" env:"OBFUSCATED_WORDS" default:"0" description:"number of words with lookalike letters of another script to consider as spam"`
+	NormalizeText       bool    `long:"normalize" env:"NORMALIZE" description:"normalize lookalike letters, leetspeak and invisible characters before content checks"`
 	ProhibitedLangs     string  `long:"prohibited-langs" env:"PROHIBITED_LANGS" default:"" description:"comma-separated prohibited languages or scripts, e.g. chinese,cyrillic (empty disables)"`
 	ProhibitedLangsMin  int     `long:"prohibited-langs-min" env:"PROHIBITED_LANGS_MIN" default:"3" description:"min prohibited-script letters in a message to consider as spam"`
 
@@ -229,6 +318,13 @@ const (
 	dynamicSpamFile   = "spam-dynamic.txt"
 	dynamicHamFile    = "ham-dynamic.txt"
 	dataFile          = "tg-spam.db"
+	federationKeyFile = "federation.key"
+)
+
+const (
+	casExportTimeout = 5 * time.Minute // timeout for CAS export download, the export is large
+	// local CAS mirror not synced for this many sync intervals is stale, users not found in it are checked with CAS API
+	casMirrorStaleIntervals = 3
 )
 
 var revision = "local"
@@ -248,6 +344,15 @@ func main() {
 		log.Printf("[ERROR] failed to add save-config command: %v", err)
 		os.Exit(1)
 	}
+
+	// add evaluate command
+	var evalOpts evaluateOptions
+	if _, err := p.AddCommand("evaluate", "Evaluate spam detection accuracy on labeled messages",
+		"Checks labeled messages and stored samples with the current settings and reports precision, recall and F1",
+		&evalOpts); err != nil {
+		log.Printf("[ERROR] failed to add evaluate command: %v", err)
+		os.Exit(1)
+	}
 	if _, err := p.Parse(); err != nil {
 		if !errors.Is(err.(*flags.Error).Type, flags.ErrHelp) {
 			log.Printf("[ERROR] cli error: %v", err)
@@ -260,9 +365,15 @@ func main() {
 	var appSettings *config.Settings
 	// reloadNormalize captures the same defaults-fill + operational CLI override
 	// policy used at startup so POST /config/reload can apply it to a freshly
-	// loaded DB blob. nil in non-confdb mode (no reload endpoint exists there).
+	// loaded DB blob, or the config file watcher to the changed file. nil in
+	// CLI mode (nothing is reloaded there).
 	var reloadNormalize func(*config.Settings)
 
+	if opts.ConfigDB && opts.ConfigFile != "" {
+		log.Printf("[ERROR] --confdb and --config can't be used together")
+		os.Exit(1)
+	}
+
 	if opts.ConfigDB {
 		// database configuration mode - load from database first
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -314,11 +425,49 @@ func main() {
 			applyOperationalCLIOverrides(s, opts, defaults)
 			normalizeFilePaths(s)
 		}
+	} else if opts.ConfigFile != "" {
+		// config file mode - yaml file is the source, CLI overrides it with the same precedence as in confdb mode
+		defaults, err := defaultSettingsTemplate()
+		if err != nil {
+			log.Printf("[ERROR] failed to build defaults template: %v", err)
+			os.Exit(1)
+		}
+
+		appSettings = config.New()
+		appSettings.Transient.ConfigFile = opts.ConfigFile
+		appSettings.Transient.ConfigWatch = opts.ConfigWatch
+		appSettings.Transient.DataBaseURL = opts.DataBaseURL
+		appSettings.Transient.Dbg = opts.Dbg
+		appSettings.Transient.TGDbg = opts.TGDbg
+		appSettings.Transient.StorageTimeout = opts.StorageTimeout
+		appSettings.InstanceID = opts.InstanceID
+
+		if err := loadConfigFromFile(opts.ConfigFile, appSettings, defaults); err != nil {
+			log.Printf("[ERROR] failed to load configuration: %v", err)
+			os.Exit(1)
+		}
+		applyCLIOverrides(appSettings, opts, defaults)
+
+		// the watched file is reloaded with the same defaults and CLI overrides as on startup, credentials included
+		reloadNormalize = func(s *config.Settings) {
+			s.ApplyDefaults(defaults)
+			applyCLIOverrides(s, opts, defaults)
+			if err := applyCLIListOverrides(s, opts); err != nil {
+				log.Printf("[WARN] %v", err) // can't happen, lists are checked on startup
+			}
+			normalizeFilePaths(s)
+		}
 	} else {
 		// traditional mode - CLI is source of truth
 		appSettings = optToSettings(opts)
 	}
 
+	// lists set on CLI, e.g. additional groups, replace the loaded ones in confdb and config file modes
+	if err := applyCLIListOverrides(appSettings, opts); err != nil {
+		log.Printf("[ERROR] %v", err)
+		os.Exit(1)
+	}
+
 	// setup logger with masked secrets BEFORE any subcommand dispatch so any
 	// error wrapping inside saveConfigToDB or later stages benefits from the
 	// secret masker. Tokens come directly from the resolved domain settings.
@@ -327,12 +476,23 @@ func main() {
 	if appSettings.Telegram.Token != "" {
 		masked = append(masked, appSettings.Telegram.Token)
 	}
+	if appSettings.Telegram.WebhookSecret != "" {
+		masked = append(masked, appSettings.Telegram.WebhookSecret)
+	}
+	if appSettings.Hooks.Secret != "" {
+		masked = append(masked, appSettings.Hooks.Secret)
+	}
 	if appSettings.OpenAI.Token != "" {
 		masked = append(masked, appSettings.OpenAI.Token)
 	}
 	if appSettings.Gemini.Token != "" {
 		masked = append(masked, appSettings.Gemini.Token)
 	}
+	for _, p := range appSettings.LLM.Providers {
+		if p.Token != "" {
+			masked = append(masked, p.Token)
+		}
+	}
 
 	// add temporary web password if not "auto"
 	if appSettings.Transient.WebAuthPasswd != "auto" && appSettings.Transient.WebAuthPasswd != "" {
@@ -357,6 +517,11 @@ func main() {
 		os.Exit(runSaveConfig(appSettings))
 	}
 
+	// handle evaluate command, reports accuracy of the detector made with the same settings and exits
+	if p.Active != nil && p.Active.Name == "evaluate" {
+		os.Exit(runEvaluate(appSettings, evalOpts))
+	}
+
 	// dump a copy with the database credentials masked; the live settings keep the original URL
 	// because that is what gets handed to the driver. masking through setupLog would not work here,
 	// as the secret masker matches literal substrings and the URL may hold a percent-encoded password
@@ -485,17 +650,122 @@ func execute(ctx context.Context, settings *config.Settings, reloadNormalize fun
 		}
 	}
 
+	// make offenses storage if punishment ladder is enabled
+	var offensesStore *storage.Offenses
+	if settings.Punish.Enabled {
+		offensesStore, err = storage.NewOffenses(ctx, dataDB)
+		if err != nil {
+			return fmt.Errorf("can't make offenses store, %w", err)
+		}
+		log.Printf("[INFO] punishment ladder enabled, mute: %v, restrict: %v, window: %v, permanent for: %v",
+			settings.Punish.Mute, settings.Punish.Restrict, settings.Punish.Window, settings.Punish.Permanent)
+	}
+
+	// make audit log storage, unbans, reports and other admin actions are always recorded
+	auditStore, err := storage.NewAudit(ctx, dataDB)
+	if err != nil {
+		return fmt.Errorf("can't make audit store, %w", err)
+	}
+
+	// make join challenges storage if captcha is enabled
+	var challengesStore *storage.Challenges
+	if settings.Captcha.Enabled {
+		challengesStore, err = storage.NewChallenges(ctx, dataDB)
+		if err != nil {
+			return fmt.Errorf("can't make challenges store, %w", err)
+		}
+	}
+
+	// make spam image hashes storage and add the check if image hash check is enabled
+	var imageHashesStore *storage.ImageHashes
+	if settings.ImageHash.Enabled {
+		imageHashesStore, err = storage.NewImageHashes(ctx, dataDB)
+		if err != nil {
+			return fmt.Errorf("can't make image hashes store, %w", err)
+		}
+		log.Printf("[INFO] image hash check enabled, max distance: %d", settings.ImageHash.Distance)
+		detector.WithMetaChecks(tgspam.ImageHashCheck(imageHashesStore, settings.ImageHash.Distance))
+	}
+
+	// make review queue storage if quarantine of suspicious messages is enabled
+	var reviewsStore *storage.Reviews
+	if settings.Review.Enabled {
+		reviewsStore, err = storage.NewReviews(ctx, dataDB)
+		if err != nil {
+			return fmt.Errorf("can't make reviews store, %w", err)
+		}
+		log.Printf("[INFO] review queue enabled, suspicious messages quarantined for admin review")
+	}
+
+	// make reputation storage if per-user reputation is enabled, approval of users with bad history is held back
+	var reputationStore *storage.Reputation
+	if settings.Reputation.Enabled {
+		reputationStore, err = storage.NewReputation(ctx, settings.Reputation.ApprovalHold, dataDB)
+		if err != nil {
+			return fmt.Errorf("can't make reputation store, %w", err)
+		}
+		detector.WithApprovalGuard(reputationStore)
+		log.Printf("[INFO] user reputation enabled, approval hold after warning: %v", settings.Reputation.ApprovalHold)
+	}
+
+	// make local CAS mirror if CAS export sync is enabled, shared by detectors of all groups
+	casBansStore, err := makeCASMirror(ctx, settings, dataDB)
+	if err != nil {
+		return fmt.Errorf("can't make cas mirror, %w", err)
+	}
+	if casBansStore != nil {
+		detector.WithCASMirror(casBansStore, casMirrorStaleIntervals*settings.CAS.SyncInterval)
+	}
+
+	// make federated bans storage and add the check if federation is enabled, peer feeds are pulled in background
+	federatedBans, err := makeFederation(ctx, settings, dataDB)
+	if err != nil {
+		return fmt.Errorf("can't make federation, %w", err)
+	}
+	if federatedBans != nil {
+		detector.WithMetaChecks(tgspam.FederationCheck(federatedBans, federationTrust(settings)))
+	}
+
+	// make LLM verdict cache if enabled, shared by detectors of all groups
+	llmCache, err := makeLLMCache(ctx, settings, dataDB)
+	if err != nil {
+		return fmt.Errorf("can't make llm cache, %w", err)
+	}
+	if llmCache != nil {
+		detector.WithLLMCache(llmCache)
+	}
+
+	// settings updated in web UI are applied to the running detectors and listener, see liveConfig
+	live := &liveConfig{detectors: []liveDetector{{detector: detector, bot: spamBot}}, imageHashes: imageHashesStore,
+		federatedBans: federatedBans}
+
 	// activate web server if enabled, server-only mode (no telegram token)
 	if settings.Server.Enabled && (settings.Telegram.Token == "" || settings.Telegram.Group == "") {
 		// server starts in background goroutine without DM users provider
-		if srvErr := activateServer(ctx, settings, spamBot, locator, dataDB, nil, "", reloadNormalize); srvErr != nil {
+		srv, srvErr := activateServer(ctx, settings, spamBot, locator, dataDB, nil, nil, nil, "", reloadNormalize, live.apply)
+		if srvErr != nil {
 			return fmt.Errorf("can't activate web server, %w", srvErr)
 		}
+		watchConfigFile(ctx, settings, reloadNormalize, srv, live)
 		log.Printf("[WARN] no telegram token and group set, web server only mode")
 		<-ctx.Done()
 		return nil
 	}
 
+	// make group configs for additional groups, groups with detector overrides get their own bots
+	groups, err := makeGroups(ctx, settings, dataDB, approvedUsersStore, locator, imageHashesStore, llmCache, reputationStore,
+		casBansStore, federatedBans)
+	if err != nil {
+		return fmt.Errorf("can't make additional groups, %w", err)
+	}
+	for _, gc := range groups {
+		if sf, ok := gc.Bot.(*bot.SpamFilter); ok {
+			if d, ok := sf.Detector.(*tgspam.Detector); ok {
+				live.detectors = append(live.detectors, liveDetector{group: gc.Group, detector: d, bot: sf})
+			}
+		}
+	}
+
 	// make telegram bot
 	tbAPI, err := tbapi.NewBotAPI(settings.Telegram.Token)
 	if err != nil {
@@ -518,9 +788,10 @@ func execute(ctx context.Context, settings *config.Settings, reloadNormalize fun
 
 	// make telegram listener
 	tgListener := events.TelegramListener{
-		TbAPI:               tbAPI,
+		TbAPI:               &metrics.Telegram{TelegramAPI: tbAPI}, // counts telegram requests and errors
 		BotUsername:         tbAPI.Self.UserName,
 		Group:               settings.Telegram.Group,
+		Groups:              groups,
 		IdleDuration:        settings.Telegram.IdleDuration,
 		SuperUsers:          settings.Admin.SuperUsers,
 		Bot:                 spamBot,
@@ -551,6 +822,61 @@ func execute(ctx context.Context, settings *config.Settings, reloadNormalize fun
 		WarnThreshold:           settings.Warn.Threshold,
 		WarnWindow:              settings.Warn.Window,
 		Warnings:                warningsStore,
+		Captcha: events.CaptchaConfig{
+			Storage:      challengesStore,
+			Enabled:      settings.Captcha.Enabled,
+			Type:         settings.Captcha.Type,
+			Timeout:      settings.Captcha.Timeout,
+			Action:       settings.Captcha.Action,
+			ApproveCount: settings.Captcha.ApproveCount,
+		},
+		ImageText: events.ImageTextConfig{
+			Extractor: makeImageTextExtractor(settings),
+			Timeout:   settings.OCR.Timeout,
+		},
+		Punishment: events.PunishConfig{
+			Enabled:         settings.Punish.Enabled,
+			Mute:            settings.Punish.Mute,
+			Restrict:        settings.Punish.Restrict,
+			Window:          settings.Punish.Window,
+			PermanentChecks: settings.Punish.Permanent,
+		},
+		AuditLog: auditStore,
+	}
+	live.listener = &tgListener
+	if imageHashesStore != nil {
+		tgListener.ImageHashes = imageHashesStore // avoid nil-interface-wrapping-nil-pointer trap
+	}
+	if reviewsStore != nil {
+		tgListener.Reviews = reviewsStore
+	}
+	if reputationStore != nil {
+		tgListener.Reputation = reputationStore
+	}
+	if offensesStore != nil {
+		tgListener.Punishment.Offenses = offensesStore
+	}
+
+	// make event webhooks dispatcher if webhook urls are set, queued events are delivered in background
+	eventsDispatcher, err := makeHooks(ctx, settings, dataDB)
+	if err != nil {
+		return fmt.Errorf("can't make event webhooks, %w", err)
+	}
+	var eventsPublisher webapi.EventPublisher
+	if eventsDispatcher != nil {
+		tgListener.Events = eventsDispatcher
+		eventsPublisher = eventsDispatcher
+	}
+
+	// in webhook mode telegram posts updates to the web server instead of the listener polling for them
+	var webhook http.Handler
+	if settings.Telegram.WebhookURL != "" {
+		secret, err := makeWebhookSecret(settings.Telegram.WebhookSecret)
+		if err != nil {
+			return fmt.Errorf("can't make webhook secret, %w", err)
+		}
+		tgListener.Webhook = events.WebhookConfig{URL: settings.Telegram.WebhookURL, Secret: secret}
+		webhook = http.HandlerFunc(tgListener.HandleWebhook)
 	}
 
 	if settings.Delete.JoinMessages {
@@ -560,20 +886,31 @@ func execute(ctx context.Context, settings *config.Settings, reloadNormalize fun
 		log.Print("[INFO] delete leave messages enabled")
 	}
 
-	log.Printf("[DEBUG] telegram listener config: {bot: %s, group: %s, idle: %v, super: %v, admin: %s, "+
+	log.Printf("[DEBUG] telegram listener config: {bot: %s, group: %s, extra groups: %d, idle: %v, super: %v, admin: %s, "+
 		"testing: %v, no-reply: %v, suppress: %v, dry: %v, training: %v}",
-		tgListener.BotUsername, tgListener.Group, tgListener.IdleDuration, tgListener.SuperUsers,
+		tgListener.BotUsername, tgListener.Group, len(tgListener.Groups), tgListener.IdleDuration, tgListener.SuperUsers,
 		tgListener.AdminGroup, tgListener.TestingIDs, tgListener.NoSpamReply, tgListener.SuppressJoinMessage,
 		tgListener.Dry, tgListener.TrainingMode)
 
 	// activate web server if enabled, with DM users provider from the telegram listener
+	var srv *webapi.Server
 	if settings.Server.Enabled {
-		if srvErr := activateServer(ctx, settings, spamBot, locator, dataDB, &tgListener,
-			tgListener.BotUsername, reloadNormalize); srvErr != nil {
+		var srvErr error
+		srv, srvErr = activateServer(ctx, settings, spamBot, locator, dataDB, &tgListener, webhook, eventsPublisher,
+			tgListener.BotUsername, reloadNormalize, live.apply)
+		if srvErr != nil {
 			return fmt.Errorf("can't activate web server, %w", srvErr)
 		}
 	}
 
+	// changes of the config file replace settings of the web server, if it's running, and are applied live
+	watchConfigFile(ctx, settings, reloadNormalize, srv, live)
+
+	// register the webhook, or remove the one left by a previous run in webhook mode as it blocks polling
+	if err := events.SetupUpdatesMode(tgListener.TbAPI, tgListener.Webhook); err != nil {
+		return fmt.Errorf("can't setup telegram updates mode, %w", err)
+	}
+
 	// run telegram listener and event processor loop
 	if err := tgListener.Do(ctx); err != nil { //nolint:staticcheck // do() runs infinite loop, always returns error on exit
 		return fmt.Errorf("telegram listener failed, %w", err)
@@ -659,8 +996,9 @@ func checkVolumeMount(settings *config.Settings) (ok bool) {
 }
 
 func activateServer(ctx context.Context, settings *config.Settings, sf *bot.SpamFilter, loc *storage.Locator,
-	db *engine.SQL, dmUsersProvider webapi.DMUsersProvider, botUsername string,
-	reloadNormalize func(*config.Settings)) (err error) {
+	db *engine.SQL, dmUsersProvider webapi.DMUsersProvider, webhook http.Handler, eventsPublisher webapi.EventPublisher,
+	botUsername string, reloadNormalize func(*config.Settings),
+	applySettings func(prev, settings *config.Settings) []string) (srv *webapi.Server, err error) {
 	// safety net: when --confdb leaves the web UI without any auth material, fall
 	// back to generating a random password (matches legacy behavior where CLI
 	// default --server.auth=auto would trigger random-password generation)
@@ -678,7 +1016,7 @@ func activateServer(ctx context.Context, settings *config.Settings, sf *bot.Spam
 		// generateAuthHash handles the "auto" password case internally
 		authHash, err = generateAuthHash(authPasswd)
 		if err != nil {
-			return fmt.Errorf("can't handle authentication setup: %w", err)
+			return nil, fmt.Errorf("can't handle authentication setup: %w", err)
 		}
 		// store the hash directly in the Server settings domain
 		settings.Server.AuthHash = authHash
@@ -688,7 +1026,7 @@ func activateServer(ctx context.Context, settings *config.Settings, sf *bot.Spam
 	// make store and load approved users
 	detectedSpamStore, dsErr := storage.NewDetectedSpam(ctx, db)
 	if dsErr != nil {
-		return fmt.Errorf("can't make detected spam store, %w", dsErr)
+		return nil, fmt.Errorf("can't make detected spam store, %w", dsErr)
 	}
 
 	// create settings store for database access if config DB mode is enabled
@@ -698,13 +1036,13 @@ func activateServer(ctx context.Context, settings *config.Settings, sf *bot.Spam
 		if settings.Transient.ConfigDBEncryptKey != "" {
 			crypter, cryptErr := config.NewCrypter(settings.Transient.ConfigDBEncryptKey, settings.InstanceID)
 			if cryptErr != nil {
-				return fmt.Errorf("invalid encryption key for settings store: %w", cryptErr)
+				return nil, fmt.Errorf("invalid encryption key for settings store: %w", cryptErr)
 			}
 			storeOpts = append(storeOpts, config.WithCrypter(crypter))
 		}
 		store, err := config.NewStore(ctx, db, storeOpts...)
 		if err != nil {
-			return fmt.Errorf("failed to create settings store: %w", err)
+			return nil, fmt.Errorf("failed to create settings store: %w", err)
 		}
 		settingsStore = store
 	}
@@ -712,7 +1050,57 @@ func activateServer(ctx context.Context, settings *config.Settings, sf *bot.Spam
 	// make dictionary store for webapi
 	dictionaryStore, dictErr := storage.NewDictionary(ctx, db)
 	if dictErr != nil {
-		return fmt.Errorf("can't make dictionary store, %w", dictErr)
+		return nil, fmt.Errorf("can't make dictionary store, %w", dictErr)
+	}
+
+	// make spam image hashes store for webapi, hashes can be managed even if the check is disabled
+	imageHashesStore, ihErr := storage.NewImageHashes(ctx, db)
+	if ihErr != nil {
+		return nil, fmt.Errorf("can't make image hashes store, %w", ihErr)
+	}
+
+	// make review queue store for webapi, the page shows earlier reviews even if quarantine is disabled now
+	reviewsStore, rvErr := storage.NewReviews(ctx, db)
+	if rvErr != nil {
+		return nil, fmt.Errorf("can't make reviews store, %w", rvErr)
+	}
+
+	// make reputation store for webapi, user profiles show the history collected while reputation was enabled
+	reputationStore, repErr := storage.NewReputation(ctx, settings.Reputation.ApprovalHold, db)
+	if repErr != nil {
+		return nil, fmt.Errorf("can't make reputation store, %w", repErr)
+	}
+
+	// make audit log store for webapi, changes made in web UI are recorded next to telegram admin actions
+	auditStore, auErr := storage.NewAudit(ctx, db)
+	if auErr != nil {
+		return nil, fmt.Errorf("can't make audit store, %w", auErr)
+	}
+
+	// make api tokens store for webapi, tokens are accepted along with basic auth
+	apiTokensStore, atErr := storage.NewAPITokens(ctx, db)
+	if atErr != nil {
+		return nil, fmt.Errorf("can't make api tokens store, %w", atErr)
+	}
+
+	// make web users store, users log in with their own password and role, the basic auth user stays an admin
+	webUsersStore, wuErr := storage.NewWebUsers(ctx, db)
+	if wuErr != nil {
+		return nil, fmt.Errorf("can't make web users store, %w", wuErr)
+	}
+
+	// load or generate the key signing own federation feed, the feed is published only if federation is enabled
+	var federationKey ed25519.PrivateKey
+	if settings.Federation.Enabled {
+		keyFile := settings.Federation.KeyFile
+		if keyFile == "" {
+			keyFile = filepath.Join(settings.Files.DynamicDataPath, federationKeyFile)
+		}
+		if federationKey, err = loadFederationKey(keyFile); err != nil {
+			return nil, fmt.Errorf("can't load federation key, %w", err)
+		}
+		log.Printf("[INFO] federation feed published on /federation/feed, public key: %s",
+			base64.StdEncoding.EncodeToString(federationKey.Public().(ed25519.PublicKey)))
 	}
 
 	cfg := webapi.Config{
@@ -722,6 +1110,17 @@ func activateServer(ctx context.Context, settings *config.Settings, sf *bot.Spam
 		Locator:         loc,
 		DetectedSpam:    detectedSpamStore,
 		Dictionary:      dictionaryStore,
+		ImageHashes:     imageHashesStore,
+		Reviews:         reviewsStore,
+		Reputation:      reputationStore,
+		AuditLog:        auditStore,
+		APITokens:       apiTokensStore,
+		WebUsers:        webUsersStore,
+		HashPassword:    generateAuthHash, // min password length of web users rules out "auto"
+		FederationKey:   federationKey,
+		FederationFeed:  settings.Federation.FeedWindow,
+		TelegramWebhook: webhook,
+		Events:          eventsPublisher,
 		StorageEngine:   db, // add database engine for backup functionality
 		DMUsersProvider: dmUsersProvider,
 		AuthUser:        settings.Server.AuthUser, // optional basic auth user (defaults to "tg-spam" when empty)
@@ -733,29 +1132,40 @@ func activateServer(ctx context.Context, settings *config.Settings, sf *bot.Spam
 		ConfigDBMode:    settings.Transient.ConfigDB, // indicate we're running with database config
 		// applies startup-equivalent defaults-fill + operational CLI overrides on /config/reload
 		ReloadNormalize: reloadNormalize,
+		ApplySettings:   applySettings, // applies updated or reloaded settings to running detectors and listener
 	}
 	if settingsStore != nil {
 		cfg.SettingsStore = settingsStore // avoid nil-interface-wrapping-nil-pointer trap
 	}
-	srv := webapi.NewServer(cfg)
+	srv = webapi.NewServer(cfg)
 
 	go func() {
 		if err := srv.Run(ctx); err != nil {
 			log.Printf("[ERROR] web server failed, %v", err)
 		}
 	}()
-	return nil
+	return srv, nil
 }
 
-// makeDetector creates spam detector with all checkers and updaters
-// it loads samples and dynamic files
+// makeDetector creates spam detector with all checkers and updaters, exits on invalid LLM settings
 func makeDetector(settings *config.Settings) *tgspam.Detector {
+	detector, err := newDetector(settings)
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+	return detector
+}
+
+// newDetector creates spam detector with all checkers, LLM clients, meta checks and Lua plugins.
+// Returns error if a LLM client can't be made, used directly for detectors rebuilt on settings change.
+func newDetector(settings *config.Settings) (*tgspam.Detector, error) {
 	detectorConfig := tgspam.Config{
 		MaxAllowedEmoji:     settings.MaxEmoji,
 		MinMsgLen:           settings.MinMsgLen,
 		MaxShortMsgCount:    settings.MaxShortMsgCount,
 		SimilarityThreshold: settings.SimilarityThreshold,
 		MinSpamProbability:  settings.MinSpamProbability,
+		MinSuspiciousProb:   settings.Review.MinProbability,
 		CasAPI:              settings.CAS.API,
 		CasUserAgent:        settings.CAS.UserAgent,
 		HTTPClient:          &http.Client{Timeout: settings.CAS.Timeout},
@@ -766,11 +1176,22 @@ func makeDetector(settings *config.Settings) *tgspam.Detector {
 		GeminiVeto:          settings.Gemini.Veto,
 		GeminiHistorySize:   settings.Gemini.HistorySize, // how many last requests sent to gemini
 		LLMConsensus:        tgspam.LLMConsensusMode(settings.LLM.Consensus),
+		LLMDisagreement:     settings.Review.LLMDisagreement,
 		LLMRequestTimeout:   settings.LLM.RequestTimeout,
 		MultiLangWords:      settings.MultiLangWords,
+		ObfuscatedWords:     settings.ObfuscatedWords,
+		NormalizeText:       settings.NormalizeText,
 		HistorySize:         settings.History.Size, // how many last request stored in memory
 	}
 
+	if settings.Scoring.Enabled {
+		detectorConfig.Scoring = tgspam.ScoringConfig{Enabled: true, Weights: settings.Scoring.Weights,
+			DefaultWeight: settings.Scoring.DefaultWeight, Threshold: settings.Scoring.Threshold,
+			SuspiciousThreshold: settings.Scoring.SuspiciousThreshold}
+		log.Printf("[INFO] weighted scoring enabled, threshold: %v, suspicious: %v, weights: %v",
+			settings.Scoring.Threshold, settings.Scoring.SuspiciousThreshold, settings.Scoring.Weights)
+	}
+
 	// prohibited scripts are validated earlier in Settings.Validate (log.Fatalf on a bad
 	// name), so this cannot fail in normal startup; handle the error defensively anyway —
 	// on error the resolver returns a nil map, which disables the check.
@@ -799,6 +1220,13 @@ func makeDetector(settings *config.Settings) *tgspam.Detector {
 		detectorConfig.StorageTimeout = settings.Transient.StorageTimeout
 	}
 
+	// blocked and allowed domains are loaded from the dictionary, only shortener expansion is configured here
+	detectorConfig.Domains.ExpandShortURLs = settings.Domains.ExpandShort
+	detectorConfig.Domains.Shorteners = settings.Domains.Shorteners
+	if settings.Domains.ExpandShort {
+		log.Printf("[INFO] url shortener links expansion enabled for domains check")
+	}
+
 	// set duplicate detection config
 	detectorConfig.DuplicateDetection.Threshold = settings.Duplicates.Threshold
 	detectorConfig.DuplicateDetection.Window = settings.Duplicates.Window
@@ -836,7 +1264,7 @@ func makeDetector(settings *config.Settings) *tgspam.Detector {
 		}
 		log.Printf("[DEBUG] openai config: %+v", openAIConfig)
 
-		detector.WithOpenAIChecker(openai.NewClientWithConfig(openaiConfig), openAIConfig)
+		detector.WithOpenAIChecker(&metrics.OpenAI{OpenAIClient: openai.NewClientWithConfig(openaiConfig)}, openAIConfig)
 	}
 
 	if settings.Gemini.Token != "" {
@@ -856,10 +1284,23 @@ func makeDetector(settings *config.Settings) *tgspam.Detector {
 			Backend: genai.BackendGeminiAPI,
 		})
 		if err != nil {
-			log.Fatalf("[ERROR] failed to create gemini client: %v", err)
+			return nil, fmt.Errorf("failed to create gemini client: %w", err)
 		}
 		log.Printf("[DEBUG] gemini config: %+v", geminiConfig)
-		detector.WithGeminiChecker(client.Models, geminiConfig)
+		detector.WithGeminiChecker(&metrics.Gemini{GeminiClient: client.Models}, geminiConfig)
+	}
+
+	for _, p := range settings.LLM.Providers {
+		checker, err := makeLLMChecker(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make llm provider %q: %w", p.Name, err)
+		}
+		opts := tgspam.LLMCheckerOpts{Veto: p.Veto, HistorySize: p.HistorySize, CheckShortMessages: p.CheckShortMessages,
+			CacheID: strings.Join([]string{p.Type, p.APIBase, p.Model, p.Prompt}, "\x00")}
+		if err := detector.WithLLMChecker(p.Name, checker, opts); err != nil {
+			return nil, fmt.Errorf("failed to add llm provider %q: %w", p.Name, err)
+		}
+		log.Printf("[WARN] llm provider %q enabled, type: %s, model: %q, veto: %v", p.Name, p.Type, p.Model, p.Veto)
 	}
 
 	if settings.AbnormalSpace.Enabled {
@@ -937,7 +1378,71 @@ func makeDetector(settings *config.Settings) *tgspam.Detector {
 		initLuaPlugins(detector, settings)
 	}
 
-	return detector
+	return detector, nil
+}
+
+// makeImageTextExtractor makes OCR engine for text extraction from images, nil if disabled
+func makeImageTextExtractor(settings *config.Settings) events.ImageTextExtractor {
+	switch settings.OCR.Type {
+	case "tesseract":
+		log.Printf("[INFO] image text extraction enabled with %s, lang: %q", settings.OCR.Tesseract, settings.OCR.Lang)
+		return &tgspam.TesseractOCR{Command: settings.OCR.Tesseract, Lang: settings.OCR.Lang}
+	case "http":
+		log.Printf("[INFO] image text extraction enabled with %s", settings.OCR.URL)
+		return &tgspam.HTTPOCR{URL: settings.OCR.URL, HTTPClient: &http.Client{Timeout: settings.OCR.Timeout}}
+	default:
+		return nil
+	}
+}
+
+// makeLLMChecker makes a named LLM checker for the provider, requests are counted in metrics by the provider name
+func makeLLMChecker(p config.LLMProviderSettings) (tgspam.LLMChecker, error) {
+	switch p.Type {
+	case "openai":
+		cfg := openai.DefaultConfig(p.Token)
+		if p.APIBase != "" {
+			cfg.BaseURL = p.APIBase
+		}
+		client := &metrics.OpenAI{OpenAIClient: openai.NewClientWithConfig(cfg), Provider: p.Name}
+		return tgspam.NewOpenAIChecker(client, tgspam.OpenAIConfig{SystemPrompt: p.Prompt, Model: p.Model,
+			MaxTokensResponse: p.MaxTokensResponse, RetryCount: p.RetryCount}), nil
+	case "gemini":
+		client, err := genai.NewClient(context.Background(), &genai.ClientConfig{APIKey: p.Token, Backend: genai.BackendGeminiAPI})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gemini client: %w", err)
+		}
+		cfg := tgspam.GeminiConfig{SystemPrompt: p.Prompt, Model: p.Model, RetryCount: p.RetryCount,
+			MaxOutputTokens: int32(min(p.MaxTokensResponse, math.MaxInt32))} //nolint:gosec // capped above
+		return tgspam.NewGeminiChecker(&metrics.Gemini{GeminiClient: client.Models, Provider: p.Name}, cfg), nil
+	case "anthropic":
+		client := &metrics.LLMHTTP{HTTPClient: &http.Client{}, Provider: p.Name}
+		return tgspam.NewAnthropicChecker(client, tgspam.AnthropicConfig{APIBase: p.APIBase, Token: p.Token, Model: p.Model,
+			SystemPrompt: p.Prompt, MaxTokensResponse: p.MaxTokensResponse, RetryCount: p.RetryCount}), nil
+	case "ollama":
+		client := &metrics.LLMHTTP{HTTPClient: &http.Client{}, Provider: p.Name}
+		return tgspam.NewOllamaChecker(client, tgspam.OllamaConfig{APIBase: p.APIBase, Model: p.Model,
+			SystemPrompt: p.Prompt, MaxTokensResponse: p.MaxTokensResponse, RetryCount: p.RetryCount}), nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider type %q", p.Type)
+	}
+}
+
+// makeLLMCache makes the cache of LLM verdicts, in-memory or persisted in the database.
+// Returns nil if the cache is disabled.
+func makeLLMCache(ctx context.Context, settings *config.Settings, dataDB *engine.SQL) (tgspam.LLMCache, error) {
+	if settings.LLM.CacheTTL <= 0 {
+		return nil, nil
+	}
+	log.Printf("[INFO] llm verdict cache enabled, ttl: %v, size: %d, persist: %v",
+		settings.LLM.CacheTTL, settings.LLM.CacheSize, settings.LLM.CachePersist)
+	if !settings.LLM.CachePersist {
+		return tgspam.NewLLMMemoryCache(settings.LLM.CacheSize, settings.LLM.CacheTTL), nil
+	}
+	res, err := storage.NewLLMCache(ctx, settings.LLM.CacheTTL, settings.LLM.CacheSize, dataDB)
+	if err != nil {
+		return nil, fmt.Errorf("can't make llm cache store, %w", err)
+	}
+	return res, nil
 }
 
 // initLuaPlugins initializes Lua plugin engine and configures it
@@ -1017,6 +1522,192 @@ func makeSpamBot(ctx context.Context, settings *config.Settings, dataDB *engine.
 	return spamBot, nil
 }
 
+// makeGroups makes listener configs for additional groups. Groups without detector overrides share the primary bot,
+// others get own detector and bot, backed by the same samples, dictionaries, approved users, locator
+// spam image hashes (nil if image hash check disabled), LLM verdict cache, user reputation, local CAS mirror
+// and federated bans (nil if disabled).
+func makeGroups(ctx context.Context, settings *config.Settings, dataDB *engine.SQL, approvedUsers *storage.ApprovedUsers,
+	locator *storage.Locator, imageHashes *storage.ImageHashes, llmCache tgspam.LLMCache,
+	reputation *storage.Reputation, casBans *storage.CASBans, federatedBans *storage.FederatedBans) ([]events.GroupConfig, error) {
+	res := make([]events.GroupConfig, 0, len(settings.Groups))
+	for _, g := range settings.Groups {
+		gc := events.GroupConfig{Group: g.Group, AdminGroup: g.AdminGroup, SuperUsers: g.SuperUsers}
+		if g.Overrides.IsEmpty() {
+			res = append(res, gc)
+			continue
+		}
+
+		gs := settings.ForGroup(g)
+		gs.Convert = "disabled" // samples already migrated by the primary bot
+		detector := makeDetector(gs)
+		groupBot, err := makeSpamBot(ctx, gs, dataDB, detector)
+		if err != nil {
+			return nil, fmt.Errorf("can't make spam bot for group %q, %w", g.Group, err)
+		}
+		if _, err := detector.WithUserStorage(approvedUsers); err != nil {
+			return nil, fmt.Errorf("can't load approved users for group %q, %w", g.Group, err)
+		}
+		detector.WithMessageCounter(locator)
+		if imageHashes != nil {
+			detector.WithMetaChecks(tgspam.ImageHashCheck(imageHashes, gs.ImageHash.Distance))
+		}
+		if llmCache != nil {
+			detector.WithLLMCache(llmCache)
+		}
+		if reputation != nil {
+			detector.WithApprovalGuard(reputation)
+		}
+		if casBans != nil {
+			detector.WithCASMirror(casBans, casMirrorStaleIntervals*gs.CAS.SyncInterval)
+		}
+		if federatedBans != nil {
+			detector.WithMetaChecks(tgspam.FederationCheck(federatedBans, federationTrust(gs)))
+		}
+		log.Printf("[INFO] group %q uses own detector settings", g.Group)
+		gc.Bot = groupBot
+		res = append(res, gc)
+	}
+	return res, nil
+}
+
+// makeCASMirror makes local mirror of CAS banned users and starts the background sync of CAS export.
+// Returns nil if CAS is disabled or the sync interval is not set.
+func makeCASMirror(ctx context.Context, settings *config.Settings, dataDB *engine.SQL) (*storage.CASBans, error) {
+	if !settings.IsCASEnabled() || settings.CAS.SyncInterval <= 0 {
+		return nil, nil
+	}
+	casBans, err := storage.NewCASBans(ctx, dataDB)
+	if err != nil {
+		return nil, fmt.Errorf("can't make cas bans store, %w", err)
+	}
+	syncer := &tgspam.CASSyncer{
+		API:        settings.CAS.API,
+		UserAgent:  settings.CAS.UserAgent,
+		HTTPClient: &http.Client{Timeout: casExportTimeout},
+		Store:      casBans,
+		Interval:   settings.CAS.SyncInterval,
+	}
+	go syncer.Run(ctx)
+	log.Printf("[INFO] local CAS mirror enabled, sync interval: %v", settings.CAS.SyncInterval)
+	return casBans, nil
+}
+
+// makeWebhookSecret returns the configured webhook secret token, or a random one if not set.
+// The webhook is registered on each start, so the random token doesn't need to be stored.
+func makeWebhookSecret(secret string) (string, error) {
+	if secret != "" {
+		return secret, nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("can't read random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// makeFederation makes storage of bans pulled from federation peers and starts the background pull of peer feeds.
+// Returns nil if federation is disabled. The own feed is published by the web server, see activateServer.
+func makeFederation(ctx context.Context, settings *config.Settings, dataDB *engine.SQL) (*storage.FederatedBans, error) {
+	if !settings.Federation.Enabled {
+		return nil, nil
+	}
+	federatedBans, err := storage.NewFederatedBans(ctx, dataDB)
+	if err != nil {
+		return nil, fmt.Errorf("can't make federated bans store, %w", err)
+	}
+	if !settings.Server.Enabled {
+		log.Printf("[WARN] web server is disabled, own federation feed is not published")
+	}
+
+	peers := make([]tgspam.FederationPeer, 0, len(settings.Federation.Peers))
+	for _, p := range settings.Federation.Peers {
+		key, err := base64.StdEncoding.DecodeString(p.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key of federation peer %q, %w", p.Name, err)
+		}
+		peers = append(peers, tgspam.FederationPeer{Name: p.Name, URL: p.URL, PublicKey: key, Trust: tgspam.FederationTrust(p.Trust)})
+		log.Printf("[INFO] federation peer %q, %s, trust: %s", p.Name, p.URL, p.Trust)
+	}
+	if len(peers) > 0 {
+		syncer := &tgspam.FederationSyncer{
+			Peers:      peers,
+			HTTPClient: &http.Client{Timeout: 30 * time.Second},
+			Store:      federatedBans,
+			Interval:   settings.Federation.PullInterval,
+		}
+		go syncer.Run(ctx)
+	}
+	log.Printf("[INFO] federation enabled, %d peers, pull interval: %v", len(peers), settings.Federation.PullInterval)
+	return federatedBans, nil
+}
+
+// makeHooks makes the dispatcher of moderation events to outgoing webhooks and starts the background delivery
+// of queued events. Returns nil if no webhook urls are set.
+func makeHooks(ctx context.Context, settings *config.Settings, dataDB *engine.SQL) (*hooks.Dispatcher, error) {
+	if len(settings.Hooks.URLs) == 0 {
+		return nil, nil
+	}
+	deliveries, err := storage.NewEventDeliveries(ctx, dataDB)
+	if err != nil {
+		return nil, fmt.Errorf("can't make event deliveries store, %w", err)
+	}
+	dispatcher := hooks.NewDispatcher(settings.Hooks.URLs, settings.Hooks.Secret, deliveries)
+	dispatcher.MaxAttempts = settings.Hooks.MaxAttempts
+	dispatcher.RetryDelay = settings.Hooks.RetryDelay
+	go dispatcher.Run(ctx)
+	return dispatcher, nil
+}
+
+// federationTrust returns trust levels of federation peers by peer name
+func federationTrust(settings *config.Settings) map[string]tgspam.FederationTrust {
+	res := make(map[string]tgspam.FederationTrust, len(settings.Federation.Peers))
+	for _, p := range settings.Federation.Peers {
+		res[p.Name] = tgspam.FederationTrust(p.Trust)
+	}
+	return res
+}
+
+// loadFederationKey loads ed25519 private key signing the federation feed from PEM encoded PKCS8 file.
+// A new key is generated and saved if the file doesn't exist.
+func loadFederationKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path from trusted config
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, genErr := ed25519.GenerateKey(nil)
+		if genErr != nil {
+			return nil, fmt.Errorf("can't generate federation key, %w", genErr)
+		}
+		der, mErr := x509.MarshalPKCS8PrivateKey(key)
+		if mErr != nil {
+			return nil, fmt.Errorf("can't marshal federation key, %w", mErr)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("can't make directory for federation key, %w", err)
+		}
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+			return nil, fmt.Errorf("can't save federation key, %w", err)
+		}
+		log.Printf("[INFO] new federation key generated in %s", path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read federation key, %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in federation key file %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse federation key, %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("federation key in %s is not an ed25519 key", path)
+	}
+	return key, nil
+}
+
 // normalizeFilePaths expands ~ and makes file paths absolute, applying the
 // empty-samples-path fallback so SamplesDataPath inherits DynamicDataPath when
 // the operator left it unset. Called at startup and from the reloadNormalize
@@ -1100,6 +1791,11 @@ func makeSpamLogger(ctx context.Context, gid string, wr io.Writer, dataDB *engin
 			UserName:  userName,
 			Timestamp: time.Now().In(time.Local),
 			GID:       gid,
+			Dry:       response.Dry,
+			Temporary: response.Temporary,
+		}
+		if msg.Image != nil {
+			rec.ImageHash = msg.Image.Hash
 		}
 		if err := detectedSpamStore.Write(ctx, rec, response.CheckResults); err != nil {
 			log.Printf("[WARN] can't write to db, %v", err)
@@ -3,10 +3,13 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
@@ -23,9 +26,12 @@ import (
 
 	"github.com/umputun/tg-spam/app/bot"
 	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/events"
+	"github.com/umputun/tg-spam/app/hooks"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/app/storage/engine"
 	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam"
 )
 
 func TestMakeSpamLogger(t *testing.T) {
@@ -350,6 +356,259 @@ func Test_makeDetector(t *testing.T) {
 	})
 }
 
+func Test_makeImageTextExtractor(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		settings := makeTestSettings()
+		assert.Nil(t, makeImageTextExtractor(settings))
+		settings.OCR.Type = "none"
+		assert.Nil(t, makeImageTextExtractor(settings))
+	})
+
+	t.Run("tesseract", func(t *testing.T) {
+		settings := makeTestSettings()
+		settings.OCR = config.OCRSettings{Type: "tesseract", Tesseract: "/usr/bin/tesseract", Lang: "eng+rus"}
+		res, ok := makeImageTextExtractor(settings).(*tgspam.TesseractOCR)
+		require.True(t, ok)
+		assert.Equal(t, "/usr/bin/tesseract", res.Command)
+		assert.Equal(t, "eng+rus", res.Lang)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		settings := makeTestSettings()
+		settings.OCR = config.OCRSettings{Type: "http", URL: "http://ocr:8080/extract", Timeout: 10 * time.Second}
+		res, ok := makeImageTextExtractor(settings).(*tgspam.HTTPOCR)
+		require.True(t, ok)
+		assert.Equal(t, "http://ocr:8080/extract", res.URL)
+		assert.Equal(t, 10*time.Second, res.HTTPClient.(*http.Client).Timeout)
+	})
+}
+
+func Test_makeLLMCache(t *testing.T) {
+	ctx := context.Background()
+	db, err := engine.NewSqlite(":memory:", "gr1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		c, err := makeLLMCache(ctx, &config.Settings{}, db)
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+
+	t.Run("in-memory", func(t *testing.T) {
+		c, err := makeLLMCache(ctx, &config.Settings{LLM: config.LLMSettings{CacheTTL: time.Hour, CacheSize: 10}}, db)
+		require.NoError(t, err)
+		assert.IsType(t, &tgspam.LLMMemoryCache{}, c)
+	})
+
+	t.Run("persistent", func(t *testing.T) {
+		settings := &config.Settings{LLM: config.LLMSettings{CacheTTL: time.Hour, CacheSize: 10, CachePersist: true}}
+		c, err := makeLLMCache(ctx, settings, db)
+		require.NoError(t, err)
+		require.IsType(t, &storage.LLMCache{}, c)
+
+		require.NoError(t, c.Put(ctx, "key", tgspam.LLMVerdict{Spam: true, Details: "scam"}))
+		restarted, err := makeLLMCache(ctx, settings, db)
+		require.NoError(t, err)
+		v, ok, err := restarted.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.True(t, ok, "verdict kept across restarts")
+		assert.Equal(t, tgspam.LLMVerdict{Spam: true, Details: "scam"}, v)
+	})
+}
+
+func Test_makeCASMirror(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, err := engine.NewSqlite(":memory:", "gr1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		m, err := makeCASMirror(ctx, &config.Settings{CAS: config.CASSettings{API: "http://localhost"}}, db)
+		require.NoError(t, err)
+		assert.Nil(t, m)
+		m, err = makeCASMirror(ctx, &config.Settings{CAS: config.CASSettings{SyncInterval: time.Hour}}, db)
+		require.NoError(t, err)
+		assert.Nil(t, m, "cas disabled")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/export.csv", r.URL.Path)
+			_, _ = w.Write([]byte("user_id,offenses,time_added\n123,1,2024-01-01T00:00:00.000Z\n"))
+		}))
+		defer ts.Close()
+
+		settings := &config.Settings{CAS: config.CASSettings{API: ts.URL, SyncInterval: time.Hour}}
+		m, err := makeCASMirror(ctx, settings, db)
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		require.Eventually(t, func() bool { return !m.SyncedAt().IsZero() }, time.Second, 10*time.Millisecond)
+		banned, err := m.IsBanned(ctx, 123)
+		require.NoError(t, err)
+		assert.True(t, banned)
+	})
+}
+
+func Test_makeWebhookSecret(t *testing.T) {
+	secret, err := makeWebhookSecret("configured")
+	require.NoError(t, err)
+	assert.Equal(t, "configured", secret)
+
+	secret, err = makeWebhookSecret("")
+	require.NoError(t, err)
+	assert.Len(t, secret, 64)
+	assert.Regexp(t, "^[0-9a-f]+$", secret, "random secret uses characters allowed by telegram")
+	other, err := makeWebhookSecret("")
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func Test_loadFederationKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "sub", "federation.key")
+
+	key, err := loadFederationKey(keyFile)
+	require.NoError(t, err)
+	require.Len(t, key, ed25519.PrivateKeySize)
+	st, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), st.Mode().Perm())
+
+	loaded, err := loadFederationKey(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded, "existing key is loaded, not regenerated")
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("garbage"), 0o600))
+	_, err = loadFederationKey(keyFile)
+	require.Error(t, err)
+}
+
+func Test_makeFederation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, err := engine.NewSqlite(":memory:", "gr1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		fb, err := makeFederation(ctx, &config.Settings{}, db)
+		require.NoError(t, err)
+		assert.Nil(t, fb)
+	})
+
+	t.Run("pulls peer feeds", func(t *testing.T) {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			feed := tgspam.FederationFeed{GeneratedAt: time.Now(), Bans: []tgspam.FederatedBan{{UserID: 123, Reasons: []string{"cas"}}}}
+			body, sig, err := tgspam.SignFederationFeed(priv, feed)
+			require.NoError(t, err)
+			w.Header().Set(tgspam.FederationSignatureHeader, sig)
+			_, _ = w.Write(body)
+		}))
+		defer ts.Close()
+
+		settings := &config.Settings{Federation: config.FederationSettings{Enabled: true, FeedWindow: time.Hour,
+			PullInterval: time.Hour, Peers: []config.FederationPeerSettings{
+				{Name: "friends", URL: ts.URL, PublicKey: base64.StdEncoding.EncodeToString(pub), Trust: "ban"}}}}
+		fb, err := makeFederation(ctx, settings, db)
+		require.NoError(t, err)
+		require.NotNil(t, fb)
+
+		check := tgspam.FederationCheck(fb, federationTrust(settings))
+		require.Eventually(t, func() bool { return check(spamcheck.Request{UserID: "123"}).Spam }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, "banned by friends (cas)", check(spamcheck.Request{UserID: "123"}).Details)
+	})
+}
+
+func Test_makeHooks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db, err := engine.NewSqlite(":memory:", "gr1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		d, err := makeHooks(ctx, &config.Settings{}, db)
+		require.NoError(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("delivers signed events", func(t *testing.T) {
+		received := make(chan *http.Request, 1)
+		bodies := make(chan []byte, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received <- r
+			bodies <- body
+		}))
+		defer ts.Close()
+
+		settings := &config.Settings{Hooks: config.HooksSettings{URLs: []string{ts.URL}, Secret: "secret",
+			MaxAttempts: 3, RetryDelay: time.Second}}
+		d, err := makeHooks(ctx, settings, db)
+		require.NoError(t, err)
+		require.NotNil(t, d)
+		assert.Equal(t, 3, d.MaxAttempts)
+		assert.Equal(t, time.Second, d.RetryDelay)
+
+		require.NoError(t, d.Publish(ctx, hooks.Event{Type: hooks.EventBan, UserID: 123}))
+		select {
+		case r := <-received:
+			body := <-bodies
+			assert.Equal(t, hooks.Sign("secret", body), r.Header.Get(hooks.SignatureHeader))
+			assert.Contains(t, string(body), `"user_id":123`)
+		case <-time.After(time.Second):
+			t.Fatal("event not delivered")
+		}
+	})
+}
+
+func Test_makeLLMChecker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verdict := `{"spam": true, "reason": "promo", "confidence": 90}`
+		switch r.URL.Path {
+		case "/api/chat": // ollama
+			_ = json.NewEncoder(w).Encode(map[string]any{"message": map[string]string{"content": verdict}})
+		case "/v1/messages": // anthropic
+			_ = json.NewEncoder(w).Encode(map[string]any{"content": []map[string]string{{"type": "text", "text": verdict}}})
+		case "/v1/chat/completions": // openai-compatible
+			_ = json.NewEncoder(w).Encode(map[string]any{"choices": []map[string]any{
+				{"message": map[string]string{"role": "assistant", "content": verdict}}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	for _, p := range []config.LLMProviderSettings{
+		{Name: "local", Type: "ollama", APIBase: ts.URL},
+		{Name: "claude", Type: "anthropic", APIBase: ts.URL, Token: "secret"},
+		{Name: "llamacpp", Type: "openai", APIBase: ts.URL + "/v1"},
+	} {
+		t.Run(p.Type, func(t *testing.T) {
+			checker, err := makeLLMChecker(p)
+			require.NoError(t, err)
+			spam, resp := checker.Check(t.Context(), "buy now", nil)
+			require.NoError(t, resp.Error)
+			assert.True(t, spam)
+			assert.Equal(t, "promo, confidence: 90%", resp.Details)
+		})
+	}
+
+	t.Run("gemini", func(t *testing.T) {
+		checker, err := makeLLMChecker(config.LLMProviderSettings{Name: "flash", Type: "gemini", Token: "secret"})
+		require.NoError(t, err)
+		assert.NotNil(t, checker)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := makeLLMChecker(config.LLMProviderSettings{Name: "x", Type: "magic"})
+		require.EqualError(t, err, `unknown llm provider type "magic"`)
+	})
+}
+
 func Test_initLuaPlugins(t *testing.T) {
 	t.Run("basic plugin initialization", func(t *testing.T) {
 		settings := makeTestSettings()
@@ -429,6 +688,52 @@ func Test_makeSpamBot(t *testing.T) {
 	})
 }
 
+func Test_makeGroups(t *testing.T) {
+	ctx := t.Context()
+	tmpDir := t.TempDir()
+
+	settings := makeTestSettings()
+	settings.Files.SamplesDataPath = tmpDir
+	settings.Files.DynamicDataPath = tmpDir
+	settings.InstanceID = "gr1"
+
+	db, err := engine.NewSqlite(path.Join(tmpDir, "tg-spam.db"), "gr1")
+	require.NoError(t, err)
+	defer db.Close()
+	samplesStore, err := storage.NewSamples(ctx, db)
+	require.NoError(t, err)
+	require.NoError(t, samplesStore.Add(ctx, storage.SampleTypeSpam, storage.SampleOriginPreset, "spam1"))
+	require.NoError(t, samplesStore.Add(ctx, storage.SampleTypeHam, storage.SampleOriginPreset, "ham1"))
+	approvedUsers, err := storage.NewApprovedUsers(ctx, db)
+	require.NoError(t, err)
+	locator, err := storage.NewLocator(ctx, time.Hour, 100, db)
+	require.NoError(t, err)
+
+	t.Run("no groups", func(t *testing.T) {
+		res, err := makeGroups(ctx, settings, db, approvedUsers, locator, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	})
+
+	t.Run("groups with and without overrides", func(t *testing.T) {
+		groups, err := config.ParseGroupSpecs([]string{"second;admin=second-admin;super=u1,u2", "third;paranoid=true"})
+		require.NoError(t, err)
+		settings.Groups = groups
+		defer func() { settings.Groups = nil }()
+
+		res, err := makeGroups(ctx, settings, db, approvedUsers, locator, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		assert.Equal(t, "second", res[0].Group)
+		assert.Equal(t, "second-admin", res[0].AdminGroup)
+		assert.Equal(t, events.SuperUsers{"u1", "u2"}, res[0].SuperUsers)
+		assert.Nil(t, res[0].Bot, "group without overrides uses primary bot")
+		assert.Equal(t, "third", res[1].Group)
+		assert.NotNil(t, res[1].Bot, "group with overrides gets own bot")
+		assert.False(t, settings.ParanoidMode, "primary settings not changed")
+	})
+}
+
 func Test_activateServerOnly(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -1245,6 +1550,10 @@ func TestREADMEAllOptionsMatchesHelp(t *testing.T) {
 	_, err = parser.AddCommand("save-config", "Save current configuration to database",
 		"Saves all current settings to the database for future use with --confdb", &struct{}{})
 	require.NoError(t, err)
+	_, err = parser.AddCommand("evaluate", "Evaluate spam detection accuracy on labeled messages",
+		"Checks labeled messages and stored samples with the current settings and reports precision, recall and F1",
+		&evaluateOptions{})
+	require.NoError(t, err)
 
 	// walk all groups recursively and collect flags + env vars
 	var (
@@ -1317,11 +1626,13 @@ func TestREADMEAllOptionsMatchesHelp(t *testing.T) {
 		assert.Contains(t, optionsBlock, header, "README options block missing group header %q", header)
 	}
 
-	// available commands block must include save-config
+	// available commands block must include save-config and evaluate
 	require.Contains(t, optionsBlock, "Available commands:",
 		`README options block must include "Available commands:" section`)
 	require.Regexp(t, `(?m)^\s+save-config\s+Save current configuration to database`,
 		optionsBlock, "README options block must list save-config command")
+	require.Regexp(t, `(?m)^\s+evaluate\s+Evaluate spam detection accuracy on labeled messages`,
+		optionsBlock, "README options block must list evaluate command")
 
 	// reverse direction: catch stale entries in README that no longer exist as flags.
 	// extract every "--<long-name>" token from the options block (not in code spans
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+)
+
+// OpenAIClient is the subset of openai client used by the openai checker
+type OpenAIClient interface {
+	CreateChatCompletion(context.Context, openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+}
+
+// GeminiClient is the subset of gemini client used by the gemini checker
+type GeminiClient interface {
+	GenerateContent(context.Context, string, []*genai.Content, *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+}
+
+// HTTPClient is the http client used by LLM checkers talking to the api directly
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// OpenAI wraps openai client and records latency, errors and token usage of each request
+type OpenAI struct {
+	OpenAIClient
+	Provider string // provider label, "openai" if empty
+}
+
+// CreateChatCompletion calls the wrapped client and records the request metrics
+func (o *OpenAI) CreateChatCompletion(ctx context.Context,
+	req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	start := time.Now()
+	resp, err := o.OpenAIClient.CreateChatCompletion(ctx, req)
+	ObserveLLM(providerLabel(o.Provider, "openai"), start, err, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	return resp, err //nolint:wrapcheck // transparent wrapper, errors are handled by the checker
+}
+
+// Gemini wraps gemini client and records latency, errors and token usage of each request
+type Gemini struct {
+	GeminiClient
+	Provider string // provider label, "gemini" if empty
+}
+
+// GenerateContent calls the wrapped client and records the request metrics
+func (g *Gemini) GenerateContent(ctx context.Context, model string, contents []*genai.Content,
+	config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	start := time.Now()
+	resp, err := g.GeminiClient.GenerateContent(ctx, model, contents, config)
+	var prompt, completion int
+	if resp != nil && resp.UsageMetadata != nil {
+		prompt, completion = int(resp.UsageMetadata.PromptTokenCount), int(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	ObserveLLM(providerLabel(g.Provider, "gemini"), start, err, prompt, completion)
+	return resp, err //nolint:wrapcheck // transparent wrapper, errors are handled by the checker
+}
+
+// LLMHTTP wraps http client of an LLM checker and records latency and errors of each request.
+// Token usage is not recorded as the response is not parsed here.
+type LLMHTTP struct {
+	HTTPClient
+	Provider string // provider label
+}
+
+// Do calls the wrapped client and records the request metrics, non-2xx responses are counted as errors
+func (l *LLMHTTP) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := l.HTTPClient.Do(req)
+	status := err
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		status = fmt.Errorf("status %d", resp.StatusCode)
+	}
+	ObserveLLM(l.Provider, start, status, 0, 0)
+	return resp, err //nolint:wrapcheck // transparent wrapper, errors are handled by the checker
+}
+
+func providerLabel(provider, def string) string {
+	if provider == "" {
+		return def
+	}
+	return provider
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+
+	"github.com/umputun/tg-spam/lib/tgspam/mocks"
+)
+
+func TestOpenAI_CreateChatCompletion(t *testing.T) {
+	fail := false
+	client := &mocks.OpenAIClientMock{
+		CreateChatCompletionFunc: func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+			if fail {
+				return openai.ChatCompletionResponse{}, errors.New("api error")
+			}
+			return openai.ChatCompletionResponse{Model: req.Model,
+				Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 3}}, nil
+		},
+	}
+	okBefore, errBefore := LLMRequests.Value("openai", "ok"), LLMRequests.Value("openai", "error")
+	promptBefore, complBefore := LLMTokens.Value("openai", "prompt"), LLMTokens.Value("openai", "completion")
+
+	o := &OpenAI{OpenAIClient: client}
+	resp, err := o.CreateChatCompletion(t.Context(), openai.ChatCompletionRequest{Model: "gpt"})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt", resp.Model)
+
+	fail = true
+	_, err = o.CreateChatCompletion(t.Context(), openai.ChatCompletionRequest{Model: "gpt"})
+	require.EqualError(t, err, "api error")
+
+	assert.Len(t, client.CreateChatCompletionCalls(), 2)
+	assert.InDelta(t, okBefore+1, LLMRequests.Value("openai", "ok"), 0.001)
+	assert.InDelta(t, errBefore+1, LLMRequests.Value("openai", "error"), 0.001)
+	assert.InDelta(t, promptBefore+10, LLMTokens.Value("openai", "prompt"), 0.001)
+	assert.InDelta(t, complBefore+3, LLMTokens.Value("openai", "completion"), 0.001)
+}
+
+func TestGemini_GenerateContent(t *testing.T) {
+	fail := false
+	client := &mocks.GeminiClientMock{
+		GenerateContentFunc: func(ctx context.Context, model string, contents []*genai.Content,
+			config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+			if fail {
+				return nil, errors.New("api error")
+			}
+			return &genai.GenerateContentResponse{ModelVersion: model,
+				UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 7, CandidatesTokenCount: 2}}, nil
+		},
+	}
+	okBefore, errBefore := LLMRequests.Value("gemini", "ok"), LLMRequests.Value("gemini", "error")
+	promptBefore, complBefore := LLMTokens.Value("gemini", "prompt"), LLMTokens.Value("gemini", "completion")
+
+	g := &Gemini{GeminiClient: client}
+	resp, err := g.GenerateContent(t.Context(), "gemma", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gemma", resp.ModelVersion)
+
+	fail = true
+	_, err = g.GenerateContent(t.Context(), "gemma", nil, nil)
+	require.EqualError(t, err, "api error")
+
+	assert.InDelta(t, okBefore+1, LLMRequests.Value("gemini", "ok"), 0.001)
+	assert.InDelta(t, errBefore+1, LLMRequests.Value("gemini", "error"), 0.001)
+	assert.InDelta(t, promptBefore+7, LLMTokens.Value("gemini", "prompt"), 0.001)
+	assert.InDelta(t, complBefore+2, LLMTokens.Value("gemini", "completion"), 0.001)
+}
+
+func TestOpenAI_CustomProvider(t *testing.T) {
+	client := &mocks.OpenAIClientMock{
+		CreateChatCompletionFunc: func(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+			return openai.ChatCompletionResponse{Usage: openai.Usage{PromptTokens: 5, CompletionTokens: 1}}, nil
+		},
+	}
+	okBefore, promptBefore := LLMRequests.Value("local", "ok"), LLMTokens.Value("local", "prompt")
+	o := &OpenAI{OpenAIClient: client, Provider: "local"}
+	_, err := o.CreateChatCompletion(t.Context(), openai.ChatCompletionRequest{})
+	require.NoError(t, err)
+	assert.InDelta(t, okBefore+1, LLMRequests.Value("local", "ok"), 0.001)
+	assert.InDelta(t, promptBefore+5, LLMTokens.Value("local", "prompt"), 0.001)
+}
+
+func TestLLMHTTP_Do(t *testing.T) {
+	status := http.StatusOK
+	client := &mocks.HTTPClientMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+		if status == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	}}
+	okBefore, errBefore := LLMRequests.Value("ollama", "ok"), LLMRequests.Value("ollama", "error")
+
+	l := &LLMHTTP{HTTPClient: client, Provider: "ollama"}
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/api/chat", http.NoBody)
+	require.NoError(t, err)
+	resp, err := l.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	status = http.StatusInternalServerError
+	resp, err = l.Do(req)
+	require.NoError(t, err, "status errors are left to the checker")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	status = 0
+	_, err = l.Do(req)
+	require.EqualError(t, err, "connection refused")
+
+	assert.Len(t, client.DoCalls(), 3)
+	assert.InDelta(t, okBefore+1, LLMRequests.Value("ollama", "ok"), 0.001)
+	assert.InDelta(t, errBefore+2, LLMRequests.Value("ollama", "error"), 0.001)
+}
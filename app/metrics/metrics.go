@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// default registry with all application metrics, served by Handler
+var registry = NewRegistry()
+
+var (
+	// Checks counts results of individual spam checks by check name and result (spam, ham or error)
+	Checks = registry.NewCounterVec("tgspam_checks_total", "results of individual spam checks", "check", "result")
+
+	// CheckDuration tracks latency of the full detector check by the final verdict (spam or ham)
+	CheckDuration = registry.NewHistogramVec("tgspam_check_duration_seconds", "latency of detector check",
+		[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}, "result")
+
+	// LLMRequests counts requests to LLM providers by provider and status (ok or error)
+	LLMRequests = registry.NewCounterVec("tgspam_llm_requests_total", "requests to LLM providers", "provider", "status")
+
+	// LLMDuration tracks latency of LLM requests by provider
+	LLMDuration = registry.NewHistogramVec("tgspam_llm_request_duration_seconds", "latency of LLM requests",
+		[]float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60}, "provider")
+
+	// LLMTokens counts tokens used by LLM providers by provider and type (prompt or completion)
+	LLMTokens = registry.NewCounterVec("tgspam_llm_tokens_total", "tokens used by LLM providers", "provider", "type")
+
+	// Actions counts moderation actions: ban, ban_channel, restrict, unban, unban_channel, report and warn
+	Actions = registry.NewCounterVec("tgspam_actions_total", "moderation actions performed by the bot", "action")
+
+	// HookDeliveries counts attempts to deliver events to outgoing webhooks by status: delivered, failed or dropped
+	HookDeliveries = registry.NewCounterVec("tgspam_hook_deliveries_total", "event deliveries to outgoing webhooks", "status")
+
+	// TelegramRequests counts telegram API calls by method and status (ok or error)
+	TelegramRequests = registry.NewCounterVec("tgspam_telegram_requests_total", "telegram API requests",
+		"method", "status")
+)
+
+// Handler returns http handler serving all application metrics
+func Handler() http.Handler {
+	return registry.Handler()
+}
+
+// ObserveCheck records latency of the detector check started at the given time, its verdict
+// and results of all individual checks
+func ObserveCheck(start time.Time, spam bool, cr []spamcheck.Response) {
+	CheckDuration.Observe(time.Since(start).Seconds(), verdict(spam))
+	CountChecks(cr)
+}
+
+// CountChecks records results of individual checks, responses without a name are skipped
+func CountChecks(cr []spamcheck.Response) {
+	for _, r := range cr {
+		if r.Name == "" {
+			continue
+		}
+		result := verdict(r.Spam)
+		if r.Error != nil {
+			result = "error"
+		}
+		Checks.Inc(r.Name, result)
+	}
+}
+
+// ObserveLLM records a single LLM request with its latency, status and used tokens
+func ObserveLLM(provider string, start time.Time, err error, promptTokens, completionTokens int) {
+	LLMDuration.Observe(time.Since(start).Seconds(), provider)
+	LLMRequests.Inc(provider, status(err))
+	LLMTokens.Add(float64(promptTokens), provider, "prompt")
+	LLMTokens.Add(float64(completionTokens), provider, "completion")
+}
+
+// ObserveTelegram records a single telegram API call by method and status
+func ObserveTelegram(method string, err error) {
+	TelegramRequests.Inc(method, status(err))
+}
+
+func verdict(spam bool) string {
+	if spam {
+		return "spam"
+	}
+	return "ham"
+}
+
+func status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestObserveCheck(t *testing.T) {
+	spamBefore := CheckDuration.Count("spam")
+	stopwordBefore := Checks.Value("stopword", "spam")
+	casBefore := Checks.Value("cas", "error")
+	classifierBefore := Checks.Value("classifier", "ham")
+
+	ObserveCheck(time.Now().Add(-10*time.Millisecond), true, []spamcheck.Response{
+		{Name: "stopword", Spam: true},
+		{Name: "cas", Error: errors.New("timeout")},
+		{Name: "classifier", Spam: false},
+		{Spam: true}, // no name, skipped
+	})
+
+	assert.Equal(t, spamBefore+1, CheckDuration.Count("spam"))
+	assert.InDelta(t, stopwordBefore+1, Checks.Value("stopword", "spam"), 0.001)
+	assert.InDelta(t, casBefore+1, Checks.Value("cas", "error"), 0.001)
+	assert.InDelta(t, classifierBefore+1, Checks.Value("classifier", "ham"), 0.001)
+	assert.InDelta(t, 0, Checks.Value("", "spam"), 0.001)
+}
+
+func TestObserveLLM(t *testing.T) {
+	okBefore := LLMRequests.Value("test-llm", "ok")
+	errBefore := LLMRequests.Value("test-llm", "error")
+
+	ObserveLLM("test-llm", time.Now(), nil, 100, 20)
+	ObserveLLM("test-llm", time.Now(), errors.New("failed"), 0, 0)
+
+	assert.InDelta(t, okBefore+1, LLMRequests.Value("test-llm", "ok"), 0.001)
+	assert.InDelta(t, errBefore+1, LLMRequests.Value("test-llm", "error"), 0.001)
+	assert.InDelta(t, 100, LLMTokens.Value("test-llm", "prompt"), 0.001)
+	assert.InDelta(t, 20, LLMTokens.Value("test-llm", "completion"), 0.001)
+	assert.Equal(t, uint64(2), LLMDuration.Count("test-llm"))
+}
+
+func TestObserveTelegram(t *testing.T) {
+	ObserveTelegram("TestMethod", nil)
+	ObserveTelegram("TestMethod", errors.New("failed"))
+	ObserveTelegram("TestMethod", nil)
+	assert.InDelta(t, 2, TelegramRequests.Value("TestMethod", "ok"), 0.001)
+	assert.InDelta(t, 1, TelegramRequests.Value("TestMethod", "error"), 0.001)
+}
+
+func TestHandler(t *testing.T) {
+	Actions.Inc("test-action")
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, "# TYPE tgspam_actions_total counter")
+	assert.Contains(t, body, `tgspam_actions_total{action="test-action"} 1`)
+	assert.Contains(t, body, "# TYPE tgspam_check_duration_seconds histogram")
+	assert.Contains(t, body, "# TYPE tgspam_telegram_requests_total counter")
+}
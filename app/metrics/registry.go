@@ -0,0 +1,276 @@
+// Package metrics implements a minimal set of Prometheus-compatible collectors (counters and histograms
+// with labels) and exposes them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry keeps a list of collectors and writes them in the Prometheus text format.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []collector
+}
+
+type collector interface {
+	name() string
+	write(w io.Writer) error
+}
+
+// NewRegistry makes an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounterVec makes a counter with the given label names and registers it.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	res := &CounterVec{vec: newVec(name, help, labels), values: map[string]*float64{}}
+	r.register(res)
+	return res
+}
+
+// NewHistogramVec makes a histogram with the given buckets and label names and registers it.
+// Buckets are upper bounds in ascending order, +Inf bucket is added automatically.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	bs := append([]float64{}, buckets...)
+	sort.Float64s(bs)
+	res := &HistogramVec{vec: newVec(name, help, labels), buckets: bs, values: map[string]*histogramValue{}}
+	r.register(res)
+	return res
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.collectors {
+		if existing.name() == c.name() {
+			panic(fmt.Sprintf("metric %q registered twice", c.name()))
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes all registered metrics in the Prometheus text format, sorted by metric name.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return fmt.Errorf("failed to write metric %s: %w", c.name(), err)
+		}
+	}
+	return nil
+}
+
+// Handler returns http handler serving metrics in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// vec is a common part of labeled collectors
+type vec struct {
+	metricName string
+	help       string
+	labels     []string
+	mu         sync.Mutex
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{metricName: name, help: help, labels: labels}
+}
+
+func (v *vec) name() string { return v.metricName }
+
+// key makes a map key for label values, panics on labels count mismatch as it is a programming error
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %q expects %d label values, got %d", v.metricName, len(v.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// labelsString formats labels as {name="value",...}, extra label appended if set, e.g. le for histogram buckets
+func (v *vec) labelsString(key string, extraName, extraValue string) string {
+	pairs := make([]string, 0, len(v.labels)+1)
+	if len(v.labels) > 0 {
+		for i, val := range strings.Split(key, "\xff") {
+			pairs = append(pairs, v.labels[i]+`="`+escapeLabel(val)+`"`)
+		}
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (v *vec) header(w io.Writer, typ string) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.metricName, escapeHelp(v.help), v.metricName, typ); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	return nil
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+type CounterVec struct {
+	vec
+	values map[string]*float64
+}
+
+// Inc increments counter for the given label values by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the given non-negative value to counter for the given label values. Negative values are ignored.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, ok := c.values[key]
+	if !ok {
+		val = new(float64)
+		c.values[key] = val
+	}
+	*val += v
+}
+
+// Value returns current counter value for the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if val, ok := c.values[key]; ok {
+		return *val
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.header(w, "counter"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(c.values) {
+		line := c.metricName + c.labelsString(key, "", "") + " " + formatFloat(*c.values[key]) + "\n"
+		if _, err := io.WriteString(w, line); err != nil {
+			return fmt.Errorf("failed to write value: %w", err)
+		}
+	}
+	return nil
+}
+
+// HistogramVec counts observations in configurable buckets, partitioned by labels.
+type HistogramVec struct {
+	vec
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64 // non-cumulative counts per bucket, last one is +Inf
+	count  uint64
+	sum    float64
+}
+
+// Observe adds a single observation to the histogram for the given label values.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	val, ok := h.values[key]
+	if !ok {
+		val = &histogramValue{counts: make([]uint64, len(h.buckets)+1)}
+		h.values[key] = val
+	}
+	idx := sort.SearchFloat64s(h.buckets, v) // first bucket with upper bound >= v
+	val.counts[idx]++
+	val.count++
+	val.sum += v
+}
+
+// Count returns the number of observations for the given label values.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if val, ok := h.values[key]; ok {
+		return val.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.header(w, "histogram"); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(h.values) {
+		val := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += val.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName,
+				h.labelsString(key, "le", formatFloat(upper)), cumulative); err != nil {
+				return fmt.Errorf("failed to write bucket: %w", err)
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.metricName, h.labelsString(key, "le", "+Inf"), val.count,
+			h.metricName, h.labelsString(key, "", ""), formatFloat(val.sum),
+			h.metricName, h.labelsString(key, "", ""), val.count)
+		if err != nil {
+			return fmt.Errorf("failed to write histogram totals: %w", err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
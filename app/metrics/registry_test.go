@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "test counter", "name", "result")
+
+	c.Inc("check1", "spam")
+	c.Inc("check1", "spam")
+	c.Add(2.5, "check2", "ham")
+	c.Add(-1, "check2", "ham") // negative ignored
+	c.Inc(`quo"te\`, "ha\nm")
+
+	assert.InDelta(t, 2, c.Value("check1", "spam"), 0.001)
+	assert.InDelta(t, 2.5, c.Value("check2", "ham"), 0.001)
+	assert.InDelta(t, 0, c.Value("check3", "ham"), 0.001)
+
+	buf := bytes.Buffer{}
+	require.NoError(t, r.Write(&buf))
+	exp := `# HELP test_total test counter
+# TYPE test_total counter
+test_total{name="check1",result="spam"} 2
+test_total{name="check2",result="ham"} 2.5
+test_total{name="quo\"te\\",result="ha\nm"} 1
+`
+	assert.Equal(t, exp, buf.String())
+
+	assert.Panics(t, func() { c.Inc("only-one") }, "label values count mismatch")
+}
+
+func TestCounterVec_NoLabels(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("plain_total", "plain counter")
+	c.Inc()
+	c.Inc()
+
+	buf := bytes.Buffer{}
+	require.NoError(t, r.Write(&buf))
+	assert.Equal(t, "# HELP plain_total plain counter\n# TYPE plain_total counter\nplain_total 2\n", buf.String())
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_seconds", "test histogram", []float64{1, 0.1, 0.5}, "kind")
+
+	h.Observe(0.05, "a")
+	h.Observe(0.1, "a") // upper bound is inclusive
+	h.Observe(0.7, "a")
+	h.Observe(5, "a")
+	h.Observe(0.3, "b")
+
+	assert.Equal(t, uint64(4), h.Count("a"))
+	assert.Equal(t, uint64(1), h.Count("b"))
+	assert.Equal(t, uint64(0), h.Count("c"))
+
+	buf := bytes.Buffer{}
+	require.NoError(t, r.Write(&buf))
+	exp := `# HELP test_seconds test histogram
+# TYPE test_seconds histogram
+test_seconds_bucket{kind="a",le="0.1"} 2
+test_seconds_bucket{kind="a",le="0.5"} 2
+test_seconds_bucket{kind="a",le="1"} 3
+test_seconds_bucket{kind="a",le="+Inf"} 4
+test_seconds_sum{kind="a"} 5.85
+test_seconds_count{kind="a"} 4
+test_seconds_bucket{kind="b",le="0.1"} 0
+test_seconds_bucket{kind="b",le="0.5"} 1
+test_seconds_bucket{kind="b",le="1"} 1
+test_seconds_bucket{kind="b",le="+Inf"} 1
+test_seconds_sum{kind="b"} 0.3
+test_seconds_count{kind="b"} 1
+`
+	assert.Equal(t, exp, buf.String())
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("b_total", "second")
+	r.NewHistogramVec("a_seconds", "first", []float64{1})
+	assert.Panics(t, func() { r.NewCounterVec("b_total", "duplicate") })
+
+	t.Run("sorted by name", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		require.NoError(t, r.Write(&buf))
+		assert.Equal(t, "# HELP a_seconds first\n# TYPE a_seconds histogram\n# HELP b_total second\n# TYPE b_total counter\n",
+			buf.String())
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		r.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Body.String(), "# TYPE b_total counter")
+	})
+}
+
+func TestCounterVec_Concurrent(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("concurrent_total", "concurrent counter", "name")
+	h := r.NewHistogramVec("concurrent_seconds", "concurrent histogram", []float64{1}, "name")
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				c.Inc("x")
+				h.Observe(0.5, "x")
+				_ = r.Write(&bytes.Buffer{})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.InDelta(t, 1000, c.Value("x"), 0.001)
+	assert.Equal(t, uint64(1000), h.Count("x"))
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+)
+
+// TelegramAPI is the subset of telegram bot api used by the listener
+type TelegramAPI interface {
+	GetUpdatesChan(config tbapi.UpdateConfig) tbapi.UpdatesChannel
+	Send(c tbapi.Chattable) (tbapi.Message, error)
+	Request(c tbapi.Chattable) (*tbapi.APIResponse, error)
+	GetChat(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error)
+	GetChatAdministrators(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error)
+	GetFileDirectURL(fileID string) (string, error)
+}
+
+// Telegram wraps telegram bot api and counts requests and errors by method
+type Telegram struct {
+	TelegramAPI
+}
+
+// Send calls the wrapped api and records the request status
+func (t *Telegram) Send(c tbapi.Chattable) (tbapi.Message, error) {
+	res, err := t.TelegramAPI.Send(c)
+	ObserveTelegram(chattableMethod(c), err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+// Request calls the wrapped api and records the request status
+func (t *Telegram) Request(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+	res, err := t.TelegramAPI.Request(c)
+	ObserveTelegram(chattableMethod(c), err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+// GetChat calls the wrapped api and records the request status
+func (t *Telegram) GetChat(config tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+	res, err := t.TelegramAPI.GetChat(config)
+	ObserveTelegram("GetChat", err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+// GetChatAdministrators calls the wrapped api and records the request status
+func (t *Telegram) GetChatAdministrators(config tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+	res, err := t.TelegramAPI.GetChatAdministrators(config)
+	ObserveTelegram("GetChatAdministrators", err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+// GetFileDirectURL calls the wrapped api and records the request status
+func (t *Telegram) GetFileDirectURL(fileID string) (string, error) {
+	res, err := t.TelegramAPI.GetFileDirectURL(fileID)
+	ObserveTelegram("GetFile", err)
+	return res, err //nolint:wrapcheck // transparent wrapper
+}
+
+// chattableMethod makes a method label from the config type, e.g. tbapi.BanChatMemberConfig -> BanChatMember.
+// The api method name itself is not exported by the library.
+func chattableMethod(c tbapi.Chattable) string {
+	name := fmt.Sprintf("%T", c)
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.TrimSuffix(name, "Config")
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	tbapi "github.com/OvyFlash/telegram-bot-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelegram(t *testing.T) {
+	api := &telegramStub{}
+	sendBefore := TelegramRequests.Value("Message", "ok")
+	banBefore := TelegramRequests.Value("BanChatMember", "error")
+	delBefore := TelegramRequests.Value("DeleteMessage", "ok")
+	chatBefore := TelegramRequests.Value("GetChat", "ok")
+	adminsBefore := TelegramRequests.Value("GetChatAdministrators", "error")
+	fileBefore := TelegramRequests.Value("GetFile", "ok")
+
+	tg := &Telegram{TelegramAPI: api}
+	msg, err := tg.Send(tbapi.NewMessage(1, "text"))
+	require.NoError(t, err)
+	assert.Equal(t, "sent", msg.Text)
+
+	_, err = tg.Request(tbapi.BanChatMemberConfig{})
+	require.EqualError(t, err, "not enough rights")
+	_, err = tg.Request(tbapi.DeleteMessageConfig{})
+	require.NoError(t, err)
+
+	chat, err := tg.GetChat(tbapi.ChatInfoConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(123), chat.ID)
+	_, err = tg.GetChatAdministrators(tbapi.ChatAdministratorsConfig{})
+	require.Error(t, err)
+	fileURL, err := tg.GetFileDirectURL("file1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/file1", fileURL)
+
+	assert.InDelta(t, sendBefore+1, TelegramRequests.Value("Message", "ok"), 0.001)
+	assert.InDelta(t, banBefore+1, TelegramRequests.Value("BanChatMember", "error"), 0.001)
+	assert.InDelta(t, delBefore+1, TelegramRequests.Value("DeleteMessage", "ok"), 0.001)
+	assert.InDelta(t, chatBefore+1, TelegramRequests.Value("GetChat", "ok"), 0.001)
+	assert.InDelta(t, adminsBefore+1, TelegramRequests.Value("GetChatAdministrators", "error"), 0.001)
+	assert.InDelta(t, fileBefore+1, TelegramRequests.Value("GetFile", "ok"), 0.001)
+	assert.Equal(t, 2, api.requests)
+}
+
+func TestChattableMethod(t *testing.T) {
+	assert.Equal(t, "Message", chattableMethod(tbapi.NewMessage(1, "text")))
+	assert.Equal(t, "BanChatMember", chattableMethod(tbapi.BanChatMemberConfig{}))
+	assert.Equal(t, "RestrictChatMember", chattableMethod(&tbapi.RestrictChatMemberConfig{}))
+	assert.Equal(t, "DeleteMessage", chattableMethod(tbapi.DeleteMessageConfig{}))
+}
+
+// telegramStub is a minimal TelegramAPI implementation, events/mocks can't be used here due to import cycle
+type telegramStub struct {
+	requests int
+}
+
+func (s *telegramStub) Send(tbapi.Chattable) (tbapi.Message, error) {
+	return tbapi.Message{Text: "sent"}, nil
+}
+
+func (s *telegramStub) Request(c tbapi.Chattable) (*tbapi.APIResponse, error) {
+	s.requests++
+	if _, ok := c.(tbapi.BanChatMemberConfig); ok {
+		return nil, errors.New("not enough rights")
+	}
+	return &tbapi.APIResponse{Ok: true}, nil
+}
+
+func (s *telegramStub) GetChat(tbapi.ChatInfoConfig) (tbapi.ChatFullInfo, error) {
+	return tbapi.ChatFullInfo{Chat: tbapi.Chat{ID: 123}}, nil
+}
+
+func (s *telegramStub) GetChatAdministrators(tbapi.ChatAdministratorsConfig) ([]tbapi.ChatMember, error) {
+	return nil, errors.New("failed")
+}
+
+func (s *telegramStub) GetUpdatesChan(tbapi.UpdateConfig) tbapi.UpdatesChannel { return nil }
+
+func (s *telegramStub) GetFileDirectURL(fileID string) (string, error) {
+	return "https://example.com/" + fileID, nil
+}
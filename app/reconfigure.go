@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/umputun/tg-spam/app/bot"
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/events"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+// liveSettings lists settings applied to the running instance without restart, a group path covers all its fields.
+// Detector settings are applied by rebuilding detectors, listener flags are picked up before the next update.
+var liveSettings = []string{
+	// detector
+	"SimilarityThreshold", "MinMsgLen", "MaxShortMsgCount", "MaxEmoji", "MinSpamProbability", "MultiLangWords",
+	"ObfuscatedWords", "NormalizeText", "ProhibitedLangs", "ProhibitedLangsMin", "ParanoidMode", "FirstMessagesCount",
+	"History.Size", "Meta", "CAS.API", "CAS.UserAgent", "CAS.Timeout", "OpenAI", "Gemini", "LLM.Consensus",
+	"LLM.RequestTimeout", "LLM.Providers", "Review.MinProbability", "Review.LLMDisagreement", "Scoring",
+	"AbnormalSpace", "Duplicates", "Reactions", "Domains", "LuaPlugins", "ImageHash.Distance",
+
+	// listener
+	"Message.Warn", "NoSpamReply", "SuppressJoinMessage", "Delete", "Training", "SoftBan",
+	"Admin.DisableAdminSpamForward", "AggressiveCleanup", "AggressiveCleanupLimit",
+}
+
+// liveConfig applies changed settings to the running detectors and telegram listener
+type liveConfig struct {
+	detectors     []liveDetector
+	listener      *events.TelegramListener // nil in server-only mode
+	imageHashes   *storage.ImageHashes     // nil if image hash check disabled
+	federatedBans *storage.FederatedBans   // nil if federation disabled
+}
+
+// liveDetector is a running detector with the bot using it, group is empty for the primary group
+type liveDetector struct {
+	group    string
+	detector *tgspam.Detector
+	bot      *bot.SpamFilter
+}
+
+// apply applies settings changed from prev to the running instance and returns settings which require restart.
+// Detectors for all groups are made first, and if any of them can't be made, e.g. because of a bad LLM provider,
+// nothing is applied and all changed settings are reported as requiring restart.
+func (c *liveConfig) apply(prev, settings *config.Settings) (restart []string) {
+	var live []string
+	for _, path := range prev.Diff(settings) {
+		if isLiveSetting(path) {
+			live = append(live, path)
+			continue
+		}
+		restart = append(restart, path)
+	}
+	if len(live) == 0 {
+		return restart
+	}
+
+	made := make([]*tgspam.Detector, 0, len(c.detectors))
+	for _, ld := range c.detectors {
+		gs := settings
+		if ld.group != "" {
+			idx := slices.IndexFunc(settings.Groups, func(g config.GroupSettings) bool { return g.Group == ld.group })
+			if idx < 0 {
+				made = append(made, nil) // group removed, takes effect on restart
+				continue
+			}
+			gs = settings.ForGroup(settings.Groups[idx])
+		}
+		detector, err := c.makeDetector(gs)
+		if err != nil {
+			log.Printf("[WARN] can't apply settings to detector of group %q, %v", ld.group, err)
+			for _, d := range made {
+				if d != nil {
+					d.Reset() // closes lua engine of the detector
+				}
+			}
+			return append(live, restart...)
+		}
+		made = append(made, detector)
+	}
+
+	for i, ld := range c.detectors {
+		if made[i] == nil {
+			continue
+		}
+		ld.detector.Reconfigure(made[i])
+		if prev.NormalizeText != settings.NormalizeText && ld.bot != nil {
+			// samples are normalized on load, reload them to match normalized messages
+			if err := ld.bot.ReloadSamples(); err != nil {
+				log.Printf("[WARN] can't reload samples of group %q, %v", ld.group, err)
+			}
+		}
+	}
+	if c.listener != nil {
+		c.listener.Reconfigure(listenerFlags(settings))
+	}
+	log.Printf("[INFO] settings applied: %v, restart required: %v", live, restart)
+	return restart
+}
+
+// makeDetector makes detector for the settings, with checks backed by the stores made on startup
+func (c *liveConfig) makeDetector(settings *config.Settings) (*tgspam.Detector, error) {
+	detector, err := newDetector(settings)
+	if err != nil {
+		return nil, err
+	}
+	if c.imageHashes != nil {
+		detector.WithMetaChecks(tgspam.ImageHashCheck(c.imageHashes, settings.ImageHash.Distance))
+	}
+	if c.federatedBans != nil {
+		detector.WithMetaChecks(tgspam.FederationCheck(c.federatedBans, federationTrust(settings)))
+	}
+	return detector, nil
+}
+
+// isLiveSetting checks if the setting path is one of liveSettings or a field of one of them
+func isLiveSetting(path string) bool {
+	for _, l := range liveSettings {
+		if path == l || strings.HasPrefix(path, l+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// listenerFlags returns listener flags for the settings
+func listenerFlags(settings *config.Settings) events.ListenerFlags {
+	return events.ListenerFlags{
+		WarnMsg:                 settings.Message.Warn,
+		NoSpamReply:             settings.NoSpamReply,
+		SuppressJoinMessage:     settings.SuppressJoinMessage,
+		DeleteJoinMessages:      settings.Delete.JoinMessages,
+		DeleteLeaveMessages:     settings.Delete.LeaveMessages,
+		TrainingMode:            settings.Training,
+		SoftBanMode:             settings.SoftBan,
+		DisableAdminSpamForward: settings.Admin.DisableAdminSpamForward,
+		AggressiveCleanup:       settings.AggressiveCleanup,
+		AggressiveCleanupLimit:  settings.AggressiveCleanupLimit,
+	}
+}
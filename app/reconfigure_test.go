@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/events"
+)
+
+func Test_liveConfig_apply(t *testing.T) {
+	makeLive := func(t *testing.T, prev *config.Settings) *liveConfig {
+		t.Helper()
+		primary, err := newDetector(prev)
+		require.NoError(t, err)
+		second, err := newDetector(prev.ForGroup(prev.Groups[0]))
+		require.NoError(t, err)
+		return &liveConfig{detectors: []liveDetector{{detector: primary}, {group: "second", detector: second}},
+			listener: &events.TelegramListener{}}
+	}
+	makePrev := func() *config.Settings {
+		prev := makeTestSettings()
+		prev.MinMsgLen = 10
+		prev.SimilarityThreshold = 0.5
+		minLen := 20
+		prev.Groups = []config.GroupSettings{{Group: "second", Overrides: config.GroupOverrides{MinMsgLen: &minLen}}}
+		return prev
+	}
+
+	t.Run("nothing changed", func(t *testing.T) {
+		prev := makePrev()
+		live := makeLive(t, prev)
+		settings := *prev
+		assert.Empty(t, live.apply(prev, &settings))
+	})
+
+	t.Run("live and restart settings", func(t *testing.T) {
+		prev := makePrev()
+		live := makeLive(t, prev)
+		settings := *prev
+		settings.SimilarityThreshold = 0.8
+		settings.MinMsgLen = 15
+		settings.Meta.Forward = true
+		settings.Training = true
+		settings.Telegram.Token = "new-token"
+		settings.History.Duration = 10
+
+		restart := live.apply(prev, &settings)
+		assert.Equal(t, []string{"Telegram.Token", "History.Duration"}, restart)
+		assert.InDelta(t, 0.8, live.detectors[0].detector.SimilarityThreshold, 1e-9)
+		assert.Equal(t, 15, live.detectors[0].detector.MinMsgLen)
+		assert.InDelta(t, 0.8, live.detectors[1].detector.SimilarityThreshold, 1e-9, "group inherits changed setting")
+		assert.Equal(t, 20, live.detectors[1].detector.MinMsgLen, "group override kept")
+	})
+
+	t.Run("bad llm provider", func(t *testing.T) {
+		prev := makePrev()
+		live := makeLive(t, prev)
+		settings := *prev
+		settings.MinMsgLen = 15
+		settings.LLM.Providers = []config.LLMProviderSettings{{Name: "x", Type: "magic"}}
+
+		restart := live.apply(prev, &settings)
+		assert.Equal(t, []string{"LLM.Providers", "MinMsgLen"}, restart, "nothing applied")
+		assert.Equal(t, 10, live.detectors[0].detector.MinMsgLen)
+	})
+}
+
+func Test_isLiveSetting(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "MinMsgLen", want: true},
+		{path: "Meta.LinksLimit", want: true},
+		{path: "LLM.Providers", want: true},
+		{path: "Message.Warn", want: true},
+		{path: "Message.Startup", want: false},
+		{path: "MetaData", want: false},
+		{path: "Telegram.Token", want: false},
+		{path: "Dry", want: false},
+		{path: "Groups", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, isLiveSetting(tt.path))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"reflect"
 	"strconv"
 	"time"
@@ -21,6 +22,7 @@ func optToSettings(opts options) *config.Settings {
 			Group:        opts.Telegram.Group,
 			IdleDuration: opts.Telegram.IdleDuration,
 			Timeout:      opts.Telegram.Timeout,
+			WebhookURL:   opts.Telegram.WebhookURL,
 		},
 
 		Admin: config.AdminSettings{
@@ -44,9 +46,10 @@ func optToSettings(opts options) *config.Settings {
 		},
 
 		CAS: config.CASSettings{
-			API:       opts.CAS.API,
-			Timeout:   opts.CAS.Timeout,
-			UserAgent: opts.CAS.UserAgent,
+			API:          opts.CAS.API,
+			Timeout:      opts.CAS.Timeout,
+			UserAgent:    opts.CAS.UserAgent,
+			SyncInterval: opts.CAS.SyncInterval,
 		},
 
 		Meta: config.MetaSettings{
@@ -96,6 +99,9 @@ func optToSettings(opts options) *config.Settings {
 		LLM: config.LLMSettings{
 			Consensus:      opts.LLM.Consensus,
 			RequestTimeout: opts.LLM.RequestTimeout,
+			CacheTTL:       opts.LLM.CacheTTL,
+			CacheSize:      opts.LLM.CacheSize,
+			CachePersist:   opts.LLM.CachePersist,
 		},
 
 		Delete: config.DeleteSettings{
@@ -126,6 +132,68 @@ func optToSettings(opts options) *config.Settings {
 			Window:    opts.Warn.Window,
 		},
 
+		Punish: config.PunishSettings{
+			Enabled:  opts.Punish.Enabled,
+			Mute:     opts.Punish.Mute,
+			Restrict: opts.Punish.Restrict,
+			Window:   opts.Punish.Window,
+		},
+
+		Captcha: config.CaptchaSettings{
+			Enabled:      opts.Captcha.Enabled,
+			Type:         opts.Captcha.Type,
+			Timeout:      opts.Captcha.Timeout,
+			Action:       opts.Captcha.Action,
+			ApproveCount: opts.Captcha.ApproveCount,
+		},
+
+		OCR: config.OCRSettings{
+			Type:      opts.OCR.Type,
+			Tesseract: opts.OCR.Tesseract,
+			Lang:      opts.OCR.Lang,
+			URL:       opts.OCR.URL,
+			Timeout:   opts.OCR.Timeout,
+		},
+
+		ImageHash: config.ImageHashSettings{
+			Enabled:  opts.ImageHash.Enabled,
+			Distance: opts.ImageHash.Distance,
+		},
+
+		Scoring: config.ScoringSettings{
+			Enabled:             opts.Scoring.Enabled,
+			DefaultWeight:       opts.Scoring.DefaultWeight,
+			Threshold:           opts.Scoring.Threshold,
+			SuspiciousThreshold: opts.Scoring.SuspiciousThreshold,
+		},
+
+		Review: config.ReviewSettings{
+			Enabled:         opts.Review.Enabled,
+			MinProbability:  opts.Review.MinProbability,
+			LLMDisagreement: opts.Review.LLMDisagreement,
+		},
+
+		Reputation: config.ReputationSettings{
+			Enabled:      opts.Reputation.Enabled,
+			ApprovalHold: opts.Reputation.ApprovalHold,
+		},
+
+		Federation: config.FederationSettings{
+			Enabled:      opts.Federation.Enabled,
+			KeyFile:      opts.Federation.KeyFile,
+			FeedWindow:   opts.Federation.FeedWindow,
+			PullInterval: opts.Federation.PullInterval,
+		},
+
+		Domains: config.DomainsSettings{
+			ExpandShort: opts.Domains.ExpandShort,
+		},
+
+		Hooks: config.HooksSettings{
+			MaxAttempts: opts.Hooks.MaxAttempts,
+			RetryDelay:  opts.Hooks.RetryDelay,
+		},
+
 		LuaPlugins: config.LuaPluginsSettings{
 			Enabled:        opts.LuaPlugins.Enabled,
 			PluginsDir:     opts.LuaPlugins.PluginsDir,
@@ -165,6 +233,8 @@ func optToSettings(opts options) *config.Settings {
 		MaxEmoji:               opts.MaxEmoji,
 		MinSpamProbability:     opts.MinSpamProbability,
 		MultiLangWords:         opts.MultiLangWords,
+		ObfuscatedWords:        opts.ObfuscatedWords,
+		NormalizeText:          opts.NormalizeText,
 		ProhibitedLangs:        opts.ProhibitedLangs,
 		ProhibitedLangsMin:     opts.ProhibitedLangsMin,
 		NoSpamReply:            opts.NoSpamReply,
@@ -193,8 +263,10 @@ func optToSettings(opts options) *config.Settings {
 
 	// set credentials in their respective domain structures
 	settings.Telegram.Token = opts.Telegram.Token
+	settings.Telegram.WebhookSecret = opts.Telegram.WebhookSecret
 	settings.OpenAI.Token = opts.OpenAI.Token
 	settings.Gemini.Token = opts.Gemini.Token
+	settings.Hooks.Secret = opts.Hooks.Secret
 	settings.Server.AuthHash = opts.Server.AuthHash
 
 	return settings
@@ -333,12 +405,18 @@ func applyCLIOverrides(settings *config.Settings, opts options, defaults *config
 	if opts.Telegram.Token != "" {
 		settings.Telegram.Token = opts.Telegram.Token
 	}
+	if opts.Telegram.WebhookSecret != "" {
+		settings.Telegram.WebhookSecret = opts.Telegram.WebhookSecret
+	}
 	if opts.OpenAI.Token != "" {
 		settings.OpenAI.Token = opts.OpenAI.Token
 	}
 	if opts.Gemini.Token != "" {
 		settings.Gemini.Token = opts.Gemini.Token
 	}
+	if opts.Hooks.Secret != "" {
+		settings.Hooks.Secret = opts.Hooks.Secret
+	}
 
 	// override auth password if explicitly provided (not using default "auto")
 	if opts.Server.AuthPasswd != "auto" {
@@ -396,6 +474,53 @@ func applyOperationalCLIOverrides(settings *config.Settings, opts options, defau
 	}
 }
 
+// applyCLIListOverrides applies lists set on CLI, e.g. additional groups and LLM providers. A list set on CLI
+// replaces the whole list loaded from the database or config file, an empty CLI list keeps the loaded one.
+func applyCLIListOverrides(settings *config.Settings, opts options) error {
+	if len(opts.Telegram.ExtraGroups) > 0 {
+		groups, err := config.ParseGroupSpecs(opts.Telegram.ExtraGroups)
+		if err != nil {
+			return fmt.Errorf("invalid extra group: %w", err)
+		}
+		settings.Groups = groups
+	}
+
+	if len(opts.LLM.Providers) > 0 {
+		providers, err := config.ParseLLMProviderSpecs(opts.LLM.Providers)
+		if err != nil {
+			return fmt.Errorf("invalid llm provider: %w", err)
+		}
+		settings.LLM.Providers = providers
+	}
+
+	if len(opts.Federation.Peers) > 0 {
+		peers, err := config.ParseFederationPeerSpecs(opts.Federation.Peers)
+		if err != nil {
+			return fmt.Errorf("invalid federation peer: %w", err)
+		}
+		settings.Federation.Peers = peers
+	}
+
+	if len(opts.Domains.Shorteners) > 0 {
+		settings.Domains.Shorteners = opts.Domains.Shorteners
+	}
+	if len(opts.Punish.Permanent) > 0 {
+		settings.Punish.Permanent = opts.Punish.Permanent
+	}
+	if len(opts.Hooks.URLs) > 0 {
+		settings.Hooks.URLs = opts.Hooks.URLs
+	}
+
+	if len(opts.Scoring.Weights) > 0 {
+		weights, err := config.ParseScoringWeights(opts.Scoring.Weights)
+		if err != nil {
+			return fmt.Errorf("invalid scoring weight: %w", err)
+		}
+		settings.Scoring.Weights = weights
+	}
+	return nil
+}
+
 // applyAutoAuthFallback enables auto-generated password mode as a safety net
 // when --confdb leaves the web UI without any auth material. It only fires
 // when the server is enabled and no hash/password is present anywhere AND
@@ -511,6 +636,37 @@ func loadConfigFromDB(ctx context.Context, settings, defaults *config.Settings)
 	return nil
 }
 
+// loadConfigFromFile loads configuration from the yaml file, see config.LoadYAML for the format.
+// Same as loadConfigFromDB, transient values are kept, the CLI instance id is used if the file doesn't set one,
+// and fields not set in the file are filled from defaults. A nil defaults template disables the fill step.
+func loadConfigFromFile(path string, settings, defaults *config.Settings) error {
+	log.Printf("[INFO] loading configuration from %s", path)
+
+	fh, err := os.Open(path) //nolint:gosec // path is set by operator
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer fh.Close()
+
+	fileSettings, err := config.LoadYAML(fh)
+	if err != nil {
+		return fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+
+	transient, instanceID := settings.Transient, settings.InstanceID
+	*settings = *fileSettings
+	settings.Transient = transient
+	if settings.InstanceID == "" {
+		settings.InstanceID = instanceID
+	} else if instanceID != "" && settings.InstanceID != instanceID {
+		log.Printf("[WARN] instance_id %q from config file differs from CLI value %q, using the one from file",
+			settings.InstanceID, instanceID)
+	}
+
+	settings.ApplyDefaults(defaults)
+	return nil
+}
+
 // saveConfigToDB saves the current configuration to the database
 func saveConfigToDB(ctx context.Context, settings *config.Settings) error {
 	log.Print("[INFO] saving configuration to database")
@@ -554,8 +710,8 @@ func saveConfigToDB(ctx context.Context, settings *config.Settings) error {
 		settings.Server.AuthHash = hash
 	}
 
-	// save settings to database
-	if err := settingsStore.Save(ctx, settings); err != nil {
+	// save settings to database, the revision has no author as it is not made in web UI
+	if err := settingsStore.Save(config.WithRevisionInfo(ctx, "", "save-config"), settings); err != nil {
 		return fmt.Errorf("failed to save configuration to database: %w", err)
 	}
 
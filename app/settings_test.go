@@ -137,6 +137,26 @@ func TestApplyCLIOverrides(t *testing.T) {
 		assert.Equal(t, "db-token", settings.Telegram.Token, "DB telegram token must survive when CLI is empty")
 	})
 
+	t.Run("webhook secret CLI overrides DB value", func(t *testing.T) {
+		settings := config.Settings{Telegram: config.TelegramSettings{WebhookSecret: "db-secret"}}
+		opts := newDefaultOpts(t)
+		applyCLIOverrides(&settings, opts, defaults)
+		assert.Equal(t, "db-secret", settings.Telegram.WebhookSecret, "DB webhook secret must survive when CLI is empty")
+		opts.Telegram.WebhookSecret = "cli-secret"
+		applyCLIOverrides(&settings, opts, defaults)
+		assert.Equal(t, "cli-secret", settings.Telegram.WebhookSecret, "CLI webhook secret must override DB value")
+	})
+
+	t.Run("hooks secret CLI overrides DB value", func(t *testing.T) {
+		settings := config.Settings{Hooks: config.HooksSettings{Secret: "db-secret"}}
+		opts := newDefaultOpts(t)
+		applyCLIOverrides(&settings, opts, defaults)
+		assert.Equal(t, "db-secret", settings.Hooks.Secret, "DB hooks secret must survive when CLI is empty")
+		opts.Hooks.Secret = "cli-secret"
+		applyCLIOverrides(&settings, opts, defaults)
+		assert.Equal(t, "cli-secret", settings.Hooks.Secret, "CLI hooks secret must override DB value")
+	})
+
 	t.Run("openai token CLI overrides DB value", func(t *testing.T) {
 		settings := config.Settings{OpenAI: config.OpenAISettings{Token: "db-openai"}}
 		opts := newDefaultOpts(t)
@@ -266,6 +286,8 @@ func TestOptToSettings(t *testing.T) {
 		o.MinSpamProbability = 0.8
 		o.SimilarityThreshold = 0.9
 		o.MultiLangWords = 3
+		o.ObfuscatedWords = 2
+		o.NormalizeText = true
 		o.ProhibitedLangs = "chinese,cyrillic"
 		o.ProhibitedLangsMin = 2
 		o.NoSpamReply = true
@@ -297,6 +319,8 @@ func TestOptToSettings(t *testing.T) {
 		o.Telegram.Group = "test-group"
 		o.Telegram.IdleDuration = 5 * time.Minute
 		o.Telegram.Timeout = 30 * time.Second
+		o.Telegram.WebhookURL = "https://bot.example.com/telegram/webhook"
+		o.Telegram.WebhookSecret = "webhook-secret"
 
 		o.Logger.Enabled = true
 		o.Logger.FileName = "test.log"
@@ -306,6 +330,7 @@ func TestOptToSettings(t *testing.T) {
 		o.CAS.API = "https://cas.example.com"
 		o.CAS.Timeout = 10 * time.Second
 		o.CAS.UserAgent = "test-agent"
+		o.CAS.SyncInterval = 6 * time.Hour
 
 		o.Meta.LinksLimit = 2
 		o.Meta.MentionsLimit = 3
@@ -349,6 +374,9 @@ func TestOptToSettings(t *testing.T) {
 
 		o.LLM.Consensus = "all"
 		o.LLM.RequestTimeout = 45 * time.Second
+		o.LLM.CacheTTL = 6 * time.Hour
+		o.LLM.CacheSize = 500
+		o.LLM.CachePersist = true
 
 		o.Delete.JoinMessages = true
 		o.Delete.LeaveMessages = true
@@ -365,6 +393,42 @@ func TestOptToSettings(t *testing.T) {
 		o.Warn.Threshold = 3
 		o.Warn.Window = 12 * time.Hour
 
+		o.Punish.Enabled = true
+		o.Punish.Mute = 5 * time.Minute
+		o.Punish.Restrict = 0
+		o.Punish.Window = 48 * time.Hour
+		o.Punish.Permanent = []string{"cas"} // lists are applied by main, not by optToSettings
+
+		o.Captcha.Enabled = true
+		o.Captcha.Type = "math"
+		o.Captcha.Timeout = 2 * time.Minute
+		o.Captcha.Action = "kick"
+		o.Captcha.ApproveCount = 2
+
+		o.OCR.Type = "http"
+		o.OCR.URL = "http://ocr:8080"
+		o.OCR.Timeout = 10 * time.Second
+
+		o.ImageHash.Enabled = true
+		o.ImageHash.Distance = 7
+		o.Scoring.Enabled = true
+		o.Scoring.DefaultWeight = 0.5
+		o.Scoring.Threshold = 2
+		o.Scoring.SuspiciousThreshold = 1
+		o.Review.Enabled = true
+		o.Review.MinProbability = 40
+		o.Review.LLMDisagreement = true
+		o.Reputation.Enabled = true
+		o.Reputation.ApprovalHold = 48 * time.Hour
+		o.Federation.Enabled = true
+		o.Federation.KeyFile = "/keys/federation.key"
+		o.Federation.FeedWindow = 240 * time.Hour
+		o.Federation.PullInterval = 30 * time.Minute
+		o.Domains.ExpandShort = true
+		o.Hooks.Secret = "hooks-secret"
+		o.Hooks.MaxAttempts = 5
+		o.Hooks.RetryDelay = time.Minute
+
 		o.LuaPlugins.Enabled = true
 		o.LuaPlugins.PluginsDir = "/custom/plugins"
 		o.LuaPlugins.EnabledPlugins = []string{"plugin1", "plugin2"}
@@ -410,6 +474,8 @@ func TestOptToSettings(t *testing.T) {
 				assert.InEpsilon(t, 0.8, settings.MinSpamProbability, 0.0001)
 				assert.InEpsilon(t, 0.9, settings.SimilarityThreshold, 0.0001)
 				assert.Equal(t, 3, settings.MultiLangWords)
+				assert.Equal(t, 2, settings.ObfuscatedWords)
+				assert.True(t, settings.NormalizeText)
 				assert.Equal(t, "chinese,cyrillic", settings.ProhibitedLangs)
 				assert.Equal(t, 2, settings.ProhibitedLangsMin)
 				assert.True(t, settings.NoSpamReply)
@@ -429,6 +495,8 @@ func TestOptToSettings(t *testing.T) {
 				assert.Equal(t, "test-group", settings.Telegram.Group)
 				assert.Equal(t, 5*time.Minute, settings.Telegram.IdleDuration)
 				assert.Equal(t, 30*time.Second, settings.Telegram.Timeout)
+				assert.Equal(t, "https://bot.example.com/telegram/webhook", settings.Telegram.WebhookURL)
+				assert.Equal(t, "webhook-secret", settings.Telegram.WebhookSecret)
 
 				// admin settings
 				assert.Equal(t, "123456", settings.Admin.AdminGroup)
@@ -451,6 +519,7 @@ func TestOptToSettings(t *testing.T) {
 				assert.Equal(t, "https://cas.example.com", settings.CAS.API)
 				assert.Equal(t, 10*time.Second, settings.CAS.Timeout)
 				assert.Equal(t, "test-agent", settings.CAS.UserAgent)
+				assert.Equal(t, 6*time.Hour, settings.CAS.SyncInterval)
 
 				// meta settings
 				assert.Equal(t, 2, settings.Meta.LinksLimit)
@@ -498,6 +567,9 @@ func TestOptToSettings(t *testing.T) {
 				// llm settings
 				assert.Equal(t, "all", settings.LLM.Consensus)
 				assert.Equal(t, 45*time.Second, settings.LLM.RequestTimeout)
+				assert.Equal(t, 6*time.Hour, settings.LLM.CacheTTL)
+				assert.Equal(t, 500, settings.LLM.CacheSize)
+				assert.True(t, settings.LLM.CachePersist)
 
 				// delete settings
 				assert.True(t, settings.Delete.JoinMessages)
@@ -518,6 +590,34 @@ func TestOptToSettings(t *testing.T) {
 				assert.Equal(t, 3, settings.Warn.Threshold)
 				assert.Equal(t, 12*time.Hour, settings.Warn.Window)
 
+				// punishment ladder settings
+				assert.Equal(t, config.PunishSettings{Enabled: true, Mute: 5 * time.Minute, Window: 48 * time.Hour},
+					settings.Punish)
+
+				// captcha settings
+				assert.True(t, settings.Captcha.Enabled)
+				assert.Equal(t, "math", settings.Captcha.Type)
+				assert.Equal(t, 2*time.Minute, settings.Captcha.Timeout)
+				assert.Equal(t, "kick", settings.Captcha.Action)
+				assert.Equal(t, 2, settings.Captcha.ApproveCount)
+
+				// ocr settings
+				assert.Equal(t, "http", settings.OCR.Type)
+				assert.Equal(t, "http://ocr:8080", settings.OCR.URL)
+				assert.Equal(t, 10*time.Second, settings.OCR.Timeout)
+
+				// image hash settings
+				assert.True(t, settings.ImageHash.Enabled)
+				assert.Equal(t, 7, settings.ImageHash.Distance)
+				assert.Equal(t, config.ScoringSettings{Enabled: true, DefaultWeight: 0.5, Threshold: 2, SuspiciousThreshold: 1},
+					settings.Scoring)
+				assert.Equal(t, config.ReviewSettings{Enabled: true, MinProbability: 40, LLMDisagreement: true}, settings.Review)
+				assert.Equal(t, config.ReputationSettings{Enabled: true, ApprovalHold: 48 * time.Hour}, settings.Reputation)
+				assert.Equal(t, config.FederationSettings{Enabled: true, KeyFile: "/keys/federation.key", FeedWindow: 240 * time.Hour,
+					PullInterval: 30 * time.Minute}, settings.Federation)
+				assert.Equal(t, config.HooksSettings{Secret: "hooks-secret", MaxAttempts: 5, RetryDelay: time.Minute}, settings.Hooks)
+				assert.Equal(t, config.DomainsSettings{ExpandShort: true}, settings.Domains)
+
 				// lua plugins settings
 				assert.True(t, settings.LuaPlugins.Enabled)
 				assert.Equal(t, "/custom/plugins", settings.LuaPlugins.PluginsDir)
@@ -600,6 +700,38 @@ func TestOptToSettings(t *testing.T) {
 		assert.Equal(t, 0, settings.Warn.Threshold, "default threshold must be 0 (disabled)")
 		assert.Equal(t, 720*time.Hour, settings.Warn.Window, "default window must match struct tag")
 	})
+
+	t.Run("captcha struct-tag defaults flow through", func(t *testing.T) {
+		var o options
+		require.NoError(t, applyStructTagDefaults(reflect.ValueOf(&o).Elem()))
+		settings := optToSettings(o)
+		assert.False(t, settings.Captcha.Enabled)
+		assert.Equal(t, "button", settings.Captcha.Type)
+		assert.Equal(t, 5*time.Minute, settings.Captcha.Timeout)
+		assert.Equal(t, "ban", settings.Captcha.Action)
+		assert.Equal(t, 0, settings.Captcha.ApproveCount)
+	})
+
+	t.Run("ocr struct-tag defaults flow through", func(t *testing.T) {
+		var o options
+		require.NoError(t, applyStructTagDefaults(reflect.ValueOf(&o).Elem()))
+		settings := optToSettings(o)
+		assert.Equal(t, "none", settings.OCR.Type)
+		assert.Equal(t, "tesseract", settings.OCR.Tesseract)
+		assert.Equal(t, "eng", settings.OCR.Lang)
+		assert.Equal(t, 30*time.Second, settings.OCR.Timeout)
+		assert.False(t, settings.ImageHash.Enabled)
+		assert.Equal(t, 5, settings.ImageHash.Distance)
+		assert.False(t, settings.Scoring.Enabled)
+		assert.InDelta(t, 1.0, settings.Scoring.DefaultWeight, 0.0001)
+		assert.InDelta(t, 1.0, settings.Scoring.Threshold, 0.0001)
+		assert.Zero(t, settings.Scoring.SuspiciousThreshold)
+		assert.Equal(t, config.ReviewSettings{}, settings.Review)
+		assert.False(t, settings.Reputation.Enabled)
+		assert.Equal(t, 24*time.Hour, settings.Reputation.ApprovalHold)
+		assert.False(t, settings.Federation.Enabled)
+		assert.Empty(t, settings.Federation.Peers)
+	})
 }
 
 func TestSaveAndLoadConfig(t *testing.T) {
@@ -1259,6 +1391,106 @@ func TestLoadConfigFromDB_DoesNotOverrideExisting(t *testing.T) {
 	assert.Equal(t, 48*time.Hour, loaded.History.Duration, "DB value preserved, not replaced by template 24h")
 }
 
+func TestLoadConfigFromFile(t *testing.T) {
+	defaults, err := defaultSettingsTemplate()
+	require.NoError(t, err)
+	t.Setenv("TG_SPAM_TEST_TOKEN", "file-token")
+
+	writeConfig := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "tg-spam.yml")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return path
+	}
+
+	t.Run("file values with defaults", func(t *testing.T) {
+		path := writeConfig(t, "min_msg_len: 321\nmeta:\n  links_limit: 0\ntelegram:\n  token: ${TG_SPAM_TEST_TOKEN}\n")
+		settings := &config.Settings{InstanceID: "cli-instance", Transient: config.TransientSettings{ConfigFile: path, Dbg: true}}
+		require.NoError(t, loadConfigFromFile(path, settings, defaults))
+
+		assert.Equal(t, 321, settings.MinMsgLen)
+		assert.Equal(t, "file-token", settings.Telegram.Token)
+		assert.Equal(t, 0, settings.Meta.LinksLimit, "zero-aware value kept")
+		assert.Equal(t, ":8080", settings.Server.ListenAddr, "filled from defaults")
+		assert.Equal(t, 24*time.Hour, settings.History.Duration, "filled from defaults")
+		assert.Equal(t, "cli-instance", settings.InstanceID, "cli instance id used if not set in file")
+		assert.Equal(t, path, settings.Transient.ConfigFile, "transient kept")
+		assert.True(t, settings.Transient.Dbg, "transient kept")
+	})
+
+	t.Run("instance id from file", func(t *testing.T) {
+		path := writeConfig(t, "instance_id: file-instance\n")
+		settings := &config.Settings{InstanceID: "cli-instance"}
+		require.NoError(t, loadConfigFromFile(path, settings, defaults))
+		assert.Equal(t, "file-instance", settings.InstanceID)
+	})
+
+	t.Run("cli overrides file", func(t *testing.T) {
+		path := writeConfig(t, "dry: false\ntelegram:\n  token: file-token\nserver:\n  listen_addr: \":9090\"\n")
+		settings := config.New()
+		require.NoError(t, loadConfigFromFile(path, settings, defaults))
+
+		opts := options{Dry: true}
+		opts.Telegram.Token = "cli-token"
+		opts.Server.ListenAddr = defaults.Server.ListenAddr
+		opts.Server.AuthPasswd = "auto"
+		opts.Files.DynamicDataPath = defaults.Files.DynamicDataPath
+		opts.Hooks.URLs = []string{"https://example.com/hook"}
+		applyCLIOverrides(settings, opts, defaults)
+		require.NoError(t, applyCLIListOverrides(settings, opts))
+
+		assert.Equal(t, "cli-token", settings.Telegram.Token)
+		assert.True(t, settings.Dry)
+		assert.Equal(t, ":9090", settings.Server.ListenAddr, "default cli value doesn't override file")
+		assert.Equal(t, []string{"https://example.com/hook"}, settings.Hooks.URLs)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := loadConfigFromFile(filepath.Join(t.TempDir(), "missing.yml"), config.New(), defaults)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to open config file")
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		path := writeConfig(t, "min_msg_lenn: 10\n")
+		err := loadConfigFromFile(path, config.New(), defaults)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown field "min_msg_lenn"`)
+	})
+}
+
+func TestApplyCLIListOverrides(t *testing.T) {
+	settings := config.New()
+	settings.Groups = []config.GroupSettings{{Group: "stored"}}
+	settings.Domains.Shorteners = []string{"bit.ly"}
+
+	t.Run("empty cli lists keep loaded", func(t *testing.T) {
+		require.NoError(t, applyCLIListOverrides(settings, options{}))
+		assert.Equal(t, []config.GroupSettings{{Group: "stored"}}, settings.Groups)
+		assert.Equal(t, []string{"bit.ly"}, settings.Domains.Shorteners)
+	})
+
+	t.Run("cli lists replace loaded", func(t *testing.T) {
+		var opts options
+		opts.Telegram.ExtraGroups = []string{"cli-group"}
+		opts.Domains.Shorteners = []string{"t.co"}
+		opts.Scoring.Weights = []string{"emoji:0.5"}
+		require.NoError(t, applyCLIListOverrides(settings, opts))
+		require.Len(t, settings.Groups, 1)
+		assert.Equal(t, "cli-group", settings.Groups[0].Group)
+		assert.Equal(t, []string{"t.co"}, settings.Domains.Shorteners)
+		assert.Equal(t, map[string]float64{"emoji": 0.5}, settings.Scoring.Weights)
+	})
+
+	t.Run("invalid cli list", func(t *testing.T) {
+		var opts options
+		opts.Scoring.Weights = []string{"emoji"}
+		err := applyCLIListOverrides(settings, opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid scoring weight")
+	})
+}
+
 func TestDefaultSettingsTemplate_KeyFields(t *testing.T) {
 	tmpl, err := defaultSettingsTemplate()
 	require.NoError(t, err)
@@ -1279,10 +1511,17 @@ func TestDefaultSettingsTemplate_KeyFields(t *testing.T) {
 
 	// durations
 	assert.Equal(t, 30*time.Second, tmpl.LLM.RequestTimeout, "LLM.RequestTimeout default")
+	assert.Equal(t, time.Duration(0), tmpl.LLM.CacheTTL, "LLM.CacheTTL default")
+	assert.Equal(t, 1000, tmpl.LLM.CacheSize, "LLM.CacheSize default")
 	assert.Equal(t, 30*time.Second, tmpl.Telegram.Timeout, "Telegram.Timeout default")
 	assert.Equal(t, 30*time.Second, tmpl.Telegram.IdleDuration, "Telegram.IdleDuration default")
 	assert.Equal(t, 24*time.Hour, tmpl.History.Duration, "HistoryDuration default")
 	assert.Equal(t, 5*time.Second, tmpl.CAS.Timeout, "CAS.Timeout default")
+	assert.Equal(t, time.Duration(0), tmpl.CAS.SyncInterval, "CAS.SyncInterval default")
+	assert.Equal(t, 720*time.Hour, tmpl.Federation.FeedWindow, "Federation.FeedWindow default")
+	assert.Equal(t, 15*time.Minute, tmpl.Federation.PullInterval, "Federation.PullInterval default")
+	assert.Equal(t, 10, tmpl.Hooks.MaxAttempts, "Hooks.MaxAttempts default")
+	assert.Equal(t, 30*time.Second, tmpl.Hooks.RetryDelay, "Hooks.RetryDelay default")
 	assert.Equal(t, time.Hour, tmpl.Duplicates.Window, "Duplicates.Window default")
 	assert.Equal(t, time.Hour, tmpl.Reactions.Window, "Reactions.Window default")
 	assert.Equal(t, time.Hour, tmpl.Report.RatePeriod, "Report.RatePeriod default")
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// APITokens is a storage for named bearer tokens of the web API. Tokens are generated by the storage and returned
+// to the caller once, only sha256 hashes of them are stored, so a leaked database doesn't reveal usable tokens.
+type APITokens struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// APIScope is a permission granted to the API token
+type APIScope string
+
+// enum of API token scopes
+const (
+	APIScopeCheck      APIScope = "check"            // check messages and users for spam
+	APIScopeSamples    APIScope = "samples:write"    // manage spam/ham samples and spam image hashes
+	APIScopeUsers      APIScope = "users:write"      // manage approved users
+	APIScopeDictionary APIScope = "dictionary:write" // manage stop phrases, ignored words and domains
+	APIScopeConfig     APIScope = "config:admin"     // read and change settings, read audit log, reviews and metrics
+	APIScopeBackup     APIScope = "backup"           // download database backups
+)
+
+// APIScopes is the list of all API token scopes
+var APIScopes = []APIScope{APIScopeCheck, APIScopeSamples, APIScopeUsers, APIScopeDictionary, APIScopeConfig, APIScopeBackup}
+
+// APIScopeList is a list of scopes stored as a comma-separated string
+type APIScopeList []APIScope
+
+// APIToken represents a named API token without the token itself
+type APIToken struct {
+	ID        int64        `db:"id" json:"id"`
+	GID       string       `db:"gid" json:"-"`
+	Name      string       `db:"name" json:"name"`
+	Prefix    string       `db:"prefix" json:"prefix"` // first chars of the token to tell tokens apart
+	Hash      string       `db:"hash" json:"-"`
+	Scopes    APIScopeList `db:"scopes" json:"scopes"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+	ExpiresAt *time.Time   `db:"expires_at" json:"expires_at,omitempty"` // nil for tokens without expiration
+	LastUsed  *time.Time   `db:"last_used" json:"last_used,omitempty"`
+}
+
+// apiTokenPrefix is added to generated tokens, so they can be recognized, e.g. by secret scanners
+const apiTokenPrefix = "tgs_"
+
+// api tokens command constants
+const (
+	CmdCreateAPITokensTable engine.DBCmd = iota + 1900
+	CmdCreateAPITokensIndexes
+)
+
+// apiTokensQueries holds all api tokens queries
+var apiTokensQueries = engine.NewQueryMap().
+	Add(CmdCreateAPITokensTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS api_tokens (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            name TEXT NOT NULL,
+            prefix TEXT NOT NULL DEFAULT '',
+            hash TEXT NOT NULL,
+            scopes TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP,
+            last_used TIMESTAMP,
+            UNIQUE(gid, name)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS api_tokens (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            name TEXT NOT NULL,
+            prefix TEXT NOT NULL DEFAULT '',
+            hash TEXT NOT NULL,
+            scopes TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            expires_at TIMESTAMP,
+            last_used TIMESTAMP,
+            UNIQUE(gid, name)
+        )`,
+	}).
+	AddSame(CmdCreateAPITokensIndexes, `CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_hash ON api_tokens(hash)`)
+
+// NewAPITokens creates a new APITokens storage and initializes the underlying table
+func NewAPITokens(ctx context.Context, db *engine.SQL) (*APITokens, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &APITokens{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "api_tokens",
+		CreateTable:   CmdCreateAPITokensTable,
+		CreateIndexes: CmdCreateAPITokensIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    apiTokensQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init api tokens storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for api tokens table (new table, no migration needed)
+func (t *APITokens) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add creates a new token with the given name and scopes and returns it. The token is not stored and can't be
+// retrieved later. Zero expiration time makes the token valid until deleted.
+func (t *APITokens) Add(ctx context.Context, name string, scopes []APIScope, expires time.Time) (token string, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("token name can't be empty")
+	}
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("token must have at least one scope")
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(APIScopes, sc) {
+			return "", fmt.Errorf("unknown scope %q", sc)
+		}
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = apiTokenPrefix + hex.EncodeToString(buf)
+
+	rec := APIToken{GID: t.GID(), Name: name, Prefix: token[:len(apiTokenPrefix)+6], Hash: hashAPIToken(token),
+		Scopes: slices.Compact(slices.Sorted(slices.Values(scopes))), CreatedAt: time.Now()}
+	if !expires.IsZero() {
+		rec.ExpiresAt = &expires
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	query := `INSERT INTO api_tokens (gid, name, prefix, hash, scopes, created_at, expires_at)
+		VALUES (:gid, :name, :prefix, :hash, :scopes, :created_at, :expires_at)`
+	if _, err := t.NamedExecContext(ctx, query, rec); err != nil {
+		return "", fmt.Errorf("failed to insert api token %q: %w", name, err)
+	}
+	log.Printf("[INFO] api token %q added, scopes: %v", name, rec.Scopes)
+	return token, nil
+}
+
+// Find returns the token record by the token, expired tokens are returned too, see APIToken.Expired.
+func (t *APITokens) Find(ctx context.Context, token string) (*APIToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, fmt.Errorf("api token not found")
+	}
+
+	t.RLock()
+	defer t.RUnlock()
+
+	var res APIToken
+	query := t.Adopt(`SELECT * FROM api_tokens WHERE gid = ? AND hash = ?`)
+	if err := t.GetContext(ctx, &res, query, t.GID(), hashAPIToken(token)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api token not found")
+		}
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+	res.localTimes()
+	return &res, nil
+}
+
+// List returns all tokens, newest first
+func (t *APITokens) List(ctx context.Context) ([]APIToken, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	var res []APIToken
+	query := t.Adopt(`SELECT * FROM api_tokens WHERE gid = ? ORDER BY created_at DESC, id DESC`)
+	if err := t.SelectContext(ctx, &res, query, t.GID()); err != nil {
+		return nil, fmt.Errorf("failed to get api tokens: %w", err)
+	}
+	for i := range res {
+		res[i].localTimes()
+	}
+	return res, nil
+}
+
+// Delete removes the token by its ID and returns the name of the deleted token
+func (t *APITokens) Delete(ctx context.Context, id int64) (name string, err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	// scoped by gid, the id comes from the web API
+	if err := t.GetContext(ctx, &name, t.Adopt(`SELECT name FROM api_tokens WHERE id = ? AND gid = ?`), id, t.GID()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("api token with id %d not found", id)
+		}
+		return "", fmt.Errorf("failed to get api token: %w", err)
+	}
+	if _, err := t.ExecContext(ctx, t.Adopt(`DELETE FROM api_tokens WHERE id = ? AND gid = ?`), id, t.GID()); err != nil {
+		return "", fmt.Errorf("failed to delete api token: %w", err)
+	}
+	log.Printf("[INFO] api token %q deleted", name)
+	return name, nil
+}
+
+// MarkUsed sets the last used time of the token
+func (t *APITokens) MarkUsed(ctx context.Context, id int64, ts time.Time) error {
+	t.Lock()
+	defer t.Unlock()
+
+	if _, err := t.ExecContext(ctx, t.Adopt(`UPDATE api_tokens SET last_used = ? WHERE id = ? AND gid = ?`),
+		ts, id, t.GID()); err != nil {
+		return fmt.Errorf("failed to update last used time of api token: %w", err)
+	}
+	return nil
+}
+
+// Expired returns true if the token has expiration time and it has passed
+func (a *APIToken) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// HasScope returns true if the token is granted the scope
+func (a *APIToken) HasScope(scope APIScope) bool {
+	return slices.Contains(a.Scopes, scope)
+}
+
+func (a *APIToken) localTimes() {
+	a.CreatedAt = a.CreatedAt.Local()
+	if a.ExpiresAt != nil {
+		ts := a.ExpiresAt.Local()
+		a.ExpiresAt = &ts
+	}
+	if a.LastUsed != nil {
+		ts := a.LastUsed.Local()
+		a.LastUsed = &ts
+	}
+}
+
+// Value implements driver.Valuer, scopes are stored as a comma-separated string
+func (l APIScopeList) Value() (driver.Value, error) {
+	res := make([]string, len(l))
+	for i, sc := range l {
+		res[i] = string(sc)
+	}
+	return strings.Join(res, ","), nil
+}
+
+// Scan implements sql.Scanner for scopes stored as a comma-separated string
+func (l *APIScopeList) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("unsupported type %T of api token scopes", src)
+	}
+	*l = nil
+	for sc := range strings.SplitSeq(s, ",") {
+		if sc = strings.TrimSpace(sc); sc != "" {
+			*l = append(*l, APIScope(sc))
+		}
+	}
+	return nil
+}
+
+// hashAPIToken returns hex-encoded sha256 hash of the token. Tokens are random, so the hash doesn't need salt
+// or a slow algorithm, and it can be looked up directly.
+func hashAPIToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
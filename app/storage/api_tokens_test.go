@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (s *StorageTestSuite) TestAPITokens_NewAPITokens() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewAPITokens(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE api_tokens")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM api_tokens`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewAPITokens(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestAPITokens_AddFindListDelete() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			tokens, err := NewAPITokens(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE api_tokens")
+
+			expires := time.Now().Add(time.Hour).Truncate(time.Second)
+			var checkToken, backupToken string
+			s.Run("add and find", func() {
+				checkToken, err = tokens.Add(ctx, " monitoring ", []APIScope{APIScopeCheck}, time.Time{})
+				s.Require().NoError(err)
+				s.True(strings.HasPrefix(checkToken, "tgs_"))
+				s.Len(checkToken, 52)
+				backupToken, err = tokens.Add(ctx, "backups", []APIScope{APIScopeBackup, APIScopeConfig, APIScopeBackup}, expires)
+				s.Require().NoError(err)
+				s.NotEqual(checkToken, backupToken)
+
+				tok, err := tokens.Find(ctx, checkToken)
+				s.Require().NoError(err)
+				s.Equal("monitoring", tok.Name)
+				s.Equal(checkToken[:10], tok.Prefix)
+				s.Equal(APIScopeList{APIScopeCheck}, tok.Scopes)
+				s.Nil(tok.ExpiresAt)
+				s.Nil(tok.LastUsed)
+				s.NotContains(tok.Hash, checkToken, "token itself not stored")
+				s.False(tok.Expired(time.Now()))
+				s.True(tok.HasScope(APIScopeCheck))
+				s.False(tok.HasScope(APIScopeBackup))
+
+				tok, err = tokens.Find(ctx, backupToken)
+				s.Require().NoError(err)
+				s.Equal(APIScopeList{APIScopeBackup, APIScopeConfig}, tok.Scopes, "sorted, duplicates removed")
+				s.Require().NotNil(tok.ExpiresAt)
+				s.True(expires.Equal(*tok.ExpiresAt))
+				s.False(tok.Expired(time.Now()))
+				s.True(tok.Expired(expires.Add(time.Second)))
+			})
+
+			s.Run("invalid tokens", func() {
+				_, err := tokens.Add(ctx, "  ", []APIScope{APIScopeCheck}, time.Time{})
+				s.Require().EqualError(err, "token name can't be empty")
+				_, err = tokens.Add(ctx, "no scopes", nil, time.Time{})
+				s.Require().EqualError(err, "token must have at least one scope")
+				_, err = tokens.Add(ctx, "bad scope", []APIScope{"root"}, time.Time{})
+				s.Require().EqualError(err, `unknown scope "root"`)
+				_, err = tokens.Add(ctx, "monitoring", []APIScope{APIScopeCheck}, time.Time{})
+				s.Require().Error(err, "duplicate name")
+			})
+
+			s.Run("unknown token", func() {
+				_, err := tokens.Find(ctx, "tgs_0000")
+				s.Require().EqualError(err, "api token not found")
+				_, err = tokens.Find(ctx, "")
+				s.Require().EqualError(err, "api token not found")
+			})
+
+			s.Run("mark used", func() {
+				tok, err := tokens.Find(ctx, checkToken)
+				s.Require().NoError(err)
+				used := time.Now().Truncate(time.Second)
+				s.Require().NoError(tokens.MarkUsed(ctx, tok.ID, used))
+				tok, err = tokens.Find(ctx, checkToken)
+				s.Require().NoError(err)
+				s.Require().NotNil(tok.LastUsed)
+				s.True(used.Equal(*tok.LastUsed))
+			})
+
+			s.Run("list and delete", func() {
+				res, err := tokens.List(ctx)
+				s.Require().NoError(err)
+				s.Require().Len(res, 2)
+				s.Equal("backups", res[0].Name, "newest first")
+				s.Equal("monitoring", res[1].Name)
+
+				name, err := tokens.Delete(ctx, res[0].ID)
+				s.Require().NoError(err)
+				s.Equal("backups", name)
+				_, err = tokens.Find(ctx, backupToken)
+				s.Require().Error(err)
+
+				_, err = tokens.Delete(ctx, res[0].ID)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "not found")
+			})
+
+			s.Run("other group tokens not visible", func() {
+				_, err := db.Exec(db.Adopt("INSERT INTO api_tokens (gid, name, hash, scopes) VALUES (?, ?, ?, ?)"),
+					"gr2", "other", hashAPIToken("tgs_other"), "check")
+				s.Require().NoError(err)
+				var id int64
+				err = db.Get(&id, db.Adopt("SELECT id FROM api_tokens WHERE gid = ?"), "gr2")
+				s.Require().NoError(err)
+
+				_, err = tokens.Find(ctx, "tgs_other")
+				s.Require().Error(err)
+				res, err := tokens.List(ctx)
+				s.Require().NoError(err)
+				s.Len(res, 1)
+				_, err = tokens.Delete(ctx, id)
+				s.Require().Error(err)
+			})
+		})
+	}
+}
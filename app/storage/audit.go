@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// Audit is a storage of moderation actions made by admins in telegram and in web UI or API.
+// Records are never pruned, the log is small and kept for review of what moderators did.
+type Audit struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// AuditAction is a type of moderation action recorded to the audit log
+type AuditAction string
+
+// enum of all audit actions
+const (
+	AuditUnban          AuditAction = "unban"           // unban from the admin chat notification
+	AuditBanConfirm     AuditAction = "ban_confirm"     // ban kept after the confirmation dialog
+	AuditSpam           AuditAction = "spam"            // message reported with /spam or forwarded to the admin chat
+	AuditBan            AuditAction = "ban"             // message reported with /ban
+	AuditWarn           AuditAction = "warn"            // message reported with /warn
+	AuditReportBan      AuditAction = "report_ban"      // user report approved and reported user banned
+	AuditReportReject   AuditAction = "report_reject"   // user report rejected
+	AuditReporterBan    AuditAction = "reporter_ban"    // reporter banned for a false report
+	AuditConfigUpdate   AuditAction = "config_update"   // settings changed in web UI
+	AuditConfigSave     AuditAction = "config_save"     // settings saved to the database
+	AuditConfigReload   AuditAction = "config_reload"   // settings reloaded from the database
+	AuditConfigDelete   AuditAction = "config_delete"   // settings deleted from the database
+	AuditConfigRollback AuditAction = "config_rollback" // settings rolled back to a saved revision
+	AuditSampleAdd      AuditAction = "sample_add"      // spam or ham sample added
+	AuditSampleDelete   AuditAction = "sample_delete"   // spam or ham sample deleted
+	AuditTokenCreate    AuditAction = "token_create"    // api token created
+	AuditTokenDelete    AuditAction = "token_delete"    // api token deleted
+	AuditUserCreate     AuditAction = "user_create"     // web UI user created
+	AuditUserDelete     AuditAction = "user_delete"     // web UI user deleted
+	AuditUserRole       AuditAction = "user_role"       // role of web UI user changed
+	AuditUserPassword   AuditAction = "user_password"   // password of web UI user reset
+)
+
+// audit sources, where the action was made
+const (
+	AuditSourceTelegram = "telegram"
+	AuditSourceWeb      = "web"
+)
+
+// AuditRecord is a single moderation action
+type AuditRecord struct {
+	ID       int64       `db:"id" json:"id"`
+	GID      string      `db:"gid" json:"gid"`
+	Time     time.Time   `db:"created_at" json:"time"`
+	Source   string      `db:"source" json:"source"`     // telegram or web
+	Actor    string      `db:"actor" json:"actor"`       // telegram superuser or web auth user
+	ActorID  int64       `db:"actor_id" json:"actor_id"` // telegram id of the actor, 0 for web
+	Action   AuditAction `db:"action" json:"action"`
+	UserID   int64       `db:"user_id" json:"user_id"`     // target user or channel, 0 if not applicable
+	UserName string      `db:"user_name" json:"user_name"` // target user or channel name
+	MsgID    int         `db:"msg_id" json:"msg_id"`       // target message, 0 if not applicable
+	Text     string      `db:"text" json:"text"`           // target message or sample text
+	Reason   string      `db:"reason" json:"reason"`       // details of the action, e.g. sample type or number of reports
+}
+
+// AuditFilter defines the records returned by Audit.List, zero fields don't filter
+type AuditFilter struct {
+	Actor  string
+	Action AuditAction
+	UserID int64
+	Since  time.Time // records made at or after this time
+	Until  time.Time // records made before this time
+	Limit  int       // max number of records, DefaultAuditLimit if not set
+}
+
+// DefaultAuditLimit is the max number of audit records listed if the filter has no limit
+const DefaultAuditLimit = 500
+
+// audit-related command constants
+const (
+	CmdCreateAuditTable engine.DBCmd = iota + 1700
+	CmdCreateAuditIndexes
+	CmdAddAudit
+	CmdListAudit
+)
+
+// auditQueries holds all audit-related queries
+var auditQueries = engine.NewQueryMap().
+	Add(CmdCreateAuditTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS audit (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            source TEXT NOT NULL DEFAULT '',
+            actor TEXT NOT NULL DEFAULT '',
+            actor_id INTEGER NOT NULL DEFAULT 0,
+            action TEXT NOT NULL,
+            user_id INTEGER NOT NULL DEFAULT 0,
+            user_name TEXT NOT NULL DEFAULT '',
+            msg_id INTEGER NOT NULL DEFAULT 0,
+            text TEXT NOT NULL DEFAULT '',
+            reason TEXT NOT NULL DEFAULT ''
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS audit (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            source TEXT NOT NULL DEFAULT '',
+            actor TEXT NOT NULL DEFAULT '',
+            actor_id BIGINT NOT NULL DEFAULT 0,
+            action TEXT NOT NULL,
+            user_id BIGINT NOT NULL DEFAULT 0,
+            user_name TEXT NOT NULL DEFAULT '',
+            msg_id INTEGER NOT NULL DEFAULT 0,
+            text TEXT NOT NULL DEFAULT '',
+            reason TEXT NOT NULL DEFAULT ''
+        )`,
+	}).
+	AddSame(CmdCreateAuditIndexes, `
+        CREATE INDEX IF NOT EXISTS idx_audit_gid_created ON audit(gid, created_at);
+        CREATE INDEX IF NOT EXISTS idx_audit_gid_user ON audit(gid, user_id)`).
+	AddSame(CmdAddAudit, "INSERT INTO audit (gid, created_at, source, actor, actor_id, action, user_id, user_name, "+
+		"msg_id, text, reason) VALUES (:gid, :created_at, :source, :actor, :actor_id, :action, :user_id, :user_name, "+
+		":msg_id, :text, :reason)").
+	AddSame(CmdListAudit, "SELECT id, gid, created_at, source, actor, actor_id, action, user_id, user_name, "+
+		"msg_id, text, reason FROM audit WHERE gid = ?")
+
+// NewAudit creates a new Audit storage and initializes the underlying table
+func NewAudit(ctx context.Context, db *engine.SQL) (*Audit, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &Audit{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "audit",
+		CreateTable:   CmdCreateAuditTable,
+		CreateIndexes: CmdCreateAuditIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    auditQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init audit storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for audit table (new table, no migration needed)
+func (a *Audit) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add records the moderation action. gid is set internally, time is set to now if empty.
+func (a *Audit) Add(ctx context.Context, rec AuditRecord) error {
+	if rec.Action == "" {
+		return fmt.Errorf("audit action is empty")
+	}
+	a.Lock()
+	defer a.Unlock()
+
+	rec.GID = a.GID()
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	query, err := auditQueries.Pick(a.Type(), CmdAddAudit)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	if _, err := a.NamedExecContext(ctx, query, rec); err != nil {
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+	return nil
+}
+
+// List returns audit records matching the filter, newest first
+func (a *Audit) List(ctx context.Context, f AuditFilter) ([]AuditRecord, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	query, err := auditQueries.Pick(a.Type(), CmdListAudit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query: %w", err)
+	}
+	var sb strings.Builder
+	sb.WriteString(query)
+	args := []any{a.GID()}
+	if f.Actor != "" {
+		sb.WriteString(" AND actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.Action != "" {
+		sb.WriteString(" AND action = ?")
+		args = append(args, f.Action)
+	}
+	if f.UserID != 0 {
+		sb.WriteString(" AND user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if !f.Since.IsZero() {
+		sb.WriteString(" AND created_at >= ?")
+		args = append(args, f.Since)
+	}
+	if !f.Until.IsZero() {
+		sb.WriteString(" AND created_at < ?")
+		args = append(args, f.Until)
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+	sb.WriteString(" ORDER BY created_at DESC, id DESC LIMIT ?")
+	args = append(args, limit)
+
+	var res []AuditRecord
+	if err := a.SelectContext(ctx, &res, a.Adopt(sb.String()), args...); err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	for i := range res {
+		res[i].Time = res[i].Time.Local()
+	}
+	return res, nil
+}
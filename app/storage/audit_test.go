@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *StorageTestSuite) TestAudit() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			audit, err := NewAudit(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE audit")
+
+			now := time.Now()
+			s.Require().NoError(audit.Add(ctx, AuditRecord{Time: now.Add(-48 * time.Hour), Source: AuditSourceTelegram,
+				Actor: "admin1", ActorID: 10, Action: AuditSpam, UserID: 1, UserName: "spammer", MsgID: 100, Text: "buy now"}))
+			s.Require().NoError(audit.Add(ctx, AuditRecord{Time: now.Add(-time.Hour), Source: AuditSourceTelegram,
+				Actor: "admin2", ActorID: 20, Action: AuditUnban, UserID: 2, UserName: "user2", MsgID: 200}))
+			s.Require().NoError(audit.Add(ctx, AuditRecord{Source: AuditSourceWeb, Actor: "tg-spam",
+				Action: AuditSampleAdd, Text: "some ham", Reason: "ham"}))
+			s.Require().Error(audit.Add(ctx, AuditRecord{Actor: "admin1"}), "action is required")
+
+			res, err := audit.List(ctx, AuditFilter{})
+			s.Require().NoError(err)
+			s.Require().Len(res, 3)
+			s.Equal(AuditSampleAdd, res[0].Action, "newest first")
+			s.WithinDuration(now, res[0].Time, 5*time.Second, "time set on add")
+			s.Equal("ham", res[0].Reason)
+			s.Equal(AuditUnban, res[1].Action)
+			s.Equal(AuditRecord{ID: res[2].ID, GID: db.GID(), Time: res[2].Time, Source: AuditSourceTelegram, Actor: "admin1",
+				ActorID: 10, Action: AuditSpam, UserID: 1, UserName: "spammer", MsgID: 100, Text: "buy now"}, res[2])
+			s.WithinDuration(now.Add(-48*time.Hour), res[2].Time, time.Second)
+
+			tests := []struct {
+				name    string
+				filter  AuditFilter
+				actions []AuditAction
+			}{
+				{name: "by actor", filter: AuditFilter{Actor: "admin2"}, actions: []AuditAction{AuditUnban}},
+				{name: "by action", filter: AuditFilter{Action: AuditSpam}, actions: []AuditAction{AuditSpam}},
+				{name: "by user", filter: AuditFilter{UserID: 1}, actions: []AuditAction{AuditSpam}},
+				{name: "since", filter: AuditFilter{Since: now.Add(-2 * time.Hour)},
+					actions: []AuditAction{AuditSampleAdd, AuditUnban}},
+				{name: "until", filter: AuditFilter{Until: now.Add(-30 * time.Minute)},
+					actions: []AuditAction{AuditUnban, AuditSpam}},
+				{name: "since and until", filter: AuditFilter{Since: now.Add(-2 * time.Hour), Until: now.Add(-30 * time.Minute)},
+					actions: []AuditAction{AuditUnban}},
+				{name: "limit", filter: AuditFilter{Limit: 2}, actions: []AuditAction{AuditSampleAdd, AuditUnban}},
+				{name: "no match", filter: AuditFilter{Actor: "admin1", Action: AuditUnban}, actions: []AuditAction{}},
+			}
+			for _, tt := range tests {
+				s.Run(tt.name, func() {
+					res, err := audit.List(ctx, tt.filter)
+					s.Require().NoError(err)
+					actions := make([]AuditAction, 0, len(res))
+					for _, r := range res {
+						actions = append(actions, r.Action)
+					}
+					s.Equal(tt.actions, actions)
+				})
+			}
+		})
+	}
+
+	s.Run("nil db connection", func() {
+		_, err := NewAudit(ctx, nil)
+		s.Require().Error(err)
+	})
+}
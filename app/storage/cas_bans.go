@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// CASBans is a storage for the local mirror of CAS (Combot Anti-Spam) banned users.
+// The list is replaced as a whole on each sync from the CAS export.
+type CASBans struct {
+	*engine.SQL
+	engine.RWLocker
+
+	syncMu   sync.RWMutex
+	syncedAt time.Time // time of the last successful sync, zero if never synced
+}
+
+// cas bans command constants
+const (
+	CmdCreateCASBansTable engine.DBCmd = iota + 1300
+	CmdCreateCASBansIndexes
+	CmdAddCASBan
+)
+
+// casBansQueries holds all cas bans queries
+var casBansQueries = engine.NewQueryMap().
+	Add(CmdCreateCASBansTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS cas_bans (
+            gid TEXT NOT NULL DEFAULT '',
+            user_id INTEGER NOT NULL,
+            synced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (gid, user_id)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS cas_bans (
+            gid TEXT NOT NULL DEFAULT '',
+            user_id BIGINT NOT NULL,
+            synced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (gid, user_id)
+        )`,
+	}).
+	AddSame(CmdCreateCASBansIndexes, `CREATE INDEX IF NOT EXISTS idx_cas_bans_gid_synced ON cas_bans(gid, synced_at DESC)`).
+	Add(CmdAddCASBan, engine.Query{
+		Sqlite:   `INSERT OR IGNORE INTO cas_bans (gid, user_id, synced_at) VALUES (?, ?, ?)`,
+		Postgres: `INSERT INTO cas_bans (gid, user_id, synced_at) VALUES ($1, $2, $3) ON CONFLICT (gid, user_id) DO NOTHING`,
+	})
+
+// NewCASBans creates a new CASBans storage and initializes the underlying table.
+// The time of the last sync is restored from the stored records.
+func NewCASBans(ctx context.Context, db *engine.SQL) (*CASBans, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &CASBans{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "cas_bans",
+		CreateTable:   CmdCreateCASBansTable,
+		CreateIndexes: CmdCreateCASBansIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    casBansQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init cas bans storage: %w", err)
+	}
+
+	var syncedAt time.Time
+	query := res.Adopt("SELECT synced_at FROM cas_bans WHERE gid = ? ORDER BY synced_at DESC LIMIT 1")
+	err := res.GetContext(ctx, &syncedAt, query, res.GID())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get last cas sync time: %w", err)
+	}
+	if err == nil {
+		res.syncedAt = syncedAt.Local()
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for cas_bans table (new table, no migration needed)
+func (c *CASBans) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Replace replaces all stored banned users with the given list in a single transaction
+// and marks the mirror as synced. Empty list is rejected to avoid wiping the mirror by a broken export.
+func (c *CASBans) Replace(ctx context.Context, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return fmt.Errorf("empty list of cas banned users")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	tx, err := c.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	gid := c.GID()
+	if _, err = tx.ExecContext(ctx, c.Adopt("DELETE FROM cas_bans WHERE gid = ?"), gid); err != nil {
+		return fmt.Errorf("failed to remove old cas bans: %w", err)
+	}
+
+	query, err := casBansQueries.Pick(c.Type(), CmdAddCASBan)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, id := range userIDs {
+		if _, err = stmt.ExecContext(ctx, gid, id, now); err != nil {
+			return fmt.Errorf("failed to add cas ban for user %d: %w", id, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cas bans: %w", err)
+	}
+
+	c.syncMu.Lock()
+	c.syncedAt = now
+	c.syncMu.Unlock()
+	return nil
+}
+
+// IsBanned checks if the user is in the local list of CAS banned users
+func (c *CASBans) IsBanned(ctx context.Context, userID int64) (bool, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var count int
+	query := c.Adopt("SELECT COUNT(*) FROM cas_bans WHERE gid = ? AND user_id = ?")
+	if err := c.GetContext(ctx, &count, query, c.GID(), userID); err != nil {
+		return false, fmt.Errorf("failed to check cas ban of user %d: %w", userID, err)
+	}
+	return count > 0, nil
+}
+
+// Count returns the number of stored CAS banned users
+func (c *CASBans) Count(ctx context.Context) (int, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var count int
+	if err := c.GetContext(ctx, &count, c.Adopt("SELECT COUNT(*) FROM cas_bans WHERE gid = ?"), c.GID()); err != nil {
+		return 0, fmt.Errorf("failed to count cas bans: %w", err)
+	}
+	return count, nil
+}
+
+// SyncedAt returns the time of the last successful sync, zero time if the mirror was never synced
+func (c *CASBans) SyncedAt() time.Time {
+	c.syncMu.RLock()
+	defer c.syncMu.RUnlock()
+	return c.syncedAt
+}
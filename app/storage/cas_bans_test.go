@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *StorageTestSuite) TestCASBans_NewCASBans() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				c, err := NewCASBans(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE cas_bans")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM cas_bans`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+				s.True(c.SyncedAt().IsZero())
+			})
+
+			s.Run("restore last sync time", func() {
+				c, err := NewCASBans(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE cas_bans")
+				s.Require().NoError(c.Replace(ctx, []int64{1, 2}))
+
+				c2, err := NewCASBans(ctx, db)
+				s.Require().NoError(err)
+				s.WithinDuration(c.SyncedAt(), c2.SyncedAt(), time.Second)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewCASBans(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestCASBans_ReplaceIsBanned() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			c, err := NewCASBans(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE cas_bans")
+
+			s.Run("empty mirror", func() {
+				banned, err := c.IsBanned(ctx, 100)
+				s.Require().NoError(err)
+				s.False(banned)
+			})
+
+			s.Run("replace with duplicates", func() {
+				s.Require().NoError(c.Replace(ctx, []int64{100, 200, 300, 200}))
+				count, err := c.Count(ctx)
+				s.Require().NoError(err)
+				s.Equal(3, count)
+				s.WithinDuration(time.Now(), c.SyncedAt(), time.Minute)
+
+				banned, err := c.IsBanned(ctx, 200)
+				s.Require().NoError(err)
+				s.True(banned)
+				banned, err = c.IsBanned(ctx, 400)
+				s.Require().NoError(err)
+				s.False(banned)
+			})
+
+			s.Run("replace drops old entries", func() {
+				s.Require().NoError(c.Replace(ctx, []int64{400}))
+				count, err := c.Count(ctx)
+				s.Require().NoError(err)
+				s.Equal(1, count)
+
+				banned, err := c.IsBanned(ctx, 100)
+				s.Require().NoError(err)
+				s.False(banned)
+				banned, err = c.IsBanned(ctx, 400)
+				s.Require().NoError(err)
+				s.True(banned)
+			})
+
+			s.Run("empty list rejected", func() {
+				syncedAt := c.SyncedAt()
+				err := c.Replace(ctx, nil)
+				s.Require().Error(err)
+				count, err := c.Count(ctx)
+				s.Require().NoError(err)
+				s.Equal(1, count, "mirror is kept")
+				s.Equal(syncedAt, c.SyncedAt())
+			})
+		})
+	}
+}
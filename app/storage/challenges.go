@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// Challenges is a storage for pending join challenges (captcha) of new chat members.
+// records are kept until the challenge is solved, failed or expired, so restricted users are
+// processed after the bot restart as well.
+type Challenges struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// Challenge represents a pending join challenge for a user in a chat
+type Challenge struct {
+	ID        int64     `db:"id"`
+	GID       string    `db:"gid"`
+	ChatID    int64     `db:"chat_id"`
+	UserID    int64     `db:"user_id"`
+	UserName  string    `db:"user_name"`
+	MsgID     int       `db:"msg_id"` // challenge message with the inline keyboard
+	Answer    string    `db:"answer"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// challenges-related command constants
+const (
+	CmdCreateChallengesTable engine.DBCmd = iota + 700
+	CmdCreateChallengesIndexes
+	CmdAddChallenge
+	CmdGetChallenge
+	CmdDeleteChallenge
+	CmdListExpiredChallenges
+)
+
+// challengesQueries holds all challenges-related queries
+var challengesQueries = engine.NewQueryMap().
+	Add(CmdCreateChallengesTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS challenges (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            chat_id INTEGER NOT NULL,
+            user_id INTEGER NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            msg_id INTEGER NOT NULL DEFAULT 0,
+            answer TEXT NOT NULL DEFAULT '',
+            expires_at TIMESTAMP NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, chat_id, user_id)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS challenges (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            chat_id BIGINT NOT NULL,
+            user_id BIGINT NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            msg_id INTEGER NOT NULL DEFAULT 0,
+            answer TEXT NOT NULL DEFAULT '',
+            expires_at TIMESTAMP NOT NULL,
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, chat_id, user_id)
+        )`,
+	}).
+	AddSame(CmdCreateChallengesIndexes,
+		`CREATE INDEX IF NOT EXISTS idx_challenges_gid_expires ON challenges(gid, expires_at)`).
+	Add(CmdAddChallenge, engine.Query{
+		Sqlite: "INSERT OR REPLACE INTO challenges (gid, chat_id, user_id, user_name, msg_id, answer, expires_at, created_at) " +
+			"VALUES (:gid, :chat_id, :user_id, :user_name, :msg_id, :answer, :expires_at, :created_at)",
+		Postgres: "INSERT INTO challenges (gid, chat_id, user_id, user_name, msg_id, answer, expires_at, created_at) " +
+			"VALUES (:gid, :chat_id, :user_id, :user_name, :msg_id, :answer, :expires_at, :created_at) " +
+			"ON CONFLICT (gid, chat_id, user_id) DO UPDATE SET user_name = EXCLUDED.user_name, msg_id = EXCLUDED.msg_id, " +
+			"answer = EXCLUDED.answer, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at",
+	}).
+	AddSame(CmdGetChallenge, "SELECT * FROM challenges WHERE gid = ? AND chat_id = ? AND user_id = ?").
+	AddSame(CmdDeleteChallenge, "DELETE FROM challenges WHERE gid = ? AND chat_id = ? AND user_id = ?").
+	AddSame(CmdListExpiredChallenges, "SELECT * FROM challenges WHERE gid = ? AND expires_at <= ? ORDER BY expires_at ASC")
+
+// NewChallenges creates a new Challenges storage and initializes the underlying table
+func NewChallenges(ctx context.Context, db *engine.SQL) (*Challenges, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &Challenges{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "challenges",
+		CreateTable:   CmdCreateChallengesTable,
+		CreateIndexes: CmdCreateChallengesIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    challengesQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init challenges storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for challenges table (new table, no migration needed)
+func (c *Challenges) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add stores a pending challenge, replacing the existing one for the same chat and user (e.g. on rejoin).
+// gid and created_at are populated internally.
+func (c *Challenges) Add(ctx context.Context, ch Challenge) error {
+	c.Lock()
+	defer c.Unlock()
+
+	ch.GID = c.GID()
+	ch.CreatedAt = time.Now()
+	query, err := challengesQueries.Pick(c.Type(), CmdAddChallenge)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	if _, err := c.NamedExecContext(ctx, query, ch); err != nil {
+		return fmt.Errorf("failed to insert challenge: %w", err)
+	}
+	log.Printf("[DEBUG] challenge added: user:%s (%d), chat:%d, expires:%s",
+		ch.UserName, ch.UserID, ch.ChatID, ch.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// Get returns a pending challenge for the user in the chat, nil if not found
+func (c *Challenges) Get(ctx context.Context, chatID, userID int64) (*Challenge, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	query, err := challengesQueries.Pick(c.Type(), CmdGetChallenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query: %w", err)
+	}
+	var res Challenge
+	err = c.GetContext(ctx, &res, c.Adopt(query), c.GID(), chatID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge for user %d in chat %d: %w", userID, chatID, err)
+	}
+	return &res, nil
+}
+
+// Delete removes a pending challenge for the user in the chat, no error if it doesn't exist
+func (c *Challenges) Delete(ctx context.Context, chatID, userID int64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	query, err := challengesQueries.Pick(c.Type(), CmdDeleteChallenge)
+	if err != nil {
+		return fmt.Errorf("failed to get query: %w", err)
+	}
+	if _, err := c.ExecContext(ctx, c.Adopt(query), c.GID(), chatID, userID); err != nil {
+		return fmt.Errorf("failed to delete challenge for user %d in chat %d: %w", userID, chatID, err)
+	}
+	return nil
+}
+
+// Expired returns challenges expired at the given time, the oldest first
+func (c *Challenges) Expired(ctx context.Context, now time.Time) ([]Challenge, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	query, err := challengesQueries.Pick(c.Type(), CmdListExpiredChallenges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query: %w", err)
+	}
+	var res []Challenge
+	if err := c.SelectContext(ctx, &res, c.Adopt(query), c.GID(), now); err != nil {
+		return nil, fmt.Errorf("failed to list expired challenges: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *StorageTestSuite) TestChallenges_NewChallenges() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewChallenges(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE challenges")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM challenges`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewChallenges(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestChallenges_AddGetDelete() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			challenges, err := NewChallenges(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE challenges")
+
+			expires := time.Now().Add(time.Minute).Truncate(time.Second)
+			s.Run("add and get", func() {
+				err := challenges.Add(ctx, Challenge{ChatID: -100123, UserID: 42, UserName: "alice", MsgID: 7,
+					Answer: "5", ExpiresAt: expires})
+				s.Require().NoError(err)
+
+				ch, err := challenges.Get(ctx, -100123, 42)
+				s.Require().NoError(err)
+				s.Require().NotNil(ch)
+				s.Equal(int64(-100123), ch.ChatID)
+				s.Equal(int64(42), ch.UserID)
+				s.Equal("alice", ch.UserName)
+				s.Equal(7, ch.MsgID)
+				s.Equal("5", ch.Answer)
+				s.Equal("gr1", ch.GID)
+				s.True(expires.Equal(ch.ExpiresAt.In(expires.Location())), "expires %v, got %v", expires, ch.ExpiresAt)
+				s.False(ch.CreatedAt.IsZero())
+			})
+
+			s.Run("get not found", func() {
+				ch, err := challenges.Get(ctx, -100123, 999)
+				s.Require().NoError(err)
+				s.Nil(ch)
+
+				ch, err = challenges.Get(ctx, -100999, 42)
+				s.Require().NoError(err)
+				s.Nil(ch, "challenge is per chat")
+			})
+
+			s.Run("add replaces existing challenge for the same user and chat", func() {
+				err := challenges.Add(ctx, Challenge{ChatID: -100123, UserID: 42, UserName: "alice2", MsgID: 8,
+					Answer: "🍎", ExpiresAt: expires.Add(time.Minute)})
+				s.Require().NoError(err)
+
+				ch, err := challenges.Get(ctx, -100123, 42)
+				s.Require().NoError(err)
+				s.Require().NotNil(ch)
+				s.Equal("alice2", ch.UserName)
+				s.Equal(8, ch.MsgID)
+				s.Equal("🍎", ch.Answer)
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM challenges`)
+				s.Require().NoError(err)
+				s.Equal(1, count)
+			})
+
+			s.Run("delete", func() {
+				s.Require().NoError(challenges.Delete(ctx, -100123, 42))
+				ch, err := challenges.Get(ctx, -100123, 42)
+				s.Require().NoError(err)
+				s.Nil(ch)
+
+				s.Require().NoError(challenges.Delete(ctx, -100123, 42), "deleting missing challenge is not an error")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestChallenges_Expired() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			challenges, err := NewChallenges(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE challenges")
+
+			now := time.Now()
+			s.Require().NoError(challenges.Add(ctx, Challenge{ChatID: 1, UserID: 1, ExpiresAt: now.Add(-time.Minute)}))
+			s.Require().NoError(challenges.Add(ctx, Challenge{ChatID: 1, UserID: 2, ExpiresAt: now.Add(-2 * time.Minute)}))
+			s.Require().NoError(challenges.Add(ctx, Challenge{ChatID: 1, UserID: 3, ExpiresAt: now.Add(time.Minute)}))
+			s.Require().NoError(challenges.Add(ctx, Challenge{ChatID: 2, UserID: 4, ExpiresAt: now.Add(-time.Second)}))
+
+			res, err := challenges.Expired(ctx, now)
+			s.Require().NoError(err)
+			s.Require().Len(res, 3)
+			s.Equal(int64(2), res[0].UserID, "oldest first")
+			s.Equal(int64(1), res[1].UserID)
+			s.Equal(int64(4), res[2].UserID)
+
+			res, err = challenges.Expired(ctx, now.Add(-time.Hour))
+			s.Require().NoError(err)
+			s.Empty(res)
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestChallenges_GIDIsolation() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			challenges, err := NewChallenges(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE challenges")
+
+			s.Require().NoError(challenges.Add(ctx, Challenge{ChatID: 1, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}))
+			_, err = db.Exec(db.Adopt("UPDATE challenges SET gid = ? WHERE user_id = ?"), "other", 1)
+			s.Require().NoError(err)
+
+			ch, err := challenges.Get(ctx, 1, 1)
+			s.Require().NoError(err)
+			s.Nil(ch)
+
+			res, err := challenges.Expired(ctx, time.Now())
+			s.Require().NoError(err)
+			s.Empty(res)
+		})
+	}
+}
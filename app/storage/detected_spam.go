@@ -32,15 +32,21 @@ type DetectedSpamInfo struct {
 	UserID     int64                `db:"user_id"`
 	UserName   string               `db:"user_name"`
 	Timestamp  time.Time            `db:"timestamp"`
-	Added      bool                 `db:"added"`  // added to samples
-	ChecksJSON string               `db:"checks"` // store as JSON
-	Checks     []spamcheck.Response `db:"-"`      // don't store in DB directly
+	Added      bool                 `db:"added"`      // added to samples
+	ChecksJSON string               `db:"checks"`     // store as JSON
+	Checks     []spamcheck.Response `db:"-"`          // don't store in DB directly
+	ImageHash  string               `db:"image_hash"` // perceptual hash of the message image, empty if none
+	Dry        bool                 `db:"dry"`        // detected in dry run or training mode, user not banned
+	Temporary  bool                 `db:"temporary"`  // punished for a limited time by the punishment ladder, not banned
 }
 
 // detected spam query commands
 const (
 	CmdCreateDetectedSpamTable engine.DBCmd = iota + 200
 	CmdCreateDetectedSpamIndexes
+	CmdAddImageHashColumn
+	CmdAddDryColumn
+	CmdAddTemporaryColumn
 )
 
 // queries holds all detected spam queries
@@ -54,7 +60,10 @@ var detectedSpamQueries = engine.NewQueryMap().
             user_name TEXT,
             timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
             added BOOLEAN DEFAULT 0,
-            checks TEXT
+            checks TEXT,
+            image_hash TEXT NOT NULL DEFAULT '',
+            dry BOOLEAN NOT NULL DEFAULT 0,
+            temporary BOOLEAN NOT NULL DEFAULT 0
         )`,
 		Postgres: `CREATE TABLE IF NOT EXISTS detected_spam (
             id SERIAL PRIMARY KEY,
@@ -64,7 +73,10 @@ var detectedSpamQueries = engine.NewQueryMap().
             user_name TEXT,
             timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
             added BOOLEAN DEFAULT false,
-            checks TEXT
+            checks TEXT,
+            image_hash TEXT NOT NULL DEFAULT '',
+            dry BOOLEAN NOT NULL DEFAULT false,
+            temporary BOOLEAN NOT NULL DEFAULT false
         )`,
 	}).
 	AddSame(CmdCreateDetectedSpamIndexes, `
@@ -75,6 +87,18 @@ var detectedSpamQueries = engine.NewQueryMap().
 	Add(CmdAddGIDColumn, engine.Query{
 		Sqlite:   "ALTER TABLE detected_spam ADD COLUMN gid TEXT DEFAULT ''",
 		Postgres: "ALTER TABLE detected_spam ADD COLUMN IF NOT EXISTS gid TEXT DEFAULT ''",
+	}).
+	Add(CmdAddImageHashColumn, engine.Query{
+		Sqlite:   "ALTER TABLE detected_spam ADD COLUMN image_hash TEXT NOT NULL DEFAULT ''",
+		Postgres: "ALTER TABLE detected_spam ADD COLUMN IF NOT EXISTS image_hash TEXT NOT NULL DEFAULT ''",
+	}).
+	Add(CmdAddDryColumn, engine.Query{
+		Sqlite:   "ALTER TABLE detected_spam ADD COLUMN dry BOOLEAN NOT NULL DEFAULT 0",
+		Postgres: "ALTER TABLE detected_spam ADD COLUMN IF NOT EXISTS dry BOOLEAN NOT NULL DEFAULT false",
+	}).
+	Add(CmdAddTemporaryColumn, engine.Query{
+		Sqlite:   "ALTER TABLE detected_spam ADD COLUMN temporary BOOLEAN NOT NULL DEFAULT 0",
+		Postgres: "ALTER TABLE detected_spam ADD COLUMN IF NOT EXISTS temporary BOOLEAN NOT NULL DEFAULT false",
 	})
 
 // NewDetectedSpam creates a new DetectedSpam storage
@@ -110,8 +134,10 @@ func (ds *DetectedSpam) Write(ctx context.Context, entry DetectedSpamInfo, check
 		return fmt.Errorf("failed to marshal checks: %w", err)
 	}
 
-	query := ds.Adopt("INSERT INTO detected_spam (gid, text, user_id, user_name, timestamp, checks) VALUES (?, ?, ?, ?, ?, ?)")
-	_, err = ds.ExecContext(ctx, query, entry.GID, entry.Text, entry.UserID, entry.UserName, entry.Timestamp, string(checksJSON))
+	query := ds.Adopt("INSERT INTO detected_spam (gid, text, user_id, user_name, timestamp, checks, image_hash, dry, " +
+		"temporary) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	_, err = ds.ExecContext(ctx, query, entry.GID, entry.Text, entry.UserID, entry.UserName, entry.Timestamp,
+		string(checksJSON), entry.ImageHash, entry.Dry, entry.Temporary)
 	if err != nil {
 		return fmt.Errorf("failed to insert detected spam entry: %w", err)
 	}
@@ -155,6 +181,28 @@ func (ds *DetectedSpam) Read(ctx context.Context) ([]DetectedSpamInfo, error) {
 	return entries, nil
 }
 
+// ReadSince returns detected spam entries added after the given time, newest first, up to limit entries
+func (ds *DetectedSpam) ReadSince(ctx context.Context, since time.Time, limit int) ([]DetectedSpamInfo, error) {
+	ds.RLock()
+	defer ds.RUnlock()
+
+	query := ds.Adopt("SELECT * FROM detected_spam WHERE gid = ? AND timestamp > ? ORDER BY timestamp DESC LIMIT ?")
+	var entries []DetectedSpamInfo
+	if err := ds.SelectContext(ctx, &entries, query, ds.GID(), since, limit); err != nil {
+		return nil, fmt.Errorf("failed to get detected spam entries since %v: %w", since, err)
+	}
+
+	for i, entry := range entries {
+		var checks []spamcheck.Response
+		if err := json.Unmarshal([]byte(entry.ChecksJSON), &checks); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checks for entry %d: %w", i, err)
+		}
+		entries[i].Checks = checks
+		entries[i].Timestamp = entry.Timestamp.Local()
+	}
+	return entries, nil
+}
+
 // FindByUserID returns the latest detected spam entry for the given user ID
 func (ds *DetectedSpam) FindByUserID(ctx context.Context, userID int64) (*DetectedSpamInfo, error) {
 	ds.RLock()
@@ -190,6 +238,39 @@ func (ds *DetectedSpam) migrate(ctx context.Context, tx *sqlx.Tx, gid string) er
 	if err := ds.dropStrayIndex(ctx, tx); err != nil {
 		return fmt.Errorf("failed to drop stray detected_spam index: %w", err)
 	}
+	if err := ds.migrateColumn(ctx, tx, "image_hash", CmdAddImageHashColumn); err != nil {
+		return fmt.Errorf("failed to add image_hash column: %w", err)
+	}
+	if err := ds.migrateColumn(ctx, tx, "dry", CmdAddDryColumn); err != nil {
+		return fmt.Errorf("failed to add dry column: %w", err)
+	}
+	if err := ds.migrateColumn(ctx, tx, "temporary", CmdAddTemporaryColumn); err != nil {
+		return fmt.Errorf("failed to add temporary column: %w", err)
+	}
+	return nil
+}
+
+// migrateColumn adds the column to tables made by older versions with the add column command.
+// the column presence is checked via table info on sqlite, as a failed probe query would abort
+// the migration transaction on postgres, where ADD COLUMN IF NOT EXISTS is used instead
+func (ds *DetectedSpam) migrateColumn(ctx context.Context, tx *sqlx.Tx, column string, addCmd engine.DBCmd) error {
+	if ds.Type() == engine.Sqlite {
+		var count int
+		query := "SELECT COUNT(*) FROM pragma_table_info('detected_spam') WHERE name = ?"
+		if err := tx.GetContext(ctx, &count, query, column); err != nil {
+			return fmt.Errorf("failed to check %s column: %w", column, err)
+		}
+		if count > 0 {
+			return nil
+		}
+	}
+	addQuery, err := detectedSpamQueries.Pick(ds.Type(), addCmd)
+	if err != nil {
+		return fmt.Errorf("failed to get add %s query: %w", column, err)
+	}
+	if _, err := tx.ExecContext(ctx, addQuery); err != nil {
+		return fmt.Errorf("failed to add column: %w", err)
+	}
 	return nil
 }
 
@@ -83,6 +83,19 @@ func (s *StorageTestSuite) TestNewDetectedSpam() {
 				s.Equal("test spam", entries[0].Text)
 				s.Equal(int64(123), entries[0].UserID)
 				s.Equal("test_user", entries[0].UserName)
+				s.Empty(entries[0].ImageHash)
+				s.False(entries[0].Dry, "old entries are banned")
+				s.False(entries[0].Temporary, "old entries are banned permanently")
+
+				// image_hash, dry and temporary columns added to the old table
+				s.Require().NoError(ds.Write(ctx, DetectedSpamInfo{GID: db.GID(), Text: "image spam", UserID: 124,
+					Timestamp: time.Now(), ImageHash: "ff00ff00ff00ff00", Dry: true, Temporary: true}, nil))
+				entries, err = ds.Read(ctx)
+				s.Require().NoError(err)
+				s.Require().Len(entries, 2)
+				s.Equal("ff00ff00ff00ff00", entries[0].ImageHash)
+				s.True(entries[0].Dry)
+				s.True(entries[0].Temporary)
 			})
 
 			s.Run("with nil db", func() {
@@ -199,6 +212,7 @@ func (s *StorageTestSuite) TestDetectedSpam_Read() {
 				UserID:    456,
 				UserName:  "spammer",
 				Timestamp: time.Now().UTC().Truncate(time.Second), // ensure consistent time comparison
+				ImageHash: "0123456789abcdef",
 			}
 			checks := []spamcheck.Response{{Name: "test", Spam: true, Details: "test details"}}
 
@@ -213,11 +227,41 @@ func (s *StorageTestSuite) TestDetectedSpam_Read() {
 			s.Equal(entry.Text, entries[0].Text)
 			s.Equal(entry.UserID, entries[0].UserID)
 			s.Equal(entry.UserName, entries[0].UserName)
+			s.Equal(entry.ImageHash, entries[0].ImageHash)
 			s.Equal(checks, entries[0].Checks)
 		})
 	}
 }
 
+func (s *StorageTestSuite) TestDetectedSpam_ReadSince() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			ds, err := NewDetectedSpam(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE detected_spam")
+
+			now := time.Now()
+			for i, ts := range []time.Time{now.Add(-48 * time.Hour), now.Add(-2 * time.Hour), now.Add(-time.Hour), now} {
+				entry := DetectedSpamInfo{GID: "gr1", Text: "spam", UserID: int64(100 + i), UserName: "spammer", Timestamp: ts}
+				s.Require().NoError(ds.Write(ctx, entry, []spamcheck.Response{{Name: "cas", Spam: true}}))
+			}
+
+			entries, err := ds.ReadSince(ctx, now.Add(-24*time.Hour), 10)
+			s.Require().NoError(err)
+			s.Require().Len(entries, 3)
+			s.Equal(int64(103), entries[0].UserID, "newest first")
+			s.Equal(int64(101), entries[2].UserID)
+			s.Equal([]spamcheck.Response{{Name: "cas", Spam: true}}, entries[0].Checks)
+
+			entries, err = ds.ReadSince(ctx, now.Add(-24*time.Hour), 2)
+			s.Require().NoError(err)
+			s.Len(entries, 2, "limited")
+		})
+	}
+}
+
 func (s *StorageTestSuite) TestDetectedSpam_Read_LimitExceeded() {
 	ctx := context.Background()
 	for _, dbt := range s.getTestDB() {
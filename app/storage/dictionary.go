@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"log"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/umputun/tg-spam/app/storage/engine"
 )
@@ -29,7 +31,7 @@ var dictionaryQueries = engine.NewQueryMap().
             id INTEGER PRIMARY KEY AUTOINCREMENT,
             gid TEXT DEFAULT '',
             timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
-            type TEXT CHECK (type IN ('stop_phrase', 'ignored_word')),
+            type TEXT CHECK (type IN ('stop_phrase', 'ignored_word', 'blocked_domain', 'allowed_domain')),
             data TEXT NOT NULL,
             UNIQUE(gid, data)
         )`,
@@ -37,7 +39,7 @@ var dictionaryQueries = engine.NewQueryMap().
             id SERIAL PRIMARY KEY,
             gid TEXT DEFAULT '',
             timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            type TEXT CHECK (type IN ('stop_phrase', 'ignored_word')),
+            type TEXT CHECK (type IN ('stop_phrase', 'ignored_word', 'blocked_domain', 'allowed_domain')),
             data TEXT NOT NULL,
             UNIQUE(gid, data)
         )`,
@@ -65,7 +67,7 @@ var dictionaryQueries = engine.NewQueryMap().
 		Postgres: `INSERT INTO dictionary (type, data, gid) VALUES ($1, $2, $3) ON CONFLICT (gid, data) DO UPDATE SET type = EXCLUDED.type`,
 	})
 
-// Dictionary is a storage for stop words/phrases and ignored words
+// Dictionary is a storage for stop words/phrases, ignored words and blocked/allowed domains
 type Dictionary struct {
 	*engine.SQL
 	engine.RWLocker
@@ -76,8 +78,10 @@ type DictionaryType string
 
 // enum for dictionary types
 const (
-	DictionaryTypeStopPhrase  DictionaryType = "stop_phrase"
-	DictionaryTypeIgnoredWord DictionaryType = "ignored_word"
+	DictionaryTypeStopPhrase    DictionaryType = "stop_phrase"
+	DictionaryTypeIgnoredWord   DictionaryType = "ignored_word"
+	DictionaryTypeBlockedDomain DictionaryType = "blocked_domain"
+	DictionaryTypeAllowedDomain DictionaryType = "allowed_domain"
 )
 
 // NewDictionary creates a new Dictionary storage
@@ -335,7 +339,7 @@ func (t DictionaryType) String() string { return string(t) }
 // Validate checks if the dictionary type is valid
 func (t DictionaryType) Validate() error {
 	switch t {
-	case DictionaryTypeStopPhrase, DictionaryTypeIgnoredWord:
+	case DictionaryTypeStopPhrase, DictionaryTypeIgnoredWord, DictionaryTypeBlockedDomain, DictionaryTypeAllowedDomain:
 		return nil
 	}
 	return fmt.Errorf("invalid dictionary type: %s", t)
@@ -343,13 +347,16 @@ func (t DictionaryType) Validate() error {
 
 // DictionaryStats returns statistics about dictionary entries
 type DictionaryStats struct {
-	TotalStopPhrases  int `db:"stop_phrases_count"`
-	TotalIgnoredWords int `db:"ignored_words_count"`
+	TotalStopPhrases    int `db:"stop_phrases_count"`
+	TotalIgnoredWords   int `db:"ignored_words_count"`
+	TotalBlockedDomains int `db:"blocked_domains_count"`
+	TotalAllowedDomains int `db:"allowed_domains_count"`
 }
 
 // String returns a string representation of the stats
 func (d *DictionaryStats) String() string {
-	return fmt.Sprintf("stop phrases: %d, ignored words: %d", d.TotalStopPhrases, d.TotalIgnoredWords)
+	return fmt.Sprintf("stop phrases: %d, ignored words: %d, blocked domains: %d, allowed domains: %d",
+		d.TotalStopPhrases, d.TotalIgnoredWords, d.TotalBlockedDomains, d.TotalAllowedDomains)
 }
 
 // Stats returns statistics about dictionary entries for the given GID
@@ -360,19 +367,112 @@ func (d *Dictionary) Stats(ctx context.Context) (*DictionaryStats, error) {
 	query := d.Adopt(`
         SELECT 
             COUNT(CASE WHEN type = ? THEN 1 END) as stop_phrases_count,
-            COUNT(CASE WHEN type = ? THEN 1 END) as ignored_words_count
+            COUNT(CASE WHEN type = ? THEN 1 END) as ignored_words_count,
+            COUNT(CASE WHEN type = ? THEN 1 END) as blocked_domains_count,
+            COUNT(CASE WHEN type = ? THEN 1 END) as allowed_domains_count
         FROM dictionary
         WHERE gid = ?`,
 	)
 
 	var stats DictionaryStats
-	if err := d.GetContext(ctx, &stats, query, DictionaryTypeStopPhrase, DictionaryTypeIgnoredWord, d.GID()); err != nil {
+	if err := d.GetContext(ctx, &stats, query, DictionaryTypeStopPhrase, DictionaryTypeIgnoredWord,
+		DictionaryTypeBlockedDomain, DictionaryTypeAllowedDomain, d.GID()); err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
 	return &stats, nil
 }
 
-func (d *Dictionary) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
-	// no migrations yet
+// migrate relaxes the type check constraint of tables created before domain types were added,
+// otherwise inserts of blocked and allowed domains are rejected by the old constraint
+func (d *Dictionary) migrate(ctx context.Context, tx *sqlx.Tx, _ string) error {
+	switch d.Type() {
+	case engine.Sqlite:
+		return d.migrateTypeCheckSqlite(ctx, tx)
+	case engine.Postgres:
+		return d.migrateTypeCheckPostgres(ctx, tx)
+	default:
+		return fmt.Errorf("unsupported database type %q", d.Type())
+	}
+}
+
+// migrateTypeCheckSqlite rebuilds the table with the current schema, sqlite can't alter check constraints.
+// indexes dropped with the old table are recreated by InitTable right after migration.
+func (d *Dictionary) migrateTypeCheckSqlite(ctx context.Context, tx *sqlx.Tx) error {
+	var schema string
+	if err := tx.GetContext(ctx, &schema,
+		"SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'dictionary'"); err != nil {
+		return fmt.Errorf("failed to read dictionary schema: %w", err)
+	}
+	if strings.Contains(schema, string(DictionaryTypeBlockedDomain)) {
+		return nil // already has domain types
+	}
+
+	create, err := dictionaryQueries.Pick(engine.Sqlite, CmdCreateDictionaryTable)
+	if err != nil {
+		return fmt.Errorf("failed to get create table query: %w", err)
+	}
+	stmts := []string{
+		strings.Replace(create, "IF NOT EXISTS dictionary", "dictionary_new", 1),
+		"INSERT INTO dictionary_new (id, gid, timestamp, type, data) SELECT id, gid, timestamp, type, data FROM dictionary",
+		"DROP TABLE dictionary",
+		"ALTER TABLE dictionary_new RENAME TO dictionary",
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to rebuild dictionary table: %w", err)
+		}
+	}
+	log.Printf("[INFO] dictionary table rebuilt with domain types")
+	return nil
+}
+
+// migrateTypeCheckPostgres replaces the type check constraint. It re-checks the constraint under
+// an exclusive table lock, so only one of concurrently upgraded instances performs the swap.
+func (d *Dictionary) migrateTypeCheckPostgres(ctx context.Context, tx *sqlx.Tx) error {
+	var checks []struct {
+		Name string `db:"name"`
+		Def  string `db:"def"`
+	}
+	readChecks := func() (upToDate bool, err error) {
+		checks = nil
+		query := `SELECT con.conname AS name, pg_get_constraintdef(con.oid) AS def FROM pg_constraint con
+            JOIN pg_class rel ON rel.oid = con.conrelid
+            JOIN pg_namespace ns ON ns.oid = rel.relnamespace
+            WHERE rel.relname = 'dictionary' AND ns.nspname = current_schema() AND con.contype = 'c'`
+		if err := tx.SelectContext(ctx, &checks, query); err != nil {
+			return false, fmt.Errorf("failed to read dictionary check constraints: %w", err)
+		}
+		for _, c := range checks {
+			if strings.Contains(c.Def, string(DictionaryTypeBlockedDomain)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	upToDate, err := readChecks()
+	if err != nil || upToDate {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "LOCK TABLE dictionary IN ACCESS EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("failed to lock dictionary table: %w", err)
+	}
+	if upToDate, err = readChecks(); err != nil || upToDate {
+		return err // nil if another instance completed the migration while we waited for the lock
+	}
+
+	alters := []string{}
+	for _, c := range checks {
+		if strings.Contains(c.Def, string(DictionaryTypeStopPhrase)) {
+			alters = append(alters, "DROP CONSTRAINT "+pq.QuoteIdentifier(c.Name))
+		}
+	}
+	alters = append(alters, "ADD CONSTRAINT dictionary_type_check CHECK "+
+		"(type IN ('stop_phrase', 'ignored_word', 'blocked_domain', 'allowed_domain'))")
+	//nolint:gosec // constraint names come from pg_constraint and are quoted, the rest is hardcoded
+	if _, err := tx.ExecContext(ctx, "ALTER TABLE dictionary "+strings.Join(alters, ", ")); err != nil {
+		return fmt.Errorf("failed to replace dictionary type check: %w", err)
+	}
+	log.Printf("[INFO] dictionary type check upgraded with domain types")
 	return nil
 }
@@ -6,6 +6,8 @@ import (
 	"io"
 	"strings"
 	"sync"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
 )
 
 func (s *StorageTestSuite) TestNewDictionary() {
@@ -28,6 +30,53 @@ func (s *StorageTestSuite) TestNewDictionary() {
 		})
 	}
 }
+
+func (s *StorageTestSuite) TestDictionary_MigrateDomainTypes() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			idColumn := "id INTEGER PRIMARY KEY AUTOINCREMENT"
+			if db.Type() == engine.Postgres {
+				idColumn = "id SERIAL PRIMARY KEY"
+			}
+			// legacy table, created before domain types were added
+			_, err := db.Exec(`CREATE TABLE dictionary (
+				` + idColumn + `,
+				gid TEXT DEFAULT '',
+				timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				type TEXT CHECK (type IN ('stop_phrase', 'ignored_word')),
+				data TEXT NOT NULL,
+				UNIQUE(gid, data)
+			)`)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE dictionary")
+			_, err = db.Exec(db.Adopt("INSERT INTO dictionary (type, data, gid) VALUES (?, ?, ?)"), "stop_phrase", "old phrase", db.GID())
+			s.Require().NoError(err)
+			_, err = db.Exec(db.Adopt("INSERT INTO dictionary (type, data, gid) VALUES (?, ?, ?)"), "blocked_domain", "spam.example.com", db.GID())
+			s.Require().Error(err, "legacy constraint rejects domains")
+
+			d, err := NewDictionary(ctx, db)
+			s.Require().NoError(err)
+			s.Require().NoError(d.Add(ctx, DictionaryTypeBlockedDomain, "spam.example.com"))
+			s.Require().NoError(d.Add(ctx, DictionaryTypeStopPhrase, "new phrase"))
+			s.Require().Error(d.Add(ctx, "invalid", "x"))
+			_, err = db.Exec(db.Adopt("INSERT INTO dictionary (type, data, gid) VALUES (?, ?, ?)"), "invalid", "x", db.GID())
+			s.Require().Error(err, "constraint still rejects unknown types")
+
+			phrases, err := d.Read(ctx, DictionaryTypeStopPhrase)
+			s.Require().NoError(err)
+			s.ElementsMatch([]string{"old phrase", "new phrase"}, phrases, "existing entries kept")
+			domains, err := d.Read(ctx, DictionaryTypeBlockedDomain)
+			s.Require().NoError(err)
+			s.Equal([]string{"spam.example.com"}, domains)
+
+			_, err = NewDictionary(ctx, db)
+			s.Require().NoError(err, "migration is idempotent")
+		})
+	}
+}
+
 func (s *StorageTestSuite) TestDictionary_AddPhrase() {
 	ctx := context.Background()
 	for _, dbt := range s.getTestDB() {
@@ -47,6 +96,11 @@ func (s *StorageTestSuite) TestDictionary_AddPhrase() {
 				s.NoError(err)
 			})
 
+			s.Run("valid blocked and allowed domains", func() {
+				s.NoError(d.Add(ctx, DictionaryTypeBlockedDomain, "spam.example.com"))
+				s.NoError(d.Add(ctx, DictionaryTypeAllowedDomain, "github.com"))
+			})
+
 			s.Run("invalid type", func() {
 				err := d.Add(ctx, "invalid", "test phrase")
 				s.Error(err)
@@ -209,12 +263,17 @@ func (s *StorageTestSuite) TestDictionary_Stats() {
 			s.Require().NoError(err)
 			err = d.Add(ctx, DictionaryTypeIgnoredWord, "ignored2")
 			s.Require().NoError(err)
+			err = d.Add(ctx, DictionaryTypeBlockedDomain, "spam.example.com")
+			s.Require().NoError(err)
 
 			stats, err := d.Stats(ctx)
 			s.Require().NoError(err)
 			s.Require().NotNil(stats)
 			s.Equal(3, stats.TotalStopPhrases)
 			s.Equal(2, stats.TotalIgnoredWords)
+			s.Equal(1, stats.TotalBlockedDomains)
+			s.Equal(0, stats.TotalAllowedDomains)
+			s.Equal("stop phrases: 3, ignored words: 2, blocked domains: 1, allowed domains: 0", stats.String())
 		})
 	}
 }
@@ -339,6 +398,8 @@ func (s *StorageTestSuite) TestDictionaryType_Validate() {
 	}{
 		{"valid stop phrase", DictionaryTypeStopPhrase, false},
 		{"valid ignored word", DictionaryTypeIgnoredWord, false},
+		{"valid blocked domain", DictionaryTypeBlockedDomain, false},
+		{"valid allowed domain", DictionaryTypeAllowedDomain, false},
 		{"invalid type", "invalid", true},
 	}
 
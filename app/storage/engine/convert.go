@@ -57,7 +57,8 @@ func (c *Converter) SqliteToPostgres(ctx context.Context, w io.Writer) error {
 	}
 
 	// define the tables specific to tg-spam that we want to convert
-	tables := []string{"detected_spam", "approved_users", "samples", "dictionary", "reports", "warnings"}
+	tables := []string{"detected_spam", "approved_users", "samples", "dictionary", "reports", "warnings", "challenges",
+		"image_hashes"}
 
 	// for each table, export schema and data if it exists
 	for _, table := range tables {
@@ -188,6 +189,15 @@ func (c *Converter) convertTableSchema(tableName, sqliteStmt string) string {
 	case "warnings":
 		// telegram user ids can exceed the 32-bit range
 		pgStmt = strings.ReplaceAll(pgStmt, "user_id INTEGER", "user_id BIGINT")
+
+	case "challenges":
+		// telegram chat and user ids can exceed the 32-bit range
+		pgStmt = strings.ReplaceAll(pgStmt, "chat_id INTEGER", "chat_id BIGINT")
+		pgStmt = strings.ReplaceAll(pgStmt, "user_id INTEGER", "user_id BIGINT")
+
+	case "image_hashes":
+		// telegram user ids can exceed the 32-bit range
+		pgStmt = strings.ReplaceAll(pgStmt, "user_id INTEGER", "user_id BIGINT")
 	}
 
 	// final conversion of boolean defaults for any boolean columns in all tables
@@ -310,6 +310,18 @@ func TestConverter_ConvertTableSchema(t *testing.T) {
 			sqliteStmt: "CREATE TABLE samples (id INTEGER PRIMARY KEY AUTOINCREMENT, message TEXT, UNIQUE(gid, message))",
 			expected:   "CREATE TABLE samples (id SERIAL PRIMARY KEY, message TEXT, message_hash TEXT GENERATED ALWAYS AS (encode(sha256(message::bytea), 'hex')) STORED,\n            UNIQUE(gid, message_hash))",
 		},
+		{
+			name:       "Convert challenges table",
+			tableName:  "challenges",
+			sqliteStmt: "CREATE TABLE challenges (id INTEGER PRIMARY KEY AUTOINCREMENT, chat_id INTEGER NOT NULL, user_id INTEGER NOT NULL)",
+			expected:   "CREATE TABLE challenges (id SERIAL PRIMARY KEY, chat_id BIGINT NOT NULL, user_id BIGINT NOT NULL)",
+		},
+		{
+			name:       "Convert image_hashes table",
+			tableName:  "image_hashes",
+			sqliteStmt: "CREATE TABLE image_hashes (id INTEGER PRIMARY KEY AUTOINCREMENT, hash TEXT NOT NULL, user_id INTEGER NOT NULL)",
+			expected:   "CREATE TABLE image_hashes (id SERIAL PRIMARY KEY, hash TEXT NOT NULL, user_id BIGINT NOT NULL)",
+		},
 	}
 
 	for _, tt := range tests {
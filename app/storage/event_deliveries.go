@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// EventDeliveries is a persistent queue of events waiting for delivery to outgoing webhooks.
+// Each event is queued once per webhook url and removed after successful delivery, so pending
+// deliveries survive restarts.
+type EventDeliveries struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// EventDelivery is a single event queued for delivery to a webhook url
+type EventDelivery struct {
+	ID            int64     `db:"id"`
+	GID           string    `db:"gid"`
+	URL           string    `db:"url"`
+	EventType     string    `db:"event_type"`
+	Payload       string    `db:"payload"` // json encoded event, sent as is
+	Attempts      int       `db:"attempts"`
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	LastError     string    `db:"last_error"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// event deliveries command constants
+const (
+	CmdCreateEventDeliveriesTable engine.DBCmd = iota + 1500
+	CmdCreateEventDeliveriesIndexes
+)
+
+// eventDeliveriesQueries holds all event deliveries queries
+var eventDeliveriesQueries = engine.NewQueryMap().
+	Add(CmdCreateEventDeliveriesTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS event_deliveries (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            url TEXT NOT NULL,
+            event_type TEXT NOT NULL,
+            payload TEXT NOT NULL,
+            attempts INTEGER NOT NULL DEFAULT 0,
+            next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_error TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS event_deliveries (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            url TEXT NOT NULL,
+            event_type TEXT NOT NULL,
+            payload TEXT NOT NULL,
+            attempts INTEGER NOT NULL DEFAULT 0,
+            next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_error TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+	}).
+	AddSame(CmdCreateEventDeliveriesIndexes,
+		`CREATE INDEX IF NOT EXISTS idx_event_deliveries_gid_next ON event_deliveries(gid, next_attempt_at)`)
+
+// NewEventDeliveries creates a new EventDeliveries storage and initializes the underlying table
+func NewEventDeliveries(ctx context.Context, db *engine.SQL) (*EventDeliveries, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &EventDeliveries{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "event_deliveries",
+		CreateTable:   CmdCreateEventDeliveriesTable,
+		CreateIndexes: CmdCreateEventDeliveriesIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    eventDeliveriesQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init event deliveries storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for event_deliveries table (new table, no migration needed)
+func (e *EventDeliveries) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add queues deliveries in a single transaction, due immediately
+func (e *EventDeliveries) Add(ctx context.Context, deliveries []EventDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	tx, err := e.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := e.Adopt("INSERT INTO event_deliveries (gid, url, event_type, payload, attempts, next_attempt_at, created_at) " +
+		"VALUES (?, ?, ?, ?, 0, ?, ?)")
+	for _, d := range deliveries {
+		if _, err := tx.ExecContext(ctx, query, e.GID(), d.URL, d.EventType, d.Payload, now, now); err != nil {
+			return fmt.Errorf("failed to add %s delivery to %s: %w", d.EventType, d.URL, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deliveries: %w", err)
+	}
+	return nil
+}
+
+// Due returns up to limit deliveries with the next attempt time not after now, oldest first
+func (e *EventDeliveries) Due(ctx context.Context, now time.Time, limit int) ([]EventDelivery, error) {
+	e.RLock()
+	defer e.RUnlock()
+
+	var res []EventDelivery
+	query := e.Adopt("SELECT id, gid, url, event_type, payload, attempts, next_attempt_at, last_error, created_at " +
+		"FROM event_deliveries WHERE gid = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?")
+	if err := e.SelectContext(ctx, &res, query, e.GID(), now, limit); err != nil {
+		return nil, fmt.Errorf("failed to get due deliveries: %w", err)
+	}
+	return res, nil
+}
+
+// Retry records a failed attempt and reschedules the delivery
+func (e *EventDeliveries) Retry(ctx context.Context, id int64, next time.Time, lastErr string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	query := e.Adopt("UPDATE event_deliveries SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? " +
+		"WHERE gid = ? AND id = ?")
+	if _, err := e.ExecContext(ctx, query, next, lastErr, e.GID(), id); err != nil {
+		return fmt.Errorf("failed to reschedule delivery %d: %w", id, err)
+	}
+	return nil
+}
+
+// Delete removes the delivery from the queue, after it is delivered or dropped
+func (e *EventDeliveries) Delete(ctx context.Context, id int64) error {
+	e.Lock()
+	defer e.Unlock()
+
+	query := e.Adopt("DELETE FROM event_deliveries WHERE gid = ? AND id = ?")
+	if _, err := e.ExecContext(ctx, query, e.GID(), id); err != nil {
+		return fmt.Errorf("failed to delete delivery %d: %w", id, err)
+	}
+	return nil
+}
+
+// Count returns the number of queued deliveries
+func (e *EventDeliveries) Count(ctx context.Context) (int, error) {
+	e.RLock()
+	defer e.RUnlock()
+
+	var count int
+	if err := e.GetContext(ctx, &count, e.Adopt("SELECT COUNT(*) FROM event_deliveries WHERE gid = ?"), e.GID()); err != nil {
+		return 0, fmt.Errorf("failed to count deliveries: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *StorageTestSuite) TestEventDeliveries_NewEventDeliveries() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewEventDeliveries(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE event_deliveries")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM event_deliveries`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewEventDeliveries(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestEventDeliveries_Queue() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			ed, err := NewEventDeliveries(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE event_deliveries")
+
+			s.Require().NoError(ed.Add(ctx, nil), "empty list is no-op")
+			s.Require().NoError(ed.Add(ctx, []EventDelivery{
+				{URL: "https://a.example.com", EventType: "ban", Payload: `{"type":"ban"}`},
+				{URL: "https://b.example.com", EventType: "ban", Payload: `{"type":"ban"}`},
+			}))
+			s.Require().NoError(ed.Add(ctx, []EventDelivery{{URL: "https://a.example.com", EventType: "warn", Payload: `{}`}}))
+			count, err := ed.Count(ctx)
+			s.Require().NoError(err)
+			s.Equal(3, count)
+
+			due, err := ed.Due(ctx, time.Now().Add(time.Second), 10)
+			s.Require().NoError(err)
+			s.Require().Len(due, 3)
+			s.Equal("https://a.example.com", due[0].URL)
+			s.Equal("ban", due[0].EventType)
+			s.Equal(`{"type":"ban"}`, due[0].Payload)
+			s.Equal(0, due[0].Attempts)
+			s.Equal("warn", due[2].EventType)
+
+			due, err = ed.Due(ctx, time.Now().Add(time.Second), 1)
+			s.Require().NoError(err)
+			s.Len(due, 1, "limited")
+
+			s.Run("retry reschedules", func() {
+				s.Require().NoError(ed.Retry(ctx, due[0].ID, time.Now().Add(time.Hour), "status 500"))
+				res, err := ed.Due(ctx, time.Now().Add(time.Second), 10)
+				s.Require().NoError(err)
+				s.Len(res, 2, "rescheduled delivery is not due")
+
+				res, err = ed.Due(ctx, time.Now().Add(2*time.Hour), 10)
+				s.Require().NoError(err)
+				s.Require().Len(res, 3)
+				s.Equal(due[0].ID, res[2].ID, "rescheduled delivery is the last")
+				s.Equal(1, res[2].Attempts)
+				s.Equal("status 500", res[2].LastError)
+			})
+
+			s.Run("delete", func() {
+				s.Require().NoError(ed.Delete(ctx, due[0].ID))
+				count, err := ed.Count(ctx)
+				s.Require().NoError(err)
+				s.Equal(2, count)
+			})
+		})
+	}
+}
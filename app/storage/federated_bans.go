@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+// FederatedBans is a storage for users banned by federation peers, pulled from the peers' feeds.
+// Bans of each peer are replaced as a whole on each pull.
+type FederatedBans struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// federatedBanInfo is a stored federated ban
+type federatedBanInfo struct {
+	Peer     string    `db:"peer"`
+	UserID   int64     `db:"user_id"`
+	UserName string    `db:"user_name"`
+	Reasons  string    `db:"reasons"` // comma separated names of the checks
+	BannedAt time.Time `db:"banned_at"`
+}
+
+// federated bans command constants
+const (
+	CmdCreateFederatedBansTable engine.DBCmd = iota + 1400
+	CmdCreateFederatedBansIndexes
+	CmdAddFederatedBan
+)
+
+// federatedBansQueries holds all federated bans queries
+var federatedBansQueries = engine.NewQueryMap().
+	Add(CmdCreateFederatedBansTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS federated_bans (
+            gid TEXT NOT NULL DEFAULT '',
+            peer TEXT NOT NULL,
+            user_id INTEGER NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            reasons TEXT NOT NULL DEFAULT '',
+            banned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (gid, peer, user_id)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS federated_bans (
+            gid TEXT NOT NULL DEFAULT '',
+            peer TEXT NOT NULL,
+            user_id BIGINT NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            reasons TEXT NOT NULL DEFAULT '',
+            banned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (gid, peer, user_id)
+        )`,
+	}).
+	AddSame(CmdCreateFederatedBansIndexes, `CREATE INDEX IF NOT EXISTS idx_federated_bans_gid_user ON federated_bans(gid, user_id)`).
+	Add(CmdAddFederatedBan, engine.Query{
+		Sqlite: `INSERT OR IGNORE INTO federated_bans (gid, peer, user_id, user_name, reasons, banned_at)
+            VALUES (?, ?, ?, ?, ?, ?)`,
+		Postgres: `INSERT INTO federated_bans (gid, peer, user_id, user_name, reasons, banned_at)
+            VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (gid, peer, user_id) DO NOTHING`,
+	})
+
+// NewFederatedBans creates a new FederatedBans storage and initializes the underlying table
+func NewFederatedBans(ctx context.Context, db *engine.SQL) (*FederatedBans, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &FederatedBans{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "federated_bans",
+		CreateTable:   CmdCreateFederatedBansTable,
+		CreateIndexes: CmdCreateFederatedBansIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    federatedBansQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init federated bans storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for federated_bans table (new table, no migration needed)
+func (f *FederatedBans) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// ReplacePeerBans replaces all stored bans of the peer with the given list in a single transaction.
+// Empty list removes all bans of the peer. Implements tgspam.FederationStore.
+func (f *FederatedBans) ReplacePeerBans(ctx context.Context, peer string, bans []tgspam.FederatedBan) error {
+	if peer == "" {
+		return fmt.Errorf("empty peer name")
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	tx, err := f.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	gid := f.GID()
+	if _, err = tx.ExecContext(ctx, f.Adopt("DELETE FROM federated_bans WHERE gid = ? AND peer = ?"), gid, peer); err != nil {
+		return fmt.Errorf("failed to remove old bans of %q: %w", peer, err)
+	}
+
+	query, err := federatedBansQueries.Pick(f.Type(), CmdAddFederatedBan)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, b := range bans {
+		bannedAt := b.BannedAt
+		if bannedAt.IsZero() {
+			bannedAt = time.Now()
+		}
+		if _, err = stmt.ExecContext(ctx, gid, peer, b.UserID, b.UserName, strings.Join(b.Reasons, ","), bannedAt); err != nil {
+			return fmt.Errorf("failed to add ban of user %d from %q: %w", b.UserID, peer, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit bans of %q: %w", peer, err)
+	}
+	return nil
+}
+
+// FindFederatedBans returns bans of the user by all peers, ordered by peer name.
+// Implements tgspam.FederationLookup.
+func (f *FederatedBans) FindFederatedBans(ctx context.Context, userID int64) ([]tgspam.FederatedBan, error) {
+	f.RLock()
+	defer f.RUnlock()
+
+	var recs []federatedBanInfo
+	query := f.Adopt("SELECT peer, user_id, user_name, reasons, banned_at FROM federated_bans " +
+		"WHERE gid = ? AND user_id = ? ORDER BY peer")
+	if err := f.SelectContext(ctx, &recs, query, f.GID(), userID); err != nil {
+		return nil, fmt.Errorf("failed to find federated bans of user %d: %w", userID, err)
+	}
+	res := make([]tgspam.FederatedBan, 0, len(recs))
+	for _, r := range recs {
+		b := tgspam.FederatedBan{Peer: r.Peer, UserID: r.UserID, UserName: r.UserName, BannedAt: r.BannedAt.Local()}
+		if r.Reasons != "" {
+			b.Reasons = strings.Split(r.Reasons, ",")
+		}
+		res = append(res, b)
+	}
+	return res, nil
+}
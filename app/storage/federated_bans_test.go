@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+func (s *StorageTestSuite) TestFederatedBans_NewFederatedBans() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewFederatedBans(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE federated_bans")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM federated_bans`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewFederatedBans(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestFederatedBans_ReplaceFind() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			fb, err := NewFederatedBans(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE federated_bans")
+
+			bannedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+			s.Require().NoError(fb.ReplacePeerBans(ctx, "peer2", []tgspam.FederatedBan{
+				{UserID: 100, UserName: "spammer", Reasons: []string{"cas", "classifier"}, BannedAt: bannedAt},
+				{UserID: 200},
+			}))
+			s.Require().NoError(fb.ReplacePeerBans(ctx, "peer1", []tgspam.FederatedBan{{UserID: 100}, {UserID: 100}}))
+
+			s.Run("find by all peers", func() {
+				bans, err := fb.FindFederatedBans(ctx, 100)
+				s.Require().NoError(err)
+				s.Require().Len(bans, 2, "duplicates ignored")
+				s.Equal("peer1", bans[0].Peer)
+				s.Nil(bans[0].Reasons)
+				s.Equal("peer2", bans[1].Peer)
+				s.Equal("spammer", bans[1].UserName)
+				s.Equal([]string{"cas", "classifier"}, bans[1].Reasons)
+				s.WithinDuration(bannedAt, bans[1].BannedAt, time.Second)
+			})
+
+			s.Run("not banned", func() {
+				bans, err := fb.FindFederatedBans(ctx, 300)
+				s.Require().NoError(err)
+				s.Empty(bans)
+			})
+
+			s.Run("replace keeps other peers", func() {
+				s.Require().NoError(fb.ReplacePeerBans(ctx, "peer2", nil))
+				bans, err := fb.FindFederatedBans(ctx, 100)
+				s.Require().NoError(err)
+				s.Require().Len(bans, 1)
+				s.Equal("peer1", bans[0].Peer)
+				bans, err = fb.FindFederatedBans(ctx, 200)
+				s.Require().NoError(err)
+				s.Empty(bans)
+			})
+
+			s.Run("empty peer name", func() {
+				s.Require().Error(fb.ReplacePeerBans(ctx, "", nil))
+			})
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// ImageHashes is a storage for perceptual hashes of spam images, used by the image-hash check.
+// hashes are kept as 16 chars hex strings, each hash is stored once per gid.
+type ImageHashes struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// ImageHash represents a perceptual hash of a spam image with the info about the user posted it
+type ImageHash struct {
+	ID        int64     `db:"id" json:"id"`
+	GID       string    `db:"gid" json:"-"`
+	Hash      string    `db:"hash" json:"hash"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	UserName  string    `db:"user_name" json:"user_name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// image hashes command constants
+const (
+	CmdCreateImageHashesTable engine.DBCmd = iota + 800
+	CmdCreateImageHashesIndexes
+	CmdAddImageHash
+)
+
+// imageHashesQueries holds all image hashes queries
+var imageHashesQueries = engine.NewQueryMap().
+	Add(CmdCreateImageHashesTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS image_hashes (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            hash TEXT NOT NULL,
+            user_id INTEGER NOT NULL DEFAULT 0,
+            user_name TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, hash)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS image_hashes (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            hash TEXT NOT NULL,
+            user_id BIGINT NOT NULL DEFAULT 0,
+            user_name TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, hash)
+        )`,
+	}).
+	AddSame(CmdCreateImageHashesIndexes,
+		`CREATE INDEX IF NOT EXISTS idx_image_hashes_gid_created ON image_hashes(gid, created_at)`).
+	Add(CmdAddImageHash, engine.Query{
+		Sqlite: "INSERT OR IGNORE INTO image_hashes (gid, hash, user_id, user_name, created_at) " +
+			"VALUES (:gid, :hash, :user_id, :user_name, :created_at)",
+		Postgres: "INSERT INTO image_hashes (gid, hash, user_id, user_name, created_at) " +
+			"VALUES (:gid, :hash, :user_id, :user_name, :created_at) ON CONFLICT (gid, hash) DO NOTHING",
+	})
+
+// NewImageHashes creates a new ImageHashes storage and initializes the underlying table
+func NewImageHashes(ctx context.Context, db *engine.SQL) (*ImageHashes, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &ImageHashes{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "image_hashes",
+		CreateTable:   CmdCreateImageHashesTable,
+		CreateIndexes: CmdCreateImageHashesIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    imageHashesQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init image hashes storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for image hashes table (new table, no migration needed)
+func (h *ImageHashes) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add stores the hash of a spam image. Adding an already stored hash is a no-op.
+func (h *ImageHashes) Add(ctx context.Context, hash string, userID int64, userName string) error {
+	if len(hash) != 16 {
+		return fmt.Errorf("invalid image hash %q, expected 16 hex chars", hash)
+	}
+	if _, err := strconv.ParseUint(hash, 16, 64); err != nil {
+		return fmt.Errorf("invalid image hash %q: %w", hash, err)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	rec := ImageHash{GID: h.GID(), Hash: hash, UserID: userID, UserName: userName, CreatedAt: time.Now()}
+	query, err := imageHashesQueries.Pick(h.Type(), CmdAddImageHash)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	if _, err := h.NamedExecContext(ctx, query, rec); err != nil {
+		return fmt.Errorf("failed to insert image hash: %w", err)
+	}
+	log.Printf("[INFO] image hash %s added, user:%s (%d)", hash, userName, userID)
+	return nil
+}
+
+// Delete removes the image hash by its ID
+func (h *ImageHashes) Delete(ctx context.Context, id int64) error {
+	h.Lock()
+	defer h.Unlock()
+
+	// scoped by gid, the id comes from the web API
+	result, err := h.ExecContext(ctx, h.Adopt(`DELETE FROM image_hashes WHERE id = ? AND gid = ?`), id, h.GID())
+	if err != nil {
+		return fmt.Errorf("failed to delete image hash: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("image hash with id %d not found", id)
+	}
+	return nil
+}
+
+// List returns all stored image hashes, newest first
+func (h *ImageHashes) List(ctx context.Context) ([]ImageHash, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var res []ImageHash
+	query := h.Adopt(`SELECT * FROM image_hashes WHERE gid = ? ORDER BY created_at DESC, id DESC`)
+	if err := h.SelectContext(ctx, &res, query, h.GID()); err != nil {
+		return nil, fmt.Errorf("failed to get image hashes: %w", err)
+	}
+	for i := range res {
+		res[i].CreatedAt = res[i].CreatedAt.Local()
+	}
+	return res, nil
+}
+
+// Hashes returns all stored hashes, implements tgspam.ImageHashStore
+func (h *ImageHashes) Hashes(ctx context.Context) ([]string, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	var res []string
+	if err := h.SelectContext(ctx, &res, h.Adopt(`SELECT hash FROM image_hashes WHERE gid = ?`), h.GID()); err != nil {
+		return nil, fmt.Errorf("failed to get image hashes: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+func (s *StorageTestSuite) TestImageHashes_NewImageHashes() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewImageHashes(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE image_hashes")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM image_hashes`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewImageHashes(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestImageHashes_AddListDelete() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			hashes, err := NewImageHashes(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE image_hashes")
+
+			s.Run("add and list", func() {
+				s.Require().NoError(hashes.Add(ctx, "ff00000000000000", 101, "alice"))
+				s.Require().NoError(hashes.Add(ctx, "00000000000000ff", 102, "bob"))
+
+				res, err := hashes.List(ctx)
+				s.Require().NoError(err)
+				s.Require().Len(res, 2)
+				s.Equal("00000000000000ff", res[0].Hash, "newest first")
+				s.Equal(int64(102), res[0].UserID)
+				s.Equal("bob", res[0].UserName)
+				s.Equal("gr1", res[0].GID)
+				s.False(res[0].CreatedAt.IsZero())
+				s.Equal("ff00000000000000", res[1].Hash)
+
+				all, err := hashes.Hashes(ctx)
+				s.Require().NoError(err)
+				s.ElementsMatch([]string{"ff00000000000000", "00000000000000ff"}, all)
+			})
+
+			s.Run("duplicate hash ignored", func() {
+				s.Require().NoError(hashes.Add(ctx, "ff00000000000000", 103, "carol"))
+				res, err := hashes.List(ctx)
+				s.Require().NoError(err)
+				s.Len(res, 2)
+			})
+
+			s.Run("invalid hash", func() {
+				err := hashes.Add(ctx, "abc", 1, "user")
+				s.Require().Error(err)
+				s.Contains(err.Error(), "expected 16 hex chars")
+				err = hashes.Add(ctx, "zz00000000000000", 1, "user")
+				s.Require().Error(err)
+				s.Contains(err.Error(), "invalid image hash")
+			})
+
+			s.Run("delete", func() {
+				res, err := hashes.List(ctx)
+				s.Require().NoError(err)
+				s.Require().Len(res, 2)
+				s.Require().NoError(hashes.Delete(ctx, res[0].ID))
+
+				all, err := hashes.Hashes(ctx)
+				s.Require().NoError(err)
+				s.Equal([]string{"ff00000000000000"}, all)
+
+				err = hashes.Delete(ctx, res[0].ID)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "not found")
+			})
+
+			s.Run("other group hashes not visible", func() {
+				_, err := db.Exec(db.Adopt("INSERT INTO image_hashes (gid, hash) VALUES (?, ?)"), "gr2", "0f0f0f0f0f0f0f0f")
+				s.Require().NoError(err)
+				var id int64
+				err = db.Get(&id, db.Adopt("SELECT id FROM image_hashes WHERE gid = ?"), "gr2")
+				s.Require().NoError(err)
+
+				all, err := hashes.Hashes(ctx)
+				s.Require().NoError(err)
+				s.Equal([]string{"ff00000000000000"}, all)
+				s.Require().Error(hashes.Delete(ctx, id))
+			})
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+// LLMCache is a persistent cache of LLM verdicts, implements tgspam.LLMCache.
+// Verdicts are kept for ttl, and only maxSize newest verdicts are kept per gid.
+type LLMCache struct {
+	*engine.SQL
+	engine.RWLocker
+	ttl     time.Duration
+	maxSize int
+}
+
+// llmCacheEntry is a single cached verdict
+type llmCacheEntry struct {
+	GID       string    `db:"gid"`
+	Hash      string    `db:"hash"`
+	Spam      bool      `db:"spam"`
+	Details   string    `db:"details"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// llm cache command constants
+const (
+	CmdCreateLLMCacheTable engine.DBCmd = iota + 900
+	CmdCreateLLMCacheIndexes
+	CmdPutLLMVerdict
+)
+
+// llmCacheQueries holds all llm cache queries
+var llmCacheQueries = engine.NewQueryMap().
+	Add(CmdCreateLLMCacheTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS llm_cache (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            hash TEXT NOT NULL,
+            spam BOOLEAN NOT NULL DEFAULT 0,
+            details TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, hash)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS llm_cache (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            hash TEXT NOT NULL,
+            spam BOOLEAN NOT NULL DEFAULT false,
+            details TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, hash)
+        )`,
+	}).
+	AddSame(CmdCreateLLMCacheIndexes,
+		`CREATE INDEX IF NOT EXISTS idx_llm_cache_gid_created ON llm_cache(gid, created_at)`).
+	Add(CmdPutLLMVerdict, engine.Query{
+		Sqlite: "INSERT OR REPLACE INTO llm_cache (gid, hash, spam, details, created_at) " +
+			"VALUES (:gid, :hash, :spam, :details, :created_at)",
+		Postgres: "INSERT INTO llm_cache (gid, hash, spam, details, created_at) " +
+			"VALUES (:gid, :hash, :spam, :details, :created_at) " +
+			"ON CONFLICT (gid, hash) DO UPDATE SET spam = EXCLUDED.spam, details = EXCLUDED.details, created_at = EXCLUDED.created_at",
+	})
+
+// NewLLMCache creates a new LLMCache storage keeping up to maxSize verdicts for ttl
+func NewLLMCache(ctx context.Context, ttl time.Duration, maxSize int, db *engine.SQL) (*LLMCache, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &LLMCache{SQL: db, RWLocker: db.MakeLock(), ttl: ttl, maxSize: maxSize}
+	cfg := engine.TableConfig{
+		Name:          "llm_cache",
+		CreateTable:   CmdCreateLLMCacheTable,
+		CreateIndexes: CmdCreateLLMCacheIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    llmCacheQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init llm cache storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for llm cache table (new table, no migration needed)
+func (c *LLMCache) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Get returns the verdict for the key if it is not expired
+func (c *LLMCache) Get(ctx context.Context, key string) (tgspam.LLMVerdict, bool, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var entry llmCacheEntry
+	query := c.Adopt(`SELECT gid, hash, spam, details, created_at FROM llm_cache WHERE gid = ? AND hash = ? AND created_at > ?`)
+	err := c.GetContext(ctx, &entry, query, c.GID(), key, time.Now().Add(-c.ttl))
+	if errors.Is(err, sql.ErrNoRows) {
+		return tgspam.LLMVerdict{}, false, nil
+	}
+	if err != nil {
+		return tgspam.LLMVerdict{}, false, fmt.Errorf("failed to get llm verdict: %w", err)
+	}
+	return tgspam.LLMVerdict{Spam: entry.Spam, Details: entry.Details}, true, nil
+}
+
+// Put stores the verdict for the key and removes expired and excessive verdicts
+func (c *LLMCache) Put(ctx context.Context, key string, verdict tgspam.LLMVerdict) error {
+	c.Lock()
+	defer c.Unlock()
+
+	entry := llmCacheEntry{GID: c.GID(), Hash: key, Spam: verdict.Spam, Details: verdict.Details, CreatedAt: time.Now()}
+	query, err := llmCacheQueries.Pick(c.Type(), CmdPutLLMVerdict)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	if _, err := c.NamedExecContext(ctx, query, entry); err != nil {
+		return fmt.Errorf("failed to insert llm verdict: %w", err)
+	}
+	return c.cleanup(ctx)
+}
+
+// cleanup removes expired verdicts and the oldest ones above maxSize within the same gid
+func (c *LLMCache) cleanup(ctx context.Context) error {
+	query := c.Adopt(`DELETE FROM llm_cache WHERE gid = ? AND created_at <= ?`)
+	if _, err := c.ExecContext(ctx, query, c.GID(), time.Now().Add(-c.ttl)); err != nil {
+		return fmt.Errorf("failed to cleanup expired llm verdicts: %w", err)
+	}
+	if c.maxSize <= 0 {
+		return nil
+	}
+	query = c.Adopt(`DELETE FROM llm_cache WHERE gid = ? AND id NOT IN
+		(SELECT id FROM llm_cache WHERE gid = ? ORDER BY created_at DESC, id DESC LIMIT ?)`)
+	if _, err := c.ExecContext(ctx, query, c.GID(), c.GID(), c.maxSize); err != nil {
+		return fmt.Errorf("failed to cleanup excessive llm verdicts: %w", err)
+	}
+	return nil
+}
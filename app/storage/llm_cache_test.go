@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+func (s *StorageTestSuite) TestLLMCache_NewLLMCache() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewLLMCache(ctx, time.Hour, 10, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE llm_cache")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM llm_cache`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewLLMCache(ctx, time.Hour, 10, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestLLMCache_GetPut() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			c, err := NewLLMCache(ctx, time.Hour, 2, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE llm_cache")
+
+			s.Run("miss", func() {
+				_, ok, err := c.Get(ctx, "key1")
+				s.Require().NoError(err)
+				s.False(ok)
+			})
+
+			s.Run("put and get", func() {
+				s.Require().NoError(c.Put(ctx, "key1", tgspam.LLMVerdict{Spam: true, Details: "scam"}))
+				v, ok, err := c.Get(ctx, "key1")
+				s.Require().NoError(err)
+				s.True(ok)
+				s.Equal(tgspam.LLMVerdict{Spam: true, Details: "scam"}, v)
+			})
+
+			s.Run("put replaces verdict", func() {
+				s.Require().NoError(c.Put(ctx, "key1", tgspam.LLMVerdict{Spam: false, Details: "ok"}))
+				v, ok, err := c.Get(ctx, "key1")
+				s.Require().NoError(err)
+				s.True(ok)
+				s.Equal(tgspam.LLMVerdict{Spam: false, Details: "ok"}, v)
+			})
+
+			s.Run("max size", func() {
+				s.Require().NoError(c.Put(ctx, "key2", tgspam.LLMVerdict{Details: "2"}))
+				time.Sleep(10 * time.Millisecond) // make sure created_at differs
+				s.Require().NoError(c.Put(ctx, "key3", tgspam.LLMVerdict{Details: "3"}))
+
+				var count int
+				s.Require().NoError(db.Get(&count, db.Adopt(`SELECT COUNT(*) FROM llm_cache WHERE gid = ?`), db.GID()))
+				s.Equal(2, count)
+				_, ok, err := c.Get(ctx, "key3")
+				s.Require().NoError(err)
+				s.True(ok, "newest verdict kept")
+			})
+
+			s.Run("expired", func() {
+				_, err := db.Exec(db.Adopt(`UPDATE llm_cache SET created_at = ? WHERE hash = ?`),
+					time.Now().Add(-2*time.Hour), "key3")
+				s.Require().NoError(err)
+				_, ok, err := c.Get(ctx, "key3")
+				s.Require().NoError(err)
+				s.False(ok)
+			})
+
+			s.Run("other group verdicts not visible", func() {
+				_, err := db.Exec(db.Adopt(`INSERT INTO llm_cache (gid, hash, spam, details, created_at) VALUES (?, ?, ?, ?, ?)`),
+					"gr2", "key9", true, "other", time.Now())
+				s.Require().NoError(err)
+				_, ok, err := c.Get(ctx, "key9")
+				s.Require().NoError(err)
+				s.False(ok)
+			})
+		})
+	}
+}
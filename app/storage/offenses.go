@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// Offenses is a storage of spam offenses punished by the bot, used by the escalating punishment ladder
+// to pick the next step and to show the history in admin notifications.
+type Offenses struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// Offense is a single punished spam detection of a user or channel
+type Offense struct {
+	ID        int64     `db:"id"`
+	GID       string    `db:"gid"`
+	UserID    int64     `db:"user_id"`
+	UserName  string    `db:"user_name"`
+	Checks    string    `db:"checks"` // names of checks detected the spam, comma separated
+	Action    string    `db:"action"` // punishment applied, e.g. "restricted for 10m0s"
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// OffensesRetention is the storage cap for offense rows, older rows are pruned on Add.
+// The configured punishment window must not exceed it.
+const OffensesRetention = 365 * 24 * time.Hour
+
+// offenses-related command constants
+const (
+	CmdCreateOffensesTable engine.DBCmd = iota + 1600
+	CmdCreateOffensesIndexes
+	CmdAddOffense
+	CmdListOffensesSince
+	CmdCleanupOffenses
+)
+
+// offensesQueries holds all offenses-related queries
+var offensesQueries = engine.NewQueryMap().
+	Add(CmdCreateOffensesTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS offenses (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            user_id INTEGER NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            checks TEXT NOT NULL DEFAULT '',
+            action TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS offenses (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            user_id BIGINT NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            checks TEXT NOT NULL DEFAULT '',
+            action TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+	}).
+	AddSame(CmdCreateOffensesIndexes,
+		`CREATE INDEX IF NOT EXISTS idx_offenses_gid_user_created ON offenses(gid, user_id, created_at)`).
+	AddSame(CmdAddOffense, "INSERT INTO offenses (gid, user_id, user_name, checks, action, created_at) "+
+		"VALUES (:gid, :user_id, :user_name, :checks, :action, :created_at)").
+	AddSame(CmdListOffensesSince, "SELECT id, gid, user_id, user_name, checks, action, created_at FROM offenses "+
+		"WHERE gid = ? AND user_id = ? AND created_at > ? ORDER BY created_at DESC, id DESC").
+	AddSame(CmdCleanupOffenses, "DELETE FROM offenses WHERE gid = ? AND created_at < ?")
+
+// NewOffenses creates a new Offenses storage and initializes the underlying table
+func NewOffenses(ctx context.Context, db *engine.SQL) (*Offenses, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &Offenses{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "offenses",
+		CreateTable:   CmdCreateOffensesTable,
+		CreateIndexes: CmdCreateOffensesIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    offensesQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init offenses storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for offenses table (new table, no migration needed)
+func (o *Offenses) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add records the offense and prunes rows older than OffensesRetention. gid is set internally,
+// created_at is set to now if empty. Pruning errors are logged but do not fail the call.
+func (o *Offenses) Add(ctx context.Context, offense Offense) error {
+	o.Lock()
+	defer o.Unlock()
+
+	offense.GID = o.GID()
+	if offense.CreatedAt.IsZero() {
+		offense.CreatedAt = time.Now()
+	}
+	query, err := offensesQueries.Pick(o.Type(), CmdAddOffense)
+	if err != nil {
+		return fmt.Errorf("failed to get insert query: %w", err)
+	}
+	if _, err := o.NamedExecContext(ctx, query, offense); err != nil {
+		return fmt.Errorf("failed to insert offense: %w", err)
+	}
+
+	if err := o.cleanupOld(ctx); err != nil {
+		log.Printf("[WARN] failed to cleanup old offenses: %v", err)
+	}
+	return nil
+}
+
+// Since returns offenses of the user or channel recorded after the given time, newest first
+func (o *Offenses) Since(ctx context.Context, userID int64, since time.Time) ([]Offense, error) {
+	o.RLock()
+	defer o.RUnlock()
+
+	query, err := offensesQueries.Pick(o.Type(), CmdListOffensesSince)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query: %w", err)
+	}
+	var res []Offense
+	if err := o.SelectContext(ctx, &res, o.Adopt(query), o.GID(), userID, since); err != nil {
+		return nil, fmt.Errorf("failed to get offenses of %d: %w", userID, err)
+	}
+	for i := range res {
+		res[i].CreatedAt = res[i].CreatedAt.Local()
+	}
+	return res, nil
+}
+
+// cleanupOld deletes offense rows older than OffensesRetention. called from Add (already locked).
+func (o *Offenses) cleanupOld(ctx context.Context) error {
+	query, err := offensesQueries.Pick(o.Type(), CmdCleanupOffenses)
+	if err != nil {
+		return fmt.Errorf("failed to get cleanup query: %w", err)
+	}
+	result, err := o.ExecContext(ctx, o.Adopt(query), o.GID(), time.Now().Add(-OffensesRetention))
+	if err != nil {
+		return fmt.Errorf("failed to cleanup old offenses: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected > 0 {
+		log.Printf("[DEBUG] cleaned up %d old offenses (retention: %s)", rowsAffected, OffensesRetention)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *StorageTestSuite) TestOffenses() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			offenses, err := NewOffenses(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE offenses")
+
+			now := time.Now()
+			s.Require().NoError(offenses.Add(ctx, Offense{UserID: 1, UserName: "spammer", Checks: "stopword",
+				Action: "restricted for 10m0s", CreatedAt: now.Add(-48 * time.Hour)}))
+			s.Require().NoError(offenses.Add(ctx, Offense{UserID: 1, UserName: "spammer", Checks: "classifier,links",
+				Action: "restricted for 24h0m0s", CreatedAt: now.Add(-time.Hour)}))
+			s.Require().NoError(offenses.Add(ctx, Offense{UserID: 2, UserName: "other", Checks: "cas",
+				Action: "permanently banned"}))
+
+			res, err := offenses.Since(ctx, 1, now.Add(-72*time.Hour))
+			s.Require().NoError(err)
+			s.Require().Len(res, 2)
+			s.Equal("classifier,links", res[0].Checks, "newest first")
+			s.Equal("restricted for 24h0m0s", res[0].Action)
+			s.Equal("spammer", res[0].UserName)
+			s.Equal(db.GID(), res[0].GID)
+			s.WithinDuration(now.Add(-time.Hour), res[0].CreatedAt, time.Second)
+			s.Equal("stopword", res[1].Checks)
+
+			res, err = offenses.Since(ctx, 1, now.Add(-24*time.Hour))
+			s.Require().NoError(err)
+			s.Len(res, 1, "older offenses are outside of the window")
+
+			res, err = offenses.Since(ctx, 2, now.Add(-time.Minute))
+			s.Require().NoError(err)
+			s.Require().Len(res, 1)
+			s.WithinDuration(now, res[0].CreatedAt, 5*time.Second, "created_at set on add")
+
+			res, err = offenses.Since(ctx, 3, now.Add(-time.Hour))
+			s.Require().NoError(err)
+			s.Empty(res)
+
+			s.Run("old offenses pruned", func() {
+				s.Require().NoError(offenses.Add(ctx, Offense{UserID: 4, CreatedAt: now.Add(-OffensesRetention - time.Hour)}))
+				s.Require().NoError(offenses.Add(ctx, Offense{UserID: 4}))
+				var count int
+				s.Require().NoError(db.Get(&count, "SELECT COUNT(*) FROM offenses WHERE user_id = 4"))
+				s.Equal(1, count)
+			})
+		})
+	}
+
+	s.Run("nil db connection", func() {
+		_, err := NewOffenses(ctx, nil)
+		s.Require().Error(err)
+	})
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// Reputation is a storage for per-user history counters, kept across sessions and restarts.
+// It is used to weight user reports and to hold back approval of users with bad history.
+type Reputation struct {
+	*engine.SQL
+	engine.RWLocker
+	approvalHold time.Duration
+}
+
+// ReputationEvent is a kind of user history event, each event has its own counter
+type ReputationEvent string
+
+// reputation events, values are the names of the counter columns
+const (
+	RepMessageChecked  ReputationEvent = "messages"         // message checked by spam detector
+	RepSpamHit         ReputationEvent = "spam"             // message detected as spam
+	RepWarning         ReputationEvent = "warnings"         // warned by admin with /warn
+	RepReportReceived  ReputationEvent = "reports_received" // message reported by other user
+	RepReportUpheld    ReputationEvent = "reports_upheld"   // report filed by the user confirmed by admin or auto-ban
+	RepReportRejected  ReputationEvent = "reports_rejected" // report filed by the user rejected by admin
+	RepReactionFlagged ReputationEvent = "reactions"        // banned for reaction spam
+)
+
+// UserReputation is a history of a single user
+type UserReputation struct {
+	GID             string     `db:"gid" json:"-"`
+	UserID          int64      `db:"user_id" json:"user_id"`
+	UserName        string     `db:"user_name" json:"user_name"`
+	Messages        int        `db:"messages" json:"messages"`
+	Spam            int        `db:"spam" json:"spam"`
+	Warnings        int        `db:"warnings" json:"warnings"`
+	ReportsReceived int        `db:"reports_received" json:"reports_received"`
+	ReportsUpheld   int        `db:"reports_upheld" json:"reports_upheld"`
+	ReportsRejected int        `db:"reports_rejected" json:"reports_rejected"`
+	Reactions       int        `db:"reactions" json:"reactions"`
+	LastWarningAt   *time.Time `db:"last_warning_at" json:"last_warning_at,omitempty"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// maxCleanMessagesScore limits the score earned by clean messages, so a long history of chatting
+// can't outweigh spam hits and warnings
+const maxCleanMessagesScore = 50
+
+// Score returns reputation score of the user. Clean messages and upheld reports increase the score,
+// spam hits, warnings, flagged reactions and rejected reports decrease it. Reports received are not counted,
+// only the confirmed outcome matters.
+func (u UserReputation) Score() int {
+	clean := min(max(u.Messages-u.Spam, 0), maxCleanMessagesScore)
+	return clean + 5*u.ReportsUpheld - 3*u.ReportsRejected - 10*u.Warnings - 20*u.Spam - 20*u.Reactions
+}
+
+// reputation command constants
+const (
+	CmdCreateReputationTable engine.DBCmd = iota + 1200
+	CmdCreateReputationIndexes
+)
+
+// reputationQueries holds all reputation queries
+var reputationQueries = engine.NewQueryMap().
+	Add(CmdCreateReputationTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS reputation (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            user_id INTEGER NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            messages INTEGER NOT NULL DEFAULT 0,
+            spam INTEGER NOT NULL DEFAULT 0,
+            warnings INTEGER NOT NULL DEFAULT 0,
+            reports_received INTEGER NOT NULL DEFAULT 0,
+            reports_upheld INTEGER NOT NULL DEFAULT 0,
+            reports_rejected INTEGER NOT NULL DEFAULT 0,
+            reactions INTEGER NOT NULL DEFAULT 0,
+            last_warning_at TIMESTAMP,
+            updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, user_id)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS reputation (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            user_id BIGINT NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            messages INTEGER NOT NULL DEFAULT 0,
+            spam INTEGER NOT NULL DEFAULT 0,
+            warnings INTEGER NOT NULL DEFAULT 0,
+            reports_received INTEGER NOT NULL DEFAULT 0,
+            reports_upheld INTEGER NOT NULL DEFAULT 0,
+            reports_rejected INTEGER NOT NULL DEFAULT 0,
+            reactions INTEGER NOT NULL DEFAULT 0,
+            last_warning_at TIMESTAMP,
+            updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE(gid, user_id)
+        )`,
+	}).
+	AddSame(CmdCreateReputationIndexes, `CREATE INDEX IF NOT EXISTS idx_reputation_gid_updated ON reputation(gid, updated_at DESC)`)
+
+// NewReputation creates a new Reputation storage and initializes the underlying table.
+// approvalHold is the period after a warning during which the user is not approved, 0 disables the hold.
+func NewReputation(ctx context.Context, approvalHold time.Duration, db *engine.SQL) (*Reputation, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &Reputation{SQL: db, RWLocker: db.MakeLock(), approvalHold: approvalHold}
+	cfg := engine.TableConfig{
+		Name:          "reputation",
+		CreateTable:   CmdCreateReputationTable,
+		CreateIndexes: CmdCreateReputationIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    reputationQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init reputation storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for reputation table (new table, no migration needed)
+func (r *Reputation) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Inc increments the counter of the event for the user, creating the user record if needed.
+// Empty userName keeps the stored one.
+func (r *Reputation) Inc(ctx context.Context, userID int64, userName string, ev ReputationEvent) error {
+	switch ev {
+	case RepMessageChecked, RepSpamHit, RepWarning, RepReportReceived, RepReportUpheld, RepReportRejected, RepReactionFlagged:
+	default:
+		return fmt.Errorf("invalid reputation event %q", ev)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	var warnedAt *time.Time
+	if ev == RepWarning {
+		warnedAt = &now
+	}
+	// column name is safe to embed, it is one of the constants checked above
+	query := r.Adopt(fmt.Sprintf("INSERT INTO reputation (gid, user_id, user_name, %[1]s, last_warning_at, updated_at) "+
+		"VALUES (?, ?, ?, 1, ?, ?) ON CONFLICT (gid, user_id) DO UPDATE SET %[1]s = reputation.%[1]s + 1, "+
+		"user_name = CASE WHEN EXCLUDED.user_name <> '' THEN EXCLUDED.user_name ELSE reputation.user_name END, "+
+		"last_warning_at = COALESCE(EXCLUDED.last_warning_at, reputation.last_warning_at), updated_at = EXCLUDED.updated_at",
+		ev))
+	if _, err := r.ExecContext(ctx, query, r.GID(), userID, userName, warnedAt, now); err != nil {
+		return fmt.Errorf("failed to update %s of user %d: %w", ev, userID, err)
+	}
+	return nil
+}
+
+// Get returns the reputation of the user. Users without history get an empty record, not an error.
+func (r *Reputation) Get(ctx context.Context, userID int64) (UserReputation, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var res UserReputation
+	query := r.Adopt("SELECT gid, user_id, user_name, messages, spam, warnings, reports_received, reports_upheld, " +
+		"reports_rejected, reactions, last_warning_at, updated_at FROM reputation WHERE gid = ? AND user_id = ?")
+	err := r.GetContext(ctx, &res, query, r.GID(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserReputation{GID: r.GID(), UserID: userID}, nil
+	}
+	if err != nil {
+		return UserReputation{}, fmt.Errorf("failed to get reputation of user %d: %w", userID, err)
+	}
+	res.UpdatedAt = res.UpdatedAt.Local()
+	if res.LastWarningAt != nil {
+		t := res.LastWarningAt.Local()
+		res.LastWarningAt = &t
+	}
+	return res, nil
+}
+
+// HoldApproval reports whether the user should not be approved yet: the user was warned within the approval hold
+// period or has a negative score. Implements tgspam.ApprovalGuard.
+func (r *Reputation) HoldApproval(ctx context.Context, userID string) (hold bool, reason string, err error) {
+	uid, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid user id %q: %w", userID, err)
+	}
+	rep, err := r.Get(ctx, uid)
+	if err != nil {
+		return false, "", err
+	}
+	if r.approvalHold > 0 && rep.LastWarningAt != nil && time.Since(*rep.LastWarningAt) < r.approvalHold {
+		return true, fmt.Sprintf("warned %v ago", time.Since(*rep.LastWarningAt).Round(time.Minute)), nil
+	}
+	if score := rep.Score(); score < 0 {
+		return true, fmt.Sprintf("negative reputation score %d", score), nil
+	}
+	return false, "", nil
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *StorageTestSuite) TestReputation_NewReputation() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewReputation(ctx, time.Hour, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE reputation")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM reputation`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewReputation(ctx, time.Hour, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestReputation_IncGet() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			rep, err := NewReputation(ctx, time.Hour, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE reputation")
+
+			s.Run("unknown user", func() {
+				res, err := rep.Get(ctx, 100)
+				s.Require().NoError(err)
+				s.Equal(UserReputation{GID: "gr1", UserID: 100}, res)
+				s.Equal(0, res.Score())
+			})
+
+			s.Run("counters", func() {
+				for range 3 {
+					s.Require().NoError(rep.Inc(ctx, 101, "alice", RepMessageChecked))
+				}
+				s.Require().NoError(rep.Inc(ctx, 101, "", RepReportUpheld))
+				s.Require().NoError(rep.Inc(ctx, 101, "", RepReportRejected))
+				s.Require().NoError(rep.Inc(ctx, 101, "", RepReportReceived))
+				s.Require().NoError(rep.Inc(ctx, 102, "bob", RepSpamHit))
+
+				res, err := rep.Get(ctx, 101)
+				s.Require().NoError(err)
+				s.Equal("alice", res.UserName, "empty name keeps the stored one")
+				s.Equal(3, res.Messages)
+				s.Equal(1, res.ReportsUpheld)
+				s.Equal(1, res.ReportsRejected)
+				s.Equal(1, res.ReportsReceived)
+				s.Equal(0, res.Spam)
+				s.Nil(res.LastWarningAt)
+				s.False(res.UpdatedAt.IsZero())
+				s.Equal(3+5-3, res.Score())
+
+				res, err = rep.Get(ctx, 102)
+				s.Require().NoError(err)
+				s.Equal(1, res.Spam)
+				s.Equal(-20, res.Score())
+			})
+
+			s.Run("warning sets last warning time", func() {
+				s.Require().NoError(rep.Inc(ctx, 103, "carol", RepWarning))
+				res, err := rep.Get(ctx, 103)
+				s.Require().NoError(err)
+				s.Equal(1, res.Warnings)
+				s.Require().NotNil(res.LastWarningAt)
+				s.WithinDuration(time.Now(), *res.LastWarningAt, time.Minute)
+
+				s.Require().NoError(rep.Inc(ctx, 103, "carol", RepMessageChecked))
+				res, err = rep.Get(ctx, 103)
+				s.Require().NoError(err)
+				s.NotNil(res.LastWarningAt, "other events keep last warning time")
+			})
+
+			s.Run("invalid event", func() {
+				err := rep.Inc(ctx, 101, "alice", ReputationEvent("messages = 0; --"))
+				s.Require().Error(err)
+				s.Contains(err.Error(), "invalid reputation event")
+			})
+
+			s.Run("other group history not visible", func() {
+				_, err := db.Exec(db.Adopt("INSERT INTO reputation (gid, user_id, spam) VALUES (?, ?, ?)"), "gr2", 104, 5)
+				s.Require().NoError(err)
+				res, err := rep.Get(ctx, 104)
+				s.Require().NoError(err)
+				s.Equal(0, res.Spam)
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestReputation_HoldApproval() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			rep, err := NewReputation(ctx, time.Hour, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE reputation")
+
+			for range 20 {
+				s.Require().NoError(rep.Inc(ctx, 101, "alice", RepMessageChecked))
+			}
+			s.Require().NoError(rep.Inc(ctx, 102, "bob", RepMessageChecked))
+			s.Require().NoError(rep.Inc(ctx, 102, "bob", RepReactionFlagged))
+
+			hold, reason, err := rep.HoldApproval(ctx, "101")
+			s.Require().NoError(err)
+			s.False(hold, "clean history")
+			s.Empty(reason)
+
+			hold, reason, err = rep.HoldApproval(ctx, "102")
+			s.Require().NoError(err)
+			s.True(hold)
+			s.Equal("negative reputation score -19", reason)
+
+			s.Require().NoError(rep.Inc(ctx, 101, "alice", RepWarning))
+			hold, reason, err = rep.HoldApproval(ctx, "101")
+			s.Require().NoError(err)
+			s.True(hold, "score is positive, but warned recently")
+			s.Contains(reason, "warned")
+
+			past := time.Now().Add(-2 * time.Hour)
+			_, err = db.Exec(db.Adopt("UPDATE reputation SET last_warning_at = ? WHERE user_id = ?"), past, 101)
+			s.Require().NoError(err)
+			hold, _, err = rep.HoldApproval(ctx, "101")
+			s.Require().NoError(err)
+			s.False(hold, "warning is older than approval hold")
+
+			_, _, err = rep.HoldApproval(ctx, "bad")
+			s.Require().Error(err)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+const maxReviewEntries = 500
+
+// Reviews is a storage for the admin review queue of quarantined (suspicious) messages
+type Reviews struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// ReviewStatus is a status of a quarantined message
+type ReviewStatus string
+
+// review statuses
+const (
+	ReviewPending  ReviewStatus = "pending"  // waiting for admin decision, the message is hidden
+	ReviewApproved ReviewStatus = "approved" // approved by admin, the message is restored
+	ReviewBanned   ReviewStatus = "banned"   // the user is banned by admin
+)
+
+// Review represents a quarantined message waiting for or resolved by admin review
+type Review struct {
+	ID         int64                `db:"id" json:"id"`
+	GID        string               `db:"gid" json:"-"`
+	ChatID     int64                `db:"chat_id" json:"chat_id"`
+	MsgID      int                  `db:"msg_id" json:"msg_id"`
+	UserID     int64                `db:"user_id" json:"user_id"`
+	UserName   string               `db:"user_name" json:"user_name"`
+	Text       string               `db:"text" json:"text"`
+	ChecksJSON string               `db:"checks" json:"-"`
+	Checks     []spamcheck.Response `db:"-" json:"checks"`
+	Status     ReviewStatus         `db:"status" json:"status"`
+	ResolvedBy string               `db:"resolved_by" json:"resolved_by,omitempty"`
+	CreatedAt  time.Time            `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time            `db:"updated_at" json:"updated_at"`
+}
+
+// review queue command constants
+const (
+	CmdCreateReviewsTable engine.DBCmd = iota + 1100
+	CmdCreateReviewsIndexes
+)
+
+// reviewsQueries holds all review queue queries
+var reviewsQueries = engine.NewQueryMap().
+	Add(CmdCreateReviewsTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS reviews (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            chat_id INTEGER NOT NULL,
+            msg_id INTEGER NOT NULL,
+            user_id INTEGER NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            text TEXT NOT NULL DEFAULT '',
+            checks TEXT NOT NULL DEFAULT '[]',
+            status TEXT NOT NULL DEFAULT 'pending',
+            resolved_by TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS reviews (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            chat_id BIGINT NOT NULL,
+            msg_id INTEGER NOT NULL,
+            user_id BIGINT NOT NULL,
+            user_name TEXT NOT NULL DEFAULT '',
+            text TEXT NOT NULL DEFAULT '',
+            checks TEXT NOT NULL DEFAULT '[]',
+            status TEXT NOT NULL DEFAULT 'pending',
+            resolved_by TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+	}).
+	AddSame(CmdCreateReviewsIndexes, `
+		CREATE INDEX IF NOT EXISTS idx_reviews_gid_created ON reviews(gid, created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_reviews_gid_status ON reviews(gid, status)`)
+
+// NewReviews creates a new Reviews storage and initializes the underlying table
+func NewReviews(ctx context.Context, db *engine.SQL) (*Reviews, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &Reviews{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "reviews",
+		CreateTable:   CmdCreateReviewsTable,
+		CreateIndexes: CmdCreateReviewsIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    reviewsQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init reviews storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for reviews table (new table, no migration needed)
+func (r *Reviews) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add puts a quarantined message to the queue as pending and returns its id
+func (r *Reviews) Add(ctx context.Context, rv Review) (int64, error) {
+	checksJSON, err := json.Marshal(rv.Checks)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal checks: %w", err)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	query := r.Adopt("INSERT INTO reviews (gid, chat_id, msg_id, user_id, user_name, text, checks, status, " +
+		"created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id")
+	var id int64
+	err = r.GetContext(ctx, &id, query, r.GID(), rv.ChatID, rv.MsgID, rv.UserID, rv.UserName, rv.Text,
+		string(checksJSON), ReviewPending, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert review: %w", err)
+	}
+	log.Printf("[INFO] message %d from %s (%d) added to review queue, id:%d", rv.MsgID, rv.UserName, rv.UserID, id)
+	return id, nil
+}
+
+// Get returns the review by its id
+func (r *Reviews) Get(ctx context.Context, id int64) (Review, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var res Review
+	err := r.GetContext(ctx, &res, r.Adopt("SELECT * FROM reviews WHERE id = ? AND gid = ?"), id, r.GID())
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, fmt.Errorf("review %d not found", id)
+	}
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to get review %d: %w", id, err)
+	}
+	if err := res.unmarshal(); err != nil {
+		return Review{}, err
+	}
+	return res, nil
+}
+
+// Resolve sets the final status of a pending review. Resolving an already resolved review is an error,
+// this prevents double processing if several admins click the buttons at the same time.
+func (r *Reviews) Resolve(ctx context.Context, id int64, status ReviewStatus, admin string) error {
+	if status != ReviewApproved && status != ReviewBanned {
+		return fmt.Errorf("invalid review status %q", status)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	query := r.Adopt("UPDATE reviews SET status = ?, resolved_by = ?, updated_at = ? WHERE id = ? AND gid = ? AND status = ?")
+	result, err := r.ExecContext(ctx, query, status, admin, time.Now(), id, r.GID(), ReviewPending)
+	if err != nil {
+		return fmt.Errorf("failed to resolve review %d: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("review %d not found or already resolved", id)
+	}
+	return nil
+}
+
+// List returns the latest reviews, newest first, up to maxReviewEntries
+func (r *Reviews) List(ctx context.Context) ([]Review, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	var res []Review
+	query := r.Adopt("SELECT * FROM reviews WHERE gid = ? ORDER BY created_at DESC, id DESC LIMIT ?")
+	if err := r.SelectContext(ctx, &res, query, r.GID(), maxReviewEntries); err != nil {
+		return nil, fmt.Errorf("failed to get reviews: %w", err)
+	}
+	for i := range res {
+		if err := res[i].unmarshal(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// unmarshal decodes stored checks and converts timestamps to local time
+func (rv *Review) unmarshal() error {
+	if err := json.Unmarshal([]byte(rv.ChecksJSON), &rv.Checks); err != nil {
+		return fmt.Errorf("failed to unmarshal checks of review %d: %w", rv.ID, err)
+	}
+	rv.CreatedAt = rv.CreatedAt.Local()
+	rv.UpdatedAt = rv.UpdatedAt.Local()
+	return nil
+}
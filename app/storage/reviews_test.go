@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func (s *StorageTestSuite) TestReviews_NewReviews() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewReviews(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE reviews")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM reviews`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewReviews(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestReviews_AddGetResolveList() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			reviews, err := NewReviews(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE reviews")
+
+			checks := []spamcheck.Response{{Name: "classifier", Details: "probability of spam: 55.00%, suspicious", Suspicious: true}}
+			id1, err := reviews.Add(ctx, Review{ChatID: -100123, MsgID: 10, UserID: 101, UserName: "alice",
+				Text: "borderline message", Checks: checks})
+			s.Require().NoError(err)
+			id2, err := reviews.Add(ctx, Review{ChatID: -100123, MsgID: 11, UserID: 102, UserName: "bob", Text: "another one"})
+			s.Require().NoError(err)
+			s.NotEqual(id1, id2)
+
+			s.Run("get", func() {
+				rv, err := reviews.Get(ctx, id1)
+				s.Require().NoError(err)
+				s.Equal(int64(-100123), rv.ChatID)
+				s.Equal(10, rv.MsgID)
+				s.Equal(int64(101), rv.UserID)
+				s.Equal("alice", rv.UserName)
+				s.Equal("borderline message", rv.Text)
+				s.Equal(checks, rv.Checks)
+				s.Equal(ReviewPending, rv.Status)
+				s.Equal("gr1", rv.GID)
+				s.False(rv.CreatedAt.IsZero())
+
+				_, err = reviews.Get(ctx, id2+100)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "not found")
+			})
+
+			s.Run("resolve", func() {
+				s.Require().NoError(reviews.Resolve(ctx, id1, ReviewApproved, "admin"))
+				rv, err := reviews.Get(ctx, id1)
+				s.Require().NoError(err)
+				s.Equal(ReviewApproved, rv.Status)
+				s.Equal("admin", rv.ResolvedBy)
+
+				err = reviews.Resolve(ctx, id1, ReviewBanned, "other admin")
+				s.Require().Error(err, "already resolved")
+				s.Contains(err.Error(), "already resolved")
+
+				err = reviews.Resolve(ctx, id2, ReviewPending, "admin")
+				s.Require().Error(err)
+				s.Contains(err.Error(), "invalid review status")
+			})
+
+			s.Run("list", func() {
+				res, err := reviews.List(ctx)
+				s.Require().NoError(err)
+				s.Require().Len(res, 2)
+				s.Equal(id2, res[0].ID, "newest first")
+				s.Equal(ReviewPending, res[0].Status)
+				s.Equal(id1, res[1].ID)
+				s.Equal(ReviewApproved, res[1].Status)
+			})
+
+			s.Run("other group reviews not visible", func() {
+				_, err := db.Exec(db.Adopt("INSERT INTO reviews (gid, chat_id, msg_id, user_id) VALUES (?, ?, ?, ?)"), "gr2", 1, 2, 3)
+				s.Require().NoError(err)
+				var id int64
+				err = db.Get(&id, db.Adopt("SELECT id FROM reviews WHERE gid = ?"), "gr2")
+				s.Require().NoError(err)
+
+				_, err = reviews.Get(ctx, id)
+				s.Require().Error(err)
+				s.Require().Error(reviews.Resolve(ctx, id, ReviewBanned, "admin"))
+				res, err := reviews.List(ctx)
+				s.Require().NoError(err)
+				s.Len(res, 2)
+			})
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/umputun/tg-spam/app/storage/engine"
+)
+
+// WebUsers is a storage for users of the web UI with their bcrypt password hashes and roles
+type WebUsers struct {
+	*engine.SQL
+	engine.RWLocker
+}
+
+// WebRole is a role of the web UI user, each role allows everything the previous one does
+type WebRole string
+
+// enum of web UI roles, from the least to the most privileged
+const (
+	WebRoleViewer    WebRole = "viewer"    // browse detected spam, samples, users and reviews
+	WebRoleModerator WebRole = "moderator" // manage samples, approved users, dictionary and spam images
+	WebRoleAdmin     WebRole = "admin"     // change settings, download backups, manage api tokens and web users
+)
+
+// WebRoles is the list of all web UI roles, from the least to the most privileged
+var WebRoles = []WebRole{WebRoleViewer, WebRoleModerator, WebRoleAdmin}
+
+// WebUser represents a user of the web UI
+type WebUser struct {
+	ID        int64      `db:"id" json:"id"`
+	GID       string     `db:"gid" json:"-"`
+	Name      string     `db:"name" json:"name"`
+	Hash      string     `db:"hash" json:"-"` // bcrypt hash of the password
+	Role      WebRole    `db:"role" json:"role"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	LastLogin *time.Time `db:"last_login" json:"last_login,omitempty"`
+}
+
+// web users command constants
+const (
+	CmdCreateWebUsersTable engine.DBCmd = iota + 2000
+	CmdCreateWebUsersIndexes
+)
+
+// webUsersQueries holds all web users queries
+var webUsersQueries = engine.NewQueryMap().
+	Add(CmdCreateWebUsersTable, engine.Query{
+		Sqlite: `CREATE TABLE IF NOT EXISTS web_users (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            gid TEXT NOT NULL DEFAULT '',
+            name TEXT NOT NULL,
+            hash TEXT NOT NULL,
+            role TEXT NOT NULL DEFAULT 'viewer',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_login TIMESTAMP,
+            UNIQUE(gid, name)
+        )`,
+		Postgres: `CREATE TABLE IF NOT EXISTS web_users (
+            id SERIAL PRIMARY KEY,
+            gid TEXT NOT NULL DEFAULT '',
+            name TEXT NOT NULL,
+            hash TEXT NOT NULL,
+            role TEXT NOT NULL DEFAULT 'viewer',
+            created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_login TIMESTAMP,
+            UNIQUE(gid, name)
+        )`,
+	}).
+	AddSame(CmdCreateWebUsersIndexes, `CREATE INDEX IF NOT EXISTS idx_web_users_gid ON web_users(gid)`)
+
+// NewWebUsers creates a new WebUsers storage and initializes the underlying table
+func NewWebUsers(ctx context.Context, db *engine.SQL) (*WebUsers, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db connection is nil")
+	}
+	res := &WebUsers{SQL: db, RWLocker: db.MakeLock()}
+	cfg := engine.TableConfig{
+		Name:          "web_users",
+		CreateTable:   CmdCreateWebUsersTable,
+		CreateIndexes: CmdCreateWebUsersIndexes,
+		MigrateFunc:   res.migrate,
+		QueriesMap:    webUsersQueries,
+	}
+	if err := engine.InitTable(ctx, db, cfg); err != nil {
+		return nil, fmt.Errorf("failed to init web users storage: %w", err)
+	}
+	return res, nil
+}
+
+// migrate is a no-op migration function for web users table (new table, no migration needed)
+func (u *WebUsers) migrate(_ context.Context, _ *sqlx.Tx, _ string) error {
+	return nil
+}
+
+// Add creates a new user with the bcrypt hash of the password and the role
+func (u *WebUsers) Add(ctx context.Context, name, hash string, role WebRole) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("user name can't be empty")
+	}
+	if hash == "" {
+		return fmt.Errorf("password hash can't be empty")
+	}
+	if !slices.Contains(WebRoles, role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	u.Lock()
+	defer u.Unlock()
+
+	rec := WebUser{GID: u.GID(), Name: name, Hash: hash, Role: role, CreatedAt: time.Now()}
+	query := `INSERT INTO web_users (gid, name, hash, role, created_at) VALUES (:gid, :name, :hash, :role, :created_at)`
+	if _, err := u.NamedExecContext(ctx, query, rec); err != nil {
+		return fmt.Errorf("failed to insert web user %q: %w", name, err)
+	}
+	log.Printf("[INFO] web user %q added with role %s", name, role)
+	return nil
+}
+
+// Get returns the user by name
+func (u *WebUsers) Get(ctx context.Context, name string) (*WebUser, error) {
+	u.RLock()
+	defer u.RUnlock()
+
+	var res WebUser
+	query := u.Adopt(`SELECT * FROM web_users WHERE gid = ? AND name = ?`)
+	if err := u.GetContext(ctx, &res, query, u.GID(), name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("web user %q not found", name)
+		}
+		return nil, fmt.Errorf("failed to get web user: %w", err)
+	}
+	res.localTimes()
+	return &res, nil
+}
+
+// List returns all users ordered by name
+func (u *WebUsers) List(ctx context.Context) ([]WebUser, error) {
+	u.RLock()
+	defer u.RUnlock()
+
+	var res []WebUser
+	if err := u.SelectContext(ctx, &res, u.Adopt(`SELECT * FROM web_users WHERE gid = ? ORDER BY name`), u.GID()); err != nil {
+		return nil, fmt.Errorf("failed to get web users: %w", err)
+	}
+	for i := range res {
+		res[i].localTimes()
+	}
+	return res, nil
+}
+
+// Delete removes the user by ID and returns the name of the deleted user
+func (u *WebUsers) Delete(ctx context.Context, id int64) (name string, err error) {
+	u.Lock()
+	defer u.Unlock()
+
+	if name, err = u.nameByID(ctx, id); err != nil {
+		return "", err
+	}
+	if _, err := u.ExecContext(ctx, u.Adopt(`DELETE FROM web_users WHERE id = ? AND gid = ?`), id, u.GID()); err != nil {
+		return "", fmt.Errorf("failed to delete web user: %w", err)
+	}
+	log.Printf("[INFO] web user %q deleted", name)
+	return name, nil
+}
+
+// SetRole changes the role of the user and returns the name of the user
+func (u *WebUsers) SetRole(ctx context.Context, id int64, role WebRole) (name string, err error) {
+	if !slices.Contains(WebRoles, role) {
+		return "", fmt.Errorf("unknown role %q", role)
+	}
+	return u.update(ctx, id, "role", string(role))
+}
+
+// SetPassword changes the bcrypt hash of the user password and returns the name of the user
+func (u *WebUsers) SetPassword(ctx context.Context, id int64, hash string) (name string, err error) {
+	if hash == "" {
+		return "", fmt.Errorf("password hash can't be empty")
+	}
+	return u.update(ctx, id, "hash", hash)
+}
+
+// MarkLogin sets the last login time of the user
+func (u *WebUsers) MarkLogin(ctx context.Context, id int64, ts time.Time) error {
+	u.Lock()
+	defer u.Unlock()
+
+	if _, err := u.ExecContext(ctx, u.Adopt(`UPDATE web_users SET last_login = ? WHERE id = ? AND gid = ?`),
+		ts, id, u.GID()); err != nil {
+		return fmt.Errorf("failed to update last login time of web user: %w", err)
+	}
+	return nil
+}
+
+// update sets the column of the user, column is one of the constant names, never user input
+func (u *WebUsers) update(ctx context.Context, id int64, column, value string) (name string, err error) {
+	u.Lock()
+	defer u.Unlock()
+
+	if name, err = u.nameByID(ctx, id); err != nil {
+		return "", err
+	}
+	query := u.Adopt(fmt.Sprintf(`UPDATE web_users SET %s = ? WHERE id = ? AND gid = ?`, column)) //nolint:gosec // constant column
+	if _, err := u.ExecContext(ctx, query, value, id, u.GID()); err != nil {
+		return "", fmt.Errorf("failed to update %s of web user %q: %w", column, name, err)
+	}
+	return name, nil
+}
+
+// nameByID returns the name of the user, scoped by gid as the id comes from the web API. Caller holds the lock.
+func (u *WebUsers) nameByID(ctx context.Context, id int64) (string, error) {
+	var name string
+	if err := u.GetContext(ctx, &name, u.Adopt(`SELECT name FROM web_users WHERE id = ? AND gid = ?`), id, u.GID()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("web user with id %d not found", id)
+		}
+		return "", fmt.Errorf("failed to get web user: %w", err)
+	}
+	return name, nil
+}
+
+func (w *WebUser) localTimes() {
+	w.CreatedAt = w.CreatedAt.Local()
+	if w.LastLogin != nil {
+		ts := w.LastLogin.Local()
+		w.LastLogin = &ts
+	}
+}
+
+// Allows returns true if the role is the required one or more privileged
+func (r WebRole) Allows(required WebRole) bool {
+	have, want := slices.Index(WebRoles, r), slices.Index(WebRoles, required)
+	return have >= 0 && want >= 0 && have >= want
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *StorageTestSuite) TestWebUsers_NewWebUsers() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			s.Run("create new table", func() {
+				_, err := NewWebUsers(ctx, db)
+				s.Require().NoError(err)
+				defer db.Exec("DROP TABLE web_users")
+
+				var count int
+				err = db.Get(&count, `SELECT COUNT(*) FROM web_users`)
+				s.Require().NoError(err)
+				s.Equal(0, count)
+			})
+
+			s.Run("nil db connection", func() {
+				_, err := NewWebUsers(ctx, nil)
+				s.Require().Error(err)
+				s.Contains(err.Error(), "db connection is nil")
+			})
+		})
+	}
+}
+
+func (s *StorageTestSuite) TestWebUsers_AddGetUpdateDelete() {
+	ctx := context.Background()
+	for _, dbt := range s.getTestDB() {
+		db := dbt.DB
+		s.Run(fmt.Sprintf("with %s", db.Type()), func() {
+			users, err := NewWebUsers(ctx, db)
+			s.Require().NoError(err)
+			defer db.Exec("DROP TABLE web_users")
+
+			s.Run("add and get", func() {
+				s.Require().NoError(users.Add(ctx, " bob ", "hash-bob", WebRoleViewer))
+				s.Require().NoError(users.Add(ctx, "alice", "hash-alice", WebRoleAdmin))
+
+				u, err := users.Get(ctx, "bob")
+				s.Require().NoError(err)
+				s.Equal("bob", u.Name)
+				s.Equal("hash-bob", u.Hash)
+				s.Equal(WebRoleViewer, u.Role)
+				s.Equal("gr1", u.GID)
+				s.False(u.CreatedAt.IsZero())
+				s.Nil(u.LastLogin)
+
+				res, err := users.List(ctx)
+				s.Require().NoError(err)
+				s.Require().Len(res, 2)
+				s.Equal("alice", res[0].Name, "ordered by name")
+				s.Equal("bob", res[1].Name)
+			})
+
+			s.Run("invalid users", func() {
+				s.Require().EqualError(users.Add(ctx, " ", "hash", WebRoleViewer), "user name can't be empty")
+				s.Require().EqualError(users.Add(ctx, "carol", "", WebRoleViewer), "password hash can't be empty")
+				s.Require().EqualError(users.Add(ctx, "carol", "hash", "root"), `unknown role "root"`)
+				s.Require().Error(users.Add(ctx, "bob", "hash", WebRoleViewer), "duplicate name")
+				_, err := users.Get(ctx, "carol")
+				s.Require().EqualError(err, `web user "carol" not found`)
+			})
+
+			s.Run("update", func() {
+				u, err := users.Get(ctx, "bob")
+				s.Require().NoError(err)
+
+				name, err := users.SetRole(ctx, u.ID, WebRoleModerator)
+				s.Require().NoError(err)
+				s.Equal("bob", name)
+				_, err = users.SetRole(ctx, u.ID, "root")
+				s.Require().Error(err)
+				_, err = users.SetPassword(ctx, u.ID, "new-hash")
+				s.Require().NoError(err)
+				login := time.Now().Truncate(time.Second)
+				s.Require().NoError(users.MarkLogin(ctx, u.ID, login))
+
+				u, err = users.Get(ctx, "bob")
+				s.Require().NoError(err)
+				s.Equal(WebRoleModerator, u.Role)
+				s.Equal("new-hash", u.Hash)
+				s.Require().NotNil(u.LastLogin)
+				s.True(login.Equal(*u.LastLogin))
+
+				_, err = users.SetPassword(ctx, 12345, "hash")
+				s.Require().EqualError(err, "web user with id 12345 not found")
+			})
+
+			s.Run("delete", func() {
+				u, err := users.Get(ctx, "bob")
+				s.Require().NoError(err)
+				name, err := users.Delete(ctx, u.ID)
+				s.Require().NoError(err)
+				s.Equal("bob", name)
+				_, err = users.Get(ctx, "bob")
+				s.Require().Error(err)
+				_, err = users.Delete(ctx, u.ID)
+				s.Require().Error(err)
+			})
+
+			s.Run("other group users not visible", func() {
+				_, err := db.Exec(db.Adopt("INSERT INTO web_users (gid, name, hash, role) VALUES (?, ?, ?, ?)"),
+					"gr2", "bob", "hash", "admin")
+				s.Require().NoError(err)
+				_, err = users.Get(ctx, "bob")
+				s.Require().Error(err)
+				res, err := users.List(ctx)
+				s.Require().NoError(err)
+				s.Len(res, 1)
+			})
+		})
+	}
+}
+
+func TestWebRole_Allows(t *testing.T) {
+	tests := []struct {
+		role, required WebRole
+		want           bool
+	}{
+		{WebRoleViewer, WebRoleViewer, true},
+		{WebRoleViewer, WebRoleModerator, false},
+		{WebRoleModerator, WebRoleViewer, true},
+		{WebRoleModerator, WebRoleAdmin, false},
+		{WebRoleAdmin, WebRoleModerator, true},
+		{"root", WebRoleViewer, false},
+		{WebRoleAdmin, "root", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.role.Allows(tt.required), "%s allows %s", tt.role, tt.required)
+	}
+}
@@ -0,0 +1,273 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+//go:generate moq --out mocks/api_tokens.go --pkg mocks --with-resets --skip-ensure . APITokens
+
+// APITokens is a storage interface for named bearer tokens of the API
+type APITokens interface {
+	Add(ctx context.Context, name string, scopes []storage.APIScope, expires time.Time) (token string, err error)
+	Find(ctx context.Context, token string) (*storage.APIToken, error)
+	List(ctx context.Context) ([]storage.APIToken, error)
+	Delete(ctx context.Context, id int64) (name string, err error)
+	MarkUsed(ctx context.Context, id int64, ts time.Time) error
+}
+
+// tokenLastUsedInterval limits updates of the token last used time, a busy integration doesn't write
+// to the database on each request
+const tokenLastUsedInterval = time.Minute
+
+// apiTokenCtxKey is the request context key of the api token the request is authenticated with
+type apiTokenCtxKey struct{}
+
+// tokenRouteScopes maps API routes to the scope required to call them with a token. Routes not listed here,
+// including web UI pages and token management, are available with basic auth only.
+var tokenRouteScopes = map[string]storage.APIScope{
+	"POST /check":               storage.APIScopeCheck,
+	"GET /check/{user_id}":      storage.APIScopeCheck,
+	"GET /reputation/{user_id}": storage.APIScopeCheck,
+
+	"POST /update/spam":           storage.APIScopeSamples,
+	"POST /update/ham":            storage.APIScopeSamples,
+	"POST /delete/spam":           storage.APIScopeSamples,
+	"POST /delete/ham":            storage.APIScopeSamples,
+	"GET /samples":                storage.APIScopeSamples,
+	"PUT /samples":                storage.APIScopeSamples,
+	"GET /download/spam":          storage.APIScopeSamples,
+	"GET /download/ham":           storage.APIScopeSamples,
+	"GET /download/detected_spam": storage.APIScopeSamples,
+	"POST /image_hashes/add":      storage.APIScopeSamples,
+	"POST /image_hashes/delete":   storage.APIScopeSamples,
+	"GET /image_hashes/{$}":       storage.APIScopeSamples,
+
+	"POST /users/add":    storage.APIScopeUsers,
+	"POST /users/delete": storage.APIScopeUsers,
+	"GET /users/{$}":     storage.APIScopeUsers,
+
+	"POST /dictionary/add":    storage.APIScopeDictionary,
+	"POST /dictionary/delete": storage.APIScopeDictionary,
+	"GET /dictionary/{$}":     storage.APIScopeDictionary,
+
+	"GET /settings":                      storage.APIScopeConfig,
+	"POST /config":                       storage.APIScopeConfig,
+	"PUT /config":                        storage.APIScopeConfig,
+	"DELETE /config":                     storage.APIScopeConfig,
+	"POST /config/reload":                storage.APIScopeConfig,
+	"GET /config/history":                storage.APIScopeConfig,
+	"GET /config/history/{id}/diff":      storage.APIScopeConfig,
+	"POST /config/history/{id}/rollback": storage.APIScopeConfig,
+	"GET /download/config":               storage.APIScopeConfig,
+	"GET /audit":                         storage.APIScopeConfig,
+	"GET /download/audit":                storage.APIScopeConfig,
+	"GET /reviews":                       storage.APIScopeConfig,
+	"GET /metrics":                       storage.APIScopeConfig,
+
+	"GET /download/backup":             storage.APIScopeBackup,
+	"GET /download/export-to-postgres": storage.APIScopeBackup,
+}
+
+// tokenRoutes matches requests to the patterns of tokenRouteScopes, the same way the router does
+var tokenRoutes = func() *http.ServeMux {
+	res := http.NewServeMux()
+	for pattern := range tokenRouteScopes {
+		res.Handle(pattern, http.NotFoundHandler())
+	}
+	return res
+}()
+
+// bearerToken returns the token from the Authorization header, if the request has a bearer token
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// serveWithToken authenticates the request with the api token and passes it to the handler if the token
+// is valid, not expired and has the scope required by the route. Responds with 401 for unknown or expired
+// tokens and with 403 for routes out of the token scopes.
+func (s *Server) serveWithToken(w http.ResponseWriter, r *http.Request, token string, h http.Handler) {
+	var tok *storage.APIToken
+	err := fmt.Errorf("api tokens are disabled")
+	if s.APITokens != nil {
+		tok, err = s.APITokens.Find(r.Context(), token)
+	}
+	if err == nil && tok.Expired(time.Now()) {
+		err = fmt.Errorf("api token %q expired", tok.Name)
+	}
+	if err != nil {
+		log.Printf("[DEBUG] api token rejected for %s %s: %v", r.Method, r.URL.Path, err)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="restricted", error="invalid_token"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	_, pattern := tokenRoutes.Handler(r)
+	scope, ok := tokenRouteScopes[pattern]
+	if !ok || !tok.HasScope(scope) {
+		log.Printf("[INFO] api token %q not allowed to %s %s", tok.Name, r.Method, r.URL.Path)
+		challenge := `Bearer realm="restricted", error="insufficient_scope"`
+		if scope != "" {
+			challenge += fmt.Sprintf(", scope=%q", scope)
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if tok.LastUsed == nil || time.Since(*tok.LastUsed) >= tokenLastUsedInterval {
+		if err := s.APITokens.MarkUsed(r.Context(), tok.ID, time.Now()); err != nil {
+			log.Printf("[WARN] failed to update last used time of api token %q: %v", tok.Name, err)
+		}
+	}
+	h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiTokenCtxKey{}, tok)))
+}
+
+// requestActor returns the name of whoever made the request, "token:<name>" for requests authenticated
+// with an api token, the logged-in web user or the basic auth user otherwise, or "anonymous" if auth is disabled.
+func requestActor(r *http.Request) string {
+	if tok, ok := r.Context().Value(apiTokenCtxKey{}).(*storage.APIToken); ok {
+		return "token:" + tok.Name
+	}
+	if a, ok := r.Context().Value(webActorCtxKey{}).(webActor); ok {
+		return a.name
+	}
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		return user
+	}
+	return "anonymous"
+}
+
+// htmlAPITokensHandler handles GET /api_tokens request. It shows the api tokens with the form to create a new one.
+func (s *Server) htmlAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderAPITokens(w, r, "api_tokens.html", "", "")
+}
+
+// addAPITokenHandler handles POST /api_tokens/add request. It creates a new api token and returns it,
+// this is the only time the token is shown. HTMX form sets expiration in days, API request as a time.
+func (s *Server) addAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name    string             `json:"name"`
+		Scopes  []storage.APIScope `json:"scopes"`
+		Expires time.Time          `json:"expires"` // zero for token without expiration
+	}
+
+	isHtmxRequest := r.Header.Get("HX-Request") == "true"
+	if isHtmxRequest {
+		if err := r.ParseForm(); err != nil {
+			w.Header().Set("HX-Retarget", "#error-message")
+			fmt.Fprintf(w, "<div class='alert alert-danger'>Can't parse form: %s</div>", html.EscapeString(err.Error()))
+			return
+		}
+		req.Name = r.FormValue("name")
+		for _, sc := range r.Form["scopes"] {
+			req.Scopes = append(req.Scopes, storage.APIScope(sc))
+		}
+		if days, err := strconv.Atoi(r.FormValue("expires_days")); err == nil && days > 0 {
+			req.Expires = time.Now().AddDate(0, 0, days)
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't decode request", "details": err.Error()})
+		return
+	}
+
+	token, err := s.APITokens.Add(r.Context(), req.Name, req.Scopes, req.Expires)
+	if err != nil {
+		if isHtmxRequest {
+			w.Header().Set("HX-Retarget", "#error-message")
+			fmt.Fprintf(w, "<div class='alert alert-danger'>Can't create api token: %s</div>", html.EscapeString(err.Error()))
+			return
+		}
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't create api token", "details": err.Error()})
+		return
+	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditTokenCreate, Text: strings.TrimSpace(req.Name),
+		Reason: fmt.Sprintf("scopes: %v", req.Scopes)})
+
+	if isHtmxRequest {
+		s.renderAPITokens(w, r, "api_tokens_list", strings.TrimSpace(req.Name), token)
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"created": true, "name": strings.TrimSpace(req.Name), "token": token})
+}
+
+// deleteAPITokenHandler handles POST /api_tokens/delete request. It deletes the api token by id,
+// requests with the token are rejected right away.
+func (s *Server) deleteAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	isHtmxRequest := r.Header.Get("HX-Request") == "true"
+	if isHtmxRequest {
+		id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		if err != nil {
+			w.Header().Set("HX-Retarget", "#error-message")
+			fmt.Fprintf(w, "<div class='alert alert-danger'>Invalid ID: %s</div>", html.EscapeString(err.Error()))
+			return
+		}
+		req.ID = id
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't decode request", "details": err.Error()})
+		return
+	}
+
+	name, err := s.APITokens.Delete(r.Context(), req.ID)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't delete api token", "details": err.Error()})
+		return
+	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditTokenDelete, Text: name})
+
+	if isHtmxRequest {
+		s.renderAPITokens(w, r, "api_tokens_list", "", "")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"deleted": true, "id": req.ID})
+}
+
+// renderAPITokens renders the template with the list of api tokens. The token just created is shown
+// with its name, as it can't be retrieved later.
+func (s *Server) renderAPITokens(w http.ResponseWriter, r *http.Request, tmplName, newName, newToken string) {
+	tokens, err := s.APITokens.List(r.Context())
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't fetch api tokens", "details": err.Error()})
+		return
+	}
+
+	tmplData := struct {
+		Tokens       []storage.APIToken
+		TotalTokens  int
+		Scopes       []storage.APIScope
+		NewTokenName string
+		NewToken     string
+		Now          time.Time
+	}{
+		Tokens:       tokens,
+		TotalTokens:  len(tokens),
+		Scopes:       storage.APIScopes,
+		NewTokenName: newName,
+		NewToken:     newToken,
+		Now:          time.Now(),
+	}
+
+	if err := tmpl.ExecuteTemplate(w, tmplName, tmplData); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't execute template", "details": err.Error()})
+		return
+	}
+}
@@ -0,0 +1,259 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/rest"
+	"github.com/go-pkgz/routegroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/webapi/mocks"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestServer_basicAuthMiddlewareAPIToken(t *testing.T) {
+	hash, err := rest.GenerateBcryptHash("secret")
+	require.NoError(t, err)
+	recent, expired := time.Now().Add(-time.Second), time.Now().Add(-time.Hour)
+	tokens := &mocks.APITokensMock{
+		FindFunc: func(ctx context.Context, token string) (*storage.APIToken, error) {
+			switch token {
+			case "tgs_check":
+				return &storage.APIToken{ID: 1, Name: "monitoring", Scopes: storage.APIScopeList{storage.APIScopeCheck}}, nil
+			case "tgs_admin":
+				return &storage.APIToken{ID: 2, Name: "admin", LastUsed: &recent,
+					Scopes: storage.APIScopeList{storage.APIScopeConfig, storage.APIScopeBackup, storage.APIScopeUsers}}, nil
+			case "tgs_expired":
+				return &storage.APIToken{ID: 3, Name: "old", ExpiresAt: &expired, Scopes: storage.APIScopeList{storage.APIScopeCheck}}, nil
+			}
+			return nil, errors.New("api token not found")
+		},
+		MarkUsedFunc: func(ctx context.Context, id int64, ts time.Time) error { return nil },
+	}
+	var actor string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = requestActor(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name, method, path, token string
+		want                      int
+		wantActor                 string
+	}{
+		{name: "check", method: "POST", path: "/check", token: "tgs_check", want: http.StatusOK, wantActor: "token:monitoring"},
+		{name: "check user", method: "GET", path: "/check/123", token: "tgs_check", want: http.StatusOK,
+			wantActor: "token:monitoring"},
+		{name: "out of scope", method: "POST", path: "/update/spam", token: "tgs_check", want: http.StatusForbidden},
+		{name: "backup", method: "GET", path: "/download/backup", token: "tgs_admin", want: http.StatusOK, wantActor: "token:admin"},
+		{name: "config history", method: "POST", path: "/config/history/5/rollback", token: "tgs_admin", want: http.StatusOK,
+			wantActor: "token:admin"},
+		{name: "users list", method: "GET", path: "/users/", token: "tgs_admin", want: http.StatusOK, wantActor: "token:admin"},
+		{name: "wrong method", method: "DELETE", path: "/download/backup", token: "tgs_admin", want: http.StatusForbidden},
+		{name: "web ui page", method: "GET", path: "/manage_samples", token: "tgs_admin", want: http.StatusForbidden},
+		{name: "tokens management", method: "POST", path: "/api_tokens/add", token: "tgs_admin", want: http.StatusForbidden},
+		{name: "expired", method: "POST", path: "/check", token: "tgs_expired", want: http.StatusUnauthorized},
+		{name: "unknown", method: "POST", path: "/check", token: "tgs_unknown", want: http.StatusUnauthorized},
+		{name: "basic auth still works", method: "GET", path: "/download/backup", want: http.StatusOK, wantActor: "tg-spam"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor = ""
+			tokens.ResetCalls()
+			srv := &Server{Config: Config{AuthHash: hash, APITokens: tokens}}
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			} else {
+				req.SetBasicAuth("tg-spam", "secret")
+			}
+			w := httptest.NewRecorder()
+			srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+			assert.Equal(t, tt.wantActor, actor)
+			switch tt.want {
+			case http.StatusUnauthorized:
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+			case http.StatusForbidden:
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+			}
+		})
+	}
+
+	t.Run("last used updated if stale", func(t *testing.T) {
+		srv := &Server{Config: Config{AuthHash: hash, APITokens: tokens}}
+		for _, token := range []string{"tgs_check", "tgs_admin"} {
+			req := httptest.NewRequest("GET", "/check/1", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+token)
+			srv.basicAuthMiddleware(next).ServeHTTP(httptest.NewRecorder(), req)
+		}
+		require.Len(t, tokens.MarkUsedCalls(), 1, "recently used token not updated")
+		assert.Equal(t, int64(1), tokens.MarkUsedCalls()[0].ID)
+	})
+
+	t.Run("tokens disabled", func(t *testing.T) {
+		srv := &Server{Config: Config{AuthHash: hash}}
+		req := httptest.NewRequest("POST", "/check", http.NoBody)
+		req.Header.Set("Authorization", "Bearer tgs_check")
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestServer_addAPITokenHandler(t *testing.T) {
+	tokens := &mocks.APITokensMock{
+		AddFunc: func(ctx context.Context, name string, scopes []storage.APIScope, expires time.Time) (string, error) {
+			if name == "bad" {
+				return "", errors.New("token must have at least one scope")
+			}
+			return "tgs_new", nil
+		},
+		ListFunc: func(ctx context.Context) ([]storage.APIToken, error) {
+			return []storage.APIToken{{ID: 1, Name: "monitoring", Prefix: "tgs_abcdef", Scopes: storage.APIScopeList{"check"}}}, nil
+		},
+	}
+	auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+	srv := NewServer(Config{APITokens: tokens, AuditLog: auditLog})
+
+	t.Run("htmx", func(t *testing.T) {
+		form := url.Values{"name": {"monitoring"}, "scopes": {"check", "backup"}, "expires_days": {"30"}}
+		req := httptest.NewRequest("POST", "/api_tokens/add", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		req.SetBasicAuth("tg-spam", "secret")
+		w := httptest.NewRecorder()
+		srv.addAPITokenHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "tgs_new")
+		assert.Contains(t, w.Body.String(), "tgs_abcdef")
+
+		require.Len(t, tokens.AddCalls(), 1)
+		assert.Equal(t, "monitoring", tokens.AddCalls()[0].Name)
+		assert.Equal(t, []storage.APIScope{"check", "backup"}, tokens.AddCalls()[0].Scopes)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), tokens.AddCalls()[0].Expires, time.Minute)
+		require.Len(t, auditLog.AddCalls(), 1)
+		assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceWeb, Actor: "tg-spam", Action: storage.AuditTokenCreate,
+			Text: "monitoring", Reason: "scopes: [check backup]"}, auditLog.AddCalls()[0].Rec)
+	})
+
+	t.Run("json without expiration", func(t *testing.T) {
+		tokens.ResetCalls()
+		req := httptest.NewRequest("POST", "/api_tokens/add", strings.NewReader(`{"name":"ci","scopes":["samples:write"]}`))
+		w := httptest.NewRecorder()
+		srv.addAPITokenHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"created":true,"name":"ci","token":"tgs_new"}`, w.Body.String())
+		require.Len(t, tokens.AddCalls(), 1)
+		assert.True(t, tokens.AddCalls()[0].Expires.IsZero())
+	})
+
+	t.Run("add failed", func(t *testing.T) {
+		auditLog.ResetCalls()
+		form := url.Values{"name": {"bad"}}
+		req := httptest.NewRequest("POST", "/api_tokens/add", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.addAPITokenHandler(w, req)
+		assert.Equal(t, "#error-message", w.Header().Get("HX-Retarget"))
+		assert.Contains(t, w.Body.String(), "at least one scope")
+		assert.Empty(t, auditLog.AddCalls())
+	})
+}
+
+func TestServer_deleteAPITokenHandler(t *testing.T) {
+	tokens := &mocks.APITokensMock{
+		DeleteFunc: func(ctx context.Context, id int64) (string, error) {
+			if id != 1 {
+				return "", errors.New("api token with id 2 not found")
+			}
+			return "monitoring", nil
+		},
+		ListFunc: func(ctx context.Context) ([]storage.APIToken, error) { return nil, nil },
+	}
+	auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+	srv := NewServer(Config{APITokens: tokens, AuditLog: auditLog})
+
+	req := httptest.NewRequest("POST", "/api_tokens/delete", strings.NewReader("id=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	w := httptest.NewRecorder()
+	srv.deleteAPITokenHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No api tokens found")
+	require.Len(t, auditLog.AddCalls(), 1)
+	assert.Equal(t, storage.AuditTokenDelete, auditLog.AddCalls()[0].Rec.Action)
+	assert.Equal(t, "monitoring", auditLog.AddCalls()[0].Rec.Text)
+
+	req = httptest.NewRequest("POST", "/api_tokens/delete", strings.NewReader(`{"id":2}`))
+	w = httptest.NewRecorder()
+	srv.deleteAPITokenHandler(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "not found")
+}
+
+func TestServer_htmlAPITokensHandler(t *testing.T) {
+	expires := time.Now().Add(-time.Hour)
+	tokens := &mocks.APITokensMock{
+		ListFunc: func(ctx context.Context) ([]storage.APIToken, error) {
+			return []storage.APIToken{
+				{ID: 1, Name: "monitoring", Prefix: "tgs_abcdef", Scopes: storage.APIScopeList{"check", "backup"}, ExpiresAt: &expires},
+			}, nil
+		},
+	}
+	srv := NewServer(Config{APITokens: tokens})
+	w := httptest.NewRecorder()
+	srv.htmlAPITokensHandler(w, httptest.NewRequest("GET", "/api_tokens", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<title>API Tokens - TG-Spam</title>")
+	assert.Contains(t, body, "tgs_abcdef")
+	assert.Contains(t, body, "expired")
+	for _, sc := range storage.APIScopes {
+		assert.Contains(t, body, `value="`+string(sc)+`"`)
+	}
+}
+
+func TestTokenRouteScopes(t *testing.T) {
+	// all patterns match routes of the server, so the scope map can't silently drift from the router
+	srv := NewServer(Config{SpamFilter: &mocks.SpamFilterMock{}, Detector: &mocks.DetectorMock{}, AuditLog: &mocks.AuditLogMock{},
+		SettingsStore: &mocks.SettingsStoreMock{}, ConfigDBMode: true, APITokens: &mocks.APITokensMock{}})
+	mux := http.NewServeMux()
+	srv.routes(routegroup.New(mux))
+	for pattern := range tokenRouteScopes {
+		method, path, _ := strings.Cut(pattern, " ")
+		path = strings.NewReplacer("{user_id}", "123", "{id}", "5", "{$}", "").Replace(path)
+		_, got := tokenRoutes.Handler(httptest.NewRequest(method, path, http.NoBody))
+		assert.Equal(t, pattern, got)
+		_, got = mux.Handler(httptest.NewRequest(method, path, http.NoBody))
+		assert.Equal(t, pattern, got, "route of the server")
+	}
+}
+
+func TestServer_checkMsgHandlerAPIToken(t *testing.T) {
+	detector := &mocks.DetectorMock{
+		CheckFunc: func(req spamcheck.Request) (bool, []spamcheck.Response) { return false, nil },
+	}
+	srv := NewServer(Config{Detector: detector})
+	for _, scopes := range []storage.APIScopeList{{storage.APIScopeCheck}, {storage.APIScopeCheck, storage.APIScopeUsers}} {
+		req := httptest.NewRequest("POST", "/check", strings.NewReader(`{"msg":"hello","user_id":"123","check_only":false}`))
+		tok := &storage.APIToken{Name: "bot", Scopes: scopes}
+		req = req.WithContext(context.WithValue(req.Context(), apiTokenCtxKey{}, tok))
+		w := httptest.NewRecorder()
+		srv.checkMsgHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	require.Len(t, detector.CheckCalls(), 2)
+	assert.True(t, detector.CheckCalls()[0].Req.CheckOnly, "token with check scope can only check")
+	assert.False(t, detector.CheckCalls()[1].Req.CheckOnly, "token with users scope")
+}
@@ -0,0 +1,155 @@
+package webapi
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+//go:generate moq --out mocks/audit_log.go --pkg mocks --with-resets --skip-ensure . AuditLog
+
+// AuditLog is a storage interface for the log of moderation actions
+type AuditLog interface {
+	Add(ctx context.Context, rec storage.AuditRecord) error
+	List(ctx context.Context, f storage.AuditFilter) ([]storage.AuditRecord, error)
+}
+
+// auditActions is the list of actions offered by the audit log page filter
+var auditActions = []storage.AuditAction{storage.AuditUnban, storage.AuditBanConfirm, storage.AuditSpam,
+	storage.AuditBan, storage.AuditWarn, storage.AuditReportBan, storage.AuditReportReject, storage.AuditReporterBan,
+	storage.AuditConfigUpdate, storage.AuditConfigSave, storage.AuditConfigReload, storage.AuditConfigDelete,
+	storage.AuditConfigRollback, storage.AuditSampleAdd, storage.AuditSampleDelete, storage.AuditTokenCreate,
+	storage.AuditTokenDelete, storage.AuditUserCreate, storage.AuditUserDelete, storage.AuditUserRole, storage.AuditUserPassword}
+
+// recordAudit adds the action made in web UI or API to the audit log, no-op if the audit log is not set.
+// The actor is the basic auth user or the api token, see requestActor. Failures are logged only.
+func (s *Server) recordAudit(r *http.Request, rec storage.AuditRecord) {
+	if s.AuditLog == nil {
+		return
+	}
+	rec.Source, rec.Actor = storage.AuditSourceWeb, requestActor(r)
+	if err := s.AuditLog.Add(r.Context(), rec); err != nil {
+		log.Printf("[WARN] failed to record %s by %q to audit log: %v", rec.Action, rec.Actor, err)
+	}
+}
+
+// getAuditHandler handles GET /audit request. It returns audit records matching the filter in query params,
+// see auditFilter for the supported params.
+func (s *Server) getAuditHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := auditFilter(r.URL.Query())
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't parse audit filter", "details": err.Error()})
+		return
+	}
+	records, err := s.AuditLog.List(r.Context(), f)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get audit log", "details": err.Error()})
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"records": records})
+}
+
+// downloadAuditHandler handles GET /download/audit request. It returns audit records matching the filter as CSV file.
+func (s *Server) downloadAuditHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := auditFilter(r.URL.Query())
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't parse audit filter", "details": err.Error()})
+		return
+	}
+	records, err := s.AuditLog.List(r.Context(), f)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get audit log", "details": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "audit.csv"))
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"time", "source", "actor", "actor_id", "action", "user_id", "user_name", "msg_id", "text", "reason"})
+	for _, rec := range records {
+		_ = cw.Write([]string{rec.Time.Format(time.RFC3339), rec.Source, rec.Actor, strconv.FormatInt(rec.ActorID, 10),
+			string(rec.Action), strconv.FormatInt(rec.UserID, 10), rec.UserName, strconv.Itoa(rec.MsgID), rec.Text, rec.Reason})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("[WARN] failed to write audit csv: %v", err)
+	}
+}
+
+// htmlAuditLogHandler handles GET /audit_log request. It shows the audit records with the filter form,
+// the filter is passed in the same query params as for GET /audit.
+func (s *Server) htmlAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	tmplData := struct {
+		Records     []storage.AuditRecord
+		Actions     []storage.AuditAction
+		Filter      url.Values
+		DownloadURL string // csv export of the same records
+		Error       string
+	}{Actions: auditActions, Filter: r.URL.Query(), DownloadURL: "/download/audit?" + r.URL.Query().Encode()}
+
+	f, err := auditFilter(r.URL.Query())
+	if err == nil {
+		tmplData.Records, err = s.AuditLog.List(r.Context(), f)
+	}
+	if err != nil {
+		log.Printf("[WARN] failed to get audit log: %v", err)
+		tmplData.Error = err.Error()
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "audit_log.html", tmplData); err != nil {
+		log.Printf("[WARN] can't execute template: %v", err)
+		http.Error(w, "Error executing template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// auditFilter makes the audit filter from query params: actor, action, user_id, limit,
+// since and until as RFC3339 time or 2006-01-02 date, inclusive. Empty params don't filter.
+func auditFilter(q url.Values) (storage.AuditFilter, error) {
+	res := storage.AuditFilter{Actor: q.Get("actor"), Action: storage.AuditAction(q.Get("action"))}
+	var err error
+	if v := q.Get("user_id"); v != "" {
+		if res.UserID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return res, fmt.Errorf("invalid user_id %q: %w", v, err)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if res.Limit, err = strconv.Atoi(v); err != nil {
+			return res, fmt.Errorf("invalid limit %q: %w", v, err)
+		}
+	}
+	if res.Since, err = parseAuditTime(q.Get("since")); err != nil {
+		return res, fmt.Errorf("invalid since: %w", err)
+	}
+	if res.Until, err = parseAuditTime(q.Get("until")); err != nil {
+		return res, fmt.Errorf("invalid until: %w", err)
+	}
+	if len(q.Get("until")) == len(time.DateOnly) {
+		res.Until = res.Until.AddDate(0, 0, 1) // until date includes the whole day
+	}
+	return res, nil
+}
+
+// parseAuditTime parses RFC3339 time or local date, zero time for empty string
+func parseAuditTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("can't parse %q, expected RFC3339 time or date: %w", v, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,189 @@
+package webapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/webapi/mocks"
+)
+
+func TestServer_getAuditHandler(t *testing.T) {
+	ts := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	auditLog := &mocks.AuditLogMock{ListFunc: func(ctx context.Context, f storage.AuditFilter) ([]storage.AuditRecord, error) {
+		return []storage.AuditRecord{{ID: 1, Time: ts, Source: storage.AuditSourceTelegram, Actor: "admin", ActorID: 10,
+			Action: storage.AuditUnban, UserID: 123, UserName: "user1", MsgID: 5}}, nil
+	}}
+	srv := NewServer(Config{AuditLog: auditLog})
+
+	t.Run("filtered", func(t *testing.T) {
+		auditLog.ResetCalls()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/audit?actor=admin&action=unban&user_id=123&since=2026-10-01&until=2026-10-02&limit=10",
+			http.NoBody)
+		srv.getAuditHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Records []storage.AuditRecord `json:"records"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Records, 1)
+		assert.Equal(t, storage.AuditUnban, resp.Records[0].Action)
+		assert.Equal(t, "admin", resp.Records[0].Actor)
+		assert.True(t, ts.Equal(resp.Records[0].Time))
+
+		require.Len(t, auditLog.ListCalls(), 1)
+		assert.Equal(t, storage.AuditFilter{Actor: "admin", Action: storage.AuditUnban, UserID: 123, Limit: 10,
+			Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local), Until: time.Date(2026, 10, 3, 0, 0, 0, 0, time.Local)},
+			auditLog.ListCalls()[0].F, "until date is inclusive")
+	})
+
+	t.Run("rfc3339 time", func(t *testing.T) {
+		auditLog.ResetCalls()
+		w := httptest.NewRecorder()
+		srv.getAuditHandler(w, httptest.NewRequest("GET", "/audit?until=2026-10-01T10:00:00Z", http.NoBody))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, auditLog.ListCalls(), 1)
+		assert.Equal(t, storage.AuditFilter{Until: time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)}, auditLog.ListCalls()[0].F)
+	})
+
+	t.Run("bad filter", func(t *testing.T) {
+		for _, q := range []string{"user_id=abc", "limit=x", "since=yesterday", "until=2026-13-01"} {
+			w := httptest.NewRecorder()
+			srv.getAuditHandler(w, httptest.NewRequest("GET", "/audit?"+q, http.NoBody))
+			assert.Equal(t, http.StatusBadRequest, w.Code, q)
+		}
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		failing := NewServer(Config{AuditLog: &mocks.AuditLogMock{
+			ListFunc: func(ctx context.Context, f storage.AuditFilter) ([]storage.AuditRecord, error) {
+				return nil, errors.New("db error")
+			}}})
+		w := httptest.NewRecorder()
+		failing.getAuditHandler(w, httptest.NewRequest("GET", "/audit", http.NoBody))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "db error")
+	})
+}
+
+func TestServer_downloadAuditHandler(t *testing.T) {
+	ts := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	auditLog := &mocks.AuditLogMock{ListFunc: func(ctx context.Context, f storage.AuditFilter) ([]storage.AuditRecord, error) {
+		return []storage.AuditRecord{
+			{Time: ts, Source: storage.AuditSourceWeb, Actor: "tg-spam", Action: storage.AuditSampleAdd,
+				Text: "buy now, \"cheap\"", Reason: "spam"},
+			{Time: ts.Add(-time.Hour), Source: storage.AuditSourceTelegram, Actor: "admin", ActorID: 10,
+				Action: storage.AuditSpam, UserID: 123, UserName: "spammer", MsgID: 5, Text: "multi\nline"},
+		}, nil
+	}}
+	srv := NewServer(Config{AuditLog: auditLog})
+
+	w := httptest.NewRecorder()
+	srv.downloadAuditHandler(w, httptest.NewRequest("GET", "/download/audit?action=spam", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "audit.csv")
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"time", "source", "actor", "actor_id", "action", "user_id", "user_name", "msg_id", "text",
+		"reason"}, rows[0])
+	assert.Equal(t, []string{"2026-10-01T12:00:00Z", "web", "tg-spam", "0", "sample_add", "0", "", "0",
+		"buy now, \"cheap\"", "spam"}, rows[1])
+	assert.Equal(t, []string{"2026-10-01T11:00:00Z", "telegram", "admin", "10", "spam", "123", "spammer", "5",
+		"multi\nline", ""}, rows[2])
+	require.Len(t, auditLog.ListCalls(), 1)
+	assert.Equal(t, storage.AuditSpam, auditLog.ListCalls()[0].F.Action)
+
+	w = httptest.NewRecorder()
+	srv.downloadAuditHandler(w, httptest.NewRequest("GET", "/download/audit?user_id=bad", http.NoBody))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServer_htmlAuditLogHandler(t *testing.T) {
+	auditLog := &mocks.AuditLogMock{ListFunc: func(ctx context.Context, f storage.AuditFilter) ([]storage.AuditRecord, error) {
+		return []storage.AuditRecord{{Time: time.Now(), Source: storage.AuditSourceTelegram, Actor: "admin",
+			Action: storage.AuditReportBan, UserID: 123, UserName: "spammer", MsgID: 5, Text: "spam text", Reason: "3 reports"}}, nil
+	}}
+	srv := NewServer(Config{AuditLog: auditLog})
+
+	w := httptest.NewRecorder()
+	srv.htmlAuditLogHandler(w, httptest.NewRequest("GET", "/audit_log?action=report_ban&actor=admin", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Audit Log")
+	assert.Contains(t, body, `<option value="report_ban" selected>`)
+	assert.Contains(t, body, `value="admin"`)
+	assert.Contains(t, body, `href="/download/audit?action=report_ban&amp;actor=admin"`)
+	assert.Contains(t, body, `<a href="/user_profile?user_id=123">123</a>`)
+	assert.Contains(t, body, "spam text")
+	assert.Contains(t, body, "3 reports")
+
+	w = httptest.NewRecorder()
+	srv.htmlAuditLogHandler(w, httptest.NewRequest("GET", "/audit_log?since=bad", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "alert-danger")
+	assert.Contains(t, w.Body.String(), "No audit records")
+}
+
+func TestServer_recordAudit(t *testing.T) {
+	auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+	spamFilter := &mocks.SpamFilterMock{
+		UpdateSpamFunc:              func(msg string) error { return nil },
+		RemoveDynamicHamSampleFunc:  func(msg string) error { return nil },
+		RemoveDynamicSpamSampleFunc: func(msg string) error { return nil },
+	}
+	srv := NewServer(Config{AuditLog: auditLog, SpamFilter: spamFilter, AppSettings: &config.Settings{}})
+
+	req := httptest.NewRequest("POST", "/update/spam", strings.NewReader(`{"msg":"buy now"}`))
+	req.SetBasicAuth("moderator", "secret")
+	w := httptest.NewRecorder()
+	srv.updateSampleHandler(spamFilter.UpdateSpam)(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("POST", "/delete/ham", strings.NewReader(`{"msg":"hello"}`))
+	w = httptest.NewRecorder()
+	srv.deleteSampleHandler(spamFilter.RemoveDynamicHamSample)(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	form := url.Values{"primaryGroup": {"group"}}
+	req = httptest.NewRequest("PUT", "/config", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("tg-spam", "secret")
+	w = httptest.NewRecorder()
+	srv.updateConfigHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, auditLog.AddCalls(), 3)
+	assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceWeb, Actor: "moderator", Action: storage.AuditSampleAdd,
+		Text: "buy now", Reason: "spam"}, auditLog.AddCalls()[0].Rec)
+	assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceWeb, Actor: "anonymous", Action: storage.AuditSampleDelete,
+		Text: "hello", Reason: "ham"}, auditLog.AddCalls()[1].Rec, "no basic auth")
+	assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceWeb, Actor: "tg-spam", Action: storage.AuditConfigUpdate,
+		Reason: "applied in memory"}, auditLog.AddCalls()[2].Rec)
+
+	t.Run("failed change not recorded", func(t *testing.T) {
+		auditLog.ResetCalls()
+		spamFilter.UpdateSpamFunc = func(msg string) error { return errors.New("failed") }
+		w := httptest.NewRecorder()
+		srv.updateSampleHandler(spamFilter.UpdateSpam)(w, httptest.NewRequest("POST", "/update/spam",
+			strings.NewReader(`{"msg":"buy now"}`)))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, auditLog.AddCalls())
+	})
+}
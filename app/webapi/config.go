@@ -3,6 +3,7 @@ package webapi
 import (
 	"context"
 	"fmt"
+	"html"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/go-pkgz/rest"
 
 	"github.com/umputun/tg-spam/app/config"
+	"github.com/umputun/tg-spam/app/storage"
 )
 
 //go:generate moq --out mocks/settings_store.go --pkg mocks --with-resets --skip-ensure . SettingsStore
@@ -23,8 +25,13 @@ type SettingsStore interface {
 	Delete(ctx context.Context) error
 	LastUpdated(ctx context.Context) (time.Time, error)
 	Exists(ctx context.Context) (bool, error)
+	Revisions(ctx context.Context, limit int) ([]config.Revision, error)
+	LoadRevision(ctx context.Context, id int64) (*config.Settings, error)
 }
 
+// configHistoryLimit is the max number of settings revisions shown in the history
+const configHistoryLimit = 50
+
 // saveConfigHandler handles POST /config request.
 // It saves the current configuration to the database.
 func (s *Server) saveConfigHandler(w http.ResponseWriter, r *http.Request) {
@@ -36,16 +43,18 @@ func (s *Server) saveConfigHandler(w http.ResponseWriter, r *http.Request) {
 	// save current settings to database; hold the read lock across the call so
 	// concurrent mutations don't race with JSON encoding in the store
 	s.appSettingsMu.RLock()
-	err := s.SettingsStore.Save(r.Context(), s.AppSettings)
+	err := s.SettingsStore.Save(s.revisionContext(r, r.FormValue("comment")), s.AppSettings)
 	s.appSettingsMu.RUnlock()
 	if err != nil {
 		log.Printf("[ERROR] failed to save configuration: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditConfigSave})
 
 	if r.Header.Get("HX-Request") == "true" {
-		// return a success message for HTMX
+		// return a success message for HTMX, the revision history panel is refreshed on the trigger
+		w.Header().Set("HX-Trigger", "configSaved")
 		if _, err := w.Write([]byte(`<div class="alert alert-success">Configuration saved successfully</div>`)); err != nil {
 			log.Printf("[ERROR] failed to write response: %v", err)
 		}
@@ -79,45 +88,24 @@ func (s *Server) loadConfigHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// reapply startup-equivalent normalization: fills any zero fields left by a
-	// partial/legacy DB blob from the defaults template and reasserts operator-
-	// supplied operational CLI overrides (--files.dynamic, --files.samples,
-	// --server.listen, --dry) so reload doesn't silently revert them to DB values.
-	// run BEFORE transient/auth preservation so the closure can't accidentally
-	// touch in-memory transient state.
-	if s.ReloadNormalize != nil {
-		s.ReloadNormalize(settings)
-	}
-
-	// preserve transient settings (never stored in DB). Tokens are NOT preserved:
-	// in --confdb mode the DB is authoritative for Telegram/OpenAI/Gemini tokens,
-	// so reload must pick up fresh DB values. picking them up only refreshes the
-	// settings struct though: the Telegram bot API client and the OpenAI/Gemini
-	// clients are built once in main and are not rebuilt here, so a rotated
-	// service token reaches them only after a restart. Auth hash is preserved only when
-	// transient.AuthFromCLI is set, which marks an in-memory hash that must
-	// survive reload (set by applyCLIOverrides for explicit --server.auth/-hash
-	// flags, and by applyAutoAuthFallback for the auto-generated safety net).
-	// when auth originated from the DB, fresh DB values win so external hash
-	// rotations are picked up.
-	settings.Transient = s.AppSettings.Transient
-	if s.AppSettings.Transient.AuthFromCLI {
-		settings.Server.AuthHash = s.AppSettings.Server.AuthHash
-	}
+	s.prepareStoredSettings(settings)
+	restart := s.applySettings(s.AppSettings, settings)
 	s.AppSettings = settings
 	s.appSettingsMu.Unlock()
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditConfigReload})
 
 	if r.Header.Get("HX-Request") == "true" {
 		// return a success message for HTMX with reload
 		w.Header().Set("HX-Refresh", "true") // force page reload to reflect new settings
-		if _, err := w.Write([]byte(`<div class="alert alert-success">Configuration loaded successfully. Refreshing page...</div>`)); err != nil {
+		msg := "Configuration loaded successfully" + restartNote(restart) + ". Refreshing page..."
+		if _, err := w.Write([]byte(`<div class="alert alert-success">` + msg + `</div>`)); err != nil {
 			log.Printf("[ERROR] failed to write response: %v", err)
 		}
 		return
 	}
 
 	// return JSON response for API calls
-	rest.RenderJSON(w, rest.JSON{"status": "ok", "message": "Configuration loaded successfully"})
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "message": "Configuration loaded successfully", "restart_required": restart})
 }
 
 // updateConfigHandler handles PUT /config request.
@@ -169,7 +157,7 @@ func (s *Server) updateConfigHandler(w http.ResponseWriter, r *http.Request) {
 	saveToDB := r.FormValue("saveToDb") == "true" && s.SettingsStore != nil
 	if saveToDB {
 		log.Printf("[DEBUG] saving settings to database")
-		if err := s.SettingsStore.Save(r.Context(), s.AppSettings); err != nil {
+		if err := s.SettingsStore.Save(s.revisionContext(r, r.FormValue("comment")), s.AppSettings); err != nil {
 			*s.AppSettings = snapshot // rollback in-memory mutation on save failure
 			s.appSettingsMu.Unlock()
 			log.Printf("[ERROR] failed to save updated configuration: %v", err)
@@ -178,20 +166,133 @@ func (s *Server) updateConfigHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		log.Printf("[DEBUG] settings saved successfully")
 	}
+	restart := s.applySettings(&snapshot, s.AppSettings)
 	s.appSettingsMu.Unlock()
+	reason := "applied in memory"
+	if saveToDB {
+		reason = "applied and saved to database"
+	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditConfigUpdate, Reason: reason})
 
 	if r.Header.Get("HX-Request") == "true" {
 		// wrap the alert in #update-result so the next outerHTML swap finds the
 		// same target — without the id, the first save replaces #update-result
 		// with a plain alert div and subsequent saves silently no-op
-		if _, err := w.Write([]byte(`<div id="update-result" class="alert alert-success">Configuration updated successfully</div>`)); err != nil {
+		msg := "Configuration updated successfully" + restartNote(restart)
+		if saveToDB {
+			w.Header().Set("HX-Trigger", "configSaved") // refresh the revision history panel
+		}
+		if _, err := w.Write([]byte(`<div id="update-result" class="alert alert-success">` + msg + `</div>`)); err != nil {
 			log.Printf("[ERROR] failed to write response: %v", err)
 		}
 		return
 	}
 
 	// return JSON response for API calls
-	rest.RenderJSON(w, rest.JSON{"status": "ok", "message": "Configuration updated successfully"})
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "message": "Configuration updated successfully", "restart_required": restart})
+}
+
+// applySettings applies changed settings to the running detector and listener with ApplySettings, if set.
+// Returns paths of changed settings which require a restart, never nil to keep the JSON response a list.
+// Without ApplySettings nothing is applied live and all changed settings require a restart.
+// Must be called with appSettingsMu held.
+func (s *Server) applySettings(prev, settings *config.Settings) []string {
+	var restart []string
+	if s.ApplySettings != nil {
+		restart = s.ApplySettings(prev, settings)
+	} else {
+		restart = prev.Diff(settings)
+	}
+	if restart == nil {
+		return []string{}
+	}
+	return restart
+}
+
+// prepareStoredSettings prepares settings loaded from the database, on reload or rollback, to replace in-memory settings.
+// Must be called with appSettingsMu held.
+func (s *Server) prepareStoredSettings(settings *config.Settings) {
+	// reapply startup-equivalent normalization: fills any zero fields left by a
+	// partial/legacy DB blob from the defaults template and reasserts operator-
+	// supplied operational CLI overrides (--files.dynamic, --files.samples,
+	// --server.listen, --dry) so reload or rollback doesn't silently revert them to DB values.
+	// run BEFORE transient/auth preservation so the closure can't accidentally
+	// touch in-memory transient state.
+	if s.ReloadNormalize != nil {
+		s.ReloadNormalize(settings)
+	}
+	s.preserveRuntimeSettings(settings)
+}
+
+// preserveRuntimeSettings copies in-memory settings which must survive replacing settings, transient values
+// and auth hash set on CLI, to the new settings. Must be called with appSettingsMu held.
+func (s *Server) preserveRuntimeSettings(settings *config.Settings) {
+	// preserve transient settings (never stored in DB). Tokens are NOT preserved:
+	// in --confdb mode the DB is authoritative for Telegram/OpenAI/Gemini tokens,
+	// so reload must pick up fresh DB values. LLM clients are rebuilt with the
+	// rotated tokens by ApplySettings, while the Telegram bot API client is built
+	// once in main, so a rotated Telegram token reaches it only after a restart
+	// and is reported as such. Auth hash is preserved only when
+	// transient.AuthFromCLI is set, which marks an in-memory hash that must
+	// survive reload (set by applyCLIOverrides for explicit --server.auth/-hash
+	// flags, and by applyAutoAuthFallback for the auto-generated safety net).
+	// when auth originated from the DB, fresh DB values win so external hash
+	// rotations are picked up.
+	settings.Transient = s.AppSettings.Transient
+	if s.AppSettings.Transient.AuthFromCLI {
+		settings.Server.AuthHash = s.AppSettings.Server.AuthHash
+	}
+}
+
+// ReplaceSettings replaces in-memory settings with settings loaded outside of web UI, e.g. from the changed
+// config file, and applies them to the running instance. Unlike reload, settings are expected to be normalized
+// by the caller. Returns paths of changed settings which require a restart.
+func (s *Server) ReplaceSettings(settings *config.Settings) (restart []string) {
+	s.appSettingsMu.Lock()
+	defer s.appSettingsMu.Unlock()
+	s.preserveRuntimeSettings(settings)
+	restart = s.applySettings(s.AppSettings, settings)
+	s.AppSettings = settings
+	return restart
+}
+
+// exportConfigHandler handles GET /download/config request. It returns current settings as the yaml file
+// for --config, with secrets written as references to environment variables.
+func (s *Server) exportConfigHandler(w http.ResponseWriter, _ *http.Request) {
+	s.appSettingsMu.RLock()
+	if s.AppSettings == nil {
+		s.appSettingsMu.RUnlock()
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "settings not available"})
+		return
+	}
+	data, err := s.AppSettings.ExportYAML()
+	s.appSettingsMu.RUnlock()
+	if err != nil {
+		log.Printf("[ERROR] failed to export configuration: %v", err)
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't export configuration",
+			"details": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", `attachment; filename="tg-spam.yml"`)
+	if _, err := w.Write(data); err != nil {
+		log.Printf("[ERROR] failed to write response: %v", err)
+	}
+}
+
+// revisionContext returns the request context with author and comment of the saved settings revision,
+// the author is the same as the actor in the audit log
+func (s *Server) revisionContext(r *http.Request, comment string) context.Context {
+	return config.WithRevisionInfo(r.Context(), requestActor(r), strings.TrimSpace(comment))
+}
+
+// restartNote makes a note about settings which require a restart to be added to the success message
+func restartNote(restart []string) string {
+	if len(restart) == 0 {
+		return ""
+	}
+	return html.EscapeString(", restart required to apply: " + strings.Join(restart, ", "))
 }
 
 // deleteConfigHandler handles DELETE /config request.
@@ -209,6 +310,7 @@ func (s *Server) deleteConfigHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to delete configuration: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditConfigDelete})
 
 	if r.Header.Get("HX-Request") == "true" {
 		// return a success message for HTMX
@@ -222,6 +324,143 @@ func (s *Server) deleteConfigHandler(w http.ResponseWriter, r *http.Request) {
 	rest.RenderJSON(w, rest.JSON{"status": "ok", "message": "Configuration deleted successfully"})
 }
 
+// configHistoryHandler handles GET /config/history request.
+// It returns saved revisions of the settings, newest first, as JSON or as the history panel for HTMX.
+func (s *Server) configHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	revisions, err := s.SettingsStore.Revisions(r.Context(), configHistoryLimit)
+	if err != nil {
+		log.Printf("[ERROR] failed to get configuration history: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get configuration history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		// each entry links to the previous revision to show changes made by it
+		type entry struct {
+			config.Revision
+			PrevID int64
+		}
+		entries := make([]entry, len(revisions))
+		for i, rev := range revisions {
+			entries[i] = entry{Revision: rev}
+			if i+1 < len(revisions) {
+				entries[i].PrevID = revisions[i+1].ID
+			}
+		}
+		if err := tmpl.ExecuteTemplate(w, "config_history", entries); err != nil {
+			log.Printf("[WARN] can't execute config history template: %v", err)
+			http.Error(w, "Error executing template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if revisions == nil {
+		revisions = []config.Revision{}
+	}
+	rest.RenderJSON(w, rest.JSON{"revisions": revisions})
+}
+
+// configDiffHandler handles GET /config/history/{id}/diff request.
+// It returns changes from the revision to the current in-memory settings, or, with "from" query param,
+// changes made from the "from" revision to the revision. Values of sensitive fields are masked.
+func (s *Server) configDiffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid revision id: %v", err), http.StatusBadRequest)
+		return
+	}
+	revision, err := s.SettingsStore.LoadRevision(r.Context(), id)
+	if err != nil {
+		log.Printf("[WARN] failed to load configuration revision %d: %v", id, err)
+		http.Error(w, fmt.Sprintf("Failed to load revision: %v", err), http.StatusNotFound)
+		return
+	}
+
+	var changes []config.Change
+	if fromParam := r.URL.Query().Get("from"); fromParam != "" {
+		fromID, err := strconv.ParseInt(fromParam, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid revision id: %v", err), http.StatusBadRequest)
+			return
+		}
+		from, err := s.SettingsStore.LoadRevision(r.Context(), fromID)
+		if err != nil {
+			log.Printf("[WARN] failed to load configuration revision %d: %v", fromID, err)
+			http.Error(w, fmt.Sprintf("Failed to load revision: %v", err), http.StatusNotFound)
+			return
+		}
+		changes = from.Changes(revision)
+	} else {
+		s.appSettingsMu.RLock()
+		changes = revision.Changes(s.AppSettings)
+		s.appSettingsMu.RUnlock()
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		if err := tmpl.ExecuteTemplate(w, "config_diff", changes); err != nil {
+			log.Printf("[WARN] can't execute config diff template: %v", err)
+			http.Error(w, "Error executing template", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if changes == nil {
+		changes = []config.Change{}
+	}
+	rest.RenderJSON(w, rest.JSON{"changes": changes})
+}
+
+// configRollbackHandler handles POST /config/history/{id}/rollback request.
+// It replaces in-memory settings with the saved revision and saves them to the database as a new revision,
+// so the rollback can be undone the same way.
+func (s *Server) configRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid revision id: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// hold the write lock across the DB read, save and memory swap, same as reload
+	s.appSettingsMu.Lock()
+	settings, err := s.SettingsStore.LoadRevision(r.Context(), id)
+	if err != nil {
+		s.appSettingsMu.Unlock()
+		log.Printf("[WARN] failed to load configuration revision %d: %v", id, err)
+		http.Error(w, fmt.Sprintf("Failed to load revision: %v", err), http.StatusNotFound)
+		return
+	}
+	s.prepareStoredSettings(settings)
+	if err := settings.Validate(); err != nil {
+		s.appSettingsMu.Unlock()
+		log.Printf("[WARN] rejected rollback to invalid revision %d: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	comment := fmt.Sprintf("rollback to revision %d", id)
+	if err := s.SettingsStore.Save(s.revisionContext(r, comment), settings); err != nil {
+		s.appSettingsMu.Unlock()
+		log.Printf("[ERROR] failed to save configuration rolled back to revision %d: %v", id, err)
+		http.Error(w, fmt.Sprintf("Failed to save configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+	restart := s.applySettings(s.AppSettings, settings)
+	s.AppSettings = settings
+	s.appSettingsMu.Unlock()
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditConfigRollback, Reason: comment})
+
+	msg := fmt.Sprintf("Configuration rolled back to revision %d", id)
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true") // force page reload to reflect restored settings
+		msg += restartNote(restart) + ". Refreshing page..."
+		if _, err := w.Write([]byte(`<div class="alert alert-success">` + msg + `</div>`)); err != nil {
+			log.Printf("[ERROR] failed to write response: %v", err)
+		}
+		return
+	}
+
+	rest.RenderJSON(w, rest.JSON{"status": "ok", "message": msg, "restart_required": restart})
+}
+
 // normalizeLuaEnabledPlugins collapses a "all available plugins selected"
 // submission back to nil so the semantic "no preference, enable all" stored in
 // EnabledPlugins survives a UI round-trip. The settings page renders every
@@ -511,6 +750,26 @@ func updateSettingsFromForm(settings *config.Settings, r *http.Request) {
 		}
 	}
 
+	// join captcha. the checkbox is rendered in the form, so missing value means unchecked,
+	// same as trainingEnabled. type and action are selects and never posted empty
+	settings.Captcha.Enabled = r.FormValue("captchaEnabled") == "on"
+	if val := r.FormValue("captchaType"); val != "" {
+		settings.Captcha.Type = val
+	}
+	if val := r.FormValue("captchaAction"); val != "" {
+		settings.Captcha.Action = val
+	}
+	if _, ok := r.Form["captchaTimeout"]; ok {
+		if d, err := time.ParseDuration(r.FormValue("captchaTimeout")); err == nil {
+			settings.Captcha.Timeout = d
+		}
+	}
+	if val := r.FormValue("captchaApproveCount"); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			settings.Captcha.ApproveCount = n
+		}
+	}
+
 	// service-message deletion. These flags are not rendered in the ConfigDB UI
 	// form; gate the writes on form presence so unrelated saves don't wipe them.
 	if _, ok := r.Form["deleteJoinMessages"]; ok {
@@ -599,6 +858,13 @@ func updateSettingsFromForm(settings *config.Settings, r *http.Request) {
 		}
 	}
 
+	if val := r.FormValue("obfuscatedWords"); val != "" {
+		if limit, err := strconv.Atoi(val); err == nil {
+			settings.ObfuscatedWords = limit
+		}
+	}
+	settings.NormalizeText = r.FormValue("normalizeText") == "on"
+
 	// gate on r.Form presence so an empty submit clears the list (disables the
 	// prohibited-language check); submits without the field preserve the value.
 	if _, ok := r.Form["prohibitedLangs"]; ok {
@@ -2,6 +2,8 @@ package webapi
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -943,6 +945,259 @@ func TestDeleteConfigHandler(t *testing.T) {
 	})
 }
 
+func TestConfigHistoryHandler(t *testing.T) {
+	created := time.Date(2026, 5, 1, 10, 20, 30, 0, time.UTC)
+	settingsStore := &mocks.SettingsStoreMock{
+		RevisionsFunc: func(ctx context.Context, limit int) ([]config.Revision, error) {
+			return []config.Revision{{ID: 3, Author: "admin", Comment: "raise <threshold>", CreatedAt: created},
+				{ID: 1, CreatedAt: created}}, nil
+		},
+	}
+	srv := Server{Config: Config{SettingsStore: settingsStore}}
+
+	t.Run("json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		srv.configHistoryHandler(w, httptest.NewRequest("GET", "/config/history", http.NoBody))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Revisions []config.Revision `json:"revisions"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Revisions, 2)
+		assert.Equal(t, "admin", resp.Revisions[0].Author)
+		assert.Equal(t, configHistoryLimit, settingsStore.RevisionsCalls()[0].Limit)
+	})
+
+	t.Run("htmx", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/config/history", http.NoBody)
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.configHistoryHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, "2026-05-01 10:20:30")
+		assert.Contains(t, body, "raise &lt;threshold&gt;", "comment escaped")
+		assert.Contains(t, body, `/config/history/3/diff?from=1`, "changes of revision against previous one")
+		assert.NotContains(t, body, `/config/history/1/diff?from=`, "first revision has no previous one")
+		assert.Contains(t, body, `/config/history/1/rollback`)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		srv := Server{Config: Config{SettingsStore: &mocks.SettingsStoreMock{
+			RevisionsFunc: func(ctx context.Context, limit int) ([]config.Revision, error) { return nil, errors.New("db error") },
+		}}}
+		w := httptest.NewRecorder()
+		srv.configHistoryHandler(w, httptest.NewRequest("GET", "/config/history", http.NoBody))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestConfigDiffHandler(t *testing.T) {
+	revisions := map[int64]*config.Settings{
+		1: {MinMsgLen: 10, Telegram: config.TelegramSettings{Token: "token-1"}},
+		2: {MinMsgLen: 20, Telegram: config.TelegramSettings{Token: "token-2"}},
+	}
+	settingsStore := &mocks.SettingsStoreMock{
+		LoadRevisionFunc: func(ctx context.Context, id int64) (*config.Settings, error) {
+			if s, ok := revisions[id]; ok {
+				return s, nil
+			}
+			return nil, sql.ErrNoRows
+		},
+	}
+	srv := Server{Config: Config{SettingsStore: settingsStore, AppSettings: &config.Settings{MinMsgLen: 30,
+		Telegram: config.TelegramSettings{Token: "token-2"}}}}
+
+	diff := func(t *testing.T, target string, htmx bool) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /config/history/{id}/diff", srv.configDiffHandler)
+		req := httptest.NewRequest("GET", target, http.NoBody)
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("revision against previous one", func(t *testing.T) {
+		w := diff(t, "/config/history/2/diff?from=1", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Changes []config.Change `json:"changes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []config.Change{{Path: "Telegram.Token", Old: `"*****"`, New: `"*****"`},
+			{Path: "MinMsgLen", Old: "10", New: "20"}}, resp.Changes)
+		assert.NotContains(t, w.Body.String(), "token-")
+	})
+
+	t.Run("revision against current settings", func(t *testing.T) {
+		w := diff(t, "/config/history/2/diff", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "<code>MinMsgLen</code>")
+		assert.Contains(t, w.Body.String(), "<code>30</code>")
+		assert.NotContains(t, w.Body.String(), "Telegram.Token", "unchanged token not listed")
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		w := diff(t, "/config/history/2/diff?from=2", false)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"changes":[]}`, w.Body.String())
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, diff(t, "/config/history/5/diff", false).Code)
+		assert.Equal(t, http.StatusNotFound, diff(t, "/config/history/2/diff?from=5", false).Code)
+	})
+
+	t.Run("bad revision id", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, diff(t, "/config/history/abc/diff", false).Code)
+		assert.Equal(t, http.StatusBadRequest, diff(t, "/config/history/2/diff?from=abc", false).Code)
+	})
+}
+
+func TestConfigRollbackHandler(t *testing.T) {
+	rollback := func(t *testing.T, srv *Server, id string, htmx bool) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /config/history/{id}/rollback", srv.configRollbackHandler)
+		req := httptest.NewRequest("POST", "/config/history/"+id+"/rollback", http.NoBody)
+		req.SetBasicAuth("admin", "passwd")
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	makeStore := func() *mocks.SettingsStoreMock {
+		return &mocks.SettingsStoreMock{
+			LoadRevisionFunc: func(ctx context.Context, id int64) (*config.Settings, error) {
+				if id != 1 {
+					return nil, sql.ErrNoRows
+				}
+				return &config.Settings{MinMsgLen: 10, Server: config.ServerSettings{AuthHash: "db-hash"}}, nil
+			},
+			SaveFunc: func(ctx context.Context, settings *config.Settings) error { return nil },
+		}
+	}
+
+	t.Run("rollback", func(t *testing.T) {
+		store := makeStore()
+		var applied []*config.Settings
+		srv := &Server{Config: Config{SettingsStore: store,
+			AppSettings: &config.Settings{MinMsgLen: 30, Transient: config.TransientSettings{Dbg: true, AuthFromCLI: true},
+				Server: config.ServerSettings{AuthHash: "cli-hash"}},
+			ApplySettings: func(prev, settings *config.Settings) []string {
+				applied = append(applied, prev, settings)
+				return nil
+			},
+		}}
+
+		w := rollback(t, srv, "1", false)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "Configuration rolled back to revision 1")
+		assert.Equal(t, 10, srv.AppSettings.MinMsgLen)
+		assert.True(t, srv.AppSettings.Transient.Dbg, "transient settings preserved")
+		assert.Equal(t, "cli-hash", srv.AppSettings.Server.AuthHash, "auth from cli preserved")
+		require.Len(t, store.SaveCalls(), 1, "rollback saved as a new revision")
+		assert.Equal(t, srv.AppSettings, store.SaveCalls()[0].Settings)
+		require.Len(t, applied, 2)
+		assert.Equal(t, 30, applied[0].MinMsgLen)
+		assert.Equal(t, 10, applied[1].MinMsgLen)
+	})
+
+	t.Run("rollback with htmx", func(t *testing.T) {
+		srv := &Server{Config: Config{SettingsStore: makeStore(), AppSettings: &config.Settings{MinMsgLen: 30}}}
+		w := rollback(t, srv, "1", true)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "true", w.Header().Get("HX-Refresh"))
+		assert.Contains(t, w.Body.String(), "restart required to apply: Server.AuthHash, MinMsgLen")
+	})
+
+	t.Run("invalid revision settings", func(t *testing.T) {
+		store := makeStore()
+		store.LoadRevisionFunc = func(ctx context.Context, id int64) (*config.Settings, error) {
+			return &config.Settings{ProhibitedLangs: "klingon", ProhibitedLangsMin: 1}, nil
+		}
+		srv := &Server{Config: Config{SettingsStore: store, AppSettings: &config.Settings{MinMsgLen: 30}}}
+		w := rollback(t, srv, "1", false)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, store.SaveCalls())
+		assert.Equal(t, 30, srv.AppSettings.MinMsgLen, "settings not changed")
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		store := makeStore()
+		store.SaveFunc = func(ctx context.Context, settings *config.Settings) error { return errors.New("db error") }
+		srv := &Server{Config: Config{SettingsStore: store, AppSettings: &config.Settings{MinMsgLen: 30}}}
+		w := rollback(t, srv, "1", false)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, 30, srv.AppSettings.MinMsgLen, "settings not changed")
+	})
+
+	t.Run("unknown revision", func(t *testing.T) {
+		srv := &Server{Config: Config{SettingsStore: makeStore(), AppSettings: &config.Settings{MinMsgLen: 30}}}
+		assert.Equal(t, http.StatusNotFound, rollback(t, srv, "5", false).Code)
+		assert.Equal(t, http.StatusBadRequest, rollback(t, srv, "abc", false).Code)
+	})
+}
+
+func TestExportConfigHandler(t *testing.T) {
+	t.Run("exported", func(t *testing.T) {
+		settings := config.New()
+		settings.MinMsgLen = 42
+		settings.Telegram.Group = "test-group"
+		settings.Telegram.Token = "secret-token"
+		srv := Server{Config: Config{AppSettings: settings}}
+
+		req := httptest.NewRequest("GET", "/download/config", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.exportConfigHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="tg-spam.yml"`, w.Header().Get("Content-Disposition"))
+		assert.Contains(t, w.Body.String(), "min_msg_len: 42")
+		assert.Contains(t, w.Body.String(), "group: test-group")
+		assert.Contains(t, w.Body.String(), "token: ${TELEGRAM_TOKEN}")
+		assert.NotContains(t, w.Body.String(), "secret-token")
+	})
+
+	t.Run("no settings", func(t *testing.T) {
+		srv := Server{}
+		req := httptest.NewRequest("GET", "/download/config", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.exportConfigHandler(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_ReplaceSettings(t *testing.T) {
+	var applied []*config.Settings
+	current := &config.Settings{MinMsgLen: 5, Server: config.ServerSettings{AuthHash: "cli-hash"},
+		Transient: config.TransientSettings{AuthFromCLI: true, Dbg: true}}
+	srv := Server{Config: Config{
+		AppSettings: current,
+		ApplySettings: func(prev, settings *config.Settings) []string {
+			applied = append(applied, prev, settings)
+			return []string{"Telegram.Token"}
+		},
+	}}
+
+	settings := &config.Settings{MinMsgLen: 42, Server: config.ServerSettings{AuthHash: "file-hash"}}
+	restart := srv.ReplaceSettings(settings)
+	assert.Equal(t, []string{"Telegram.Token"}, restart)
+	require.Len(t, applied, 2)
+	assert.Same(t, current, applied[0])
+	assert.Same(t, settings, applied[1])
+	assert.Same(t, settings, srv.AppSettings)
+	assert.Equal(t, 42, srv.AppSettings.MinMsgLen)
+	assert.True(t, srv.AppSettings.Transient.Dbg, "transient settings preserved")
+	assert.Equal(t, "cli-hash", srv.AppSettings.Server.AuthHash, "auth hash set on cli preserved")
+}
+
 func TestUpdateSettingsFromForm(t *testing.T) {
 	t.Run("boolean flags and simple fields", func(t *testing.T) {
 		settings := &config.Settings{
@@ -1249,6 +1504,23 @@ func TestUpdateSettingsFromForm(t *testing.T) {
 		assert.Equal(t, 7, settings.MultiLangWords)
 	})
 
+	t.Run("normalization and obfuscated words", func(t *testing.T) {
+		settings := &config.Settings{ObfuscatedWords: 1}
+
+		form := url.Values{}
+		form.Add("obfuscatedWords", "3")
+		form.Add("normalizeText", "on")
+
+		req := httptest.NewRequest("PUT", "/config", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		require.NoError(t, req.ParseForm())
+
+		updateSettingsFromForm(settings, req)
+
+		assert.Equal(t, 3, settings.ObfuscatedWords)
+		assert.True(t, settings.NormalizeText)
+	})
+
 	t.Run("Lua plugins settings", func(t *testing.T) {
 		settings := &config.Settings{
 			LuaPlugins: config.LuaPluginsSettings{
@@ -1684,6 +1956,47 @@ func TestUpdateSettingsFromForm_Reactions_PresentZeroExplicit(t *testing.T) {
 	assert.Equal(t, 1*time.Hour, settings.Reactions.Window, "Window must be preserved when not in form")
 }
 
+func TestUpdateSettingsFromForm_Captcha(t *testing.T) {
+	t.Run("present values applied", func(t *testing.T) {
+		settings := &config.Settings{}
+		form := url.Values{}
+		form.Add("captchaEnabled", "on")
+		form.Add("captchaType", "math")
+		form.Add("captchaTimeout", "2m")
+		form.Add("captchaAction", "kick")
+		form.Add("captchaApproveCount", "3")
+
+		req := httptest.NewRequest("PUT", "/config", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		require.NoError(t, req.ParseForm())
+		updateSettingsFromForm(settings, req)
+
+		assert.True(t, settings.Captcha.Enabled)
+		assert.Equal(t, "math", settings.Captcha.Type)
+		assert.Equal(t, 2*time.Minute, settings.Captcha.Timeout)
+		assert.Equal(t, "kick", settings.Captcha.Action)
+		assert.Equal(t, 3, settings.Captcha.ApproveCount)
+	})
+
+	t.Run("absent values preserved, unchecked box disables", func(t *testing.T) {
+		settings := &config.Settings{Captcha: config.CaptchaSettings{Enabled: true, Type: "emoji",
+			Timeout: 5 * time.Minute, Action: "ban", ApproveCount: 2}}
+		form := url.Values{}
+		form.Add("primaryGroup", "some-group")
+
+		req := httptest.NewRequest("PUT", "/config", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		require.NoError(t, req.ParseForm())
+		updateSettingsFromForm(settings, req)
+
+		assert.False(t, settings.Captcha.Enabled)
+		assert.Equal(t, "emoji", settings.Captcha.Type)
+		assert.Equal(t, 5*time.Minute, settings.Captcha.Timeout)
+		assert.Equal(t, "ban", settings.Captcha.Action)
+		assert.Equal(t, 2, settings.Captcha.ApproveCount)
+	})
+}
+
 func TestUpdateSettingsFromForm_Warn_PresentApplied(t *testing.T) {
 	// warnThreshold and warnWindow present in form must be parsed and applied
 	settings := &config.Settings{}
@@ -2117,6 +2430,61 @@ func TestUpdateConfigHandler_HTMXResponse_HasTargetID(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Configuration updated successfully")
 }
 
+func TestUpdateConfigHandler_AppliesSettings(t *testing.T) {
+	var applied []*config.Settings
+	srv := Server{
+		Config: Config{
+			AppSettings: &config.Settings{Telegram: config.TelegramSettings{Group: "test-group"}, MinMsgLen: 5},
+			ApplySettings: func(prev, settings *config.Settings) []string {
+				applied = append(applied, prev, settings)
+				return []string{"Telegram.Group"}
+			},
+		},
+	}
+
+	form := url.Values{}
+	form.Add("primaryGroup", "new-group")
+	form.Add("minMsgLen", "42")
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/config", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.updateConfigHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []any{"Telegram.Group"}, resp["restart_required"])
+		require.Len(t, applied, 2)
+		assert.Equal(t, 5, applied[0].MinMsgLen, "previous settings passed")
+		assert.Equal(t, 42, applied[1].MinMsgLen, "updated settings passed")
+	})
+
+	t.Run("htmx", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/config", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.updateConfigHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "restart required to apply: Telegram.Group")
+	})
+
+	t.Run("without apply func all changes reported", func(t *testing.T) {
+		srv := Server{Config: Config{AppSettings: &config.Settings{MinMsgLen: 5}}}
+		req := httptest.NewRequest("PUT", "/config", strings.NewReader("minMsgLen=42"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		srv.updateConfigHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, []any{"MinMsgLen"}, resp["restart_required"])
+	})
+}
+
 func TestUpdateConfigHandler_SaveFailure_PreservesSlices(t *testing.T) {
 	// rollback must restore slice fields (Admin.SuperUsers, LuaPlugins.EnabledPlugins)
 	// to their pre-call value. Verifies the snapshot-and-restore approach is sound for
@@ -0,0 +1,111 @@
+package webapi
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+// maxFederationFeedEntries limits the number of detected spam entries published in the federation feed
+const maxFederationFeedEntries = 10000
+
+// isFederationPath reports whether the request is for the public federation endpoints. The feed is signed
+// and peers pull it without credentials, so these endpoints skip basic auth if federation is enabled.
+func (s *Server) isFederationPath(r *http.Request) bool {
+	if s.FederationKey == nil || r.Method != http.MethodGet {
+		return false
+	}
+	return r.URL.Path == "/federation/feed" || r.URL.Path == "/federation/key"
+}
+
+// getFederationFeedHandler handles GET /federation/feed request. It returns the feed of users banned within
+// the feed window, signed with the federation key. The signature of the body is in the X-Tg-Spam-Signature header.
+func (s *Server) getFederationFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if s.FederationKey == nil {
+		http.Error(w, "federation is disabled", http.StatusNotFound)
+		return
+	}
+	entries, err := s.DetectedSpam.ReadSince(r.Context(), time.Now().Add(-s.FederationFeed), maxFederationFeedEntries)
+	if err != nil {
+		log.Printf("[WARN] can't read detected spam for federation feed: %v", err)
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't read detected spam", "details": err.Error()})
+		return
+	}
+
+	// users unbanned by admin are approved, so approval after the detection excludes both unbanned and approved users
+	approvedAt := map[int64]time.Time{}
+	if s.Detector != nil {
+		for _, u := range s.Detector.ApprovedUsers() {
+			if id, err := strconv.ParseInt(u.UserID, 10, 64); err == nil {
+				approvedAt[id] = u.Timestamp
+			}
+		}
+	}
+
+	body, sig, err := tgspam.SignFederationFeed(s.FederationKey, makeFederationFeed(entries, approvedAt))
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't sign feed", "details": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(tgspam.FederationSignatureHeader, sig)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("[WARN] can't write federation feed: %v", err)
+	}
+}
+
+// getFederationKeyHandler handles GET /federation/key request. It returns base64 encoded public key
+// peers use to verify the federation feed.
+func (s *Server) getFederationKeyHandler(w http.ResponseWriter, _ *http.Request) {
+	if s.FederationKey == nil {
+		http.Error(w, "federation is disabled", http.StatusNotFound)
+		return
+	}
+	pub, ok := s.FederationKey.Public().(ed25519.PublicKey)
+	if !ok {
+		http.Error(w, "invalid federation key", http.StatusInternalServerError)
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"public_key": base64.StdEncoding.EncodeToString(pub)})
+}
+
+// makeFederationFeed makes the feed of banned users from detected spam entries, newest first.
+// Each user is published once, with names of the checks detected spam as reasons. Users banned only
+// because of federation matches are not published, to avoid echoing bans between peers. Detections made
+// in dry run or training mode are not published, as the user was never banned, and neither are users
+// approved after the detection, i.e. unbanned or approved by admin. Mutes, restrictions and bans for a limited
+// time by the punishment ladder are not published either, peers trusting the feed would ban such users permanently.
+func makeFederationFeed(entries []storage.DetectedSpamInfo, approvedAt map[int64]time.Time) tgspam.FederationFeed {
+	res := tgspam.FederationFeed{GeneratedAt: time.Now().UTC(), Bans: []tgspam.FederatedBan{}}
+	seen := map[int64]bool{}
+	for _, e := range entries {
+		if e.UserID == 0 || e.Dry || e.Temporary || seen[e.UserID] {
+			continue
+		}
+		if ts, ok := approvedAt[e.UserID]; ok && ts.After(e.Timestamp) {
+			seen[e.UserID] = true // older detections are cleared by the approval as well
+			continue
+		}
+		var reasons []string
+		for _, c := range e.Checks {
+			if c.Spam && c.Name != tgspam.ScoreCheckName && c.Name != tgspam.FederationCheckName {
+				reasons = append(reasons, c.Name)
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		seen[e.UserID] = true
+		res.Bans = append(res.Bans, tgspam.FederatedBan{UserID: e.UserID, UserName: e.UserName, Reasons: reasons,
+			BannedAt: e.Timestamp.UTC()})
+	}
+	return res
+}
@@ -0,0 +1,160 @@
+package webapi
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/webapi/mocks"
+	"github.com/umputun/tg-spam/lib/approved"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam"
+)
+
+func TestServer_getFederationFeedHandler(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ds := &mocks.DetectedSpamMock{ReadSinceFunc: func(ctx context.Context, since time.Time, limit int) ([]storage.DetectedSpamInfo, error) {
+		return []storage.DetectedSpamInfo{
+			{UserID: 1, UserName: "spammer", Timestamp: ts,
+				Checks: []spamcheck.Response{{Name: "cas", Spam: true}, {Name: "emoji"}, {Name: "score", Spam: true}}},
+			{UserID: 1, Timestamp: ts.Add(-time.Hour), Checks: []spamcheck.Response{{Name: "stopword", Spam: true}}},
+			{UserID: 2, Timestamp: ts, Checks: []spamcheck.Response{{Name: "federation", Spam: true}}},
+			{UserID: 3, Timestamp: ts, Checks: []spamcheck.Response{{Name: "federation", Spam: true}, {Name: "classifier", Spam: true}}},
+		}, nil
+	}}
+
+	t.Run("signed feed", func(t *testing.T) {
+		srv := NewServer(Config{DetectedSpam: ds, FederationKey: priv, FederationFeed: 24 * time.Hour})
+		w := httptest.NewRecorder()
+		srv.getFederationFeedHandler(w, httptest.NewRequest("GET", "/federation/feed", http.NoBody))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		feed, err := tgspam.VerifyFederationFeed(pub, w.Body.Bytes(), w.Header().Get(tgspam.FederationSignatureHeader))
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), feed.GeneratedAt, time.Minute)
+		assert.Equal(t, []tgspam.FederatedBan{
+			{UserID: 1, UserName: "spammer", Reasons: []string{"cas"}, BannedAt: ts},
+			{UserID: 3, Reasons: []string{"classifier"}, BannedAt: ts},
+		}, feed.Bans, "each user once, federation-only bans not echoed")
+
+		require.Len(t, ds.ReadSinceCalls(), 1)
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), ds.ReadSinceCalls()[0].Since, time.Minute)
+		assert.Equal(t, maxFederationFeedEntries, ds.ReadSinceCalls()[0].Limit)
+	})
+
+	t.Run("not banned users excluded", func(t *testing.T) {
+		entries := &mocks.DetectedSpamMock{ReadSinceFunc: func(context.Context, time.Time, int) ([]storage.DetectedSpamInfo, error) {
+			return []storage.DetectedSpamInfo{
+				{UserID: 10, Timestamp: ts, Checks: []spamcheck.Response{{Name: "cas", Spam: true}}},
+				{UserID: 11, Timestamp: ts, Checks: []spamcheck.Response{{Name: "cas", Spam: true}}},
+				{UserID: 11, Timestamp: ts.Add(-time.Hour), Checks: []spamcheck.Response{{Name: "stopword", Spam: true}}},
+				{UserID: 12, Timestamp: ts, Checks: []spamcheck.Response{{Name: "cas", Spam: true}}},
+				{UserID: 13, Timestamp: ts, Dry: true, Checks: []spamcheck.Response{{Name: "cas", Spam: true}}},
+				{UserID: 14, Timestamp: ts, Dry: true, Checks: []spamcheck.Response{{Name: "cas", Spam: true}}},
+				{UserID: 14, Timestamp: ts.Add(-time.Hour), Checks: []spamcheck.Response{{Name: "stopword", Spam: true}}},
+				{UserID: 15, Timestamp: ts, Checks: []spamcheck.Response{{Name: "cas", Spam: true}}},
+				{UserID: 16, Timestamp: ts, Temporary: true, Checks: []spamcheck.Response{{Name: "stopword", Spam: true}}},
+			}, nil
+		}}
+		detector := &mocks.DetectorMock{ApprovedUsersFunc: func() []approved.UserInfo {
+			return []approved.UserInfo{
+				{UserID: "11", UserName: "unbanned", Timestamp: ts.Add(time.Minute)}, // unbanned by admin, approved
+				{UserID: "12", UserName: "approved", Timestamp: ts.Add(time.Hour)},   // approved with web ui
+				{UserID: "15", UserName: "counted", Timestamp: ts.Add(-time.Minute)}, // ham messages before spam
+				{UserID: "bad", Timestamp: ts.Add(time.Hour)},
+			}
+		}}
+		srv := NewServer(Config{DetectedSpam: entries, Detector: detector, FederationKey: priv, FederationFeed: time.Hour})
+		w := httptest.NewRecorder()
+		srv.getFederationFeedHandler(w, httptest.NewRequest("GET", "/federation/feed", http.NoBody))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		feed, err := tgspam.VerifyFederationFeed(pub, w.Body.Bytes(), w.Header().Get(tgspam.FederationSignatureHeader))
+		require.NoError(t, err)
+		assert.Equal(t, []tgspam.FederatedBan{
+			{UserID: 10, Reasons: []string{"cas"}, BannedAt: ts},
+			{UserID: 14, Reasons: []string{"stopword"}, BannedAt: ts.Add(-time.Hour)},
+			{UserID: 15, Reasons: []string{"cas"}, BannedAt: ts},
+		}, feed.Bans, "unbanned, approved, muted and dry run detections not published")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		srv := NewServer(Config{DetectedSpam: ds})
+		w := httptest.NewRecorder()
+		srv.getFederationFeedHandler(w, httptest.NewRequest("GET", "/federation/feed", http.NoBody))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		failing := &mocks.DetectedSpamMock{ReadSinceFunc: func(context.Context, time.Time, int) ([]storage.DetectedSpamInfo, error) {
+			return nil, errors.New("db error")
+		}}
+		srv := NewServer(Config{DetectedSpam: failing, FederationKey: priv, FederationFeed: time.Hour})
+		w := httptest.NewRecorder()
+		srv.getFederationFeedHandler(w, httptest.NewRequest("GET", "/federation/feed", http.NoBody))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_getFederationKeyHandler(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	srv := NewServer(Config{FederationKey: priv})
+	w := httptest.NewRecorder()
+	srv.getFederationKeyHandler(w, httptest.NewRequest("GET", "/federation/key", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		PublicKey string `json:"public_key"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(pub), resp.PublicKey)
+
+	srv = NewServer(Config{})
+	w = httptest.NewRecorder()
+	srv.getFederationKeyHandler(w, httptest.NewRequest("GET", "/federation/key", http.NoBody))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestServer_basicAuthMiddlewareFederation(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	hash, err := rest.GenerateBcryptHash("secret")
+	require.NoError(t, err)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		key    ed25519.PrivateKey
+		method string
+		path   string
+		want   int
+	}{
+		{name: "feed is public", key: priv, method: "GET", path: "/federation/feed", want: http.StatusOK},
+		{name: "key is public", key: priv, method: "GET", path: "/federation/key", want: http.StatusOK},
+		{name: "other paths need auth", key: priv, method: "GET", path: "/reviews", want: http.StatusUnauthorized},
+		{name: "other methods need auth", key: priv, method: "POST", path: "/federation/feed", want: http.StatusUnauthorized},
+		{name: "feed needs auth if federation disabled", method: "GET", path: "/federation/feed", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &Server{Config: Config{AuthHash: hash, FederationKey: tt.key}}
+			w := httptest.NewRecorder()
+			srv.basicAuthMiddleware(next).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"github.com/umputun/tg-spam/app/storage"
 	"sync"
+	"time"
 )
 
 // DetectedSpamMock is a mock implementation of webapi.DetectedSpam.
@@ -21,6 +22,9 @@ import (
 //			ReadFunc: func(ctx context.Context) ([]storage.DetectedSpamInfo, error) {
 //				panic("mock out the Read method")
 //			},
+//			ReadSinceFunc: func(ctx context.Context, since time.Time, limit int) ([]storage.DetectedSpamInfo, error) {
+//				panic("mock out the ReadSince method")
+//			},
 //			SetAddedToSamplesFlagFunc: func(ctx context.Context, id int64) error {
 //				panic("mock out the SetAddedToSamplesFlag method")
 //			},
@@ -37,6 +41,9 @@ type DetectedSpamMock struct {
 	// ReadFunc mocks the Read method.
 	ReadFunc func(ctx context.Context) ([]storage.DetectedSpamInfo, error)
 
+	// ReadSinceFunc mocks the ReadSince method.
+	ReadSinceFunc func(ctx context.Context, since time.Time, limit int) ([]storage.DetectedSpamInfo, error)
+
 	// SetAddedToSamplesFlagFunc mocks the SetAddedToSamplesFlag method.
 	SetAddedToSamplesFlagFunc func(ctx context.Context, id int64) error
 
@@ -54,6 +61,15 @@ type DetectedSpamMock struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// ReadSince holds details about calls to the ReadSince method.
+		ReadSince []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Since is the since argument value.
+			Since time.Time
+			// Limit is the limit argument value.
+			Limit int
+		}
 		// SetAddedToSamplesFlag holds details about calls to the SetAddedToSamplesFlag method.
 		SetAddedToSamplesFlag []struct {
 			// Ctx is the ctx argument value.
@@ -64,6 +80,7 @@ type DetectedSpamMock struct {
 	}
 	lockFindByUserID          sync.RWMutex
 	lockRead                  sync.RWMutex
+	lockReadSince             sync.RWMutex
 	lockSetAddedToSamplesFlag sync.RWMutex
 }
 
@@ -149,6 +166,53 @@ func (mock *DetectedSpamMock) ResetReadCalls() {
 	mock.lockRead.Unlock()
 }
 
+// ReadSince calls ReadSinceFunc.
+func (mock *DetectedSpamMock) ReadSince(ctx context.Context, since time.Time, limit int) ([]storage.DetectedSpamInfo, error) {
+	if mock.ReadSinceFunc == nil {
+		panic("DetectedSpamMock.ReadSinceFunc: method is nil but DetectedSpam.ReadSince was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Since time.Time
+		Limit int
+	}{
+		Ctx:   ctx,
+		Since: since,
+		Limit: limit,
+	}
+	mock.lockReadSince.Lock()
+	mock.calls.ReadSince = append(mock.calls.ReadSince, callInfo)
+	mock.lockReadSince.Unlock()
+	return mock.ReadSinceFunc(ctx, since, limit)
+}
+
+// ReadSinceCalls gets all the calls that were made to ReadSince.
+// Check the length with:
+//
+//	len(mockedDetectedSpam.ReadSinceCalls())
+func (mock *DetectedSpamMock) ReadSinceCalls() []struct {
+	Ctx   context.Context
+	Since time.Time
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Since time.Time
+		Limit int
+	}
+	mock.lockReadSince.RLock()
+	calls = mock.calls.ReadSince
+	mock.lockReadSince.RUnlock()
+	return calls
+}
+
+// ResetReadSinceCalls reset all the calls that were made to ReadSince.
+func (mock *DetectedSpamMock) ResetReadSinceCalls() {
+	mock.lockReadSince.Lock()
+	mock.calls.ReadSince = nil
+	mock.lockReadSince.Unlock()
+}
+
 // SetAddedToSamplesFlag calls SetAddedToSamplesFlagFunc.
 func (mock *DetectedSpamMock) SetAddedToSamplesFlag(ctx context.Context, id int64) error {
 	if mock.SetAddedToSamplesFlagFunc == nil {
@@ -202,6 +266,10 @@ func (mock *DetectedSpamMock) ResetCalls() {
 	mock.calls.Read = nil
 	mock.lockRead.Unlock()
 
+	mock.lockReadSince.Lock()
+	mock.calls.ReadSince = nil
+	mock.lockReadSince.Unlock()
+
 	mock.lockSetAddedToSamplesFlag.Lock()
 	mock.calls.SetAddedToSamplesFlag = nil
 	mock.lockSetAddedToSamplesFlag.Unlock()
@@ -28,6 +28,12 @@ import (
 //			LoadFunc: func(ctx context.Context) (*config.Settings, error) {
 //				panic("mock out the Load method")
 //			},
+//			LoadRevisionFunc: func(ctx context.Context, id int64) (*config.Settings, error) {
+//				panic("mock out the LoadRevision method")
+//			},
+//			RevisionsFunc: func(ctx context.Context, limit int) ([]config.Revision, error) {
+//				panic("mock out the Revisions method")
+//			},
 //			SaveFunc: func(ctx context.Context, settings *config.Settings) error {
 //				panic("mock out the Save method")
 //			},
@@ -50,6 +56,12 @@ type SettingsStoreMock struct {
 	// LoadFunc mocks the Load method.
 	LoadFunc func(ctx context.Context) (*config.Settings, error)
 
+	// LoadRevisionFunc mocks the LoadRevision method.
+	LoadRevisionFunc func(ctx context.Context, id int64) (*config.Settings, error)
+
+	// RevisionsFunc mocks the Revisions method.
+	RevisionsFunc func(ctx context.Context, limit int) ([]config.Revision, error)
+
 	// SaveFunc mocks the Save method.
 	SaveFunc func(ctx context.Context, settings *config.Settings) error
 
@@ -75,6 +87,20 @@ type SettingsStoreMock struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 		}
+		// LoadRevision holds details about calls to the LoadRevision method.
+		LoadRevision []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID int64
+		}
+		// Revisions holds details about calls to the Revisions method.
+		Revisions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
 		// Save holds details about calls to the Save method.
 		Save []struct {
 			// Ctx is the ctx argument value.
@@ -83,11 +109,13 @@ type SettingsStoreMock struct {
 			Settings *config.Settings
 		}
 	}
-	lockDelete      sync.RWMutex
-	lockExists      sync.RWMutex
-	lockLastUpdated sync.RWMutex
-	lockLoad        sync.RWMutex
-	lockSave        sync.RWMutex
+	lockDelete       sync.RWMutex
+	lockExists       sync.RWMutex
+	lockLastUpdated  sync.RWMutex
+	lockLoad         sync.RWMutex
+	lockLoadRevision sync.RWMutex
+	lockRevisions    sync.RWMutex
+	lockSave         sync.RWMutex
 }
 
 // Delete calls DeleteFunc.
@@ -246,6 +274,92 @@ func (mock *SettingsStoreMock) ResetLoadCalls() {
 	mock.lockLoad.Unlock()
 }
 
+// LoadRevision calls LoadRevisionFunc.
+func (mock *SettingsStoreMock) LoadRevision(ctx context.Context, id int64) (*config.Settings, error) {
+	if mock.LoadRevisionFunc == nil {
+		panic("SettingsStoreMock.LoadRevisionFunc: method is nil but SettingsStore.LoadRevision was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  int64
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockLoadRevision.Lock()
+	mock.calls.LoadRevision = append(mock.calls.LoadRevision, callInfo)
+	mock.lockLoadRevision.Unlock()
+	return mock.LoadRevisionFunc(ctx, id)
+}
+
+// LoadRevisionCalls gets all the calls that were made to LoadRevision.
+// Check the length with:
+//
+//	len(mockedSettingsStore.LoadRevisionCalls())
+func (mock *SettingsStoreMock) LoadRevisionCalls() []struct {
+	Ctx context.Context
+	ID  int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  int64
+	}
+	mock.lockLoadRevision.RLock()
+	calls = mock.calls.LoadRevision
+	mock.lockLoadRevision.RUnlock()
+	return calls
+}
+
+// ResetLoadRevisionCalls reset all the calls that were made to LoadRevision.
+func (mock *SettingsStoreMock) ResetLoadRevisionCalls() {
+	mock.lockLoadRevision.Lock()
+	mock.calls.LoadRevision = nil
+	mock.lockLoadRevision.Unlock()
+}
+
+// Revisions calls RevisionsFunc.
+func (mock *SettingsStoreMock) Revisions(ctx context.Context, limit int) ([]config.Revision, error) {
+	if mock.RevisionsFunc == nil {
+		panic("SettingsStoreMock.RevisionsFunc: method is nil but SettingsStore.Revisions was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockRevisions.Lock()
+	mock.calls.Revisions = append(mock.calls.Revisions, callInfo)
+	mock.lockRevisions.Unlock()
+	return mock.RevisionsFunc(ctx, limit)
+}
+
+// RevisionsCalls gets all the calls that were made to Revisions.
+// Check the length with:
+//
+//	len(mockedSettingsStore.RevisionsCalls())
+func (mock *SettingsStoreMock) RevisionsCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockRevisions.RLock()
+	calls = mock.calls.Revisions
+	mock.lockRevisions.RUnlock()
+	return calls
+}
+
+// ResetRevisionsCalls reset all the calls that were made to Revisions.
+func (mock *SettingsStoreMock) ResetRevisionsCalls() {
+	mock.lockRevisions.Lock()
+	mock.calls.Revisions = nil
+	mock.lockRevisions.Unlock()
+}
+
 // Save calls SaveFunc.
 func (mock *SettingsStoreMock) Save(ctx context.Context, settings *config.Settings) error {
 	if mock.SaveFunc == nil {
@@ -307,6 +421,14 @@ func (mock *SettingsStoreMock) ResetCalls() {
 	mock.calls.Load = nil
 	mock.lockLoad.Unlock()
 
+	mock.lockLoadRevision.Lock()
+	mock.calls.LoadRevision = nil
+	mock.lockLoadRevision.Unlock()
+
+	mock.lockRevisions.Lock()
+	mock.calls.Revisions = nil
+	mock.lockRevisions.Unlock()
+
 	mock.lockSave.Lock()
 	mock.calls.Save = nil
 	mock.lockSave.Unlock()
@@ -0,0 +1,362 @@
+package webapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/didip/tollbooth/v8"
+	"github.com/didip/tollbooth/v8/limiter"
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest/realip"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+// session and csrf cookies of users logged in with the login form
+const (
+	sessionCookie = "tg-spam-session"
+	csrfCookie    = "tg-spam-csrf" // readable by scripts, htmx sends it back in csrfHeader
+	csrfHeader    = "X-CSRF-Token"
+	sessionTTL    = 24 * time.Hour
+)
+
+// login attempts allowed per client ip and per user name, after the burst one attempt per loginAttemptsEvery
+const (
+	loginAttemptsBurst = 5
+	loginAttemptsEvery = 12 * time.Second
+)
+
+// routeRoles maps routes to the least privileged role allowed to call them. Routes not listed here,
+// e.g. settings, backups, api tokens and web users management, require the admin role.
+var routeRoles = map[string]storage.WebRole{
+	"GET /{$}":                    storage.WebRoleViewer,
+	"GET /detected_spam":          storage.WebRoleViewer,
+	"GET /review_queue":           storage.WebRoleViewer,
+	"GET /user_profile":           storage.WebRoleViewer,
+	"GET /manage_samples":         storage.WebRoleViewer,
+	"GET /manage_users":           storage.WebRoleViewer,
+	"GET /manage_dictionary":      storage.WebRoleViewer,
+	"GET /manage_images":          storage.WebRoleViewer,
+	"POST /check":                 storage.WebRoleViewer, // check-only for viewers, see checkMsgHandler
+	"GET /check/{user_id}":        storage.WebRoleViewer,
+	"GET /reputation/{user_id}":   storage.WebRoleViewer,
+	"GET /reviews":                storage.WebRoleViewer,
+	"GET /samples":                storage.WebRoleViewer,
+	"GET /users/{$}":              storage.WebRoleViewer,
+	"GET /dictionary/{$}":         storage.WebRoleViewer,
+	"GET /image_hashes/{$}":       storage.WebRoleViewer,
+	"GET /download/spam":          storage.WebRoleViewer,
+	"GET /download/ham":           storage.WebRoleViewer,
+	"GET /download/detected_spam": storage.WebRoleViewer,
+
+	"POST /update/spam":         storage.WebRoleModerator,
+	"POST /update/ham":          storage.WebRoleModerator,
+	"POST /delete/spam":         storage.WebRoleModerator,
+	"POST /delete/ham":          storage.WebRoleModerator,
+	"PUT /samples":              storage.WebRoleModerator,
+	"POST /users/add":           storage.WebRoleModerator,
+	"POST /users/delete":        storage.WebRoleModerator,
+	"POST /dictionary/add":      storage.WebRoleModerator,
+	"POST /dictionary/delete":   storage.WebRoleModerator,
+	"POST /image_hashes/add":    storage.WebRoleModerator,
+	"POST /image_hashes/delete": storage.WebRoleModerator,
+	"POST /detected_spam/add":   storage.WebRoleModerator,
+	"GET /audit_log":            storage.WebRoleModerator,
+	"GET /audit":                storage.WebRoleModerator,
+	"GET /download/audit":       storage.WebRoleModerator,
+}
+
+// roleRoutes matches requests to the patterns of routeRoles, the same way the router does
+var roleRoutes = func() *http.ServeMux {
+	res := http.NewServeMux()
+	for pattern := range routeRoles {
+		res.Handle(pattern, http.NotFoundHandler())
+	}
+	return res
+}()
+
+// webActor is the user the request is made by, set in the request context by the auth middleware
+type webActor struct {
+	name string
+	role storage.WebRole
+}
+
+// webActorCtxKey is the request context key of webActor
+type webActorCtxKey struct{}
+
+// session of the user logged in with the login form
+type session struct {
+	webActor
+	csrf    string
+	expires time.Time
+}
+
+// sessionStore keeps sessions in memory, users log in again after restart. Zero value is ready to use.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]session // by session id
+}
+
+// create makes a new session of the user and returns its id
+func (ss *sessionStore) create(actor webActor) (id string, sess session, err error) {
+	if id, err = randomHex(32); err != nil {
+		return "", session{}, err
+	}
+	csrf, err := randomHex(32)
+	if err != nil {
+		return "", session{}, err
+	}
+	sess = session{webActor: actor, csrf: csrf, expires: time.Now().Add(sessionTTL)}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.sessions == nil {
+		ss.sessions = map[string]session{}
+	}
+	for k, v := range ss.sessions { // cleanup expired, there are not many sessions
+		if time.Now().After(v.expires) {
+			delete(ss.sessions, k)
+		}
+	}
+	ss.sessions[id] = sess
+	return id, sess, nil
+}
+
+// get returns the session by id if it exists and not expired
+func (ss *sessionStore) get(id string) (session, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	sess, ok := ss.sessions[id]
+	if !ok || time.Now().After(sess.expires) {
+		return session{}, false
+	}
+	return sess, true
+}
+
+// remove deletes the session by id
+func (ss *sessionStore) remove(id string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.sessions, id)
+}
+
+// removeUser deletes all sessions of the user, e.g. after the user is deleted or the role is changed
+func (ss *sessionStore) removeUser(name string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for k, v := range ss.sessions {
+		if v.name == name {
+			delete(ss.sessions, k)
+		}
+	}
+}
+
+// loginLimiter limits login attempts per client ip and per user name, to slow down password guessing
+// from one address as well as distributed guessing of one user's password. Zero value is ready to use.
+type loginLimiter struct {
+	once sync.Once
+	lmt  *limiter.Limiter
+}
+
+// allow consumes an attempt of the ip and the user name, returns false if either of them is out of attempts
+func (ll *loginLimiter) allow(ip, name string) bool {
+	ll.once.Do(func() {
+		ll.lmt = tollbooth.NewLimiter(1/loginAttemptsEvery.Seconds(), &limiter.ExpirableOptions{DefaultExpirationTTL: time.Hour}).
+			SetBurst(loginAttemptsBurst)
+	})
+	if tollbooth.LimitByKeys(ll.lmt, []string{"ip", ip}) != nil {
+		return false
+	}
+	return tollbooth.LimitByKeys(ll.lmt, []string{"user", strings.ToLower(name)}) == nil
+}
+
+// isLoginPath reports whether the request is for the login form, logout or static files used by the login page,
+// available without credentials if web users are enabled
+func (s *Server) isLoginPath(r *http.Request) bool {
+	if s.WebUsers == nil {
+		return false
+	}
+	switch r.URL.Path {
+	case "/login":
+		return r.Method == http.MethodGet || r.Method == http.MethodPost
+	case "/logout", "/styles.css", "/logo.png", "/spinner.svg":
+		return r.Method == http.MethodGet
+	}
+	return false
+}
+
+// requestSession returns the session of the request cookie
+func (s *Server) requestSession(r *http.Request) (id string, sess session, ok bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil || c.Value == "" {
+		return "", session{}, false
+	}
+	sess, ok = s.sessions.get(c.Value)
+	return c.Value, sess, ok
+}
+
+// serveWithSession passes the request of the logged-in user to the handler if the user role allows the route.
+// Requests changing data must have the csrf token of the session in the X-CSRF-Token header.
+func (s *Server) serveWithSession(w http.ResponseWriter, r *http.Request, sess session, h http.Handler) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeader)), []byte(sess.csrf)) != 1 {
+			log.Printf("[WARN] invalid csrf token from %q for %s %s", sess.name, r.Method, r.URL.Path)
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+	}
+
+	_, pattern := roleRoutes.Handler(r)
+	required, ok := routeRoles[pattern]
+	if !ok {
+		required = storage.WebRoleAdmin
+	}
+	if !sess.role.Allows(required) {
+		log.Printf("[INFO] %s %q not allowed to %s %s", sess.role, sess.name, r.Method, r.URL.Path)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webActorCtxKey{}, sess.webActor)))
+}
+
+// redirectToLogin sends browsers without credentials to the login form. It returns false for other clients,
+// they get the basic auth prompt as before.
+func (s *Server) redirectToLogin(w http.ResponseWriter, r *http.Request) bool {
+	if s.WebUsers == nil {
+		return false
+	}
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/login") // session expired, htmx reloads the whole page
+		w.WriteHeader(http.StatusUnauthorized)
+		return true
+	}
+	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return true
+	}
+	return false
+}
+
+// htmlLoginHandler handles GET /login request. It shows the login form.
+func (s *Server) htmlLoginHandler(w http.ResponseWriter, _ *http.Request) {
+	s.renderLogin(w, http.StatusOK, "", "")
+}
+
+// loginHandler handles POST /login request. It checks the user name and password and starts the session.
+// Users of the web users storage are checked first, the basic auth user logs in with the admin role.
+func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
+	name, passwd := strings.TrimSpace(r.FormValue("username")), r.FormValue("password")
+	ip, err := realip.Get(r)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !s.loginLimits.allow(ip, name) {
+		log.Printf("[WARN] too many login attempts of %q from %s", name, ip)
+		s.renderLogin(w, http.StatusTooManyRequests, name, "Too many login attempts, try again later")
+		return
+	}
+
+	actor, ok := s.authenticateUser(r.Context(), name, passwd)
+	if !ok {
+		log.Printf("[WARN] failed login of %q from %s", name, ip)
+		s.renderLogin(w, http.StatusUnauthorized, name, "Invalid user name or password")
+		return
+	}
+
+	id, sess, err := s.sessions.create(actor)
+	if err != nil {
+		log.Printf("[WARN] can't create session: %v", err)
+		s.renderLogin(w, http.StatusInternalServerError, name, "Can't create session")
+		return
+	}
+	secure := r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: id, Path: "/", Expires: sess.expires,
+		HttpOnly: true, Secure: secure, SameSite: http.SameSiteLaxMode})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: sess.csrf, Path: "/", Expires: sess.expires,
+		Secure: secure, SameSite: http.SameSiteStrictMode})
+	log.Printf("[INFO] %s %q logged in", actor.role, actor.name)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// logoutHandler handles GET /logout request. Logged-in users are logged out and sent to the login form,
+// basic auth users get the auth prompt, so the browser forgets the credentials.
+func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := r.BasicAuth(); ok || s.WebUsers == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="tg-spam"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, "Logged out successfully")
+		return
+	}
+	if id, _, ok := s.requestSession(r); ok {
+		s.sessions.remove(id)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Value: "", Path: "/", MaxAge: -1})
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", "/login")
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// authenticateUser checks the password of the web user or the basic auth user and returns the actor
+func (s *Server) authenticateUser(ctx context.Context, name, passwd string) (webActor, bool) {
+	if name == "" || passwd == "" {
+		return webActor{}, false
+	}
+	if s.WebUsers != nil {
+		if u, err := s.WebUsers.Get(ctx, name); err == nil {
+			if bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(passwd)) != nil {
+				return webActor{}, false
+			}
+			if err := s.WebUsers.MarkLogin(ctx, u.ID, time.Now()); err != nil {
+				log.Printf("[WARN] failed to update last login of %q: %v", name, err)
+			}
+			return webActor{name: u.Name, role: u.Role}, true
+		}
+	}
+	if s.checkBasicAuth(name, passwd) {
+		return webActor{name: name, role: storage.WebRoleAdmin}, true
+	}
+	return webActor{}, false
+}
+
+// renderLogin renders the login form with optional error
+func (s *Server) renderLogin(w http.ResponseWriter, code int, name, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	tmplData := struct {
+		Name  string
+		Error string
+	}{Name: name, Error: errMsg}
+	if err := tmpl.ExecuteTemplate(w, "login.html", tmplData); err != nil {
+		log.Printf("[WARN] can't execute template: %v", err)
+	}
+}
+
+// requestRole returns the role of the user the request is made by. Requests with basic auth and without auth,
+// if auth is disabled, are made by admin.
+func requestRole(r *http.Request) storage.WebRole {
+	if a, ok := r.Context().Value(webActorCtxKey{}).(webActor); ok {
+		return a.role
+	}
+	return storage.WebRoleAdmin
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,352 @@
+package webapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-pkgz/rest"
+	"github.com/go-pkgz/routegroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/webapi/mocks"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestServer_basicAuthMiddlewareSession(t *testing.T) {
+	hash, err := rest.GenerateBcryptHash("secret")
+	require.NoError(t, err)
+	srv := &Server{Config: Config{AuthHash: hash, WebUsers: &mocks.WebUsersMock{}}}
+	sessions := map[storage.WebRole]session{}
+	ids := map[storage.WebRole]string{}
+	for _, role := range storage.WebRoles {
+		ids[role], sessions[role], err = srv.sessions.create(webActor{name: "user-" + string(role), role: role})
+		require.NoError(t, err)
+	}
+	var actor string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = requestActor(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name, method, path string
+		role               storage.WebRole
+		noCSRF             bool
+		want               int
+	}{
+		{name: "viewer home", method: "GET", path: "/", role: storage.WebRoleViewer, want: http.StatusOK},
+		{name: "viewer detected spam", method: "GET", path: "/detected_spam", role: storage.WebRoleViewer, want: http.StatusOK},
+		{name: "viewer check", method: "POST", path: "/check", role: storage.WebRoleViewer, want: http.StatusOK},
+		{name: "viewer update spam", method: "POST", path: "/update/spam", role: storage.WebRoleViewer, want: http.StatusForbidden},
+		{name: "viewer audit log", method: "GET", path: "/audit_log", role: storage.WebRoleViewer, want: http.StatusForbidden},
+		{name: "moderator update spam", method: "POST", path: "/update/spam", role: storage.WebRoleModerator, want: http.StatusOK},
+		{name: "moderator add user", method: "POST", path: "/users/add", role: storage.WebRoleModerator, want: http.StatusOK},
+		{name: "moderator settings", method: "GET", path: "/settings", role: storage.WebRoleModerator, want: http.StatusForbidden},
+		{name: "moderator backup", method: "GET", path: "/download/backup", role: storage.WebRoleModerator,
+			want: http.StatusForbidden},
+		{name: "moderator export", method: "GET", path: "/download/export-to-postgres", role: storage.WebRoleModerator,
+			want: http.StatusForbidden},
+		{name: "moderator web users", method: "GET", path: "/web_users", role: storage.WebRoleModerator, want: http.StatusForbidden},
+		{name: "admin settings", method: "GET", path: "/settings", role: storage.WebRoleAdmin, want: http.StatusOK},
+		{name: "admin export", method: "GET", path: "/download/export-to-postgres", role: storage.WebRoleAdmin, want: http.StatusOK},
+		{name: "admin update config", method: "PUT", path: "/config", role: storage.WebRoleAdmin, want: http.StatusOK},
+		{name: "admin without csrf", method: "PUT", path: "/config", role: storage.WebRoleAdmin, noCSRF: true,
+			want: http.StatusForbidden},
+		{name: "get without csrf", method: "GET", path: "/settings", role: storage.WebRoleAdmin, noCSRF: true, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor = ""
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: ids[tt.role]})
+			if !tt.noCSRF {
+				req.Header.Set(csrfHeader, sessions[tt.role].csrf)
+			}
+			w := httptest.NewRecorder()
+			srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusOK {
+				assert.Equal(t, "user-"+string(tt.role), actor)
+			} else {
+				assert.Empty(t, actor)
+			}
+		})
+	}
+
+	t.Run("csrf of another session", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/update/spam", http.NoBody)
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: ids[storage.WebRoleModerator]})
+		req.Header.Set(csrfHeader, sessions[storage.WebRoleAdmin].csrf)
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Invalid CSRF token")
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/settings", http.NoBody)
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: "bad"})
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("basic auth user is admin", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/settings", http.NoBody)
+		req.SetBasicAuth("tg-spam", "secret")
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "tg-spam", actor)
+	})
+
+	t.Run("login form without credentials", func(t *testing.T) {
+		for _, path := range []string{"/login", "/styles.css"} {
+			w := httptest.NewRecorder()
+			srv.basicAuthMiddleware(next).ServeHTTP(w, httptest.NewRequest("GET", path, http.NoBody))
+			assert.Equal(t, http.StatusOK, w.Code, path)
+		}
+	})
+}
+
+func TestServer_redirectToLogin(t *testing.T) {
+	hash, err := rest.GenerateBcryptHash("secret")
+	require.NoError(t, err)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	t.Run("browser", func(t *testing.T) {
+		srv := &Server{Config: Config{AuthHash: hash, WebUsers: &mocks.WebUsersMock{}}}
+		req := httptest.NewRequest("GET", "/detected_spam", http.NoBody)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "/login", w.Header().Get("Location"))
+	})
+
+	t.Run("htmx", func(t *testing.T) {
+		srv := &Server{Config: Config{AuthHash: hash, WebUsers: &mocks.WebUsersMock{}}}
+		req := httptest.NewRequest("POST", "/update/spam", http.NoBody)
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "/login", w.Header().Get("HX-Redirect"))
+	})
+
+	t.Run("api client", func(t *testing.T) {
+		srv := &Server{Config: Config{AuthHash: hash, WebUsers: &mocks.WebUsersMock{}}}
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, httptest.NewRequest("POST", "/check", http.NoBody))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+	})
+
+	t.Run("web users disabled", func(t *testing.T) {
+		srv := &Server{Config: Config{AuthHash: hash}}
+		req := httptest.NewRequest("GET", "/detected_spam", http.NoBody)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+		w = httptest.NewRecorder()
+		srv.basicAuthMiddleware(next).ServeHTTP(w, httptest.NewRequest("GET", "/login", http.NoBody))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "login form is not public")
+	})
+}
+
+func TestServer_loginHandler(t *testing.T) {
+	authHash, err := rest.GenerateBcryptHash("secret")
+	require.NoError(t, err)
+	userHash, err := rest.GenerateBcryptHash("bob-password")
+	require.NoError(t, err)
+	users := &mocks.WebUsersMock{
+		GetFunc: func(ctx context.Context, name string) (*storage.WebUser, error) {
+			if name == "bob" {
+				return &storage.WebUser{ID: 7, Name: "bob", Hash: userHash, Role: storage.WebRoleModerator}, nil
+			}
+			return nil, errors.New("web user not found")
+		},
+		MarkLoginFunc: func(ctx context.Context, id int64, ts time.Time) error { return nil },
+	}
+	srv := NewServer(Config{AuthHash: authHash, WebUsers: users})
+
+	attempts := 0
+	loginFrom := func(ip, name, passwd string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {name}, "password": {passwd}}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		srv.loginHandler(w, req)
+		return w
+	}
+	login := func(name, passwd string) *httptest.ResponseRecorder {
+		attempts++ // each attempt from its own ip, not limited by the per-ip limit
+		return loginFrom(fmt.Sprintf("192.0.2.%d", attempts), name, passwd)
+	}
+
+	t.Run("web user", func(t *testing.T) {
+		w := login("bob", "bob-password")
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "/", w.Header().Get("Location"))
+		cookies := map[string]*http.Cookie{}
+		for _, c := range w.Result().Cookies() {
+			cookies[c.Name] = c
+		}
+		require.Contains(t, cookies, sessionCookie)
+		require.Contains(t, cookies, csrfCookie)
+		assert.True(t, cookies[sessionCookie].HttpOnly)
+		assert.False(t, cookies[csrfCookie].HttpOnly, "csrf token is read by htmx")
+
+		sess, ok := srv.sessions.get(cookies[sessionCookie].Value)
+		require.True(t, ok)
+		assert.Equal(t, webActor{name: "bob", role: storage.WebRoleModerator}, sess.webActor)
+		assert.Equal(t, cookies[csrfCookie].Value, sess.csrf)
+		require.Len(t, users.MarkLoginCalls(), 1)
+		assert.Equal(t, int64(7), users.MarkLoginCalls()[0].ID)
+	})
+
+	t.Run("basic auth user", func(t *testing.T) {
+		w := login("tg-spam", "secret")
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		sess, ok := srv.sessions.get(w.Result().Cookies()[0].Value)
+		require.True(t, ok)
+		assert.Equal(t, webActor{name: "tg-spam", role: storage.WebRoleAdmin}, sess.webActor)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		for _, creds := range [][2]string{{"bob", "secret"}, {"tg-spam", "bob-password"}, {"alice", "secret"}, {"bob", ""}} {
+			w := login(creds[0], creds[1])
+			assert.Equal(t, http.StatusUnauthorized, w.Code, creds)
+			assert.Empty(t, w.Result().Cookies())
+			assert.Contains(t, w.Body.String(), "Invalid user name or password")
+		}
+	})
+
+	t.Run("limited per user name", func(t *testing.T) {
+		for i := 0; i < loginAttemptsBurst; i++ {
+			w := loginFrom(fmt.Sprintf("198.51.100.%d", i), "carol", "secret")
+			assert.Equal(t, http.StatusUnauthorized, w.Code, i)
+		}
+		w := loginFrom("198.51.100.100", "Carol", "secret")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Empty(t, w.Result().Cookies())
+		assert.Contains(t, w.Body.String(), "Too many login attempts")
+		assert.Equal(t, http.StatusSeeOther, loginFrom("198.51.100.101", "bob", "bob-password").Code, "other users not limited")
+	})
+
+	t.Run("limited per ip", func(t *testing.T) {
+		for i := 0; i < loginAttemptsBurst; i++ {
+			w := loginFrom("203.0.113.1", fmt.Sprintf("user%d", i), "secret")
+			assert.Equal(t, http.StatusUnauthorized, w.Code, i)
+		}
+		w := loginFrom("203.0.113.1", "tg-spam", "secret")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, "limited even with the right password")
+		assert.Empty(t, w.Result().Cookies())
+		assert.Equal(t, http.StatusSeeOther, loginFrom("203.0.113.2", "tg-spam", "secret").Code, "other ips not limited")
+	})
+}
+
+func TestServer_logoutHandlerSession(t *testing.T) {
+	srv := NewServer(Config{WebUsers: &mocks.WebUsersMock{}})
+	for _, htmx := range []bool{false, true} {
+		id, _, err := srv.sessions.create(webActor{name: "bob", role: storage.WebRoleViewer})
+		require.NoError(t, err)
+		req := httptest.NewRequest("GET", "/logout", http.NoBody)
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: id})
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		w := httptest.NewRecorder()
+		srv.logoutHandler(w, req)
+		if htmx {
+			assert.Equal(t, "/login", w.Header().Get("HX-Redirect"))
+		} else {
+			assert.Equal(t, http.StatusSeeOther, w.Code)
+			assert.Equal(t, "/login", w.Header().Get("Location"))
+		}
+		_, ok := srv.sessions.get(id)
+		assert.False(t, ok, "session removed")
+		for _, c := range w.Result().Cookies() {
+			assert.Negative(t, c.MaxAge, c.Name)
+		}
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	var ss sessionStore
+	id1, sess, err := ss.create(webActor{name: "bob", role: storage.WebRoleViewer})
+	require.NoError(t, err)
+	assert.Len(t, id1, 64)
+	assert.Len(t, sess.csrf, 64)
+	id2, _, err := ss.create(webActor{name: "bob", role: storage.WebRoleViewer})
+	require.NoError(t, err)
+	id3, _, err := ss.create(webActor{name: "alice", role: storage.WebRoleAdmin})
+	require.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+
+	got, ok := ss.get(id1)
+	require.True(t, ok)
+	assert.Equal(t, sess, got)
+
+	ss.removeUser("bob")
+	_, ok = ss.get(id1)
+	assert.False(t, ok)
+	_, ok = ss.get(id2)
+	assert.False(t, ok)
+	_, ok = ss.get(id3)
+	assert.True(t, ok)
+
+	ss.sessions[id3] = session{webActor: webActor{name: "alice"}, expires: time.Now().Add(-time.Second)}
+	_, ok = ss.get(id3)
+	assert.False(t, ok, "expired")
+	_, _, err = ss.create(webActor{name: "carol", role: storage.WebRoleViewer})
+	require.NoError(t, err)
+	assert.Len(t, ss.sessions, 1, "expired session cleaned")
+}
+
+func TestServer_checkMsgHandlerViewer(t *testing.T) {
+	detector := &mocks.DetectorMock{
+		CheckFunc: func(req spamcheck.Request) (bool, []spamcheck.Response) { return false, nil },
+	}
+	srv := NewServer(Config{Detector: detector})
+	for _, role := range storage.WebRoles {
+		req := httptest.NewRequest("POST", "/check", strings.NewReader(`{"msg":"hello","user_id":"123","check_only":false}`))
+		req = req.WithContext(context.WithValue(req.Context(), webActorCtxKey{}, webActor{name: "bob", role: role}))
+		w := httptest.NewRecorder()
+		srv.checkMsgHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	require.Len(t, detector.CheckCalls(), 3)
+	assert.True(t, detector.CheckCalls()[0].Req.CheckOnly, "viewer can only check")
+	assert.False(t, detector.CheckCalls()[1].Req.CheckOnly, "moderator")
+	assert.False(t, detector.CheckCalls()[2].Req.CheckOnly, "admin")
+}
+
+func TestRouteRoles(t *testing.T) {
+	// all patterns match routes of the server, so the role map can't silently drift from the router
+	srv := NewServer(Config{SpamFilter: &mocks.SpamFilterMock{}, Detector: &mocks.DetectorMock{}, AuditLog: &mocks.AuditLogMock{},
+		SettingsStore: &mocks.SettingsStoreMock{}, ConfigDBMode: true, WebUsers: &mocks.WebUsersMock{}})
+	mux := http.NewServeMux()
+	srv.routes(routegroup.New(mux))
+	for pattern := range routeRoles {
+		method, path, _ := strings.Cut(pattern, " ")
+		path = strings.NewReplacer("{user_id}", "123", "{$}", "").Replace(path)
+		_, got := roleRoutes.Handler(httptest.NewRequest(method, path, http.NoBody))
+		assert.Equal(t, pattern, got)
+		_, got = mux.Handler(httptest.NewRequest(method, path, http.NoBody))
+		assert.Equal(t, pattern, got, "route of the server")
+	}
+}
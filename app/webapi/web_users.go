@@ -0,0 +1,246 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+	"github.com/go-pkgz/rest"
+
+	"github.com/umputun/tg-spam/app/storage"
+)
+
+//go:generate moq --out mocks/web_users.go --pkg mocks --with-resets --skip-ensure . WebUsers
+
+// WebUsers is a storage interface for users of the web UI
+type WebUsers interface {
+	Add(ctx context.Context, name, hash string, role storage.WebRole) error
+	Get(ctx context.Context, name string) (*storage.WebUser, error)
+	List(ctx context.Context) ([]storage.WebUser, error)
+	Delete(ctx context.Context, id int64) (name string, err error)
+	SetRole(ctx context.Context, id int64, role storage.WebRole) (name string, err error)
+	SetPassword(ctx context.Context, id int64, hash string) (name string, err error)
+	MarkLogin(ctx context.Context, id int64, ts time.Time) error
+}
+
+// minWebUserPasswordLen is the minimal length of the password set by admin, empty password is generated
+const minWebUserPasswordLen = 8
+
+// webUserRequest is the request of web users management handlers, from the HTMX form or JSON body
+type webUserRequest struct {
+	ID       int64           `json:"id"`
+	Name     string          `json:"name"`
+	Password string          `json:"password"`
+	Role     storage.WebRole `json:"role"`
+}
+
+// htmlWebUsersHandler handles GET /web_users request. It shows the web users with the form to add a new one.
+func (s *Server) htmlWebUsersHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderWebUsers(w, r, "web_users.html", "", "")
+}
+
+// addWebUserHandler handles POST /web_users/add request. It adds a new web user with the role. If the password
+// is not set, a random password is generated and returned, this is the only time it is shown.
+func (s *Server) addWebUserHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeWebUserRequest(w, r)
+	if !ok {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	err := func() error {
+		if strings.EqualFold(req.Name, s.activeAuthUser()) {
+			return fmt.Errorf("name %q is used by the basic auth user", req.Name)
+		}
+		if req.Password != "" && len(req.Password) < minWebUserPasswordLen {
+			return fmt.Errorf("password should be at least %d characters", minWebUserPasswordLen)
+		}
+		passwd, hash, err := s.webUserPassword(req.Password)
+		if err != nil {
+			return err
+		}
+		if err := s.WebUsers.Add(r.Context(), req.Name, hash, req.Role); err != nil {
+			return err
+		}
+		if req.Password == "" {
+			req.Password = passwd // generated, shown once
+		} else {
+			req.Password = ""
+		}
+		return nil
+	}()
+	if err != nil {
+		s.webUserError(w, r, http.StatusBadRequest, "can't add web user", err)
+		return
+	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditUserCreate, Text: req.Name, Reason: string(req.Role)})
+
+	if r.Header.Get("HX-Request") == "true" {
+		s.renderWebUsers(w, r, "web_users_list", req.Name, req.Password)
+		return
+	}
+	resp := rest.JSON{"added": true, "name": req.Name, "role": req.Role}
+	if req.Password != "" {
+		resp["password"] = req.Password
+	}
+	rest.RenderJSON(w, resp)
+}
+
+// setWebUserRoleHandler handles POST /web_users/role request. It changes the role of the web user,
+// sessions of the user are ended, so the new role applies right away.
+func (s *Server) setWebUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeWebUserRequest(w, r)
+	if !ok {
+		return
+	}
+	name, err := s.WebUsers.SetRole(r.Context(), req.ID, req.Role)
+	if err != nil {
+		s.webUserError(w, r, http.StatusBadRequest, "can't change role", err)
+		return
+	}
+	s.sessions.removeUser(name)
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditUserRole, Text: name, Reason: string(req.Role)})
+
+	if r.Header.Get("HX-Request") == "true" {
+		s.renderWebUsers(w, r, "web_users_list", "", "")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"updated": true, "name": name, "role": req.Role})
+}
+
+// resetWebUserPasswordHandler handles POST /web_users/password request. It sets a new random password
+// of the web user and returns it, sessions of the user are ended.
+func (s *Server) resetWebUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeWebUserRequest(w, r)
+	if !ok {
+		return
+	}
+	passwd, hash, err := s.webUserPassword("")
+	if err != nil {
+		s.webUserError(w, r, http.StatusInternalServerError, "can't reset password", err)
+		return
+	}
+	name, err := s.WebUsers.SetPassword(r.Context(), req.ID, hash)
+	if err != nil {
+		s.webUserError(w, r, http.StatusBadRequest, "can't reset password", err)
+		return
+	}
+	s.sessions.removeUser(name)
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditUserPassword, Text: name})
+
+	if r.Header.Get("HX-Request") == "true" {
+		s.renderWebUsers(w, r, "web_users_list", name, passwd)
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"updated": true, "name": name, "password": passwd})
+}
+
+// deleteWebUserHandler handles POST /web_users/delete request. It deletes the web user by id
+// and ends the user sessions.
+func (s *Server) deleteWebUserHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeWebUserRequest(w, r)
+	if !ok {
+		return
+	}
+	name, err := s.WebUsers.Delete(r.Context(), req.ID)
+	if err != nil {
+		s.webUserError(w, r, http.StatusBadRequest, "can't delete web user", err)
+		return
+	}
+	s.sessions.removeUser(name)
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditUserDelete, Text: name})
+
+	if r.Header.Get("HX-Request") == "true" {
+		s.renderWebUsers(w, r, "web_users_list", "", "")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"deleted": true, "id": req.ID})
+}
+
+// decodeWebUserRequest gets the request from the HTMX form or JSON body, responds with error if it can't
+func (s *Server) decodeWebUserRequest(w http.ResponseWriter, r *http.Request) (webUserRequest, bool) {
+	var req webUserRequest
+	if r.Header.Get("HX-Request") != "true" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't decode request", "details": err.Error()})
+			return req, false
+		}
+		return req, true
+	}
+
+	req.Name, req.Password, req.Role = r.FormValue("name"), r.FormValue("password"), storage.WebRole(r.FormValue("role"))
+	if v := r.FormValue("id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			s.webUserError(w, r, http.StatusBadRequest, "invalid id", err)
+			return req, false
+		}
+		req.ID = id
+	}
+	return req, true
+}
+
+// webUserPassword returns the password with its bcrypt hash, random password is generated for empty one
+func (s *Server) webUserPassword(passwd string) (password, hash string, err error) {
+	if passwd == "" {
+		if passwd, err = GenerateRandomPassword(20); err != nil {
+			return "", "", err
+		}
+	}
+	hashFn := s.HashPassword
+	if hashFn == nil {
+		hashFn = rest.GenerateBcryptHash
+	}
+	if hash, err = hashFn(passwd); err != nil {
+		return "", "", fmt.Errorf("can't make password hash: %w", err)
+	}
+	return passwd, hash, nil
+}
+
+// webUserError responds with the error, as an alert for HTMX requests or JSON for API
+func (s *Server) webUserError(w http.ResponseWriter, r *http.Request, code int, msg string, err error) {
+	log.Printf("[WARN] %s: %v", msg, err)
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Retarget", "#error-message")
+		fmt.Fprintf(w, "<div class='alert alert-danger'>%s: %s</div>", html.EscapeString(msg), html.EscapeString(err.Error()))
+		return
+	}
+	_ = rest.EncodeJSON(w, code, rest.JSON{"error": msg, "details": err.Error()})
+}
+
+// renderWebUsers renders the template with the list of web users. The generated password is shown
+// with the user name, as it can't be retrieved later.
+func (s *Server) renderWebUsers(w http.ResponseWriter, r *http.Request, tmplName, name, passwd string) {
+	users, err := s.WebUsers.List(r.Context())
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't fetch web users", "details": err.Error()})
+		return
+	}
+
+	tmplData := struct {
+		Users       []storage.WebUser
+		TotalUsers  int
+		Roles       []storage.WebRole
+		AuthUser    string // basic auth user, always admin
+		NewName     string
+		NewPassword string
+	}{
+		Users:       users,
+		TotalUsers:  len(users),
+		Roles:       storage.WebRoles,
+		AuthUser:    s.activeAuthUser(),
+		NewName:     name,
+		NewPassword: passwd,
+	}
+
+	if err := tmpl.ExecuteTemplate(w, tmplName, tmplData); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't execute template", "details": err.Error()})
+		return
+	}
+}
@@ -0,0 +1,209 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/app/storage"
+	"github.com/umputun/tg-spam/app/webapi/mocks"
+)
+
+func TestServer_addWebUserHandler(t *testing.T) {
+	users := &mocks.WebUsersMock{
+		AddFunc: func(ctx context.Context, name, hash string, role storage.WebRole) error {
+			if role == "root" {
+				return errors.New(`unknown role "root"`)
+			}
+			return nil
+		},
+		ListFunc: func(ctx context.Context) ([]storage.WebUser, error) {
+			return []storage.WebUser{{ID: 1, Name: "alice", Role: storage.WebRoleAdmin, CreatedAt: time.Now()}}, nil
+		},
+	}
+	auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+	hashPassword := func(passwd string) (string, error) { return "hash:" + passwd, nil }
+	srv := NewServer(Config{WebUsers: users, AuditLog: auditLog, HashPassword: hashPassword})
+
+	postForm := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/web_users/add", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		req.SetBasicAuth("tg-spam", "secret")
+		w := httptest.NewRecorder()
+		srv.addWebUserHandler(w, req)
+		return w
+	}
+
+	t.Run("htmx with password", func(t *testing.T) {
+		w := postForm(url.Values{"name": {"bob"}, "password": {"bob-password"}, "role": {"moderator"}})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "alice")
+		assert.NotContains(t, w.Body.String(), "bob-password", "password set by admin is not shown")
+
+		require.Len(t, users.AddCalls(), 1)
+		assert.Equal(t, "bob", users.AddCalls()[0].Name)
+		assert.Equal(t, "hash:bob-password", users.AddCalls()[0].Hash)
+		assert.Equal(t, storage.WebRoleModerator, users.AddCalls()[0].Role)
+		require.Len(t, auditLog.AddCalls(), 1)
+		assert.Equal(t, storage.AuditRecord{Source: storage.AuditSourceWeb, Actor: "tg-spam", Action: storage.AuditUserCreate,
+			Text: "bob", Reason: "moderator"}, auditLog.AddCalls()[0].Rec)
+	})
+
+	t.Run("json with generated password", func(t *testing.T) {
+		users.ResetCalls()
+		req := httptest.NewRequest("POST", "/web_users/add", strings.NewReader(`{"name":"carol","role":"viewer"}`))
+		w := httptest.NewRecorder()
+		srv.addWebUserHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Added    bool   `json:"added"`
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Added)
+		assert.Equal(t, "carol", resp.Name)
+		assert.Len(t, resp.Password, 20)
+		require.Len(t, users.AddCalls(), 1)
+		assert.Equal(t, "hash:"+resp.Password, users.AddCalls()[0].Hash)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		tests := []struct {
+			name string
+			form url.Values
+			want string
+		}{
+			{name: "basic auth user", form: url.Values{"name": {"tg-spam"}, "role": {"viewer"}}, want: "used by the basic auth user"},
+			{name: "short password", form: url.Values{"name": {"dave"}, "password": {"short"}, "role": {"viewer"}},
+				want: "at least 8 characters"},
+			{name: "unknown role", form: url.Values{"name": {"dave"}, "role": {"root"}}, want: "unknown role"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				auditLog.ResetCalls()
+				w := postForm(tt.form)
+				assert.Equal(t, "#error-message", w.Header().Get("HX-Retarget"))
+				assert.Contains(t, w.Body.String(), tt.want)
+				assert.Empty(t, auditLog.AddCalls())
+			})
+		}
+	})
+}
+
+func TestServer_updateWebUserHandlers(t *testing.T) {
+	users := &mocks.WebUsersMock{
+		SetRoleFunc: func(ctx context.Context, id int64, role storage.WebRole) (string, error) { return "bob", nil },
+		SetPasswordFunc: func(ctx context.Context, id int64, hash string) (string, error) {
+			if id != 1 {
+				return "", errors.New("web user with id 2 not found")
+			}
+			return "bob", nil
+		},
+		DeleteFunc: func(ctx context.Context, id int64) (string, error) { return "bob", nil },
+		ListFunc:   func(ctx context.Context) ([]storage.WebUser, error) { return nil, nil },
+	}
+	auditLog := &mocks.AuditLogMock{AddFunc: func(ctx context.Context, rec storage.AuditRecord) error { return nil }}
+	srv := NewServer(Config{WebUsers: users, AuditLog: auditLog,
+		HashPassword: func(passwd string) (string, error) { return "hash:" + passwd, nil }})
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		form       url.Values
+		wantAction storage.AuditAction
+		wantReason string
+	}{
+		{name: "role", handler: srv.setWebUserRoleHandler, form: url.Values{"id": {"1"}, "role": {"admin"}},
+			wantAction: storage.AuditUserRole, wantReason: "admin"},
+		{name: "password", handler: srv.resetWebUserPasswordHandler, form: url.Values{"id": {"1"}},
+			wantAction: storage.AuditUserPassword},
+		{name: "delete", handler: srv.deleteWebUserHandler, form: url.Values{"id": {"1"}}, wantAction: storage.AuditUserDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog.ResetCalls()
+			id, _, err := srv.sessions.create(webActor{name: "bob", role: storage.WebRoleModerator})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("POST", "/web_users/"+tt.name, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("HX-Request", "true")
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "No web users found")
+
+			_, ok := srv.sessions.get(id)
+			assert.False(t, ok, "sessions of the user ended")
+			require.Len(t, auditLog.AddCalls(), 1)
+			assert.Equal(t, tt.wantAction, auditLog.AddCalls()[0].Rec.Action)
+			assert.Equal(t, "bob", auditLog.AddCalls()[0].Rec.Text)
+			assert.Equal(t, tt.wantReason, auditLog.AddCalls()[0].Rec.Reason)
+		})
+	}
+
+	t.Run("password shown once", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/web_users/password", strings.NewReader(`{"id":1}`))
+		w := httptest.NewRecorder()
+		srv.resetWebUserPasswordHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Password string `json:"password"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Password, 20)
+		calls := users.SetPasswordCalls()
+		assert.Equal(t, "hash:"+resp.Password, calls[len(calls)-1].Hash)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/web_users/password", strings.NewReader(`{"id":2}`))
+		w := httptest.NewRecorder()
+		srv.resetWebUserPasswordHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "not found")
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/web_users/delete", strings.NewReader("id=abc"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.deleteWebUserHandler(w, req)
+		assert.Equal(t, "#error-message", w.Header().Get("HX-Retarget"))
+		assert.Contains(t, w.Body.String(), "invalid id")
+	})
+}
+
+func TestServer_htmlWebUsersHandler(t *testing.T) {
+	login := time.Now()
+	users := &mocks.WebUsersMock{
+		ListFunc: func(ctx context.Context) ([]storage.WebUser, error) {
+			return []storage.WebUser{
+				{ID: 1, Name: "alice", Role: storage.WebRoleAdmin, CreatedAt: time.Now(), LastLogin: &login},
+				{ID: 2, Name: "bob", Role: storage.WebRoleViewer, CreatedAt: time.Now()},
+			}, nil
+		},
+	}
+	srv := NewServer(Config{WebUsers: users})
+	w := httptest.NewRecorder()
+	srv.htmlWebUsersHandler(w, httptest.NewRequest("GET", "/web_users", http.NoBody))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<title>Web Users - TG-Spam</title>")
+	assert.Contains(t, body, "Users (2)")
+	assert.Contains(t, body, "alice")
+	assert.Contains(t, body, `<option value="viewer" selected>viewer</option>`)
+	assert.Contains(t, body, "<code>tg-spam</code> is always an admin")
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/sha1" //nolint
 	"database/sql"
@@ -18,6 +19,7 @@ import (
 	"math/big"
 	"net/http"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -32,6 +34,8 @@ import (
 
 	"github.com/umputun/tg-spam/app/config"
 	"github.com/umputun/tg-spam/app/events"
+	"github.com/umputun/tg-spam/app/hooks"
+	"github.com/umputun/tg-spam/app/metrics"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/app/storage/engine"
 	"github.com/umputun/tg-spam/lib/approved"
@@ -45,6 +49,10 @@ import (
 //go:generate moq --out mocks/storage_engine.go --pkg mocks --with-resets --skip-ensure . StorageEngine
 //go:generate moq --out mocks/dictionary.go --pkg mocks --with-resets --skip-ensure . Dictionary
 //go:generate moq --out mocks/dm_users_provider.go --pkg mocks --with-resets --skip-ensure . DMUsersProvider
+//go:generate moq --out mocks/image_hashes.go --pkg mocks --with-resets --skip-ensure . ImageHashes
+//go:generate moq --out mocks/reviews.go --pkg mocks --with-resets --skip-ensure . Reviews
+//go:generate moq --out mocks/reputation.go --pkg mocks --with-resets --skip-ensure . Reputation
+//go:generate moq --out mocks/event_publisher.go --pkg mocks --with-resets --skip-ensure . EventPublisher
 
 //go:embed assets/* assets/components/*
 var templateFS embed.FS
@@ -59,6 +67,8 @@ type Server struct {
 	// appSettingsMu guards AppSettings against data races between the config
 	// handlers (load/update/save) and read paths (settings pages, /settings API).
 	appSettingsMu sync.RWMutex
+	sessions      sessionStore // sessions of web users logged in with the login form
+	loginLimits   loginLimiter // login attempts per client ip and per user name
 }
 
 // Config defines server parameters
@@ -70,6 +80,12 @@ type Config struct {
 	DetectedSpam    DetectedSpam     // detected spam accessor
 	Locator         Locator          // locator for user info
 	Dictionary      Dictionary       // dictionary for stop phrases and ignored words
+	ImageHashes     ImageHashes      // perceptual hashes of spam images
+	Reviews         Reviews          // admin review queue of quarantined messages
+	Reputation      Reputation       // per-user reputation, shown on user profile page
+	AuditLog        AuditLog         // log of moderation actions, web UI changes are recorded to it if set
+	APITokens       APITokens        // named bearer tokens with scopes, accepted along with basic auth if set
+	WebUsers        WebUsers         // web UI users with roles, enables the login form if set
 	StorageEngine   StorageEngine    // database engine access for backups
 	DMUsersProvider DMUsersProvider  // provider for recent DM users
 	SettingsStore   SettingsStore    // configuration storage interface
@@ -88,6 +104,20 @@ type Config struct {
 	// POST /config/reload. Credentials (Telegram/OpenAI/Gemini tokens) are
 	// intentionally NOT reapplied here — DB rotation wins on reload.
 	ReloadNormalize func(*config.Settings)
+	// HashPassword makes the bcrypt hash of the web user password, rest.GenerateBcryptHash is used if nil
+	HashPassword func(passwd string) (string, error)
+	// ApplySettings, when non-nil, is invoked by loadConfigHandler and updateConfigHandler
+	// with the previous and the new settings, while the settings lock is held. It applies
+	// changed settings to the running detector and telegram listener and returns paths of
+	// changed settings which take effect only after a restart, e.g. "Telegram.Token".
+	ApplySettings func(prev, settings *config.Settings) (restart []string)
+
+	FederationKey  ed25519.PrivateKey // key signing the federation feed, nil disables the feed
+	FederationFeed time.Duration      // users banned within this period are published in the federation feed
+
+	TelegramWebhook http.Handler // handler of updates posted by telegram in webhook mode, nil if webhook mode is disabled
+
+	Events EventPublisher // publishes samples added in web UI to outgoing webhooks, nil if webhooks are disabled
 }
 
 // Detector is a spam detector interface.
@@ -118,6 +148,7 @@ type Locator interface {
 // DetectedSpam is a storage interface used to get detected spam messages and set added flag.
 type DetectedSpam interface {
 	Read(ctx context.Context) ([]storage.DetectedSpamInfo, error)
+	ReadSince(ctx context.Context, since time.Time, limit int) ([]storage.DetectedSpamInfo, error)
 	SetAddedToSamplesFlag(ctx context.Context, id int64) error
 	FindByUserID(ctx context.Context, userID int64) (*storage.DetectedSpamInfo, error)
 }
@@ -138,6 +169,28 @@ type Dictionary interface {
 	Stats(ctx context.Context) (*storage.DictionaryStats, error)
 }
 
+// ImageHashes is a storage interface for managing perceptual hashes of spam images
+type ImageHashes interface {
+	Add(ctx context.Context, hash string, userID int64, userName string) error
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context) ([]storage.ImageHash, error)
+}
+
+// Reviews is a storage interface for the admin review queue of quarantined messages
+type Reviews interface {
+	List(ctx context.Context) ([]storage.Review, error)
+}
+
+// Reputation is a storage interface for per-user reputation
+type Reputation interface {
+	Get(ctx context.Context, userID int64) (storage.UserReputation, error)
+}
+
+// EventPublisher publishes moderation events to outgoing webhooks
+type EventPublisher interface {
+	Publish(ctx context.Context, e hooks.Event) error
+}
+
 // DMUsersProvider provides access to recent DM users for the admin UI
 type DMUsersProvider interface {
 	GetDMUsers() []events.DMUser
@@ -196,18 +249,58 @@ func (s *Server) Run(ctx context.Context) error {
 // no hash. Reading under RLock keeps it consistent with POST /config/reload
 // swaps, so DB hash rotations take effect without restarting. Mirrors
 // rest.BasicAuthWithBcryptHashAndPrompt's WWW-Authenticate prompt behavior.
+// Requests with a bearer token are checked by serveWithToken and requests with a session cookie
+// of the logged-in web user by serveWithSession instead.
 func (s *Server) basicAuthMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.isLoginPath(r) {
+			h.ServeHTTP(w, r) // login form and its assets
+			return
+		}
+		if s.isFederationPath(r) {
+			h.ServeHTTP(w, r) // signed feed is public, peers pull it without credentials
+			return
+		}
+		if s.isTelegramWebhookPath(r) {
+			h.ServeHTTP(w, r) // telegram can't do basic auth, the handler verifies the secret token instead
+			return
+		}
+		if token, ok := bearerToken(r); ok {
+			s.serveWithToken(w, r, token, h) // api token is checked instead of basic auth, limited by its scopes
+			return
+		}
+		if _, sess, ok := s.requestSession(r); ok {
+			s.serveWithSession(w, r, sess, h) // logged-in web user, limited by the role
+			return
+		}
 		u, p, ok := r.BasicAuth()
 		if ok && s.checkBasicAuth(u, p) {
 			h.ServeHTTP(w, r)
 			return
 		}
+		if s.redirectToLogin(w, r) {
+			return
+		}
 		w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 	})
 }
 
+// isTelegramWebhookPath reports whether the request is an update posted by telegram in webhook mode
+func (s *Server) isTelegramWebhookPath(r *http.Request) bool {
+	return s.TelegramWebhook != nil && r.Method == http.MethodPost && r.URL.Path == "/telegram/webhook"
+}
+
+// telegramWebhookHandler handles POST /telegram/webhook request, passing the update posted by telegram
+// to the webhook handler of the telegram listener
+func (s *Server) telegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if s.TelegramWebhook == nil {
+		http.Error(w, "webhook mode is disabled", http.StatusNotFound)
+		return
+	}
+	s.TelegramWebhook.ServeHTTP(w, r)
+}
+
 // checkBasicAuth returns true when user matches the active auth user and
 // passwd matches the currently active bcrypt hash. The active user is
 // AppSettings.Server.AuthUser when non-empty, else the startup AuthUser, else
@@ -254,8 +347,8 @@ func (s *Server) routes(router *routegroup.Bundle) *routegroup.Bundle {
 
 		authApi.Mount("/update").Route(func(r *routegroup.Bundle) {
 			// update spam/ham samples
-			r.HandleFunc("POST /spam", s.updateSampleHandler(s.SpamFilter.UpdateSpam)) // update spam samples
-			r.HandleFunc("POST /ham", s.updateSampleHandler(s.SpamFilter.UpdateHam))   // update ham samples
+			r.HandleFunc("POST /spam", s.updateSampleHandler(s.withSampleEvent("spam", s.SpamFilter.UpdateSpam))) // update spam samples
+			r.HandleFunc("POST /ham", s.updateSampleHandler(s.withSampleEvent("ham", s.SpamFilter.UpdateHam)))    // update ham samples
 		})
 
 		authApi.Mount("/delete").Route(func(r *routegroup.Bundle) {
@@ -274,6 +367,10 @@ func (s *Server) routes(router *routegroup.Bundle) *routegroup.Bundle {
 			r.HandleFunc("GET /detected_spam", s.downloadDetectedSpamHandler)
 			r.HandleFunc("GET /backup", s.downloadBackupHandler)
 			r.HandleFunc("GET /export-to-postgres", s.downloadExportToPostgresHandler)
+			r.HandleFunc("GET /config", s.exportConfigHandler) // settings as yaml file for --config
+			if s.AuditLog != nil {
+				r.HandleFunc("GET /audit", s.downloadAuditHandler) // audit log as csv, filtered by query params
+			}
 		})
 
 		authApi.HandleFunc("GET /samples", s.getDynamicSamplesHandler)    // get dynamic samples
@@ -289,6 +386,7 @@ func (s *Server) routes(router *routegroup.Bundle) *routegroup.Bundle {
 		})
 
 		authApi.HandleFunc("GET /settings", s.getSettingsHandler) // get application settings
+		authApi.Handle("GET /metrics", metrics.Handler())         // prometheus metrics
 
 		authApi.Mount("/dictionary").Route(func(r *routegroup.Bundle) { // manage dictionary
 			// add stop phrase or ignored word
@@ -298,6 +396,21 @@ func (s *Server) routes(router *routegroup.Bundle) *routegroup.Bundle {
 			// get all entries
 			r.HandleFunc("GET /", s.getDictionaryEntriesHandler)
 		})
+
+		authApi.Mount("/image_hashes").Route(func(r *routegroup.Bundle) { // manage spam image hashes
+			r.HandleFunc("POST /add", s.addImageHashHandler)       // add image hash, e.g. from detected spam
+			r.HandleFunc("POST /delete", s.deleteImageHashHandler) // delete image hash by id
+			r.HandleFunc("GET /", s.getImageHashesHandler)         // get all image hashes
+		})
+
+		authApi.HandleFunc("GET /reviews", s.getReviewsHandler)                 // get admin review queue
+		authApi.HandleFunc("GET /reputation/{user_id}", s.getReputationHandler) // get user reputation
+		authApi.HandleFunc("GET /federation/feed", s.getFederationFeedHandler)  // signed feed of banned users, public
+		authApi.HandleFunc("GET /federation/key", s.getFederationKeyHandler)    // federation public key, public
+		authApi.HandleFunc("POST /telegram/webhook", s.telegramWebhookHandler)  // telegram updates, checked by secret token
+		if s.AuditLog != nil {
+			authApi.HandleFunc("GET /audit", s.getAuditHandler) // get audit log, filtered by query params
+		}
 	})
 
 	router.Route(func(webUI *routegroup.Bundle) {
@@ -305,10 +418,22 @@ func (s *Server) routes(router *routegroup.Bundle) *routegroup.Bundle {
 		webUI.HandleFunc("GET /manage_samples", s.htmlManageSamplesHandler)       // serve manage samples page
 		webUI.HandleFunc("GET /manage_users", s.htmlManageUsersHandler)           // serve manage users page
 		webUI.HandleFunc("GET /manage_dictionary", s.htmlManageDictionaryHandler) // serve manage dictionary page
+		webUI.HandleFunc("GET /manage_images", s.htmlManageImageHashesHandler)    // serve manage image hashes page
+		webUI.HandleFunc("GET /review_queue", s.htmlReviewQueueHandler)           // serve review queue page
+		webUI.HandleFunc("GET /user_profile", s.htmlUserProfileHandler)           // serve user profile page
 		webUI.HandleFunc("GET /detected_spam", s.htmlDetectedSpamHandler)         // serve detected spam page
 		webUI.HandleFunc("GET /list_settings", s.htmlSettingsHandler)             // serve settings
 		webUI.HandleFunc("POST /detected_spam/add", s.htmlAddDetectedSpamHandler) // add detected spam to samples
 		webUI.HandleFunc("GET /dm-users", s.getDMUsersHandler)                    // get recent DM users (HTMX/JSON)
+		if s.AuditLog != nil {
+			webUI.HandleFunc("GET /audit_log", s.htmlAuditLogHandler) // serve audit log page
+		}
+		if s.APITokens != nil {
+			// api tokens management, not in tokenRouteScopes, so a token can't create or delete tokens
+			webUI.HandleFunc("GET /api_tokens", s.htmlAPITokensHandler)
+			webUI.HandleFunc("POST /api_tokens/add", s.addAPITokenHandler)       // create token, shown once
+			webUI.HandleFunc("POST /api_tokens/delete", s.deleteAPITokenHandler) // delete token by id
+		}
 
 		// configuration management endpoints
 		if s.SettingsStore != nil && s.ConfigDBMode {
@@ -318,15 +443,26 @@ func (s *Server) routes(router *routegroup.Bundle) *routegroup.Bundle {
 				cfgRouter.HandleFunc("POST /config/reload", s.loadConfigHandler)
 				cfgRouter.HandleFunc("PUT /config", s.updateConfigHandler)    // update configuration
 				cfgRouter.HandleFunc("DELETE /config", s.deleteConfigHandler) // delete configuration
+				// history of saved revisions with changes of each revision and rollback to it
+				cfgRouter.HandleFunc("GET /config/history", s.configHistoryHandler)
+				cfgRouter.HandleFunc("GET /config/history/{id}/diff", s.configDiffHandler)
+				cfgRouter.HandleFunc("POST /config/history/{id}/rollback", s.configRollbackHandler)
 			})
 		}
 
-		// handle logout - force Basic Auth re-authentication
-		webUI.HandleFunc("GET /logout", func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("WWW-Authenticate", `Basic realm="tg-spam"`)
-			w.WriteHeader(http.StatusUnauthorized)
-			fmt.Fprintln(w, "Logged out successfully")
-		})
+		if s.WebUsers != nil {
+			webUI.HandleFunc("GET /login", s.htmlLoginHandler) // serve login form
+			webUI.HandleFunc("POST /login", s.loginHandler)    // check credentials and start session
+			// web users management, admin only as not listed in routeRoles
+			webUI.HandleFunc("GET /web_users", s.htmlWebUsersHandler)
+			webUI.HandleFunc("POST /web_users/add", s.addWebUserHandler)
+			webUI.HandleFunc("POST /web_users/role", s.setWebUserRoleHandler)
+			webUI.HandleFunc("POST /web_users/password", s.resetWebUserPasswordHandler) // new random password, shown once
+			webUI.HandleFunc("POST /web_users/delete", s.deleteWebUserHandler)
+		}
+
+		// handle logout - end the session or force Basic Auth re-authentication
+		webUI.HandleFunc("GET /logout", s.logoutHandler)
 
 		// serve only specific static files at root level
 		staticFiles := newStaticFS(templateFS,
@@ -365,6 +501,12 @@ func (s *Server) checkMsgHandler(w http.ResponseWriter, r *http.Request) {
 		req.Msg = r.FormValue("msg")
 	}
 
+	// viewers and api tokens without the users scope can't change approved users
+	tok, isToken := r.Context().Value(apiTokenCtxKey{}).(*storage.APIToken)
+	if requestRole(r) == storage.WebRoleViewer || (isToken && !tok.HasScope(storage.APIScopeUsers)) {
+		req.CheckOnly = true
+	}
+
 	spam, cr := s.Detector.Check(req)
 	if !isHtmxRequest {
 		// for API request return JSON
@@ -483,6 +625,8 @@ func (s *Server) updateSampleHandler(updFn func(msg string) error) func(w http.R
 			_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't update samples", "details": err.Error()})
 			return
 		}
+		// sample type is the last path element of /update/spam and /update/ham
+		s.recordAudit(r, storage.AuditRecord{Action: storage.AuditSampleAdd, Text: req.Msg, Reason: path.Base(r.URL.Path)})
 
 		if isHtmxRequest {
 			s.renderSamples(w, "samples_list")
@@ -492,6 +636,23 @@ func (s *Server) updateSampleHandler(updFn func(msg string) error) func(w http.R
 	}
 }
 
+// withSampleEvent wraps the samples update function to publish the added sample
+func (s *Server) withSampleEvent(sampleType string, updFn func(msg string) error) func(msg string) error {
+	if s.Events == nil {
+		return updFn
+	}
+	return func(msg string) error {
+		if err := updFn(msg); err != nil {
+			return err
+		}
+		e := hooks.Event{Type: hooks.EventSampleAdded, Source: hooks.SourceWeb, Text: msg, Details: sampleType}
+		if err := s.Events.Publish(context.Background(), e); err != nil {
+			log.Printf("[WARN] failed to publish %s event: %v", e.Type, err)
+		}
+		return nil
+	}
+}
+
 // deleteSampleHandler handles DELETE /samples request. It deletes dynamic samples both for spam and ham.
 func (s *Server) deleteSampleHandler(delFn func(msg string) error) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -512,6 +673,7 @@ func (s *Server) deleteSampleHandler(delFn func(msg string) error) func(w http.R
 			_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't delete sample", "details": err.Error()})
 			return
 		}
+		s.recordAudit(r, storage.AuditRecord{Action: storage.AuditSampleDelete, Text: req.Msg, Reason: path.Base(r.URL.Path)})
 
 		if isHtmxRequest {
 			s.renderSamples(w, "samples_list")
@@ -613,9 +775,15 @@ func (s *Server) getSettingsHandler(w http.ResponseWriter, _ *http.Request) {
 	}
 	s.appSettingsMu.RUnlock()
 	safe.Telegram.Token = ""
+	safe.Telegram.WebhookSecret = ""
 	safe.OpenAI.Token = ""
 	safe.Gemini.Token = ""
+	safe.Hooks.Secret = ""
 	safe.Server.AuthHash = ""
+	safe.LLM.Providers = slices.Clone(safe.LLM.Providers) // tokens are cleared in the copy only
+	for i := range safe.LLM.Providers {
+		safe.LLM.Providers[i].Token = ""
+	}
 
 	resp := struct {
 		*config.Settings
@@ -627,7 +795,8 @@ func (s *Server) getSettingsHandler(w http.ResponseWriter, _ *http.Request) {
 	rest.RenderJSON(w, resp)
 }
 
-// getDictionaryEntriesHandler handles GET /dictionary request. It returns stop phrases and ignored words.
+// getDictionaryEntriesHandler handles GET /dictionary request. It returns stop phrases, ignored words
+// and blocked/allowed domains.
 func (s *Server) getDictionaryEntriesHandler(w http.ResponseWriter, r *http.Request) {
 	stopPhrases, err := s.Dictionary.Read(r.Context(), storage.DictionaryTypeStopPhrase)
 	if err != nil {
@@ -641,10 +810,23 @@ func (s *Server) getDictionaryEntriesHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	rest.RenderJSON(w, rest.JSON{"stop_phrases": stopPhrases, "ignored_words": ignoredWords})
+	blockedDomains, err := s.Dictionary.Read(r.Context(), storage.DictionaryTypeBlockedDomain)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get blocked domains", "details": err.Error()})
+		return
+	}
+
+	allowedDomains, err := s.Dictionary.Read(r.Context(), storage.DictionaryTypeAllowedDomain)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get allowed domains", "details": err.Error()})
+		return
+	}
+
+	rest.RenderJSON(w, rest.JSON{"stop_phrases": stopPhrases, "ignored_words": ignoredWords,
+		"blocked_domains": blockedDomains, "allowed_domains": allowedDomains})
 }
 
-// addDictionaryEntryHandler handles POST /dictionary/add request. It adds a stop phrase or ignored word.
+// addDictionaryEntryHandler handles POST /dictionary/add request. It adds a stop phrase, ignored word or domain.
 func (s *Server) addDictionaryEntryHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Type string `json:"type"`
@@ -754,6 +936,110 @@ func (s *Server) deleteDictionaryEntryHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// getImageHashesHandler handles GET /image_hashes request. It returns all stored spam image hashes.
+func (s *Server) getImageHashesHandler(w http.ResponseWriter, r *http.Request) {
+	hashes, err := s.ImageHashes.List(r.Context())
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get image hashes", "details": err.Error()})
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"image_hashes": hashes})
+}
+
+// addImageHashHandler handles POST /image_hashes/add request. It adds a spam image hash to the blocklist.
+// HTMX requests come from the detected spam page, the response is empty to remove the button.
+func (s *Server) addImageHashHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Hash     string `json:"hash"`
+		UserID   int64  `json:"user_id"`
+		UserName string `json:"user_name"`
+	}
+
+	isHtmxRequest := r.Header.Get("HX-Request") == "true"
+	if isHtmxRequest {
+		req.Hash = r.FormValue("hash")
+		req.UserName = r.FormValue("user_name")
+		req.UserID, _ = strconv.ParseInt(r.FormValue("user_id"), 10, 64) // user id is informational only
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't decode request", "details": err.Error()})
+		return
+	}
+
+	if err := s.ImageHashes.Add(r.Context(), req.Hash, req.UserID, req.UserName); err != nil {
+		if isHtmxRequest {
+			w.Header().Set("HX-Retarget", "#error-message")
+			fmt.Fprintf(w, "<div class='alert alert-danger'>Can't add image hash: %v</div>", err)
+			return
+		}
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't add image hash", "details": err.Error()})
+		return
+	}
+
+	if isHtmxRequest {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"added": true, "hash": req.Hash})
+}
+
+// deleteImageHashHandler handles POST /image_hashes/delete request. It deletes a spam image hash by id.
+func (s *Server) deleteImageHashHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	isHtmxRequest := r.Header.Get("HX-Request") == "true"
+	if isHtmxRequest {
+		id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+		if err != nil {
+			w.Header().Set("HX-Retarget", "#error-message")
+			fmt.Fprintf(w, "<div class='alert alert-danger'>Invalid ID: %v</div>", err)
+			return
+		}
+		req.ID = id
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't decode request", "details": err.Error()})
+		return
+	}
+
+	if err := s.ImageHashes.Delete(r.Context(), req.ID); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't delete image hash", "details": err.Error()})
+		return
+	}
+
+	if isHtmxRequest {
+		s.renderImageHashes(r.Context(), w, "image_hashes_list")
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"deleted": true, "id": req.ID})
+}
+
+// getReputationHandler handles GET /reputation/{user_id} request. It returns the user reputation with its score,
+// users without history get zero counters.
+func (s *Server) getReputationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.PathValue("user_id"), 10, 64)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusBadRequest, rest.JSON{"error": "can't parse user id", "details": err.Error()})
+		return
+	}
+	rep, err := s.Reputation.Get(r.Context(), userID)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get reputation", "details": err.Error()})
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"reputation": rep, "score": rep.Score()})
+}
+
+// getReviewsHandler handles GET /reviews request. It returns the latest quarantined messages with review status.
+func (s *Server) getReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	reviews, err := s.Reviews.List(r.Context())
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't get reviews", "details": err.Error()})
+		return
+	}
+	rest.RenderJSON(w, rest.JSON{"reviews": reviews})
+}
+
 // htmlSpamCheckHandler handles GET / request.
 // It returns rendered spam_check.html template with all the components.
 func (s *Server) htmlSpamCheckHandler(w http.ResponseWriter, _ *http.Request) {
@@ -798,6 +1084,81 @@ func (s *Server) htmlManageDictionaryHandler(w http.ResponseWriter, r *http.Requ
 	s.renderDictionary(r.Context(), w, "manage_dictionary.html")
 }
 
+func (s *Server) htmlManageImageHashesHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderImageHashes(r.Context(), w, "manage_images.html")
+}
+
+func (s *Server) htmlReviewQueueHandler(w http.ResponseWriter, r *http.Request) {
+	reviews, err := s.Reviews.List(r.Context())
+	if err != nil {
+		log.Printf("[ERROR] Failed to fetch reviews: %v", err)
+		http.Error(w, "Error fetching reviews", http.StatusInternalServerError)
+		return
+	}
+
+	pending := 0
+	for _, rv := range reviews {
+		if rv.Status == storage.ReviewPending {
+			pending++
+		}
+	}
+	tmplData := struct {
+		Reviews      []storage.Review
+		TotalReviews int
+		Pending      int
+	}{
+		Reviews:      reviews,
+		TotalReviews: len(reviews),
+		Pending:      pending,
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "review_queue.html", tmplData); err != nil {
+		log.Printf("[WARN] can't execute template: %v", err)
+		http.Error(w, "Error executing template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// htmlUserProfileHandler handles GET /user_profile?user_id=123 request. It shows the user reputation,
+// approval status and detected spam. Without user_id only the lookup form is shown.
+func (s *Server) htmlUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	tmplData := struct {
+		UserID     string
+		Found      bool
+		Reputation storage.UserReputation
+		Score      int
+		Approved   bool
+		Spam       *storage.DetectedSpamInfo
+		Error      string
+	}{UserID: strings.TrimSpace(r.URL.Query().Get("user_id"))}
+
+	userID, err := strconv.ParseInt(tmplData.UserID, 10, 64)
+	if tmplData.UserID != "" && err != nil {
+		tmplData.Error = fmt.Sprintf("invalid user id %q", tmplData.UserID)
+	}
+	if tmplData.UserID != "" && err == nil {
+		if tmplData.Reputation, err = s.Reputation.Get(r.Context(), userID); err != nil {
+			log.Printf("[ERROR] Failed to fetch reputation of %d: %v", userID, err)
+			http.Error(w, "Error fetching user reputation", http.StatusInternalServerError)
+			return
+		}
+		if tmplData.Spam, err = s.DetectedSpam.FindByUserID(r.Context(), userID); err != nil {
+			log.Printf("[WARN] failed to fetch detected spam of %d: %v", userID, err)
+		}
+		tmplData.Found = true
+		tmplData.Score = tmplData.Reputation.Score()
+		tmplData.Approved = slices.ContainsFunc(s.Detector.ApprovedUsers(), func(u approved.UserInfo) bool {
+			return u.UserID == tmplData.UserID
+		})
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "user_profile.html", tmplData); err != nil {
+		log.Printf("[WARN] can't execute template: %v", err)
+		http.Error(w, "Error executing template", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (s *Server) htmlDetectedSpamHandler(w http.ResponseWriter, r *http.Request) {
 	ds, err := s.DetectedSpam.Read(r.Context())
 	if err != nil {
@@ -946,6 +1307,7 @@ func (s *Server) htmlAddDetectedSpamHandler(w http.ResponseWriter, r *http.Reque
 		return
 
 	}
+	s.recordAudit(r, storage.AuditRecord{Action: storage.AuditSampleAdd, Text: msg, Reason: "spam, from detected spam"})
 	if err := s.DetectedSpam.SetAddedToSamplesFlag(r.Context(), id); err != nil {
 		log.Printf("[WARN] failed to update detected spam: %v", err)
 		reportErr(fmt.Errorf("can't update detected spam: %v", err), http.StatusInternalServerError)
@@ -1378,16 +1740,60 @@ func (s *Server) renderDictionary(ctx context.Context, w http.ResponseWriter, tm
 		return
 	}
 
+	blockedDomains, err := s.Dictionary.ReadWithIDs(ctx, storage.DictionaryTypeBlockedDomain)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError,
+			rest.JSON{"error": "can't fetch blocked domains", "details": err.Error()})
+		return
+	}
+
+	allowedDomains, err := s.Dictionary.ReadWithIDs(ctx, storage.DictionaryTypeAllowedDomain)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError,
+			rest.JSON{"error": "can't fetch allowed domains", "details": err.Error()})
+		return
+	}
+
+	tmplData := struct {
+		StopPhrases         []storage.DictionaryEntry
+		IgnoredWords        []storage.DictionaryEntry
+		BlockedDomains      []storage.DictionaryEntry
+		AllowedDomains      []storage.DictionaryEntry
+		TotalStopPhrases    int
+		TotalIgnoredWords   int
+		TotalBlockedDomains int
+		TotalAllowedDomains int
+	}{
+		StopPhrases:         stopPhrases,
+		IgnoredWords:        ignoredWords,
+		BlockedDomains:      blockedDomains,
+		AllowedDomains:      allowedDomains,
+		TotalStopPhrases:    len(stopPhrases),
+		TotalIgnoredWords:   len(ignoredWords),
+		TotalBlockedDomains: len(blockedDomains),
+		TotalAllowedDomains: len(allowedDomains),
+	}
+
+	if err := tmpl.ExecuteTemplate(w, tmplName, tmplData); err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't execute template", "details": err.Error()})
+		return
+	}
+}
+
+// renderImageHashes renders spam image hashes for HTMX or full page request
+func (s *Server) renderImageHashes(ctx context.Context, w http.ResponseWriter, tmplName string) {
+	hashes, err := s.ImageHashes.List(ctx)
+	if err != nil {
+		_ = rest.EncodeJSON(w, http.StatusInternalServerError, rest.JSON{"error": "can't fetch image hashes", "details": err.Error()})
+		return
+	}
+
 	tmplData := struct {
-		StopPhrases       []storage.DictionaryEntry
-		IgnoredWords      []storage.DictionaryEntry
-		TotalStopPhrases  int
-		TotalIgnoredWords int
+		ImageHashes      []storage.ImageHash
+		TotalImageHashes int
 	}{
-		StopPhrases:       stopPhrases,
-		IgnoredWords:      ignoredWords,
-		TotalStopPhrases:  len(stopPhrases),
-		TotalIgnoredWords: len(ignoredWords),
+		ImageHashes:      hashes,
+		TotalImageHashes: len(hashes),
 	}
 
 	if err := tmpl.ExecuteTemplate(w, tmplName, tmplData); err != nil {
@@ -6,7 +6,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"html/template"
 	"io"
 	"net/http"
@@ -22,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/umputun/tg-spam/app/config"
 	"github.com/umputun/tg-spam/app/events"
+	"github.com/umputun/tg-spam/app/hooks"
 	"github.com/umputun/tg-spam/app/storage"
 	"github.com/umputun/tg-spam/app/storage/engine"
 	"github.com/umputun/tg-spam/app/webapi/mocks"
@@ -242,6 +242,41 @@ func TestServer_checkBasicAuth(t *testing.T) {
 	})
 }
 
+func TestServer_telegramWebhook(t *testing.T) {
+	hash, err := rest.GenerateBcryptHash("secret")
+	require.NoError(t, err)
+	webhook := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusAccepted) })
+
+	tests := []struct {
+		name    string
+		webhook http.Handler
+		method  string
+		path    string
+		want    int
+	}{
+		{name: "webhook passed without basic auth", webhook: webhook, method: "POST", path: "/telegram/webhook", want: http.StatusAccepted},
+		{name: "other methods need auth", webhook: webhook, method: "GET", path: "/telegram/webhook", want: http.StatusUnauthorized},
+		{name: "other paths need auth", webhook: webhook, method: "POST", path: "/check", want: http.StatusUnauthorized},
+		{name: "webhook mode disabled", method: "POST", path: "/telegram/webhook", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &Server{Config: Config{AuthHash: hash, TelegramWebhook: tt.webhook}}
+			w := httptest.NewRecorder()
+			srv.basicAuthMiddleware(http.HandlerFunc(srv.telegramWebhookHandler)).
+				ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, http.NoBody))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	t.Run("handler without webhook", func(t *testing.T) {
+		srv := NewServer(Config{})
+		w := httptest.NewRecorder()
+		srv.telegramWebhookHandler(w, httptest.NewRequest("POST", "/telegram/webhook", http.NoBody))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
 func TestServer_routes(t *testing.T) {
 	detectorMock := &mocks.DetectorMock{
 		CheckFunc: func(req spamcheck.Request) (bool, []spamcheck.Response) {
@@ -487,6 +522,17 @@ func TestServer_routes(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, 10, res.MinMsgLen)
 	})
+	t.Run("get metrics", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), "# TYPE tgspam_checks_total counter")
+		assert.Contains(t, string(body), "# TYPE tgspam_check_duration_seconds histogram")
+	})
 }
 func TestServer_checkHandler(t *testing.T) {
 	mockDetector := &mocks.DetectorMock{
@@ -665,6 +711,27 @@ func TestServer_updateSampleHandler(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, rr.Code, "handler returned wrong status code")
 	})
 }
+
+func TestServer_withSampleEvent(t *testing.T) {
+	updFn := func(msg string) error {
+		if msg == "error" {
+			return assert.AnError
+		}
+		return nil
+	}
+	pub := &mocks.EventPublisherMock{PublishFunc: func(ctx context.Context, e hooks.Event) error { return nil }}
+	server := NewServer(Config{Events: pub})
+
+	require.NoError(t, server.withSampleEvent("spam", updFn)("buy now"))
+	require.Error(t, server.withSampleEvent("spam", updFn)("error"))
+	require.Len(t, pub.PublishCalls(), 1, "failed update not published")
+	assert.Equal(t, hooks.Event{Type: hooks.EventSampleAdded, Source: hooks.SourceWeb, Text: "buy now", Details: "spam"},
+		pub.PublishCalls()[0].E)
+
+	server = NewServer(Config{})
+	require.NoError(t, server.withSampleEvent("ham", updFn)("hello"), "works without events")
+}
+
 func TestServer_deleteSampleHandler(t *testing.T) {
 	spamFilterMock := &mocks.SpamFilterMock{
 		RemoveDynamicHamSampleFunc: func(sample string) error { return nil },
@@ -848,6 +915,7 @@ func TestServer_htmlDetectedSpamHandler(t *testing.T) {
 						UserID:    67890,
 						UserName:  "user2",
 						Timestamp: ts,
+						ImageHash: "ff00ff00ff00ff00",
 					},
 				}, nil
 			},
@@ -874,6 +942,9 @@ func TestServer_htmlDetectedSpamHandler(t *testing.T) {
 		assert.Contains(t, body, "spam2")
 		assert.Contains(t, body, "user2")
 		assert.Contains(t, body, "67890")
+		// image button shown for the entry with image hash only, in desktop and mobile views
+		assert.Equal(t, 2, strings.Count(body, `hx-post="/image_hashes/add"`))
+		assert.Contains(t, body, "ff00ff00ff00ff00")
 	})
 	t.Run("read failure", func(t *testing.T) {
 		ds := &mocks.DetectedSpamMock{
@@ -1246,10 +1317,13 @@ func TestServer_getSettingsHandler(t *testing.T) {
 			Detector: detectorMock,
 			AppSettings: &config.Settings{
 				InstanceID: "test",
-				Telegram:   config.TelegramSettings{Token: "tg-secret"},
+				Telegram:   config.TelegramSettings{Token: "tg-secret", WebhookSecret: "webhook-secret"},
 				OpenAI:     config.OpenAISettings{Token: "openai-secret"},
 				Gemini:     config.GeminiSettings{Token: "gemini-secret"},
+				Hooks:      config.HooksSettings{URLs: []string{"https://example.com/hook"}, Secret: "hooks-secret"},
 				Server:     config.ServerSettings{AuthHash: "$2a$bcrypt-hash"},
+				LLM: config.LLMSettings{Providers: []config.LLMProviderSettings{
+					{Name: "claude", Type: "anthropic", Token: "anthropic-secret"}}},
 			},
 		})
 		rr := httptest.NewRecorder()
@@ -1260,9 +1334,14 @@ func TestServer_getSettingsHandler(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rr.Code)
 		body := rr.Body.String()
 		assert.NotContains(t, body, "tg-secret", "telegram token must be redacted")
+		assert.NotContains(t, body, "webhook-secret", "webhook secret must be redacted")
 		assert.NotContains(t, body, "openai-secret", "openai token must be redacted")
 		assert.NotContains(t, body, "gemini-secret", "gemini token must be redacted")
+		assert.NotContains(t, body, "hooks-secret", "hooks secret must be redacted")
 		assert.NotContains(t, body, "$2a$bcrypt-hash", "auth hash must be redacted")
+		assert.NotContains(t, body, "anthropic-secret", "llm provider token must be redacted")
+		assert.Contains(t, body, `"name":"claude"`)
+		assert.Equal(t, "anthropic-secret", server.AppSettings.LLM.Providers[0].Token, "live settings not changed")
 	})
 	t.Run("nil app settings does not panic", func(t *testing.T) {
 		detectorMock := &mocks.DetectorMock{
@@ -1392,10 +1471,11 @@ func TestServer_htmlSettingsHandler(t *testing.T) {
 		body := rr.Body.String()
 		assert.Contains(t, body, "The web auth hash is re-read on every request, so it rotates immediately",
 			"auth hash is the only credential reload picks up")
-		assert.Contains(t, body, "Telegram, OpenAI and Gemini tokens",
-			"service tokens must be named as needing a restart")
+		assert.Contains(t, body, "Other settings, like the Telegram token",
+			"telegram token must be named as needing a restart")
+		assert.Contains(t, body, "LLM providers with their tokens", "llm clients are rebuilt on update and reload")
 		assert.NotContains(t, body, "credential rotations apply immediately",
-			"the telegram and llm clients are built once at startup and reload does not rebuild them")
+			"the telegram client is built once at startup and reload does not rebuild it")
 	})
 
 	// test execution error
@@ -2082,15 +2162,10 @@ func TestServer_downloadExportToPostgresHandler(t *testing.T) {
 // Additional test cases for the checkIDHandler have been implicitly covered in the TestServer_routes tests
 // where the routing infrastructure properly sets Path Values.
 func TestServer_logoutHandler(t *testing.T) {
-	// create a function that matches our logout handler implementation in routes
-	logoutHandler := func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("WWW-Authenticate", `Basic realm="tg-spam"`)
-		w.WriteHeader(http.StatusUnauthorized)
-		fmt.Fprintln(w, "Logged out successfully")
-	}
+	srv := NewServer(Config{})
 	req := httptest.NewRequest("GET", "/logout", http.NoBody)
 	w := httptest.NewRecorder()
-	logoutHandler(w, req)
+	srv.logoutHandler(w, req)
 	resp := w.Result()
 	defer resp.Body.Close()
 	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
@@ -2405,6 +2480,50 @@ func TestServer_getDictionaryEntriesHandler(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 		assert.Contains(t, string(body), "can't get ignored words")
 	})
+
+	t.Run("with domains", func(t *testing.T) {
+		mockDict := &mocks.DictionaryMock{
+			ReadFunc: func(ctx context.Context, t storage.DictionaryType) ([]string, error) {
+				switch t {
+				case storage.DictionaryTypeBlockedDomain:
+					return []string{"spam.example.com"}, nil
+				case storage.DictionaryTypeAllowedDomain:
+					return []string{"github.com"}, nil
+				}
+				return []string{}, nil
+			},
+		}
+
+		srv := NewServer(Config{Dictionary: mockDict})
+		req := httptest.NewRequest("GET", "/dictionary", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.getDictionaryEntriesHandler(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res map[string][]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, []string{"spam.example.com"}, res["blocked_domains"])
+		assert.Equal(t, []string{"github.com"}, res["allowed_domains"])
+		assert.Empty(t, res["stop_phrases"])
+	})
+
+	t.Run("error reading domains", func(t *testing.T) {
+		mockDict := &mocks.DictionaryMock{
+			ReadFunc: func(ctx context.Context, t storage.DictionaryType) ([]string, error) {
+				if t == storage.DictionaryTypeAllowedDomain {
+					return nil, errors.New("db error")
+				}
+				return []string{}, nil
+			},
+		}
+
+		srv := NewServer(Config{Dictionary: mockDict})
+		req := httptest.NewRequest("GET", "/dictionary", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.getDictionaryEntriesHandler(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "can't get allowed domains")
+	})
 }
 
 func TestServer_addDictionaryEntryHandler(t *testing.T) {
@@ -2578,6 +2697,40 @@ func TestServer_addDictionaryEntryHandler(t *testing.T) {
 		assert.Len(t, mockSpamFilter.ReloadSamplesCalls(), 1)
 	})
 
+	t.Run("blocked domain htmx", func(t *testing.T) {
+		mockDict := &mocks.DictionaryMock{
+			AddFunc: func(ctx context.Context, t storage.DictionaryType, data string) error {
+				return nil
+			},
+			ReadWithIDsFunc: func(ctx context.Context, t storage.DictionaryType) ([]storage.DictionaryEntry, error) {
+				if t == storage.DictionaryTypeBlockedDomain {
+					return []storage.DictionaryEntry{{ID: 7, Data: "spam.example.com"}}, nil
+				}
+				return nil, nil
+			},
+		}
+		mockSpamFilter := &mocks.SpamFilterMock{ReloadSamplesFunc: func() error { return nil }}
+
+		srv := NewServer(Config{Dictionary: mockDict, SpamFilter: mockSpamFilter})
+		form := url.Values{}
+		form.Set("type", "blocked_domain")
+		form.Set("data", "spam.example.com")
+		req := httptest.NewRequest("POST", "/dictionary/add", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+
+		srv.addDictionaryEntryHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Blocked Domains (1)")
+		assert.Contains(t, w.Body.String(), "spam.example.com")
+		assert.Contains(t, w.Body.String(), "No allowed domains found")
+		require.Len(t, mockDict.AddCalls(), 1)
+		assert.Equal(t, storage.DictionaryTypeBlockedDomain, mockDict.AddCalls()[0].T)
+		assert.Len(t, mockSpamFilter.ReloadSamplesCalls(), 1)
+	})
+
 	t.Run("empty data htmx", func(t *testing.T) {
 		mockDict := &mocks.DictionaryMock{}
 		srv := NewServer(Config{Dictionary: mockDict})
@@ -3238,3 +3391,361 @@ func TestDMUsers_settingsPageContainsDMUsersSection(t *testing.T) {
 	assert.Contains(t, body, "function copyUserID(userId, btn)")
 	assert.Contains(t, body, "navigator.clipboard")
 }
+
+func TestServer_getImageHashesHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockHashes := &mocks.ImageHashesMock{ListFunc: func(ctx context.Context) ([]storage.ImageHash, error) {
+			return []storage.ImageHash{{ID: 1, Hash: "ff00ff00ff00ff00", UserID: 123, UserName: "spammer"}}, nil
+		}}
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("GET", "/image_hashes", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.getImageHashesHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			ImageHashes []storage.ImageHash `json:"image_hashes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.ImageHashes, 1)
+		assert.Equal(t, "ff00ff00ff00ff00", resp.ImageHashes[0].Hash)
+		assert.Equal(t, int64(123), resp.ImageHashes[0].UserID)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockHashes := &mocks.ImageHashesMock{ListFunc: func(ctx context.Context) ([]storage.ImageHash, error) {
+			return nil, errors.New("db error")
+		}}
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("GET", "/image_hashes", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.getImageHashesHandler(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "can't get image hashes")
+	})
+}
+
+func TestServer_addImageHashHandler(t *testing.T) {
+	newMock := func(err error) *mocks.ImageHashesMock {
+		return &mocks.ImageHashesMock{AddFunc: func(ctx context.Context, hash string, userID int64, userName string) error {
+			return err
+		}}
+	}
+
+	t.Run("success json", func(t *testing.T) {
+		mockHashes := newMock(nil)
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("POST", "/image_hashes/add",
+			strings.NewReader(`{"hash": "ff00ff00ff00ff00", "user_id": 123, "user_name": "spammer"}`))
+		w := httptest.NewRecorder()
+		srv.addImageHashHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"added":true`)
+		require.Len(t, mockHashes.AddCalls(), 1)
+		assert.Equal(t, "ff00ff00ff00ff00", mockHashes.AddCalls()[0].Hash)
+		assert.Equal(t, int64(123), mockHashes.AddCalls()[0].UserID)
+		assert.Equal(t, "spammer", mockHashes.AddCalls()[0].UserName)
+	})
+
+	t.Run("success htmx", func(t *testing.T) {
+		mockHashes := newMock(nil)
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		form := url.Values{"hash": {"ff00ff00ff00ff00"}, "user_id": {"123"}, "user_name": {"spammer"}}
+		req := httptest.NewRequest("POST", "/image_hashes/add", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.addImageHashHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+		require.Len(t, mockHashes.AddCalls(), 1)
+		assert.Equal(t, int64(123), mockHashes.AddCalls()[0].UserID)
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		srv := NewServer(Config{ImageHashes: newMock(errors.New("invalid image hash"))})
+		req := httptest.NewRequest("POST", "/image_hashes/add", strings.NewReader(`{"hash": "bad"}`))
+		w := httptest.NewRecorder()
+		srv.addImageHashHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "can't add image hash")
+	})
+
+	t.Run("invalid hash htmx", func(t *testing.T) {
+		srv := NewServer(Config{ImageHashes: newMock(errors.New("invalid image hash"))})
+		req := httptest.NewRequest("POST", "/image_hashes/add", strings.NewReader("hash=bad"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.addImageHashHandler(w, req)
+		assert.Equal(t, "#error-message", w.Header().Get("HX-Retarget"))
+		assert.Contains(t, w.Body.String(), "invalid image hash")
+	})
+
+	t.Run("bad json", func(t *testing.T) {
+		mockHashes := newMock(nil)
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("POST", "/image_hashes/add", strings.NewReader(`{bad json`))
+		w := httptest.NewRecorder()
+		srv.addImageHashHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, mockHashes.AddCalls())
+	})
+}
+
+func TestServer_deleteImageHashHandler(t *testing.T) {
+	t.Run("success json", func(t *testing.T) {
+		mockHashes := &mocks.ImageHashesMock{DeleteFunc: func(ctx context.Context, id int64) error { return nil }}
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("POST", "/image_hashes/delete", strings.NewReader(`{"id": 123}`))
+		w := httptest.NewRecorder()
+		srv.deleteImageHashHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"deleted":true`)
+		require.Len(t, mockHashes.DeleteCalls(), 1)
+		assert.Equal(t, int64(123), mockHashes.DeleteCalls()[0].ID)
+	})
+
+	t.Run("success htmx renders list", func(t *testing.T) {
+		mockHashes := &mocks.ImageHashesMock{
+			DeleteFunc: func(ctx context.Context, id int64) error { return nil },
+			ListFunc: func(ctx context.Context) ([]storage.ImageHash, error) {
+				return []storage.ImageHash{{ID: 2, Hash: "00ff00ff00ff00ff", UserName: "other"}}, nil
+			},
+		}
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("POST", "/image_hashes/delete", strings.NewReader("id=1"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.deleteImageHashHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Image Hashes (1)")
+		assert.Contains(t, w.Body.String(), "00ff00ff00ff00ff")
+		assert.NotContains(t, w.Body.String(), "<html>")
+		require.Len(t, mockHashes.DeleteCalls(), 1)
+		assert.Equal(t, int64(1), mockHashes.DeleteCalls()[0].ID)
+	})
+
+	t.Run("invalid id htmx", func(t *testing.T) {
+		mockHashes := &mocks.ImageHashesMock{}
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("POST", "/image_hashes/delete", strings.NewReader("id=abc"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("HX-Request", "true")
+		w := httptest.NewRecorder()
+		srv.deleteImageHashHandler(w, req)
+		assert.Equal(t, "#error-message", w.Header().Get("HX-Retarget"))
+		assert.Contains(t, w.Body.String(), "Invalid ID")
+		assert.Empty(t, mockHashes.DeleteCalls())
+	})
+
+	t.Run("delete error", func(t *testing.T) {
+		mockHashes := &mocks.ImageHashesMock{DeleteFunc: func(ctx context.Context, id int64) error {
+			return errors.New("not found")
+		}}
+		srv := NewServer(Config{ImageHashes: mockHashes})
+		req := httptest.NewRequest("POST", "/image_hashes/delete", strings.NewReader(`{"id": 999}`))
+		w := httptest.NewRecorder()
+		srv.deleteImageHashHandler(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "can't delete image hash")
+	})
+}
+
+func TestServer_htmlManageImageHashesHandler(t *testing.T) {
+	mockHashes := &mocks.ImageHashesMock{ListFunc: func(ctx context.Context) ([]storage.ImageHash, error) {
+		return []storage.ImageHash{
+			{ID: 1, Hash: "ff00ff00ff00ff00", UserID: 123, UserName: "spammer", CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		}, nil
+	}}
+	srv := NewServer(Config{ImageHashes: mockHashes})
+	req := httptest.NewRequest("GET", "/manage_images", http.NoBody)
+	w := httptest.NewRecorder()
+	srv.htmlManageImageHashesHandler(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Manage Spam Images")
+	assert.Contains(t, body, "Image Hashes (1)")
+	assert.Contains(t, body, "ff00ff00ff00ff00")
+	assert.Contains(t, body, "spammer")
+	assert.Contains(t, body, "2024-05-01 10:00:00")
+}
+
+func TestServer_getReviewsHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockReviews := &mocks.ReviewsMock{ListFunc: func(ctx context.Context) ([]storage.Review, error) {
+			return []storage.Review{{ID: 1, UserID: 123, UserName: "user", Text: "borderline", Status: storage.ReviewPending}}, nil
+		}}
+		srv := NewServer(Config{Reviews: mockReviews})
+		req := httptest.NewRequest("GET", "/reviews", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.getReviewsHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Reviews []storage.Review `json:"reviews"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Reviews, 1)
+		assert.Equal(t, "borderline", resp.Reviews[0].Text)
+		assert.Equal(t, storage.ReviewPending, resp.Reviews[0].Status)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockReviews := &mocks.ReviewsMock{ListFunc: func(ctx context.Context) ([]storage.Review, error) {
+			return nil, errors.New("db error")
+		}}
+		srv := NewServer(Config{Reviews: mockReviews})
+		req := httptest.NewRequest("GET", "/reviews", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.getReviewsHandler(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "can't get reviews")
+	})
+}
+
+func TestServer_htmlReviewQueueHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockReviews := &mocks.ReviewsMock{ListFunc: func(ctx context.Context) ([]storage.Review, error) {
+			return []storage.Review{
+				{ID: 2, UserID: 124, UserName: "user2", Text: "pending text", Status: storage.ReviewPending,
+					Checks: []spamcheck.Response{{Name: "classifier", Details: "probability of spam: 55.00%, suspicious", Suspicious: true},
+						{Name: "emoji", Details: "0/2"}}},
+				{ID: 1, UserID: 123, UserName: "user1", Text: "approved text", Status: storage.ReviewApproved, ResolvedBy: "admin"},
+			}, nil
+		}}
+		srv := NewServer(Config{Reviews: mockReviews})
+		req := httptest.NewRequest("GET", "/review_queue", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.htmlReviewQueueHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, "Reviews (1 pending of 2)")
+		assert.Contains(t, body, "pending text")
+		assert.Contains(t, body, "classifier: probability of spam: 55.00%, suspicious")
+		assert.NotContains(t, body, "emoji: 0/2", "only suspicious checks listed")
+		assert.Contains(t, body, "approved text")
+		assert.Contains(t, body, "by admin")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockReviews := &mocks.ReviewsMock{ListFunc: func(ctx context.Context) ([]storage.Review, error) {
+			return nil, errors.New("db error")
+		}}
+		srv := NewServer(Config{Reviews: mockReviews})
+		req := httptest.NewRequest("GET", "/review_queue", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.htmlReviewQueueHandler(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestServer_getReputationHandler(t *testing.T) {
+	mockReputation := &mocks.ReputationMock{GetFunc: func(ctx context.Context, userID int64) (storage.UserReputation, error) {
+		if userID == 500 {
+			return storage.UserReputation{}, errors.New("db error")
+		}
+		return storage.UserReputation{UserID: userID, UserName: "user", Messages: 10, ReportsUpheld: 1}, nil
+	}}
+	srv := NewServer(Config{Reputation: mockReputation})
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reputation/123", http.NoBody)
+		req.SetPathValue("user_id", "123")
+		w := httptest.NewRecorder()
+		srv.getReputationHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Reputation storage.UserReputation `json:"reputation"`
+			Score      int                    `json:"score"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, int64(123), resp.Reputation.UserID)
+		assert.Equal(t, 10, resp.Reputation.Messages)
+		assert.Equal(t, 15, resp.Score)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reputation/abc", http.NoBody)
+		req.SetPathValue("user_id", "abc")
+		w := httptest.NewRecorder()
+		srv.getReputationHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reputation/500", http.NoBody)
+		req.SetPathValue("user_id", "500")
+		w := httptest.NewRecorder()
+		srv.getReputationHandler(w, req)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "can't get reputation")
+	})
+}
+
+func TestServer_htmlUserProfileHandler(t *testing.T) {
+	mockReputation := &mocks.ReputationMock{GetFunc: func(ctx context.Context, userID int64) (storage.UserReputation, error) {
+		return storage.UserReputation{UserID: userID, UserName: "spammer", Messages: 3, Spam: 1, Warnings: 2}, nil
+	}}
+	mockSpam := &mocks.DetectedSpamMock{FindByUserIDFunc: func(ctx context.Context, userID int64) (*storage.DetectedSpamInfo, error) {
+		return &storage.DetectedSpamInfo{UserID: userID, Text: "buy crypto now",
+			Checks: []spamcheck.Response{{Name: "stopword", Spam: true, Details: "buy crypto"}}}, nil
+	}}
+	mockDetector := &mocks.DetectorMock{ApprovedUsersFunc: func() []approved.UserInfo {
+		return []approved.UserInfo{{UserID: "124"}}
+	}}
+	srv := NewServer(Config{Reputation: mockReputation, DetectedSpam: mockSpam, Detector: mockDetector})
+
+	t.Run("lookup form only", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/user_profile", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.htmlUserProfileHandler(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `name="user_id"`)
+		assert.NotContains(t, w.Body.String(), "Messages checked")
+		assert.Empty(t, mockReputation.GetCalls())
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/user_profile?user_id=123", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.htmlUserProfileHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, "spammer (123)")
+		assert.Contains(t, body, "score -38")
+		assert.Contains(t, body, "<tr><th>Warnings</th><td>2</td></tr>")
+		assert.Contains(t, body, "buy crypto now")
+		assert.Contains(t, body, "stopword: buy crypto")
+		assert.NotContains(t, body, ">approved<")
+	})
+
+	t.Run("approved user", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/user_profile?user_id=124", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.htmlUserProfileHandler(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), ">approved<")
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		mockReputation.ResetGetCalls()
+		req := httptest.NewRequest("GET", "/user_profile?user_id=abc", http.NoBody)
+		w := httptest.NewRecorder()
+		srv.htmlUserProfileHandler(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid user id &#34;abc&#34;")
+		assert.Empty(t, mockReputation.GetCalls())
+	})
+}
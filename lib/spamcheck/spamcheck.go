@@ -7,15 +7,16 @@ import (
 
 // Request is a request to check a message for spam.
 type Request struct {
-	Msg       string   `json:"msg"`             // message to check, includes any appended quoted/reply-to context
-	Quote     string   `json:"quote,omitempty"` // quoted/reply-to text appended to Msg, empty when the message has none
-	UserID    string   `json:"user_id"`         // user id
-	UserName  string   `json:"user_name"`       // user name
-	FirstName string   `json:"first_name"`      // user's first name
-	LastName  string   `json:"last_name"`       // user's last name
-	IsPremium bool     `json:"is_premium"`      // true if user has telegram premium
-	Meta      MetaData `json:"meta"`            // meta-info, provided by the client
-	CheckOnly bool     `json:"check_only"`      // if true, only check the message, do not write newly approved user to the database
+	Msg       string   `json:"msg"`                  // message to check, includes any appended quoted/reply-to context
+	Quote     string   `json:"quote,omitempty"`      // quoted/reply-to text appended to Msg, empty when the message has none
+	ImageText string   `json:"image_text,omitempty"` // text extracted from attached images (OCR), empty if none
+	UserID    string   `json:"user_id"`              // user id
+	UserName  string   `json:"user_name"`            // user name
+	FirstName string   `json:"first_name"`           // user's first name
+	LastName  string   `json:"last_name"`            // user's last name
+	IsPremium bool     `json:"is_premium"`           // true if user has telegram premium
+	Meta      MetaData `json:"meta"`                 // meta-info, provided by the client
+	CheckOnly bool     `json:"check_only"`           // if true, only check the message, do not write newly approved user to the database
 }
 
 // AuthoredText returns the text the user themselves wrote, excluding any quoted or
@@ -31,6 +32,18 @@ func (r *Request) AuthoredText() string {
 	return strings.TrimSuffix(r.Msg, "\n"+r.Quote)
 }
 
+// ContentText returns the text for content checks (stop words, similarity, classifier and LLMs):
+// Msg followed by the text extracted from images. With no ImageText it returns Msg unchanged.
+func (r *Request) ContentText() string {
+	if r.ImageText == "" {
+		return r.Msg
+	}
+	if r.Msg == "" {
+		return r.ImageText
+	}
+	return r.Msg + "\n" + r.ImageText
+}
+
 // MetaData is a meta-info about the message, provided by the client.
 type MetaData struct {
 	Images      int  `json:"images"`       // number of images in the message
@@ -43,8 +56,11 @@ type MetaData struct {
 	HasContact  bool `json:"has_contact"`  // true if the message has a shared contact
 	HasGiveaway bool `json:"has_giveaway"` // true if the message is a giveaway
 	// HasExternalReply is true if the message replies to a message from another chat (external_reply)
-	HasExternalReply bool `json:"has_external_reply"`
-	MessageID        int  `json:"message_id"` // telegram message ID
+	HasExternalReply bool   `json:"has_external_reply"`
+	MessageID        int    `json:"message_id"`           // telegram message ID
+	ImageHash        string `json:"image_hash,omitempty"` // perceptual hash of the attached image, empty if none
+	// URLs are link targets not visible in the message text, e.g. urls of text links
+	URLs []string `json:"urls,omitempty"`
 }
 
 func (r *Request) String() string {
@@ -64,6 +80,9 @@ type Response struct {
 	Details        string `json:"details"`                    // details of the check
 	Error          error  `json:"-"`                          // error message, if any. Do not serialize it
 	ExtraDeleteIDs []int  `json:"extra_delete_ids,omitempty"` // additional message IDs to delete when spam detected
+
+	Score      float64 `json:"score,omitempty"`      // weighted contribution of the check, set in scoring mode only
+	Suspicious bool    `json:"suspicious,omitempty"` // borderline result, the message should be reviewed by admins
 }
 
 func (r *Response) String() string {
@@ -71,6 +90,9 @@ func (r *Response) String() string {
 	if r.Spam {
 		spamOrHam = "spam"
 	}
+	if r.Score != 0 {
+		return fmt.Sprintf("%s: %s, %s, score: %.2f", r.Name, spamOrHam, r.Details, r.Score)
+	}
 	return fmt.Sprintf("%s: %s, %s", r.Name, spamOrHam, r.Details)
 }
 
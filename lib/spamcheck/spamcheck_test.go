@@ -30,6 +30,16 @@ func TestResponse_String(t *testing.T) {
 			},
 			expected: "name2: ham, details",
 		},
+		{
+			name: "test with score",
+			input: &Response{
+				Name:    "name3",
+				Spam:    true,
+				Details: "details",
+				Score:   1.5,
+			},
+			expected: "name3: spam, details, score: 1.50",
+		},
 	}
 
 	for _, tt := range tests {
@@ -109,6 +119,25 @@ func TestRequest_AuthoredText(t *testing.T) {
 	}
 }
 
+func TestRequest_ContentText(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  Request
+		expected string
+	}{
+		{name: "no image text returns Msg", request: Request{Msg: "hello"}, expected: "hello"},
+		{name: "image text appended to Msg", request: Request{Msg: "hello", ImageText: "buy crypto"}, expected: "hello\nbuy crypto"},
+		{name: "image only", request: Request{ImageText: "buy crypto"}, expected: "buy crypto"},
+		{name: "empty", request: Request{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.request.ContentText())
+		})
+	}
+}
+
 func TestChecksToString(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,26 @@
+package textnorm
+
+// confusables maps letters of other scripts, and a few Latin letter variants, to the Latin letters they look like.
+// It is a subset of Unicode confusables (UTS #39 confusables.txt) covering lookalikes used to obfuscate spam.
+// Fullwidth, mathematical and other compatibility forms are not listed, they are folded by NFKD decomposition.
+var confusables = map[rune]rune{
+	// cyrillic
+	'А': 'a', 'а': 'a', 'В': 'b', 'С': 'c', 'с': 'c', 'Ԁ': 'd', 'ԁ': 'd', 'Е': 'e', 'е': 'e', 'Н': 'h', 'Һ': 'h', 'һ': 'h',
+	'І': 'i', 'і': 'i', 'Ј': 'j', 'ј': 'j', 'К': 'k', 'к': 'k', 'Ӏ': 'l', 'ӏ': 'l', 'М': 'm', 'О': 'o', 'о': 'o',
+	'Р': 'p', 'р': 'p', 'Ԛ': 'q', 'ԛ': 'q', 'Ѕ': 's', 'ѕ': 's', 'Т': 't', 'т': 't', 'Ԝ': 'w', 'ԝ': 'w', 'Х': 'x',
+	'х': 'x', 'У': 'y', 'у': 'y', 'Ү': 'y', 'ү': 'y',
+
+	// greek
+	'Α': 'a', 'α': 'a', 'Β': 'b', 'Ϲ': 'c', 'ϲ': 'c', 'Ε': 'e', 'Η': 'h', 'Ι': 'i', 'ι': 'i', 'Κ': 'k', 'κ': 'k',
+	'Μ': 'm', 'Ν': 'n', 'Ο': 'o', 'ο': 'o', 'Ρ': 'p', 'ρ': 'p', 'Τ': 't', 'υ': 'u', 'ν': 'v', 'Χ': 'x', 'χ': 'x',
+	'Υ': 'y', 'γ': 'y', 'Ζ': 'z',
+
+	// armenian
+	'ց': 'g', 'հ': 'h', 'ո': 'n', 'Օ': 'o', 'օ': 'o', 'զ': 'q', 'Տ': 's', 'Ս': 'u', 'ս': 'u',
+
+	// latin variants without decomposition
+	'ɑ': 'a', 'đ': 'd', 'ɡ': 'g', 'ħ': 'h', 'ı': 'i', 'ł': 'l', 'ø': 'o', 'ᴠ': 'v', 'ᴡ': 'w', 'ʏ': 'y', 'ᴢ': 'z',
+}
+
+// leet maps digits and symbols used instead of letters
+var leet = map[rune]rune{'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's'}
@@ -0,0 +1,213 @@
+// Package textnorm folds text obfuscated to evade spam checks to a canonical form. It handles lookalike letters
+// from other scripts, invisible characters, combining marks, leetspeak and spaced-out letters, so "crypт0",
+// "c r y p t o" and "ｃｒｙｐｔｏ" are all normalized to "crypto".
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// minSpacedLetters is the min number of single letters separated by spaces to be joined into a word
+const minSpacedLetters = 3
+
+// Normalize returns the lowercased skeleton of the text with single spaces between words:
+//   - compatibility forms, e.g. fullwidth and mathematical letters, are decomposed (NFKD)
+//   - combining marks, control, format and invisible characters are removed
+//   - lookalike letters of other scripts are replaced with Latin letters they look like
+//   - digits and symbols used as letters are replaced in words with at least two letters, e.g. "b1tc0in"
+//   - three or more single letters separated by spaces are joined, e.g. "c r y p t o"
+//
+// The result is meant for comparison only, both sides should be normalized, e.g. message and stop words.
+func Normalize(s string) string {
+	// lookalikes are replaced before decomposition too, as it turns some of them to letters
+	// which are not lookalikes anymore, e.g. Greek lunate sigma "Ϲ" to sigma "Σ"
+	s = strings.Map(func(r rune) rune {
+		if p, ok := confusables[r]; ok {
+			return p
+		}
+		return r
+	}, s)
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		case isInvisible(r):
+			continue
+		default:
+			sb.WriteRune(fold(r))
+		}
+	}
+
+	words := strings.Fields(sb.String())
+	for i, w := range words {
+		words[i] = deleet(w)
+	}
+	return strings.Join(joinSpaced(words), " ")
+}
+
+// ObfuscatedWords returns words mixing letters of different scripts, where all letters not in one of the scripts
+// are lookalikes, e.g. "crypт" with Cyrillic "т" or "зaработок" with Latin "a". Words with letters of another
+// script which don't look like letters of the main one, e.g. "iPhoneы", are not considered obfuscated.
+func ObfuscatedWords(s string) []string {
+	var res []string
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '-' }) {
+		if isObfuscated(w) {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// isObfuscated checks if the word has letters of at least two scripts and either all non-Latin letters
+// look like Latin ones, or all letters not in the main script, the one with most letters, look like its letters
+func isObfuscated(word string) bool {
+	letters := []rune{}
+	scripts := map[*unicode.RangeTable]int{}
+	for _, r := range word {
+		if t := script(r); t != nil {
+			letters = append(letters, r)
+			scripts[t]++
+		}
+	}
+	if len(scripts) < 2 || len(letters) < 3 {
+		return false
+	}
+
+	maxCount := 0
+	for _, n := range scripts {
+		maxCount = max(maxCount, n)
+	}
+	for main, n := range scripts {
+		if n < maxCount && main != unicode.Latin {
+			continue // latin is checked anyway, to catch latin words written mostly with lookalikes, e.g. "сrурtо"
+		}
+		lookalikes := true
+		for _, r := range letters {
+			if !unicode.Is(main, r) && !isLookalike(r, main) {
+				lookalikes = false
+				break
+			}
+		}
+		if lookalikes {
+			return true
+		}
+	}
+	return false
+}
+
+// script returns the script of the letter if it is one of the scripts with lookalikes, nil otherwise
+func script(r rune) *unicode.RangeTable {
+	if !unicode.IsLetter(r) {
+		return nil
+	}
+	for _, t := range []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek, unicode.Armenian} {
+		if unicode.Is(t, r) {
+			return t
+		}
+	}
+	return nil
+}
+
+// isLookalike checks if the letter of another script looks like a letter of the main script:
+// for Latin it must be a confusable, for other scripts it must be a Latin letter some confusable of this script looks like
+func isLookalike(r rune, main *unicode.RangeTable) bool {
+	if main == unicode.Latin {
+		_, ok := confusables[r]
+		return ok
+	}
+	if !unicode.Is(unicode.Latin, r) {
+		return false
+	}
+	_, ok := prototypes[main][unicode.ToLower(r)]
+	return ok
+}
+
+// prototypes are Latin letters confusables look like, by script of confusables
+var prototypes = func() map[*unicode.RangeTable]map[rune]struct{} {
+	res := map[*unicode.RangeTable]map[rune]struct{}{}
+	for r, p := range confusables {
+		t := script(r)
+		if t == unicode.Latin {
+			continue
+		}
+		if res[t] == nil {
+			res[t] = map[rune]struct{}{}
+		}
+		res[t][p] = struct{}{}
+	}
+	return res
+}()
+
+// isInvisible checks if the rune is a combining mark, control, format or invisible filler character
+func isInvisible(r rune) bool {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cc, unicode.Cf) {
+		return true
+	}
+	switch r {
+	case 'ᅟ', 'ᅠ', 'ㅤ', 'ﾠ': // hangul fillers, rendered as blank
+		return true
+	}
+	return (r >= 0x200B && r <= 0x200F) || (r >= 0x2060 && r <= 0x206F)
+}
+
+// fold returns the Latin letter the rune looks like, or the lowercased rune itself
+func fold(r rune) rune {
+	if p, ok := confusables[r]; ok {
+		return p
+	}
+	r = unicode.ToLower(r)
+	if p, ok := confusables[r]; ok {
+		return p
+	}
+	return r
+}
+
+// deleet replaces digits and symbols used as letters, only in words with at least two letters,
+// so numbers, prices and dates are kept as is
+func deleet(word string) string {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < 2 {
+		return word
+	}
+	return strings.Map(func(r rune) rune {
+		if l, ok := leet[r]; ok {
+			return l
+		}
+		return r
+	}, word)
+}
+
+// joinSpaced joins runs of minSpacedLetters or more single-letter words into one word
+func joinSpaced(words []string) []string {
+	isLetter := func(w string) bool {
+		runes := []rune(w)
+		return len(runes) == 1 && unicode.IsLetter(runes[0])
+	}
+
+	res := make([]string, 0, len(words))
+	for i := 0; i < len(words); {
+		j := i
+		for j < len(words) && isLetter(words[j]) {
+			j++
+		}
+		if j-i >= minSpacedLetters {
+			res = append(res, strings.Join(words[i:j], ""))
+			i = j
+			continue
+		}
+		res = append(res, words[i])
+		i++
+	}
+	return res
+}
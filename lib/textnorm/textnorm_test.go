@@ -0,0 +1,80 @@
+package textnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain text", in: "Buy Crypto Now", want: "buy crypto now"},
+		{name: "cyrillic lookalikes", in: "Вuy сrурtо nоw", want: "buy crypto now"},
+		{name: "greek lookalikes", in: "ΒΙΤϹΟΙΝ", want: "bitcoin"},
+		{name: "lookalike and leet", in: "crypт0 inv3stm3nt", want: "crypto investment"},
+		{name: "leet symbols", in: "fr33 $pins @ll d4y", want: "free spins all day"},
+		{name: "numbers kept", in: "100 usd, 24/7, 2024г, 5k", want: "100 usd, 24/7, 2024г, 5k"},
+		{name: "zero width and soft hyphen", in: "cry​p‍to fre­e", want: "crypto free"},
+		{name: "combining marks", in: "crýptó ïnvèst", want: "crypto invest"},
+		{name: "zalgo", in: "c̶r̶y̶p̶t̶o̶", want: "crypto"},
+		{name: "fullwidth", in: "ｃｒｙｐｔｏ", want: "crypto"},
+		{name: "mathematical bold", in: "𝐜𝐫𝐲𝐩𝐭𝐨 𝐟𝐫𝐞𝐞", want: "crypto free"},
+		{name: "spaced letters", in: "buy c r y p t o now", want: "buy crypto now"},
+		{name: "two spaced letters kept", in: "a b testing", want: "a b testing"},
+		{name: "spaced lookalikes", in: "с r у р т о", want: "crypto"},
+		{name: "whitespace collapsed", in: " hello\n\tworld  ", want: "hello world"},
+		{name: "hangul filler", in: "freeㅤmoney", want: "freemoney"},
+		{name: "empty", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.in))
+		})
+	}
+
+	t.Run("idempotent", func(t *testing.T) {
+		for _, tt := range tests {
+			assert.Equal(t, Normalize(tt.in), Normalize(Normalize(tt.in)), tt.name)
+		}
+	})
+
+	t.Run("same skeleton for obfuscated and plain cyrillic", func(t *testing.T) {
+		assert.Equal(t, Normalize("Заработок в интернете"), Normalize("Зaрaбoтoк в интeрнeтe"))
+	})
+}
+
+func TestObfuscatedWords(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "plain latin", in: "buy crypto now", want: nil},
+		{name: "plain cyrillic", in: "купить крипту сейчас", want: nil},
+		{name: "cyrillic in latin word", in: "buy crypт now", want: []string{"crypт"}},
+		{name: "latin in cyrillic word", in: "быстрый зaработок", want: []string{"зaработок"}},
+		{name: "several words", in: "Вuy сrурtо nоw-please", want: []string{"Вuy", "сrурtо", "nоw"}},
+		{name: "not lookalike", in: "iPhoneы", want: nil},
+		{name: "latin not lookalike of cyrillic", in: "привfет", want: nil},
+		{name: "short word", in: "оk", want: nil},
+		{name: "different words", in: "telegram канал", want: nil},
+		{name: "digits ignored", in: "crypto2025", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ObfuscatedWords(tt.in))
+		})
+	}
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	msg := "🔥 EXCLUSIVE OFFER! Вuy сrурtо n0w and get 50% OFF! Limited time offer. c l i c k here: http://example.com"
+	b.ReportAllocs()
+	for b.Loop() {
+		Normalize(msg)
+	}
+}
@@ -0,0 +1,105 @@
+package tgspam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// anthropicChecker checks if a text is spam with Anthropic Messages API
+type anthropicChecker struct {
+	client HTTPClient
+	params AnthropicConfig
+}
+
+// AnthropicConfig contains parameters for anthropicChecker
+type AnthropicConfig struct {
+	APIBase           string   // api base url, https://api.anthropic.com if empty
+	Token             string   // api key
+	Model             string   // model name
+	MaxTokensResponse int      // max tokens in the response
+	MaxSymbolsRequest int      // max request length in symbols
+	SystemPrompt      string   // system prompt for spam detection
+	CustomPrompts     []string // additional prompts for specific spam patterns
+	RetryCount        int      // number of retries on failure
+}
+
+// anthropicVersion is the Messages API version sent in anthropic-version header
+const anthropicVersion = "2023-06-01"
+
+// NewAnthropicChecker makes an LLMChecker for Anthropic Messages API
+func NewAnthropicChecker(client HTTPClient, params AnthropicConfig) LLMChecker {
+	if params.APIBase == "" {
+		params.APIBase = "https://api.anthropic.com"
+	}
+	params.APIBase = strings.TrimSuffix(params.APIBase, "/")
+	if params.SystemPrompt == "" {
+		params.SystemPrompt = defaultPrompt
+	}
+	if params.Model == "" {
+		params.Model = "claude-haiku-4-5"
+	}
+	if params.MaxTokensResponse == 0 {
+		params.MaxTokensResponse = 1024
+	}
+	if params.MaxSymbolsRequest == 0 {
+		params.MaxSymbolsRequest = 8192
+	}
+	if params.RetryCount <= 0 {
+		params.RetryCount = 1
+	}
+	return &anthropicChecker{client: client, params: params}
+}
+
+// Check checks if a text is spam using Anthropic API
+func (a *anthropicChecker) Check(ctx context.Context, msg string,
+	history []spamcheck.Request) (spam bool, cr spamcheck.Response) {
+	return runLLMProviderCheck(ctx, "anthropic", "Anthropic", a.params.RetryCount, msg, history, a.sendRequest)
+}
+
+func (a *anthropicChecker) sendRequest(ctx context.Context, msg string) (response llmResponse, err error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	req := struct {
+		Model     string    `json:"model"`
+		MaxTokens int       `json:"max_tokens"`
+		System    string    `json:"system"`
+		Messages  []message `json:"messages"`
+	}{
+		Model:     a.params.Model,
+		MaxTokens: a.params.MaxTokensResponse,
+		System:    buildLLMSystemPrompt(a.params.SystemPrompt, a.params.CustomPrompts),
+		Messages:  []message{{Role: "user", Content: truncateLLMRequest(msg, a.params.MaxSymbolsRequest)}},
+	}
+	resp := struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}{}
+	headers := map[string]string{"x-api-key": a.params.Token, "anthropic-version": anthropicVersion}
+	if err := postLLMRequest(ctx, a.client, a.params.APIBase+"/v1/messages", headers, req, &resp); err != nil {
+		return llmResponse{}, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	var content strings.Builder
+	for _, c := range resp.Content {
+		if c.Type == "text" {
+			content.WriteString(c.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return llmResponse{}, fmt.Errorf("no text content in response")
+	}
+
+	text := extractLLMJSON(content.String())
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		return llmResponse{}, fmt.Errorf("can't unmarshal response: %s - %w", text, err)
+	}
+	return response, nil
+}
@@ -0,0 +1,74 @@
+package tgspam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropicChecker_Check(t *testing.T) {
+	t.Run("spam response", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/messages", r.URL.Path)
+			assert.Equal(t, "secret", r.Header.Get("x-api-key"))
+			assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+			var req struct {
+				Model     string `json:"model"`
+				MaxTokens int    `json:"max_tokens"`
+				System    string `json:"system"`
+				Messages  []struct {
+					Role    string `json:"role"`
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "claude-test", req.Model)
+			assert.Equal(t, 1024, req.MaxTokens)
+			assert.Contains(t, req.System, "1. crypto scams")
+			require.Len(t, req.Messages, 1)
+			assert.Equal(t, "user", req.Messages[0].Role)
+			assert.Equal(t, "buy crypto", req.Messages[0].Content)
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"` +
+				"```json\\n{\\\"spam\\\": true, \\\"reason\\\": \\\"crypto scam.\\\", \\\"confidence\\\": 90}\\n```" + `"}]}`))
+		}))
+		defer ts.Close()
+
+		checker := NewAnthropicChecker(ts.Client(), AnthropicConfig{APIBase: ts.URL + "/", Token: "secret",
+			Model: "claude-test", CustomPrompts: []string{"crypto scams"}})
+		spam, resp := checker.Check(context.Background(), "buy crypto", nil)
+		assert.True(t, spam)
+		assert.Equal(t, "anthropic", resp.Name)
+		assert.Equal(t, "crypto scam, confidence: 90%", resp.Details)
+		assert.NoError(t, resp.Error)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"type":"error"}`, http.StatusUnauthorized)
+		}))
+		defer ts.Close()
+
+		checker := NewAnthropicChecker(ts.Client(), AnthropicConfig{APIBase: ts.URL, RetryCount: 2})
+		spam, resp := checker.Check(context.Background(), "hello", nil)
+		assert.False(t, spam)
+		require.Error(t, resp.Error)
+		assert.Contains(t, resp.Details, "Anthropic error: failed to create message: unexpected response status 401")
+	})
+
+	t.Run("no text content", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"content":[]}`))
+		}))
+		defer ts.Close()
+
+		checker := NewAnthropicChecker(ts.Client(), AnthropicConfig{APIBase: ts.URL})
+		spam, resp := checker.Check(context.Background(), "hello", nil)
+		assert.False(t, spam)
+		require.EqualError(t, resp.Error, "no text content in response")
+	})
+}
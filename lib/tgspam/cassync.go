@@ -0,0 +1,120 @@
+package tgspam
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate moq --out mocks/cas_store.go --pkg mocks --skip-ensure --with-resets . CASStore
+
+// CASStore is an interface for storing CAS banned users loaded from the CAS export.
+// Implemented by *storage.CASBans.
+type CASStore interface {
+	Replace(ctx context.Context, userIDs []int64) error // replace all stored banned users
+}
+
+// CASSyncer periodically downloads the CAS export with all banned users and stores it in CASStore.
+// Together with Detector.WithCASMirror it allows answering CAS checks locally.
+type CASSyncer struct {
+	API        string        // CAS API URL, the export is loaded from {API}/export.csv
+	UserAgent  string        // User-Agent header value, set only if non-empty
+	HTTPClient HTTPClient    // http client to use for requests, should have a timeout suitable for a large download
+	Store      CASStore      // storage for banned users
+	Interval   time.Duration // interval between syncs
+}
+
+// Run syncs the CAS export immediately and then every Interval, until the context is canceled.
+// Sync errors are logged, the previously synced list stays in use.
+func (s *CASSyncer) Run(ctx context.Context) {
+	if s.Interval <= 0 {
+		log.Printf("[WARN] CAS sync interval is not set, sync disabled")
+		return
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		st := time.Now()
+		count, err := s.Sync(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("[WARN] failed to sync CAS export: %v", err)
+		}
+		if err == nil {
+			log.Printf("[INFO] CAS export synced, %d banned users, took %v", count, time.Since(st).Round(time.Millisecond))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync downloads the CAS export, parses banned user IDs and replaces the stored list.
+// Returns the number of loaded user IDs.
+func (s *CASSyncer) Sync(ctx context.Context) (int, error) {
+	reqURL := strings.TrimSuffix(s.API, "/") + "/export.csv"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make request %s: %w", reqURL, err)
+	}
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request %s: %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, reqURL)
+	}
+
+	ids, err := parseCasExport(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", reqURL, err)
+	}
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("no banned users in %s", reqURL)
+	}
+	if err := s.Store.Replace(ctx, ids); err != nil {
+		return 0, fmt.Errorf("failed to store CAS banned users: %w", err)
+	}
+	return len(ids), nil
+}
+
+// parseCasExport reads user IDs from the first column of the CAS export csv.
+// Header and other lines without a numeric user ID are skipped.
+func parseCasExport(r io.Reader) ([]int64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	cr.LazyQuotes = true
+
+	var res []int64
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) == 0 {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSpace(rec[0]), 10, 64)
+		if err != nil {
+			continue // header or garbage line
+		}
+		res = append(res, id)
+	}
+}
@@ -0,0 +1,114 @@
+package tgspam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/tgspam/mocks"
+)
+
+const casExportSample = `user_id,offenses,time_added
+100,1,2024-01-01T00:00:00.000Z
+200,3,2024-01-02T00:00:00.000Z
+
+300,1,2024-01-03T00:00:00.000Z
+bad,1,2024-01-03T00:00:00.000Z
+`
+
+func TestCASSyncer_Sync(t *testing.T) {
+	t.Run("load export", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/export.csv", r.URL.Path)
+			assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+			_, _ = w.Write([]byte(casExportSample))
+		}))
+		defer ts.Close()
+
+		store := &mocks.CASStoreMock{ReplaceFunc: func(ctx context.Context, userIDs []int64) error { return nil }}
+		s := CASSyncer{API: ts.URL + "/", UserAgent: "test-agent", HTTPClient: ts.Client(), Store: store}
+		count, err := s.Sync(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 3, count)
+		require.Len(t, store.ReplaceCalls(), 1)
+		assert.Equal(t, []int64{100, 200, 300}, store.ReplaceCalls()[0].UserIDs)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer ts.Close()
+
+		store := &mocks.CASStoreMock{}
+		s := CASSyncer{API: ts.URL, HTTPClient: ts.Client(), Store: store}
+		_, err := s.Sync(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected status 502")
+		assert.Empty(t, store.ReplaceCalls())
+	})
+
+	t.Run("empty export", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("user_id,offenses,time_added\n"))
+		}))
+		defer ts.Close()
+
+		store := &mocks.CASStoreMock{}
+		s := CASSyncer{API: ts.URL, HTTPClient: ts.Client(), Store: store}
+		_, err := s.Sync(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no banned users")
+		assert.Empty(t, store.ReplaceCalls())
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(casExportSample))
+		}))
+		defer ts.Close()
+
+		store := &mocks.CASStoreMock{ReplaceFunc: func(ctx context.Context, userIDs []int64) error { return errors.New("db error") }}
+		s := CASSyncer{API: ts.URL, HTTPClient: ts.Client(), Store: store}
+		_, err := s.Sync(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db error")
+	})
+}
+
+func TestCASSyncer_Run(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		if n == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable) // failed sync doesn't stop the loop
+			return
+		}
+		_, _ = fmt.Fprintf(w, "user_id\n%s\n", strings.Repeat("1", int(n)))
+	}))
+	defer ts.Close()
+
+	store := &mocks.CASStoreMock{ReplaceFunc: func(ctx context.Context, userIDs []int64) error { return nil }}
+	s := CASSyncer{API: ts.URL, HTTPClient: ts.Client(), Store: store, Interval: 10 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return len(store.ReplaceCalls()) >= 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, []int64{1}, store.ReplaceCalls()[0].UserIDs, "first sync done immediately")
+	assert.Equal(t, []int64{111}, store.ReplaceCalls()[1].UserIDs)
+}
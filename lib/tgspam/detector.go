@@ -11,6 +11,8 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -19,11 +21,13 @@ import (
 	"unicode"
 
 	"github.com/forPelevin/gomoji"
+	cache "github.com/go-pkgz/expirable-cache/v3"
 	"github.com/go-pkgz/repeater"
 	"github.com/go-pkgz/repeater/strategy"
 
 	"github.com/umputun/tg-spam/lib/approved"
 	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/textnorm"
 	"github.com/umputun/tg-spam/lib/tgspam/plugin"
 )
 
@@ -31,6 +35,8 @@ import (
 //go:generate moq --out mocks/http_client.go --pkg mocks --skip-ensure --with-resets . HTTPClient
 //go:generate moq --out mocks/user_storage.go --pkg mocks --skip-ensure --with-resets . UserStorage
 //go:generate moq --out mocks/message_counter.go --pkg mocks --skip-ensure --with-resets . MessageCounter
+//go:generate moq --out mocks/approval_guard.go --pkg mocks --skip-ensure --with-resets . ApprovalGuard
+//go:generate moq --out mocks/cas_mirror.go --pkg mocks --skip-ensure --with-resets . CASMirror
 //go:generate moq --out mocks/lua_plugin_engine.go --pkg mocks --skip-ensure --with-resets . LuaPluginEngine
 
 // Detector is a spam detector, thread-safe.
@@ -40,13 +46,19 @@ type Detector struct {
 	classifier        classifier
 	openaiChecker     *openAIChecker
 	geminiChecker     *geminiChecker
+	llmCheckers       []detectorLLMCheck // named LLM checkers added with WithLLMChecker, in order of registration
+	llmCache          LLMCache           // cache of LLM verdicts, nil disables caching
 	duplicateDetector *duplicateDetector
 	reactionDetector  *reactionDetector
 	metaChecks        []MetaCheck
 	luaChecks         []plugin.ResultCheck // separate field for Lua plugin checks
-	tokenizedSpam     []map[string]int
+	similarityIndex   similarityIndex      // inverted index of tokenized spam samples, used by the similarity check
 	approvedUsers     map[string]approved.UserInfo
 	stopWords         []string
+	blockedDomains    []string
+	allowedDomains    []string
+	shortenerClient   HTTPClient                    // client resolving short links, doesn't follow redirects
+	shortURLs         cache.Cache[string, *url.URL] // resolved short links, nil value for links without redirect
 	excludedTokens    map[string]struct{}
 	luaEngine         LuaPluginEngine
 
@@ -54,6 +66,10 @@ type Detector struct {
 	hamSamplesUpd  SampleUpdater
 	userStorage    UserStorage
 	messageCounter MessageCounter
+	approvalGuard  ApprovalGuard
+
+	casMirror       CASMirror     // local mirror of CAS banned users, nil if not used
+	casMirrorMaxAge time.Duration // mirror older than this answers only for banned users, 0 - never stale
 
 	// history of recent messages to keep in memory
 	// can be passed to checkers supporting history
@@ -87,13 +103,16 @@ type detectorLLMCheck struct {
 	veto bool
 	// number of recent ham messages to pass as context
 	historySize int
-	check       func(context.Context, string, []spamcheck.Request) (bool, spamcheck.Response) // provider check function
+	// provider, model and prompt identity, part of the verdict cache key
+	cacheID string
+	check   func(context.Context, string, []spamcheck.Request) (bool, spamcheck.Response) // provider check function
 }
 
 // llmCheckInput groups the per-message state passed to collectLLMCheck.
 type llmCheckInput struct {
 	req            spamcheck.Request    // original request, used for logging
 	cleanMsg       string               // sanitized message text to check
+	cacheMsg       string               // normalized message text, part of the verdict cache key
 	checks         []spamcheck.Response // accumulated check results so far
 	baseSpam       bool                 // base spam decision before LLM
 	isShortMessage bool                 // whether the message is below min length
@@ -116,13 +135,17 @@ type Config struct {
 	FirstMessagesCount  int              // number of first messages to check for spam
 	HTTPClient          HTTPClient       // http client to use for requests
 	MinSpamProbability  float64          // minimum spam probability to consider a message spam with classifier, if 0 - ignored
+	MinSuspiciousProb   float64          // minimum classifier spam probability to mark ham as suspicious, if 0 - ignored
 	OpenAIVeto          bool             // if true, openai vetos spam, otherwise vetos ham
 	OpenAIHistorySize   int              // history size for openai
 	GeminiVeto          bool             // if true, gemini vetos spam, otherwise vetos ham
 	GeminiHistorySize   int              // history size for gemini
 	LLMConsensus        LLMConsensusMode // how eligible LLM checks flip the base decision
+	LLMDisagreement     bool             // if true, ham with disagreeing LLM checks is marked as suspicious
 	LLMRequestTimeout   time.Duration    // timeout for individual LLM requests, if not set - 30s default
 	MultiLangWords      int              // if true, check for number of multi-lingual words
+	ObfuscatedWords     int              // number of words mixing scripts with lookalike letters to mark as spam, 0 disables
+	NormalizeText       bool             // normalize obfuscated text for stop words, similarity and classifier, see textnorm
 	StorageTimeout      time.Duration    // timeout for storage operations, if not set - no timeout
 
 	// ProhibitedScripts maps a unicode.Scripts name to its range table; a message with
@@ -157,6 +180,13 @@ type Config struct {
 		Window       time.Duration // time window for reaction spam detection
 	}
 
+	Domains struct {
+		ExpandShortURLs bool     // if true, resolve links of url shorteners with HEAD requests before matching domains
+		Shorteners      []string // shortener domains to resolve, DefaultShorteners used if empty
+	}
+
+	Scoring ScoringConfig // weighted scoring mode, if not enabled any spam check marks the message as spam
+
 	HistorySize int // history of recent messages to keep in memory
 }
 
@@ -181,6 +211,19 @@ type MessageCounter interface {
 	UserMessageIDs(ctx context.Context, userID string, limit int) ([]int, error)
 }
 
+// ApprovalGuard is an interface to hold back approval of users with bad history, e.g. recently warned ones.
+// Implemented by *storage.Reputation.
+type ApprovalGuard interface {
+	HoldApproval(ctx context.Context, userID string) (hold bool, reason string, err error)
+}
+
+// CASMirror is an interface for the local mirror of CAS banned users, synced from the CAS export.
+// Implemented by *storage.CASBans.
+type CASMirror interface {
+	IsBanned(ctx context.Context, userID int64) (bool, error)
+	SyncedAt() time.Time // time of the last successful sync, zero if never synced
+}
+
 // HTTPClient is an interface for http client, satisfied by http.Client.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -207,6 +250,8 @@ type LoadResult struct {
 	SpamSamples    int // number of spam samples
 	HamSamples     int // number of ham samples
 	StopWords      int // number of stop words (phrases)
+	BlockedDomains int // number of blocked domains
+	AllowedDomains int // number of allowed domains
 }
 
 // NewDetector makes a new Detector with the given config.
@@ -215,7 +260,6 @@ func NewDetector(p Config) *Detector {
 		Config:            p,
 		classifier:        newClassifier(),
 		approvedUsers:     make(map[string]approved.UserInfo),
-		tokenizedSpam:     []map[string]int{},
 		metaChecks:        []MetaCheck{},
 		luaChecks:         []plugin.ResultCheck{},
 		hamHistory:        spamcheck.NewLastRequests(p.HistorySize),
@@ -223,6 +267,8 @@ func NewDetector(p Config) *Detector {
 		duplicateDetector: newDuplicateDetector(p.DuplicateDetection.Threshold, p.DuplicateDetection.Window),
 		reactionDetector:  newReactionDetector(p.ReactionSpam.MaxReactions, p.ReactionSpam.Window),
 		luaEngine:         nil, // will be set with WithLuaEngine if needed
+		shortenerClient:   newShortenerClient(),
+		shortURLs:         cache.NewCache[string, *url.URL]().WithMaxKeys(shortURLsCacheSize).WithTTL(shortURLsCacheTTL).WithLRU(),
 	}
 	res.LLMConsensus = res.normalizeLLMConsensusMode(p.LLMConsensus)
 	// if FirstMessagesCount is set, FirstMessageOnly enforced to true.
@@ -240,7 +286,11 @@ func NewDetector(p Config) *Detector {
 // Check checks if a given message is spam. Returns true if spam and also returns a list of check results.
 func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Response) {
 
+	probs := checkScores{} // spam probabilities of classifier and similarity checks, used in scoring mode
 	isSpamDetected := func(cr []spamcheck.Response) bool {
+		if d.Scoring.Enabled {
+			return d.Scoring.score(cr, probs) >= d.Scoring.Threshold
+		}
 		for _, r := range cr {
 			if r.Spam {
 				return true
@@ -248,11 +298,24 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 		}
 		return false
 	}
+	// withScore adds the total score summary in scoring mode
+	withScore := func(cr []spamcheck.Response) []spamcheck.Response {
+		if !d.Scoring.Enabled {
+			return cr
+		}
+		return append(cr, d.Scoring.summary(d.Scoring.score(cr, probs)))
+	}
+
+	// short links resolved before taking the lock, network requests should not block detector updates
+	expandedLinks := d.expandShortLinks(req)
 
-	cleanMsg := d.cleanText(req.Msg)
 	d.lock.RLock()
 	defer d.lock.RUnlock()
 
+	// content checks see the text extracted from images as well, e.g. image-only scam posts.
+	// LLMs get the text before normalization, as folded lookalikes may look like obfuscation to them
+	cleanMsg, llmMsg := d.cleanText(req.ContentText()), stripInvisible(req.ContentText())
+
 	// check for duplicate messages FIRST - behavioral check that applies to all users
 	if d.duplicateDetector != nil {
 		cr = append(cr, d.duplicateDetector.check(req))
@@ -300,6 +363,14 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 		cr = append(cr, d.isStopWord(cleanMsg, req))
 	}
 
+	// check linked domains if any blocked or allowed domains are loaded
+	linksAllowed := false
+	if len(d.blockedDomains) > 0 || len(d.allowedDomains) > 0 {
+		var resp spamcheck.Response
+		resp, linksAllowed = d.isBlockedDomain(req, expandedLinks)
+		cr = append(cr, resp)
+	}
+
 	// check for emojis if max allowed emojis is set
 	if d.MaxAllowedEmoji >= 0 {
 		cr = append(cr, d.isManyEmojis(req.Msg))
@@ -307,7 +378,12 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 
 	// check for spam with meta-checks
 	for _, mc := range d.metaChecks {
-		cr = append(cr, mc(req))
+		resp := mc(req)
+		if linksAllowed && resp.Name == linksCheckName && resp.Spam {
+			// all links point to allowed domains, the number of links doesn't matter
+			resp = spamcheck.Response{Name: linksCheckName, Spam: false, Details: "links to allowed domains only"}
+		}
+		cr = append(cr, resp)
 	}
 
 	// check for spam with Lua plugin checks
@@ -328,6 +404,10 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 		cr = append(cr, d.isMultiLang(req.Msg))
 	}
 
+	if d.ObfuscatedWords > 0 {
+		cr = append(cr, d.isObfuscated(req.Msg))
+	}
+
 	if d.AbnormalSpacing.Enabled {
 		cr = append(cr, d.isAbnormalSpacing(req.Msg))
 	}
@@ -335,18 +415,20 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 	// check for message length exceed the minimum size, if min message length is set.
 	// the check is done after first simple checks, because stop words and emojis can be triggered by short messages as well.
 	isShortMessage := false
-	if len([]rune(req.Msg)) < d.MinMsgLen {
+	if len([]rune(req.ContentText())) < d.MinMsgLen {
 		isShortMessage = true
 		cr = append(cr, spamcheck.Response{Name: "message length", Spam: false, Details: "too short"})
 		// only return early if:
 		// 1. we already detected spam from simple checks above, OR
 		// 2. no LLM checker is configured for short messages, OR
 		// 3. LLM checkers are configured but LLMs won't run (FirstMessageOnly/FirstMessagesCount not set)
-		openaiChecksShort := d.openaiChecker != nil && d.openaiChecker.params.CheckShortMessagesWithOpenAI
-		geminiChecksShort := d.geminiChecker != nil && d.geminiChecker.params.CheckShortMessages
+		llmChecksShort := slices.ContainsFunc(d.llmChecks(), func(c detectorLLMCheck) bool {
+			return c.enabled && c.checkShortMessages
+		})
 		llmEligible := d.FirstMessageOnly || d.FirstMessagesCount > 0
 		softSpam := isSpamDetected(cr)
-		if softSpam || !llmEligible || (!openaiChecksShort && !geminiChecksShort) {
+		if softSpam || !llmEligible || !llmChecksShort {
+			cr = withScore(cr)
 			if softSpam {
 				if approval, ok := luaApprovalResponse(luaApprovers); ok {
 					return false, append(cr, approval)
@@ -362,8 +444,10 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 
 	// check for spam similarity if a similarity threshold is set and spam samples are loaded
 	// skip for short messages as similarity doesn't work well on short text
-	if !isShortMessage && d.SimilarityThreshold > 0 && len(d.tokenizedSpam) > 0 {
-		cr = append(cr, d.isSpamSimilarityHigh(cleanMsg))
+	if !isShortMessage && d.SimilarityThreshold > 0 && d.similarityIndex.size() > 0 {
+		resp, similarity := d.isSpamSimilarityHigh(cleanMsg)
+		probs[resp.Name] = similarity
+		cr = append(cr, resp)
 	}
 
 	// check for spam with classifier if classifier is loaded
@@ -371,10 +455,13 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 	classifierReady := d.classifier.nAllDocument > 0 &&
 		d.classifier.nDocumentByClass["ham"] > 0 && d.classifier.nDocumentByClass["spam"] > 0
 	if !isShortMessage && classifierReady {
-		cr = append(cr, d.isSpamClassified(cleanMsg))
+		resp, prob := d.isSpamClassified(cleanMsg)
+		probs[resp.Name] = prob
+		cr = append(cr, resp)
 	}
 
 	baseSpam := isSpamDetected(cr)
+	cr = withScore(cr)
 	spamDetected := baseSpam
 	luaApproved := false
 	if baseSpam {
@@ -391,31 +478,10 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 	//  - checks failed (spam) and veto is true - improves false positive rate
 	// FirstMessageOnly or FirstMessagesCount has to be set to use LLMs, because they are slow and expensive to run on all messages
 	if !luaApproved && (d.FirstMessageOnly || d.FirstMessagesCount > 0) {
-		llmResults := make([]detectorLLMResult, 0, 2)
-		llmChecks := []detectorLLMCheck{
-			{
-				name:               "openai",
-				enabled:            d.openaiChecker != nil,
-				checkShortMessages: d.openaiChecker != nil && d.openaiChecker.params.CheckShortMessagesWithOpenAI,
-				veto:               d.OpenAIVeto,
-				historySize:        d.OpenAIHistorySize,
-				check: func(ctx context.Context, msg string, history []spamcheck.Request) (bool, spamcheck.Response) {
-					return d.openaiChecker.check(ctx, msg, history)
-				},
-			},
-			{
-				name:               "gemini",
-				enabled:            d.geminiChecker != nil,
-				checkShortMessages: d.geminiChecker != nil && d.geminiChecker.params.CheckShortMessages,
-				veto:               d.GeminiVeto,
-				historySize:        d.GeminiHistorySize,
-				check: func(ctx context.Context, msg string, history []spamcheck.Request) (bool, spamcheck.Response) {
-					return d.geminiChecker.check(ctx, msg, history)
-				},
-			},
-		}
-
-		inp := llmCheckInput{req: req, cleanMsg: cleanMsg, checks: cr, baseSpam: baseSpam, isShortMessage: isShortMessage}
+		llmChecks := d.llmChecks()
+		llmResults := make([]detectorLLMResult, 0, len(llmChecks))
+		inp := llmCheckInput{req: req, cleanMsg: llmMsg, cacheMsg: cleanMsg, checks: cr, baseSpam: baseSpam,
+			isShortMessage: isShortMessage}
 		for _, llmCheck := range llmChecks {
 			if res, ok := d.collectLLMCheck(inp, llmCheck); ok {
 				cr = append(cr, res.details)
@@ -425,6 +491,11 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 		}
 
 		spamDetected = d.applyLLMConsensus(baseSpam, llmResults, d.LLMConsensus)
+		if d.LLMDisagreement && !spamDetected {
+			if resp, ok := llmDisagreementResponse(baseSpam, llmResults); ok {
+				cr = append(cr, resp)
+			}
+		}
 	}
 
 	if spamDetected {
@@ -433,10 +504,16 @@ func (d *Detector) Check(req spamcheck.Request) (spam bool, cr []spamcheck.Respo
 	}
 
 	// update approved users only if it's not paranoid mode and not a check-only request
-	// and only if the message was not too short (to ensure we have meaningful content)
-	if (d.FirstMessageOnly || d.FirstMessagesCount > 0) && !req.CheckOnly && !isShortMessage {
+	// and only if the message was not too short (to ensure we have meaningful content).
+	// suspicious messages don't count, otherwise the user skips checks of the next messages before admins review it
+	suspicious := slices.ContainsFunc(cr, func(r spamcheck.Response) bool { return r.Suspicious })
+	if (d.FirstMessageOnly || d.FirstMessagesCount > 0) && !req.CheckOnly && !isShortMessage && !suspicious {
 		ctx, cancel := d.ctxWithStoreTimeout()
 		defer cancel()
+		if resp, hold := d.holdApproval(ctx, req.UserID); hold {
+			d.hamHistory.Push(req)
+			return false, append(cr, resp)
+		}
 		d.auLock.Lock()
 		// cap the count at the organic approval level: concurrent first messages from the same
 		// user can all pass the pre-approved check before any increment lands, and an inflated
@@ -481,6 +558,50 @@ func luaApprovalResponse(names []string) (spamcheck.Response, bool) {
 	return spamcheck.Response{Name: "lua-approve", Details: "cleared by " + strings.Join(uniqueNames, ", ")}, true
 }
 
+// llmChecks returns all LLM checks in order of execution: openai, gemini and the named checkers
+func (d *Detector) llmChecks() []detectorLLMCheck {
+	res := make([]detectorLLMCheck, 0, 2+len(d.llmCheckers))
+	res = append(res,
+		detectorLLMCheck{
+			name:               "openai",
+			enabled:            d.openaiChecker != nil,
+			checkShortMessages: d.openaiChecker != nil && d.openaiChecker.params.CheckShortMessagesWithOpenAI,
+			veto:               d.OpenAIVeto,
+			historySize:        d.OpenAIHistorySize,
+			cacheID:            d.openaiCacheID(),
+			check: func(ctx context.Context, msg string, history []spamcheck.Request) (bool, spamcheck.Response) {
+				return d.openaiChecker.check(ctx, msg, history)
+			},
+		},
+		detectorLLMCheck{
+			name:               "gemini",
+			enabled:            d.geminiChecker != nil,
+			checkShortMessages: d.geminiChecker != nil && d.geminiChecker.params.CheckShortMessages,
+			veto:               d.GeminiVeto,
+			historySize:        d.GeminiHistorySize,
+			cacheID:            d.geminiCacheID(),
+			check: func(ctx context.Context, msg string, history []spamcheck.Request) (bool, spamcheck.Response) {
+				return d.geminiChecker.check(ctx, msg, history)
+			},
+		},
+	)
+	return append(res, d.llmCheckers...)
+}
+
+func (d *Detector) openaiCacheID() string {
+	if d.openaiChecker == nil {
+		return ""
+	}
+	return "openai\x00" + d.openaiChecker.params.Model + "\x00" + d.openaiChecker.buildSystemPrompt()
+}
+
+func (d *Detector) geminiCacheID() string {
+	if d.geminiChecker == nil {
+		return ""
+	}
+	return "gemini\x00" + d.geminiChecker.params.Model + "\x00" + d.geminiChecker.buildSystemPrompt()
+}
+
 func (d *Detector) normalizeLLMConsensusMode(mode LLMConsensusMode) LLMConsensusMode {
 	if mode == LLMConsensusAll {
 		return mode
@@ -512,7 +633,7 @@ func (d *Detector) collectLLMCheck(inp llmCheckInput, cfg detectorLLMCheck) (det
 	ctx, cancel := d.ctxWithLLMTimeout()
 	defer cancel()
 
-	spam, details := cfg.check(ctx, inp.cleanMsg, hist)
+	spam, details := d.cachedLLMCheck(ctx, cfg, inp, hist)
 	if inp.baseSpam && details.Error != nil {
 		log.Printf("[WARN] %s error: %v", cfg.name, details.Error)
 	}
@@ -532,6 +653,33 @@ func (d *Detector) collectLLMCheck(inp llmCheckInput, cfg detectorLLMCheck) (det
 	return detectorLLMResult{details: details, flip: flip}, true
 }
 
+// cachedLLMCheck runs the LLM check, the verdict for the same normalized message, history, provider, model and prompt
+// is reused from the cache if set. Only successful checks are cached, cache errors are logged and don't affect the check.
+func (d *Detector) cachedLLMCheck(ctx context.Context, cfg detectorLLMCheck, inp llmCheckInput, hist []spamcheck.Request,
+) (bool, spamcheck.Response) {
+	if d.llmCache == nil {
+		return cfg.check(ctx, inp.cleanMsg, hist)
+	}
+
+	key := llmCacheKey(cfg.cacheID, inp.cacheMsg, hist)
+	verdict, ok, err := d.llmCache.Get(ctx, key)
+	if err != nil {
+		log.Printf("[WARN] failed to get cached %s verdict: %v", cfg.name, err)
+	}
+	if ok {
+		return verdict.Spam, spamcheck.Response{Name: cfg.name, Spam: verdict.Spam, Details: verdict.Details + " (cached)"}
+	}
+
+	spam, resp := cfg.check(ctx, inp.cleanMsg, hist)
+	if resp.Error != nil {
+		return spam, resp
+	}
+	if err := d.llmCache.Put(ctx, key, LLMVerdict{Spam: spam, Details: resp.Details}); err != nil {
+		log.Printf("[WARN] failed to cache %s verdict: %v", cfg.name, err)
+	}
+	return spam, resp
+}
+
 func (d *Detector) applyLLMConsensus(baseSpam bool, results []detectorLLMResult, mode LLMConsensusMode) bool {
 	if len(results) == 0 {
 		return baseSpam
@@ -555,6 +703,29 @@ func (d *Detector) applyLLMConsensus(baseSpam bool, results []detectorLLMResult,
 	}
 }
 
+// llmDisagreementResponse returns a suspicious response if LLM checks disagree with each other,
+// or if they overturned the spam decision of the other checks.
+func llmDisagreementResponse(baseSpam bool, results []detectorLLMResult) (spamcheck.Response, bool) {
+	if len(results) == 0 {
+		return spamcheck.Response{}, false
+	}
+	flips := 0
+	for _, r := range results {
+		if r.flip {
+			flips++
+		}
+	}
+	switch {
+	case flips > 0 && flips < len(results):
+		return spamcheck.Response{Name: "llm-disagreement", Suspicious: true,
+			Details: fmt.Sprintf("%d of %d llm checks disagree", flips, len(results))}, true
+	case baseSpam && flips == len(results):
+		return spamcheck.Response{Name: "llm-disagreement", Suspicious: true,
+			Details: "llm checks overturned spam decision"}, true
+	}
+	return spamcheck.Response{}, false
+}
+
 // RecordReaction records one net-new reaction for a user and returns spam=true if the reaction threshold is exceeded.
 // Callers should invoke this once per added reaction; if a single external event adds multiple reactions, each
 // added reaction is counted separately. If the reaction detector is disabled, it returns a non-spam response with
@@ -569,18 +740,19 @@ func (d *Detector) RecordReaction(userID int64) spamcheck.Response {
 	return d.reactionDetector.check(userID)
 }
 
-// Reset resets spam samples/classifier, excluded tokens, stop words and approved users.
+// Reset resets spam samples/classifier, excluded tokens, stop words, domains and approved users.
 func (d *Detector) Reset() {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.tokenizedSpam = []map[string]int{}
+	d.similarityIndex.reset()
 	d.excludedTokens = map[string]struct{}{}
 	d.classifier.reset()
 	d.auLock.Lock()
 	d.approvedUsers = make(map[string]approved.UserInfo)
 	d.auLock.Unlock()
 	d.stopWords = []string{}
+	d.blockedDomains, d.allowedDomains = nil, nil
 
 	// close the Lua engine and reset Lua checks if it exists
 	if d.luaEngine != nil {
@@ -590,6 +762,40 @@ func (d *Detector) Reset() {
 	}
 }
 
+// Reconfigure applies configuration of src, a detector made with NewDetector for the new settings and set up
+// with LLM checkers, meta checks and Lua engine the same way as on startup. Config, LLM checkers, meta checks and
+// Lua checks are taken from src, while loaded samples, stop words, domains, approved users, storages and the LLM
+// cache are kept. Duplicate and reaction trackers, as well as messages history, are replaced only if their
+// settings changed, as the replacement drops what they collected.
+//
+// Checks in progress complete with the previous configuration, the ones started after the call use the new one.
+// Samples are not reloaded, they have to be reloaded by the caller if NormalizeText changed.
+// The previous Lua engine is closed, src must not be used after the call.
+func (d *Detector) Reconfigure(src *Detector) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	prev := d.Config
+	d.Config = src.Config
+	d.openaiChecker, d.geminiChecker, d.llmCheckers = src.openaiChecker, src.geminiChecker, src.llmCheckers
+	d.metaChecks = src.metaChecks
+
+	if d.luaEngine != nil && d.luaEngine != src.luaEngine {
+		d.luaEngine.Close()
+	}
+	d.luaEngine, d.luaChecks = src.luaEngine, src.luaChecks
+
+	if prev.DuplicateDetection != d.DuplicateDetection {
+		d.duplicateDetector = src.duplicateDetector
+	}
+	if prev.ReactionSpam != d.ReactionSpam {
+		d.reactionDetector = src.reactionDetector
+	}
+	if prev.HistorySize != d.HistorySize {
+		d.hamHistory, d.spamHistory = src.hamHistory, src.spamHistory
+	}
+}
+
 // WithOpenAIChecker sets an openAIChecker for spam checking.
 func (d *Detector) WithOpenAIChecker(client openAIClient, config OpenAIConfig) {
 	d.openaiChecker = newOpenAIChecker(client, config)
@@ -600,6 +806,48 @@ func (d *Detector) WithGeminiChecker(client geminiClient, config GeminiConfig) {
 	d.geminiChecker = newGeminiChecker(client, config)
 }
 
+// WithLLMChecker adds a named LLM checker, e.g. made with NewAnthropicChecker or NewOllamaChecker.
+// Checkers run after openai and gemini in order of registration and take part in the LLM consensus.
+// The name is used as the check name in responses, it must be unique and can't be openai or gemini.
+func (d *Detector) WithLLMChecker(name string, checker LLMChecker, opts LLMCheckerOpts) error {
+	if name == "" {
+		return fmt.Errorf("empty LLM checker name")
+	}
+	if checker == nil {
+		return fmt.Errorf("nil LLM checker %q", name)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, c := range d.llmChecks() {
+		if c.name == name {
+			return fmt.Errorf("duplicate LLM checker name %q", name)
+		}
+	}
+	d.llmCheckers = append(d.llmCheckers, detectorLLMCheck{
+		name:               name,
+		enabled:            true,
+		checkShortMessages: opts.CheckShortMessages,
+		veto:               opts.Veto,
+		historySize:        opts.HistorySize,
+		cacheID:            name + "\x00" + opts.CacheID,
+		check: func(ctx context.Context, msg string, history []spamcheck.Request) (bool, spamcheck.Response) {
+			spam, resp := checker.Check(ctx, msg, history)
+			resp.Name = name
+			return spam, resp
+		},
+	})
+	return nil
+}
+
+// WithLLMCache sets the cache of LLM verdicts, e.g. LLMMemoryCache. Verdicts are keyed by the cleaned message
+// along with the provider, model and prompt, so a wave of identical messages costs a single LLM request.
+func (d *Detector) WithLLMCache(c LLMCache) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.llmCache = c
+}
+
 // WithLuaEngine sets a Lua plugin engine and loads plugins
 func (d *Detector) WithLuaEngine(engine LuaPluginEngine) error {
 	d.luaEngine = engine
@@ -709,6 +957,24 @@ func (d *Detector) WithMessageCounter(mc MessageCounter) {
 	d.messageCounter = mc
 }
 
+// WithApprovalGuard sets an ApprovalGuard consulted before counting a ham message towards user approval.
+func (d *Detector) WithApprovalGuard(g ApprovalGuard) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.approvalGuard = g
+}
+
+// WithCASMirror sets a local mirror of CAS banned users, checked before the CAS API.
+// Users found in the mirror are reported as spam without API calls. Users not found in the mirror
+// are reported as clean as long as the mirror is fresh, i.e. synced within maxAge (0 means always fresh).
+// The CAS API is called only for users missing in a stale or never synced mirror, or if the mirror fails.
+func (d *Detector) WithCASMirror(m CASMirror, maxAge time.Duration) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.casMirror = m
+	d.casMirrorMaxAge = maxAge
+}
+
 // WithMetaChecks sets a list of meta-checkers.
 func (d *Detector) WithMetaChecks(mc ...MetaCheck) {
 	d.metaChecks = append(d.metaChecks, mc...)
@@ -785,6 +1051,38 @@ func (d *Detector) AddApprovedUser(user approved.UserInfo) error {
 	return nil
 }
 
+// SeedApprovedUser credits user with the given number of checked messages, as if these messages were sent
+// and passed the first messages check. Used for users verified by other means, e.g. by the join challenge.
+// The user is approved if the count reaches FirstMessagesCount. The existing count is never decreased.
+func (d *Detector) SeedApprovedUser(user approved.UserInfo) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if user.Count <= 0 {
+		return nil
+	}
+	ts := user.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	d.auLock.Lock()
+	if existing, ok := d.approvedUsers[user.UserID]; ok && existing.Count >= user.Count {
+		d.auLock.Unlock()
+		return nil
+	}
+	au := approved.UserInfo{UserID: user.UserID, UserName: user.UserName, Count: user.Count, Timestamp: ts}
+	d.approvedUsers[user.UserID] = au
+	d.auLock.Unlock()
+
+	if d.userStorage != nil {
+		ctx, cancel := d.ctxWithStoreTimeout()
+		defer cancel()
+		if err := d.userStorage.Write(ctx, au); err != nil {
+			return fmt.Errorf("failed to write seeded user %+v to storage: %w", au, err)
+		}
+	}
+	return nil
+}
+
 // RemoveApprovedUser removes approved user for given IDs
 func (d *Detector) RemoveApprovedUser(id string) error {
 	d.lock.Lock()
@@ -830,21 +1128,21 @@ func (d *Detector) LoadSamples(exclReader io.Reader, spamReaders, hamReaders []i
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.tokenizedSpam = []map[string]int{}
+	d.similarityIndex.reset()
 	d.excludedTokens = map[string]struct{}{}
 	d.classifier.reset()
 
 	// excluded tokens should be loaded before spam samples to exclude them from spam tokenization
 	for t := range d.readerIterator(exclReader) {
-		d.excludedTokens[strings.ToLower(t)] = struct{}{}
+		d.excludedTokens[strings.ToLower(d.normalize(t))] = struct{}{}
 	}
 	lr := LoadResult{ExcludedTokens: len(d.excludedTokens)}
 
 	// load spam samples and update the classifier with them
 	docs := make([]document, 0) //nolint:prealloc // iterator size unknown
 	for token := range d.readerIterator(spamReaders...) {
-		tokenizedSpam := d.tokenize(token)
-		d.tokenizedSpam = append(d.tokenizedSpam, tokenizedSpam) // add to list of samples
+		tokenizedSpam := d.tokenize(d.normalize(token))
+		d.similarityIndex.add(tokenizedSpam)
 		tokens := make([]string, 0, len(tokenizedSpam))
 		for token := range tokenizedSpam {
 			tokens = append(tokens, token)
@@ -855,7 +1153,7 @@ func (d *Detector) LoadSamples(exclReader io.Reader, spamReaders, hamReaders []i
 
 	// load ham samples and update the classifier with them
 	for token := range d.readerIterator(hamReaders...) {
-		tokenizedSpam := d.tokenize(token)
+		tokenizedSpam := d.tokenize(d.normalize(token))
 		tokens := make([]string, 0, len(tokenizedSpam))
 		for token := range tokenizedSpam {
 			tokens = append(tokens, token)
@@ -875,7 +1173,7 @@ func (d *Detector) LoadStopWords(readers ...io.Reader) (LoadResult, error) {
 
 	d.stopWords = []string{}
 	for t := range d.readerIterator(readers...) {
-		d.stopWords = append(d.stopWords, strings.ToLower(t))
+		d.stopWords = append(d.stopWords, strings.ToLower(d.normalize(t)))
 	}
 	return LoadResult{StopWords: len(d.stopWords)}, nil
 }
@@ -921,8 +1219,8 @@ func (d *Detector) updateSample(msg string, upd SampleUpdater, sc spamClass) err
 
 	// update tokenized spam samples for similarity check
 	if sc == ClassSpam {
-		tokenizedSpam := d.tokenize(msg)
-		d.tokenizedSpam = append(d.tokenizedSpam, tokenizedSpam)
+		tokenizedSpam := d.tokenize(d.normalize(msg))
+		d.similarityIndex.add(tokenizedSpam)
 	}
 
 	return nil
@@ -956,7 +1254,7 @@ func (d *Detector) removeSample(msg string, upd SampleUpdater, sc spamClass) err
 func (d *Detector) buildDocs(msg string, sc spamClass) []document {
 	docs := make([]document, 0) //nolint:prealloc // iterator size unknown
 	for token := range d.readerIterator(bytes.NewBufferString(msg)) {
-		tokenizedSample := d.tokenize(token)
+		tokenizedSample := d.tokenize(d.normalize(token))
 		tokens := make([]string, 0, len(tokenizedSample))
 		for token := range tokenizedSample {
 			tokens = append(tokens, token)
@@ -1016,48 +1314,12 @@ func (d *Detector) tokenize(inp string) map[string]int {
 	return tokenFrequency
 }
 
-// isSpam checks if a given message is similar to any of the known bad messages
-func (d *Detector) isSpamSimilarityHigh(msg string) spamcheck.Response {
-	// check for spam similarity
-	tokenizedMessage := d.tokenize(msg)
-	maxSimilarity := 0.0
-	for _, spam := range d.tokenizedSpam {
-		similarity := d.cosineSimilarity(tokenizedMessage, spam)
-		if similarity > maxSimilarity {
-			maxSimilarity = similarity
-		}
-		if similarity >= d.SimilarityThreshold {
-			return spamcheck.Response{Spam: true, Name: "similarity",
-				Details: fmt.Sprintf("%0.2f/%0.2f", maxSimilarity, d.SimilarityThreshold)}
-		}
-	}
-	return spamcheck.Response{Spam: false, Name: "similarity",
-		Details: fmt.Sprintf("%0.2f/%0.2f", maxSimilarity, d.SimilarityThreshold)}
-}
-
-// cosineSimilarity calculates the cosine similarity between two token frequency maps.
-func (d *Detector) cosineSimilarity(a, b map[string]int) float64 {
-	if len(a) == 0 || len(b) == 0 {
-		return 0.0
-	}
-
-	dotProduct := 0      // sum of product of corresponding frequencies
-	normA, normB := 0, 0 // square root of sum of squares of frequencies
-
-	for key, val := range a {
-		dotProduct += val * b[key]
-		normA += val * val
-	}
-	for _, val := range b {
-		normB += val * val
-	}
-
-	if normA == 0 || normB == 0 {
-		return 0.0
-	}
-
-	// cosine similarity formula
-	return float64(dotProduct) / (math.Sqrt(float64(normA)) * math.Sqrt(float64(normB)))
+// isSpamSimilarityHigh checks if a given message is similar to any of the known bad messages, returns the max similarity as well.
+// Only samples sharing tokens with the message are compared, see similarityIndex.
+func (d *Detector) isSpamSimilarityHigh(msg string) (spamcheck.Response, float64) {
+	spam, maxSimilarity := d.similarityIndex.match(d.tokenize(msg), d.SimilarityThreshold)
+	return spamcheck.Response{Spam: spam, Name: "similarity",
+		Details: fmt.Sprintf("%0.2f/%0.2f", maxSimilarity, d.SimilarityThreshold)}, maxSimilarity
 }
 
 // isCasSpam checks if a given user ID is a spammer with CAS API.
@@ -1065,9 +1327,13 @@ func (d *Detector) isCasSpam(msgID string) spamcheck.Response {
 	if msgID == "" {
 		return spamcheck.Response{Spam: false, Name: "cas", Details: "check disabled"}
 	}
-	if _, err := strconv.ParseInt(msgID, 10, 64); err != nil {
+	userID, err := strconv.ParseInt(msgID, 10, 64)
+	if err != nil {
 		return spamcheck.Response{Spam: false, Name: "cas", Details: fmt.Sprintf("invalid user id %q", msgID)}
 	}
+	if resp, ok := d.checkCasMirror(userID); ok {
+		return resp
+	}
 	reqURL := fmt.Sprintf("%s/check?user_id=%s", d.CasAPI, msgID)
 	req, err := http.NewRequest("GET", reqURL, http.NoBody)
 	if err != nil {
@@ -1148,8 +1414,8 @@ func (d *Detector) isCasSpam(msgID string) spamcheck.Response {
 	return spamcheck.Response{Name: "cas", Spam: false, Details: details}
 }
 
-// isSpamClassified classify tokens from a document
-func (d *Detector) isSpamClassified(msg string) spamcheck.Response {
+// isSpamClassified classify tokens from a document, returns the check response and the spam probability
+func (d *Detector) isSpamClassified(msg string) (spamcheck.Response, float64) {
 	tm := d.tokenize(msg)
 	tokens := make([]string, 0, len(tm))
 	for token := range tm {
@@ -1164,8 +1430,53 @@ func (d *Detector) isSpamClassified(msg string) spamcheck.Response {
 		probStr = fmt.Sprintf("%.2f", prob)
 	}
 
-	return spamcheck.Response{Name: "classifier", Spam: isSpam,
-		Details: fmt.Sprintf("probability of %s: %s%%", class, probStr)}
+	spamProb := classifierSpamProbability(class, prob)
+	resp := spamcheck.Response{Name: "classifier", Spam: isSpam, Details: fmt.Sprintf("probability of %s: %s%%", class, probStr)}
+	if !isSpam && d.MinSuspiciousProb > 0 && spamProb*100 >= d.MinSuspiciousProb {
+		resp.Suspicious = true
+		resp.Details += ", suspicious"
+	}
+	return resp, spamProb
+}
+
+// checkCasMirror checks the user against the local CAS mirror. Returns false if the mirror can't answer
+// and the CAS API should be called: no mirror set, lookup failed, or the user is not found in a stale mirror.
+func (d *Detector) checkCasMirror(userID int64) (spamcheck.Response, bool) {
+	if d.casMirror == nil {
+		return spamcheck.Response{}, false
+	}
+	ctx, cancel := d.ctxWithStoreTimeout()
+	defer cancel()
+	banned, err := d.casMirror.IsBanned(ctx, userID)
+	if err != nil {
+		log.Printf("[WARN] failed to check user %d in local CAS mirror: %v", userID, err)
+		return spamcheck.Response{}, false
+	}
+	if banned {
+		return spamcheck.Response{Spam: true, Name: "cas", Details: "banned, local mirror"}, true
+	}
+	syncedAt := d.casMirror.SyncedAt()
+	if syncedAt.IsZero() || (d.casMirrorMaxAge > 0 && time.Since(syncedAt) > d.casMirrorMaxAge) {
+		return spamcheck.Response{}, false // stale mirror, user may be banned after the last sync
+	}
+	return spamcheck.Response{Spam: false, Name: "cas", Details: "not found, local mirror"}, true
+}
+
+// holdApproval checks with the approval guard whether the ham message should not count towards user approval.
+// Guard errors are logged and don't hold the approval. Expected to be called from Check while d.lock is held.
+func (d *Detector) holdApproval(ctx context.Context, userID string) (spamcheck.Response, bool) {
+	if d.approvalGuard == nil || userID == "" {
+		return spamcheck.Response{}, false
+	}
+	hold, reason, err := d.approvalGuard.HoldApproval(ctx, userID)
+	if err != nil {
+		log.Printf("[WARN] failed to check approval hold for user %s: %v", userID, err)
+		return spamcheck.Response{}, false
+	}
+	if !hold {
+		return spamcheck.Response{}, false
+	}
+	return spamcheck.Response{Name: "approval-hold", Spam: false, Details: reason}, true
 }
 
 // isShortMsgFlood checks whether an unapproved user has accumulated too many short
@@ -1298,7 +1609,7 @@ func (d *Detector) isStopWord(msg string, req spamcheck.Request) spamcheck.Respo
 		names = append(names, req.UserID)
 	}
 	for _, name := range names {
-		normalizedName := normalizeSpaces(strings.ToLower(name))
+		normalizedName := normalizeSpaces(strings.ToLower(d.normalize(name)))
 		for _, word := range d.stopWords {
 			if matchStopWord(normalizedName, word) {
 				return spamcheck.Response{Name: "stopword", Spam: true, Details: strings.TrimPrefix(word, "=")}
@@ -1414,6 +1725,17 @@ func (d *Detector) isMultiLang(msg string) spamcheck.Response {
 	return spamcheck.Response{Name: "multi-lingual", Spam: false, Details: fmt.Sprintf("%d/%d", count, d.MultiLangWords)}
 }
 
+// isObfuscated checks if a given message contains ObfuscatedWords or more words written with lookalike letters
+// of another script, e.g. "crypт" with Cyrillic "т"
+func (d *Detector) isObfuscated(msg string) spamcheck.Response {
+	words := textnorm.ObfuscatedWords(msg)
+	details := fmt.Sprintf("%d/%d", len(words), d.ObfuscatedWords)
+	if len(words) > 0 {
+		details += fmt.Sprintf(", %q", words[0])
+	}
+	return spamcheck.Response{Name: "obfuscation", Spam: len(words) >= d.ObfuscatedWords, Details: details}
+}
+
 // isAbnormalSpacing detects abnormal spacing patterns used to evade filters
 // things like this: "w o r d s p a c i n g some thing he re blah blah"
 func (d *Detector) isAbnormalSpacing(msg string) spamcheck.Response {
@@ -1486,8 +1808,22 @@ func (d *Detector) isAbnormalSpacing(msg string) spamcheck.Response {
 	}
 }
 
-// cleanText removes control and format characters from a given text
+// cleanText removes control and format characters from a given text and normalizes it if NormalizeText is set
 func (d *Detector) cleanText(text string) string {
+	return d.normalize(stripInvisible(text))
+}
+
+// normalize folds obfuscated text to the canonical form with textnorm.Normalize if NormalizeText is set.
+// Samples, stop words and excluded tokens are normalized on load as well, so they match normalized messages.
+func (d *Detector) normalize(text string) string {
+	if !d.NormalizeText {
+		return text
+	}
+	return textnorm.Normalize(text)
+}
+
+// stripInvisible removes control, format and invisible characters from a given text
+func stripInvisible(text string) string {
 	var result strings.Builder
 	result.Grow(len(text))
 	for _, r := range text {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -1309,6 +1310,68 @@ func TestSpam_CheckIsCasSpamNon200Status(t *testing.T) {
 	})
 }
 
+func TestSpam_CheckIsCasSpamWithMirror(t *testing.T) {
+	casResp := func(ok bool) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(bytes.NewBufferString(fmt.Sprintf(`{"ok": %v, "description": "from api"}`, ok))),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		banned      bool
+		mirrorErr   error
+		syncedAt    time.Time
+		apiSpam     bool
+		wantSpam    bool
+		wantDetails string
+		wantAPICall bool
+	}{
+		{name: "banned in fresh mirror", banned: true, syncedAt: time.Now(),
+			wantSpam: true, wantDetails: "banned, local mirror"},
+		{name: "banned in stale mirror", banned: true, syncedAt: time.Now().Add(-2 * time.Hour),
+			wantSpam: true, wantDetails: "banned, local mirror"},
+		{name: "not found in fresh mirror", syncedAt: time.Now(), apiSpam: true,
+			wantDetails: "not found, local mirror"},
+		{name: "not found in stale mirror", syncedAt: time.Now().Add(-2 * time.Hour), apiSpam: true,
+			wantSpam: true, wantDetails: "from api", wantAPICall: true},
+		{name: "not found in never synced mirror", apiSpam: false,
+			wantDetails: "from api", wantAPICall: true},
+		{name: "mirror error", mirrorErr: errors.New("db error"), syncedAt: time.Now(), apiSpam: true,
+			wantSpam: true, wantDetails: "from api", wantAPICall: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient := &mocks.HTTPClientMock{
+				DoFunc: func(req *http.Request) (*http.Response, error) { return casResp(tt.apiSpam), nil },
+			}
+			mirror := &mocks.CASMirrorMock{
+				IsBannedFunc: func(ctx context.Context, userID int64) (bool, error) { return tt.banned, tt.mirrorErr },
+				SyncedAtFunc: func() time.Time { return tt.syncedAt },
+			}
+			d := NewDetector(Config{CasAPI: "http://localhost", HTTPClient: httpClient, MaxAllowedEmoji: -1})
+			d.WithCASMirror(mirror, time.Hour)
+
+			spam, cr := d.Check(spamcheck.Request{UserID: "123", Msg: "test"})
+			assert.Equal(t, tt.wantSpam, spam)
+			require.Len(t, cr, 1)
+			assert.Equal(t, "cas", cr[0].Name)
+			assert.Equal(t, tt.wantSpam, cr[0].Spam)
+			assert.Equal(t, tt.wantDetails, cr[0].Details)
+			require.Len(t, mirror.IsBannedCalls(), 1)
+			assert.Equal(t, int64(123), mirror.IsBannedCalls()[0].UserID)
+			if tt.wantAPICall {
+				assert.Len(t, httpClient.DoCalls(), 1)
+			} else {
+				assert.Empty(t, httpClient.DoCalls())
+			}
+		})
+	}
+}
+
 func TestDetector_CheckSimilarity(t *testing.T) {
 	d := NewDetector(Config{MaxAllowedEmoji: -1})
 	spamSamples := strings.NewReader("win free iPhone\nlottery prize xyz")
@@ -1316,10 +1379,9 @@ func TestDetector_CheckSimilarity(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, LoadResult{ExcludedTokens: 1, SpamSamples: 2}, lr)
 	d.classifier.reset() // we don't need a classifier for this test
-	assert.Len(t, d.tokenizedSpam, 2)
-	t.Logf("%+v", d.tokenizedSpam)
-	assert.Equal(t, map[string]int{"win": 1, "free": 1, "iphone": 1}, d.tokenizedSpam[0])
-	assert.Equal(t, map[string]int{"lottery": 1, "prize": 1}, d.tokenizedSpam[1])
+	assert.Equal(t, 2, d.similarityIndex.size())
+	assert.Equal(t, map[string]int{"win": 1, "free": 1, "iphone": 1}, sampleTokens(&d.similarityIndex, 0))
+	assert.Equal(t, map[string]int{"lottery": 1, "prize": 1}, sampleTokens(&d.similarityIndex, 1))
 
 	tests := []struct {
 		name      string
@@ -1353,7 +1415,7 @@ func TestDetector_CheckClassifier(t *testing.T) {
 	lr, err := d.LoadSamples(strings.NewReader("xyz"), []io.Reader{spamSamples}, []io.Reader{hamsSamples})
 	require.NoError(t, err)
 	assert.Equal(t, LoadResult{ExcludedTokens: 1, SpamSamples: 2, HamSamples: 3}, lr)
-	d.tokenizedSpam = nil // we don't need tokenizedSpam samples for this test
+	d.similarityIndex.reset() // we don't need similarity samples for this test
 	assert.Equal(t, 5, d.classifier.nAllDocument)
 	exp := map[string]map[spamClass]int{"win": {"spam": 1}, "free": {"spam": 1}, "iphone": {"spam": 1}, "lottery": {"spam": 1},
 		"prize": {"spam": 1}, "hello": {"ham": 1}, "world": {"ham": 1}, "how": {"ham": 1}, "are": {"ham": 1}, "you": {"ham": 1},
@@ -1386,6 +1448,36 @@ func TestDetector_CheckClassifier(t *testing.T) {
 		})
 	}
 
+	t.Run("suspicious probability band", func(t *testing.T) {
+		d.MinSuspiciousProb = 50
+		defer func() { d.MinSuspiciousProb = 0 }()
+		spam, cr := d.Check(spamcheck.Request{Msg: "You won a free lottery iphone have a good day"})
+		assert.False(t, spam)
+		require.Len(t, cr, 1)
+		assert.True(t, cr[0].Suspicious)
+		assert.Equal(t, "probability of spam: 53.36%, suspicious", cr[0].Details)
+
+		spam, cr = d.Check(spamcheck.Request{Msg: "win a good day"})
+		assert.False(t, spam)
+		assert.False(t, cr[0].Suspicious, "spam probability 34.61% is below the band")
+
+		spam, cr = d.Check(spamcheck.Request{Msg: "Win a free iPhone now!"})
+		assert.True(t, spam)
+		assert.False(t, cr[0].Suspicious, "spam is not suspicious")
+	})
+
+	t.Run("suspicious first message doesn't approve user", func(t *testing.T) {
+		d.MinSuspiciousProb, d.FirstMessageOnly, d.FirstMessagesCount = 50, true, 1
+		defer func() { d.MinSuspiciousProb, d.FirstMessageOnly, d.FirstMessagesCount = 0, false, 0 }()
+		spam, cr := d.Check(spamcheck.Request{Msg: "You won a free lottery iphone have a good day", UserID: "123"})
+		assert.False(t, spam)
+		assert.True(t, cr[0].Suspicious)
+		assert.False(t, d.IsApprovedUser("123"))
+
+		_, cr = d.Check(spamcheck.Request{Msg: "You won a free lottery iphone have a good day", UserID: "123"})
+		assert.NotEqual(t, "pre-approved", cr[0].Name, "next message is checked")
+	})
+
 	t.Run("without minSpamProbability", func(t *testing.T) {
 		d.MinSpamProbability = 0
 		spam, cr := d.Check(spamcheck.Request{Msg: "You won a free lottery iphone have a good day"})
@@ -1395,13 +1487,108 @@ func TestDetector_CheckClassifier(t *testing.T) {
 	})
 }
 
+func TestDetector_CheckWithScoring(t *testing.T) {
+	newDetector := func() *Detector {
+		d := NewDetector(Config{MaxAllowedEmoji: 1, Scoring: ScoringConfig{Enabled: true, DefaultWeight: 1,
+			Weights: map[string]float64{"emoji": 0.5, "classifier": 2}, Threshold: 2, SuspiciousThreshold: 1}})
+		spamSamples := strings.NewReader("win free iPhone\nlottery prize xyz")
+		hamsSamples := strings.NewReader("hello world\nhow are you\nhave a good day")
+		_, err := d.LoadSamples(strings.NewReader("xyz"), []io.Reader{spamSamples}, []io.Reader{hamsSamples})
+		require.NoError(t, err)
+		d.similarityIndex.reset() // similarity is not needed for this test
+		_, err = d.LoadStopWords(strings.NewReader("buy now"))
+		require.NoError(t, err)
+		return d
+	}
+
+	t.Run("noisy check alone is not spam", func(t *testing.T) {
+		spam, cr := newDetector().Check(spamcheck.Request{Msg: "Hello, how are you? 😀😀😀"})
+		assert.False(t, spam)
+		emoji := cr[slices.IndexFunc(cr, func(r spamcheck.Response) bool { return r.Name == "emoji" })]
+		assert.True(t, emoji.Spam)
+		assert.InDelta(t, 0.5, emoji.Score, 0.0001)
+		summary := cr[len(cr)-1]
+		assert.Equal(t, ScoreCheckName, summary.Name)
+		assert.False(t, summary.Spam)
+		assert.False(t, summary.Suspicious, "0.5 from emoji and ~0.14 from ham classifier")
+	})
+
+	t.Run("classifier probability weighted", func(t *testing.T) {
+		spam, cr := newDetector().Check(spamcheck.Request{Msg: "Win a free iPhone now!"})
+		assert.False(t, spam, "2 * 0.908 is below the threshold")
+		summary := cr[len(cr)-1]
+		assert.True(t, summary.Suspicious)
+		assert.Equal(t, "1.82/2.00, suspicious", summary.Details)
+	})
+
+	t.Run("suspicious message doesn't approve user", func(t *testing.T) {
+		d := newDetector()
+		d.FirstMessageOnly, d.FirstMessagesCount = true, 1
+		spam, cr := d.Check(spamcheck.Request{Msg: "Win a free iPhone now!", UserID: "123"})
+		assert.False(t, spam)
+		assert.True(t, cr[len(cr)-1].Suspicious)
+		assert.False(t, d.IsApprovedUser("123"), "suspicious first message")
+
+		spam, _ = d.Check(spamcheck.Request{Msg: "hello world, how are you", UserID: "123"})
+		assert.False(t, spam)
+		assert.True(t, d.IsApprovedUser("123"), "ham below the suspicious threshold")
+	})
+
+	t.Run("several checks sum up to spam", func(t *testing.T) {
+		spam, cr := newDetector().Check(spamcheck.Request{Msg: "Win a free iPhone now! buy now 😀😀"})
+		assert.True(t, spam)
+		summary := cr[len(cr)-1]
+		assert.True(t, summary.Spam)
+		assert.False(t, summary.Suspicious)
+		assert.Greater(t, summary.Score, 2.0)
+	})
+}
+
+func TestDetector_CheckImageText(t *testing.T) {
+	d := NewDetector(Config{MaxAllowedEmoji: -1, MinMsgLen: 10})
+	spamSamples := strings.NewReader("win free iPhone\nlottery prize xyz")
+	hamsSamples := strings.NewReader("hello world\nhow are you\nhave a good day")
+	_, err := d.LoadSamples(strings.NewReader(""), []io.Reader{spamSamples}, []io.Reader{hamsSamples})
+	require.NoError(t, err)
+	d.similarityIndex.reset()
+	_, err = d.LoadStopWords(bytes.NewBufferString("crypto giveaway"))
+	require.NoError(t, err)
+
+	t.Run("image text with stop word", func(t *testing.T) {
+		spam, cr := d.Check(spamcheck.Request{Msg: "look", ImageText: "Join our CRYPTO giveaway", Meta: spamcheck.MetaData{Images: 1}})
+		assert.True(t, spam)
+		assert.Equal(t, "stopword", cr[0].Name)
+		assert.True(t, cr[0].Spam)
+	})
+
+	t.Run("image-only message goes to classifier", func(t *testing.T) {
+		spam, cr := d.Check(spamcheck.Request{ImageText: "Win a free iPhone now!", Meta: spamcheck.MetaData{Images: 1}})
+		assert.True(t, spam)
+		require.Len(t, cr, 2)
+		assert.Equal(t, "classifier", cr[1].Name)
+		assert.True(t, cr[1].Spam)
+	})
+
+	t.Run("same message without image text is too short", func(t *testing.T) {
+		spam, cr := d.Check(spamcheck.Request{Msg: "look", Meta: spamcheck.MetaData{Images: 1}})
+		assert.False(t, spam)
+		require.Len(t, cr, 2)
+		assert.Equal(t, "message length", cr[1].Name)
+	})
+
+	t.Run("ham image text", func(t *testing.T) {
+		spam, _ := d.Check(spamcheck.Request{Msg: "hello", ImageText: "how are you, have a good day"})
+		assert.False(t, spam)
+	})
+}
+
 func TestDetector_CheckClassifierNoHam(t *testing.T) {
 	d := NewDetector(Config{MaxAllowedEmoji: -1, MinSpamProbability: 60})
 	spamSamples := strings.NewReader("win free iPhone\nlottery prize xyz")
 	lr, err := d.LoadSamples(strings.NewReader("xyz"), []io.Reader{spamSamples}, nil)
 	require.NoError(t, err)
 	assert.Equal(t, LoadResult{ExcludedTokens: 1, SpamSamples: 2, HamSamples: 0}, lr)
-	d.tokenizedSpam = nil // we don't need tokenizedSpam samples for this test
+	d.similarityIndex.reset() // we don't need similarity samples for this test
 	assert.Equal(t, 2, d.classifier.nAllDocument)
 	assert.Equal(t, 2, d.classifier.nDocumentByClass["spam"])
 	assert.Equal(t, 0, d.classifier.nDocumentByClass["ham"])
@@ -2163,6 +2350,87 @@ func TestDetector_CheckMultiLang(t *testing.T) {
 	}
 }
 
+func TestDetector_CheckObfuscated(t *testing.T) {
+	d := NewDetector(Config{ObfuscatedWords: 2, MaxAllowedEmoji: -1})
+	tests := []struct {
+		name    string
+		input   string
+		details string
+		spam    bool
+	}{
+		{"plain", "Hello, world! Привет мир", "0/2", false},
+		{"one word", "buy crypт now", `1/2, "crypт"`, false},
+		{"two words", "Вuy сrурtо now", `2/2, "Вuy"`, true},
+		{"latin in cyrillic", "Ищем заинтeрeсoвaнных в зaрaбoткe", `2/2, "заинтeрeсoвaнных"`, true},
+		{"mixed but not lookalikes", "iPhoneы и Androidы", "0/2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spam, cr := d.Check(spamcheck.Request{Msg: tt.input})
+			assert.Equal(t, tt.spam, spam)
+			require.Len(t, cr, 1)
+			assert.Equal(t, "obfuscation", cr[0].Name)
+			assert.Equal(t, tt.spam, cr[0].Spam)
+			assert.Equal(t, tt.details, cr[0].Details)
+		})
+	}
+
+	d.ObfuscatedWords = 0 // disable obfuscation check
+	spam, cr := d.Check(spamcheck.Request{Msg: "Вuy сrурtо now"})
+	assert.False(t, spam)
+	assert.Empty(t, cr)
+}
+
+func TestDetector_CheckNormalizeText(t *testing.T) {
+	newDetector := func(normalize bool) *Detector {
+		d := NewDetector(Config{NormalizeText: normalize, SimilarityThreshold: 0.8, MaxAllowedEmoji: -1})
+		_, err := d.LoadStopWords(strings.NewReader("crypto investment"))
+		require.NoError(t, err)
+		_, err = d.LoadSamples(strings.NewReader(""),
+			[]io.Reader{strings.NewReader("earn passive income with our trading bot today")},
+			[]io.Reader{strings.NewReader("hello everyone, how are you doing")})
+		require.NoError(t, err)
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		check string
+	}{
+		{"stop word with lookalikes and leet", "best crypт0 inv3stm3nt here", "stopword"},
+		{"stop word spaced out", "best c r y p t o investment here", "stopword"},
+		{"stop word with combining marks", "best crýptó invéstment", "stopword"},
+		{"similarity with lookalikes", "еаrn раssivе incоmе with оur trаding bоt tоdаy", "similarity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spam, cr := newDetector(true).Check(spamcheck.Request{Msg: tt.input})
+			assert.True(t, spam)
+			for _, r := range cr {
+				if r.Name == tt.check {
+					assert.True(t, r.Spam, "check %s, %s", r.Name, r.Details)
+				}
+			}
+
+			spam, _ = newDetector(false).Check(spamcheck.Request{Msg: tt.input})
+			assert.False(t, spam, "not detected without normalization")
+		})
+	}
+
+	t.Run("stop words normalized on load", func(t *testing.T) {
+		d := NewDetector(Config{NormalizeText: true, MaxAllowedEmoji: -1})
+		_, err := d.LoadStopWords(strings.NewReader("Frее Мoney"))
+		require.NoError(t, err)
+		spam, cr := d.Check(spamcheck.Request{Msg: "get free money"})
+		assert.True(t, spam)
+		require.Len(t, cr, 1)
+		assert.Equal(t, "stopword", cr[0].Name)
+	})
+}
+
 func TestDetector_CheckWithAbnormalSpacing(t *testing.T) {
 	d := NewDetector(Config{MaxAllowedEmoji: -1})
 	d.AbnormalSpacing.Enabled = true
@@ -2295,7 +2563,7 @@ func TestDetector_UpdateSpam(t *testing.T) {
 	lr, err := d.LoadSamples(strings.NewReader("xyz"), []io.Reader{spamSamples}, []io.Reader{hamsSamples})
 	require.NoError(t, err)
 	assert.Equal(t, LoadResult{ExcludedTokens: 1, SpamSamples: 2, HamSamples: 3}, lr)
-	d.tokenizedSpam = nil // we don't need tokenizedSpam samples for this test
+	d.similarityIndex.reset() // we don't need similarity samples for this test
 	assert.Equal(t, 5, d.classifier.nAllDocument)
 	exp := map[string]map[spamClass]int{"win": {"spam": 1}, "free": {"spam": 1}, "iphone": {"spam": 1}, "lottery": {"spam": 1},
 		"prize": {"spam": 1}, "hello": {"ham": 1}, "world": {"ham": 1}, "how": {"ham": 1}, "are": {"ham": 1}, "you": {"ham": 1},
@@ -2342,7 +2610,7 @@ func TestDetector_UpdateHam(t *testing.T) {
 	lr, err := d.LoadSamples(strings.NewReader("xyz"), []io.Reader{spamSamples}, []io.Reader{hamsSamples})
 	require.NoError(t, err)
 	assert.Equal(t, LoadResult{ExcludedTokens: 1, SpamSamples: 2, HamSamples: 3}, lr)
-	d.tokenizedSpam = nil // we don't need tokenizedSpam samples for this test
+	d.similarityIndex.reset() // we don't need similarity samples for this test
 	assert.Equal(t, 5, d.classifier.nAllDocument)
 	exp := map[string]map[spamClass]int{"win": {"spam": 1}, "free": {"spam": 1}, "iphone": {"spam": 1}, "lottery": {"spam": 1},
 		"prize": {"spam": 1}, "hello": {"ham": 1}, "world": {"ham": 1}, "how": {"ham": 1}, "are": {"ham": 1}, "you": {"ham": 1},
@@ -2386,17 +2654,62 @@ func TestDetector_Reset(t *testing.T) {
 	assert.Equal(t, LoadResult{StopWords: 2}, sr)
 
 	assert.Equal(t, 5, d.classifier.nAllDocument)
-	assert.Len(t, d.tokenizedSpam, 2)
+	assert.Equal(t, 2, d.similarityIndex.size())
 	assert.Len(t, d.excludedTokens, 1)
 	assert.Len(t, d.stopWords, 2)
 
 	d.Reset()
 	assert.Equal(t, 0, d.classifier.nAllDocument)
-	assert.Empty(t, d.tokenizedSpam)
+	assert.Zero(t, d.similarityIndex.size())
 	assert.Empty(t, d.excludedTokens)
 	assert.Empty(t, d.stopWords)
 }
 
+func TestDetector_Reconfigure(t *testing.T) {
+	d := NewDetector(Config{MaxAllowedEmoji: 1, MinMsgLen: 5, DuplicateDetection: struct {
+		Threshold int
+		Window    time.Duration
+	}{Threshold: 2, Window: time.Hour}})
+	_, err := d.LoadSamples(strings.NewReader(""), []io.Reader{strings.NewReader("win a free iphone now")},
+		[]io.Reader{strings.NewReader("hello world")})
+	require.NoError(t, err)
+	_, err = d.LoadStopWords(strings.NewReader("в личку"))
+	require.NoError(t, err)
+	require.NoError(t, d.AddApprovedUser(approved.UserInfo{UserID: "123", UserName: "user"}))
+	oldEngine := &mocks.LuaPluginEngineMock{CloseFunc: func() {}}
+	d.luaEngine = oldEngine
+	dups := d.duplicateDetector
+
+	spam, _ := d.Check(spamcheck.Request{Msg: "hi 😀😁😂 there"})
+	assert.True(t, spam, "too many emojis with the initial config")
+
+	src := NewDetector(Config{MaxAllowedEmoji: 5, MinMsgLen: 5, MultiLangWords: 1, DuplicateDetection: d.DuplicateDetection})
+	src.WithMetaChecks(LinksCheck(0))
+	d.Reconfigure(src)
+
+	assert.Equal(t, 5, d.MaxAllowedEmoji)
+	assert.Len(t, d.metaChecks, 1)
+	assert.Len(t, oldEngine.CloseCalls(), 1, "previous lua engine closed")
+	assert.Nil(t, d.luaEngine)
+	assert.Same(t, dups, d.duplicateDetector, "duplicates tracker kept, settings not changed")
+	assert.True(t, d.IsApprovedUser("123"), "approved users kept")
+	assert.Equal(t, 1, d.similarityIndex.size(), "samples kept")
+	assert.Equal(t, []string{"в личку"}, d.stopWords, "stop words kept")
+
+	spam, cr := d.Check(spamcheck.Request{Msg: "hi 😀😁😂 there"})
+	assert.False(t, spam, "emojis allowed with the new config, %v", cr)
+	spam, _ = d.Check(spamcheck.Request{Msg: "check http://example.com"})
+	assert.True(t, spam, "links check added")
+
+	src = NewDetector(Config{MinMsgLen: 5, DuplicateDetection: struct {
+		Threshold int
+		Window    time.Duration
+	}{Threshold: 3, Window: time.Hour}})
+	d.Reconfigure(src)
+	assert.NotSame(t, dups, d.duplicateDetector, "duplicates tracker replaced, settings changed")
+	assert.Equal(t, 3, d.duplicateDetector.threshold)
+}
+
 func TestDetector_FirstMessagesCount(t *testing.T) {
 	t.Run("first message is spam", func(t *testing.T) {
 		d := NewDetector(Config{MaxAllowedEmoji: 1, MinMsgLen: 5, FirstMessagesCount: 2, FirstMessageOnly: true})
@@ -2445,6 +2758,40 @@ func TestDetector_FirstMessagesCount(t *testing.T) {
 		spam, _ = d.Check(spamcheck.Request{Msg: "spam, too many emojis 🤣🤣🤣", UserID: "123"})
 		assert.False(t, spam)
 	})
+	t.Run("approval held by guard", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: 1, MinMsgLen: 5, FirstMessagesCount: 2, FirstMessageOnly: true})
+		guard := &mocks.ApprovalGuardMock{HoldApprovalFunc: func(ctx context.Context, userID string) (bool, string, error) {
+			if userID == "123" {
+				return true, "warned 5m0s ago", nil
+			}
+			return false, "", nil
+		}}
+		d.WithApprovalGuard(guard)
+
+		for range 3 {
+			spam, cr := d.Check(spamcheck.Request{Msg: "ham, no emojis", UserID: "123"})
+			assert.False(t, spam)
+			assert.Equal(t, spamcheck.Response{Name: "approval-hold", Details: "warned 5m0s ago"}, cr[len(cr)-1])
+		}
+		assert.False(t, d.IsApprovedUser("123"))
+		assert.Len(t, guard.HoldApprovalCalls(), 3)
+
+		spam, _ := d.Check(spamcheck.Request{Msg: "spam, too many emojis 🤣🤣🤣", UserID: "123"})
+		assert.True(t, spam, "held user is still checked")
+
+		for range 2 {
+			_, _ = d.Check(spamcheck.Request{Msg: "ham, no emojis", UserID: "456"})
+		}
+		assert.True(t, d.IsApprovedUser("456"))
+	})
+	t.Run("approval guard error doesn't hold", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: 1, MinMsgLen: 5, FirstMessagesCount: 1, FirstMessageOnly: true})
+		d.WithApprovalGuard(&mocks.ApprovalGuardMock{HoldApprovalFunc: func(ctx context.Context, userID string) (bool, string, error) {
+			return false, "", errors.New("db error")
+		}})
+		_, _ = d.Check(spamcheck.Request{Msg: "ham, no emojis", UserID: "123"})
+		assert.True(t, d.IsApprovedUser("123"))
+	})
 }
 
 func TestDetector_ApprovedUsers(t *testing.T) {
@@ -2639,9 +2986,9 @@ func TestDetector_LoadSamples(t *testing.T) {
 		assert.Contains(t, d.excludedTokens, "xyz")
 
 		// verify tokenized spam samples
-		assert.Len(t, d.tokenizedSpam, 2)
-		assert.Contains(t, d.tokenizedSpam[0], "win")
-		assert.Contains(t, d.tokenizedSpam[1], "lottery")
+		assert.Equal(t, 2, d.similarityIndex.size())
+		assert.Contains(t, sampleTokens(&d.similarityIndex, 0), "win")
+		assert.Contains(t, sampleTokens(&d.similarityIndex, 1), "lottery")
 
 		// verify classifier learning
 		assert.Equal(t, 5, d.classifier.nAllDocument)
@@ -3410,6 +3757,267 @@ func TestDetector_CheckWithShortMessageRunsOnlyEligibleLLMs(t *testing.T) {
 	assert.NotContains(t, checkNames, "openai")
 }
 
+func TestDetector_WithLLMChecker(t *testing.T) {
+	makeChecker := func(spam bool, reason string, calls *int) LLMChecker {
+		return LLMCheckFunc(func(_ context.Context, _ string, _ []spamcheck.Request) (bool, spamcheck.Response) {
+			*calls++
+			return spam, spamcheck.Response{Name: "provider", Spam: spam, Details: reason}
+		})
+	}
+
+	t.Run("named checkers take part in consensus", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessageOnly: true, LLMConsensus: LLMConsensusAll})
+		var localCalls, paidCalls int
+		require.NoError(t, d.WithLLMChecker("local", makeChecker(true, "local verdict", &localCalls), LLMCheckerOpts{}))
+		require.NoError(t, d.WithLLMChecker("paid", makeChecker(false, "paid verdict", &paidCalls), LLMCheckerOpts{}))
+
+		spam, cr := d.Check(spamcheck.Request{Msg: "hello there"})
+		assert.False(t, spam, "all consensus requires both checkers to flag spam")
+		assert.Equal(t, 1, localCalls)
+		assert.Equal(t, 1, paidCalls)
+		require.Len(t, cr, 2)
+		assert.Equal(t, spamcheck.Response{Name: "local", Spam: true, Details: "local verdict"}, cr[0])
+		assert.Equal(t, spamcheck.Response{Name: "paid", Spam: false, Details: "paid verdict"}, cr[1])
+	})
+
+	t.Run("veto checker runs on spam only", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessageOnly: true})
+		_, err := d.LoadStopWords(strings.NewReader("spamword"))
+		require.NoError(t, err)
+		var vetoCalls, regularCalls int
+		require.NoError(t, d.WithLLMChecker("veto", makeChecker(false, "not spam", &vetoCalls), LLMCheckerOpts{Veto: true}))
+		require.NoError(t, d.WithLLMChecker("regular", makeChecker(true, "spam", &regularCalls), LLMCheckerOpts{}))
+
+		spam, _ := d.Check(spamcheck.Request{Msg: "spamword message"})
+		assert.False(t, spam, "spam vetoed")
+		assert.Equal(t, 1, vetoCalls)
+		assert.Equal(t, 0, regularCalls)
+
+		spam, _ = d.Check(spamcheck.Request{Msg: "good message", UserID: "123"})
+		assert.True(t, spam)
+		assert.Equal(t, 1, vetoCalls)
+		assert.Equal(t, 1, regularCalls)
+	})
+
+	t.Run("short messages and history", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10, MinMsgLen: 50, HistorySize: 5})
+		var shortCalls int
+		var gotHistory []spamcheck.Request
+		short := LLMCheckFunc(func(_ context.Context, _ string, history []spamcheck.Request) (bool, spamcheck.Response) {
+			shortCalls++
+			gotHistory = history
+			return false, spamcheck.Response{Details: "ok"}
+		})
+		require.NoError(t, d.WithLLMChecker("short", short, LLMCheckerOpts{CheckShortMessages: true, HistorySize: 2}))
+
+		d.Check(spamcheck.Request{Msg: "a long enough message to be checked and added to the ham history", UserID: "1"})
+		spam, cr := d.Check(spamcheck.Request{Msg: "hi", UserID: "2"})
+		assert.False(t, spam)
+		assert.Equal(t, 2, shortCalls)
+		require.Len(t, gotHistory, 1)
+		assert.Equal(t, "1", gotHistory[0].UserID)
+		assert.Equal(t, "short", cr[len(cr)-1].Name)
+	})
+
+	t.Run("invalid registrations", func(t *testing.T) {
+		d := NewDetector(Config{})
+		var calls int
+		checker := makeChecker(false, "", &calls)
+		require.EqualError(t, d.WithLLMChecker("", checker, LLMCheckerOpts{}), "empty LLM checker name")
+		require.EqualError(t, d.WithLLMChecker("local", nil, LLMCheckerOpts{}), `nil LLM checker "local"`)
+		require.EqualError(t, d.WithLLMChecker("openai", checker, LLMCheckerOpts{}), `duplicate LLM checker name "openai"`)
+		require.NoError(t, d.WithLLMChecker("local", checker, LLMCheckerOpts{}))
+		require.EqualError(t, d.WithLLMChecker("local", checker, LLMCheckerOpts{}), `duplicate LLM checker name "local"`)
+	})
+}
+
+func TestDetector_CheckWithLLMDisagreement(t *testing.T) {
+	checker := func(spam bool) LLMChecker {
+		return LLMCheckFunc(func(_ context.Context, _ string, _ []spamcheck.Request) (bool, spamcheck.Response) {
+			return spam, spamcheck.Response{Spam: spam, Details: "verdict"}
+		})
+	}
+
+	tests := []struct {
+		name       string
+		baseSpam   bool
+		consensus  LLMConsensusMode
+		verdicts   []bool
+		wantSpam   bool
+		wantDetail string // empty if not suspicious
+	}{
+		{name: "agree on ham", verdicts: []bool{false, false}},
+		{name: "agree on spam", verdicts: []bool{true, true}, wantSpam: true},
+		{name: "disagree, all consensus keeps ham", consensus: LLMConsensusAll, verdicts: []bool{true, false},
+			wantDetail: "1 of 2 llm checks disagree"},
+		{name: "disagree, any consensus flips to spam", verdicts: []bool{true, false}, wantSpam: true},
+		{name: "veto overturned spam", baseSpam: true, verdicts: []bool{false},
+			wantDetail: "llm checks overturned spam decision"},
+		{name: "veto confirmed spam", baseSpam: true, verdicts: []bool{true}, wantSpam: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessageOnly: true, LLMConsensus: tc.consensus, LLMDisagreement: true})
+			req := spamcheck.Request{Msg: "hello there"}
+			if tc.baseSpam {
+				_, err := d.LoadStopWords(strings.NewReader("spamword"))
+				require.NoError(t, err)
+				req.Msg = "spamword message"
+			}
+			for i, v := range tc.verdicts {
+				require.NoError(t, d.WithLLMChecker(fmt.Sprintf("llm%d", i), checker(v), LLMCheckerOpts{Veto: tc.baseSpam}))
+			}
+
+			spam, cr := d.Check(req)
+			assert.Equal(t, tc.wantSpam, spam)
+			idx := slices.IndexFunc(cr, func(r spamcheck.Response) bool { return r.Name == "llm-disagreement" })
+			if tc.wantDetail == "" {
+				assert.Equal(t, -1, idx)
+				return
+			}
+			require.NotEqual(t, -1, idx)
+			assert.True(t, cr[idx].Suspicious)
+			assert.False(t, cr[idx].Spam)
+			assert.Equal(t, tc.wantDetail, cr[idx].Details)
+		})
+	}
+
+	t.Run("suspicious first message doesn't approve user", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessageOnly: true, FirstMessagesCount: 1,
+			LLMConsensus: LLMConsensusAll, LLMDisagreement: true})
+		require.NoError(t, d.WithLLMChecker("llm0", checker(true), LLMCheckerOpts{}))
+		require.NoError(t, d.WithLLMChecker("llm1", checker(false), LLMCheckerOpts{}))
+		spam, cr := d.Check(spamcheck.Request{Msg: "hello there", UserID: "123"})
+		assert.False(t, spam)
+		assert.True(t, slices.ContainsFunc(cr, func(r spamcheck.Response) bool { return r.Suspicious }))
+		assert.False(t, d.IsApprovedUser("123"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessageOnly: true, LLMConsensus: LLMConsensusAll})
+		require.NoError(t, d.WithLLMChecker("llm0", checker(true), LLMCheckerOpts{}))
+		require.NoError(t, d.WithLLMChecker("llm1", checker(false), LLMCheckerOpts{}))
+		spam, cr := d.Check(spamcheck.Request{Msg: "hello there"})
+		assert.False(t, spam)
+		assert.False(t, slices.ContainsFunc(cr, func(r spamcheck.Response) bool { return r.Suspicious }))
+	})
+}
+
+func TestDetector_WithLLMCache(t *testing.T) {
+	var calls int
+	checker := LLMCheckFunc(func(_ context.Context, msg string, _ []spamcheck.Request) (bool, spamcheck.Response) {
+		calls++
+		if msg == "broken message" {
+			return false, spamcheck.Response{Details: "failed", Error: errors.New("llm error")}
+		}
+		return true, spamcheck.Response{Spam: true, Details: "crypto scam, confidence: 90%"}
+	})
+
+	t.Run("repeated message reuses verdict", func(t *testing.T) {
+		calls = 0
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10})
+		d.WithLLMCache(NewLLMMemoryCache(10, time.Hour))
+		require.NoError(t, d.WithLLMChecker("local", checker, LLMCheckerOpts{CacheID: "model-1"}))
+
+		spam, cr := d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "1"})
+		assert.True(t, spam)
+		assert.Equal(t, spamcheck.Response{Name: "local", Spam: true, Details: "crypto scam, confidence: 90%"}, cr[len(cr)-1])
+
+		spam, cr = d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "2"})
+		assert.True(t, spam)
+		assert.Equal(t, 1, calls, "second check served from cache")
+		assert.Equal(t, spamcheck.Response{Name: "local", Spam: true, Details: "crypto scam, confidence: 90% (cached)"},
+			cr[len(cr)-1])
+
+		d.Check(spamcheck.Request{Msg: "buy crypto later", UserID: "3"})
+		assert.Equal(t, 2, calls, "different message not cached")
+	})
+
+	t.Run("cache keyed by checker identity", func(t *testing.T) {
+		calls = 0
+		c := NewLLMMemoryCache(10, time.Hour)
+		d1 := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10})
+		d1.WithLLMCache(c)
+		require.NoError(t, d1.WithLLMChecker("local", checker, LLMCheckerOpts{CacheID: "model-1"}))
+		d2 := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10})
+		d2.WithLLMCache(c)
+		require.NoError(t, d2.WithLLMChecker("local", checker, LLMCheckerOpts{CacheID: "model-2"}))
+
+		d1.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "1"})
+		d2.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "1"})
+		assert.Equal(t, 2, calls, "other model doesn't reuse the verdict")
+		d1.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "2"})
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("keyed by normalized message", func(t *testing.T) {
+		calls = 0
+		var got []string
+		recorder := LLMCheckFunc(func(ctx context.Context, msg string, hist []spamcheck.Request) (bool, spamcheck.Response) {
+			got = append(got, msg)
+			return checker(ctx, msg, hist)
+		})
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10, NormalizeText: true})
+		d.WithLLMCache(NewLLMMemoryCache(10, time.Hour))
+		require.NoError(t, d.WithLLMChecker("local", recorder, LLMCheckerOpts{}))
+
+		d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "1"})
+		_, cr := d.Check(spamcheck.Request{Msg: "buy crypt\u043e n\u043ew", UserID: "2"}) // cyrillic o
+		assert.Equal(t, 1, calls, "lookalike message reuses the verdict")
+		assert.Contains(t, cr[len(cr)-1].Details, "(cached)")
+		assert.Equal(t, []string{"buy crypto now"}, got, "checker gets the text before normalization")
+	})
+
+	t.Run("keyed by history", func(t *testing.T) {
+		calls = 0
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10, HistorySize: 5})
+		d.WithLLMCache(NewLLMMemoryCache(10, time.Hour))
+		require.NoError(t, d.WithLLMChecker("local", checker, LLMCheckerOpts{HistorySize: 5}))
+
+		d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "1"})
+		d.hamHistory.Push(spamcheck.Request{Msg: "hello everyone", UserName: "bob"})
+		_, cr := d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "2"})
+		assert.Equal(t, 2, calls, "verdict made with other history not reused")
+		assert.NotContains(t, cr[len(cr)-1].Details, "(cached)")
+	})
+
+	t.Run("errors not cached", func(t *testing.T) {
+		calls = 0
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10})
+		d.WithLLMCache(NewLLMMemoryCache(10, time.Hour))
+		require.NoError(t, d.WithLLMChecker("local", checker, LLMCheckerOpts{}))
+
+		d.Check(spamcheck.Request{Msg: "broken message", UserID: "1"})
+		_, cr := d.Check(spamcheck.Request{Msg: "broken message", UserID: "2"})
+		assert.Equal(t, 2, calls)
+		require.Error(t, cr[len(cr)-1].Error)
+	})
+
+	t.Run("cache failure falls back to checker", func(t *testing.T) {
+		calls = 0
+		d := NewDetector(Config{MaxAllowedEmoji: -1, FirstMessagesCount: 10})
+		d.WithLLMCache(failingLLMCache{})
+		require.NoError(t, d.WithLLMChecker("local", checker, LLMCheckerOpts{}))
+
+		spam, _ := d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "1"})
+		assert.True(t, spam)
+		spam, _ = d.Check(spamcheck.Request{Msg: "buy crypto now", UserID: "2"})
+		assert.True(t, spam)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+type failingLLMCache struct{}
+
+func (failingLLMCache) Get(context.Context, string) (LLMVerdict, bool, error) {
+	return LLMVerdict{}, false, errors.New("get failed")
+}
+
+func (failingLLMCache) Put(context.Context, string, LLMVerdict) error {
+	return errors.New("put failed")
+}
+
 func BenchmarkTokenize(b *testing.B) {
 	d := &Detector{
 		excludedTokens: map[string]struct{}{"the": {}, "and": {}, "or": {}, "but": {}, "in": {}, "on": {}, "at": {}, "to": {}},
@@ -4189,3 +4797,65 @@ func TestDetector_ProhibitedLang(t *testing.T) {
 		require.NotNil(t, findResponseByName(cr, "prohibited-language"))
 	})
 }
+
+func TestDetector_SeedApprovedUser(t *testing.T) {
+	mockUserStore := &mocks.UserStorageMock{
+		ReadFunc:  func(context.Context) ([]approved.UserInfo, error) { return nil, nil },
+		WriteFunc: func(_ context.Context, au approved.UserInfo) error { return nil },
+	}
+
+	t.Run("partial credit, user not approved yet", func(t *testing.T) {
+		mockUserStore.ResetCalls()
+		d := NewDetector(Config{MaxAllowedEmoji: -1, MinMsgLen: 5, FirstMessagesCount: 3})
+		_, err := d.WithUserStorage(mockUserStore)
+		require.NoError(t, err)
+
+		require.NoError(t, d.SeedApprovedUser(approved.UserInfo{UserID: "123", UserName: "user", Count: 2}))
+		assert.False(t, d.IsApprovedUser("123"))
+		require.Len(t, mockUserStore.WriteCalls(), 1)
+		assert.Equal(t, "123", mockUserStore.WriteCalls()[0].Au.UserID)
+		assert.Equal(t, 2, mockUserStore.WriteCalls()[0].Au.Count)
+
+		// one more ham message makes the user approved
+		isSpam, _ := d.Check(spamcheck.Request{Msg: "Hello, how are you my friend?", UserID: "123"})
+		assert.False(t, isSpam)
+		assert.True(t, d.IsApprovedUser("123"))
+	})
+
+	t.Run("full credit approves user", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, MinMsgLen: 5, FirstMessagesCount: 3})
+		require.NoError(t, d.SeedApprovedUser(approved.UserInfo{UserID: "123", Count: 3}))
+		assert.True(t, d.IsApprovedUser("123"))
+	})
+
+	t.Run("existing higher count preserved", func(t *testing.T) {
+		mockUserStore.ResetCalls()
+		d := NewDetector(Config{MaxAllowedEmoji: -1, MinMsgLen: 5, FirstMessagesCount: 3})
+		_, err := d.WithUserStorage(mockUserStore)
+		require.NoError(t, err)
+		require.NoError(t, d.AddApprovedUser(approved.UserInfo{UserID: "123"}))
+		mockUserStore.ResetCalls()
+
+		require.NoError(t, d.SeedApprovedUser(approved.UserInfo{UserID: "123", Count: 1}))
+		assert.True(t, d.IsApprovedUser("123"))
+		assert.Empty(t, mockUserStore.WriteCalls())
+	})
+
+	t.Run("zero count ignored", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, MinMsgLen: 5, FirstMessagesCount: 3})
+		require.NoError(t, d.SeedApprovedUser(approved.UserInfo{UserID: "123"}))
+		assert.Empty(t, d.ApprovedUsers())
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1, MinMsgLen: 5, FirstMessagesCount: 3})
+		_, err := d.WithUserStorage(&mocks.UserStorageMock{
+			ReadFunc:  func(context.Context) ([]approved.UserInfo, error) { return nil, nil },
+			WriteFunc: func(_ context.Context, au approved.UserInfo) error { return errors.New("write failed") },
+		})
+		require.NoError(t, err)
+		err = d.SeedApprovedUser(approved.UserInfo{UserID: "123", Count: 1})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "write failed")
+	})
+}
@@ -0,0 +1,268 @@
+package tgspam
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// DefaultShorteners is a list of well-known url shorteners, resolved if Domains.ExpandShortURLs is set
+var DefaultShorteners = []string{"bit.ly", "bit.do", "buff.ly", "clck.ru", "cutt.ly", "goo.gl", "is.gd", "ow.ly",
+	"rb.gy", "rebrand.ly", "s.id", "shorturl.at", "t.co", "t.ly", "tiny.cc", "tinyurl.com", "v.gd"}
+
+const (
+	maxDomainLinks      = 10               // links per message checked against domain lists, the rest are ignored
+	maxShortenerHops    = 3                // max chained redirects followed for a single short link
+	shortenerTimeout    = 5 * time.Second  // timeout of a single shortener request
+	shortenerMsgTimeout = 10 * time.Second // total time of resolving short links of a message
+	shortURLsCacheSize  = 1000             // max resolved short links kept in cache
+	shortURLsCacheTTL   = 24 * time.Hour   // how long resolved short links are kept in cache
+)
+
+// sharedAddrSpace is the carrier-grade NAT range (RFC 6598), not reachable from the internet
+var sharedAddrSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// domainLinkRe matches links with a scheme as well as bare domains like "example.com/path"
+var domainLinkRe = regexp.MustCompile(
+	`(?i)(?:https?://)?(?:[\p{L}\p{N}](?:[\p{L}\p{N}-]*[\p{L}\p{N}])?\.)+\p{L}{2,}(?::\d+)?(?:/\S*)?`)
+
+// LoadDomains loads blocked and allowed domains, one per line. Resets both lists before loading.
+// A domain matches itself and all its subdomains. Links to blocked domains are spam, and
+// messages with links to allowed domains only are not flagged by LinksCheck.
+func (d *Detector) LoadDomains(blocked, allowed io.Reader) (LoadResult, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.blockedDomains, d.allowedDomains = nil, nil
+	for t := range d.readerIterator(blocked) {
+		if domain := normalizeDomain(t); domain != "" {
+			d.blockedDomains = append(d.blockedDomains, domain)
+		}
+	}
+	for t := range d.readerIterator(allowed) {
+		if domain := normalizeDomain(t); domain != "" {
+			d.allowedDomains = append(d.allowedDomains, domain)
+		}
+	}
+	return LoadResult{BlockedDomains: len(d.blockedDomains), AllowedDomains: len(d.allowedDomains)}, nil
+}
+
+// isBlockedDomain checks links of the message against blocked domains. Short links are checked with their
+// destinations from expanded, see expandShortLinks. The second value is true if all links point to allowed domains.
+func (d *Detector) isBlockedDomain(req spamcheck.Request, expanded map[string]*url.URL) (resp spamcheck.Response, allowed bool) {
+	links := extractLinks(req)
+	if len(links) == 0 {
+		return spamcheck.Response{Name: "domains", Spam: false, Details: "no links"}, false
+	}
+
+	allowed = true
+	for _, link := range links {
+		hosts := []string{link.Hostname()}
+		if target, ok := expanded[link.String()]; ok && target.Hostname() != link.Hostname() {
+			hosts = append(hosts, target.Hostname())
+		}
+		for _, host := range hosts {
+			if domain, ok := matchDomain(host, d.blockedDomains); ok {
+				return spamcheck.Response{Name: "domains", Spam: true, Details: "blocked domain " + domain}, false
+			}
+		}
+		// the final destination decides for short links, the shortener itself is not allowed implicitly
+		if _, ok := matchDomain(hosts[len(hosts)-1], d.allowedDomains); !ok {
+			allowed = false
+		}
+	}
+	if allowed {
+		return spamcheck.Response{Name: "domains", Spam: false, Details: "allowed domains only"}, true
+	}
+	return spamcheck.Response{Name: "domains", Spam: false, Details: "no blocked domains"}, false
+}
+
+// expandShortLinks resolves links of url shorteners in the message if enabled, returns destinations by short link.
+// Called by Check before taking the detector lock, as resolving makes network requests, with the total time
+// limited by shortenerMsgTimeout. Messages of approved users are not checked, so their links are not resolved.
+func (d *Detector) expandShortLinks(req spamcheck.Request) map[string]*url.URL {
+	d.lock.RLock()
+	enabled := d.Domains.ExpandShortURLs && (len(d.blockedDomains) > 0 || len(d.allowedDomains) > 0) &&
+		!(req.UserID != "" && d.FirstMessageOnly && d.approvedCount(req.UserID) >= d.FirstMessagesCount)
+	shorteners := d.shorteners()
+	d.lock.RUnlock()
+	if !enabled {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shortenerMsgTimeout)
+	defer cancel()
+	res := map[string]*url.URL{}
+	for _, link := range extractLinks(req) {
+		if _, ok := matchDomain(link.Hostname(), shorteners); !ok {
+			continue
+		}
+		if target := d.expandShortURL(ctx, link, shorteners); target != nil {
+			res[link.String()] = target
+		}
+	}
+	return res
+}
+
+// shorteners returns the list of url shorteners to resolve, DefaultShorteners if not set
+func (d *Detector) shorteners() []string {
+	if len(d.Domains.Shorteners) == 0 {
+		return DefaultShorteners
+	}
+	return d.Domains.Shorteners
+}
+
+// expandShortURL resolves the short link walking Location headers of HEAD responses, up to maxShortenerHops
+// chained redirects. Returns nil if the link can't be resolved. Resolved links are cached, failed ones are not,
+// as the failure may be temporary.
+func (d *Detector) expandShortURL(ctx context.Context, link *url.URL, shorteners []string) *url.URL {
+	if target, ok := d.shortURLs.Get(link.String()); ok {
+		return target
+	}
+	current := link
+	for range maxShortenerHops {
+		next, err := d.resolveRedirect(ctx, current)
+		if err != nil {
+			log.Printf("[DEBUG] failed to expand short url %s: %v", current, err)
+			return nil
+		}
+		if next == nil || next.String() == current.String() {
+			break
+		}
+		current = next
+		if _, ok := matchDomain(current.Hostname(), shorteners); !ok {
+			break
+		}
+	}
+	if current == link {
+		current = nil
+	}
+	d.shortURLs.Add(link.String(), current)
+	return current
+}
+
+// resolveRedirect makes a HEAD request to the link and returns the target from the Location header
+// of the redirect response, nil if not redirected. The client doesn't follow redirects.
+func (d *Detector) resolveRedirect(ctx context.Context, link *url.URL) (*url.URL, error) {
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q", link.Scheme)
+	}
+	ctx, cancel := context.WithTimeout(ctx, shortenerTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link.String(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	resp, err := d.shortenerClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	loc := resp.Header.Get("Location")
+	if loc == "" || resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return nil, nil
+	}
+	target, err := link.Parse(loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect location %q: %w", loc, err)
+	}
+	return target, nil
+}
+
+// newShortenerClient makes the client resolving short links. It doesn't follow redirects, the detector walks
+// Location headers itself, and connects to public addresses only, so links posted to the chat can't be used
+// to reach the bot's local network. The address is checked on dial, after the host name is resolved.
+func newShortenerClient() *http.Client {
+	dialer := &net.Dialer{Timeout: shortenerTimeout, Control: func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		ip, err := netip.ParseAddr(host)
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		if !isPublicAddr(ip) {
+			return fmt.Errorf("non-public address %s", ip)
+		}
+		return nil
+	}}
+	return &http.Client{
+		Timeout:       shortenerTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: shortenerTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     time.Minute,
+		},
+	}
+}
+
+// isPublicAddr checks if the ip is a public unicast address, not a loopback, private, link-local or shared one
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddrSpace.Contains(ip)
+}
+
+// extractLinks returns links from the message text and from meta urls, e.g. text links.
+// Bare domains are treated as http links.
+func extractLinks(req spamcheck.Request) []*url.URL {
+	candidates := domainLinkRe.FindAllString(req.Msg, -1)
+	candidates = append(candidates, req.Meta.URLs...)
+
+	res := make([]*url.URL, 0, len(candidates))
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		if !strings.Contains(c, "://") {
+			c = "http://" + c
+		}
+		u, err := url.Parse(c)
+		if err != nil || u.Hostname() == "" || seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		res = append(res, u)
+		if len(res) == maxDomainLinks {
+			break
+		}
+	}
+	return res
+}
+
+// normalizeDomain makes a domain from the list entry, which may be a domain, a wildcard like *.example.com or a url
+func normalizeDomain(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil {
+			s = u.Hostname()
+		}
+	}
+	s = strings.TrimPrefix(s, "*.")
+	return strings.Trim(s, ".")
+}
+
+// matchDomain checks if the host is one of the domains or their subdomain, returns the matched domain
+func matchDomain(host string, domains []string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	idx := slices.IndexFunc(domains, func(domain string) bool {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	})
+	if idx < 0 {
+		return "", false
+	}
+	return domains[idx], true
+}
@@ -0,0 +1,259 @@
+package tgspam
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/approved"
+	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam/mocks"
+)
+
+func TestDetector_LoadDomains(t *testing.T) {
+	d := NewDetector(Config{MaxAllowedEmoji: -1})
+	lr, err := d.LoadDomains(strings.NewReader("Spam.example.com\n*.scam.io\nhttps://phish.net/login\n\n"),
+		strings.NewReader("github.com."))
+	require.NoError(t, err)
+	assert.Equal(t, LoadResult{BlockedDomains: 3, AllowedDomains: 1}, lr)
+	assert.Equal(t, []string{"spam.example.com", "scam.io", "phish.net"}, d.blockedDomains)
+	assert.Equal(t, []string{"github.com"}, d.allowedDomains)
+
+	lr, err = d.LoadDomains(strings.NewReader(""), strings.NewReader("go.dev"))
+	require.NoError(t, err)
+	assert.Equal(t, LoadResult{AllowedDomains: 1}, lr, "lists are reset on load")
+	assert.Empty(t, d.blockedDomains)
+
+	d.Reset()
+	assert.Empty(t, d.allowedDomains)
+}
+
+func TestDetector_CheckDomains(t *testing.T) {
+	d := NewDetector(Config{MaxAllowedEmoji: -1})
+	d.WithMetaChecks(LinksCheck(1))
+	_, err := d.LoadDomains(strings.NewReader("spam.example.com\nscam.io"), strings.NewReader("github.com\ngo.dev"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		req         spamcheck.Request
+		spam        bool // overall result
+		blocked     bool // domains check result
+		details     string
+		linksDetail string
+	}{
+		{name: "no links", req: spamcheck.Request{Msg: "hello there"}, details: "no links", linksDetail: "links 0/1"},
+		{name: "blocked domain", req: spamcheck.Request{Msg: "buy now https://spam.example.com/offer"}, spam: true, blocked: true,
+			details: "blocked domain spam.example.com", linksDetail: "links 1/1"},
+		{name: "blocked subdomain, bare link", req: spamcheck.Request{Msg: "visit promo.scam.io today"}, spam: true, blocked: true,
+			details: "blocked domain scam.io", linksDetail: "links 0/1"},
+		{name: "blocked text link", req: spamcheck.Request{Msg: "click here",
+			Meta: spamcheck.MetaData{Links: 1, URLs: []string{"https://SCAM.io/x"}}}, spam: true, blocked: true,
+			details: "blocked domain scam.io", linksDetail: "links 1/1"},
+		{name: "lookalike domain not blocked", req: spamcheck.Request{Msg: "see https://notscam.io"},
+			details: "no blocked domains", linksDetail: "links 1/1"},
+		{name: "allowed domains exempt from links check",
+			req:     spamcheck.Request{Msg: "see https://github.com/umputun and https://go.dev/doc"},
+			details: "allowed domains only", linksDetail: "links to allowed domains only"},
+		{name: "mixed domains not exempt", req: spamcheck.Request{Msg: "see https://github.com/a and https://other.com/b"},
+			spam: true, details: "no blocked domains", linksDetail: "too many links 2/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spam, cr := d.Check(tt.req)
+			assert.Equal(t, tt.spam, spam)
+			assert.Contains(t, cr, spamcheck.Response{Name: "domains", Spam: tt.blocked, Details: tt.details})
+			for _, r := range cr {
+				if r.Name == "links" {
+					assert.Equal(t, tt.linksDetail, r.Details)
+				}
+			}
+		})
+	}
+
+	t.Run("disabled without lists", func(t *testing.T) {
+		d := NewDetector(Config{MaxAllowedEmoji: -1})
+		_, cr := d.Check(spamcheck.Request{Msg: "https://spam.example.com"})
+		for _, r := range cr {
+			assert.NotEqual(t, "domains", r.Name)
+		}
+	})
+}
+
+func TestDetector_CheckDomainsShortener(t *testing.T) {
+	redirects := map[string]string{
+		"https://bit.ly/abc":      "https://tinyurl.com/xyz",
+		"https://tinyurl.com/xyz": "https://spam.example.com/landing",
+		"https://bit.ly/ok":       "https://github.com/umputun/tg-spam",
+	}
+	client := &mocks.HTTPClientMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodHead {
+			return nil, errors.New("unexpected method " + req.Method)
+		}
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}
+		if target, ok := redirects[req.URL.String()]; ok {
+			resp.StatusCode = http.StatusMovedPermanently
+			resp.Header.Set("Location", target)
+		}
+		if req.URL.Path == "/broken" {
+			return nil, errors.New("connection refused")
+		}
+		return resp, nil
+	}}
+
+	newDetector := func(expand bool) *Detector {
+		d := NewDetector(Config{MaxAllowedEmoji: -1})
+		d.shortenerClient = client
+		d.Domains.ExpandShortURLs = expand
+		_, err := d.LoadDomains(strings.NewReader("spam.example.com"), strings.NewReader("github.com"))
+		require.NoError(t, err)
+		return d
+	}
+
+	t.Run("chained short links resolved", func(t *testing.T) {
+		client.ResetCalls()
+		spam, cr := newDetector(true).Check(spamcheck.Request{Msg: "free stuff https://bit.ly/abc"})
+		assert.True(t, spam)
+		assert.Contains(t, cr, spamcheck.Response{Name: "domains", Spam: true, Details: "blocked domain spam.example.com"})
+		assert.Len(t, client.DoCalls(), 2)
+	})
+
+	t.Run("resolved links cached", func(t *testing.T) {
+		client.ResetCalls()
+		d := newDetector(true)
+		for range 3 {
+			spam, _ := d.Check(spamcheck.Request{Msg: "free stuff https://bit.ly/abc"})
+			assert.True(t, spam)
+		}
+		assert.Len(t, client.DoCalls(), 2, "resolved once")
+	})
+
+	t.Run("short link to allowed domain", func(t *testing.T) {
+		_, cr := newDetector(true).Check(spamcheck.Request{Msg: "https://bit.ly/ok"})
+		assert.Contains(t, cr, spamcheck.Response{Name: "domains", Spam: false, Details: "allowed domains only"})
+	})
+
+	t.Run("failed expansion", func(t *testing.T) {
+		spam, cr := newDetector(true).Check(spamcheck.Request{Msg: "https://bit.ly/broken"})
+		assert.False(t, spam)
+		assert.Contains(t, cr, spamcheck.Response{Name: "domains", Spam: false, Details: "no blocked domains"})
+	})
+
+	t.Run("expansion disabled", func(t *testing.T) {
+		client.ResetCalls()
+		spam, _ := newDetector(false).Check(spamcheck.Request{Msg: "free stuff https://bit.ly/abc"})
+		assert.False(t, spam)
+		assert.Empty(t, client.DoCalls())
+	})
+
+	t.Run("approved user", func(t *testing.T) {
+		client.ResetCalls()
+		d := newDetector(true)
+		d.FirstMessageOnly, d.FirstMessagesCount = true, 1
+		require.NoError(t, d.AddApprovedUser(approved.UserInfo{UserID: "123"}))
+		spam, _ := d.Check(spamcheck.Request{Msg: "free stuff https://bit.ly/abc", UserID: "123"})
+		assert.False(t, spam)
+		assert.Empty(t, client.DoCalls(), "links of approved users not resolved")
+	})
+
+	t.Run("final url of response ignored", func(t *testing.T) {
+		followClient := &mocks.HTTPClientMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+			final, _ := url.Parse("https://spam.example.com/final")
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")),
+				Request: &http.Request{URL: final}}, nil
+		}}
+		d := NewDetector(Config{MaxAllowedEmoji: -1})
+		d.shortenerClient = followClient
+		d.Domains.ExpandShortURLs = true
+		d.Domains.Shorteners = []string{"short.example.org"}
+		_, err := d.LoadDomains(strings.NewReader("spam.example.com"), strings.NewReader(""))
+		require.NoError(t, err)
+
+		spam, _ := d.Check(spamcheck.Request{Msg: "https://short.example.org/a"})
+		assert.False(t, spam, "only Location header of redirect response is followed")
+		spam, _ = d.Check(spamcheck.Request{Msg: "https://bit.ly/abc"})
+		assert.False(t, spam, "custom shorteners replace the default list")
+		assert.Len(t, followClient.DoCalls(), 1)
+	})
+
+	t.Run("total time limited", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skip slow test in short mode")
+		}
+		slowClient := &mocks.HTTPClientMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}}
+		d := newDetector(true)
+		d.shortenerClient = slowClient
+		links := make([]string, 0, maxDomainLinks)
+		for i := range maxDomainLinks {
+			links = append(links, fmt.Sprintf("https://bit.ly/slow%d", i))
+		}
+		st := time.Now()
+		spam, _ := d.Check(spamcheck.Request{Msg: strings.Join(links, " ")})
+		assert.False(t, spam)
+		assert.Less(t, time.Since(st), shortenerMsgTimeout+time.Second)
+	})
+}
+
+func TestNewShortenerClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://spam.example.com/landing", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	client := newShortenerClient()
+	req, err := http.NewRequest(http.MethodHead, ts.URL, http.NoBody)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	require.Error(t, err, "loopback address rejected")
+	assert.Contains(t, err.Error(), "non-public address 127.0.0.1")
+
+	// same client with the address check dropped, to check redirects are not followed
+	client.Transport = http.DefaultTransport
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://spam.example.com/landing", resp.Header.Get("Location"))
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true}, {"2001:4860:4860::8888", true}, {"::ffff:8.8.8.8", true},
+		{"127.0.0.1", false}, {"::1", false}, {"10.1.2.3", false}, {"172.16.0.1", false}, {"192.168.1.1", false},
+		{"169.254.169.254", false}, {"fe80::1", false}, {"fc00::1", false}, {"0.0.0.0", false}, {"::", false},
+		{"100.64.0.1", false}, {"224.0.0.1", false}, {"::ffff:127.0.0.1", false}, {"::ffff:192.168.1.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPublicAddr(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	links := extractLinks(spamcheck.Request{
+		Msg:  "visit example.com, https://Sub.Example.org:8080/path?q=1 and пример.рф/страница, again example.com",
+		Meta: spamcheck.MetaData{URLs: []string{"https://hidden.example.net/x", "://bad"}},
+	})
+	hosts := make([]string, 0, len(links))
+	for _, l := range links {
+		hosts = append(hosts, l.Hostname())
+	}
+	assert.Equal(t, []string{"example.com", "Sub.Example.org", "пример.рф", "hidden.example.net"}, hosts)
+}
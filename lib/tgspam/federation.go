@@ -0,0 +1,225 @@
+package tgspam
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// FederationCheckName is the name of the check matching users banned by federation peers
+const FederationCheckName = "federation"
+
+// FederationSignatureHeader is the http header with base64 ed25519 signature of the federation feed body
+const FederationSignatureHeader = "X-Tg-Spam-Signature"
+
+// maxFederationFeedAge is the max age of a peer feed, older feeds are rejected to prevent replay of stale lists
+const maxFederationFeedAge = 24 * time.Hour
+
+// maxFederationFeedSize limits the size of a peer feed response
+const maxFederationFeedSize = 64 * 1024 * 1024
+
+// FederationTrust is a trust level of a federation peer, defines what a match with the peer's ban means
+type FederationTrust string
+
+// federation trust levels
+const (
+	FederationTrustBan    FederationTrust = "ban"    // match is spam
+	FederationTrustReview FederationTrust = "review" // match is suspicious, the message goes to admins for review
+)
+
+// FederatedBan is a user banned by an instance, published in the federation feed
+type FederatedBan struct {
+	Peer     string    `json:"-"` // name of the peer the ban came from, set by the subscriber
+	UserID   int64     `json:"user_id"`
+	UserName string    `json:"user_name,omitempty"`
+	Reasons  []string  `json:"reasons,omitempty"` // names of the checks detected spam
+	BannedAt time.Time `json:"banned_at"`
+}
+
+// FederationFeed is a list of recently banned users published by an instance
+type FederationFeed struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Bans        []FederatedBan `json:"bans"`
+}
+
+// FederationPeer is a subscribed instance with its public key and trust level
+type FederationPeer struct {
+	Name      string            // unique peer name, used in check details and storage
+	URL       string            // url of the peer feed, e.g. https://example.com/federation/feed
+	PublicKey ed25519.PublicKey // peer public key to verify the feed signature
+	Trust     FederationTrust   // trust level of the peer
+}
+
+// FederationLookup is an interface to find users banned by federation peers.
+// Implemented by *storage.FederatedBans.
+type FederationLookup interface {
+	FindFederatedBans(ctx context.Context, userID int64) ([]FederatedBan, error)
+}
+
+// FederationStore is an interface to store bans pulled from federation peers.
+// Implemented by *storage.FederatedBans.
+type FederationStore interface {
+	ReplacePeerBans(ctx context.Context, peer string, bans []FederatedBan) error // replace all stored bans of the peer
+}
+
+// SignFederationFeed marshals the feed and signs it with the private key.
+// Returns the feed body and base64 encoded signature of the body.
+func SignFederationFeed(key ed25519.PrivateKey, feed FederationFeed) (body []byte, signature string, err error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, "", fmt.Errorf("invalid federation private key size %d", len(key))
+	}
+	body, err = json.Marshal(feed)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal federation feed: %w", err)
+	}
+	return body, base64.StdEncoding.EncodeToString(ed25519.Sign(key, body)), nil
+}
+
+// VerifyFederationFeed checks the base64 encoded signature of the feed body with the public key and unmarshals the feed
+func VerifyFederationFeed(key ed25519.PublicKey, body []byte, signature string) (FederationFeed, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return FederationFeed{}, fmt.Errorf("invalid federation public key size %d", len(key))
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return FederationFeed{}, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !ed25519.Verify(key, body, sig) {
+		return FederationFeed{}, errors.New("signature mismatch")
+	}
+	var feed FederationFeed
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return FederationFeed{}, fmt.Errorf("failed to unmarshal federation feed: %w", err)
+	}
+	return feed, nil
+}
+
+// FederationCheck is a function that returns a MetaCheck function.
+// It checks if the user is banned by any of the federation peers. A match with a peer of FederationTrustBan
+// level is spam, a match with a peer of FederationTrustReview level marks the message as suspicious.
+// Matches with peers missing in trust map (e.g. removed from config) are ignored.
+func FederationCheck(lookup FederationLookup, trust map[string]FederationTrust) MetaCheck {
+	return func(req spamcheck.Request) spamcheck.Response {
+		userID, err := strconv.ParseInt(req.UserID, 10, 64)
+		if err != nil || userID == 0 {
+			return spamcheck.Response{Name: FederationCheckName, Spam: false, Details: "no user id"}
+		}
+		bans, err := lookup.FindFederatedBans(context.Background(), userID)
+		if err != nil {
+			return spamcheck.Response{Name: FederationCheckName, Spam: false, Details: "failed to find federated bans", Error: err}
+		}
+
+		var banned, review []string
+		for _, b := range bans {
+			details := b.Peer
+			if len(b.Reasons) > 0 {
+				details += " (" + strings.Join(b.Reasons, ", ") + ")"
+			}
+			switch trust[b.Peer] {
+			case FederationTrustBan:
+				banned = append(banned, details)
+			case FederationTrustReview:
+				review = append(review, details)
+			}
+		}
+		switch {
+		case len(banned) > 0:
+			return spamcheck.Response{Name: FederationCheckName, Spam: true,
+				Details: "banned by " + strings.Join(append(banned, review...), ", ")}
+		case len(review) > 0:
+			return spamcheck.Response{Name: FederationCheckName, Spam: false, Suspicious: true,
+				Details: "banned by " + strings.Join(review, ", ")}
+		}
+		return spamcheck.Response{Name: FederationCheckName, Spam: false, Details: "not banned by peers"}
+	}
+}
+
+// FederationSyncer periodically pulls feeds of federation peers, verifies signatures and stores the bans
+type FederationSyncer struct {
+	Peers      []FederationPeer // peers to pull feeds from
+	HTTPClient HTTPClient       // http client to use for requests
+	Store      FederationStore  // storage for pulled bans
+	Interval   time.Duration    // interval between pulls
+}
+
+// Run pulls all peer feeds immediately and then every Interval, until the context is canceled.
+// Failed pulls are logged, previously pulled bans of the peer stay in use.
+func (s *FederationSyncer) Run(ctx context.Context) {
+	if s.Interval <= 0 || len(s.Peers) == 0 {
+		log.Printf("[WARN] no federation peers or pull interval, federation pull disabled")
+		return
+	}
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		for _, p := range s.Peers {
+			count, err := s.Sync(ctx, p)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("[WARN] failed to pull federation feed of %q: %v", p.Name, err)
+				continue
+			}
+			log.Printf("[DEBUG] federation feed of %q pulled, %d bans", p.Name, count)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pulls the feed of a single peer, verifies its signature and replaces stored bans of the peer.
+// Returns the number of bans in the feed.
+func (s *FederationSyncer) Sync(ctx context.Context, peer FederationPeer) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer.URL, http.NoBody)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make request %s: %w", peer.URL, err)
+	}
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request %s: %w", peer.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, peer.URL)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFederationFeedSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read feed from %s: %w", peer.URL, err)
+	}
+
+	feed, err := VerifyFederationFeed(peer.PublicKey, body, resp.Header.Get(FederationSignatureHeader))
+	if err != nil {
+		return 0, fmt.Errorf("invalid feed from %s: %w", peer.URL, err)
+	}
+	if age := time.Since(feed.GeneratedAt); age > maxFederationFeedAge {
+		return 0, fmt.Errorf("stale feed from %s, generated %v ago", peer.URL, age.Round(time.Minute))
+	}
+
+	bans := make([]FederatedBan, 0, len(feed.Bans))
+	for _, b := range feed.Bans {
+		if b.UserID == 0 {
+			continue
+		}
+		b.Peer = peer.Name
+		bans = append(bans, b)
+	}
+	if err := s.Store.ReplacePeerBans(ctx, peer.Name, bans); err != nil {
+		return 0, fmt.Errorf("failed to store bans of %q: %w", peer.Name, err)
+	}
+	return len(bans), nil
+}
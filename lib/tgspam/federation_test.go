@@ -0,0 +1,234 @@
+package tgspam
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestSignVerifyFederationFeed(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	feed := FederationFeed{GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Bans: []FederatedBan{{UserID: 123, UserName: "spammer", Reasons: []string{"cas"}, BannedAt: time.Now().UTC().Truncate(time.Second)}}}
+
+	body, sig, err := SignFederationFeed(priv, feed)
+	require.NoError(t, err)
+
+	res, err := VerifyFederationFeed(pub, body, sig)
+	require.NoError(t, err)
+	assert.Equal(t, feed, res)
+
+	t.Run("tampered body", func(t *testing.T) {
+		tampered := append([]byte{}, body...)
+		tampered[len(tampered)-2] = ' '
+		_, err := VerifyFederationFeed(pub, tampered, sig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "signature mismatch")
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		otherPub, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		_, err = VerifyFederationFeed(otherPub, body, sig)
+		require.Error(t, err)
+	})
+
+	t.Run("bad signature encoding", func(t *testing.T) {
+		_, err := VerifyFederationFeed(pub, body, "not base64!")
+		require.Error(t, err)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		_, _, err := SignFederationFeed(ed25519.PrivateKey("short"), feed)
+		require.Error(t, err)
+		_, err = VerifyFederationFeed(ed25519.PublicKey("short"), body, sig)
+		require.Error(t, err)
+	})
+}
+
+func TestFederationCheck(t *testing.T) {
+	lookup := federationLookupFunc(func(_ context.Context, userID int64) ([]FederatedBan, error) {
+		switch userID {
+		case 1:
+			return []FederatedBan{{Peer: "friends", UserID: 1, Reasons: []string{"cas", "stopword"}}}, nil
+		case 2:
+			return []FederatedBan{{Peer: "strangers", UserID: 2}}, nil
+		case 3:
+			return []FederatedBan{{Peer: "strangers", UserID: 3}, {Peer: "friends", UserID: 3}}, nil
+		case 4:
+			return []FederatedBan{{Peer: "removed", UserID: 4}}, nil
+		case 5:
+			return nil, errors.New("db error")
+		}
+		return nil, nil
+	})
+	check := FederationCheck(lookup, map[string]FederationTrust{"friends": FederationTrustBan, "strangers": FederationTrustReview})
+
+	tests := []struct {
+		name   string
+		userID string
+		want   spamcheck.Response
+	}{
+		{name: "trusted peer", userID: "1",
+			want: spamcheck.Response{Name: "federation", Spam: true, Details: "banned by friends (cas, stopword)"}},
+		{name: "review peer", userID: "2",
+			want: spamcheck.Response{Name: "federation", Suspicious: true, Details: "banned by strangers"}},
+		{name: "both peers", userID: "3",
+			want: spamcheck.Response{Name: "federation", Spam: true, Details: "banned by friends, strangers"}},
+		{name: "unknown peer", userID: "4",
+			want: spamcheck.Response{Name: "federation", Details: "not banned by peers"}},
+		{name: "not banned", userID: "6",
+			want: spamcheck.Response{Name: "federation", Details: "not banned by peers"}},
+		{name: "no user id", userID: "",
+			want: spamcheck.Response{Name: "federation", Details: "no user id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, check(spamcheck.Request{UserID: tt.userID}))
+		})
+	}
+
+	t.Run("lookup error", func(t *testing.T) {
+		resp := check(spamcheck.Request{UserID: "5"})
+		assert.False(t, resp.Spam)
+		require.Error(t, resp.Error)
+	})
+}
+
+func TestFederationSyncer_Sync(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	feedHandler := func(feed FederationFeed, key ed25519.PrivateKey) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, sig, err := SignFederationFeed(key, feed)
+			require.NoError(t, err)
+			w.Header().Set(FederationSignatureHeader, sig)
+			_, _ = w.Write(body)
+		}
+	}
+	feed := FederationFeed{GeneratedAt: time.Now(), Bans: []FederatedBan{
+		{UserID: 100, Reasons: []string{"classifier"}, BannedAt: time.Now()}, {UserID: 0}, {UserID: 200}}}
+
+	t.Run("valid feed", func(t *testing.T) {
+		ts := httptest.NewServer(feedHandler(feed, priv))
+		defer ts.Close()
+
+		store := &fakeFederationStore{}
+		s := FederationSyncer{HTTPClient: ts.Client(), Store: store}
+		count, err := s.Sync(context.Background(), FederationPeer{Name: "peer1", URL: ts.URL, PublicKey: pub})
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		require.Len(t, store.bans["peer1"], 2)
+		assert.Equal(t, "peer1", store.bans["peer1"][0].Peer)
+		assert.Equal(t, int64(100), store.bans["peer1"][0].UserID)
+		assert.Equal(t, []string{"classifier"}, store.bans["peer1"][0].Reasons)
+		assert.Equal(t, int64(200), store.bans["peer1"][1].UserID)
+	})
+
+	t.Run("signed by other key", func(t *testing.T) {
+		_, otherPriv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		ts := httptest.NewServer(feedHandler(feed, otherPriv))
+		defer ts.Close()
+
+		store := &fakeFederationStore{}
+		s := FederationSyncer{HTTPClient: ts.Client(), Store: store}
+		_, err = s.Sync(context.Background(), FederationPeer{Name: "peer1", URL: ts.URL, PublicKey: pub})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "signature mismatch")
+		assert.Empty(t, store.bans)
+	})
+
+	t.Run("stale feed", func(t *testing.T) {
+		ts := httptest.NewServer(feedHandler(FederationFeed{GeneratedAt: time.Now().Add(-48 * time.Hour)}, priv))
+		defer ts.Close()
+
+		store := &fakeFederationStore{}
+		s := FederationSyncer{HTTPClient: ts.Client(), Store: store}
+		_, err = s.Sync(context.Background(), FederationPeer{Name: "peer1", URL: ts.URL, PublicKey: pub})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "stale feed")
+		assert.Empty(t, store.bans)
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer ts.Close()
+
+		s := FederationSyncer{HTTPClient: ts.Client(), Store: &fakeFederationStore{}}
+		_, err = s.Sync(context.Background(), FederationPeer{Name: "peer1", URL: ts.URL, PublicKey: pub})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected status 403")
+	})
+}
+
+func TestFederationSyncer_Run(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, sig, err := SignFederationFeed(priv, FederationFeed{GeneratedAt: time.Now(), Bans: []FederatedBan{{UserID: 1}}})
+		require.NoError(t, err)
+		w.Header().Set(FederationSignatureHeader, sig)
+		_, _ = w.Write(body)
+	}))
+	defer ts.Close()
+
+	store := &fakeFederationStore{}
+	s := FederationSyncer{HTTPClient: ts.Client(), Store: store, Interval: 10 * time.Millisecond, Peers: []FederationPeer{
+		{Name: "broken", URL: ts.URL, PublicKey: ed25519.PublicKey("bad key")},
+		{Name: "good", URL: ts.URL, PublicKey: pub},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return store.calls() >= 2 }, time.Second, 5*time.Millisecond,
+		"broken peer doesn't stop pulling of others")
+	cancel()
+	<-done
+}
+
+type federationLookupFunc func(ctx context.Context, userID int64) ([]FederatedBan, error)
+
+func (f federationLookupFunc) FindFederatedBans(ctx context.Context, userID int64) ([]FederatedBan, error) {
+	return f(ctx, userID)
+}
+
+type fakeFederationStore struct {
+	mu      sync.Mutex
+	bans    map[string][]FederatedBan
+	replace int
+}
+
+func (f *fakeFederationStore) ReplacePeerBans(_ context.Context, peer string, bans []FederatedBan) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.bans == nil {
+		f.bans = map[string][]FederatedBan{}
+	}
+	f.bans[peer] = bans
+	f.replace++
+	return nil
+}
+
+func (f *fakeFederationStore) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.replace
+}
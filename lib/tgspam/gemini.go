@@ -4,8 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strconv"
-	"strings"
 
 	"google.golang.org/genai"
 
@@ -55,6 +53,11 @@ func newGeminiChecker(client geminiClient, params GeminiConfig) *geminiChecker {
 	return &geminiChecker{client: client, params: params}
 }
 
+// NewGeminiChecker makes an LLMChecker for Gemini API
+func NewGeminiChecker(client geminiClient, params GeminiConfig) LLMChecker {
+	return LLMCheckFunc(newGeminiChecker(client, params).check)
+}
+
 // check checks if a text is spam using Gemini API
 func (g *geminiChecker) check(ctx context.Context, msg string, history []spamcheck.Request) (spam bool, cr spamcheck.Response) {
 	if g.client == nil {
@@ -66,31 +69,11 @@ func (g *geminiChecker) check(ctx context.Context, msg string, history []spamche
 
 // buildSystemPrompt creates the complete system prompt by combining the base prompt with custom prompts
 func (g *geminiChecker) buildSystemPrompt() string {
-	basePrompt := g.params.SystemPrompt
-
-	if len(g.params.CustomPrompts) == 0 {
-		return basePrompt
-	}
-
-	var sb strings.Builder
-	sb.WriteString(basePrompt)
-	sb.WriteString("\n\nAlso, specifically check for these patterns:\n")
-
-	for i, prompt := range g.params.CustomPrompts {
-		sb.WriteString(strconv.Itoa(i+1) + ". " + prompt + "\n")
-	}
-
-	return sb.String()
+	return buildLLMSystemPrompt(g.params.SystemPrompt, g.params.CustomPrompts)
 }
 
 func (g *geminiChecker) sendRequest(ctx context.Context, msg string) (response llmResponse, err error) {
-	// truncate request if needed
-	if len(msg) > g.params.MaxSymbolsRequest {
-		runes := []rune(msg)
-		if len(runes) > g.params.MaxSymbolsRequest {
-			msg = string(runes[:g.params.MaxSymbolsRequest])
-		}
-	}
+	msg = truncateLLMRequest(msg, g.params.MaxSymbolsRequest)
 
 	completeSystemPrompt := g.buildSystemPrompt()
 
@@ -0,0 +1,108 @@
+package tgspam
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"  // register gif decoder
+	_ "image/jpeg" // register jpeg decoder
+	_ "image/png"  // register png decoder
+	"math/bits"
+	"strconv"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+//go:generate moq --out mocks/image_hash_store.go --pkg mocks --skip-ensure --with-resets . ImageHashStore
+
+// ImageHashStore provides perceptual hashes of known spam images, as made by ImageHash
+type ImageHashStore interface {
+	Hashes(ctx context.Context) ([]string, error)
+}
+
+// dHash is made of 8 rows with 9 cells each, every row gives 8 bits comparing adjacent cells
+const (
+	imageHashWidth  = 9
+	imageHashHeight = 8
+	imageHashSample = 256 // max number of sampled pixels per dimension, larger images are sampled with a step
+)
+
+// ImageHash calculates a perceptual difference hash (dHash) of the image and returns it as a 16 chars hex string.
+// The image is reduced to 9x8 grayscale cells and each bit is set if the cell is brighter than its right neighbor,
+// so the hash survives re-compression, resizing and small edits like an added caption or a changed phone number.
+// Supports jpeg, png and gif images.
+func ImageHash(img []byte) (string, error) {
+	src, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	b := src.Bounds()
+	if b.Dx() < imageHashWidth || b.Dy() < imageHashHeight {
+		return "", fmt.Errorf("image is too small, %dx%d", b.Dx(), b.Dy())
+	}
+
+	stepX, stepY := max(1, b.Dx()/imageHashSample), max(1, b.Dy()/imageHashSample)
+	var sums [imageHashHeight][imageHashWidth]float64
+	var counts [imageHashHeight][imageHashWidth]int
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		cy := (y - b.Min.Y) * imageHashHeight / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			cx := (x - b.Min.X) * imageHashWidth / b.Dx()
+			r, g, bl, _ := src.At(x, y).RGBA()
+			sums[cy][cx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl) // luminance
+			counts[cy][cx]++
+		}
+	}
+
+	var hash uint64
+	for y := range imageHashHeight {
+		for x := range imageHashWidth - 1 {
+			hash <<= 1
+			if sums[y][x]/float64(counts[y][x]) > sums[y][x+1]/float64(counts[y][x+1]) {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+// ImageHashDistance returns the Hamming distance between two image hashes, i.e. the number of different bits.
+// 0 means the same (or nearly the same) image, distances up to 5-10 usually mean a modified copy of the image.
+func ImageHashDistance(a, b string) (int, error) {
+	ha, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid image hash %q: %w", a, err)
+	}
+	hb, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid image hash %q: %w", b, err)
+	}
+	return bits.OnesCount64(ha ^ hb), nil
+}
+
+// ImageHashCheck is a function that returns a MetaCheck function.
+// It checks if the message image hash (req.Meta.ImageHash) is within maxDistance from any of the known spam
+// image hashes provided by the store. Messages without image hash are not checked.
+func ImageHashCheck(store ImageHashStore, maxDistance int) MetaCheck {
+	return func(req spamcheck.Request) spamcheck.Response {
+		if req.Meta.ImageHash == "" {
+			return spamcheck.Response{Name: "image-hash", Spam: false, Details: "no image hash"}
+		}
+		hashes, err := store.Hashes(context.Background())
+		if err != nil {
+			return spamcheck.Response{Name: "image-hash", Spam: false, Details: "failed to get image hashes", Error: err}
+		}
+		for _, h := range hashes {
+			dist, err := ImageHashDistance(req.Meta.ImageHash, h)
+			if err != nil {
+				return spamcheck.Response{Name: "image-hash", Spam: false, Details: "invalid image hash", Error: err}
+			}
+			if dist <= maxDistance {
+				return spamcheck.Response{Name: "image-hash", Spam: true,
+					Details: fmt.Sprintf("image matches spam image %s, distance %d/%d", h, dist, maxDistance)}
+			}
+		}
+		return spamcheck.Response{Name: "image-hash", Spam: false, Details: "no matching spam images"}
+	}
+}
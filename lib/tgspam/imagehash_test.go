@@ -0,0 +1,149 @@
+package tgspam
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+	"github.com/umputun/tg-spam/lib/tgspam/mocks"
+)
+
+// makeTestImage makes an image with a pattern, the same pattern gives visually the same image for any size
+func makeTestImage(w, h int, pattern func(x, y float64) uint8) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			v := pattern(float64(x)/float64(w), float64(y)/float64(h))
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestImageHash(t *testing.T) {
+	waves := func(x, y float64) uint8 { return uint8((int(x*7)%2*120 + int(y*5)%2*100 + int(x*200)) % 256) }
+	diagonal := func(x, y float64) uint8 { return uint8((1 - (x+y)/2) * 255) }
+
+	encodePNG := func(img image.Image) []byte {
+		buf := bytes.Buffer{}
+		require.NoError(t, png.Encode(&buf, img))
+		return buf.Bytes()
+	}
+	encodeJPEG := func(img image.Image, quality int) []byte {
+		buf := bytes.Buffer{}
+		require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}))
+		return buf.Bytes()
+	}
+
+	orig, err := ImageHash(encodePNG(makeTestImage(640, 480, waves)))
+	require.NoError(t, err)
+	assert.Len(t, orig, 16)
+
+	t.Run("same image, different size and compression", func(t *testing.T) {
+		h, err := ImageHash(encodeJPEG(makeTestImage(1280, 960, waves), 50))
+		require.NoError(t, err)
+		dist, err := ImageHashDistance(orig, h)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, dist, 5)
+	})
+
+	t.Run("different image", func(t *testing.T) {
+		h, err := ImageHash(encodePNG(makeTestImage(640, 480, diagonal)))
+		require.NoError(t, err)
+		dist, err := ImageHashDistance(orig, h)
+		require.NoError(t, err)
+		assert.Greater(t, dist, 10)
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		_, err := ImageHash([]byte("not an image"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode image")
+	})
+
+	t.Run("too small image", func(t *testing.T) {
+		_, err := ImageHash(encodePNG(makeTestImage(5, 5, waves)))
+		require.EqualError(t, err, "image is too small, 5x5")
+	})
+}
+
+func TestImageHashDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{name: "same", a: "8f3c00ff12345678", b: "8f3c00ff12345678", want: 0},
+		{name: "one bit", a: "0000000000000000", b: "0000000000000001", want: 1},
+		{name: "all bits", a: "0000000000000000", b: "ffffffffffffffff", want: 64},
+		{name: "invalid first", a: "xyz", b: "0000000000000000", wantErr: true},
+		{name: "invalid second", a: "0000000000000000", b: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dist, err := ImageHashDistance(tt.a, tt.b)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, dist)
+		})
+	}
+}
+
+func TestImageHashCheck(t *testing.T) {
+	store := &mocks.ImageHashStoreMock{HashesFunc: func(ctx context.Context) ([]string, error) {
+		return []string{"ff00000000000000", "00000000000000ff"}, nil
+	}}
+
+	tests := []struct {
+		name     string
+		hash     string
+		expected spamcheck.Response
+	}{
+		{name: "no image hash", hash: "",
+			expected: spamcheck.Response{Name: "image-hash", Spam: false, Details: "no image hash"}},
+		{name: "exact match", hash: "00000000000000ff",
+			expected: spamcheck.Response{Name: "image-hash", Spam: true,
+				Details: "image matches spam image 00000000000000ff, distance 0/3"}},
+		{name: "close match", hash: "fe00000000000001",
+			expected: spamcheck.Response{Name: "image-hash", Spam: true,
+				Details: "image matches spam image ff00000000000000, distance 2/3"}},
+		{name: "no match", hash: "0f0000000000f000",
+			expected: spamcheck.Response{Name: "image-hash", Spam: false, Details: "no matching spam images"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := ImageHashCheck(store, 3)
+			assert.Equal(t, tt.expected, check(spamcheck.Request{Meta: spamcheck.MetaData{ImageHash: tt.hash}}))
+		})
+	}
+
+	t.Run("store error", func(t *testing.T) {
+		errStore := &mocks.ImageHashStoreMock{HashesFunc: func(ctx context.Context) ([]string, error) {
+			return nil, errors.New("db error")
+		}}
+		resp := ImageHashCheck(errStore, 3)(spamcheck.Request{Meta: spamcheck.MetaData{ImageHash: "00000000000000ff"}})
+		assert.False(t, resp.Spam)
+		assert.Equal(t, "failed to get image hashes", resp.Details)
+		require.EqualError(t, resp.Error, "db error")
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		resp := ImageHashCheck(store, 3)(spamcheck.Request{Meta: spamcheck.MetaData{ImageHash: "not-hex"}})
+		assert.False(t, resp.Spam)
+		assert.Equal(t, "invalid image hash", resp.Details)
+		require.Error(t, resp.Error)
+	})
+}
@@ -1,13 +1,43 @@
 package tgspam
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/umputun/tg-spam/lib/spamcheck"
 )
 
+// LLMChecker checks a message with an LLM, history contains recent ham messages if requested.
+// Registered with Detector.WithLLMChecker, it participates in the LLM consensus along with
+// the OpenAI and Gemini checkers.
+type LLMChecker interface {
+	Check(ctx context.Context, msg string, history []spamcheck.Request) (spam bool, cr spamcheck.Response)
+}
+
+// LLMCheckFunc is an adapter to use a function as LLMChecker
+type LLMCheckFunc func(ctx context.Context, msg string, history []spamcheck.Request) (spam bool, cr spamcheck.Response)
+
+// Check calls f(ctx, msg, history)
+func (f LLMCheckFunc) Check(ctx context.Context, msg string, history []spamcheck.Request) (spam bool, cr spamcheck.Response) {
+	return f(ctx, msg, history)
+}
+
+// LLMCheckerOpts defines how a registered LLM checker participates in Detector.Check
+type LLMCheckerOpts struct {
+	Veto               bool // if true, the checker vetos spam, otherwise vetos ham
+	HistorySize        int  // number of recent ham messages passed as context
+	CheckShortMessages bool // if true, check messages shorter than MinMsgLen
+	// model, prompt and other settings affecting the verdict, combined with the checker name
+	// to key cached verdicts, see Detector.WithLLMCache
+	CacheID string
+}
+
 type llmResponse struct {
 	IsSpam     bool   `json:"spam"`
 	Reason     string `json:"reason"`
@@ -46,6 +76,73 @@ func runLLMProviderCheck(ctx context.Context, name, errorPrefix string, retryCou
 	}
 }
 
+// buildLLMSystemPrompt combines the base prompt with numbered custom prompts
+func buildLLMSystemPrompt(basePrompt string, customPrompts []string) string {
+	if len(customPrompts) == 0 {
+		return basePrompt
+	}
+
+	var sb strings.Builder
+	sb.WriteString(basePrompt)
+	sb.WriteString("\n\nAlso, specifically check for these patterns:\n")
+	for i, prompt := range customPrompts {
+		sb.WriteString(strconv.Itoa(i+1) + ". " + prompt + "\n")
+	}
+	return sb.String()
+}
+
+// truncateLLMRequest limits the message to maxSymbols runes
+func truncateLLMRequest(msg string, maxSymbols int) string {
+	if len(msg) <= maxSymbols {
+		return msg
+	}
+	if runes := []rune(msg); len(runes) > maxSymbols {
+		return string(runes[:maxSymbols])
+	}
+	return msg
+}
+
+// extractLLMJSON returns the json object from the model response. Models without json mode
+// may wrap the object in markdown fences or add a few words around it.
+func extractLLMJSON(content string) string {
+	content = stripThoughtTags(content)
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return strings.TrimSpace(content)
+	}
+	return content[start : end+1]
+}
+
+// postLLMRequest posts json request to the LLM api and decodes json response into respData
+func postLLMRequest(ctx context.Context, client HTTPClient, url string, headers map[string]string, reqData, respData any) error {
+	body, err := json.Marshal(reqData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, strings.TrimSpace(string(errBody)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respData); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
 func appendHistoryToLLMMessage(msg string, history []spamcheck.Request) string {
 	if len(history) == 0 {
 		return msg
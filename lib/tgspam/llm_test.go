@@ -92,3 +92,33 @@ func TestRunLLMProviderCheck(t *testing.T) {
 		assert.EqualError(t, details.Error, "boom")
 	})
 }
+
+func TestBuildLLMSystemPrompt(t *testing.T) {
+	assert.Equal(t, "base", buildLLMSystemPrompt("base", nil))
+	assert.Equal(t, "base\n\nAlso, specifically check for these patterns:\n1. first\n2. second\n",
+		buildLLMSystemPrompt("base", []string{"first", "second"}))
+}
+
+func TestTruncateLLMRequest(t *testing.T) {
+	assert.Equal(t, "hello", truncateLLMRequest("hello", 10))
+	assert.Equal(t, "hel", truncateLLMRequest("hello", 3))
+	assert.Equal(t, "привет", truncateLLMRequest("привет", 6), "counted in runes, not bytes")
+	assert.Equal(t, "при", truncateLLMRequest("привет", 3))
+}
+
+func TestExtractLLMJSON(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{name: "plain json", in: `{"spam": true}`, want: `{"spam": true}`},
+		{name: "markdown fence", in: "```json\n{\"spam\": true}\n```", want: `{"spam": true}`},
+		{name: "text around", in: `Here is the result: {"spam": false} hope it helps`, want: `{"spam": false}`},
+		{name: "thought tags", in: `<thought>is it {spam}?</thought>{"spam": true}`, want: `{"spam": true}`},
+		{name: "no json", in: " not sure ", want: "not sure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, extractLLMJSON(tt.in))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package tgspam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	cache "github.com/go-pkgz/expirable-cache/v3"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// LLMCache keeps LLM verdicts to reuse them for repeated messages, see Detector.WithLLMCache.
+// Implementations are responsible for the expiration and size limits.
+type LLMCache interface {
+	Get(ctx context.Context, key string) (verdict LLMVerdict, ok bool, err error)
+	Put(ctx context.Context, key string, verdict LLMVerdict) error
+}
+
+// LLMVerdict is a cached result of a successful LLM check
+type LLMVerdict struct {
+	Spam    bool
+	Details string
+}
+
+// LLMMemoryCache is an in-memory LLMCache with TTL and max size, the least recently used verdicts evicted first
+type LLMMemoryCache struct {
+	cache cache.Cache[string, LLMVerdict]
+}
+
+// NewLLMMemoryCache makes an in-memory LLM verdict cache keeping up to maxKeys verdicts for ttl
+func NewLLMMemoryCache(maxKeys int, ttl time.Duration) *LLMMemoryCache {
+	return &LLMMemoryCache{cache: cache.NewCache[string, LLMVerdict]().WithMaxKeys(maxKeys).WithTTL(ttl).WithLRU()}
+}
+
+// Get returns the cached verdict for the key, never fails
+func (c *LLMMemoryCache) Get(_ context.Context, key string) (LLMVerdict, bool, error) {
+	v, ok := c.cache.Get(key)
+	return v, ok, nil
+}
+
+// Put stores the verdict for the key, never fails
+func (c *LLMMemoryCache) Put(_ context.Context, key string, verdict LLMVerdict) error {
+	c.cache.Add(key, verdict)
+	return nil
+}
+
+// llmCacheKey makes the verdict cache key from the checker identity (provider, model and prompt), the normalized message
+// and the history sent to the model, so a verdict made in one conversation context is not reused for another one.
+func llmCacheKey(checkerID, msg string, history []spamcheck.Request) string {
+	h := sha256.New()
+	h.Write([]byte(checkerID + "\x00" + msg))
+	for _, r := range history {
+		h.Write([]byte("\x00" + r.UserName + "\x00" + r.Msg))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package tgspam
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestLLMMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("get and put", func(t *testing.T) {
+		c := NewLLMMemoryCache(10, time.Hour)
+		_, ok, err := c.Get(ctx, "key1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		require.NoError(t, c.Put(ctx, "key1", LLMVerdict{Spam: true, Details: "spam"}))
+		v, ok, err := c.Get(ctx, "key1")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, LLMVerdict{Spam: true, Details: "spam"}, v)
+	})
+
+	t.Run("max keys", func(t *testing.T) {
+		c := NewLLMMemoryCache(2, time.Hour)
+		require.NoError(t, c.Put(ctx, "key1", LLMVerdict{Details: "1"}))
+		require.NoError(t, c.Put(ctx, "key2", LLMVerdict{Details: "2"}))
+		_, ok, _ := c.Get(ctx, "key1") // makes key1 recently used
+		assert.True(t, ok)
+		require.NoError(t, c.Put(ctx, "key3", LLMVerdict{Details: "3"}))
+
+		_, ok, _ = c.Get(ctx, "key2")
+		assert.False(t, ok, "least recently used evicted")
+		_, ok, _ = c.Get(ctx, "key1")
+		assert.True(t, ok)
+		_, ok, _ = c.Get(ctx, "key3")
+		assert.True(t, ok)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		c := NewLLMMemoryCache(10, 10*time.Millisecond)
+		require.NoError(t, c.Put(ctx, "key1", LLMVerdict{Details: "1"}))
+		time.Sleep(20 * time.Millisecond)
+		_, ok, _ := c.Get(ctx, "key1")
+		assert.False(t, ok)
+	})
+}
+
+func TestLLMCacheKey(t *testing.T) {
+	k := llmCacheKey("openai\x00gpt-4o\x00prompt", "buy crypto", nil)
+	assert.Len(t, k, 64)
+	assert.Equal(t, k, llmCacheKey("openai\x00gpt-4o\x00prompt", "buy crypto", nil))
+	assert.NotEqual(t, k, llmCacheKey("openai\x00gpt-4o-mini\x00prompt", "buy crypto", nil))
+	assert.NotEqual(t, k, llmCacheKey("openai\x00gpt-4o\x00prompt", "buy crypto!", nil))
+
+	hist := []spamcheck.Request{{UserName: "bob", Msg: "hi all"}}
+	kh := llmCacheKey("openai\x00gpt-4o\x00prompt", "buy crypto", hist)
+	assert.NotEqual(t, k, kh, "history is a part of the key")
+	assert.Equal(t, kh, llmCacheKey("openai\x00gpt-4o\x00prompt", "buy crypto", []spamcheck.Request{{UserName: "bob", Msg: "hi all"}}))
+	assert.NotEqual(t, kh, llmCacheKey("openai\x00gpt-4o\x00prompt", "buy crypto", []spamcheck.Request{{UserName: "bob", Msg: "hi"}}))
+}
@@ -13,8 +13,11 @@ import (
 // The boolean value indicates whether the check. It checks the message's meta.
 type MetaCheck func(req spamcheck.Request) spamcheck.Response
 
+const linksCheckName = "links"
+
 // LinksCheck is a function that returns a MetaCheck function that checks the number of links in the message.
 // It uses custom meta-info if it is provided, otherwise it counts the number of links in the message.
+// Detector doesn't flag messages linking to allowed domains only, see Detector.LoadDomains.
 func LinksCheck(limit int) MetaCheck {
 	return func(req spamcheck.Request) spamcheck.Response {
 		links := req.Meta.Links
@@ -23,12 +26,12 @@ func LinksCheck(limit int) MetaCheck {
 		}
 		if links > limit {
 			return spamcheck.Response{
-				Name:    "links",
+				Name:    linksCheckName,
 				Spam:    true,
 				Details: fmt.Sprintf("too many links %d/%d", links, limit),
 			}
 		}
-		return spamcheck.Response{Spam: false, Name: "links", Details: fmt.Sprintf("links %d/%d", links, limit)}
+		return spamcheck.Response{Spam: false, Name: linksCheckName, Details: fmt.Sprintf("links %d/%d", links, limit)}
 	}
 }
 
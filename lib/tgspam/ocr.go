@@ -0,0 +1,78 @@
+package tgspam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// ImageTextExtractor extracts text from an image (OCR). The caller passes the extracted text to the detector
+// in spamcheck.Request.ImageText, so it goes through the same content checks as the message text.
+type ImageTextExtractor interface {
+	ExtractText(ctx context.Context, img []byte) (string, error)
+}
+
+// TesseractOCR extracts text from images with the local tesseract CLI.
+// The image is passed to tesseract via stdin, the text is read from stdout.
+type TesseractOCR struct {
+	Command string // tesseract binary, "tesseract" if empty
+	Lang    string // tesseract languages, e.g. "eng+rus", tesseract default if empty
+}
+
+// ExtractText runs tesseract on the image and returns the recognized text
+func (t *TesseractOCR) ExtractText(ctx context.Context, img []byte) (string, error) {
+	command := t.Command
+	if command == "" {
+		command = "tesseract"
+	}
+	args := []string{"stdin", "stdout"}
+	if t.Lang != "" {
+		args = append(args, "-l", t.Lang)
+	}
+	cmd := exec.CommandContext(ctx, command, args...) //nolint:gosec // command is set by the operator
+	cmd.Stdin = bytes.NewReader(img)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w, %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// HTTPOCR extracts text from images with an external OCR service.
+// The image is posted as the request body and the service responds with {"text": "recognized text"}.
+type HTTPOCR struct {
+	URL        string     // OCR service endpoint
+	HTTPClient HTTPClient // http client to use for requests
+}
+
+// ExtractText posts the image to the OCR service and returns the recognized text
+func (h *HTTPOCR) ExtractText(ctx context.Context, img []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(img))
+	if err != nil {
+		return "", fmt.Errorf("failed to make OCR request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := h.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send OCR request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("unexpected OCR response status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	respData := struct {
+		Text string `json:"text"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return "", fmt.Errorf("failed to decode OCR response: %w", err)
+	}
+	return strings.TrimSpace(respData.Text), nil
+}
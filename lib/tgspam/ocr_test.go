@@ -0,0 +1,107 @@
+package tgspam
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTesseractOCR_ExtractText(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script as a fake tesseract")
+	}
+	dir := t.TempDir()
+
+	t.Run("text from stdout", func(t *testing.T) {
+		// fake tesseract echoes args and the image from stdin
+		script := filepath.Join(dir, "tesseract-ok")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\ncat\necho\n"), 0o700)) //nolint:gosec // test script
+		ocr := &TesseractOCR{Command: script, Lang: "eng+rus"}
+		text, err := ocr.ExtractText(context.Background(), []byte("image text"))
+		require.NoError(t, err)
+		assert.Equal(t, "stdin stdout -l eng+rus\nimage text", text)
+	})
+
+	t.Run("no lang", func(t *testing.T) {
+		script := filepath.Join(dir, "tesseract-args")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\"\n"), 0o700)) //nolint:gosec // test script
+		ocr := &TesseractOCR{Command: script}
+		text, err := ocr.ExtractText(context.Background(), []byte("img"))
+		require.NoError(t, err)
+		assert.Equal(t, "stdin stdout", text)
+	})
+
+	t.Run("failed command", func(t *testing.T) {
+		script := filepath.Join(dir, "tesseract-fail")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho 'bad image' >&2\nexit 1\n"), 0o700)) //nolint:gosec // test script
+		ocr := &TesseractOCR{Command: script}
+		_, err := ocr.ExtractText(context.Background(), []byte("img"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "tesseract failed")
+		assert.Contains(t, err.Error(), "bad image")
+	})
+
+	t.Run("missing command", func(t *testing.T) {
+		ocr := &TesseractOCR{Command: filepath.Join(dir, "not-found")}
+		_, err := ocr.ExtractText(context.Background(), []byte("img"))
+		require.Error(t, err)
+	})
+}
+
+func TestHTTPOCR_ExtractText(t *testing.T) {
+	t.Run("successful extraction", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/octet-stream", r.Header.Get("Content-Type"))
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "image data", string(body))
+			_, _ = w.Write([]byte(`{"text": "  buy crypto now \n"}`))
+		}))
+		defer ts.Close()
+
+		ocr := &HTTPOCR{URL: ts.URL, HTTPClient: ts.Client()}
+		text, err := ocr.ExtractText(context.Background(), []byte("image data"))
+		require.NoError(t, err)
+		assert.Equal(t, "buy crypto now", text)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unsupported image", http.StatusBadRequest)
+		}))
+		defer ts.Close()
+
+		ocr := &HTTPOCR{URL: ts.URL, HTTPClient: ts.Client()}
+		_, err := ocr.ExtractText(context.Background(), []byte("image data"))
+		require.EqualError(t, err, "unexpected OCR response status 400: unsupported image")
+	})
+
+	t.Run("bad json", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`not json`))
+		}))
+		defer ts.Close()
+
+		ocr := &HTTPOCR{URL: ts.URL, HTTPClient: ts.Client()}
+		_, err := ocr.ExtractText(context.Background(), []byte("image data"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode OCR response")
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ocr := &HTTPOCR{URL: "http://127.0.0.1:1", HTTPClient: http.DefaultClient}
+		_, err := ocr.ExtractText(ctx, []byte("image data"))
+		require.Error(t, err)
+	})
+}
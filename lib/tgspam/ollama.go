@@ -0,0 +1,93 @@
+package tgspam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// ollamaChecker checks if a text is spam with a local Ollama server
+type ollamaChecker struct {
+	client HTTPClient
+	params OllamaConfig
+}
+
+// OllamaConfig contains parameters for ollamaChecker
+type OllamaConfig struct {
+	APIBase           string   // ollama server url, http://localhost:11434 if empty
+	Model             string   // model name, e.g. llama3.1
+	MaxTokensResponse int      // max tokens in the response (num_predict)
+	MaxSymbolsRequest int      // max request length in symbols
+	SystemPrompt      string   // system prompt for spam detection
+	CustomPrompts     []string // additional prompts for specific spam patterns
+	RetryCount        int      // number of retries on failure
+}
+
+// NewOllamaChecker makes an LLMChecker for Ollama chat API.
+// The model is asked for json output, so any model pulled to the server can be used.
+func NewOllamaChecker(client HTTPClient, params OllamaConfig) LLMChecker {
+	if params.APIBase == "" {
+		params.APIBase = "http://localhost:11434"
+	}
+	params.APIBase = strings.TrimSuffix(params.APIBase, "/")
+	if params.SystemPrompt == "" {
+		params.SystemPrompt = defaultPrompt
+	}
+	if params.Model == "" {
+		params.Model = "llama3.1"
+	}
+	if params.MaxTokensResponse == 0 {
+		params.MaxTokensResponse = 1024
+	}
+	if params.MaxSymbolsRequest == 0 {
+		params.MaxSymbolsRequest = 8192
+	}
+	if params.RetryCount <= 0 {
+		params.RetryCount = 1
+	}
+	return &ollamaChecker{client: client, params: params}
+}
+
+// Check checks if a text is spam using Ollama API
+func (o *ollamaChecker) Check(ctx context.Context, msg string, history []spamcheck.Request) (spam bool, cr spamcheck.Response) {
+	return runLLMProviderCheck(ctx, "ollama", "Ollama", o.params.RetryCount, msg, history, o.sendRequest)
+}
+
+func (o *ollamaChecker) sendRequest(ctx context.Context, msg string) (response llmResponse, err error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	req := struct {
+		Model    string         `json:"model"`
+		Messages []message      `json:"messages"`
+		Stream   bool           `json:"stream"`
+		Format   string         `json:"format"`
+		Options  map[string]any `json:"options"`
+	}{
+		Model: o.params.Model,
+		Messages: []message{
+			{Role: "system", Content: buildLLMSystemPrompt(o.params.SystemPrompt, o.params.CustomPrompts)},
+			{Role: "user", Content: truncateLLMRequest(msg, o.params.MaxSymbolsRequest)},
+		},
+		Format:  "json",
+		Options: map[string]any{"num_predict": o.params.MaxTokensResponse},
+	}
+	resp := struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}{}
+	if err := postLLMRequest(ctx, o.client, o.params.APIBase+"/api/chat", nil, req, &resp); err != nil {
+		return llmResponse{}, fmt.Errorf("failed to send chat request: %w", err)
+	}
+
+	text := extractLLMJSON(resp.Message.Content)
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		return llmResponse{}, fmt.Errorf("can't unmarshal response: %s - %w", text, err)
+	}
+	return response, nil
+}
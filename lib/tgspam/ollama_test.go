@@ -0,0 +1,73 @@
+package tgspam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestOllamaChecker_Check(t *testing.T) {
+	t.Run("ham response with history", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/chat", r.URL.Path)
+			var req struct {
+				Model    string `json:"model"`
+				Stream   bool   `json:"stream"`
+				Format   string `json:"format"`
+				Messages []struct {
+					Role    string `json:"role"`
+					Content string `json:"content"`
+				} `json:"messages"`
+				Options map[string]any `json:"options"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "llama3.1", req.Model)
+			assert.False(t, req.Stream)
+			assert.Equal(t, "json", req.Format)
+			assert.InDelta(t, 1024, req.Options["num_predict"], 0.001)
+			require.Len(t, req.Messages, 2)
+			assert.Equal(t, "system", req.Messages[0].Role)
+			assert.Equal(t, defaultPrompt, req.Messages[0].Content)
+			assert.Equal(t, "user", req.Messages[1].Role)
+			assert.Contains(t, req.Messages[1].Content, "User message:\nhello\n")
+			assert.Contains(t, req.Messages[1].Content, `"user1": "earlier message"`)
+			_, _ = w.Write([]byte(`{"message":{"role":"assistant",` +
+				`"content":"{\"spam\": false, \"reason\": \"greeting\", \"confidence\": 95}"}}`))
+		}))
+		defer ts.Close()
+
+		checker := NewOllamaChecker(ts.Client(), OllamaConfig{APIBase: ts.URL})
+		hist := []spamcheck.Request{{Msg: "earlier message", UserName: "user1"}}
+		spam, resp := checker.Check(context.Background(), "hello", hist)
+		assert.False(t, spam)
+		assert.Equal(t, spamcheck.Response{Name: "ollama", Details: "greeting, confidence: 95%"}, resp)
+	})
+
+	t.Run("bad model output", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"message":{"content":"not sure"}}`))
+		}))
+		defer ts.Close()
+
+		checker := NewOllamaChecker(ts.Client(), OllamaConfig{APIBase: ts.URL})
+		spam, resp := checker.Check(context.Background(), "hello", nil)
+		assert.False(t, spam)
+		require.Error(t, resp.Error)
+		assert.Contains(t, resp.Details, "Ollama error: can't unmarshal response: not sure")
+	})
+
+	t.Run("server down", func(t *testing.T) {
+		checker := NewOllamaChecker(http.DefaultClient, OllamaConfig{APIBase: "http://127.0.0.1:1"})
+		spam, resp := checker.Check(context.Background(), "hello", nil)
+		assert.False(t, spam)
+		require.Error(t, resp.Error)
+		assert.Contains(t, resp.Details, "failed to send request")
+	})
+}
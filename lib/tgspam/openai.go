@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 
@@ -96,6 +95,12 @@ func newOpenAIChecker(client openAIClient, params OpenAIConfig) *openAIChecker {
 	return &openAIChecker{client: client, params: params}
 }
 
+// NewOpenAIChecker makes an LLMChecker for OpenAI API or any OpenAI-compatible server,
+// e.g. llama.cpp, vLLM or LM Studio, with the client pointed to its base url
+func NewOpenAIChecker(client openAIClient, params OpenAIConfig) LLMChecker {
+	return LLMCheckFunc(newOpenAIChecker(client, params).check)
+}
+
 // check checks if a text is spam using OpenAI API
 func (o *openAIChecker) check(ctx context.Context, msg string, history []spamcheck.Request) (spam bool, cr spamcheck.Response) {
 	if o.client == nil {
@@ -107,24 +112,7 @@ func (o *openAIChecker) check(ctx context.Context, msg string, history []spamche
 
 // buildSystemPrompt creates the complete system prompt by combining the base prompt with custom prompts
 func (o *openAIChecker) buildSystemPrompt() string {
-	basePrompt := o.params.SystemPrompt
-
-	// if there are no custom prompts, just return the base prompt
-	if len(o.params.CustomPrompts) == 0 {
-		return basePrompt
-	}
-
-	// combine base prompt with custom prompts
-	var sb strings.Builder
-	sb.WriteString(basePrompt)
-	sb.WriteString("\n\nAlso, specifically check for these patterns:\n")
-
-	// add each custom prompt as a numbered item
-	for i, prompt := range o.params.CustomPrompts {
-		sb.WriteString(strconv.Itoa(i+1) + ". " + prompt + "\n")
-	}
-
-	return sb.String()
+	return buildLLMSystemPrompt(o.params.SystemPrompt, o.params.CustomPrompts)
 }
 
 // isReasoningModel checks if the model requires MaxCompletionTokens instead of MaxTokens
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	lua "github.com/yuin/gopher-lua"
+
+	"github.com/umputun/tg-spam/lib/textnorm"
 )
 
 // RegisterHelpers registers common helper functions for Lua scripts
@@ -25,6 +27,7 @@ func (c *Checker) RegisterHelpers() {
 	c.vm.SetGlobal("join", c.vm.NewFunction(join))
 	c.vm.SetGlobal("starts_with", c.vm.NewFunction(startsWith))
 	c.vm.SetGlobal("ends_with", c.vm.NewFunction(endsWith))
+	c.vm.SetGlobal("normalize_text", c.vm.NewFunction(normalizeText))
 
 	// HTTP and JSON helpers
 	c.vm.SetGlobal("http_request", c.vm.NewFunction(httpRequest))
@@ -180,6 +183,14 @@ func endsWith(l *lua.LState) int {
 	return 1
 }
 
+// normalizeText folds lookalike letters, leetspeak, invisible characters and spaced-out letters,
+// e.g. "crypт0" to "crypto". The result is lowercased, see textnorm.Normalize for details
+func normalizeText(l *lua.LState) int {
+	str := l.CheckString(1)
+	l.Push(lua.LString(textnorm.Normalize(str)))
+	return 1
+}
+
 // httpRequest makes an HTTP request to the given URL and returns the response
 // Lua usage: response, status_code, err = http_request(url, [method], [headers], [body], [timeout])
 // Example: http_request("https://example.com/api", "POST", {["Content-Type"]="application/json"}, "{}", 10)
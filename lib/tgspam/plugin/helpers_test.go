@@ -424,3 +424,28 @@ func TestURLEncode(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeText(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"Hello World", "hello world"},
+		{"crypт0 inv3stm3nt", "crypto investment"},
+		{"buy c r y p t o now", "buy crypto now"},
+		{"cry\u200bpto", "crypto"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			L := lua.NewState()
+			defer L.Close()
+
+			L.Push(lua.LString(tc.input))
+			ret := normalizeText(L)
+			assert.Equal(t, 1, ret)
+			assert.Equal(t, lua.LString(tc.expected), L.Get(-1))
+			L.Pop(1)
+		})
+	}
+}
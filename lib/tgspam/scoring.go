@@ -0,0 +1,74 @@
+package tgspam
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+// ScoreCheckName is the name of the summary check added to results in scoring mode
+const ScoreCheckName = "score"
+
+// ScoringConfig defines weighted scoring mode. Instead of any spam check marking the message as spam,
+// each check contributes its weight if it detected spam, and probabilistic checks (classifier and similarity)
+// contribute the weight multiplied by the spam probability. The message is spam if the total reaches Threshold.
+type ScoringConfig struct {
+	Enabled             bool               // if true, spam is decided by the total score of checks
+	Weights             map[string]float64 // weights of checks by check name, e.g. "classifier", "stopword" or "cas"
+	DefaultWeight       float64            // weight of checks not listed in Weights
+	Threshold           float64            // total score to mark the message as spam
+	SuspiciousThreshold float64            // total score to mark the message as suspicious, 0 disables
+}
+
+// checkScores keeps spam probabilities of probabilistic checks, by check name, for the weighted scoring
+type checkScores map[string]float64
+
+// weight returns the weight of the check by name
+func (s ScoringConfig) weight(name string) float64 {
+	if w, ok := s.Weights[name]; ok {
+		return w
+	}
+	return s.DefaultWeight
+}
+
+// score sets the weighted contribution of each check and returns the total score.
+// Failed checks and the summary check itself don't contribute.
+func (s ScoringConfig) score(cr []spamcheck.Response, probs checkScores) float64 {
+	total := 0.0
+	for i := range cr {
+		cr[i].Score = 0
+		if cr[i].Name == ScoreCheckName || cr[i].Error != nil {
+			continue
+		}
+		if prob, ok := probs[cr[i].Name]; ok {
+			cr[i].Score = s.weight(cr[i].Name) * prob
+		} else if cr[i].Spam {
+			cr[i].Score = s.weight(cr[i].Name)
+		}
+		total += cr[i].Score
+	}
+	return total
+}
+
+// summary makes the summary check response for the total score
+func (s ScoringConfig) summary(total float64) spamcheck.Response {
+	resp := spamcheck.Response{Name: ScoreCheckName, Spam: total >= s.Threshold, Score: total,
+		Details: fmt.Sprintf("%.2f/%.2f", total, s.Threshold)}
+	if !resp.Spam && s.SuspiciousThreshold > 0 && total >= s.SuspiciousThreshold {
+		resp.Suspicious = true
+		resp.Details += ", suspicious"
+	}
+	return resp
+}
+
+// classifierSpamProbability converts classifier result to spam probability, 0.0 - 1.0
+func classifierSpamProbability(class spamClass, prob float64) float64 {
+	if math.IsNaN(prob) || math.IsInf(prob, 0) {
+		return 0
+	}
+	if class == ClassSpam {
+		return prob / 100
+	}
+	return 1 - prob/100
+}
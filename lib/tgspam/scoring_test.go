@@ -0,0 +1,66 @@
+package tgspam
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/umputun/tg-spam/lib/spamcheck"
+)
+
+func TestScoringConfig_score(t *testing.T) {
+	s := ScoringConfig{Weights: map[string]float64{"cas": 3, "emoji": 0.5, "classifier": 2}, DefaultWeight: 1}
+	cr := []spamcheck.Response{
+		{Name: "cas", Spam: true},
+		{Name: "emoji", Spam: true},
+		{Name: "stopword", Spam: false},
+		{Name: "multi-lingual", Spam: true},
+		{Name: "classifier", Spam: false},
+		{Name: "lua-check", Spam: true, Error: errors.New("failed")},
+		{Name: ScoreCheckName, Spam: true, Score: 10},
+	}
+	total := s.score(cr, checkScores{"classifier": 0.25})
+	assert.InDelta(t, 5.0, total, 0.0001)
+	assert.InDelta(t, 3.0, cr[0].Score, 0.0001)
+	assert.InDelta(t, 0.5, cr[1].Score, 0.0001)
+	assert.Zero(t, cr[2].Score, "ham check doesn't contribute")
+	assert.InDelta(t, 1.0, cr[3].Score, 0.0001, "default weight")
+	assert.InDelta(t, 0.5, cr[4].Score, 0.0001, "probabilistic check contributes weight*probability even if ham")
+	assert.Zero(t, cr[5].Score, "failed check doesn't contribute")
+	assert.Zero(t, cr[6].Score, "summary doesn't contribute")
+}
+
+func TestScoringConfig_summary(t *testing.T) {
+	s := ScoringConfig{Threshold: 3, SuspiciousThreshold: 1.5}
+	tests := []struct {
+		name  string
+		total float64
+		want  spamcheck.Response
+	}{
+		{name: "spam", total: 3.5,
+			want: spamcheck.Response{Name: "score", Spam: true, Score: 3.5, Details: "3.50/3.00"}},
+		{name: "suspicious", total: 2,
+			want: spamcheck.Response{Name: "score", Spam: false, Suspicious: true, Score: 2, Details: "2.00/3.00, suspicious"}},
+		{name: "ham", total: 1,
+			want: spamcheck.Response{Name: "score", Spam: false, Score: 1, Details: "1.00/3.00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.summary(tt.total))
+		})
+	}
+
+	t.Run("suspicious disabled", func(t *testing.T) {
+		resp := ScoringConfig{Threshold: 3}.summary(2)
+		assert.False(t, resp.Suspicious)
+	})
+}
+
+func TestClassifierSpamProbability(t *testing.T) {
+	assert.InDelta(t, 0.9, classifierSpamProbability(ClassSpam, 90), 0.0001)
+	assert.InDelta(t, 0.1, classifierSpamProbability(ClassHam, 90), 0.0001)
+	assert.Zero(t, classifierSpamProbability(ClassSpam, math.NaN()))
+	assert.Zero(t, classifierSpamProbability(ClassSpam, math.Inf(1)))
+}
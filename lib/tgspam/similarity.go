@@ -0,0 +1,128 @@
+package tgspam
+
+import (
+	"math"
+	"sync"
+)
+
+// similarityIndex is an inverted index of tokenized spam samples for the similarity check.
+// It maps each token to the samples containing it, so dot products are calculated only for the samples
+// sharing at least one token with the message. All other samples have zero cosine similarity and can't match.
+type similarityIndex struct {
+	postings map[string][]posting // token -> samples with this token, in order of addition
+	norms    []float64            // square root of sum of squared token frequencies of each sample, by sample position
+	scratch  sync.Pool            // *matchScratch, reused by concurrent matches
+}
+
+// denseMatchRatio defines when match scans all samples instead of touched ones, if more than 1/denseMatchRatio
+// of samples share tokens with the message
+const denseMatchRatio = 4
+
+// matchScratch keeps dot products by sample position and positions of samples with non-zero dot product.
+// Only touched positions are scored and cleared, so a match costs the number of candidates, not of all samples,
+// unless most samples share tokens with the message, see denseMatchRatio.
+type matchScratch struct {
+	dots    []int
+	touched []int
+}
+
+// posting is a sample containing the token, with the token frequency in the sample
+type posting struct {
+	sample int
+	freq   int
+}
+
+// add adds a tokenized sample to the index. Samples without tokens are counted but never match.
+func (x *similarityIndex) add(tokens map[string]int) {
+	if x.postings == nil {
+		x.postings = map[string][]posting{}
+	}
+	sample, norm := len(x.norms), 0
+	for token, freq := range tokens {
+		norm += freq * freq
+		x.postings[token] = append(x.postings[token], posting{sample: sample, freq: freq})
+	}
+	x.norms = append(x.norms, math.Sqrt(float64(norm)))
+}
+
+// size returns the number of samples in the index
+func (x *similarityIndex) size() int {
+	return len(x.norms)
+}
+
+// reset removes all samples from the index
+func (x *similarityIndex) reset() {
+	x.postings = map[string][]posting{}
+	x.norms = nil
+}
+
+// match calculates cosine similarity of the tokenized message with the indexed samples sharing tokens with it.
+// The result and the returned max similarity are the same as for a linear scan of all samples in order of addition,
+// stopping on the first one with similarity above the threshold.
+func (x *similarityIndex) match(tokens map[string]int, threshold float64) (found bool, maxSimilarity float64) {
+	sc, ok := x.scratch.Get().(*matchScratch)
+	if !ok {
+		sc = &matchScratch{}
+	}
+	if len(sc.dots) < len(x.norms) {
+		sc.dots = make([]int, len(x.norms))
+	}
+	dense := false // too many samples touched, scan and clear all of them, it's faster than random access
+	defer func() {
+		if dense {
+			clear(sc.dots[:len(x.norms)])
+		} else {
+			for _, sample := range sc.touched {
+				sc.dots[sample] = 0
+			}
+		}
+		sc.touched = sc.touched[:0]
+		x.scratch.Put(sc)
+	}()
+
+	normA, maxTouched := 0, len(x.norms)/denseMatchRatio
+	for token, freq := range tokens {
+		normA += freq * freq
+		for _, p := range x.postings[token] {
+			if !dense && sc.dots[p.sample] == 0 { // frequencies are positive, so zero means not touched yet
+				sc.touched = append(sc.touched, p.sample)
+				dense = len(sc.touched) > maxTouched
+			}
+			sc.dots[p.sample] += freq * p.freq
+		}
+	}
+	if normA == 0 {
+		return false, 0
+	}
+
+	sqrtNormA := math.Sqrt(float64(normA))
+	similarity := func(sample int) float64 { return float64(sc.dots[sample]) / (sqrtNormA * x.norms[sample]) }
+
+	if dense {
+		for sample, dot := range sc.dots[:len(x.norms)] {
+			if dot == 0 {
+				continue // no shared tokens
+			}
+			sim := similarity(sample)
+			maxSimilarity = max(maxSimilarity, sim)
+			if sim >= threshold {
+				return true, maxSimilarity
+			}
+		}
+		return false, maxSimilarity
+	}
+
+	// touched samples are not ordered, find the first match and then the max similarity of samples up to it
+	first := math.MaxInt
+	for _, sample := range sc.touched {
+		if sample < first && similarity(sample) >= threshold {
+			first = sample
+		}
+	}
+	for _, sample := range sc.touched {
+		if sample <= first {
+			maxSimilarity = max(maxSimilarity, similarity(sample))
+		}
+	}
+	return first != math.MaxInt, maxSimilarity
+}
@@ -0,0 +1,220 @@
+package tgspam
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/umputun/tg-spam/lib/tgspam/mocks"
+)
+
+func TestSimilarityIndex_match(t *testing.T) {
+	x := similarityIndex{}
+	x.add(map[string]int{"win": 1, "free": 1, "iphone": 1})
+	x.add(map[string]int{})
+	x.add(map[string]int{"lottery": 2, "prize": 1, "free": 1})
+
+	tests := []struct {
+		name      string
+		msg       map[string]int
+		threshold float64
+		found     bool
+		max       float64
+	}{
+		{name: "exact match", msg: map[string]int{"win": 1, "free": 1, "iphone": 1}, threshold: 0.9, found: true, max: 1},
+		{name: "partial match", msg: map[string]int{"win": 1, "iphone": 1}, threshold: 0.5, found: true, max: 2 / math.Sqrt(6)},
+		{name: "below threshold", msg: map[string]int{"win": 1, "iphone": 1}, threshold: 0.9, found: false, max: 2 / math.Sqrt(6)},
+		{name: "max over candidates", msg: map[string]int{"lottery": 1, "free": 1}, threshold: 0.9, found: false,
+			max: 3 / (math.Sqrt(2) * math.Sqrt(6))},
+		{name: "no shared tokens", msg: map[string]int{"hello": 1, "world": 1}, threshold: 0.1, found: false, max: 0},
+		{name: "empty message", msg: map[string]int{}, threshold: 0.1, found: false, max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, maxSimilarity := x.match(tt.msg, tt.threshold)
+			assert.Equal(t, tt.found, found)
+			assert.InDelta(t, tt.max, maxSimilarity, 1e-9)
+		})
+	}
+
+	x.reset()
+	found, maxSimilarity := x.match(map[string]int{"win": 1, "free": 1, "iphone": 1}, 0.5)
+	assert.False(t, found)
+	assert.Zero(t, maxSimilarity)
+	x.add(map[string]int{"win": 1})
+	found, _ = x.match(map[string]int{"win": 1}, 0.5)
+	assert.True(t, found, "index usable after reset")
+}
+
+func TestSimilarityIndex_SameAsLinearScan(t *testing.T) {
+	samples, messages := makeSimilaritySamples(1000, 200)
+	x := similarityIndex{}
+	for _, s := range samples {
+		x.add(s)
+	}
+	for _, threshold := range []float64{0.2, 0.5, 0.8} {
+		for i, msg := range messages {
+			wantFound, wantMax := linearSimilarity(samples, msg, threshold)
+			found, maxSimilarity := x.match(msg, threshold)
+			require.Equal(t, wantFound, found, "message %d, threshold %v", i, threshold)
+			require.Equal(t, wantMax, maxSimilarity, "message %d, threshold %v", i, threshold) //nolint:testifylint // must be identical, not close
+		}
+	}
+}
+
+func TestSimilarityIndex_SparseSameAsLinearScan(t *testing.T) {
+	// uniform vocabulary, messages share tokens with a few samples only, so touched samples are scored
+	rnd := rand.New(rand.NewPCG(3, 4)) //nolint:gosec // test data
+	samples := make([]map[string]int, 0, 1000)
+	for range 1000 {
+		doc := map[string]int{}
+		for range 5 + rnd.IntN(10) {
+			doc[fmt.Sprintf("word%d", rnd.IntN(50000))]++
+		}
+		samples = append(samples, doc)
+	}
+	x := similarityIndex{}
+	for _, s := range samples {
+		x.add(s)
+	}
+	for i := range 200 {
+		msg := map[string]int{fmt.Sprintf("word%d", rnd.IntN(50000)): 1}
+		for token, freq := range samples[rnd.IntN(len(samples))] {
+			if rnd.IntN(2) > 0 {
+				msg[token] = freq
+			}
+		}
+		for _, threshold := range []float64{0.2, 0.5, 0.8} {
+			wantFound, wantMax := linearSimilarity(samples, msg, threshold)
+			found, maxSimilarity := x.match(msg, threshold)
+			require.Equal(t, wantFound, found, "message %d, threshold %v", i, threshold)
+			require.Equal(t, wantMax, maxSimilarity, "message %d, threshold %v", i, threshold) //nolint:testifylint // must be identical, not close
+		}
+	}
+}
+
+func TestDetector_SimilarityIndexUpdates(t *testing.T) {
+	d := NewDetector(Config{SimilarityThreshold: 0.5, MaxAllowedEmoji: -1})
+	_, err := d.LoadSamples(strings.NewReader(""), []io.Reader{strings.NewReader("win a free iphone now")},
+		[]io.Reader{strings.NewReader("hello world")})
+	require.NoError(t, err)
+
+	resp, _ := d.isSpamSimilarityHigh("lottery prize winner today")
+	assert.False(t, resp.Spam)
+
+	d.WithSpamUpdater(&mocks.SampleUpdaterMock{AppendFunc: func(msg string) error { return nil }})
+	require.NoError(t, d.UpdateSpam("lottery prize winner today"))
+	resp, similarity := d.isSpamSimilarityHigh("lottery prize winner today")
+	assert.True(t, resp.Spam, "updated sample is indexed")
+	assert.InDelta(t, 1.0, similarity, 1e-9)
+	assert.Equal(t, "1.00/0.50", resp.Details)
+
+	d.Reset()
+	resp, _ = d.isSpamSimilarityHigh("win a free iphone now")
+	assert.False(t, resp.Spam, "index is empty after reset")
+}
+
+func BenchmarkSimilarity(b *testing.B) {
+	for _, size := range []int{1000, 10000, 50000} {
+		samples, messages := makeSimilaritySamples(size, 100)
+		x := similarityIndex{}
+		for _, s := range samples {
+			x.add(s)
+		}
+
+		b.Run(fmt.Sprintf("linear_%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				linearSimilarity(samples, messages[i%len(messages)], 0.5)
+			}
+		})
+		b.Run(fmt.Sprintf("indexed_%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				x.match(messages[i%len(messages)], 0.5)
+			}
+		})
+	}
+}
+
+// sampleTokens returns tokens of the indexed sample with their frequencies
+func sampleTokens(x *similarityIndex, sample int) map[string]int {
+	res := map[string]int{}
+	for token, postings := range x.postings {
+		for _, p := range postings {
+			if p.sample == sample {
+				res[token] = p.freq
+			}
+		}
+	}
+	return res
+}
+
+// linearSimilarity is the reference implementation, compares the message with every sample
+func linearSimilarity(samples []map[string]int, msg map[string]int, threshold float64) (found bool, maxSimilarity float64) {
+	cosine := func(a, b map[string]int) float64 {
+		if len(a) == 0 || len(b) == 0 {
+			return 0.0
+		}
+		dotProduct, normA, normB := 0, 0, 0
+		for key, val := range a {
+			dotProduct += val * b[key]
+			normA += val * val
+		}
+		for _, val := range b {
+			normB += val * val
+		}
+		if normA == 0 || normB == 0 {
+			return 0.0
+		}
+		return float64(dotProduct) / (math.Sqrt(float64(normA)) * math.Sqrt(float64(normB)))
+	}
+	for _, s := range samples {
+		similarity := cosine(msg, s)
+		maxSimilarity = max(maxSimilarity, similarity)
+		if similarity >= threshold {
+			return true, maxSimilarity
+		}
+	}
+	return false, maxSimilarity
+}
+
+// makeSimilaritySamples makes tokenized spam samples and messages with zipf-distributed words,
+// i.e. a few common words shared by many samples and a long tail of rare ones, like in real texts.
+// Half of the messages are variations of samples, to get matches above threshold.
+func makeSimilaritySamples(nSamples, nMessages int) (samples, messages []map[string]int) {
+	rnd := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // test data
+	zipf := rand.NewZipf(rnd, 1.1, 1, 20000)
+	makeDoc := func() map[string]int {
+		doc := map[string]int{}
+		for range 10 + rnd.IntN(30) {
+			doc[fmt.Sprintf("word%d", zipf.Uint64())]++
+		}
+		return doc
+	}
+
+	for range nSamples {
+		samples = append(samples, makeDoc())
+	}
+	for i := range nMessages {
+		if i%2 == 0 {
+			messages = append(messages, makeDoc())
+			continue
+		}
+		msg := map[string]int{}
+		for token, freq := range samples[rnd.IntN(len(samples))] {
+			if rnd.IntN(4) > 0 { // keep most of the sample tokens
+				msg[token] = freq
+			}
+		}
+		msg[fmt.Sprintf("word%d", zipf.Uint64())]++
+		messages = append(messages, msg)
+	}
+	return samples, messages
+}